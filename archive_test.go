@@ -0,0 +1,31 @@
+package wal
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOnSegmentSealedFiresOnRotation(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-archive-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	var sealedIDs []SegmentID
+	w, err := Open(WithDirPath(dir), WithSegmentSize(32), WithOnSegmentSealed(func(path string, id SegmentID) {
+		_, statErr := os.Stat(path)
+		require.Nil(t, statErr)
+		sealedIDs = append(sealedIDs, id)
+	}))
+	require.Nil(t, err)
+	defer w.Close()
+
+	for i := 0; i < 5; i++ {
+		_, err := w.Write([]byte("0123456789"))
+		require.Nil(t, err)
+	}
+
+	require.NotEmpty(t, sealedIDs)
+	require.Equal(t, SegmentID(1), sealedIDs[0])
+}
@@ -0,0 +1,66 @@
+package wal
+
+// asyncJob is one queued WriteAsync call.
+type asyncJob struct {
+	data []byte
+	cb   func(*ChunkPosition, error)
+}
+
+// asyncQueueDepth bounds how many WriteAsync calls can be queued ahead of
+// the background writer before WriteAsync itself starts blocking, so a
+// producer that outruns disk throughput gets backpressure instead of an
+// unbounded, memory-growing queue.
+const asyncQueueDepth = 1024
+
+// WriteAsync queues data to be written by a single background goroutine and
+// returns immediately, letting a producer pipeline many writes without
+// blocking on disk for each one. cb, if non-nil, is invoked with the result
+// once that write (and, depending on Options.Sync and Options.GroupCommit,
+// its fsync) completes; writes queued this way are applied in the order
+// WriteAsync was called, exactly as if each one had been a blocking Write
+// in turn.
+//
+// cb runs on the background goroutine, so it must not block or call Close
+// and wait for it -- Close drains this same goroutine before returning, so
+// doing so from within cb deadlocks.
+//
+// Calling WriteAsync concurrently with or after Close is safe: it reports
+// ErrClosed to cb instead of sending on (or racing to send on) a channel
+// Close may be closing.
+func (w *WAL) WriteAsync(data []byte, cb func(*ChunkPosition, error)) {
+	w.startAsyncWriter()
+
+	w.asyncMu.RLock()
+	defer w.asyncMu.RUnlock()
+	if w.asyncClosed {
+		if cb != nil {
+			cb(nil, ErrClosed)
+		}
+		return
+	}
+	w.asyncJobs <- asyncJob{data: data, cb: cb}
+}
+
+// startAsyncWriter lazily starts the background goroutine WriteAsync feeds,
+// so a WAL that never calls WriteAsync never pays for it. Close's closing
+// of asyncJobs, and this function's creation of it, both happen under
+// asyncMu's write lock, so one or the other always runs first in full --
+// never a half-visible asyncJobs field or a close racing a first-time send.
+func (w *WAL) startAsyncWriter() {
+	w.asyncMu.Lock()
+	defer w.asyncMu.Unlock()
+	if w.asyncJobs != nil || w.asyncClosed {
+		return
+	}
+	w.asyncJobs = make(chan asyncJob, asyncQueueDepth)
+	w.asyncDone = make(chan struct{})
+	go func() {
+		defer close(w.asyncDone)
+		for job := range w.asyncJobs {
+			pos, err := w.Write(job.data)
+			if job.cb != nil {
+				job.cb(pos, err)
+			}
+		}
+	}()
+}
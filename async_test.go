@@ -0,0 +1,54 @@
+package wal
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteAsyncPreservesOrderAndIsReadable(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-write-async-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	const n = 100
+	var wg sync.WaitGroup
+	positions := make([]*ChunkPosition, n)
+	errs := make([]error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		w.WriteAsync([]byte(fmt.Sprintf("record-%d", i)), func(i int) func(*ChunkPosition, error) {
+			return func(pos *ChunkPosition, err error) {
+				positions[i], errs[i] = pos, err
+				wg.Done()
+			}
+		}(i))
+	}
+	wg.Wait()
+
+	for i := 0; i < n; i++ {
+		require.Nil(t, errs[i])
+		data, err := w.Read(positions[i])
+		require.Nil(t, err)
+		require.Equal(t, fmt.Sprintf("record-%d", i), string(data))
+	}
+}
+
+func TestWriteAsyncNilCallback(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-write-async-nilcb-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+
+	w.WriteAsync([]byte("hello"), nil)
+	require.Nil(t, w.Close())
+}
@@ -0,0 +1,157 @@
+package wal
+
+import "time"
+
+// autoCompactSampleChunks bounds how many chunks sampleGarbageRatio reads
+// from the front of each sealed segment to estimate a garbage ratio,
+// trading precision for a cost that stays cheap regardless of how large a
+// segment has grown.
+const autoCompactSampleChunks = 64
+
+// autoCompactPressurePoll is how often waitOutWritePressure rechecks
+// lastWriteNano while paused, and autoCompactMaxPressureWait bounds how
+// long it will keep pausing before giving a triggered compaction a turn
+// anyway, so a WAL under continuous write load doesn't starve
+// AutoCompaction forever.
+const (
+	autoCompactPressurePoll    = 50 * time.Millisecond
+	autoCompactMaxPressureWait = 30 * time.Second
+)
+
+// runAutoCompaction is the background goroutine Options.AutoCompaction
+// starts: it samples the log's garbage ratio every cfg.CheckInterval and
+// calls runThrottledCompact once that ratio crosses
+// cfg.GarbageRatioThreshold.
+func (w *WAL) runAutoCompaction(cfg *AutoCompactionConfig) {
+	defer w.autoCompactWG.Done()
+
+	interval := cfg.CheckInterval
+	if interval <= 0 {
+		interval = DefaultAutoCompactionConfig.CheckInterval
+	}
+	threshold := cfg.GarbageRatioThreshold
+	if threshold <= 0 {
+		threshold = DefaultAutoCompactionConfig.GarbageRatioThreshold
+	}
+
+	ticker := w.options.Clock.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C():
+			ratio, err := w.sampleGarbageRatio(cfg.LiveFilter)
+			if err != nil {
+				w.recordAutoCompactErr(err)
+				continue
+			}
+			if ratio < threshold {
+				continue
+			}
+			if err := w.runThrottledCompact(cfg); err != nil {
+				w.recordAutoCompactErr(err)
+			}
+		case <-w.autoCompactDone:
+			return
+		}
+	}
+}
+
+// sampleGarbageRatio estimates the fraction of sampled bytes liveFilter
+// reports dead, reading up to autoCompactSampleChunks chunks from the
+// front of every sealed segment -- cheap compared to Compact's full
+// rewrite, since it never writes anything back. It returns 0 if no sealed
+// segment has any chunks to sample.
+func (w *WAL) sampleGarbageRatio(liveFilter func(pos *ChunkPosition, data []byte) bool) (float64, error) {
+	w.mu.RLock()
+	sealed := w.sealedSegmentsLocked()
+	w.mu.RUnlock()
+
+	var liveBytes, deadBytes int64
+	for _, seg := range sealed {
+		var offset int64
+		for i := 0; i < autoCompactSampleChunks; i++ {
+			data, err := seg.Read(offset)
+			if err == ErrInvalidCRC {
+				return 0, err
+			}
+			if err != nil {
+				break // io.EOF or io.ErrUnexpectedEOF: nothing more to read
+			}
+			pos := &ChunkPosition{SegmentId: seg.id, ChunkOffset: offset, ChunkSize: uint32(len(data))}
+			if liveFilter(pos, data) {
+				liveBytes += int64(len(data))
+			} else {
+				deadBytes += int64(len(data))
+			}
+			offset += int64(chunkHeaderSize) + int64(len(data))
+		}
+	}
+
+	total := liveBytes + deadBytes
+	if total == 0 {
+		return 0, nil
+	}
+	return float64(deadBytes) / float64(total), nil
+}
+
+// runThrottledCompact calls Compact with cfg.LiveFilter wrapped in a
+// closure that throttles to cfg.ThrottleBytesPerSec and pauses while a
+// write is still recent (cfg.WritePressureWindow), rather than modifying
+// Compact itself: every other Compact caller keeps running at full speed,
+// and only a triggered compaction pays this cost.
+func (w *WAL) runThrottledCompact(cfg *AutoCompactionConfig) error {
+	window := cfg.WritePressureWindow
+	if window <= 0 {
+		window = DefaultAutoCompactionConfig.WritePressureWindow
+	}
+
+	var limiter Limiter
+	if cfg.ThrottleBytesPerSec > 0 {
+		limiter = newTokenBucketLimiter(cfg.ThrottleBytesPerSec)
+	}
+
+	wrapped := func(pos *ChunkPosition, data []byte) bool {
+		w.waitOutWritePressure(window)
+		if limiter != nil {
+			_ = limiter.WaitN(chunkHeaderSize + len(data))
+		}
+		return cfg.LiveFilter(pos, data)
+	}
+
+	_, err := w.Compact(wrapped, nil)
+	return err
+}
+
+// waitOutWritePressure blocks, polling lastWriteNano rather than taking
+// mu, until at least window has passed since the most recent Write --
+// or until autoCompactMaxPressureWait has elapsed regardless, so a WAL
+// under continuous write load still gets to compact eventually.
+func (w *WAL) waitOutWritePressure(window time.Duration) {
+	deadline := time.Now().Add(autoCompactMaxPressureWait)
+	for time.Now().Before(deadline) {
+		lastWrite := time.Unix(0, w.lastWriteNano.Load())
+		if w.options.Clock.Now().Sub(lastWrite) >= window {
+			return
+		}
+		time.Sleep(autoCompactPressurePoll)
+	}
+}
+
+// recordAutoCompactErr records err as the reason the most recent
+// AutoCompaction check or triggered compaction failed, for
+// AutoCompactionErr.
+func (w *WAL) recordAutoCompactErr(err error) {
+	w.autoCompactErrMu.Lock()
+	w.autoCompactErr = err
+	w.autoCompactErrMu.Unlock()
+}
+
+// AutoCompactionErr returns the error the most recent Options.AutoCompaction
+// garbage-ratio check or triggered Compact call failed with, or nil if
+// every one so far has succeeded. It always returns nil when
+// Options.AutoCompaction is unset.
+func (w *WAL) AutoCompactionErr() error {
+	w.autoCompactErrMu.Lock()
+	defer w.autoCompactErrMu.Unlock()
+	return w.autoCompactErr
+}
@@ -0,0 +1,150 @@
+package wal
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAutoCompactionTriggersOnceGarbageRatioExceedsThreshold(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-autocompact-trigger-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	clock := NewFakeClock(time.Now())
+
+	var deadPos *ChunkPosition
+	liveFilter := func(pos *ChunkPosition, data []byte) bool {
+		return !pos.Equal(deadPos)
+	}
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(32), WithClock(clock),
+		WithAutoCompaction(AutoCompactionConfig{
+			LiveFilter:            liveFilter,
+			CheckInterval:         time.Second,
+			GarbageRatioThreshold: 0.1,
+		}))
+	require.Nil(t, err)
+	defer w.Close()
+
+	deadPos, err = w.Write([]byte("garbage value"))
+	require.Nil(t, err)
+	live, err := w.Write([]byte("live value"))
+	require.Nil(t, err)
+	for i := 0; i < 3; i++ {
+		_, err := w.Write([]byte("filler to force rotation"))
+		require.Nil(t, err)
+	}
+	require.Greater(t, len(w.olderSegments), 0)
+
+	wantLiveBytes, err := w.Read(live)
+	require.Nil(t, err)
+
+	require.Eventually(t, func() bool {
+		clock.Tick(time.Second)
+		clock.RunPending()
+
+		var seen []string
+		r := w.NewReader()
+		for {
+			data, _, err := r.Next()
+			if err != nil {
+				break
+			}
+			seen = append(seen, string(data))
+		}
+		return len(seen) > 0 && !contains(seen, "garbage value") && contains(seen, "live value")
+	}, 2*time.Second, 5*time.Millisecond, "auto-compaction never removed the dead chunk")
+
+	require.Nil(t, w.AutoCompactionErr())
+
+	gotLiveBytes, err := w.Read(live)
+	// live's own position is stale once Compact has rewritten the segment;
+	// rereading through it would fail if it wasn't rewritten at all, so just
+	// confirm the underlying bytes still exist in the log via NewReader did
+	// above, and that the original position still resolves only if nothing
+	// moved it.
+	if err == nil {
+		require.Equal(t, wantLiveBytes, gotLiveBytes)
+	}
+}
+
+func TestAutoCompactionSkipsBelowThreshold(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-autocompact-below-threshold-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	clock := NewFakeClock(time.Now())
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(32), WithClock(clock),
+		WithAutoCompaction(AutoCompactionConfig{
+			LiveFilter:            func(pos *ChunkPosition, data []byte) bool { return true },
+			CheckInterval:         time.Second,
+			GarbageRatioThreshold: 0.9,
+		}))
+	require.Nil(t, err)
+	defer w.Close()
+
+	for i := 0; i < 5; i++ {
+		_, err := w.Write([]byte("filler to force rotation"))
+		require.Nil(t, err)
+	}
+	before := len(w.olderSegments)
+	require.Greater(t, before, 0)
+
+	for i := 0; i < 5; i++ {
+		clock.Tick(time.Second)
+		clock.RunPending()
+	}
+
+	w.mu.RLock()
+	after := len(w.olderSegments)
+	w.mu.RUnlock()
+	require.Equal(t, before, after)
+	require.Nil(t, w.AutoCompactionErr())
+}
+
+func TestWaitOutWritePressureBlocksUntilWriteIsStale(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-autocompact-pressure-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	clock := NewFakeClock(time.Now())
+	w, err := Open(WithDirPath(dir), WithClock(clock))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("recent write"))
+	require.Nil(t, err)
+
+	done := make(chan struct{})
+	go func() {
+		w.waitOutWritePressure(time.Minute)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("waitOutWritePressure should still be blocked on a recent write")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	clock.Tick(2 * time.Minute)
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("waitOutWritePressure never unblocked once the write was stale")
+	}
+}
+
+func contains(haystack []string, needle string) bool {
+	for _, s := range haystack {
+		if s == needle {
+			return true
+		}
+	}
+	return false
+}
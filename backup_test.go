@@ -0,0 +1,112 @@
+package wal
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackupCopiesEverySegment(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-backup-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(64))
+	require.Nil(t, err)
+	defer w.Close()
+
+	for i := 0; i < 20; i++ {
+		_, err := w.Write(bytes.Repeat([]byte{byte('a' + i)}, 8))
+		require.Nil(t, err)
+	}
+
+	dstDir, err := os.MkdirTemp("", "wal-backup-dst")
+	require.Nil(t, err)
+	defer os.RemoveAll(dstDir)
+
+	result, err := w.Backup(dstDir)
+	require.Nil(t, err)
+	require.False(t, result.FragmentCopied)
+	require.Positive(t, result.SegmentsCopied)
+
+	restored, err := Open(WithDirPath(dstDir), WithReadOnly(true))
+	require.Nil(t, err)
+	defer restored.Close()
+
+	r := restored.NewReader()
+	var records [][]byte
+	for {
+		data, _, err := r.Next()
+		if err != nil {
+			break
+		}
+		records = append(records, append([]byte{}, data...))
+	}
+	require.Len(t, records, 20)
+	for i, record := range records {
+		require.Equal(t, bytes.Repeat([]byte{byte('a' + i)}, 8), record)
+	}
+}
+
+func TestBackupSinceOnlyCopiesDataAfterPos(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-backup-since-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(64))
+	require.Nil(t, err)
+	defer w.Close()
+
+	var marker *ChunkPosition
+	for i := 0; i < 10; i++ {
+		pos, err := w.Write(bytes.Repeat([]byte{byte('a' + i)}, 8))
+		require.Nil(t, err)
+		if i == 4 {
+			marker = pos
+		}
+	}
+
+	dstDir, err := os.MkdirTemp("", "wal-backup-since-dst")
+	require.Nil(t, err)
+	defer os.RemoveAll(dstDir)
+
+	result, err := w.BackupSince(marker, dstDir)
+	require.Nil(t, err)
+	require.True(t, result.FragmentCopied)
+
+	fragmentPath := segmentFileName(dstDir, w.options.SegmentFileExt, marker.SegmentId, nil) + incrementalSuffix
+	info, err := os.Stat(fragmentPath)
+	require.Nil(t, err)
+	require.Positive(t, info.Size())
+
+	full, err := w.Backup(dstDir + "-full")
+	defer os.RemoveAll(dstDir + "-full")
+	require.Nil(t, err)
+	require.Positive(t, full.BytesCopied)
+	require.Greater(t, full.BytesCopied, result.BytesCopied)
+}
+
+func TestBackupSinceAtTheVeryLastPositionCopiesNothing(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-backup-since-empty-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	pos, err := w.Write([]byte("only record"))
+	require.Nil(t, err)
+
+	dstDir, err := os.MkdirTemp("", "wal-backup-since-empty-dst")
+	require.Nil(t, err)
+	defer os.RemoveAll(dstDir)
+
+	result, err := w.BackupSince(pos, dstDir)
+	require.Nil(t, err)
+	require.False(t, result.FragmentCopied)
+	require.Zero(t, result.SegmentsCopied)
+	require.Zero(t, result.BytesCopied)
+}
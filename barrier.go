@@ -0,0 +1,119 @@
+package wal
+
+import (
+	"fmt"
+	"io"
+)
+
+// barrierMagic prefixes a WriteBarrier marker's payload so Barriers can
+// pick it back out of the log when scanning -- the same discriminator
+// trick checkpointMagic uses for WriteCheckpoint, on the assumption
+// nothing else written to this WAL happens to start with these exact
+// bytes.
+const barrierMagic = "WAL:BARR"
+
+// encodeBarrierMarker prepends barrierMagic to meta, so it can be told
+// apart from an ordinary record by decodeBarrierMarker.
+func encodeBarrierMarker(meta []byte) []byte {
+	raw := make([]byte, len(barrierMagic)+len(meta))
+	copy(raw, barrierMagic)
+	copy(raw[len(barrierMagic):], meta)
+	return raw
+}
+
+// decodeBarrierMarker reports whether raw is a barrier marker
+// encodeBarrierMarker produced, and if so, its meta.
+func decodeBarrierMarker(raw []byte) (meta []byte, ok bool) {
+	if len(raw) < len(barrierMagic) || string(raw[:len(barrierMagic)]) != barrierMagic {
+		return nil, false
+	}
+	return raw[len(barrierMagic):], true
+}
+
+// WriteBarrier appends a barrier marker carrying meta -- typically a
+// caller's own description of the epoch it's opening, e.g. a schema
+// version -- and returns its position. It's an ordinary record as far as
+// Read and Reader.Next are concerned, but Barriers and ReadersAfterBarrier
+// can pick it back out again, letting several cooperating readers agree
+// on the same epoch boundary or snapshot cut point without each inventing
+// its own out-of-band signal for it.
+func (w *WAL) WriteBarrier(meta []byte) (*ChunkPosition, error) {
+	pos, err := w.Write(encodeBarrierMarker(meta))
+	if err != nil {
+		return nil, err
+	}
+	w.mu.Lock()
+	w.barrierPositions = append(w.barrierPositions, pos)
+	w.mu.Unlock()
+	return pos, nil
+}
+
+// Barriers returns the position of every marker WriteBarrier has written
+// to this WAL, oldest first. The first call scans the whole log to
+// recover markers a previous process wrote; every call after that is
+// served from memory.
+//
+// Like LastCheckpoint, this assumes every chunk in the WAL was written
+// through Write, WriteAll, WriteCheckpoint, CommitOffset, or WriteBarrier
+// itself, since the scan reads raw chunk bytes looking for barrierMagic.
+func (w *WAL) Barriers() ([]*ChunkPosition, error) {
+	w.mu.Lock()
+	scanned := w.barriersScanned
+	positions := w.barrierPositions
+	w.mu.Unlock()
+	if scanned {
+		return positions, nil
+	}
+
+	var found []*ChunkPosition
+	r := w.NewReader()
+	for {
+		data, pos, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := decodeBarrierMarker(data); ok {
+			found = append(found, pos)
+		}
+	}
+
+	w.mu.Lock()
+	if !w.barriersScanned {
+		w.barrierPositions = found
+		w.barriersScanned = true
+	}
+	positions = w.barrierPositions
+	w.mu.Unlock()
+	return positions, nil
+}
+
+// ErrNotEnoughBarriers is returned by ReadersAfterBarrier when fewer than
+// Want barriers have been written to the WAL.
+type ErrNotEnoughBarriers struct {
+	Want, Have int
+}
+
+func (e *ErrNotEnoughBarriers) Error() string {
+	return fmt.Sprintf("wal: asked for reader after barrier %d, but only %d barriers have been written", e.Want, e.Have)
+}
+
+// ReadersAfterBarrier returns a Reader that resumes just after the nth
+// barrier WriteBarrier has written to this WAL (1-indexed, oldest first),
+// so every caller that asks for the same n starts reading from exactly
+// the same epoch boundary -- coordinating a schema change or snapshot cut
+// point across several consumers without them separately agreeing on a
+// position out of band. It returns *ErrNotEnoughBarriers if fewer than n
+// barriers have been written yet.
+func (w *WAL) ReadersAfterBarrier(n int, opts ...ReaderOption) (*Reader, error) {
+	barriers, err := w.Barriers()
+	if err != nil {
+		return nil, err
+	}
+	if n <= 0 || n > len(barriers) {
+		return nil, &ErrNotEnoughBarriers{Want: n, Have: len(barriers)}
+	}
+	return w.NewReaderWithStart(barriers[n-1], opts...)
+}
@@ -0,0 +1,103 @@
+package wal
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBarriersReturnsEveryMarkerOldestFirst(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-barrier-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("ordinary record"))
+	require.Nil(t, err)
+
+	first, err := w.WriteBarrier([]byte("schema-v1"))
+	require.Nil(t, err)
+
+	_, err = w.Write([]byte("more ordinary records"))
+	require.Nil(t, err)
+
+	second, err := w.WriteBarrier([]byte("schema-v2"))
+	require.Nil(t, err)
+
+	barriers, err := w.Barriers()
+	require.Nil(t, err)
+	require.Equal(t, []*ChunkPosition{first, second}, barriers)
+}
+
+func TestBarriersRecoversAfterReopen(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-barrier-reopen-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	barrier, err := w.WriteBarrier([]byte("schema-v1"))
+	require.Nil(t, err)
+	_, err = w.Write([]byte("after"))
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+
+	w2, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w2.Close()
+
+	barriers, err := w2.Barriers()
+	require.Nil(t, err)
+	require.Equal(t, []*ChunkPosition{barrier}, barriers)
+}
+
+func TestReadersAfterBarrierResumesJustPastTheNthBarrier(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-barrier-readers-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("before first barrier"))
+	require.Nil(t, err)
+	_, err = w.WriteBarrier([]byte("schema-v1"))
+	require.Nil(t, err)
+	_, err = w.Write([]byte("between barriers"))
+	require.Nil(t, err)
+	_, err = w.WriteBarrier([]byte("schema-v2"))
+	require.Nil(t, err)
+	_, err = w.Write([]byte("after second barrier"))
+	require.Nil(t, err)
+
+	r, err := w.ReadersAfterBarrier(2)
+	require.Nil(t, err)
+
+	data, _, err := r.Next()
+	require.Nil(t, err)
+	require.Equal(t, "after second barrier", string(data))
+}
+
+func TestReadersAfterBarrierReturnsErrNotEnoughBarriers(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-barrier-not-enough-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.WriteBarrier([]byte("schema-v1"))
+	require.Nil(t, err)
+
+	_, err = w.ReadersAfterBarrier(2)
+	var notEnough *ErrNotEnoughBarriers
+	require.ErrorAs(t, err, &notEnough)
+	require.Equal(t, 2, notEnough.Want)
+	require.Equal(t, 1, notEnough.Have)
+}
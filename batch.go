@@ -0,0 +1,263 @@
+package wal
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// batchFrameSize is the size, in bytes, of the prefix Batch.Commit and
+// Batch.Prepare add to every chunk they write: an 8-byte batch ID shared by
+// every chunk in the batch, including its trailing marker, followed by a
+// 1-byte flag identifying what kind of chunk it is. See encodeBatchFrame.
+const batchFrameSize = 8 + 1
+
+// The batchFrame flag values. batchFlagRecord is zero so that a frame
+// carrying a record, the common case, needs no flag bits set at all.
+const (
+	batchFlagRecord byte = iota
+	batchFlagCommit
+	batchFlagPrepare
+	batchFlagAbort
+)
+
+// encodeBatchFrame prefixes data with batchID and flag, the same way
+// WriteWithFlags prefixes data with a flags byte; see decodeBatchFrame for
+// the reverse.
+func encodeBatchFrame(batchID uint64, flag byte, data []byte) []byte {
+	frame := make([]byte, batchFrameSize+len(data))
+	binary.BigEndian.PutUint64(frame[:8], batchID)
+	frame[8] = flag
+	copy(frame[batchFrameSize:], data)
+	return frame
+}
+
+// decodeBatchFrame reverses encodeBatchFrame.
+func decodeBatchFrame(raw []byte) (batchID uint64, flag byte, data []byte) {
+	return binary.BigEndian.Uint64(raw[:8]), raw[8], raw[batchFrameSize:]
+}
+
+// ErrBatchNotPrepared is returned by Batch.CommitPrepared and Batch.Abort
+// when called on a Batch that was never returned by Batch.Prepare or
+// WAL.ResumeBatch, since neither has a prepare marker on disk for them to
+// resolve.
+var ErrBatchNotPrepared = fmt.Errorf("wal: batch was not prepared")
+
+// Batch collects records to be appended to the WAL as a single atomic
+// unit: Reader.NextBatch returns either every record Put into a Batch
+// before Commit returned successfully, or none of them, even if a crash
+// landed partway through writing one out. Create one with WAL.NewBatch.
+//
+// Unlike PendingWrites, which buffers on the WAL itself, each Batch owns
+// its own buffer: two goroutines each building a Batch concurrently never
+// interleave each other's records the way two goroutines both calling
+// PendingWrites on the same WAL would. A single Batch is still not meant
+// to be Put into or Committed from more than one goroutine at a time.
+//
+// A Batch also supports a two-phase commit in place of Commit, for
+// coordinating with an external store: Prepare durably writes the records
+// and returns a token, and CommitPrepared or Abort, called later -- even
+// from a ResumeBatch reconstructed after a restart -- resolves it. See
+// Prepare.
+type Batch struct {
+	w        *WAL
+	id       uint64
+	records  [][]byte
+	prepared bool
+}
+
+// NewBatch returns an empty Batch with its own buffer, independent of any
+// other Batch or of WAL.PendingWrites. Multiple Batches, including ones
+// built and committed concurrently from different goroutines, can be in
+// flight against the same WAL at once -- each gets its own batch ID, so
+// Reader.NextBatch can tell their chunks apart even if their writes
+// interleave in the log.
+func (w *WAL) NewBatch() *Batch {
+	w.mu.Lock()
+	id := w.nextBatchSeq
+	w.nextBatchSeq++
+	w.mu.Unlock()
+	return &Batch{w: w, id: id}
+}
+
+// ResumeBatch reconstructs a handle onto the batch behind token, so that
+// CommitPrepared or Abort can be called for it without the original Batch
+// still being around -- the situation WAL.InDoubtBatches exists to find
+// after a restart. It has no record buffer of its own: whatever Prepare
+// wrote is already durable, so there's nothing left to Put.
+func (w *WAL) ResumeBatch(token BatchToken) *Batch {
+	return &Batch{w: w, id: uint64(token), prepared: true}
+}
+
+// Put buffers data to be written by Commit or Prepare, without touching
+// the segment files yet, the same way PendingWrites does for WriteAll.
+func (b *Batch) Put(data []byte) {
+	b.records = append(b.records, data)
+}
+
+// Discard drops every record Put into the Batch so far, leaving it empty
+// and ready to be reused, without ever having written any of them to the
+// WAL.
+func (b *Batch) Discard() {
+	b.records = nil
+}
+
+// Commit appends every record Put into the Batch to the WAL as a single
+// writeBatch call, the same way WriteAll does, immediately followed by a
+// zero-length commit marker chunk tagged with the same batch ID. Reader.
+// NextBatch only ever returns a batch once it has seen that marker, so a
+// crash or I/O error that leaves Commit's write incomplete -- the marker
+// included -- leaves the records it already wrote durable on disk but
+// unreachable through NextBatch, rather than half-applied.
+//
+// Committing an empty Batch is a no-op: it returns nil, nil without
+// touching the WAL at all, since there would be nothing to make atomic.
+//
+// Commit settles the batch immediately; use Prepare instead if an
+// external store needs a chance to durably record its own side of the
+// transaction before this one is finalized.
+func (b *Batch) Commit() ([]*ChunkPosition, error) {
+	if len(b.records) == 0 {
+		return nil, nil
+	}
+
+	datas := make([][]byte, len(b.records)+1)
+	for i, data := range b.records {
+		datas[i] = encodeBatchFrame(b.id, batchFlagRecord, data)
+	}
+	datas[len(b.records)] = encodeBatchFrame(b.id, batchFlagCommit, nil)
+
+	b.w.mu.Lock()
+	defer b.w.mu.Unlock()
+	if b.w.options.ReadOnly {
+		return nil, ErrReadOnly
+	}
+	positions, err := b.w.writeBatch(datas)
+	if err != nil {
+		return nil, err
+	}
+	b.records = nil
+	return positions[:len(positions)-1], nil
+}
+
+// BatchToken identifies a Batch that has been Prepare'd but not yet
+// resolved with CommitPrepared or Abort. It survives a restart: save it
+// alongside whatever an external store needs to decide how a prepared
+// batch should be resolved, and pass it to WAL.ResumeBatch to resolve it
+// later, possibly from a different process than the one that prepared it.
+type BatchToken uint64
+
+// Prepare durably writes every record Put into the Batch, immediately
+// followed by a zero-length prepare marker chunk tagged with the same
+// batch ID, and fsyncs before returning -- regardless of Options.Sync or
+// Options.BytesPerSync -- so the caller can safely tell an external store
+// "this half of the transaction is durable" the moment Prepare returns.
+// It returns a BatchToken identifying the prepared batch.
+//
+// The records are not visible through Reader.NextBatch yet: a prepared
+// batch behaves exactly like an uncommitted one until CommitPrepared or
+// Abort is called for its token, possibly much later and, via
+// ResumeBatch, possibly after a restart. WAL.InDoubtBatches finds every
+// token left unresolved that way, e.g. by a crash between Prepare and its
+// resolution, so the application can ask whatever it was coordinating
+// with which way each one actually went.
+func (b *Batch) Prepare() (BatchToken, error) {
+	datas := make([][]byte, len(b.records)+1)
+	for i, data := range b.records {
+		datas[i] = encodeBatchFrame(b.id, batchFlagRecord, data)
+	}
+	datas[len(b.records)] = encodeBatchFrame(b.id, batchFlagPrepare, nil)
+
+	b.w.mu.Lock()
+	if b.w.options.ReadOnly {
+		b.w.mu.Unlock()
+		return 0, ErrReadOnly
+	}
+	_, err := b.w.writeBatch(datas)
+	b.w.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+	if _, err := b.w.Sync(); err != nil {
+		return 0, err
+	}
+
+	b.records = nil
+	b.prepared = true
+	return BatchToken(b.id), nil
+}
+
+// CommitPrepared resolves a batch Prepare'd earlier -- on this Batch, or
+// on one reconstructed with ResumeBatch -- by writing a trailing commit
+// marker chunk tagged with its batch ID, making its records visible
+// through Reader.NextBatch from then on.
+func (b *Batch) CommitPrepared() error {
+	if !b.prepared {
+		return ErrBatchNotPrepared
+	}
+	return b.writeMarker(batchFlagCommit)
+}
+
+// Abort resolves a batch Prepare'd earlier -- on this Batch, or on one
+// reconstructed with ResumeBatch -- by writing a trailing abort marker
+// chunk tagged with its batch ID. Its records, already durable on disk
+// from Prepare, are never returned through Reader.NextBatch, the same as
+// if Prepare had never resolved at all.
+func (b *Batch) Abort() error {
+	if !b.prepared {
+		return ErrBatchNotPrepared
+	}
+	return b.writeMarker(batchFlagAbort)
+}
+
+// writeMarker appends a single zero-length chunk tagged with the batch's
+// ID and flag, for CommitPrepared and Abort to resolve a prepared batch
+// without rewriting its already-durable records.
+func (b *Batch) writeMarker(flag byte) error {
+	b.w.mu.Lock()
+	defer b.w.mu.Unlock()
+	if b.w.options.ReadOnly {
+		return ErrReadOnly
+	}
+	_, err := b.w.writeBatch([][]byte{encodeBatchFrame(b.id, flag, nil)})
+	return err
+}
+
+// InDoubtBatches scans the WAL from the beginning and returns the token of
+// every batch that reached Prepare but was never resolved with
+// CommitPrepared or Abort -- typically because the process crashed
+// between the two. The caller is expected to ask whatever external store
+// it was coordinating with which way each one actually went, then call
+// ResumeBatch(token).CommitPrepared() or .Abort() accordingly before
+// resuming normal operation.
+//
+// Like Reader.NextBatch, it assumes every chunk in the WAL was written
+// through the Batch API; a WAL that also carries plain Write,
+// WriteWithFlags, or WriteWithTTL chunks can misread their leading bytes
+// as batch framing.
+func (w *WAL) InDoubtBatches() ([]BatchToken, error) {
+	prepared := make(map[uint64]bool)
+	r := w.NewReader()
+	for {
+		raw, _, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		id, flag, _ := decodeBatchFrame(raw)
+		switch flag {
+		case batchFlagPrepare:
+			prepared[id] = true
+		case batchFlagCommit, batchFlagAbort:
+			delete(prepared, id)
+		}
+	}
+
+	tokens := make([]BatchToken, 0, len(prepared))
+	for id := range prepared {
+		tokens = append(tokens, BatchToken(id))
+	}
+	return tokens, nil
+}
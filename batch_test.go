@@ -0,0 +1,247 @@
+package wal
+
+import (
+	"io"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchCommitRoundTripsThroughNextBatch(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-batch-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"))
+	require.Nil(t, err)
+	defer w.Close()
+
+	b := w.NewBatch()
+	b.Put([]byte("first"))
+	b.Put([]byte("second"))
+	positions, err := b.Commit()
+	require.Nil(t, err)
+	require.Equal(t, 2, len(positions))
+
+	r := w.NewReader()
+	records, pos, err := r.NextBatch()
+	require.Nil(t, err)
+	require.Equal(t, 2, len(records))
+	assert.Equal(t, "first", string(records[0]))
+	assert.Equal(t, "second", string(records[1]))
+	assert.Equal(t, positions, pos)
+
+	_, _, err = r.NextBatch()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestEmptyBatchCommitIsANoOp(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-batch-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"))
+	require.Nil(t, err)
+	defer w.Close()
+
+	positions, err := w.NewBatch().Commit()
+	require.Nil(t, err)
+	assert.Nil(t, positions)
+
+	_, err = w.Write([]byte("still usable"))
+	require.Nil(t, err)
+}
+
+func TestDiscardDropsBufferedRecordsWithoutWritingThem(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-batch-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"))
+	require.Nil(t, err)
+	defer w.Close()
+
+	b := w.NewBatch()
+	b.Put([]byte("never written"))
+	b.Discard()
+	b.Put([]byte("kept"))
+	positions, err := b.Commit()
+	require.Nil(t, err)
+	require.Equal(t, 1, len(positions))
+
+	r := w.NewReader()
+	records, _, err := r.NextBatch()
+	require.Nil(t, err)
+	require.Equal(t, 1, len(records))
+	assert.Equal(t, "kept", string(records[0]))
+}
+
+func TestConcurrentBatchesDoNotInterleaveEachOthersRecords(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-batch-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"))
+	require.Nil(t, err)
+	defer w.Close()
+
+	const goroutines = 8
+	const perBatch = 20
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			b := w.NewBatch()
+			for i := 0; i < perBatch; i++ {
+				b.Put([]byte{byte(g)})
+			}
+			_, err := b.Commit()
+			assert.Nil(t, err)
+		}(g)
+	}
+	wg.Wait()
+
+	r := w.NewReader()
+	seen := 0
+	for {
+		records, _, err := r.NextBatch()
+		if err == io.EOF {
+			break
+		}
+		require.Nil(t, err)
+		require.Equal(t, perBatch, len(records))
+		first := records[0][0]
+		for _, rec := range records {
+			require.Equal(t, first, rec[0], "a batch's records must all come from the same goroutine")
+		}
+		seen++
+	}
+	assert.Equal(t, goroutines, seen)
+}
+
+func TestNextBatchSkipsRecordsLeftByAnUncommittedBatch(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-batch-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"))
+	require.Nil(t, err)
+	defer w.Close()
+
+	// Simulate a crash partway through Batch.Commit: the records land,
+	// but the trailing commit marker never does.
+	uncommitted := w.NewBatch()
+	_, err = w.Write(encodeBatchFrame(uncommitted.id, batchFlagRecord, []byte("orphaned")))
+	require.Nil(t, err)
+
+	committed := w.NewBatch()
+	committed.Put([]byte("real"))
+	_, err = committed.Commit()
+	require.Nil(t, err)
+
+	r := w.NewReader()
+	records, _, err := r.NextBatch()
+	require.Nil(t, err)
+	require.Equal(t, 1, len(records))
+	assert.Equal(t, "real", string(records[0]))
+
+	_, _, err = r.NextBatch()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestPrepareThenCommitPreparedMakesRecordsVisibleToNextBatch(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-batch-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"))
+	require.Nil(t, err)
+	defer w.Close()
+
+	b := w.NewBatch()
+	b.Put([]byte("first"))
+	b.Put([]byte("second"))
+	token, err := b.Prepare()
+	require.Nil(t, err)
+
+	// Not yet visible: the batch is only prepared, not committed.
+	r := w.NewReader()
+	_, _, err = r.NextBatch()
+	assert.Equal(t, io.EOF, err)
+
+	require.Nil(t, b.CommitPrepared())
+
+	r = w.NewReader()
+	records, _, err := r.NextBatch()
+	require.Nil(t, err)
+	require.Equal(t, 2, len(records))
+	assert.Equal(t, "first", string(records[0]))
+	assert.Equal(t, "second", string(records[1]))
+
+	tokens, err := w.InDoubtBatches()
+	require.Nil(t, err)
+	assert.NotContains(t, tokens, token)
+}
+
+func TestAbortDropsAPreparedBatchsRecords(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-batch-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"))
+	require.Nil(t, err)
+	defer w.Close()
+
+	b := w.NewBatch()
+	b.Put([]byte("never visible"))
+	_, err = b.Prepare()
+	require.Nil(t, err)
+	require.Nil(t, b.Abort())
+
+	r := w.NewReader()
+	_, _, err = r.NextBatch()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestInDoubtBatchesReportsAPreparedBatchUntilResolved(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-batch-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"))
+	require.Nil(t, err)
+	defer w.Close()
+
+	b := w.NewBatch()
+	b.Put([]byte("in doubt"))
+	token, err := b.Prepare()
+	require.Nil(t, err)
+
+	tokens, err := w.InDoubtBatches()
+	require.Nil(t, err)
+	require.Equal(t, []BatchToken{token}, tokens)
+
+	require.Nil(t, w.ResumeBatch(token).Abort())
+
+	tokens, err = w.InDoubtBatches()
+	require.Nil(t, err)
+	assert.Empty(t, tokens)
+}
+
+func TestCommitPreparedOnAnUnpreparedBatchFails(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-batch-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"))
+	require.Nil(t, err)
+	defer w.Close()
+
+	b := w.NewBatch()
+	assert.Equal(t, ErrBatchNotPrepared, b.CommitPrepared())
+	assert.Equal(t, ErrBatchNotPrepared, b.Abort())
+}
@@ -0,0 +1,150 @@
+package wal
+
+import (
+	"container/list"
+	"sync"
+)
+
+// blockCacheShardCount is the number of independent shards a blockCache
+// splits its budget and locking across. Sharding by key (see shardFor)
+// means two goroutines reading different segments -- the common case for
+// rosedb's random point reads -- almost never contend on the same mutex,
+// unlike a single cache-wide lock would.
+const blockCacheShardCount = 16
+
+// blockCacheGranularity is the size of the region chunk offsets are
+// grouped into for shardFor's purposes. It is unrelated to
+// Options.BlockSize -- that field is a no-op recorded only for
+// compatibility (see its doc comment) -- this is purely an internal
+// grouping so that nearby reads land in the same shard.
+const blockCacheGranularity = 4096
+
+// blockCacheKey identifies one cached entry: the chunk at ChunkOffset
+// within segment SegmentId. Despite the "block" name (kept for symmetry
+// with Options.BlockSize and ChunkMeta.BlockNumber, and because entries
+// are grouped into shards by BlockNumber, not just SegmentId), a cache
+// entry holds exactly one chunk's decoded payload, not a fixed-size
+// physical block -- this package has no such framing; see
+// Options.BlockSize's doc comment.
+type blockCacheKey struct {
+	segmentID   SegmentID
+	chunkOffset int64
+}
+
+// blockCache is a sharded LRU cache of decoded chunk payloads (after CRC
+// verification, decryption, and decompression -- i.e. exactly what
+// WAL.readDecoded would otherwise recompute), keyed by blockCacheKey and
+// sharded by (segmentID, blockNumber) so that reads into the same region
+// of a segment -- the access pattern a rosedb-style random-read workload
+// actually has -- land in the same shard instead of spreading lock
+// contention evenly across all of them.
+//
+// It exists to let hot random reads skip the pread (or mmap fault) that
+// segment.Read would otherwise issue on every call; see WithBlockCache.
+type blockCache struct {
+	shards        [blockCacheShardCount]blockCacheShard
+	shardCapacity int64 // maxBytes split evenly across shards
+}
+
+// blockCacheShard is one independently-locked slice of a blockCache's
+// budget, evicting its own least-recently-used entries once its share of
+// bytes is exceeded.
+type blockCacheShard struct {
+	mu        sync.Mutex
+	ll        *list.List // of *blockCacheEntry, most-recently-used at the front
+	index     map[blockCacheKey]*list.Element
+	usedBytes int64
+}
+
+// blockCacheEntry is one blockCacheShard.ll node.
+type blockCacheEntry struct {
+	key  blockCacheKey
+	data []byte
+}
+
+// newBlockCache returns a blockCache that keeps at most maxBytes worth of
+// decoded chunk payloads in memory; see blockCache's doc comment.
+func newBlockCache(maxBytes int64) *blockCache {
+	c := &blockCache{
+		shardCapacity: maxBytes / blockCacheShardCount,
+	}
+	for i := range c.shards {
+		c.shards[i].ll = list.New()
+		c.shards[i].index = make(map[blockCacheKey]*list.Element)
+	}
+	return c
+}
+
+// shardFor returns the shard key's entry belongs in, grouping every chunk
+// offset that falls in the same blockNumber-sized region of segmentID
+// together.
+func (c *blockCache) shardFor(segmentID SegmentID, chunkOffset int64) *blockCacheShard {
+	blockNumber := chunkOffset / blockCacheGranularity
+	h := uint64(segmentID)*31 + uint64(blockNumber)
+	return &c.shards[h%uint64(blockCacheShardCount)]
+}
+
+// get returns the cached payload for (segmentID, chunkOffset), and
+// whether it was found. A hit moves the entry to the front of its shard's
+// LRU list.
+func (c *blockCache) get(segmentID SegmentID, chunkOffset int64) ([]byte, bool) {
+	shard := c.shardFor(segmentID, chunkOffset)
+	key := blockCacheKey{segmentID, chunkOffset}
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	elem, ok := shard.index[key]
+	if !ok {
+		return nil, false
+	}
+	shard.ll.MoveToFront(elem)
+	return elem.Value.(*blockCacheEntry).data, true
+}
+
+// put caches data for (segmentID, chunkOffset), evicting the shard's
+// least-recently-used entries until it again fits within shardCapacity.
+func (c *blockCache) put(segmentID SegmentID, chunkOffset int64, data []byte) {
+	if int64(len(data)) > c.shardCapacity {
+		return // would never fit; not worth evicting the rest of the shard for
+	}
+	shard := c.shardFor(segmentID, chunkOffset)
+	key := blockCacheKey{segmentID, chunkOffset}
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if elem, ok := shard.index[key]; ok {
+		shard.usedBytes -= int64(len(elem.Value.(*blockCacheEntry).data))
+		shard.ll.Remove(elem)
+		delete(shard.index, key)
+	}
+
+	entry := &blockCacheEntry{key: key, data: data}
+	elem := shard.ll.PushFront(entry)
+	shard.index[key] = elem
+	shard.usedBytes += int64(len(data))
+
+	for shard.usedBytes > c.shardCapacity {
+		oldest := shard.ll.Back()
+		if oldest == nil {
+			break
+		}
+		shard.ll.Remove(oldest)
+		oldestEntry := oldest.Value.(*blockCacheEntry)
+		delete(shard.index, oldestEntry.key)
+		shard.usedBytes -= int64(len(oldestEntry.data))
+	}
+}
+
+// bytes returns the cache's total memory usage across all shards, for
+// Stats.
+func (c *blockCache) bytes() uint64 {
+	var total int64
+	for i := range c.shards {
+		c.shards[i].mu.Lock()
+		total += c.shards[i].usedBytes
+		c.shards[i].mu.Unlock()
+	}
+	return uint64(total)
+}
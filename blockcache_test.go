@@ -0,0 +1,91 @@
+package wal
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlockCachePutGet(t *testing.T) {
+	c := newBlockCache(1 << 20)
+
+	_, ok := c.get(1, 0)
+	assert.False(t, ok)
+
+	c.put(1, 0, []byte("hello"))
+	data, ok := c.get(1, 0)
+	require.True(t, ok)
+	assert.Equal(t, "hello", string(data))
+
+	// A different segment or offset is a distinct entry.
+	_, ok = c.get(2, 0)
+	assert.False(t, ok)
+	_, ok = c.get(1, blockCacheGranularity)
+	assert.False(t, ok)
+}
+
+func TestBlockCacheEvictsLeastRecentlyUsedWithinShard(t *testing.T) {
+	c := newBlockCache(blockCacheShardCount * 10)
+
+	// Force every entry into the same shard by giving them the same
+	// segmentID and blockNumber, so capacity pressure is deterministic.
+	c.put(1, 0, make([]byte, 6))
+	c.put(1, 1, make([]byte, 6))
+
+	// Adding the second entry should have evicted the first: a shard's
+	// capacity here is 10 bytes, and both entries don't fit together.
+	_, ok := c.get(1, 0)
+	assert.False(t, ok)
+	_, ok = c.get(1, 1)
+	assert.True(t, ok)
+}
+
+func TestBlockCacheBytesTracksUsage(t *testing.T) {
+	c := newBlockCache(1 << 20)
+	assert.EqualValues(t, 0, c.bytes())
+
+	c.put(1, 0, make([]byte, 5))
+	c.put(2, 0, make([]byte, 7))
+	assert.EqualValues(t, 12, c.bytes())
+}
+
+func TestReadUsesBlockCache(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-blockcache-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithBlockCache(1<<20))
+	require.Nil(t, err)
+	defer w.Close()
+
+	pos, err := w.Write([]byte("cached"))
+	require.Nil(t, err)
+
+	data, err := w.Read(pos)
+	require.Nil(t, err)
+	assert.Equal(t, "cached", string(data))
+	assert.Positive(t, w.Stats().BlockCacheBytes)
+
+	// Second read should come back identical, whether served from the
+	// cache or not.
+	data, err = w.Read(pos)
+	require.Nil(t, err)
+	assert.Equal(t, "cached", string(data))
+}
+
+func TestBlockCacheDisabledByDefault(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-blockcache-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("hello"))
+	require.Nil(t, err)
+
+	assert.EqualValues(t, 0, w.Stats().BlockCacheBytes)
+}
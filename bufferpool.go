@@ -0,0 +1,37 @@
+package wal
+
+import "sync"
+
+// BufferPool is the interface a custom write-path scratch-buffer pool
+// must satisfy for WithBufferPool. Get returns a buffer ready to be
+// grown by append (typically zero-length, whatever its capacity); Put
+// returns one obtained from Get once the WAL is done writing it out, for
+// later reuse. Implementations must be safe for concurrent use.
+type BufferPool interface {
+	Get() []byte
+	Put(buf []byte)
+}
+
+// syncBufferPool is the default BufferPool, backed by a sync.Pool whose
+// buffers start at writeBufferSize capacity (see WithWriteBufferSize).
+type syncBufferPool struct {
+	pool sync.Pool
+}
+
+func newSyncBufferPool(size int) *syncBufferPool {
+	return &syncBufferPool{
+		pool: sync.Pool{
+			New: func() any {
+				return make([]byte, 0, size)
+			},
+		},
+	}
+}
+
+func (p *syncBufferPool) Get() []byte {
+	return p.pool.Get().([]byte)[:0]
+}
+
+func (p *syncBufferPool) Put(buf []byte) {
+	p.pool.Put(buf)
+}
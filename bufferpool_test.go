@@ -0,0 +1,67 @@
+package wal
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteBufferSizeDefaultsPoolingToDisabled(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-bufferpool-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"), WithCompression(CompressionSnappy))
+	require.Nil(t, err)
+	defer w.Close()
+
+	assert.Nil(t, w.bufPool, "WriteBufferSize defaults to 0, leaving pooling disabled")
+}
+
+func TestWithWriteBufferSizeBuildsDefaultPool(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-bufferpool-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"), WithCompression(CompressionSnappy), WithWriteBufferSize(4*KB))
+	require.Nil(t, err)
+	defer w.Close()
+
+	require.NotNil(t, w.bufPool)
+	buf := w.bufPool.Get()
+	assert.Equal(t, 0, len(buf))
+	assert.Equal(t, 4*KB, cap(buf))
+}
+
+func TestWithBufferPoolUsesCustomPool(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-bufferpool-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	custom := newSyncBufferPool(8 * KB)
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"), WithCompression(CompressionSnappy), WithBufferPool(custom), WithWriteBufferSize(4*KB))
+	require.Nil(t, err)
+	defer w.Close()
+
+	assert.Same(t, custom, w.bufPool, "an explicit BufferPool takes precedence over WriteBufferSize")
+}
+
+func TestWriteWithPoolingStillReadsBackCorrectly(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-bufferpool-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"), WithCompression(CompressionSnappy), WithWriteBufferSize(4*KB))
+	require.Nil(t, err)
+	defer w.Close()
+
+	want := []byte("pooled buffer write path round-trip")
+	pos, err := w.Write(want)
+	require.Nil(t, err)
+
+	got, err := w.Read(pos)
+	require.Nil(t, err)
+	assert.Equal(t, want, got)
+}
@@ -0,0 +1,151 @@
+package wal
+
+import (
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChainChecksumsRoundTrip(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-chain-checksums-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithChainChecksums(true))
+	require.Nil(t, err)
+
+	var positions []*ChunkPosition
+	for i := 0; i < 20; i++ {
+		pos, err := w.Write([]byte{byte('a' + i)})
+		require.Nil(t, err)
+		positions = append(positions, pos)
+	}
+	require.Nil(t, w.Close())
+
+	w, err = Open(WithDirPath(dir), WithChainChecksums(true))
+	require.Nil(t, err)
+	defer w.Close()
+
+	for i, pos := range positions {
+		data, err := w.Read(pos)
+		require.Nil(t, err)
+		require.Equal(t, []byte{byte('a' + i)}, data)
+	}
+
+	r := w.NewReader()
+	var i int
+	for {
+		data, _, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		require.Nil(t, err)
+		require.Equal(t, []byte{byte('a' + i)}, data)
+		i++
+	}
+	require.Equal(t, 20, i)
+}
+
+func TestChainChecksumsMismatchOnReopen(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-chain-checksums-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithChainChecksums(true))
+	require.Nil(t, err)
+	_, err = w.Write([]byte("hello"))
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+
+	_, err = Open(WithDirPath(dir), WithChainChecksums(false))
+	require.Equal(t, ErrChainChecksumsMismatch, err)
+}
+
+// spliceOutChunk removes the chunk at pos from dir's segment file entirely,
+// shifting every byte after it back -- simulating a chunk having been
+// deleted or reordered out from under the log, which an ordinary
+// per-chunk checksum can't detect since every remaining chunk's own bytes
+// are untouched.
+func spliceOutChunk(t *testing.T, dir string, pos *ChunkPosition) {
+	t.Helper()
+	path := segmentFileName(dir, ".SEG", pos.SegmentId, nil)
+	data, err := os.ReadFile(path)
+	require.Nil(t, err)
+
+	start := segmentHeaderSize + pos.ChunkOffset
+	end := start + int64(chunkHeaderSize) + int64(pos.ChunkSize)
+	spliced := append(data[:start:start], data[end:]...)
+
+	require.Nil(t, os.WriteFile(path, spliced, 0o644))
+}
+
+// TestChainChecksumsCatchSpliceSequentialOnly confirms the central
+// guarantee behind Options.ChainChecksums: a chunk spliced out of the
+// middle of a segment -- leaving every surviving chunk's own framing and
+// checksum internally consistent -- is caught by a sequential scan
+// (Reader.Next, Verify) but not by a random-access Read, since verifying
+// a chained chunk in isolation would need the exact predecessor checksum
+// in effect when it was written, which only a sequential scan from a
+// known starting point has.
+func TestChainChecksumsCatchSpliceSequentialOnly(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-chain-checksums-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithChainChecksums(true))
+	require.Nil(t, err)
+	_, err = w.Write([]byte("aaaa"))
+	require.Nil(t, err)
+	posB, err := w.Write([]byte("bbbb"))
+	require.Nil(t, err)
+	posC, err := w.Write([]byte("cccc"))
+	require.Nil(t, err)
+	_, err = w.Write([]byte("dddd"))
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+
+	spliceOutChunk(t, dir, posB)
+
+	// C now sits where B used to start; a random-access Read at that
+	// offset skips the (unverifiable in isolation) chain check and
+	// returns C's bytes as-is.
+	movedC := &ChunkPosition{SegmentId: posC.SegmentId, ChunkOffset: posB.ChunkOffset, ChunkSize: posC.ChunkSize}
+	w, err = Open(WithDirPath(dir), WithChainChecksums(true))
+	require.Nil(t, err)
+	data, err := w.Read(movedC)
+	require.Nil(t, err)
+	require.Equal(t, []byte("cccc"), data)
+	require.Nil(t, w.Close())
+
+	// A sequential scan from the segment's own start, however, catches it:
+	// C's recorded checksum was chained onto B's, not A's.
+	_, err = Verify(dir, WithChainChecksums(true))
+	var corrupted *ErrCorrupted
+	require.ErrorAs(t, err, &corrupted)
+	require.ErrorIs(t, corrupted.Reason, ErrInvalidCRC)
+}
+
+func TestChainChecksumsRepairSkipCorruptRefusesMidSegment(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-chain-checksums-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithChainChecksums(true))
+	require.Nil(t, err)
+	posB, err := w.Write([]byte("bbbb"))
+	require.Nil(t, err)
+	_, err = w.Write([]byte("cccc"))
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+
+	f, err := os.OpenFile(segmentFileName(dir, ".SEG", posB.SegmentId, nil), os.O_RDWR, 0o644)
+	require.Nil(t, err)
+	_, err = f.WriteAt([]byte{0xff}, segmentHeaderSize+posB.ChunkOffset+4)
+	require.Nil(t, err)
+	require.Nil(t, f.Close())
+
+	_, err = Repair(dir, WithChainChecksums(true), WithRecoveryMode(RecoverSkipCorrupt))
+	require.ErrorIs(t, err, ErrCorruptMidSegment)
+}
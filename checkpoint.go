@@ -0,0 +1,395 @@
+package wal
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// checkpointPrefix names the directory entries written by Checkpoint and
+// discovered by NewCheckpointIterator: "checkpoint.NNNNNNNNN". Unlike
+// segment files, a checkpoint file has no segmentHeaderSize-byte header:
+// its chunks start at offset 0.
+const checkpointPrefix = "checkpoint."
+
+// CheckpointResult reports the outcome of a single WAL.Checkpoint call.
+type CheckpointResult struct {
+	// SegmentsCompacted is the number of sealed segments folded into the
+	// new checkpoint.
+	SegmentsCompacted int
+
+	// RecordsKept is the number of records the keep callback chose to
+	// retain, possibly transformed.
+	RecordsKept int
+
+	// RecordsDropped is the number of records the keep callback chose to
+	// drop.
+	RecordsDropped int
+
+	// BytesWritten is the size, in bytes, of the new checkpoint.
+	BytesWritten int64
+}
+
+// Checkpoint compacts every sealed segment up to, but not including, the
+// current active segment into a new checkpoint.NNNNNNNNN file, folding in
+// any existing checkpoint first so compaction is cumulative. For every
+// record it reads, Checkpoint calls keep with the record's raw bytes; keep
+// returns the bytes to retain in the new checkpoint, optionally
+// transformed, and whether to drop the record entirely.
+//
+// Checkpoint installs the new checkpoint atomically via rename and then
+// removes the sealed segments and the previous checkpoint it replaced.
+// Once it returns successfully, the caller can discard any ChunkPosition
+// values that referenced the compacted segments.
+//
+// NewCheckpointIterator always starts from the highest-numbered
+// checkpoint Checkpoint has written, so callers rebuilding in-memory state
+// after a restart never have to read compacted-away segments directly.
+//
+// Checkpoint only holds w's lock to snapshot the sealed segments up front
+// and to install the result at the end; the read-and-rewrite work in
+// between, which can take a while for a large log, runs unlocked so
+// Write, Read, and Sync are not blocked for its duration. Sealed segments
+// are never appended to again, so reading them unlocked is safe; a second
+// Checkpoint call is serialized against this one by checkpointMu rather
+// than w's lock.
+//
+// Checkpoint is also tombstone-aware: before it reads a single record for
+// keep, it scans the same sealed segments for markers written by Delete,
+// then drops both every tombstone and the record each one targets as it
+// compacts, without ever showing either to keep. See Delete for why a
+// tombstone only resolves against a target still in a sealed segment.
+func (w *WAL) Checkpoint(ctx context.Context, keep func(record []byte) (keepBytes []byte, drop bool)) (*CheckpointResult, error) {
+	if w.options.ReadOnly {
+		return nil, ErrReadOnly
+	}
+
+	w.checkpointMu.Lock()
+	defer w.checkpointMu.Unlock()
+
+	w.mu.RLock()
+	sealed := w.sealedSegmentsLocked()
+	w.mu.RUnlock()
+	if len(sealed) == 0 {
+		return &CheckpointResult{}, nil
+	}
+
+	// Resolve the previous checkpoint, if any, before creating the new
+	// one's .tmp file: that .tmp file's name would otherwise itself match
+	// checkpointPrefix and be mistaken for an existing checkpoint.
+	prevPath, hasPrev, err := latestCheckpointPath(w.options.DirPath)
+	if err != nil {
+		return nil, err
+	}
+
+	nextID, _, err := latestCheckpointID(w.options.DirPath)
+	if err != nil {
+		return nil, err
+	}
+	nextID++
+
+	tmpPath := filepath.Join(w.options.DirPath, fmt.Sprintf("%s%09d.tmp", checkpointPrefix, nextID))
+	finalPath := filepath.Join(w.options.DirPath, fmt.Sprintf("%s%09d", checkpointPrefix, nextID))
+
+	out, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &CheckpointResult{SegmentsCompacted: len(sealed)}
+
+	// Resolved against these same sealed segments, not the previous
+	// checkpoint: see Delete and compactInto.
+	deletedTargets, err := tombstoneTargets(sealed)
+	if err != nil {
+		out.Close()
+		os.Remove(tmpPath)
+		return nil, err
+	}
+
+	if hasPrev {
+		prevInfo, err := os.Stat(prevPath)
+		if err != nil {
+			out.Close()
+			os.Remove(tmpPath)
+			return nil, err
+		}
+		// A checkpoint file has no header of its own to record the
+		// algorithm it was written with (see checkpointPrefix), so it's
+		// always read back with the WAL's current Options.Checksum -- the
+		// same constraint Options.Compression documents for the same
+		// reason. It also has no segment to key deletedTargets against
+		// (see Delete), so nil is passed instead of an impossible match.
+		if err := compactInto(ctx, out, prevPath, 0, prevInfo.Size(), keep, result, w.options.Checksum, w.options.Checksum, 0, nil); err != nil {
+			out.Close()
+			os.Remove(tmpPath)
+			return nil, err
+		}
+	}
+	for _, seg := range sealed {
+		if err := compactInto(ctx, out, seg.path, segmentHeaderSize, segmentHeaderSize+seg.offset, keep, result, seg.checksum, w.options.Checksum, seg.id, deletedTargets); err != nil {
+			out.Close()
+			os.Remove(tmpPath)
+			return nil, err
+		}
+	}
+
+	info, err := out.Stat()
+	if err != nil {
+		out.Close()
+		os.Remove(tmpPath)
+		return nil, err
+	}
+	result.BytesWritten = info.Size()
+
+	if err := out.Sync(); err != nil {
+		out.Close()
+		os.Remove(tmpPath)
+		return nil, err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmpPath)
+		return nil, err
+	}
+
+	// The rename has to happen under the same lock as the sealed-segment
+	// cleanup below: otherwise a concurrent NewCheckpointIterator could see
+	// the new checkpoint (which already contains the compacted records) and
+	// the still-present sealed segments at once, replaying those records
+	// twice. Everything up to here only read already-sealed segments and
+	// wrote a private .tmp file, so it's safe to have done unlocked.
+	w.mu.Lock()
+	if err := os.Rename(tmpPath, finalPath); err != nil {
+		w.mu.Unlock()
+		os.Remove(tmpPath)
+		return nil, err
+	}
+	if hasPrev {
+		os.Remove(prevPath)
+	}
+	for _, seg := range sealed {
+		// retire defers the actual Close/Remove until any WAL.Read or
+		// Reader.Next already in flight against seg has released it, so a
+		// reader that resolved seg just before this lock was taken can
+		// still finish its I/O instead of hitting a closed fd.
+		seg.retire(w.recyclePipeline())
+		delete(w.olderSegments, seg.id)
+	}
+	w.options.MetricsCollector.SetActiveSegments(len(w.olderSegments) + 1)
+	w.enforceDiskWatermarksLocked()
+	w.publishSegmentsLocked()
+	w.mu.Unlock()
+
+	return result, nil
+}
+
+// compactInto reads path's chunks from the byte range [startOffset,
+// endOffset), calls keep on each record, and appends the kept ones to
+// out, tallying the result as it goes. endOffset bounds the read to
+// path's logical content: a sealed segment's footer (see segment.seal)
+// follows right after it and must not be misread as more chunk data.
+// readChecksum is the algorithm path's chunks were written with; out's
+// new chunks are always written with w.options.Checksum (the new
+// checkpoint being produced has no mixed-algorithm content).
+//
+// Every record is checked against Delete's tombstone markers before it
+// ever reaches keep: a tombstone itself is always dropped, and so is any
+// record whose position is in deletedTargets, which segID and each
+// record's running offset within path are compared against. path has no
+// meaningful segment identity when it's the previous checkpoint rather
+// than a sealed segment (see Checkpoint's doc comment), so that call
+// passes segID 0 and a nil deletedTargets, under which every record
+// simply falls through to keep as before, same as a record whose
+// position isn't in a non-nil deletedTargets either.
+func compactInto(ctx context.Context, out *os.File, path string, startOffset, endOffset int64, keep func([]byte) ([]byte, bool), result *CheckpointResult, readChecksum, outChecksum Checksum, segID SegmentID, deletedTargets map[chunkKey]bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := io.NewSectionReader(f, startOffset, endOffset-startOffset)
+
+	var offset int64
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		record, err := readChunk(r, readChecksum)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		pos := chunkKey{segID, offset}
+		offset += int64(chunkHeaderSize) + int64(len(record))
+
+		if _, ok := decodeTombstoneMarker(record); ok {
+			result.RecordsDropped++
+			continue
+		}
+		if deletedTargets[pos] {
+			result.RecordsDropped++
+			continue
+		}
+
+		keepBytes, drop := keep(record)
+		if drop {
+			result.RecordsDropped++
+			continue
+		}
+		result.RecordsKept++
+		if _, err := appendChunk(out, keepBytes, outChecksum, false, 0); err != nil {
+			return err
+		}
+	}
+}
+
+// sealedSegmentsLocked returns every segment the WAL knows about except
+// the active one, in ascending order. w.mu must be held.
+func (w *WAL) sealedSegmentsLocked() []*segment {
+	segs := w.orderedSegmentsLocked()
+	if len(segs) <= 1 {
+		return nil
+	}
+	return segs[:len(segs)-1]
+}
+
+// latestCheckpointID returns the highest checkpoint ID found in dir, and
+// whether any checkpoint exists at all.
+func latestCheckpointID(dir string) (int, bool, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, false, err
+	}
+	best := -1
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasPrefix(e.Name(), checkpointPrefix) {
+			continue
+		}
+		var id int
+		if _, err := fmt.Sscanf(e.Name(), checkpointPrefix+"%09d", &id); err != nil {
+			continue
+		}
+		if id > best {
+			best = id
+		}
+	}
+	if best == -1 {
+		return 0, false, nil
+	}
+	return best, true, nil
+}
+
+// latestCheckpointPath returns the path of the highest-numbered checkpoint
+// in dir, and whether one exists at all.
+func latestCheckpointPath(dir string) (string, bool, error) {
+	id, ok, err := latestCheckpointID(dir)
+	if err != nil || !ok {
+		return "", ok, err
+	}
+	return filepath.Join(dir, fmt.Sprintf("%s%09d", checkpointPrefix, id)), true, nil
+}
+
+// CheckpointIterator stitches a WAL's latest checkpoint together with the
+// segments written after it, so callers can replay the full logical log
+// without caring whether part of it has already been compacted away.
+type CheckpointIterator struct {
+	paths       []string
+	headers     []int64    // bytes to skip at the start of the corresponding path
+	ends        []int64    // byte offset, exclusive, to stop reading the corresponding path at
+	checksums   []Checksum // checksum algorithm the corresponding path's chunks were written with
+	idx         int
+	f           *os.File
+	r           *io.SectionReader // bounded to [headers[idx-1], ends[idx-1]) of f
+	curChecksum Checksum          // checksums[idx-1], the algorithm for the currently-open f
+}
+
+// NewCheckpointIterator returns an iterator over w's latest checkpoint (if
+// any) followed by every segment, including the active one, in order.
+func (w *WAL) NewCheckpointIterator() (*CheckpointIterator, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	it := &CheckpointIterator{}
+	if path, ok, err := latestCheckpointPath(w.options.DirPath); err != nil {
+		return nil, err
+	} else if ok {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		it.paths = append(it.paths, path)
+		it.headers = append(it.headers, 0)
+		it.ends = append(it.ends, info.Size())
+		// A checkpoint file has no header recording its own checksum
+		// algorithm (see checkpointPrefix), so it's read back with the
+		// WAL's current Options.Checksum, the same constraint
+		// Options.Compression documents.
+		it.checksums = append(it.checksums, w.options.Checksum)
+	}
+
+	for _, seg := range w.orderedSegmentsLocked() {
+		it.paths = append(it.paths, seg.path)
+		it.headers = append(it.headers, segmentHeaderSize)
+		it.ends = append(it.ends, segmentHeaderSize+seg.offset)
+		it.checksums = append(it.checksums, seg.checksum)
+	}
+
+	return it, nil
+}
+
+// Next returns the next record in the stitched checkpoint-then-segments
+// stream, or io.EOF once it is exhausted.
+func (it *CheckpointIterator) Next() ([]byte, error) {
+	for {
+		if it.f == nil {
+			if it.idx >= len(it.paths) {
+				return nil, io.EOF
+			}
+			f, err := os.Open(it.paths[it.idx])
+			if err != nil {
+				return nil, err
+			}
+			it.f = f
+			// Bounded to the path's logical content: a sealed segment's
+			// footer (see segment.seal) follows right after it and must
+			// not be misread as more chunk data.
+			it.r = io.NewSectionReader(f, it.headers[it.idx], it.ends[it.idx]-it.headers[it.idx])
+			it.curChecksum = it.checksums[it.idx]
+			it.idx++
+		}
+
+		record, err := readChunk(it.r, it.curChecksum)
+		if err == io.EOF {
+			it.f.Close()
+			it.f = nil
+			it.r = nil
+			continue
+		}
+		if err != nil {
+			it.f.Close()
+			it.f = nil
+			it.r = nil
+			return nil, err
+		}
+		return record, nil
+	}
+}
+
+// Close releases the file handle the iterator currently has open, if any.
+func (it *CheckpointIterator) Close() error {
+	if it.f == nil {
+		return nil
+	}
+	err := it.f.Close()
+	it.f = nil
+	return err
+}
@@ -0,0 +1,114 @@
+package wal
+
+import "io"
+
+// checkpointMagic prefixes a WriteCheckpoint marker's payload so
+// LastCheckpoint can pick it back out of the log when scanning: it
+// assumes, the same way decodeBatchFrame does for the Batch API, that
+// nothing else written to this WAL happens to start with these exact
+// bytes. Picking an 8-byte, rather than single-byte, discriminator keeps
+// that collision chance negligible even mixed in with ordinary records.
+const checkpointMagic = "WAL:CKPT"
+
+// encodeCheckpointMarker prepends checkpointMagic to meta, so it can be
+// told apart from an ordinary record by decodeCheckpointMarker.
+func encodeCheckpointMarker(meta []byte) []byte {
+	raw := make([]byte, len(checkpointMagic)+len(meta))
+	copy(raw, checkpointMagic)
+	copy(raw[len(checkpointMagic):], meta)
+	return raw
+}
+
+// decodeCheckpointMarker reports whether raw is a checkpoint marker
+// encodeCheckpointMarker produced, and if so, its meta.
+func decodeCheckpointMarker(raw []byte) (meta []byte, ok bool) {
+	if len(raw) < len(checkpointMagic) || string(raw[:len(checkpointMagic)]) != checkpointMagic {
+		return nil, false
+	}
+	return raw[len(checkpointMagic):], true
+}
+
+// WriteCheckpoint appends a checkpoint marker carrying meta -- typically a
+// caller's own serialized description of how far it has durably applied
+// the log, e.g. a state machine's last-applied index -- and returns its
+// position. LastCheckpoint finds the newest marker again, including after
+// a restart, and TruncateToCheckpoint reclaims every segment it fully
+// covers, so callers don't each have to reinvent their own checkpoint
+// bookkeeping on top of the log.
+//
+// This is unrelated to the compaction-based Checkpoint: that folds old
+// records into a new, smaller checkpoint.NNNNNNNNN file and is meant to
+// shrink the log itself. WriteCheckpoint just drops a marker inline for a
+// caller to find its way back to later, the way a periodic snapshot
+// remembers where it last ran without changing anything else in the log.
+func (w *WAL) WriteCheckpoint(meta []byte) (*ChunkPosition, error) {
+	pos, err := w.Write(encodeCheckpointMarker(meta))
+	if err != nil {
+		return nil, err
+	}
+	w.mu.Lock()
+	w.lastCheckpointPos = pos
+	w.lastCheckpointMeta = meta
+	w.mu.Unlock()
+	return pos, nil
+}
+
+// LastCheckpoint returns the position and meta of the newest marker
+// WriteCheckpoint has written to this WAL. The first call after Open
+// scans the whole log for one, to recover a marker a previous process
+// wrote; every call after that is served from memory. ok is false if
+// WriteCheckpoint has never been called against this WAL.
+//
+// LastCheckpoint assumes every chunk in the WAL was written through
+// Write, WriteAll, or WriteCheckpoint itself -- the same assumption
+// InDoubtBatches makes for the Batch API -- since it reads raw chunk
+// bytes looking for checkpointMagic rather than going through
+// WriteWithFlags or WriteWithTTL's own framing.
+func (w *WAL) LastCheckpoint() (pos *ChunkPosition, meta []byte, ok bool, err error) {
+	w.mu.Lock()
+	scanned := w.checkpointScanned
+	pos, meta = w.lastCheckpointPos, w.lastCheckpointMeta
+	w.mu.Unlock()
+	if scanned {
+		return pos, meta, pos != nil, nil
+	}
+
+	r := w.NewReader()
+	for {
+		data, p, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, false, err
+		}
+		if m, ok := decodeCheckpointMarker(data); ok {
+			pos, meta = p, m
+		}
+	}
+
+	w.mu.Lock()
+	if !w.checkpointScanned {
+		w.lastCheckpointPos, w.lastCheckpointMeta = pos, meta
+		w.checkpointScanned = true
+	} else {
+		// Another concurrent LastCheckpoint call's scan (or a
+		// WriteCheckpoint since) already won; defer to what it left
+		// behind instead of overwriting it with this scan's own result.
+		pos, meta = w.lastCheckpointPos, w.lastCheckpointMeta
+	}
+	w.mu.Unlock()
+	return pos, meta, pos != nil, nil
+}
+
+// TruncateToCheckpoint calls TruncateBefore with LastCheckpoint's
+// position, reclaiming every segment fully covered by it. found is false,
+// with no error and nothing truncated, if WriteCheckpoint has never been
+// called against this WAL.
+func (w *WAL) TruncateToCheckpoint() (found bool, err error) {
+	pos, _, ok, err := w.LastCheckpoint()
+	if err != nil || !ok {
+		return false, err
+	}
+	return true, w.TruncateBefore(pos)
+}
@@ -0,0 +1,133 @@
+package wal
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLastCheckpointReturnsNotFoundBeforeAnyWriteCheckpoint(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-checkpoint-record-missing-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("ordinary record"))
+	require.Nil(t, err)
+
+	pos, meta, ok, err := w.LastCheckpoint()
+	require.Nil(t, err)
+	require.False(t, ok)
+	require.Nil(t, pos)
+	require.Nil(t, meta)
+}
+
+func TestLastCheckpointReturnsTheNewestMarker(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-checkpoint-record-newest-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("before"))
+	require.Nil(t, err)
+	_, err = w.WriteCheckpoint([]byte("first checkpoint"))
+	require.Nil(t, err)
+	_, err = w.Write([]byte("between"))
+	require.Nil(t, err)
+	wantPos, err := w.WriteCheckpoint([]byte("second checkpoint"))
+	require.Nil(t, err)
+	_, err = w.Write([]byte("after"))
+	require.Nil(t, err)
+
+	pos, meta, ok, err := w.LastCheckpoint()
+	require.Nil(t, err)
+	require.True(t, ok)
+	require.Equal(t, wantPos, pos)
+	require.Equal(t, "second checkpoint", string(meta))
+}
+
+func TestLastCheckpointRecoversAfterReopen(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-checkpoint-record-reopen-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	_, err = w.Write([]byte("before"))
+	require.Nil(t, err)
+	wantPos, err := w.WriteCheckpoint([]byte("durable checkpoint"))
+	require.Nil(t, err)
+	_, err = w.Write([]byte("after"))
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+
+	w2, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w2.Close()
+
+	pos, meta, ok, err := w2.LastCheckpoint()
+	require.Nil(t, err)
+	require.True(t, ok)
+	require.Equal(t, wantPos, pos)
+	require.Equal(t, "durable checkpoint", string(meta))
+}
+
+func TestTruncateToCheckpointReclaimsSegmentsBeforeTheMarker(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-truncate-to-checkpoint-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(32))
+	require.Nil(t, err)
+	defer w.Close()
+
+	for i := 0; i < 3; i++ {
+		_, err := w.Write([]byte("0123456789"))
+		require.Nil(t, err)
+	}
+	checkpointPos, err := w.WriteCheckpoint([]byte("checkpoint"))
+	require.Nil(t, err)
+	for i := 0; i < 3; i++ {
+		_, err := w.Write([]byte("0123456789"))
+		require.Nil(t, err)
+	}
+	require.Greater(t, len(w.olderSegments), 0)
+
+	var olderThanCheckpoint []SegmentID
+	for id := range w.olderSegments {
+		if id < checkpointPos.SegmentId {
+			olderThanCheckpoint = append(olderThanCheckpoint, id)
+		}
+	}
+	require.NotEmpty(t, olderThanCheckpoint)
+
+	found, err := w.TruncateToCheckpoint()
+	require.Nil(t, err)
+	require.True(t, found)
+
+	for _, id := range olderThanCheckpoint {
+		require.Nil(t, w.segmentByID(id))
+	}
+	require.NotNil(t, w.segmentByID(checkpointPos.SegmentId))
+}
+
+func TestTruncateToCheckpointReportsNotFoundBeforeAnyWriteCheckpoint(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-truncate-to-checkpoint-missing-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	found, err := w.TruncateToCheckpoint()
+	require.Nil(t, err)
+	require.False(t, found)
+}
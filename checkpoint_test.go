@@ -0,0 +1,165 @@
+package wal
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckpointCompactsSealedSegments(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-checkpoint-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"), WithSegmentSize(64))
+	require.Nil(t, err)
+	defer w.Close()
+
+	for i := 0; i < 20; i++ {
+		_, err := w.Write(bytes.Repeat([]byte{byte('a' + i)}, 8))
+		require.Nil(t, err)
+	}
+
+	dropEvens := func(record []byte) ([]byte, bool) {
+		return record, record[0]%2 == 1
+	}
+	result, err := w.Checkpoint(context.Background(), dropEvens)
+	require.Nil(t, err)
+	assert.Positive(t, result.SegmentsCompacted)
+	assert.Positive(t, result.RecordsKept+result.RecordsDropped)
+
+	it, err := w.NewCheckpointIterator()
+	require.Nil(t, err)
+	defer it.Close()
+
+	var records [][]byte
+	for {
+		record, err := it.Next()
+		if err != nil {
+			break
+		}
+		records = append(records, record)
+	}
+	assert.NotEmpty(t, records)
+}
+
+func TestCheckpointNoSealedSegments(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-checkpoint-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"))
+	require.Nil(t, err)
+	defer w.Close()
+
+	result, err := w.Checkpoint(context.Background(), func(record []byte) ([]byte, bool) {
+		return record, false
+	})
+	require.Nil(t, err)
+	assert.Equal(t, &CheckpointResult{}, result)
+}
+
+func TestCheckpointDoesNotBlockConcurrentWrites(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-checkpoint-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"), WithSegmentSize(64))
+	require.Nil(t, err)
+	defer w.Close()
+
+	for i := 0; i < 20; i++ {
+		_, err := w.Write(bytes.Repeat([]byte{byte('a' + i)}, 8))
+		require.Nil(t, err)
+	}
+
+	// keep blocks until released, simulating a slow compaction; a Write
+	// issued while Checkpoint is still running it must not be blocked.
+	release := make(chan struct{})
+	slowKeep := func(record []byte) ([]byte, bool) {
+		<-release
+		return record, false
+	}
+
+	checkpointDone := make(chan error, 1)
+	go func() {
+		_, err := w.Checkpoint(context.Background(), slowKeep)
+		checkpointDone <- err
+	}()
+
+	writeDone := make(chan error, 1)
+	go func() {
+		_, err := w.Write([]byte("during checkpoint"))
+		writeDone <- err
+	}()
+
+	select {
+	case err := <-writeDone:
+		require.Nil(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Write was blocked by a concurrent Checkpoint")
+	}
+
+	close(release)
+	require.Nil(t, <-checkpointDone)
+}
+
+func TestCheckpointDoesNotRaceConcurrentReads(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-checkpoint-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"), WithSegmentSize(64))
+	require.Nil(t, err)
+	defer w.Close()
+
+	var positions []*ChunkPosition
+	for i := 0; i < 20; i++ {
+		pos, err := w.Write(bytes.Repeat([]byte{byte('a' + i)}, 8))
+		require.Nil(t, err)
+		positions = append(positions, pos)
+	}
+
+	// slowKeep blocks mid-compaction, giving the reader goroutine below a
+	// wide window to race Read against the segment cleanup that follows.
+	release := make(chan struct{})
+	slowKeep := func(record []byte) ([]byte, bool) {
+		<-release
+		return record, false
+	}
+
+	checkpointDone := make(chan error, 1)
+	go func() {
+		_, err := w.Checkpoint(context.Background(), slowKeep)
+		checkpointDone <- err
+	}()
+
+	// Read positions[0] in a tight loop while Checkpoint is compacting its
+	// segment away. Once Checkpoint finishes, the segment is legitimately
+	// gone and "segment not found" is the expected error; anything else
+	// (e.g. a closed-file I/O error) means Read raced the segment's
+	// retirement instead of being safely excluded from it.
+	readErrs := make(chan error, 1)
+	go func() {
+		for {
+			if _, err := w.Read(positions[0]); err != nil {
+				if errors.Is(err, ErrSegmentNotFound) {
+					readErrs <- nil
+				} else {
+					readErrs <- err
+				}
+				return
+			}
+		}
+	}()
+
+	close(release)
+	require.Nil(t, <-checkpointDone)
+	assert.Nil(t, <-readErrs)
+}
@@ -0,0 +1,96 @@
+package wal
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+// Checksum selects the algorithm used to detect a corrupted chunk; see
+// Options.Checksum. It is recorded in a segment's header at checksumByte
+// when the segment is created, so a segment is always read back with the
+// algorithm it was written with regardless of what a later Open is
+// configured with -- see openSegmentFile's checksum argument.
+type Checksum byte
+
+const (
+	// ChecksumCRC32 checksums chunk payloads with CRC-32 (IEEE), the
+	// polynomial this package has always used. It is the default.
+	ChecksumCRC32 Checksum = iota
+
+	// ChecksumCRC32C checksums chunk payloads with CRC-32C (Castagnoli).
+	// Most ARM cores and recent x86 chips compute it with a dedicated
+	// instruction, making it noticeably faster than CRC-32's table-driven
+	// software implementation on that hardware.
+	ChecksumCRC32C
+
+	// ChecksumXXH64 checksums chunk payloads with xxHash64, trading some
+	// error-detection strength for raw speed on hardware without a CRC32C
+	// instruction. Its 64-bit output is truncated to 32 bits to fit the
+	// chunk header's checksum field, the same width every Checksum value
+	// uses, so choosing it never changes chunk framing.
+	ChecksumXXH64
+
+	// ChecksumNone skips checksumming: every chunk header's checksum field
+	// is written as zero and never verified on read. Only use this when
+	// corruption is already caught some other way (e.g. a filesystem with
+	// its own checksumming), since a torn or bit-flipped chunk otherwise
+	// goes undetected.
+	ChecksumNone
+)
+
+// castagnoliTable is computed once and reused by every ChecksumCRC32C call,
+// the same way crc32.ChecksumIEEE reuses crc32.IEEETable internally.
+var castagnoliTable = crc32.MakeTable(crc32.Castagnoli)
+
+// checksumPayload computes payload's checksum under algo, truncating to 32
+// bits if algo's native width is wider.
+func checksumPayload(algo Checksum, payload []byte) uint32 {
+	switch algo {
+	case ChecksumCRC32C:
+		return crc32.Checksum(payload, castagnoliTable)
+	case ChecksumXXH64:
+		return uint32(xxhash.Sum64(payload))
+	case ChecksumNone:
+		return 0
+	default:
+		return crc32.ChecksumIEEE(payload)
+	}
+}
+
+// verifyChecksum reports whether want matches payload's checksum under
+// algo. ChecksumNone always reports true, since it never had a real
+// checksum to compare against.
+func verifyChecksum(algo Checksum, payload []byte, want uint32) bool {
+	if algo == ChecksumNone {
+		return true
+	}
+	return checksumPayload(algo, payload) == want
+}
+
+// chainedChecksumPayload is checksumPayload, but folds prev -- the checksum
+// recorded on the chunk written immediately before this one, or 0 for a
+// segment's first chunk -- into the bytes being checksummed, for
+// Options.ChainChecksums. Tampering with an earlier chunk therefore changes
+// every checksum chained after it, not just the checksum on the chunk that
+// was actually touched.
+func chainedChecksumPayload(algo Checksum, prev uint32, payload []byte) uint32 {
+	if algo == ChecksumNone {
+		return 0
+	}
+	buf := make([]byte, 4+len(payload))
+	binary.BigEndian.PutUint32(buf[:4], prev)
+	copy(buf[4:], payload)
+	return checksumPayload(algo, buf)
+}
+
+// verifyChainedChecksum is verifyChecksum for a chunk written under
+// Options.ChainChecksums: it reports whether want matches payload's
+// checksum chained onto prev under algo.
+func verifyChainedChecksum(algo Checksum, prev uint32, payload []byte, want uint32) bool {
+	if algo == ChecksumNone {
+		return true
+	}
+	return chainedChecksumPayload(algo, prev, payload) == want
+}
@@ -0,0 +1,76 @@
+package wal
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChecksumAlgorithmsRoundTrip(t *testing.T) {
+	for _, algo := range []Checksum{ChecksumCRC32, ChecksumCRC32C, ChecksumXXH64, ChecksumNone} {
+		dir, err := os.MkdirTemp("", "wal-checksum-test")
+		require.Nil(t, err)
+
+		w, err := Open(WithDirPath(dir), WithChecksum(algo))
+		require.Nil(t, err)
+
+		var positions []*ChunkPosition
+		for i := 0; i < 20; i++ {
+			pos, err := w.Write([]byte{byte('a' + i)})
+			require.Nil(t, err)
+			positions = append(positions, pos)
+		}
+		require.Nil(t, w.Close())
+
+		w, err = Open(WithDirPath(dir), WithChecksum(algo))
+		require.Nil(t, err)
+		for i, pos := range positions {
+			data, err := w.Read(pos)
+			require.Nil(t, err)
+			require.Equal(t, []byte{byte('a' + i)}, data)
+		}
+		require.Nil(t, w.Close())
+
+		os.RemoveAll(dir)
+	}
+}
+
+func TestChecksumMismatchOnReopen(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-checksum-mismatch-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithChecksum(ChecksumCRC32C))
+	require.Nil(t, err)
+	_, err = w.Write([]byte("hello"))
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+
+	_, err = Open(WithDirPath(dir), WithChecksum(ChecksumXXH64))
+	require.Equal(t, ErrChecksumMismatch, err)
+}
+
+func TestChecksumNoneDoesNotDetectCorruption(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-checksum-none-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithChecksum(ChecksumNone))
+	require.Nil(t, err)
+	pos, err := w.Write([]byte("hello"))
+	require.Nil(t, err)
+	_, err = w.Sync()
+	require.Nil(t, err)
+
+	f, err := os.OpenFile(w.activeSegment.path, os.O_RDWR, 0o644)
+	require.Nil(t, err)
+	_, err = f.WriteAt([]byte("X"), segmentHeaderSize+int64(chunkHeaderSize))
+	require.Nil(t, err)
+	require.Nil(t, f.Close())
+
+	data, err := w.Read(pos)
+	require.Nil(t, err)
+	require.Equal(t, []byte("Xello"), data)
+	require.Nil(t, w.Close())
+}
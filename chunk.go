@@ -0,0 +1,74 @@
+package wal
+
+import "encoding/binary"
+
+// EncodeChunk frames payload exactly the way appendChunk writes one to a
+// segment file: a 4-byte big-endian length, a 4-byte big-endian checksum,
+// then payload itself. It's exposed as a pure function over byte slices so
+// external tooling -- forensic scripts, fuzzers, other languages binding
+// via cgo -- can build chunks without opening a WAL.
+//
+// It always frames an unchained checksum: a segment written with
+// Options.ChainChecksums needs the checksum chained into each chunk ahead
+// of it, which this single-chunk function has no way to be handed.
+func EncodeChunk(payload []byte, checksum Checksum) []byte {
+	buf := make([]byte, chunkHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(buf[:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(buf[4:chunkHeaderSize], checksumPayload(checksum, payload))
+	copy(buf[chunkHeaderSize:], payload)
+	return buf
+}
+
+// DecodeChunks parses block as a sequence of back-to-back chunks framed by
+// EncodeChunk -- the chunk-data portion of a segment file, with its
+// segmentHeaderSize-byte header and any footer already stripped -- and
+// returns every payload it confirmed, oldest first. It's the counterpart
+// to EncodeChunk: a pure function over byte slices so external tooling can
+// parse a segment file's chunks without opening a WAL against its
+// directory.
+//
+// It stops, without error, at the first header that doesn't fully fit in
+// what's left of block, or whose declared length reaches past the end of
+// block: either one means block ends with a torn tail write or
+// pre-allocated padding, the same ambiguity scanToLogicalEnd resolves the
+// same way when a WAL opens its active segment. A chunk that does fit but
+// fails its checksum is reported as *ErrCorrupted instead, alongside every
+// payload decoded before it; its SegmentID is left zero since block carries
+// no segment identity of its own.
+//
+// Like EncodeChunk, it always checks an unchained checksum: block from a
+// segment written with Options.ChainChecksums reports every chunk after
+// the first as corrupt, since each one's checksum only validates against
+// the checksum chained in from the chunk before it, not against its own
+// payload alone.
+func DecodeChunks(block []byte, checksum Checksum) ([][]byte, error) {
+	var chunks [][]byte
+	var offset int64
+	for {
+		if offset+int64(chunkHeaderSize) > int64(len(block)) {
+			return chunks, nil
+		}
+		header := block[offset : offset+int64(chunkHeaderSize)]
+		length := int64(binary.BigEndian.Uint32(header[:4]))
+		if length == 0 {
+			return chunks, nil
+		}
+
+		payloadStart := offset + int64(chunkHeaderSize)
+		payloadEnd := payloadStart + length
+		if payloadEnd > int64(len(block)) {
+			return chunks, nil
+		}
+
+		wantChecksum := binary.BigEndian.Uint32(header[4:chunkHeaderSize])
+		payload := block[payloadStart:payloadEnd]
+		if !verifyChecksum(checksum, payload, wantChecksum) {
+			return chunks, &ErrCorrupted{ChunkOffset: offset, Reason: ErrInvalidCRC}
+		}
+
+		out := make([]byte, length)
+		copy(out, payload)
+		chunks = append(chunks, out)
+		offset = payloadEnd
+	}
+}
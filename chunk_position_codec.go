@@ -0,0 +1,49 @@
+package wal
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// MarshalText renders pos as "segmentId:chunkOffset:chunkSize:sequence", so
+// it can be stored in config files, HTTP APIs, and manifests without a
+// bespoke codec.
+func (pos *ChunkPosition) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("%d:%d:%d:%d", pos.SegmentId, pos.ChunkOffset, pos.ChunkSize, pos.Sequence)), nil
+}
+
+// UnmarshalText parses the format produced by MarshalText.
+func (pos *ChunkPosition) UnmarshalText(text []byte) error {
+	var segmentId SegmentID
+	var chunkOffset int64
+	var chunkSize uint32
+	var sequence uint64
+	if _, err := fmt.Sscanf(string(text), "%d:%d:%d:%d", &segmentId, &chunkOffset, &chunkSize, &sequence); err != nil {
+		return fmt.Errorf("wal: invalid ChunkPosition %q: %w", text, err)
+	}
+	pos.SegmentId = segmentId
+	pos.ChunkOffset = chunkOffset
+	pos.ChunkSize = chunkSize
+	pos.Sequence = sequence
+	return nil
+}
+
+// MarshalJSON encodes pos as the JSON string produced by MarshalText,
+// rather than as a JSON object, so positions read naturally as opaque
+// tokens wherever they're embedded.
+func (pos *ChunkPosition) MarshalJSON() ([]byte, error) {
+	text, err := pos.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(string(text))
+}
+
+// UnmarshalJSON decodes the format produced by MarshalJSON.
+func (pos *ChunkPosition) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+	return pos.UnmarshalText([]byte(text))
+}
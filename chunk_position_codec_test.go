@@ -0,0 +1,37 @@
+package wal
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChunkPositionTextRoundTrip(t *testing.T) {
+	pos := &ChunkPosition{SegmentId: 3, ChunkOffset: 128, ChunkSize: 64, Sequence: 7}
+
+	text, err := pos.MarshalText()
+	require.Nil(t, err)
+	require.Equal(t, "3:128:64:7", string(text))
+
+	var got ChunkPosition
+	require.Nil(t, got.UnmarshalText(text))
+	require.Equal(t, *pos, got)
+}
+
+func TestChunkPositionJSONRoundTrip(t *testing.T) {
+	pos := &ChunkPosition{SegmentId: 3, ChunkOffset: 128, ChunkSize: 64, Sequence: 7}
+
+	data, err := json.Marshal(pos)
+	require.Nil(t, err)
+	require.Equal(t, `"3:128:64:7"`, string(data))
+
+	var got ChunkPosition
+	require.Nil(t, json.Unmarshal(data, &got))
+	require.Equal(t, *pos, got)
+}
+
+func TestChunkPositionUnmarshalTextRejectsGarbage(t *testing.T) {
+	var got ChunkPosition
+	require.Error(t, got.UnmarshalText([]byte("not-a-position")))
+}
@@ -0,0 +1,38 @@
+package wal
+
+// Compare reports the relative write order of pos and other: -1 if pos was
+// written before other, 0 if they're the same position, and 1 if pos was
+// written after other. Positions are ordered first by segment, then by
+// offset within that segment, which matches write order since segments are
+// created and filled in increasing ID order.
+func (pos *ChunkPosition) Compare(other *ChunkPosition) int {
+	if pos.SegmentId != other.SegmentId {
+		if pos.SegmentId < other.SegmentId {
+			return -1
+		}
+		return 1
+	}
+	switch {
+	case pos.ChunkOffset < other.ChunkOffset:
+		return -1
+	case pos.ChunkOffset > other.ChunkOffset:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Before reports whether pos was written before other.
+func (pos *ChunkPosition) Before(other *ChunkPosition) bool {
+	return pos.Compare(other) < 0
+}
+
+// After reports whether pos was written after other.
+func (pos *ChunkPosition) After(other *ChunkPosition) bool {
+	return pos.Compare(other) > 0
+}
+
+// Equal reports whether pos and other refer to the same chunk.
+func (pos *ChunkPosition) Equal(other *ChunkPosition) bool {
+	return pos.Compare(other) == 0
+}
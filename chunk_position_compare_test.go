@@ -0,0 +1,39 @@
+package wal
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChunkPositionCompare(t *testing.T) {
+	a := &ChunkPosition{SegmentId: 1, ChunkOffset: 10, ChunkSize: 5}
+	b := &ChunkPosition{SegmentId: 1, ChunkOffset: 20, ChunkSize: 5}
+	c := &ChunkPosition{SegmentId: 2, ChunkOffset: 0, ChunkSize: 5}
+	d := &ChunkPosition{SegmentId: 1, ChunkOffset: 10, ChunkSize: 5}
+
+	require.True(t, a.Before(b))
+	require.True(t, b.Before(c))
+	require.True(t, c.After(b))
+	require.True(t, b.After(a))
+	require.True(t, a.Equal(d))
+	require.False(t, a.Before(d))
+	require.False(t, a.After(d))
+}
+
+func TestChunkPositionSort(t *testing.T) {
+	positions := []*ChunkPosition{
+		{SegmentId: 2, ChunkOffset: 0, ChunkSize: 5},
+		{SegmentId: 1, ChunkOffset: 20, ChunkSize: 5},
+		{SegmentId: 1, ChunkOffset: 10, ChunkSize: 5},
+	}
+	sort.Slice(positions, func(i, j int) bool {
+		return positions[i].Before(positions[j])
+	})
+	require.Equal(t, []*ChunkPosition{
+		{SegmentId: 1, ChunkOffset: 10, ChunkSize: 5},
+		{SegmentId: 1, ChunkOffset: 20, ChunkSize: 5},
+		{SegmentId: 2, ChunkOffset: 0, ChunkSize: 5},
+	}, positions)
+}
@@ -0,0 +1,49 @@
+package wal
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// chunkPositionFixedVersion is the first byte of EncodeFixed's output, so a
+// future incompatible layout change can be told apart from this one instead
+// of DecodeFixed silently misreading it.
+const chunkPositionFixedVersion byte = 1
+
+// ChunkPositionFixedSize is the exact length, in bytes, EncodeFixed always
+// produces: chunkPositionFixedVersion, then SegmentId, ChunkOffset,
+// ChunkSize, and Sequence, each big-endian. Unlike MarshalText's
+// variable-length "segmentId:chunkOffset:chunkSize:sequence", this is fixed
+// regardless of the values involved, so it can be embedded as one slot in a
+// fixed-width on-disk index format.
+const ChunkPositionFixedSize = 1 + 4 + 8 + 4 + 8
+
+// EncodeFixed renders pos in ChunkPositionFixedSize bytes, for an on-disk
+// index format that needs every entry to be the same width. Read it back
+// with DecodeFixed.
+func (pos *ChunkPosition) EncodeFixed() []byte {
+	buf := make([]byte, ChunkPositionFixedSize)
+	buf[0] = chunkPositionFixedVersion
+	binary.BigEndian.PutUint32(buf[1:5], pos.SegmentId)
+	binary.BigEndian.PutUint64(buf[5:13], uint64(pos.ChunkOffset))
+	binary.BigEndian.PutUint32(buf[13:17], pos.ChunkSize)
+	binary.BigEndian.PutUint64(buf[17:25], pos.Sequence)
+	return buf
+}
+
+// DecodeFixed parses the format produced by EncodeFixed, rejecting buf if
+// it isn't exactly ChunkPositionFixedSize bytes or carries a version
+// EncodeFixed didn't produce.
+func (pos *ChunkPosition) DecodeFixed(buf []byte) error {
+	if len(buf) != ChunkPositionFixedSize {
+		return fmt.Errorf("wal: fixed-encoded ChunkPosition must be %d bytes, got %d", ChunkPositionFixedSize, len(buf))
+	}
+	if buf[0] != chunkPositionFixedVersion {
+		return fmt.Errorf("wal: fixed-encoded ChunkPosition has unsupported version %d", buf[0])
+	}
+	pos.SegmentId = binary.BigEndian.Uint32(buf[1:5])
+	pos.ChunkOffset = int64(binary.BigEndian.Uint64(buf[5:13]))
+	pos.ChunkSize = binary.BigEndian.Uint32(buf[13:17])
+	pos.Sequence = binary.BigEndian.Uint64(buf[17:25])
+	return nil
+}
@@ -0,0 +1,32 @@
+package wal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChunkPositionFixedRoundTrip(t *testing.T) {
+	pos := &ChunkPosition{SegmentId: 3, ChunkOffset: 128, ChunkSize: 64, Sequence: 7}
+
+	buf := pos.EncodeFixed()
+	require.Len(t, buf, ChunkPositionFixedSize)
+
+	var got ChunkPosition
+	require.Nil(t, got.DecodeFixed(buf))
+	require.Equal(t, *pos, got)
+}
+
+func TestChunkPositionDecodeFixedRejectsWrongSize(t *testing.T) {
+	var got ChunkPosition
+	require.Error(t, got.DecodeFixed([]byte{1, 2, 3}))
+}
+
+func TestChunkPositionDecodeFixedRejectsUnknownVersion(t *testing.T) {
+	pos := &ChunkPosition{SegmentId: 3, ChunkOffset: 128, ChunkSize: 64, Sequence: 7}
+	buf := pos.EncodeFixed()
+	buf[0] = 255
+
+	var got ChunkPosition
+	require.Error(t, got.DecodeFixed(buf))
+}
@@ -0,0 +1,62 @@
+package wal
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeDecodeChunkRoundTrip(t *testing.T) {
+	var block []byte
+	block = append(block, EncodeChunk([]byte("first"), ChecksumCRC32)...)
+	block = append(block, EncodeChunk([]byte("second"), ChecksumCRC32)...)
+
+	chunks, err := DecodeChunks(block, ChecksumCRC32)
+	require.Nil(t, err)
+	require.Equal(t, [][]byte{[]byte("first"), []byte("second")}, chunks)
+}
+
+func TestDecodeChunksStopsAtTornTail(t *testing.T) {
+	block := EncodeChunk([]byte("whole"), ChecksumCRC32)
+	block = append(block, EncodeChunk([]byte("torn"), ChecksumCRC32)[:chunkHeaderSize+2]...)
+
+	chunks, err := DecodeChunks(block, ChecksumCRC32)
+	require.Nil(t, err)
+	require.Equal(t, [][]byte{[]byte("whole")}, chunks)
+}
+
+func TestDecodeChunksStopsAtZeroLengthPadding(t *testing.T) {
+	block := EncodeChunk([]byte("whole"), ChecksumCRC32)
+	block = append(block, make([]byte, chunkHeaderSize)...)
+
+	chunks, err := DecodeChunks(block, ChecksumCRC32)
+	require.Nil(t, err)
+	require.Equal(t, [][]byte{[]byte("whole")}, chunks)
+}
+
+func TestDecodeChunksReportsCorruption(t *testing.T) {
+	block := EncodeChunk([]byte("whole"), ChecksumCRC32)
+	block[chunkHeaderSize] ^= 0xFF // flip a payload byte without touching its checksum
+
+	chunks, err := DecodeChunks(block, ChecksumCRC32)
+	require.Empty(t, chunks)
+
+	var corrupted *ErrCorrupted
+	require.True(t, errors.As(err, &corrupted))
+	require.ErrorIs(t, err, ErrInvalidCRC)
+	require.Equal(t, int64(0), corrupted.ChunkOffset)
+}
+
+func TestEncodeChunkMatchesChecksumAlgorithm(t *testing.T) {
+	payload := []byte("crc32c")
+	encoded := EncodeChunk(payload, ChecksumCRC32C)
+
+	_, err := DecodeChunks(encoded, ChecksumCRC32)
+	var corrupted *ErrCorrupted
+	require.True(t, errors.As(err, &corrupted), "decoding with the wrong checksum algorithm must not validate")
+
+	chunks, err := DecodeChunks(encoded, ChecksumCRC32C)
+	require.Nil(t, err)
+	require.Equal(t, [][]byte{payload}, chunks)
+}
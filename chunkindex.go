@@ -0,0 +1,130 @@
+package wal
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+)
+
+// chunkIndexMagic marks a segment's chunk-index sidecar file, for
+// readNthChunkIndexEntry to recognize it as one of these rather than some
+// unrelated file that happens to share its name.
+const chunkIndexMagic = 0x57414c49 // "WALI"
+
+// chunkIndexHeaderSize is the fixed portion of a chunk-index sidecar:
+// everything but the entries themselves, whose count varies with how many
+// chunks the segment holds.
+const chunkIndexHeaderSize = 4 /*magic*/ + 8 /*baseSequence*/ + 4 /*count*/
+
+// chunkIndexEntrySize is the serialized size, in bytes, of one
+// chunkIndexEntry within a sidecar.
+const chunkIndexEntrySize = 8 /*offset*/ + 4 /*size*/
+
+// chunkIndexEntry records one chunk's position within its segment, at the
+// ordinal implied by its place in the sidecar (the first entry is ordinal
+// 0, and so on).
+type chunkIndexEntry struct {
+	offset int64
+	size   uint32
+}
+
+// chunkIndexFileName returns the path of segmentPath's chunk-index sidecar:
+// a file separate from the segment itself, so that losing or rebuilding an
+// external index (e.g. a key/value store's hint file) never has to touch,
+// or be blocked on, the WAL's own segment files.
+func chunkIndexFileName(segmentPath string) string {
+	return segmentPath + ".CIDX"
+}
+
+// writeChunkIndex writes a chunk-index sidecar for the segment at
+// segmentPath, recording baseSequence (the sequence number of entries[0])
+// and one entry per chunk, in order. It's called once, by seal, for a
+// segment opened with Options.ChunkIndex -- the sidecar is never updated
+// incrementally, since seal only ever runs after the segment has stopped
+// accepting writes.
+func writeChunkIndex(segmentPath string, baseSequence uint64, entries []chunkIndexEntry) error {
+	buf := make([]byte, chunkIndexHeaderSize+len(entries)*chunkIndexEntrySize)
+	binary.BigEndian.PutUint32(buf[0:4], chunkIndexMagic)
+	binary.BigEndian.PutUint64(buf[4:12], baseSequence)
+	binary.BigEndian.PutUint32(buf[12:16], uint32(len(entries)))
+	for i, entry := range entries {
+		start := chunkIndexHeaderSize + i*chunkIndexEntrySize
+		binary.BigEndian.PutUint64(buf[start:start+8], uint64(entry.offset))
+		binary.BigEndian.PutUint32(buf[start+8:start+12], entry.size)
+	}
+	return os.WriteFile(chunkIndexFileName(segmentPath), buf, 0o644)
+}
+
+// readNthChunkIndexEntry looks up the ordinal n within the chunk-index
+// sidecar at path (see chunkIndexFileName), returning its entry and the
+// sidecar's baseSequence and total count.
+//
+// Its three return states matter to ReadNth:
+//   - ok == false, err == nil: no sidecar exists at all -- an older segment
+//     sealed before Options.ChunkIndex was set, or one whose sidecar write
+//     never made it to disk. The caller should fall back to scanning the
+//     segment itself.
+//   - ok == true, err == io.EOF: a sidecar exists but n is out of range for
+//     it.
+//   - ok == true, err == nil: entry is valid.
+//
+// Anything else is a genuine I/O or corruption error, distinct from the
+// graceful "no sidecar" case above.
+func readNthChunkIndexEntry(path string, n int) (entry chunkIndexEntry, baseSequence uint64, count int, ok bool, err error) {
+	if n < 0 {
+		return chunkIndexEntry{}, 0, 0, false, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return chunkIndexEntry{}, 0, 0, false, nil
+		}
+		return chunkIndexEntry{}, 0, 0, false, err
+	}
+	if len(data) < chunkIndexHeaderSize {
+		return chunkIndexEntry{}, 0, 0, false, nil
+	}
+	if binary.BigEndian.Uint32(data[0:4]) != chunkIndexMagic {
+		return chunkIndexEntry{}, 0, 0, false, nil
+	}
+	baseSequence = binary.BigEndian.Uint64(data[4:12])
+	count = int(binary.BigEndian.Uint32(data[12:16]))
+	if len(data) != chunkIndexHeaderSize+count*chunkIndexEntrySize {
+		// A torn write: the sidecar is present but not trustworthy, same as
+		// if it were missing outright.
+		return chunkIndexEntry{}, 0, 0, false, nil
+	}
+	if n >= count {
+		return chunkIndexEntry{}, baseSequence, count, true, io.EOF
+	}
+	start := chunkIndexHeaderSize + n*chunkIndexEntrySize
+	entry = chunkIndexEntry{
+		offset: int64(binary.BigEndian.Uint64(data[start : start+8])),
+		size:   binary.BigEndian.Uint32(data[start+8 : start+12]),
+	}
+	return entry, baseSequence, count, true, nil
+}
+
+// scanChunkIndexEntries walks payload -- a sealed segment's full chunk
+// data, the same buffer seal reads to compute its whole-segment checksum --
+// extracting each chunk's (offset, size) in order. It trusts that
+// whole-segment checksum rather than re-verifying every chunk's own CRC,
+// the same trade openSegmentFile already makes for a footer-backed sealed
+// segment.
+func scanChunkIndexEntries(payload []byte) ([]chunkIndexEntry, error) {
+	var entries []chunkIndexEntry
+	var offset int64
+	for offset < int64(len(payload)) {
+		if offset+int64(chunkHeaderSize) > int64(len(payload)) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		length := binary.BigEndian.Uint32(payload[offset : offset+4])
+		if offset+int64(chunkHeaderSize)+int64(length) > int64(len(payload)) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		entries = append(entries, chunkIndexEntry{offset: offset, size: length})
+		offset += int64(chunkHeaderSize) + int64(length)
+	}
+	return entries, nil
+}
@@ -0,0 +1,128 @@
+package wal
+
+import (
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteAndReadNthChunkIndexEntry(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-chunkindex-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	segmentPath := dir + "/000000001.SEG"
+	entries := []chunkIndexEntry{
+		{offset: 0, size: 10},
+		{offset: 18, size: 20},
+		{offset: 46, size: 3},
+	}
+	require.Nil(t, writeChunkIndex(segmentPath, 100, entries))
+
+	entry, baseSeq, count, ok, err := readNthChunkIndexEntry(chunkIndexFileName(segmentPath), 1)
+	require.Nil(t, err)
+	require.True(t, ok)
+	assert.Equal(t, uint64(100), baseSeq)
+	assert.Equal(t, 3, count)
+	assert.Equal(t, entries[1], entry)
+
+	_, _, _, ok, err = readNthChunkIndexEntry(chunkIndexFileName(segmentPath), 3)
+	assert.True(t, ok)
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestReadNthChunkIndexEntryNoSidecar(t *testing.T) {
+	_, _, _, ok, err := readNthChunkIndexEntry("/no/such/sidecar.CIDX", 0)
+	assert.False(t, ok)
+	assert.Nil(t, err)
+}
+
+func TestReadNthUsesChunkIndexSidecar(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-chunkindex-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(64), WithChunkIndex(true))
+	require.Nil(t, err)
+
+	var want [][]byte
+	for i := 0; i < 20; i++ {
+		data := []byte{byte(i), byte(i), byte(i)}
+		want = append(want, data)
+		_, err := w.Write(data)
+		require.Nil(t, err)
+	}
+	require.Nil(t, w.Close())
+
+	w2, err := Open(WithDirPath(dir), WithSegmentSize(64), WithChunkIndex(true))
+	require.Nil(t, err)
+	defer w2.Close()
+
+	require.NotEmpty(t, w2.olderSegments, "test needs at least one sealed segment to exercise the sidecar path")
+	var sealedID SegmentID
+	for id := range w2.olderSegments {
+		sealedID = id
+		break
+	}
+	_, statErr := os.Stat(chunkIndexFileName(w2.olderSegments[sealedID].path))
+	require.Nil(t, statErr, "seal should have written a chunk-index sidecar for a sealed segment")
+
+	for n := 0; n < 3; n++ {
+		data, err := w2.ReadNth(sealedID, n)
+		require.Nil(t, err, "ordinal %d", n)
+		assert.NotEmpty(t, data)
+	}
+
+	_, err = w2.ReadNth(sealedID, 1<<20)
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestReadNthFallsBackToScanningWithoutChunkIndex(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-chunkindex-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(64))
+	require.Nil(t, err)
+
+	var want [][]byte
+	for i := 0; i < 20; i++ {
+		data := []byte{byte(i), byte(i), byte(i)}
+		want = append(want, data)
+		_, err := w.Write(data)
+		require.Nil(t, err)
+	}
+	require.Nil(t, w.Close())
+
+	w2, err := Open(WithDirPath(dir), WithSegmentSize(64))
+	require.Nil(t, err)
+	defer w2.Close()
+
+	require.NotEmpty(t, w2.olderSegments)
+	var sealedID SegmentID = ^SegmentID(0)
+	for id := range w2.olderSegments {
+		if id < sealedID {
+			sealedID = id
+		}
+	}
+	_, statErr := os.Stat(chunkIndexFileName(w2.olderSegments[sealedID].path))
+	assert.True(t, os.IsNotExist(statErr), "no sidecar should exist without Options.ChunkIndex")
+
+	data, err := w2.ReadNth(sealedID, 0)
+	require.Nil(t, err)
+	assert.Equal(t, want[0], data)
+}
+
+func TestChunkIndexDisabledByDefault(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-chunkindex-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+	assert.False(t, w.options.ChunkIndex)
+}
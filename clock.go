@@ -0,0 +1,143 @@
+package wal
+
+import (
+	"runtime"
+	"sync"
+	"time"
+)
+
+// Clock abstracts the passage of time for the WAL's background
+// sync-interval flusher (see startFlusher) and its age-based retention
+// (Options.MaxSegmentAge, Options.DropExpiredSegments), so a test can
+// drive both deterministically with a FakeClock instead of sleeping and
+// waiting on a real timer. It defaults to the real wall clock; set it
+// with WithClock.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// NewTicker returns a Ticker that fires every d, the same as
+	// time.NewTicker.
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker is the subset of *time.Ticker's behavior the WAL's background
+// flusher needs. *time.Ticker satisfies it once wrapped by realClock.
+type Ticker interface {
+	// C returns the channel on which ticks are delivered.
+	C() <-chan time.Time
+	// Stop turns off the ticker. It does not close C.
+	Stop()
+}
+
+// realClock is the default Clock, backed directly by the time package.
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+// realTicker adapts *time.Ticker to Ticker.
+type realTicker struct{ t *time.Ticker }
+
+func (r realTicker) C() <-chan time.Time { return r.t.C }
+func (r realTicker) Stop()               { r.t.Stop() }
+
+// FakeClock is a Clock a test fully controls: Now never advances and no
+// Ticker ever fires on its own until Tick is called, so a test involving
+// Options.SyncInterval or Options.MaxSegmentAge runs as fast as the code
+// under test, not as fast as a real timer.
+//
+// A FakeClock is safe for concurrent use.
+type FakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	tickers []*fakeTicker
+}
+
+// NewFakeClock returns a FakeClock starting at start.
+func NewFakeClock(start time.Time) *FakeClock {
+	return &FakeClock{now: start}
+}
+
+// Now returns the FakeClock's current time, as of the last Tick.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// NewTicker returns a Ticker that only fires in response to this
+// FakeClock's Tick, never on its own.
+func (c *FakeClock) NewTicker(d time.Duration) Ticker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &fakeTicker{period: d, next: c.now.Add(d), ch: make(chan time.Time, 1)}
+	c.tickers = append(c.tickers, t)
+	return t
+}
+
+// Tick advances the clock by d and fires every live Ticker whose period
+// has elapsed one or more times since it was created or last fired --
+// coalesced into a single, non-blocking send, the same as a real
+// time.Ticker does when its consumer falls behind.
+//
+// Call RunPending afterward to give whatever goroutine a fired Ticker
+// wakes (e.g. the sync-interval flusher's resulting Sync call) a chance
+// to finish before asserting on its effect.
+func (c *FakeClock) Tick(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	now := c.now
+	var due []*fakeTicker
+	for _, t := range c.tickers {
+		t.mu.Lock()
+		if !t.stopped && !t.next.After(now) {
+			due = append(due, t)
+			t.next = now.Add(t.period)
+		}
+		t.mu.Unlock()
+	}
+	c.mu.Unlock()
+
+	for _, t := range due {
+		select {
+		case t.ch <- now:
+		default:
+		}
+	}
+}
+
+// RunPending yields the scheduler so a goroutine woken by Tick's delivery
+// on a Ticker's channel gets a chance to run.
+//
+// This is a best-effort convenience, not a hard synchronization barrier:
+// it does not wait for that goroutine's work (e.g. a Sync call) to
+// actually complete, only for it to have been scheduled. A test asserting
+// on something slower should poll, or check WAL.Stats(), rather than
+// relying on RunPending alone.
+func (c *FakeClock) RunPending() {
+	for i := 0; i < 100; i++ {
+		runtime.Gosched()
+	}
+}
+
+// fakeTicker is the Ticker implementation behind FakeClock.NewTicker.
+type fakeTicker struct {
+	period time.Duration
+
+	mu      sync.Mutex
+	next    time.Time
+	stopped bool
+
+	ch chan time.Time
+}
+
+func (t *fakeTicker) C() <-chan time.Time { return t.ch }
+
+func (t *fakeTicker) Stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stopped = true
+}
@@ -0,0 +1,98 @@
+package wal
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCloseWritesShutdownMarker(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-close-marker-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	_, err = w.Write([]byte("hello"))
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+
+	_, err = os.Stat(filepath.Join(dir, shutdownMarkerFileName))
+	require.Nil(t, err)
+
+	// Reopening removes the marker; a WAL that's currently running never
+	// looks like one that shut down cleanly.
+	w, err = Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+	_, err = os.Stat(filepath.Join(dir, shutdownMarkerFileName))
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestCloseRejectsFurtherWrites(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-close-rejects-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+
+	_, err = w.Write([]byte("hello"))
+	require.Equal(t, ErrClosed, err)
+
+	err = w.PendingWrites([]byte("hello"))
+	require.Equal(t, ErrClosed, err)
+
+	_, err = w.WriteAll()
+	require.Equal(t, ErrClosed, err)
+
+	_, err = w.Sync()
+	require.Equal(t, ErrClosed, err)
+}
+
+func TestCloseDrainsOutstandingWriteAsyncCalls(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-close-drain-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+
+	const n = 200
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			w.WriteAsync([]byte("hello"), nil)
+		}(i)
+	}
+	wg.Wait()
+
+	require.Nil(t, w.Close())
+}
+
+func TestWriteAsyncAfterCloseReportsErrClosed(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-write-async-after-close-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+
+	var gotErr error
+	var wg sync.WaitGroup
+	wg.Add(1)
+	w.WriteAsync([]byte("hello"), func(_ *ChunkPosition, err error) {
+		gotErr = err
+		wg.Done()
+	})
+	wg.Wait()
+	require.True(t, errors.Is(gotErr, ErrClosed))
+}
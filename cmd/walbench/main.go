@@ -0,0 +1,80 @@
+// Command walbench drives a load-generation run against a WAL directory
+// and reports throughput and latency percentiles, for sizing hardware or
+// comparing WAL configurations (segment size, sync mode) under a given
+// workload shape.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/rosedblabs/wal/walbench"
+)
+
+func main() {
+	dir := flag.String("dir", "", "WAL directory to benchmark against (required; created if missing)")
+	segmentSize := flag.Int64("segment-size", 0, "segment size in bytes (0 uses wal's default)")
+	sync := flag.Bool("sync", false, "fsync every write (wal.WithSync)")
+	concurrency := flag.Int("concurrency", 1, "number of goroutines issuing operations concurrently")
+	duration := flag.Duration("duration", 10*time.Second, "how long the timed run lasts")
+	minSize := flag.Int("min-size", 128, "minimum record payload size in bytes")
+	maxSize := flag.Int("max-size", 128, "maximum record payload size in bytes")
+	readFraction := flag.Float64("read-fraction", 0, "probability in [0,1] that an operation is a read rather than a write")
+	warmup := flag.Int("warmup", 0, "records to write before the timed run starts, for -read-fraction's reads to sample from")
+	flag.Parse()
+
+	if *dir == "" {
+		fmt.Fprintln(os.Stderr, "walbench: -dir is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	result, err := walbench.Run(walbench.Config{
+		Dir:           *dir,
+		SegmentSize:   *segmentSize,
+		Sync:          *sync,
+		Concurrency:   *concurrency,
+		Duration:      *duration,
+		MinRecordSize: *minSize,
+		MaxRecordSize: *maxSize,
+		ReadFraction:  *readFraction,
+		Warmup:        *warmup,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "walbench:", err)
+		os.Exit(1)
+	}
+
+	report(os.Stdout, result)
+}
+
+func report(out io.Writer, r *walbench.Result) {
+	fmt.Fprintf(out, "duration: %s\n", r.Duration)
+	fmt.Fprintf(out, "writes: %d ops, %d bytes (%.0f ops/s, %.0f bytes/s)\n",
+		r.WriteOps, r.WriteBytes, opsPerSec(r.WriteOps, r.Duration), bytesPerSec(r.WriteBytes, r.Duration))
+	printPercentiles(out, "write latency", r.WriteLatency)
+	fmt.Fprintf(out, "reads: %d ops (%.0f ops/s)\n", r.ReadOps, opsPerSec(r.ReadOps, r.Duration))
+	printPercentiles(out, "read latency", r.ReadLatency)
+}
+
+func printPercentiles(out io.Writer, label string, p walbench.Percentiles) {
+	fmt.Fprintf(out, "%s: p50=%s p90=%s p99=%s p999=%s max=%s\n",
+		label, p.P50, p.P90, p.P99, p.P999, p.Max)
+}
+
+func opsPerSec(ops uint64, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return float64(ops) / d.Seconds()
+}
+
+func bytesPerSec(bytes uint64, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return float64(bytes) / d.Seconds()
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/rosedblabs/wal/walbench"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReportPrintsThroughputAndPercentiles(t *testing.T) {
+	var out bytes.Buffer
+	report(&out, &walbench.Result{
+		Duration:   time.Second,
+		WriteOps:   100,
+		WriteBytes: 1600,
+		WriteLatency: walbench.Percentiles{
+			P50: time.Microsecond, P90: 2 * time.Microsecond,
+			P99: 3 * time.Microsecond, P999: 4 * time.Microsecond, Max: 5 * time.Microsecond,
+		},
+		ReadOps: 50,
+		ReadLatency: walbench.Percentiles{
+			P50: time.Microsecond, P90: 2 * time.Microsecond,
+			P99: 3 * time.Microsecond, P999: 4 * time.Microsecond, Max: 5 * time.Microsecond,
+		},
+	})
+
+	text := out.String()
+	assert.Contains(t, text, "writes: 100 ops, 1600 bytes (100 ops/s, 1600 bytes/s)")
+	assert.Contains(t, text, "reads: 50 ops (50 ops/s)")
+	assert.Contains(t, text, "write latency: p50=1µs p90=2µs p99=3µs p999=4µs max=5µs")
+}
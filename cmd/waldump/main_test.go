@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rosedblabs/wal"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDumpPrintsEveryChunk(t *testing.T) {
+	dir, err := os.MkdirTemp("", "waldump-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := wal.Open(wal.WithDirPath(dir), wal.WithSegmentFileExt(".SEG"))
+	require.Nil(t, err)
+	for _, s := range []string{"hello", "world"} {
+		_, err := w.Write([]byte(s))
+		require.Nil(t, err)
+	}
+	require.Nil(t, w.Close())
+
+	var out bytes.Buffer
+	require.Nil(t, dump(&out, dir, ".SEG", decoders["string"]))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.Contains(t, lines[0], `"hello"`)
+	assert.Contains(t, lines[1], `"world"`)
+	assert.Contains(t, lines[2], "2 chunks")
+}
+
+func TestDumpReportsCorruptionAndStops(t *testing.T) {
+	dir, err := os.MkdirTemp("", "waldump-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := wal.Open(wal.WithDirPath(dir), wal.WithSegmentFileExt(".SEG"))
+	require.Nil(t, err)
+	_, err = w.Write([]byte("hello"))
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+
+	// Flip the last byte of the (single-chunk) segment file: whatever the
+	// exact header layout, that byte falls within the chunk's payload, so
+	// this invalidates its checksum without needing to know the format.
+	entries, err := os.ReadDir(dir)
+	require.Nil(t, err)
+	var segPath string
+	for _, e := range entries {
+		if filepath.Ext(e.Name()) == ".SEG" {
+			segPath = filepath.Join(dir, e.Name())
+		}
+	}
+	require.NotEmpty(t, segPath)
+
+	info, err := os.Stat(segPath)
+	require.Nil(t, err)
+	f, err := os.OpenFile(segPath, os.O_RDWR, 0o644)
+	require.Nil(t, err)
+	_, err = f.WriteAt([]byte{0xFF}, info.Size()-1)
+	require.Nil(t, err)
+	require.Nil(t, f.Close())
+
+	var out bytes.Buffer
+	err = dump(&out, dir, ".SEG", decoders["string"])
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "segment 1 offset 0")
+}
+
+func TestDumpJSONLPrintsOneObjectPerChunk(t *testing.T) {
+	dir, err := os.MkdirTemp("", "waldump-jsonl-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := wal.Open(wal.WithDirPath(dir), wal.WithSegmentFileExt(".SEG"))
+	require.Nil(t, err)
+	_, err = w.Write([]byte("hello"))
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+
+	var out bytes.Buffer
+	require.Nil(t, dumpJSONL(&out, dir, ".SEG", jsonlDecoders["string"]))
+
+	var rec map[string]any
+	require.Nil(t, json.Unmarshal(bytes.TrimSpace(out.Bytes()), &rec))
+	assert.Equal(t, "hello", rec["payload"])
+	assert.Equal(t, float64(1), rec["segment"])
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rosedblabs/wal"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateExitsZeroAndRewritesWAL(t *testing.T) {
+	srcDir, err := os.MkdirTemp("", "walmigrate-test-src")
+	require.Nil(t, err)
+	defer os.RemoveAll(srcDir)
+	dstDir := filepath.Join(os.TempDir(), "walmigrate-test-dst")
+	require.Nil(t, os.RemoveAll(dstDir))
+	defer os.RemoveAll(dstDir)
+
+	src, err := wal.Open(wal.WithDirPath(srcDir), wal.WithSegmentFileExt(".SEG"))
+	require.Nil(t, err)
+	_, err = src.Write([]byte("hello"))
+	require.Nil(t, err)
+	require.Nil(t, src.Close())
+
+	out, errOut := captureOutput(t, func(out, errOut *os.File) int {
+		return migrate(out, errOut, srcDir, dstDir, ".SEG", "snappy", 0)
+	})
+	assert.Equal(t, 0, out.code)
+	assert.Contains(t, out.text, "migrated")
+	assert.Empty(t, errOut.text)
+
+	dst, err := wal.Open(wal.WithDirPath(dstDir), wal.WithSegmentFileExt(".SEG"), wal.WithCompression(wal.CompressionSnappy))
+	require.Nil(t, err)
+	defer dst.Close()
+
+	data, _, err := dst.NewReader().Next()
+	require.Nil(t, err)
+	assert.Equal(t, "hello", string(data))
+}
+
+func TestMigrateExitsTwoOnUnknownCompression(t *testing.T) {
+	out, errOut := captureOutput(t, func(out, errOut *os.File) int {
+		return migrate(out, errOut, "src", "dst", ".SEG", "bogus", 0)
+	})
+	assert.Equal(t, 2, out.code)
+	assert.Contains(t, errOut.text, "unknown -compression")
+}
+
+// capturedOutput is what one of migrate's output files accumulated.
+type capturedOutput struct {
+	text string
+	code int // only meaningful on the value returned for stdout
+}
+
+// captureOutput runs fn with two os.Pipe-backed files standing in for
+// stdout and stderr, and returns what was written to each along with fn's
+// return value, stashed on the stdout result's code field.
+func captureOutput(t *testing.T, fn func(out, errOut *os.File) int) (stdout, stderr capturedOutput) {
+	t.Helper()
+
+	outR, outW, err := os.Pipe()
+	require.Nil(t, err)
+	errR, errW, err := os.Pipe()
+	require.Nil(t, err)
+
+	code := fn(outW, errW)
+	require.Nil(t, outW.Close())
+	require.Nil(t, errW.Close())
+
+	outBytes, err := io.ReadAll(outR)
+	require.Nil(t, err)
+	errBytes, err := io.ReadAll(errR)
+	require.Nil(t, err)
+
+	return capturedOutput{string(outBytes), code}, capturedOutput{string(errBytes), 0}
+}
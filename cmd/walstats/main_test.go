@@ -0,0 +1,147 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rosedblabs/wal"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatsReportsCleanWAL(t *testing.T) {
+	dir, err := os.MkdirTemp("", "walstats-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := wal.Open(wal.WithDirPath(dir), wal.WithSegmentFileExt(".SEG"))
+	require.Nil(t, err)
+	_, err = w.Write([]byte("first"))
+	require.Nil(t, err)
+	_, err = w.Write([]byte("second"))
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+
+	out, stderr := captureOutput(t, func(out, errOut *os.File) int {
+		return stats(out, errOut, dir, ".SEG", false)
+	})
+	assert.Equal(t, 0, out.code)
+	assert.Contains(t, out.text, "segments: 1")
+	assert.Contains(t, out.text, "records: 2")
+	assert.Contains(t, out.text, "corruption: none")
+	assert.Empty(t, stderr.text)
+}
+
+func TestStatsReportsTimestampsWhenEnabled(t *testing.T) {
+	dir, err := os.MkdirTemp("", "walstats-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := wal.Open(wal.WithDirPath(dir), wal.WithSegmentFileExt(".SEG"), wal.WithTimestamps(true))
+	require.Nil(t, err)
+	_, err = w.Write([]byte("hello"))
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+
+	out, _ := captureOutput(t, func(out, errOut *os.File) int {
+		return stats(out, errOut, dir, ".SEG", true)
+	})
+	assert.Equal(t, 0, out.code)
+	assert.Contains(t, out.text, "oldest record:")
+	assert.Contains(t, out.text, "newest record:")
+	assert.NotContains(t, out.text, "unavailable")
+}
+
+func TestStatsWithoutTimestampsFlagReportsUnavailable(t *testing.T) {
+	dir, err := os.MkdirTemp("", "walstats-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := wal.Open(wal.WithDirPath(dir), wal.WithSegmentFileExt(".SEG"))
+	require.Nil(t, err)
+	_, err = w.Write([]byte("hello"))
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+
+	out, _ := captureOutput(t, func(out, errOut *os.File) int {
+		return stats(out, errOut, dir, ".SEG", false)
+	})
+	assert.Equal(t, 0, out.code)
+	assert.Contains(t, out.text, "oldest/newest record: unavailable")
+}
+
+func TestStatsExitsOneOnCorruption(t *testing.T) {
+	dir, err := os.MkdirTemp("", "walstats-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := wal.Open(wal.WithDirPath(dir), wal.WithSegmentFileExt(".SEG"))
+	require.Nil(t, err)
+	_, err = w.Write([]byte("hello"))
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+
+	entries, err := os.ReadDir(dir)
+	require.Nil(t, err)
+	var segPath string
+	for _, e := range entries {
+		if filepath.Ext(e.Name()) == ".SEG" {
+			segPath = filepath.Join(dir, e.Name())
+		}
+	}
+	require.NotEmpty(t, segPath)
+
+	info, err := os.Stat(segPath)
+	require.Nil(t, err)
+	f, err := os.OpenFile(segPath, os.O_RDWR, 0o644)
+	require.Nil(t, err)
+	_, err = f.WriteAt([]byte{0xFF}, info.Size()-1)
+	require.Nil(t, err)
+	require.Nil(t, f.Close())
+
+	out, errOut := captureOutput(t, func(out, errOut *os.File) int {
+		return stats(out, errOut, dir, ".SEG", false)
+	})
+	assert.Equal(t, 1, out.code)
+	assert.Contains(t, out.text, "corruption: segment 1 offset 0")
+	assert.Empty(t, errOut.text)
+}
+
+func TestStatsExitsTwoOnMissingDirectory(t *testing.T) {
+	out, _ := captureOutput(t, func(out, errOut *os.File) int {
+		return stats(out, errOut, filepath.Join(os.TempDir(), "does-not-exist-walstats"), ".SEG", false)
+	})
+	assert.Equal(t, 2, out.code)
+}
+
+// capturedOutput is what one of stats's output files accumulated.
+type capturedOutput struct {
+	text string
+	code int // only meaningful on the value returned for stdout
+}
+
+// captureOutput runs fn with two os.Pipe-backed files standing in for
+// stdout and stderr, and returns what was written to each along with fn's
+// return value, stashed on the stdout result's code field.
+func captureOutput(t *testing.T, fn func(out, errOut *os.File) int) (stdout, stderr capturedOutput) {
+	t.Helper()
+
+	outR, outW, err := os.Pipe()
+	require.Nil(t, err)
+	errR, errW, err := os.Pipe()
+	require.Nil(t, err)
+
+	code := fn(outW, errW)
+	require.Nil(t, outW.Close())
+	require.Nil(t, errW.Close())
+
+	outBytes, err := io.ReadAll(outR)
+	require.Nil(t, err)
+	errBytes, err := io.ReadAll(errR)
+	require.Nil(t, err)
+
+	return capturedOutput{string(outBytes), code}, capturedOutput{string(errBytes), 0}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rosedblabs/wal"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// syncBuffer is a bytes.Buffer safe for one writer goroutine and one reader
+// goroutine, for tests that read tail's output while it's still running.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]byte(nil), b.buf.Bytes()...)
+}
+
+var _ io.Writer = (*syncBuffer)(nil)
+
+func TestTailPrintsRecordsFromStart(t *testing.T) {
+	dir, err := os.MkdirTemp("", "waltail-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := wal.Open(wal.WithDirPath(dir), wal.WithSegmentFileExt(".SEG"))
+	require.Nil(t, err)
+	_, err = w.Write([]byte("first"))
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	var out bytes.Buffer
+	err = tail(&out, ctx, dir, ".SEG", decoders["string"])
+	require.Nil(t, err)
+
+	assert.Contains(t, out.String(), `segment=1 offset=0 size=5 seq=0 "first"`)
+}
+
+func TestTailPicksUpRecordsWrittenWhileRunning(t *testing.T) {
+	dir, err := os.MkdirTemp("", "waltail-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := wal.Open(wal.WithDirPath(dir), wal.WithSegmentFileExt(".SEG"))
+	require.Nil(t, err)
+	defer w.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	out := &syncBuffer{}
+	go func() {
+		defer close(done)
+		tail(out, ctx, dir, ".SEG", decoders["string"])
+	}()
+
+	require.Eventually(t, func() bool {
+		_, err := w.Write([]byte("late"))
+		require.Nil(t, err)
+		return bytes.Contains(out.Bytes(), []byte(`"late"`))
+	}, time.Second, 10*time.Millisecond)
+
+	cancel()
+	<-done
+}
+
+func TestTailReportsCorruption(t *testing.T) {
+	dir, err := os.MkdirTemp("", "waltail-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := wal.Open(wal.WithDirPath(dir), wal.WithSegmentFileExt(".SEG"))
+	require.Nil(t, err)
+	_, err = w.Write([]byte("hello"))
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+
+	entries, err := os.ReadDir(dir)
+	require.Nil(t, err)
+	var segPath string
+	for _, e := range entries {
+		if filepath.Ext(e.Name()) == ".SEG" {
+			segPath = filepath.Join(dir, e.Name())
+		}
+	}
+	require.NotEmpty(t, segPath)
+
+	info, err := os.Stat(segPath)
+	require.Nil(t, err)
+	f, err := os.OpenFile(segPath, os.O_RDWR, 0o644)
+	require.Nil(t, err)
+	_, err = f.WriteAt([]byte{0xFF}, info.Size()-1)
+	require.Nil(t, err)
+	require.Nil(t, f.Close())
+
+	var out bytes.Buffer
+	err = tail(&out, context.Background(), dir, ".SEG", decoders["string"])
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "segment 1 offset 0")
+}
+
+func TestTailExitsOnMissingDirectory(t *testing.T) {
+	var out bytes.Buffer
+	err := tail(&out, context.Background(), filepath.Join(os.TempDir(), "does-not-exist-waltail"), ".SEG", decoders["string"])
+	require.NotNil(t, err)
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rosedblabs/wal"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyExitsZeroOnCleanWAL(t *testing.T) {
+	dir, err := os.MkdirTemp("", "walverify-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := wal.Open(wal.WithDirPath(dir), wal.WithSegmentFileExt(".SEG"))
+	require.Nil(t, err)
+	_, err = w.Write([]byte("hello"))
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+
+	out, stderr := captureOutput(t, func(out, errOut *os.File) int {
+		return verify(out, errOut, dir, ".SEG", nil)
+	})
+	assert.Equal(t, 0, out.code)
+	assert.Contains(t, out.text, "OK:")
+	assert.Empty(t, stderr.text)
+}
+
+func TestVerifyExitsOneOnCorruption(t *testing.T) {
+	dir, err := os.MkdirTemp("", "walverify-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := wal.Open(wal.WithDirPath(dir), wal.WithSegmentFileExt(".SEG"))
+	require.Nil(t, err)
+	_, err = w.Write([]byte("hello"))
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+
+	entries, err := os.ReadDir(dir)
+	require.Nil(t, err)
+	var segPath string
+	for _, e := range entries {
+		if filepath.Ext(e.Name()) == ".SEG" {
+			segPath = filepath.Join(dir, e.Name())
+		}
+	}
+	require.NotEmpty(t, segPath)
+
+	info, err := os.Stat(segPath)
+	require.Nil(t, err)
+	f, err := os.OpenFile(segPath, os.O_RDWR, 0o644)
+	require.Nil(t, err)
+	_, err = f.WriteAt([]byte{0xFF}, info.Size()-1)
+	require.Nil(t, err)
+	require.Nil(t, f.Close())
+
+	out, errOut := captureOutput(t, func(out, errOut *os.File) int {
+		return verify(out, errOut, dir, ".SEG", nil)
+	})
+	assert.Equal(t, 1, out.code)
+	assert.Contains(t, out.text, "CORRUPT:")
+	assert.Contains(t, errOut.text, "segment 1 offset 0")
+}
+
+func TestVerifyExitsTwoOnMissingDirectory(t *testing.T) {
+	out, _ := captureOutput(t, func(out, errOut *os.File) int {
+		return verify(out, errOut, filepath.Join(os.TempDir(), "does-not-exist-walverify"), ".SEG", nil)
+	})
+	assert.Equal(t, 2, out.code)
+}
+
+func TestVerifyExitsZeroOnValidSignatures(t *testing.T) {
+	dir, err := os.MkdirTemp("", "walverify-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.Nil(t, err)
+
+	w, err := wal.Open(wal.WithDirPath(dir), wal.WithSegmentFileExt(".SEG"), wal.WithSegmentSize(32), wal.WithSegmentSigning(priv))
+	require.Nil(t, err)
+	for i := 0; i < 6; i++ {
+		_, err := w.Write([]byte("0123456789"))
+		require.Nil(t, err)
+	}
+	require.Nil(t, w.Close())
+
+	out, errOut := captureOutput(t, func(out, errOut *os.File) int {
+		return verify(out, errOut, dir, ".SEG", pub)
+	})
+	assert.Equal(t, 0, out.code)
+	assert.Contains(t, out.text, "OK:")
+	assert.Contains(t, out.text, "SIGNATURES OK:")
+	assert.Empty(t, errOut.text)
+}
+
+func TestVerifyExitsOneOnTamperedSignature(t *testing.T) {
+	dir, err := os.MkdirTemp("", "walverify-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.Nil(t, err)
+	otherPub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.Nil(t, err)
+
+	w, err := wal.Open(wal.WithDirPath(dir), wal.WithSegmentFileExt(".SEG"), wal.WithSegmentSize(32), wal.WithSegmentSigning(priv))
+	require.Nil(t, err)
+	for i := 0; i < 6; i++ {
+		_, err := w.Write([]byte("0123456789"))
+		require.Nil(t, err)
+	}
+	require.Nil(t, w.Close())
+
+	out, errOut := captureOutput(t, func(out, errOut *os.File) int {
+		return verify(out, errOut, dir, ".SEG", otherPub)
+	})
+	assert.Equal(t, 1, out.code)
+	assert.Contains(t, out.text, "CORRUPT:")
+	assert.Contains(t, errOut.text, "signature check")
+}
+
+func TestVerifyExitsOneOnMissingSignature(t *testing.T) {
+	dir, err := os.MkdirTemp("", "walverify-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.Nil(t, err)
+
+	w, err := wal.Open(wal.WithDirPath(dir), wal.WithSegmentFileExt(".SEG"), wal.WithSegmentSize(32))
+	require.Nil(t, err)
+	for i := 0; i < 6; i++ {
+		_, err := w.Write([]byte("0123456789"))
+		require.Nil(t, err)
+	}
+	require.Nil(t, w.Close())
+
+	out, errOut := captureOutput(t, func(out, errOut *os.File) int {
+		return verify(out, errOut, dir, ".SEG", pub)
+	})
+	assert.Equal(t, 1, out.code)
+	assert.Contains(t, out.text, "CORRUPT:")
+	assert.Contains(t, errOut.text, "signature check")
+}
+
+// capturedOutput is what one of verify's output files accumulated.
+type capturedOutput struct {
+	text string
+	code int // only meaningful on the value returned for stdout
+}
+
+// captureOutput runs fn with two os.Pipe-backed files standing in for
+// stdout and stderr, and returns what was written to each along with fn's
+// return value, stashed on the stdout result's code field.
+func captureOutput(t *testing.T, fn func(out, errOut *os.File) int) (stdout, stderr capturedOutput) {
+	t.Helper()
+
+	outR, outW, err := os.Pipe()
+	require.Nil(t, err)
+	errR, errW, err := os.Pipe()
+	require.Nil(t, err)
+
+	code := fn(outW, errW)
+	require.Nil(t, outW.Close())
+	require.Nil(t, errW.Close())
+
+	outBytes, err := io.ReadAll(outR)
+	require.Nil(t, err)
+	errBytes, err := io.ReadAll(errR)
+	require.Nil(t, err)
+
+	return capturedOutput{string(outBytes), code}, capturedOutput{string(errBytes), 0}
+}
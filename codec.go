@@ -0,0 +1,39 @@
+package wal
+
+import (
+	"bytes"
+	"encoding/gob"
+)
+
+// Codec encodes a value of type T to the bytes Typed[T] writes to the WAL,
+// and decodes it back from the bytes Typed[T] reads. Decode must write
+// through v, the same way gob.Decoder.Decode and json.Unmarshal do, rather
+// than returning a new value, since a Codec doesn't know how to construct
+// one of its own.
+//
+// GobCodec is the built-in implementation; see walproto and walmsgpack for
+// protobuf- and msgpack-backed ones.
+type Codec[T any] interface {
+	Encode(v T) ([]byte, error)
+	Decode(data []byte, v *T) error
+}
+
+// GobCodec is a Codec that encodes with encoding/gob, requiring no schema
+// or generated code at the cost of gob's own compatibility rules -- most
+// notably, that a field renamed or removed from T changes what Decode can
+// read back from data written by an older build.
+type GobCodec[T any] struct{}
+
+// Encode implements Codec.
+func (GobCodec[T]) Encode(v T) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decode implements Codec.
+func (GobCodec[T]) Decode(data []byte, v *T) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
@@ -0,0 +1,223 @@
+package wal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Compact rewrites every sealed segment, keeping only the chunks
+// liveFilter reports as still live, and returns how every surviving
+// chunk's position changed, keyed by its old position, so a caller's own
+// index (e.g. rosedb's key->position map) can be updated to match. This
+// is how a long-running value log reclaims space from garbage that
+// accumulates between runs of Checkpoint, without an external rebuild of
+// the whole WAL.
+//
+// If onRelocate is non-nil, it's additionally called once for every
+// surviving chunk, as soon as that chunk's new position is known, with
+// its old and new position. This lets a caller update its own index
+// incrementally as Compact runs instead of waiting for the full
+// returned map, which still reflects every relocation in case the
+// caller would rather apply them as a single batch afterward; passing
+// nil skips this without affecting the returned map.
+//
+// Unlike Checkpoint, which folds sealed segments into one
+// checkpoint.NNNNNNNNN file and leaves every position it touches stale
+// (see Checkpoint's doc comment), Compact keeps rewriting segments in
+// place under the SegmentId they already have, preserving the invariant
+// that segments are created and filled in increasing ID order (see
+// ChunkPosition.Compare): a caller that applies the returned remap can
+// keep reading through Read exactly as before. A sealed segment left
+// with no live chunks at all is removed outright rather than replaced by
+// an empty one.
+//
+// Like Checkpoint, Compact only ever touches sealed segments: the active
+// segment is still being appended to, so there's nothing here it could
+// safely rewrite, and Compact never rotates it early just to make more
+// segments eligible. A second Compact or Checkpoint call is serialized
+// against this one by checkpointMu.
+func (w *WAL) Compact(liveFilter func(pos *ChunkPosition, data []byte) bool, onRelocate func(old, new *ChunkPosition)) (map[ChunkPosition]*ChunkPosition, error) {
+	if w.options.ReadOnly {
+		return nil, ErrReadOnly
+	}
+
+	w.checkpointMu.Lock()
+	defer w.checkpointMu.Unlock()
+
+	w.mu.RLock()
+	sealed := w.sealedSegmentsLocked()
+	w.mu.RUnlock()
+	if len(sealed) == 0 {
+		return map[ChunkPosition]*ChunkPosition{}, nil
+	}
+
+	remap := make(map[ChunkPosition]*ChunkPosition)
+	replacements := make(map[SegmentID]*segment, len(sealed))
+	originals := make(map[SegmentID]*segment, len(sealed))
+	originalPaths := make(map[SegmentID]string, len(sealed))
+	var emptied []SegmentID
+
+	var preceding uint64
+	for _, seg := range sealed {
+		chunkCount := seg.chunkCountSnapshot()
+		originals[seg.id] = seg
+		originalPaths[seg.id] = seg.path
+		newSeg, err := compactSegment(w, seg, preceding, liveFilter, onRelocate, remap)
+		if err != nil {
+			rollbackCompact(replacements, originals, originalPaths)
+			return nil, err
+		}
+		if newSeg == nil {
+			emptied = append(emptied, seg.id)
+		} else {
+			replacements[seg.id] = newSeg
+		}
+		preceding += uint64(chunkCount)
+	}
+
+	w.mu.Lock()
+	for id, newSeg := range replacements {
+		old := w.olderSegments[id]
+		old.retire(w.recyclePipeline())
+		w.olderSegments[id] = newSeg
+	}
+	for _, id := range emptied {
+		old := w.olderSegments[id]
+		old.retire(w.recyclePipeline())
+		delete(w.olderSegments, id)
+	}
+	w.options.MetricsCollector.SetActiveSegments(len(w.olderSegments) + 1)
+	w.enforceDiskWatermarksLocked()
+	w.publishSegmentsLocked()
+	w.mu.Unlock()
+
+	return remap, nil
+}
+
+// liveChunk is one chunk compactSegment decided to keep: its position in
+// the segment being replaced, and its payload.
+type liveChunk struct {
+	oldPos *ChunkPosition
+	data   []byte
+}
+
+// compactSegment rewrites one sealed segment, reading its chunks
+// unlocked (safe, since a sealed segment is never appended to again) and
+// keeping only the ones liveFilter approves. precedingChunks is the
+// number of chunks in every earlier segment, needed to reconstruct each
+// chunk's original Sequence the same way positionsInSegment does. It
+// returns the replacement segment, or nil if seg had no live chunks left
+// to keep, and records every kept chunk's old->new position in remap.
+//
+// seg's on-disk file is renamed aside to a private path before the
+// replacement is written and renamed into seg's old, now-vacated path,
+// so seg's own already-open fd keeps serving any read already in flight
+// against it (see segment.retire) while seg.path itself is updated to
+// point at that aside file, so retire's eventual cleanup removes the old
+// content instead of the replacement that has taken its place.
+func compactSegment(w *WAL, seg *segment, precedingChunks uint64, liveFilter func(*ChunkPosition, []byte) bool, onRelocate func(old, new *ChunkPosition), remap map[ChunkPosition]*ChunkPosition) (*segment, error) {
+	var live []liveChunk
+	var offset int64
+	seq := precedingChunks
+	for {
+		data, err := seg.Read(offset)
+		if err == ErrInvalidCRC {
+			return nil, err
+		}
+		if err != nil {
+			break // io.EOF or io.ErrUnexpectedEOF: nothing more to read
+		}
+		oldPos := &ChunkPosition{SegmentId: seg.id, ChunkOffset: offset, ChunkSize: uint32(len(data)), Sequence: seq}
+		if liveFilter(oldPos, data) {
+			live = append(live, liveChunk{oldPos: oldPos, data: data})
+		}
+		seq++
+		offset += int64(chunkHeaderSize) + int64(len(data))
+	}
+
+	if len(live) == 0 {
+		return nil, nil
+	}
+
+	originalPath := seg.path
+	stalePath := originalPath + ".stale"
+	if err := os.Rename(originalPath, stalePath); err != nil {
+		return nil, err
+	}
+	seg.path = stalePath
+
+	newSeg, err := buildReplacementSegment(w, seg.id, live, onRelocate, remap)
+	if err != nil {
+		os.Rename(stalePath, originalPath)
+		seg.path = originalPath
+		return nil, err
+	}
+	return newSeg, nil
+}
+
+// buildReplacementSegment writes live's chunks into a brand new segment
+// file and renames it into segID's now-vacated canonical path, preserving
+// every chunk's original Sequence (see positionsInSegment), recording its
+// new position in remap, and, if onRelocate is non-nil, reporting it
+// through onRelocate as soon as it's known.
+func buildReplacementSegment(w *WAL, segID SegmentID, live []liveChunk, onRelocate func(old, new *ChunkPosition), remap map[ChunkPosition]*ChunkPosition) (newSeg *segment, err error) {
+	// Named after segID, which is unique across this Compact call's sealed
+	// segments, rather than off a shared counter like filePipeline.alloc
+	// needs: there's no pool of these handed out concurrently to collide
+	// with.
+	tmpPath := filepath.Join(w.options.DirPath, fmt.Sprintf("compact-%d%s", segID, w.options.SegmentFileExt))
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	newSeg, err = newSegmentFile(tmp, w.options.DirPath, w.options.SegmentFileExt, segID, w.options.Mode, w.options.Fdatasync, w.options.Checksum, !w.options.SkipReadVerification, w.options.ChainChecksums, w.options.Compression, w.dictID, w.options.BlockSize, w.options.ChunkIndex, w.options.SegmentSigningKey, w.options.Clock, w.options.SegmentFileNameFunc, w.options.SegmentWriteBufferSize)
+	if err != nil {
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			newSeg.Close()
+			os.Remove(newSeg.path)
+			newSeg = nil
+		}
+	}()
+
+	for _, c := range live {
+		var newPos *ChunkPosition
+		if newPos, err = newSeg.Write(c.data); err != nil {
+			return nil, err
+		}
+		w.rewrittenBytes.Add(uint64(chunkHeaderSize + len(c.data)))
+		newPos.Sequence = c.oldPos.Sequence
+		newSeg.recordSequence(newPos.Sequence, newPos.ChunkOffset)
+		remap[*c.oldPos] = newPos
+		if onRelocate != nil {
+			onRelocate(c.oldPos, newPos)
+		}
+	}
+	if err = newSeg.Sync(); err != nil {
+		return nil, err
+	}
+	if err = newSeg.seal(); err != nil {
+		return nil, err
+	}
+	return newSeg, nil
+}
+
+// rollbackCompact undoes every successful compactSegment call made
+// earlier in a Compact run that ultimately failed on a later segment: it
+// closes and removes each prepared replacement, renames the original
+// segment's file (set aside in compactSegment) back into place, and
+// restores that original segment's path field, which compactSegment had
+// pointed at the now-undone aside file -- leaving the WAL exactly as it
+// was before Compact was called.
+func rollbackCompact(replacements map[SegmentID]*segment, originals map[SegmentID]*segment, originalPaths map[SegmentID]string) {
+	for id, newSeg := range replacements {
+		newSeg.Close()
+		os.Remove(newSeg.path)
+		os.Rename(originalPaths[id]+".stale", originalPaths[id])
+		originals[id].path = originalPaths[id]
+	}
+}
@@ -0,0 +1,147 @@
+package wal
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompactDropsDeadChunksAndRemapsSurvivors(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-compact-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(32))
+	require.Nil(t, err)
+	defer w.Close()
+
+	dead, err := w.Write([]byte("garbage value"))
+	require.Nil(t, err)
+	live, err := w.Write([]byte("live value"))
+	require.Nil(t, err)
+	for i := 0; i < 3; i++ {
+		_, err := w.Write([]byte("filler to force rotation"))
+		require.Nil(t, err)
+	}
+	require.Greater(t, len(w.olderSegments), 0)
+
+	wantLiveBytes, err := w.Read(live)
+	require.Nil(t, err)
+
+	remap, err := w.Compact(func(pos *ChunkPosition, data []byte) bool {
+		return !pos.Equal(dead)
+	}, nil)
+	require.Nil(t, err)
+
+	newLive, ok := remap[*live]
+	require.True(t, ok)
+	gotLiveBytes, err := w.Read(newLive)
+	require.Nil(t, err)
+	require.Equal(t, wantLiveBytes, gotLiveBytes)
+
+	_, ok = remap[*dead]
+	require.False(t, ok)
+
+	var seen []string
+	r := w.NewReader()
+	for {
+		data, _, err := r.Next()
+		if err != nil {
+			break
+		}
+		seen = append(seen, string(data))
+	}
+	require.NotContains(t, seen, "garbage value")
+	require.Contains(t, seen, "live value")
+}
+
+func TestCompactRemovesASegmentLeftWithNoLiveChunks(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-compact-empty-segment-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(32))
+	require.Nil(t, err)
+	defer w.Close()
+
+	emptiedID := w.activeSegment.id
+	var allDead []*ChunkPosition
+	pos, err := w.Write([]byte("0123456789"))
+	require.Nil(t, err)
+	allDead = append(allDead, pos)
+	for i := 0; i < 3; i++ {
+		_, err := w.Write([]byte("filler to force rotation"))
+		require.Nil(t, err)
+	}
+	require.NotEqual(t, emptiedID, w.activeSegment.id)
+	require.NotNil(t, w.segmentByID(emptiedID))
+
+	remap, err := w.Compact(func(pos *ChunkPosition, data []byte) bool {
+		return pos.SegmentId != emptiedID
+	}, nil)
+	require.Nil(t, err)
+
+	for _, pos := range allDead {
+		_, ok := remap[*pos]
+		require.False(t, ok)
+	}
+	require.Nil(t, w.segmentByID(emptiedID))
+}
+
+func TestCompactCallsOnRelocateForEverySurvivor(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-compact-onrelocate-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(32))
+	require.Nil(t, err)
+	defer w.Close()
+
+	dead, err := w.Write([]byte("garbage value"))
+	require.Nil(t, err)
+	live, err := w.Write([]byte("live value"))
+	require.Nil(t, err)
+	for i := 0; i < 3; i++ {
+		_, err := w.Write([]byte("filler to force rotation"))
+		require.Nil(t, err)
+	}
+	require.Greater(t, len(w.olderSegments), 0)
+
+	relocated := make(map[ChunkPosition]*ChunkPosition)
+	remap, err := w.Compact(func(pos *ChunkPosition, data []byte) bool {
+		return !pos.Equal(dead)
+	}, func(old, new *ChunkPosition) {
+		relocated[*old] = new
+	})
+	require.Nil(t, err)
+
+	require.Equal(t, remap, relocated)
+	_, ok := relocated[*dead]
+	require.False(t, ok)
+	_, ok = relocated[*live]
+	require.True(t, ok)
+}
+
+func TestCompactOnlyTouchesSealedSegments(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-compact-active-segment-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("active segment record"))
+	require.Nil(t, err)
+
+	remap, err := w.Compact(func(pos *ChunkPosition, data []byte) bool {
+		return false
+	}, nil)
+	require.Nil(t, err)
+	require.Empty(t, remap)
+
+	data, err := w.Read(&ChunkPosition{SegmentId: w.activeSegment.id, ChunkOffset: 0})
+	require.Nil(t, err)
+	require.Equal(t, "active segment record", string(data))
+}
@@ -0,0 +1,144 @@
+package wal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// CompressionCodec selects the algorithm WithCompression applies to chunk
+// payloads before they are written to a segment; Read and Reader.Next
+// reverse it on the way back out. A chunk that wouldn't shrink under the
+// codec -- already-compressed data, ciphertext, and other high-entropy
+// payloads -- is stored as-is instead, marked by a leading flag byte, so
+// enabling compression never grows a record that doesn't compress well.
+//
+// A WAL must be reopened with the same CompressionCodec it was written
+// with -- there is nothing in a chunk's own header recording which codec
+// (if any) compressed it. Open catches a mismatch at the segment level
+// instead, via the codec recorded in each segment's header (see
+// Options.Compression), and returns ErrCompressionMismatch rather than
+// letting Read or Reader.Next return garbage.
+type CompressionCodec byte
+
+const (
+	// CompressionNone writes and reads chunk payloads unmodified. It is the
+	// default.
+	CompressionNone CompressionCodec = iota
+
+	// CompressionSnappy compresses chunk payloads with Snappy, favoring
+	// speed over compression ratio.
+	CompressionSnappy
+
+	// CompressionZstd compresses chunk payloads with zstd, favoring
+	// compression ratio over speed.
+	CompressionZstd
+)
+
+// rawFlag and compressedFlag are the leading byte compressPayload prepends
+// to any CompressionSnappy or CompressionZstd payload, marking whether
+// what follows actually went through the codec or was stored as-is
+// because doing so didn't come out smaller -- already-compressed data
+// (images, ciphertext) and other high-entropy payloads reliably land in
+// the latter case, and paying a codec's per-chunk overhead for them would
+// only grow the record instead of shrinking it. CompressionNone needs
+// neither flag nor overhead, since there is no codec output to compare
+// data against in the first place.
+const (
+	rawFlag        byte = 0
+	compressedFlag byte = 1
+)
+
+// compressPayload compresses data with codec, appending the result to dst
+// (which may be nil) and returning the grown slice the way append does, so
+// a caller pooling scratch buffers (see Options.BufferPool) can reuse dst's
+// backing array instead of a fresh allocation when it has the capacity.
+// dict, if non-empty, is a zstd dictionary (see Options.CompressionDict);
+// it is ignored for every codec but CompressionZstd.
+func compressPayload(codec CompressionCodec, data, dst, dict []byte) ([]byte, error) {
+	var compressed []byte
+	switch codec {
+	case CompressionNone:
+		return data, nil
+	case CompressionSnappy:
+		compressed = snappy.Encode(nil, data)
+	case CompressionZstd:
+		var opts []zstd.EOption
+		if len(dict) > 0 {
+			opts = append(opts, zstd.WithEncoderDict(dict))
+		}
+		enc, err := zstd.NewWriter(nil, opts...)
+		if err != nil {
+			return nil, err
+		}
+		defer enc.Close()
+		compressed = enc.EncodeAll(data, nil)
+	default:
+		return nil, fmt.Errorf("wal: unknown compression codec %d", codec)
+	}
+
+	if len(compressed) < len(data) {
+		return append(append(dst[:0], compressedFlag), compressed...), nil
+	}
+	return append(append(dst[:0], rawFlag), data...), nil
+}
+
+// decompressPayload reverses compressPayload; dict must be the same
+// dictionary, if any, data was compressed with.
+func decompressPayload(codec CompressionCodec, data, dict []byte) ([]byte, error) {
+	if codec == CompressionNone {
+		return data, nil
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("wal: compressed chunk payload is empty, missing its leading raw/compressed flag byte")
+	}
+	flag, body := data[0], data[1:]
+	if flag == rawFlag {
+		return body, nil
+	}
+	switch codec {
+	case CompressionSnappy:
+		return snappy.Decode(nil, body)
+	case CompressionZstd:
+		var opts []zstd.DOption
+		if len(dict) > 0 {
+			opts = append(opts, zstd.WithDecoderDicts(dict))
+		}
+		dec, err := zstd.NewReader(nil, opts...)
+		if err != nil {
+			return nil, err
+		}
+		defer dec.Close()
+		return dec.DecodeAll(body, nil)
+	default:
+		return nil, fmt.Errorf("wal: unknown compression codec %d", codec)
+	}
+}
+
+// zstdDictMagic is the 4-byte magic number at the start of a dictionary in
+// the format "zstd --train" (or zstd.BuildDict) produces -- the same format
+// WithCompressionDict, zstd.WithEncoderDict, and zstd.WithDecoderDicts all
+// expect.
+var zstdDictMagic = []byte{0x37, 0xa4, 0x30, 0xec}
+
+// ErrInvalidCompressionDict is returned by Open when Options.CompressionDict
+// is set but doesn't start with zstdDictMagic -- most often a raw sample of
+// data handed to WithCompressionDict directly instead of the dictionary
+// zstd.BuildDict (or the "zstd --train" CLI) trains from a corpus of such
+// samples.
+var ErrInvalidCompressionDict = fmt.Errorf("wal: CompressionDict is not a valid zstd dictionary (see zstd.BuildDict or \"zstd --train\")")
+
+// zstdDictID extracts the ID a properly-formatted zstd dictionary carries
+// in its own header -- the same ID the encoder and decoder use internally
+// to tell dictionaries apart -- so that ID, rather than one this package
+// invented by hashing the dictionary's bytes, is what gets recorded in a
+// segment's header (see dictIDOffset) and compared on a later Open.
+func zstdDictID(dict []byte) (uint32, error) {
+	if len(dict) < 8 || !bytes.Equal(dict[:4], zstdDictMagic) {
+		return 0, ErrInvalidCompressionDict
+	}
+	return binary.LittleEndian.Uint32(dict[4:8]), nil
+}
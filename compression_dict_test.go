@@ -0,0 +1,97 @@
+package wal
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/require"
+)
+
+// buildTestDict returns a trained zstd dictionary -- in the same format
+// "zstd --train" or zstd.BuildDict produces -- with the given ID, for tests
+// that need real dictionary bytes rather than a hand-rolled magic number.
+func buildTestDict(t *testing.T, id uint32) []byte {
+	t.Helper()
+	dict, err := zstd.BuildDict(zstd.BuildDictOptions{
+		ID:       id,
+		Contents: [][]byte{[]byte("sample record one"), []byte("sample record two"), []byte("sample record three")},
+		History:  bytes.Repeat([]byte("a sample record used to train the dictionary "), 4),
+		Offsets:  [3]int{1, 4, 8},
+	})
+	require.Nil(t, err)
+	return dict
+}
+
+func TestWriteReadWithCompressionDict(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-compression-dict-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	dict := buildTestDict(t, 7)
+	w, err := Open(WithDirPath(dir), WithCompression(CompressionZstd), WithCompressionDict(dict))
+	require.Nil(t, err)
+	defer w.Close()
+
+	payload := bytes.Repeat([]byte("sample record "), 20)
+	pos, err := w.Write(payload)
+	require.Nil(t, err)
+
+	got, err := w.Read(pos)
+	require.Nil(t, err)
+	require.Equal(t, payload, got)
+}
+
+func TestOpenRejectsCompressionDictWithoutZstd(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-compression-dict-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	dict := buildTestDict(t, 7)
+	_, err = Open(WithDirPath(dir), WithCompression(CompressionSnappy), WithCompressionDict(dict))
+	require.ErrorContains(t, err, "Options.CompressionDict")
+}
+
+func TestOpenRejectsMalformedCompressionDict(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-compression-dict-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	_, err = Open(WithDirPath(dir), WithCompression(CompressionZstd), WithCompressionDict([]byte("not a dictionary")))
+	require.ErrorIs(t, err, ErrInvalidCompressionDict)
+}
+
+func TestCompressionDictMismatchOnReopen(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-compression-dict-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithCompression(CompressionZstd), WithCompressionDict(buildTestDict(t, 7)))
+	require.Nil(t, err)
+	_, err = w.Write([]byte("hello"))
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+
+	_, err = Open(WithDirPath(dir), WithCompression(CompressionZstd), WithCompressionDict(buildTestDict(t, 9)))
+	require.ErrorIs(t, err, ErrCompressionDictMismatch)
+
+	_, err = Open(WithDirPath(dir), WithCompression(CompressionZstd))
+	require.ErrorIs(t, err, ErrCompressionDictMismatch)
+}
+
+func TestSegmentsWithoutCompressionDictStayOpenable(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-compression-dict-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithCompression(CompressionZstd))
+	require.Nil(t, err)
+	_, err = w.Write([]byte("hello"))
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+
+	w2, err := Open(WithDirPath(dir), WithCompression(CompressionZstd))
+	require.Nil(t, err)
+	require.Nil(t, w2.Close())
+}
@@ -0,0 +1,38 @@
+package wal
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteAllCompressesBatchInParallel(t *testing.T) {
+	for _, codec := range []CompressionCodec{CompressionSnappy, CompressionZstd} {
+		dir, err := os.MkdirTemp("", "wal-compression-parallel-test")
+		require.Nil(t, err)
+
+		w, err := Open(WithDirPath(dir), WithCompression(codec), WithCompressionParallelism(4))
+		require.Nil(t, err)
+
+		want := make([][]byte, 50)
+		for i := range want {
+			want[i] = bytes.Repeat([]byte(fmt.Sprintf("batch entry %d ", i)), 20)
+			require.Nil(t, w.PendingWrites(want[i]))
+		}
+		positions, err := w.WriteAll()
+		require.Nil(t, err)
+		require.Len(t, positions, len(want))
+
+		for i, pos := range positions {
+			got, err := w.Read(pos)
+			require.Nil(t, err)
+			require.Equal(t, want[i], got)
+		}
+
+		require.Nil(t, w.Close())
+		require.Nil(t, os.RemoveAll(dir))
+	}
+}
@@ -0,0 +1,63 @@
+package wal
+
+import (
+	"bytes"
+	"crypto/rand"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIncompressiblePayloadIsStoredRaw(t *testing.T) {
+	for _, codec := range []CompressionCodec{CompressionSnappy, CompressionZstd} {
+		dir, err := os.MkdirTemp("", "wal-compression-test")
+		require.Nil(t, err)
+
+		w, err := Open(WithDirPath(dir), WithCompression(codec))
+		require.Nil(t, err)
+
+		// A payload snappy and zstd both can't shrink -- already
+		// high-entropy, courtesy of crypto/rand -- should round-trip via
+		// the raw-fallback path rather than being stored compressed.
+		payload := make([]byte, 4096)
+		_, err = rand.Read(payload)
+		require.Nil(t, err)
+
+		pos, err := w.Write(payload)
+		require.Nil(t, err)
+
+		got, err := w.Read(pos)
+		require.Nil(t, err)
+		require.Equal(t, payload, got)
+
+		require.Nil(t, w.Close())
+		require.Nil(t, os.RemoveAll(dir))
+	}
+}
+
+func TestWriteReadWithCompression(t *testing.T) {
+	for _, codec := range []CompressionCodec{CompressionSnappy, CompressionZstd} {
+		dir, err := os.MkdirTemp("", "wal-compression-test")
+		require.Nil(t, err)
+
+		w, err := Open(WithDirPath(dir), WithCompression(codec))
+		require.Nil(t, err)
+
+		payload := bytes.Repeat([]byte("compress me please "), 100)
+		pos, err := w.Write(payload)
+		require.Nil(t, err)
+
+		got, err := w.Read(pos)
+		require.Nil(t, err)
+		require.Equal(t, payload, got)
+
+		r := w.NewReader()
+		got, _, err = r.Next()
+		require.Nil(t, err)
+		require.Equal(t, payload, got)
+
+		require.Nil(t, w.Close())
+		require.Nil(t, os.RemoveAll(dir))
+	}
+}
@@ -0,0 +1,32 @@
+package wal
+
+import "context"
+
+// WriteContext is Write, but checks ctx before doing any work and again
+// just before the (possibly blocking, possibly fsync-ing) write itself, so a
+// caller stuck behind a slow fsync or a full disk can bail out with ctx's
+// error instead of waiting on Write indefinitely.
+//
+// Cancellation after the write has started is best-effort: Go's os.File has
+// no way to interrupt an in-flight Write or fsync, so once past the second
+// check, WriteContext runs exactly like Write and ctx is not consulted
+// again. It does not participate in Options.GroupCommit: like WriteAll, it
+// always fsyncs inline under the lock it holds for the whole call.
+func (w *WAL) WriteContext(ctx context.Context, data []byte) (*ChunkPosition, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return nil, ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	pos, _, err := w.write(data, false)
+	return pos, err
+}
@@ -0,0 +1,42 @@
+package wal
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteContextCancelledBeforeWrite(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-write-context-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = w.WriteContext(ctx, []byte("hello"))
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestWriteContextSucceeds(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-write-context-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	pos, err := w.WriteContext(context.Background(), []byte("hello"))
+	require.Nil(t, err)
+
+	data, err := w.Read(pos)
+	require.Nil(t, err)
+	require.Equal(t, []byte("hello"), data)
+}
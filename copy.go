@@ -0,0 +1,51 @@
+package wal
+
+import "io"
+
+// CopyTo rewrites every record up to, but not including, upTo (or the
+// whole log if upTo is nil) into a fresh WAL at dstDir, opened with opts
+// -- typically a different WithSegmentSize or other option than this
+// WAL's own, since re-chunking under a changed option is exactly what
+// hand-rolled reader/writer code would otherwise be needed for. It
+// returns the new WAL still open, since migrating to new option values
+// usually means keeping it around to write to afterward rather than
+// closing it right away.
+//
+// Records are copied through Reader.Next and Write, not byte for byte:
+// each one is decoded under this WAL's own encryption and compression
+// settings and re-encoded under dstDir's, so CopyTo also works for
+// migrating those, not just SegmentSize. Any WriteWithFlags, WriteWithTTL,
+// or Batch framing a record carries is opaque to Write and Reader.Next
+// alike, so it comes along unchanged either way -- though a WriteWithTTL
+// record arrives at dst through plain Write, so dst's Options.
+// DropExpiredSegments won't know to retire the segment it lands in early
+// purely on that record's account the way it would have in w.
+func (w *WAL) CopyTo(dstDir string, upTo *ChunkPosition, opts ...Option) (*WAL, error) {
+	dst, err := Open(append(opts, WithDirPath(dstDir))...)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := w.NewReaderWithRange(nil, upTo)
+	if err != nil {
+		dst.Close()
+		return nil, err
+	}
+
+	for {
+		data, _, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			dst.Close()
+			return nil, err
+		}
+		if _, err := dst.Write(data); err != nil {
+			dst.Close()
+			return nil, err
+		}
+	}
+
+	return dst, nil
+}
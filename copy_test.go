@@ -0,0 +1,116 @@
+package wal
+
+import (
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCopyToRewritesTheWholeLogUnderNewOptions(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-copyto-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	for i := 0; i < 20; i++ {
+		_, err := w.Write([]byte{byte('a' + i)})
+		require.Nil(t, err)
+	}
+
+	dstDir, err := os.MkdirTemp("", "wal-copyto-dst")
+	require.Nil(t, err)
+	defer os.RemoveAll(dstDir)
+	require.Nil(t, os.RemoveAll(dstDir))
+
+	dst, err := w.CopyTo(dstDir, nil, WithSegmentSize(32))
+	require.Nil(t, err)
+	defer dst.Close()
+
+	r := dst.NewReader()
+	var got []byte
+	var segments = map[SegmentID]bool{}
+	for {
+		data, pos, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		require.Nil(t, err)
+		got = append(got, data...)
+		segments[pos.SegmentId] = true
+	}
+	require.Equal(t, "abcdefghijklmnopqrst", string(got))
+	require.Greater(t, len(segments), 1)
+}
+
+func TestCopyToStopsAtUpTo(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-copyto-upto-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	var stopAt *ChunkPosition
+	for i := 0; i < 5; i++ {
+		pos, err := w.Write([]byte{byte('a' + i)})
+		require.Nil(t, err)
+		if i == 2 {
+			stopAt = pos
+		}
+	}
+
+	dstDir, err := os.MkdirTemp("", "wal-copyto-upto-dst")
+	require.Nil(t, err)
+	defer os.RemoveAll(dstDir)
+	require.Nil(t, os.RemoveAll(dstDir))
+
+	dst, err := w.CopyTo(dstDir, stopAt)
+	require.Nil(t, err)
+	defer dst.Close()
+
+	r := dst.NewReader()
+	var got []byte
+	for {
+		data, _, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		require.Nil(t, err)
+		got = append(got, data...)
+	}
+	require.Equal(t, "ab", string(got))
+}
+
+func TestCopyToPreservesFlagsAndTTLFraming(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-copyto-flags-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.WriteWithFlags([]byte("flagged"), 7)
+	require.Nil(t, err)
+
+	dstDir, err := os.MkdirTemp("", "wal-copyto-flags-dst")
+	require.Nil(t, err)
+	defer os.RemoveAll(dstDir)
+	require.Nil(t, os.RemoveAll(dstDir))
+
+	dst, err := w.CopyTo(dstDir, nil)
+	require.Nil(t, err)
+	defer dst.Close()
+
+	r := dst.NewReader()
+	data, flags, _, err := r.NextWithFlags()
+	require.Nil(t, err)
+	require.Equal(t, byte(7), flags)
+	require.Equal(t, []byte("flagged"), data)
+}
@@ -0,0 +1,133 @@
+package wal
+
+import "encoding/binary"
+
+// SegmentDigest returns a whole-segment digest of the sealed segment
+// identified by id: the same whole-segment checksum, under the WAL's own
+// Options.Checksum algorithm, that seal recorded in the segment's footer
+// when it rotated out of being active (see footer.checksum). Two replicas
+// holding byte-identical data for a segment always compute the same
+// digest, so comparing SegmentDigest(id) between them confirms they agree
+// on it without transferring or rereading the segment itself.
+//
+// It returns errSegmentNotFound(id) if no segment with that ID currently
+// exists, ErrSegmentNotSealed if id names the still-active segment, and
+// ErrFooterMissing for a sealed segment with no footer to read a digest
+// from. See LogDigest to combine every segment's digest, up to a given
+// position, into one.
+func (w *WAL) SegmentDigest(id SegmentID) (uint32, error) {
+	view := w.segmentsView.Load()
+	if view == nil {
+		return 0, errSegmentNotFound(id)
+	}
+	if view.active != nil && view.active.id == id {
+		return 0, ErrSegmentNotSealed
+	}
+	seg, ok := view.older[id]
+	if !ok {
+		return 0, errSegmentNotFound(id)
+	}
+
+	if !seg.acquire() {
+		return 0, errSegmentNotFound(id)
+	}
+	defer seg.release()
+
+	info, err := seg.fd.Stat()
+	if err != nil {
+		return 0, err
+	}
+	ft, ok, err := readFooter(seg.fd, info.Size())
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 0, ErrFooterMissing
+	}
+	return ft.checksum, nil
+}
+
+// LogDigest returns a single digest summarizing every byte of every chunk
+// up to and including upTo, so two replicas whose LogDigest(upTo) agree
+// can be confident they hold byte-identical logs up to that point -- all
+// the way back to the oldest segment either still holds -- without
+// transferring or rereading any of it themselves.
+//
+// It folds each segment's own digest into the last, oldest to newest, the
+// same way Options.ChainChecksums folds one chunk's checksum into the
+// next (see chainedChecksumPayload): every segment older than
+// upTo.SegmentId contributes its SegmentDigest, and upTo.SegmentId itself
+// -- sealed or still active -- contributes a digest computed fresh over
+// just its bytes through upTo's own chunk, since a still-active segment
+// has no footer to read one from and a sealed one's footer only covers
+// the whole segment, not an arbitrary prefix of it.
+//
+// It returns errSegmentNotFound(upTo.SegmentId) if the WAL no longer
+// holds that segment -- most likely because a Checkpoint or retention
+// policy has already retired it -- and ErrSegmentNotSealed if a segment
+// older than upTo.SegmentId is somehow still active, which would mean
+// upTo names a position no chunk has actually reached yet.
+func (w *WAL) LogDigest(upTo *ChunkPosition) (uint32, error) {
+	w.mu.RLock()
+	segments := w.orderedSegmentsLocked()
+	w.mu.RUnlock()
+
+	var digest uint32
+	var found bool
+	for _, seg := range segments {
+		if seg.id > upTo.SegmentId {
+			break
+		}
+		if seg.id < upTo.SegmentId {
+			segDigest, err := w.SegmentDigest(seg.id)
+			if err != nil {
+				return 0, err
+			}
+			digest = foldDigest(w.options.Checksum, digest, segDigest)
+			continue
+		}
+
+		found = true
+		partial, err := partialSegmentDigest(seg, upTo)
+		if err != nil {
+			return 0, err
+		}
+		digest = foldDigest(w.options.Checksum, digest, partial)
+	}
+	if !found {
+		return 0, errSegmentNotFound(upTo.SegmentId)
+	}
+	return digest, nil
+}
+
+// foldDigest combines next -- one segment's whole or partial digest --
+// into prev, the running digest of every segment before it, via the same
+// chained-checksum construction Options.ChainChecksums uses for chunks.
+func foldDigest(algo Checksum, prev uint32, next uint32) uint32 {
+	buf := make([]byte, 4)
+	binary.BigEndian.PutUint32(buf, next)
+	return chainedChecksumPayload(algo, prev, buf)
+}
+
+// partialSegmentDigest computes seg's digest through the end of upTo's own
+// chunk -- upTo must name a chunk within seg -- without relying on a
+// footer, since seg may still be the active segment. Like SegmentDigest,
+// it checksums under seg's own recorded Checksum algorithm (see
+// openSegmentFile), not whatever the WAL is currently configured with: a
+// segment is always read back with the algorithm it was written with
+// (see checksum.go), and computing its digest any other way would make
+// the same segment's bytes hash differently depending on which API -- or
+// which later reopen -- computed it.
+func partialSegmentDigest(seg *segment, upTo *ChunkPosition) (uint32, error) {
+	if !seg.acquire() {
+		return 0, errSegmentNotFound(seg.id)
+	}
+	defer seg.release()
+
+	through := upTo.ChunkOffset + int64(chunkHeaderSize) + int64(upTo.ChunkSize)
+	payload := make([]byte, through)
+	if _, err := seg.fd.ReadAt(payload, segmentHeaderSize); err != nil {
+		return 0, err
+	}
+	return checksumPayload(seg.checksum, payload), nil
+}
@@ -0,0 +1,233 @@
+package wal
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSegmentDigestMatchesAcrossIdenticalCopies(t *testing.T) {
+	dir1, err := os.MkdirTemp("", "wal-digest-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir1)
+	dir2, err := os.MkdirTemp("", "wal-digest-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir2)
+
+	for _, dir := range []string{dir1, dir2} {
+		w, err := Open(WithDirPath(dir), WithSegmentSize(32))
+		require.Nil(t, err)
+		for i := 0; i < 6; i++ {
+			_, err := w.Write([]byte("0123456789"))
+			require.Nil(t, err)
+		}
+		require.Nil(t, w.Close())
+	}
+
+	w1, err := Open(WithDirPath(dir1), WithSegmentSize(32))
+	require.Nil(t, err)
+	defer w1.Close()
+	w2, err := Open(WithDirPath(dir2), WithSegmentSize(32))
+	require.Nil(t, err)
+	defer w2.Close()
+
+	require.Greater(t, len(w1.olderSegments), 0, "test needs at least one sealed segment")
+
+	for id := range w1.olderSegments {
+		digest1, err := w1.SegmentDigest(id)
+		require.Nil(t, err)
+		digest2, err := w2.SegmentDigest(id)
+		require.Nil(t, err)
+		require.Equal(t, digest1, digest2)
+	}
+}
+
+func TestSegmentDigestDetectsDivergence(t *testing.T) {
+	dir1, err := os.MkdirTemp("", "wal-digest-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir1)
+	dir2, err := os.MkdirTemp("", "wal-digest-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir2)
+
+	w1, err := Open(WithDirPath(dir1), WithSegmentSize(32))
+	require.Nil(t, err)
+	w2, err := Open(WithDirPath(dir2), WithSegmentSize(32))
+	require.Nil(t, err)
+	for i := 0; i < 6; i++ {
+		_, err := w1.Write([]byte("0123456789"))
+		require.Nil(t, err)
+		suffix := byte('a' + i)
+		_, err = w2.Write([]byte{'0', '1', '2', '3', '4', '5', '6', '7', '8', suffix})
+		require.Nil(t, err)
+	}
+	require.Nil(t, w1.Close())
+	require.Nil(t, w2.Close())
+
+	w1, err = Open(WithDirPath(dir1), WithSegmentSize(32))
+	require.Nil(t, err)
+	defer w1.Close()
+	w2, err = Open(WithDirPath(dir2), WithSegmentSize(32))
+	require.Nil(t, err)
+	defer w2.Close()
+
+	require.Greater(t, len(w1.olderSegments), 0, "test needs at least one sealed segment")
+	for id := range w1.olderSegments {
+		digest1, err := w1.SegmentDigest(id)
+		require.Nil(t, err)
+		digest2, err := w2.SegmentDigest(id)
+		require.Nil(t, err)
+		require.NotEqual(t, digest1, digest2)
+	}
+}
+
+func TestSegmentDigestOnActiveSegmentReturnsErrSegmentNotSealed(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-digest-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("hello"))
+	require.Nil(t, err)
+
+	_, err = w.SegmentDigest(w.activeSegment.id)
+	require.ErrorIs(t, err, ErrSegmentNotSealed)
+}
+
+func TestSegmentDigestOnUnknownSegmentReturnsErrSegmentNotFound(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-digest-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.SegmentDigest(9999)
+	require.ErrorIs(t, err, ErrSegmentNotFound)
+}
+
+func TestLogDigestMatchesAcrossIdenticalCopiesAndCatchesDivergence(t *testing.T) {
+	dir1, err := os.MkdirTemp("", "wal-digest-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir1)
+	dir2, err := os.MkdirTemp("", "wal-digest-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir2)
+
+	w1, err := Open(WithDirPath(dir1), WithSegmentSize(32))
+	require.Nil(t, err)
+	w2, err := Open(WithDirPath(dir2), WithSegmentSize(32))
+	require.Nil(t, err)
+
+	var lastPos1, lastPos2 *ChunkPosition
+	for i := 0; i < 10; i++ {
+		lastPos1, err = w1.Write([]byte("0123456789"))
+		require.Nil(t, err)
+		lastPos2, err = w2.Write([]byte("0123456789"))
+		require.Nil(t, err)
+	}
+	require.Equal(t, lastPos1, lastPos2)
+
+	digest1, err := w1.LogDigest(lastPos1)
+	require.Nil(t, err)
+	digest2, err := w2.LogDigest(lastPos2)
+	require.Nil(t, err)
+	require.Equal(t, digest1, digest2)
+	require.Nil(t, w1.Close())
+	require.Nil(t, w2.Close())
+}
+
+// TestLogDigestCatchesDivergenceInAnOlderSealedSegment confirms that
+// LogDigest folds every preceding sealed segment's own digest into the
+// result, not just upTo's own segment: two logs that diverge in an
+// earlier segment but hold identical bytes from there on still get
+// different LogDigest(upTo) results at the same later position.
+func TestLogDigestCatchesDivergenceInAnOlderSealedSegment(t *testing.T) {
+	dir1, err := os.MkdirTemp("", "wal-digest-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir1)
+	dir2, err := os.MkdirTemp("", "wal-digest-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir2)
+
+	w1, err := Open(WithDirPath(dir1), WithSegmentSize(32))
+	require.Nil(t, err)
+	w2, err := Open(WithDirPath(dir2), WithSegmentSize(32))
+	require.Nil(t, err)
+
+	_, err = w1.Write([]byte("0123456789"))
+	require.Nil(t, err)
+	_, err = w2.Write([]byte("9876543210")) // diverges from w1 in the first segment
+	require.Nil(t, err)
+
+	var lastPos1, lastPos2 *ChunkPosition
+	for i := 0; i < 9; i++ {
+		lastPos1, err = w1.Write([]byte("0123456789"))
+		require.Nil(t, err)
+		lastPos2, err = w2.Write([]byte("0123456789"))
+		require.Nil(t, err)
+	}
+	require.Equal(t, lastPos1, lastPos2, "same layout, so the two logs agree on where the last chunk landed")
+
+	digest1, err := w1.LogDigest(lastPos1)
+	require.Nil(t, err)
+	digest2, err := w2.LogDigest(lastPos2)
+	require.Nil(t, err)
+	require.NotEqual(t, digest1, digest2)
+
+	require.Nil(t, w1.Close())
+	require.Nil(t, w2.Close())
+}
+
+// TestLogDigestOnFuturePositionReturnsErrSegmentNotSealed confirms that
+// naming a position past everything actually written surfaces as
+// ErrSegmentNotSealed, from the active segment LogDigest reaches trying
+// to fold in its (nonexistent) predecessors' digests, rather than as a
+// misleading errSegmentNotFound naming a segment that was never created
+// in the first place.
+func TestLogDigestOnFuturePositionReturnsErrSegmentNotSealed(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-digest-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("hello"))
+	require.Nil(t, err)
+
+	_, err = w.LogDigest(&ChunkPosition{SegmentId: 9999})
+	require.ErrorIs(t, err, ErrSegmentNotSealed)
+}
+
+func TestLogDigestOnRetiredSegmentReturnsErrSegmentNotFound(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-digest-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(32))
+	require.Nil(t, err)
+	defer w.Close()
+
+	var positions []*ChunkPosition
+	for i := 0; i < 6; i++ {
+		pos, err := w.Write([]byte("0123456789"))
+		require.Nil(t, err)
+		positions = append(positions, pos)
+	}
+	require.Greater(t, len(w.olderSegments), 0, "test needs at least one sealed segment to retire")
+	firstID := positions[0].SegmentId
+
+	require.Nil(t, w.TruncateBefore(positions[len(positions)-1]))
+	_, stillThere := w.olderSegments[firstID]
+	require.False(t, stillThere, "test needs TruncateBefore to have actually retired the first segment")
+
+	_, err = w.LogDigest(positions[0])
+	require.ErrorIs(t, err, ErrSegmentNotFound)
+}
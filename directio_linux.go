@@ -0,0 +1,168 @@
+//go:build linux
+
+package wal
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// openFileDirectIO opens path like os.OpenFile, but adds O_DIRECT so the
+// kernel bypasses its page cache for this fd. If the filesystem underneath
+// path doesn't support O_DIRECT at all -- tmpfs, for one, rejects it right
+// at open -- it falls back to a normal buffered open immediately. Either
+// way the result is wrapped in a directIOFile, since this package's chunk
+// writes aren't padded to the filesystem's logical block size: an
+// operation that violates O_DIRECT's alignment requirements only fails at
+// read/write time, not at open time, and directIOFile downgrades to
+// buffered I/O the first time that happens instead of failing the caller.
+func openFileDirectIO(path string, flag int, perm os.FileMode) (File, error) {
+	f, err := os.OpenFile(path, flag|unix.O_DIRECT, perm)
+	if err != nil {
+		f, err = os.OpenFile(path, flag, perm)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &directIOFile{file: f, path: path, flag: flag, perm: perm}, nil
+}
+
+// directIOFile wraps a file possibly opened with O_DIRECT, transparently
+// downgrading to a plain buffered fd the first time a read or write
+// returns EINVAL -- the signature of an operation that didn't meet
+// O_DIRECT's alignment requirements on this filesystem. Every operation
+// after that goes through the buffered fd instead, for the rest of this
+// directIOFile's lifetime.
+type directIOFile struct {
+	mu   sync.RWMutex
+	file *os.File
+	path string
+	flag int
+	perm os.FileMode
+}
+
+func isAlignmentError(err error) bool {
+	return errors.Is(err, syscall.EINVAL)
+}
+
+// downgrade reopens f.path without O_DIRECT, seeks the replacement to
+// wherever the O_DIRECT fd's sequential position was, and swaps it in.
+func (f *directIOFile) downgrade() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	pos, err := f.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	buffered, err := os.OpenFile(f.path, f.flag, f.perm)
+	if err != nil {
+		return err
+	}
+	if _, err := buffered.Seek(pos, io.SeekStart); err != nil {
+		buffered.Close()
+		return err
+	}
+	f.file.Close()
+	f.file = buffered
+	return nil
+}
+
+func (f *directIOFile) Write(p []byte) (int, error) {
+	f.mu.RLock()
+	n, err := f.file.Write(p)
+	f.mu.RUnlock()
+	if isAlignmentError(err) {
+		if derr := f.downgrade(); derr != nil {
+			return n, err
+		}
+		f.mu.RLock()
+		defer f.mu.RUnlock()
+		return f.file.Write(p)
+	}
+	return n, err
+}
+
+func (f *directIOFile) WriteAt(p []byte, off int64) (int, error) {
+	f.mu.RLock()
+	n, err := f.file.WriteAt(p, off)
+	f.mu.RUnlock()
+	if isAlignmentError(err) {
+		if derr := f.downgrade(); derr != nil {
+			return n, err
+		}
+		f.mu.RLock()
+		defer f.mu.RUnlock()
+		return f.file.WriteAt(p, off)
+	}
+	return n, err
+}
+
+func (f *directIOFile) Read(p []byte) (int, error) {
+	f.mu.RLock()
+	n, err := f.file.Read(p)
+	f.mu.RUnlock()
+	if isAlignmentError(err) {
+		if derr := f.downgrade(); derr != nil {
+			return n, err
+		}
+		f.mu.RLock()
+		defer f.mu.RUnlock()
+		return f.file.Read(p)
+	}
+	return n, err
+}
+
+func (f *directIOFile) ReadAt(p []byte, off int64) (int, error) {
+	f.mu.RLock()
+	n, err := f.file.ReadAt(p, off)
+	f.mu.RUnlock()
+	if isAlignmentError(err) {
+		if derr := f.downgrade(); derr != nil {
+			return n, err
+		}
+		f.mu.RLock()
+		defer f.mu.RUnlock()
+		return f.file.ReadAt(p, off)
+	}
+	return n, err
+}
+
+func (f *directIOFile) Seek(offset int64, whence int) (int64, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.file.Seek(offset, whence)
+}
+
+func (f *directIOFile) Close() error {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.file.Close()
+}
+
+func (f *directIOFile) Sync() error {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.file.Sync()
+}
+
+func (f *directIOFile) Stat() (os.FileInfo, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.file.Stat()
+}
+
+func (f *directIOFile) Name() string {
+	return f.path
+}
+
+func (f *directIOFile) Truncate(size int64) error {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.file.Truncate(size)
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+package wal
+
+import "os"
+
+// openFileDirectIO is openFileDirectIO, but O_DIRECT has no portable
+// equivalent outside Linux in this package, so Options.DirectIO falls back
+// to a normal buffered open on every other platform.
+func openFileDirectIO(path string, flag int, perm os.FileMode) (*os.File, error) {
+	return os.OpenFile(path, flag, perm)
+}
@@ -0,0 +1,51 @@
+package wal
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirectIOFallsBackAndWritesReadBack(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-directio-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(64), WithDirectIO(true))
+	require.Nil(t, err)
+	defer w.Close()
+
+	var positions []*ChunkPosition
+	for i := 0; i < 10; i++ {
+		pos, err := w.Write([]byte{byte('a' + i)})
+		require.Nil(t, err)
+		positions = append(positions, pos)
+	}
+
+	for i, pos := range positions {
+		data, err := w.Read(pos)
+		require.Nil(t, err)
+		require.Equal(t, []byte{byte('a' + i)}, data)
+	}
+}
+
+func TestDirectIOSurvivesReopen(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-directio-reopen-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(64), WithDirectIO(true))
+	require.Nil(t, err)
+	pos, err := w.Write([]byte("hello"))
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+
+	w2, err := Open(WithDirPath(dir), WithSegmentSize(64), WithDirectIO(true))
+	require.Nil(t, err)
+	defer w2.Close()
+
+	data, err := w2.Read(pos)
+	require.Nil(t, err)
+	require.Equal(t, []byte("hello"), data)
+}
@@ -0,0 +1,109 @@
+package wal
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiskWatermarksAreANoOpWhenUnset(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-watermarks-noop-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(32))
+	require.Nil(t, err)
+	defer w.Close()
+
+	for i := 0; i < 5; i++ {
+		_, err = w.Write([]byte("0123456789"))
+		require.Nil(t, err)
+	}
+}
+
+func TestDiskSoftWatermarkFiresCallbackOnRotation(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-watermarks-soft-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	var calls int
+	var lastUsed int64
+	w, err := Open(WithDirPath(dir), WithSegmentSize(32),
+		WithDiskWatermarks(10, 0),
+		WithOnDiskWatermark(func(usedBytes int64) {
+			calls++
+			lastUsed = usedBytes
+		}))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("0123456789"))
+	require.Nil(t, err)
+	// Forces a rotation, which is where DiskSoftWatermark gets checked.
+	_, err = w.Write([]byte("second-segment-forces-rotation"))
+	require.Nil(t, err)
+
+	require.Greater(t, calls, 0)
+	require.GreaterOrEqual(t, lastUsed, int64(10))
+}
+
+func TestDiskHardWatermarkFailsWritesFastAndClearsOnceUsageDrops(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-watermarks-hard-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(32), WithDiskWatermarks(0, 40))
+	require.Nil(t, err)
+	defer w.Close()
+
+	// Keep writing (each write past SegmentSize forces a rotation) until
+	// the accumulated segments push total on-disk size at or above the
+	// hard watermark and a write fails fast.
+	for i := 0; i < 20; i++ {
+		if _, err = w.Write([]byte("0123456789")); err != nil {
+			break
+		}
+	}
+	require.True(t, errors.Is(err, ErrDiskQuotaExceeded))
+
+	_, err = w.Write([]byte("0123456789"))
+	require.True(t, errors.Is(err, ErrDiskQuotaExceeded))
+
+	require.Nil(t, w.PendingWrites([]byte("0123456789")))
+	_, err = w.WriteAll()
+	require.True(t, errors.Is(err, ErrDiskQuotaExceeded))
+
+	// Once Compact reclaims the sealed segments' dead chunks, usage drops
+	// back under the hard watermark; Compact itself re-checks the
+	// watermarks, so diskQuotaExceeded clears without waiting on a
+	// rotation that write and writeBatch would otherwise keep refusing to
+	// reach.
+	_, err = w.Compact(func(pos *ChunkPosition, data []byte) bool { return false }, nil)
+	require.Nil(t, err)
+
+	_, err = w.Write([]byte("0123456789"))
+	require.Nil(t, err)
+}
+
+func TestOpenRejectsNegativeDiskWatermarks(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-watermarks-validate-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	_, err = Open(WithDirPath(dir), WithDiskWatermarks(-1, 0))
+	require.ErrorContains(t, err, "Options.DiskSoftWatermark")
+
+	_, err = Open(WithDirPath(dir), WithDiskWatermarks(0, -1))
+	require.ErrorContains(t, err, "Options.DiskHardWatermark")
+}
+
+func TestOpenRejectsHardWatermarkSmallerThanSoftWatermark(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-watermarks-validate-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	_, err = Open(WithDirPath(dir), WithDiskWatermarks(100, 50))
+	require.ErrorContains(t, err, "Options.DiskHardWatermark")
+}
@@ -0,0 +1,90 @@
+package wal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// reserveFileName is the pre-allocated headroom file Open creates in
+// DirPath when Options.DiskFullReserve is set, sized to hold that many
+// bytes. releaseReserveLocked frees it the first time a write or sync
+// reports the disk full, buying just enough room for a Checkpoint or
+// TruncateBefore/TruncateAfter to run afterward and reclaim space
+// properly -- without it, those would-be space-reclaiming calls could
+// themselves fail with the same ErrDiskFull they're meant to fix.
+const reserveFileName = "RESERVE"
+
+// ErrIOFailed reports an I/O error encountered while appending to or
+// syncing SegmentID, positioned at Offset within it. errors.Is(err,
+// ErrDiskFull) reports whether Err was specifically the filesystem
+// holding Options.DirPath running out of space.
+type ErrIOFailed struct {
+	SegmentID SegmentID
+	Offset    int64
+	Err       error
+}
+
+func (e *ErrIOFailed) Error() string {
+	return fmt.Sprintf("wal: I/O error in segment %d at offset %d: %v", e.SegmentID, e.Offset, e.Err)
+}
+
+func (e *ErrIOFailed) Unwrap() error {
+	return e.Err
+}
+
+func (e *ErrIOFailed) Is(target error) bool {
+	return target == ErrDiskFull && isDiskFull(e.Err)
+}
+
+// createReserve preallocates reserveFileName in dirPath to size bytes,
+// leaving it alone if it already exists at that size or larger from a
+// previous run.
+func createReserve(dirPath string, size int64) error {
+	f, err := os.OpenFile(filepath.Join(dirPath, reserveFileName), os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() >= size {
+		return nil
+	}
+	if err := f.Truncate(size); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// releaseReserveLocked removes the reserve file to give the filesystem
+// back Options.DiskFullReserve bytes of headroom, the first time a write
+// or sync reports the disk full; later calls are no-ops until the WAL is
+// reopened, which recreates it. w.mu must already be held.
+func (w *WAL) releaseReserveLocked() {
+	if w.reserveReleased || w.options.DiskFullReserve <= 0 {
+		return
+	}
+	w.reserveReleased = true
+	_ = os.Remove(filepath.Join(w.options.DirPath, reserveFileName))
+}
+
+// handleIOErrorLocked inspects err, returned by an append or sync against
+// segID at offset: if it's ENOSPC, it releases the disk-full reserve (see
+// releaseReserveLocked), invokes Options.OnDiskFull if set, and wraps it
+// in an *ErrIOFailed so the caller can match it with errors.Is(err,
+// ErrDiskFull) instead of matching an OS-specific errno itself. Any other
+// error is returned unchanged. w.mu must already be held.
+func (w *WAL) handleIOErrorLocked(err error, segID SegmentID, offset int64) error {
+	if err == nil || !isDiskFull(err) {
+		return err
+	}
+	w.releaseReserveLocked()
+	if w.options.OnDiskFull != nil {
+		w.options.OnDiskFull(err)
+	}
+	return &ErrIOFailed{SegmentID: segID, Offset: offset, Err: err}
+}
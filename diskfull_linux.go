@@ -0,0 +1,14 @@
+//go:build linux
+
+package wal
+
+import (
+	"errors"
+	"syscall"
+)
+
+// isDiskFull reports whether err is, or wraps, ENOSPC -- the filesystem
+// holding Options.DirPath has run out of space.
+func isDiskFull(err error) bool {
+	return errors.Is(err, syscall.ENOSPC)
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package wal
+
+// isDiskFull always reports false on this platform: this package only
+// recognizes ENOSPC, a Linux/POSIX errno, via the linux-only build of
+// this function.
+func isDiskFull(err error) bool {
+	return false
+}
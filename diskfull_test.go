@@ -0,0 +1,104 @@
+package wal
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// failWriteFS wraps osFS so tests can make the active segment's Write
+// fail with ENOSPC on demand, without needing a real full disk.
+type failWriteFS struct {
+	osFS
+	fail *bool
+}
+
+func (fs failWriteFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	f, err := fs.osFS.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return failWriteFile{f.(*os.File), fs.fail}, nil
+}
+
+type failWriteFile struct {
+	*os.File
+	fail *bool
+}
+
+func (f failWriteFile) Write(p []byte) (int, error) {
+	if *f.fail {
+		return 0, syscall.ENOSPC
+	}
+	return f.File.Write(p)
+}
+
+func TestCreateReservePreallocatesAndIsIdempotent(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-reserve-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	require.Nil(t, createReserve(dir, 4096))
+	info, err := os.Stat(filepath.Join(dir, reserveFileName))
+	require.Nil(t, err)
+	require.Equal(t, int64(4096), info.Size())
+
+	// Shrinking the requested size must not shrink an existing reserve.
+	require.Nil(t, createReserve(dir, 1024))
+	info, err = os.Stat(filepath.Join(dir, reserveFileName))
+	require.Nil(t, err)
+	require.Equal(t, int64(4096), info.Size())
+}
+
+func TestOpenWithDiskFullReserveCreatesReserveFile(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-reserve-open-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithDiskFullReserve(4096))
+	require.Nil(t, err)
+	defer w.Close()
+
+	info, err := os.Stat(filepath.Join(dir, reserveFileName))
+	require.Nil(t, err)
+	require.Equal(t, int64(4096), info.Size())
+}
+
+func TestDiskFullReleasesReserveAndFiresCallback(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-diskfull-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	fail := false
+	var gotErr error
+	w, err := Open(
+		WithDirPath(dir),
+		WithFS(failWriteFS{fail: &fail}),
+		WithDiskFullReserve(4096),
+		WithOnDiskFull(func(err error) { gotErr = err }),
+	)
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("hello"))
+	require.Nil(t, err)
+
+	fail = true
+	_, err = w.Write([]byte("world"))
+	require.NotNil(t, err)
+	require.True(t, errors.Is(err, ErrDiskFull))
+	require.NotNil(t, gotErr)
+
+	_, statErr := os.Stat(filepath.Join(dir, reserveFileName))
+	require.True(t, os.IsNotExist(statErr))
+
+	// The WAL itself is not poisoned by a disk-full append: once space
+	// frees up, writes succeed again.
+	fail = false
+	_, err = w.Write([]byte("world"))
+	require.Nil(t, err)
+}
@@ -0,0 +1,64 @@
+package wal
+
+import (
+	"fmt"
+	"io"
+)
+
+// Dispatcher maps the flags byte WriteWithFlags reserves on a chunk to a
+// decoder for that record type, so Replay can apply a whole log to a
+// state machine on startup without its caller hand-rolling the
+// read-then-type-switch loop themselves. The zero value is ready to use.
+type Dispatcher struct {
+	decoders map[byte]func([]byte) error
+}
+
+// RegisterType registers fn to be called, by Replay, for every record
+// written with WriteWithFlags(data, flag). Registering the same flag
+// twice replaces the previous registration.
+func (d *Dispatcher) RegisterType(flag byte, fn func([]byte) error) {
+	if d.decoders == nil {
+		d.decoders = make(map[byte]func([]byte) error)
+	}
+	d.decoders[flag] = fn
+}
+
+// ErrUnregisteredType is returned by Replay when it reaches a record
+// whose flag has no decoder registered for it via RegisterType.
+type ErrUnregisteredType struct {
+	Flag byte
+}
+
+func (e *ErrUnregisteredType) Error() string {
+	return fmt.Sprintf("wal: no decoder registered for record type %d", e.Flag)
+}
+
+// Replay walks every chunk in w, oldest to newest, and dispatches each to
+// the decoder RegisterType registered for its flag -- the standard "apply
+// the WAL to a state machine on startup" loop. It reads every chunk with
+// Reader.NextWithFlags, so it only works on a WAL written entirely with
+// WriteWithFlags; see WriteWithFlags's doc comment for why a chunk Write
+// wrote can't be told apart from one whose flags byte happens to be zero.
+//
+// Replay stops at, and returns, the first error: ErrUnregisteredType for a
+// flag nothing was registered for, whatever a decoder itself returns, or
+// an error Reader.NextWithFlags surfaces reading the log.
+func (d *Dispatcher) Replay(w *WAL) error {
+	r := w.NewReader()
+	for {
+		data, flag, _, err := r.NextWithFlags()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		fn, ok := d.decoders[flag]
+		if !ok {
+			return &ErrUnregisteredType{Flag: flag}
+		}
+		if err := fn(data); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package wal
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDispatcherReplayAppliesRecordsByType(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-dispatch-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	const (
+		flagPut byte = iota
+		flagDelete
+	)
+
+	_, err = w.WriteWithFlags([]byte("a"), flagPut)
+	require.Nil(t, err)
+	_, err = w.WriteWithFlags([]byte("b"), flagPut)
+	require.Nil(t, err)
+	_, err = w.WriteWithFlags([]byte("a"), flagDelete)
+	require.Nil(t, err)
+
+	state := map[string]bool{}
+	var d Dispatcher
+	d.RegisterType(flagPut, func(data []byte) error {
+		state[string(data)] = true
+		return nil
+	})
+	d.RegisterType(flagDelete, func(data []byte) error {
+		delete(state, string(data))
+		return nil
+	})
+
+	require.Nil(t, d.Replay(w))
+	assert.Equal(t, map[string]bool{"b": true}, state)
+}
+
+func TestDispatcherReplayReturnsErrUnregisteredType(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-dispatch-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.WriteWithFlags([]byte("a"), 7)
+	require.Nil(t, err)
+
+	var d Dispatcher
+	err = d.Replay(w)
+	var unregistered *ErrUnregisteredType
+	require.ErrorAs(t, err, &unregistered)
+	assert.Equal(t, byte(7), unregistered.Flag)
+}
+
+func TestDispatcherReplayStopsOnDecoderError(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-dispatch-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.WriteWithFlags([]byte("a"), 0)
+	require.Nil(t, err)
+	_, err = w.WriteWithFlags([]byte("b"), 0)
+	require.Nil(t, err)
+
+	wantErr := fmt.Errorf("boom")
+	var applied []string
+	var d Dispatcher
+	d.RegisterType(0, func(data []byte) error {
+		applied = append(applied, string(data))
+		return wantErr
+	})
+
+	err = d.Replay(w)
+	assert.Equal(t, wantErr, err)
+	assert.Equal(t, []string{"a"}, applied)
+}
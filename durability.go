@@ -0,0 +1,81 @@
+package wal
+
+import (
+	"context"
+	"time"
+)
+
+// isDurableLocked reports whether pos has survived an fsync to stable
+// storage. w.mu must already be held, for either reading or writing.
+func (w *WAL) isDurableLocked(pos *ChunkPosition) bool {
+	if pos.SegmentId < w.activeSegment.id {
+		// rotateIfNeeded always fsyncs a segment before sealing it, so
+		// anything in an older segment is unconditionally durable.
+		return true
+	}
+	if pos.SegmentId > w.activeSegment.id {
+		return false
+	}
+	return w.durablePos != nil && w.durablePos.ChunkOffset >= pos.ChunkOffset
+}
+
+// advanceDurableLocked records pos as the new durable frontier if it's
+// past whatever was recorded before, and reports it via Options.OnDurable.
+// w.mu must already be held.
+func (w *WAL) advanceDurableLocked(pos *ChunkPosition) {
+	if pos == nil {
+		return
+	}
+	if w.durablePos == nil || pos.SegmentId > w.durablePos.SegmentId ||
+		(pos.SegmentId == w.durablePos.SegmentId && pos.ChunkOffset > w.durablePos.ChunkOffset) {
+		w.durablePos = pos
+		if w.options.OnDurable != nil {
+			w.options.OnDurable(pos)
+		}
+	}
+}
+
+// WaitForSync blocks until pos has survived an fsync to stable storage, or
+// ctx is done, whichever comes first. It's the wait half of "commit when
+// durable" for a WAL running with Options.Sync off, or a write-behind log
+// opened with OpenWBL: Write returns as soon as data is appended, without
+// waiting on an fsync, and a caller that must not acknowledge its own
+// client until that write is actually on disk calls WaitForSync on the
+// returned position instead of forcing an fsync on every single Write.
+//
+// It returns nil immediately if pos is already durable -- including any
+// position in a segment sealed before this call, since rotateIfNeeded
+// always fsyncs a segment before sealing it -- ErrClosed if the WAL has
+// since been closed, and ErrWALPoisoned if an fsync has since failed (see
+// Options.OnSyncError). Otherwise it polls, backing off exponentially the
+// same way TailReader and Watcher do, since wal has nothing more precise
+// than that to wait on.
+func (w *WAL) WaitForSync(ctx context.Context, pos *ChunkPosition) error {
+	backoff := DefaultWatcherOptions.MinBackoff
+	for {
+		w.mu.RLock()
+		durable := w.isDurableLocked(pos)
+		closed := w.closed
+		syncErr := w.syncErr
+		w.mu.RUnlock()
+
+		if durable {
+			return nil
+		}
+		if closed {
+			return ErrClosed
+		}
+		if syncErr != nil {
+			return &ErrPoisoned{Cause: syncErr}
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if backoff *= 2; backoff > DefaultWatcherOptions.MaxBackoff {
+			backoff = DefaultWatcherOptions.MaxBackoff
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package wal
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaitForSyncReturnsImmediatelyForSealedSegment(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-waitforsync-sealed-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(32))
+	require.Nil(t, err)
+	defer w.Close()
+
+	pos, err := w.Write([]byte("hello"))
+	require.Nil(t, err)
+
+	// Force a rotation without ever calling Sync ourselves: rotateIfNeeded
+	// always fsyncs the outgoing segment before sealing it, so pos should
+	// already read as durable.
+	for i := 0; i < 4; i++ {
+		_, err = w.Write([]byte("pad-it-past-one-segment"))
+		require.Nil(t, err)
+	}
+	require.Greater(t, w.activeSegment.id, pos.SegmentId)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	require.Nil(t, w.WaitForSync(ctx, pos))
+}
+
+func TestWaitForSyncBlocksUntilSync(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-waitforsync-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	pos, err := w.Write([]byte("hello"))
+	require.Nil(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	require.Equal(t, context.DeadlineExceeded, w.WaitForSync(ctx, pos))
+
+	done := make(chan error, 1)
+	go func() {
+		done <- w.WaitForSync(context.Background(), pos)
+	}()
+
+	_, err = w.Sync()
+	require.Nil(t, err)
+
+	require.Nil(t, <-done)
+}
+
+func TestWaitForSyncReturnsErrClosed(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-waitforsync-closed-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+
+	pos, err := w.Write([]byte("hello"))
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+
+	require.Equal(t, ErrClosed, w.WaitForSync(context.Background(), pos))
+}
+
+func TestWaitForSyncReturnsErrWALPoisoned(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-waitforsync-poisoned-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	fail := false
+	w, err := Open(WithDirPath(dir), WithFS(failSyncFS{fail: &fail}))
+	require.Nil(t, err)
+	defer w.Close()
+
+	pos, err := w.Write([]byte("hello"))
+	require.Nil(t, err)
+
+	fail = true
+	_, err = w.Sync()
+	require.Equal(t, errSimulatedFsyncFailure, err)
+
+	require.True(t, errors.Is(w.WaitForSync(context.Background(), pos), ErrWALPoisoned))
+}
+
+func TestWithOnDurableFiresWithAdvancingFrontier(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-ondurable-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	var durable []*ChunkPosition
+	w, err := Open(WithDirPath(dir), WithOnDurable(func(pos *ChunkPosition) {
+		durable = append(durable, pos)
+	}))
+	require.Nil(t, err)
+	defer w.Close()
+
+	pos1, err := w.Write([]byte("hello"))
+	require.Nil(t, err)
+	pos2, err := w.Write([]byte("world"))
+	require.Nil(t, err)
+
+	require.Empty(t, durable)
+
+	_, err = w.Sync()
+	require.Nil(t, err)
+
+	require.Len(t, durable, 1)
+	require.Equal(t, pos2, durable[0])
+	require.True(t, w.isDurableLocked(pos1))
+}
+
+func TestWithOnDurableDoesNotFireWhenFrontierUnchanged(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-ondurable-noop-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	calls := 0
+	w, err := Open(WithDirPath(dir), WithOnDurable(func(pos *ChunkPosition) {
+		calls++
+	}))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.Sync()
+	require.Nil(t, err)
+	require.Equal(t, 0, calls)
+}
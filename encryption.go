@@ -0,0 +1,180 @@
+package wal
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// ErrInvalidEncryptionKey is returned by Open when WithEncryption's key is
+// not a valid AES-256 key (32 bytes).
+var ErrInvalidEncryptionKey = fmt.Errorf("wal: encryption key must be 32 bytes for AES-256")
+
+// newGCM builds the AES-256-GCM AEAD that encryptPayload and decryptPayload
+// use, from a 32-byte key.
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, ErrInvalidEncryptionKey
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encryptPayload seals data with a freshly generated nonce, which it
+// prepends to the returned ciphertext so decryptPayload doesn't need it
+// recorded anywhere else.
+func encryptPayload(gcm cipher.AEAD, data []byte) ([]byte, error) {
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, data, nil), nil
+}
+
+// decryptPayload reverses encryptPayload.
+func decryptPayload(gcm cipher.AEAD, data []byte) ([]byte, error) {
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("wal: encrypted chunk is shorter than a nonce")
+	}
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
+
+// KeyProvider supplies the AES-256 keys encryptChunk and decryptChunk use,
+// letting key material live in AWS KMS, HashiCorp Vault, an HSM, or
+// anywhere else that shouldn't hand the WAL raw key bytes to hold onto for
+// its whole lifetime -- the WAL calls back into it for a key whenever it
+// needs one instead of keeping its own copy. See Options.KeyProvider;
+// Options.Keyring's fixed map is implemented as a KeyProvider (mapKeyProvider)
+// under the hood.
+type KeyProvider interface {
+	// CurrentKey returns the key new chunks should be encrypted with, and
+	// the ID it should be tagged with so a later GetKey call can find it
+	// again. The ID only needs to be unique within this provider; the WAL
+	// never interprets it.
+	CurrentKey() (id uint32, key []byte, err error)
+
+	// GetKey returns the key that was current when a chunk tagged with id
+	// was encrypted, so a chunk written under a key that's since stopped
+	// being current can still be decrypted. It returns ErrUnknownKeyID if
+	// id is no longer available -- e.g. a key retired before every segment
+	// encrypted under it was rewritten with RewriteWithKey.
+	GetKey(id uint32) (key []byte, err error)
+}
+
+// mapKeyProvider is the KeyProvider WithKeyring builds: a fixed set of keys
+// held in memory for the WAL's whole lifetime, with the highest ID always
+// current. It exists so Options.Keyring can be implemented in terms of
+// KeyProvider instead of its own separate code path.
+type mapKeyProvider struct {
+	keys        map[uint32][]byte
+	activeKeyID uint32
+}
+
+// newMapKeyProvider validates every key in keys is a valid AES-256 key and
+// wraps them in a mapKeyProvider whose current key is the one under the
+// highest ID.
+func newMapKeyProvider(keys map[uint32][]byte) (*mapKeyProvider, error) {
+	p := &mapKeyProvider{keys: keys}
+	first := true
+	for id, key := range keys {
+		if len(key) != 32 {
+			return nil, ErrInvalidEncryptionKey
+		}
+		if first || id > p.activeKeyID {
+			p.activeKeyID = id
+			first = false
+		}
+	}
+	return p, nil
+}
+
+func (p *mapKeyProvider) CurrentKey() (id uint32, key []byte, err error) {
+	return p.activeKeyID, p.keys[p.activeKeyID], nil
+}
+
+func (p *mapKeyProvider) GetKey(id uint32) ([]byte, error) {
+	key, ok := p.keys[id]
+	if !ok {
+		return nil, &ErrUnknownKeyID{KeyID: id}
+	}
+	return key, nil
+}
+
+// keyIDSize is the width, in bytes, of the key ID encryptChunk prepends
+// ahead of the nonce for the KeyProvider key-rotation scheme.
+const keyIDSize = 4
+
+// ErrUnknownKeyID is returned when decrypting a chunk whose recorded key ID
+// isn't available from the WAL's KeyProvider -- most often a key that was
+// retired before every segment encrypted under it was rewritten with
+// RewriteWithKey.
+type ErrUnknownKeyID struct {
+	KeyID uint32
+}
+
+func (e *ErrUnknownKeyID) Error() string {
+	return fmt.Sprintf("wal: no key registered for key ID %d", e.KeyID)
+}
+
+// encryptChunk encrypts payload the way Options.EncryptionKey or a
+// KeyProvider (built from Options.KeyProvider or Options.Keyring) requires,
+// or returns it unchanged if neither is set. A WAL using a KeyProvider
+// prepends the current key's ID ahead of the nonce, so decryptChunk can look
+// the right key back up regardless of which key is current by the time the
+// chunk is read; one using the older Options.EncryptionKey does not, since
+// there's only ever the one key to try.
+func (w *WAL) encryptChunk(payload []byte) ([]byte, error) {
+	switch {
+	case w.gcm != nil:
+		return encryptPayload(w.gcm, payload)
+	case w.keyProvider != nil:
+		id, key, err := w.keyProvider.CurrentKey()
+		if err != nil {
+			return nil, err
+		}
+		gcm, err := newGCM(key)
+		if err != nil {
+			return nil, err
+		}
+		sealed, err := encryptPayload(gcm, payload)
+		if err != nil {
+			return nil, err
+		}
+		out := make([]byte, keyIDSize, keyIDSize+len(sealed))
+		binary.BigEndian.PutUint32(out, id)
+		return append(out, sealed...), nil
+	default:
+		return payload, nil
+	}
+}
+
+// decryptChunk reverses encryptChunk.
+func (w *WAL) decryptChunk(data []byte) ([]byte, error) {
+	switch {
+	case w.gcm != nil:
+		return decryptPayload(w.gcm, data)
+	case w.keyProvider != nil:
+		if len(data) < keyIDSize {
+			return nil, fmt.Errorf("wal: encrypted chunk is shorter than a key ID")
+		}
+		keyID := binary.BigEndian.Uint32(data[:keyIDSize])
+		key, err := w.keyProvider.GetKey(keyID)
+		if err != nil {
+			return nil, err
+		}
+		gcm, err := newGCM(key)
+		if err != nil {
+			return nil, err
+		}
+		return decryptPayload(gcm, data[keyIDSize:])
+	default:
+		return data, nil
+	}
+}
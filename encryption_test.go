@@ -0,0 +1,64 @@
+package wal
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteReadWithEncryption(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-encryption-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	key := bytes.Repeat([]byte{0x42}, 32)
+	w, err := Open(WithDirPath(dir), WithEncryption(key))
+	require.Nil(t, err)
+	defer w.Close()
+
+	payload := []byte("sensitive PII")
+	pos, err := w.Write(payload)
+	require.Nil(t, err)
+
+	got, err := w.Read(pos)
+	require.Nil(t, err)
+	require.Equal(t, payload, got)
+
+	r := w.NewReader()
+	got, _, err = r.Next()
+	require.Nil(t, err)
+	require.Equal(t, payload, got)
+}
+
+func TestOpenRejectsInvalidEncryptionKey(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-encryption-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	_, err = Open(WithDirPath(dir), WithEncryption([]byte("too short")))
+	require.ErrorIs(t, err, ErrInvalidEncryptionKey)
+}
+
+func TestEncryptedSegmentUnreadableWithoutKey(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-encryption-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	key := bytes.Repeat([]byte{0x42}, 32)
+	w, err := Open(WithDirPath(dir), WithEncryption(key))
+	require.Nil(t, err)
+	pos, err := w.Write([]byte("sensitive PII"))
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+
+	plain, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer plain.Close()
+
+	got, err := plain.Read(pos)
+	if err == nil {
+		require.NotEqual(t, []byte("sensitive PII"), got)
+	}
+}
@@ -0,0 +1,60 @@
+package wal
+
+import "fmt"
+
+// ErrClosed is returned by Write, WriteWithFlags, WriteWithTTL, WriteAll,
+// PendingWrites, and Sync once Close has returned, instead of operating
+// on segment files Close has already flushed and closed.
+var ErrClosed = fmt.Errorf("wal: WAL is closed")
+
+// ErrValueTooLarge is the sentinel an *ErrRecordTooLarge unwraps to, so
+// errors.Is(err, ErrValueTooLarge) identifies an oversized record from
+// Write, WriteWithFlags, WriteWithTTL, or PendingWrites without needing
+// errors.As to get at the struct's Size and MaxSize fields.
+var ErrValueTooLarge = fmt.Errorf("wal: record is too large")
+
+// ErrPendingSizeTooLarge is returned by PendingWrites when
+// Options.MaxPendingSize is set and buffering data would put the batch
+// WriteAll has yet to flush over that cap.
+var ErrPendingSizeTooLarge = fmt.Errorf("wal: pending writes would exceed MaxPendingSize")
+
+// ErrCorruptedData is the sentinel every *ErrCorrupted matches via Is, for
+// callers that only care that a chunk failed its checksum or was
+// truncated, not which segment or offset -- see ErrCorrupted for that.
+var ErrCorruptedData = fmt.Errorf("wal: corrupted data")
+
+// ErrSegmentNotFound is the sentinel wrapped by a lookup that names a
+// segment ID the WAL doesn't currently have -- most commonly a
+// ChunkPosition read from before a Checkpoint, TruncateBefore, or
+// MaxSegmentAge/MaxTotalSize retirement removed the segment it pointed
+// into.
+var ErrSegmentNotFound = fmt.Errorf("wal: segment not found")
+
+// errSegmentNotFound wraps ErrSegmentNotFound with the segment ID a
+// lookup couldn't find, so the error message names it without every call
+// site having to format that itself, while still satisfying
+// errors.Is(err, ErrSegmentNotFound).
+func errSegmentNotFound(id SegmentID) error {
+	return fmt.Errorf("wal: segment %d not found: %w", id, ErrSegmentNotFound)
+}
+
+// ErrSegmentNotSealed is returned by SegmentDigest when id names the
+// still-active segment: seal is what computes and records the
+// whole-segment digest a footer carries (see footer.checksum), so an
+// unsealed segment doesn't have one yet -- and computing one early would
+// need to be redone after every further Write.
+var ErrSegmentNotSealed = fmt.Errorf("wal: segment is still active, has no digest yet")
+
+// ErrFooterMissing is returned by SegmentDigest for a sealed segment with
+// no footer to read a digest from: one sealed before footers existed, or
+// whose footer write never reached disk before a crash. Repair or Verify
+// can confirm the segment's chunks are otherwise intact; reseal isn't
+// something this package can redo for you, since seal only ever runs once,
+// when a segment first rotates out of being active.
+var ErrFooterMissing = fmt.Errorf("wal: segment has no footer to read a digest from")
+
+// ErrDiskFull is the sentinel a write or sync that failed because the
+// filesystem holding Options.DirPath ran out of space is meant to wrap.
+// It is defined here for forward compatibility, the same as
+// Options.BlockSize: nothing in this package returns it yet.
+var ErrDiskFull = fmt.Errorf("wal: disk is full")
@@ -0,0 +1,95 @@
+package wal
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteAfterCloseReturnsErrClosed(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-errclosed-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+
+	_, err = w.Write([]byte("hello"))
+	require.True(t, errors.Is(err, ErrClosed))
+
+	require.True(t, errors.Is(w.PendingWrites([]byte("hello")), ErrClosed))
+
+	_, err = w.WriteAll()
+	require.True(t, errors.Is(err, ErrClosed))
+
+	_, err = w.Sync()
+	require.True(t, errors.Is(err, ErrClosed))
+}
+
+func TestErrRecordTooLargeMatchesErrValueTooLarge(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-errvaluetoolarge-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithMaxRecordSize(2))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("hello"))
+	require.True(t, errors.Is(err, ErrValueTooLarge))
+}
+
+func TestPendingWritesRejectsOverMaxPendingSize(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-maxpendingsize-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithMaxPendingSize(4))
+	require.Nil(t, err)
+	defer w.Close()
+
+	require.Nil(t, w.PendingWrites([]byte("ab")))
+	err = w.PendingWrites([]byte("cde"))
+	require.True(t, errors.Is(err, ErrPendingSizeTooLarge))
+
+	positions, err := w.WriteAll()
+	require.Nil(t, err)
+	require.Equal(t, 1, len(positions))
+}
+
+func TestErrCorruptedMatchesErrCorruptedData(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-errcorrupteddata-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	_, err = w.Write([]byte("hello"))
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+
+	f, err := os.OpenFile(segmentFileName(dir, ".SEG", 1, nil), os.O_RDWR, 0o644)
+	require.Nil(t, err)
+	_, err = f.WriteAt([]byte{0xFF}, segmentHeaderSize+chunkHeaderSize)
+	require.Nil(t, err)
+	require.Nil(t, f.Close())
+
+	_, err = Verify(dir)
+	require.True(t, errors.Is(err, ErrCorruptedData))
+}
+
+func TestSegmentNotFoundMatchesSentinel(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-errsegmentnotfound-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.Read(&ChunkPosition{SegmentId: 999, ChunkOffset: 0})
+	require.True(t, errors.Is(err, ErrSegmentNotFound))
+}
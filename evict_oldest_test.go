@@ -0,0 +1,77 @@
+package wal
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvictOldestOnFullRetiresOldestSegmentsToStayUnderHardWatermark(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-evict-oldest-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	var evicted []SegmentID
+	w, err := Open(WithDirPath(dir), WithSegmentSize(32),
+		WithDiskWatermarks(0, 40),
+		WithEvictOldestOnFull(true),
+		WithOnSegmentEvicted(func(id SegmentID) { evicted = append(evicted, id) }))
+	require.Nil(t, err)
+	defer w.Close()
+
+	for i := 0; i < 20; i++ {
+		_, err = w.Write([]byte("0123456789"))
+		require.Nil(t, err)
+	}
+
+	require.Greater(t, len(evicted), 0)
+
+	w.mu.RLock()
+	var total int64 = w.activeSegment.Size()
+	for _, seg := range w.olderSegments {
+		total += seg.Size()
+	}
+	w.mu.RUnlock()
+	// Ring-buffer semantics: writes keep being accepted instead of failing
+	// fast, so total can still sit up to one segment's worth over the
+	// watermark, the same slop MaxTotalSize already tolerates.
+	require.LessOrEqual(t, total, int64(40+32))
+}
+
+func TestEvictOldestOnFullFallsBackToErrDiskQuotaExceededWhenEverySegmentIsPinned(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-evict-oldest-pinned-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(32),
+		WithDiskWatermarks(0, 10),
+		WithEvictOldestOnFull(true))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("0123456789"))
+	require.Nil(t, err)
+
+	w.mu.RLock()
+	pinned := w.activeSegment
+	w.mu.RUnlock()
+	require.True(t, pinned.acquire())
+	defer pinned.release()
+
+	for i := 0; i < 20; i++ {
+		if _, err = w.Write([]byte("0123456789")); err != nil {
+			break
+		}
+	}
+	require.ErrorIs(t, err, ErrDiskQuotaExceeded)
+}
+
+func TestOpenRejectsEvictOldestOnFullWithoutDiskHardWatermark(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-evict-oldest-validate-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	_, err = Open(WithDirPath(dir), WithEvictOldestOnFull(true))
+	require.ErrorContains(t, err, "Options.EvictOldestOnFull")
+}
@@ -0,0 +1,94 @@
+package wal
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// jsonlRecord is one line of ExportJSONL's output: a chunk's physical
+// framing alongside its decoded payload, laid out flat rather than
+// nesting ChunkMeta as its own object, so a jq filter or a BigQuery
+// external table schema can address every field directly.
+type jsonlRecord struct {
+	Segment     SegmentID  `json:"segment"`
+	ChunkOffset int64      `json:"offset"`
+	ChunkSize   uint32     `json:"size"`
+	Sequence    uint64     `json:"sequence"`
+	WrittenAt   *time.Time `json:"written_at,omitempty"`
+	Flags       *byte      `json:"flags,omitempty"`
+	Payload     any        `json:"payload"`
+}
+
+// exportJSONLOptions holds ExportJSONL's optional configuration.
+type exportJSONLOptions struct {
+	flagged bool
+}
+
+// ExportJSONLOption configures ExportJSONL.
+type ExportJSONLOption func(*exportJSONLOptions)
+
+// WithFlaggedRecords tells ExportJSONL that every chunk was written with
+// WriteWithFlags, so it's safe to split each payload's leading flags byte
+// out into its own "flags" field before handing the rest to decode --
+// see WriteWithFlags's doc comment for why ExportJSONL can't tell a
+// flags-carrying chunk apart from a plain one on its own. Without this
+// option, "flags" is left out of every row and decode sees the whole
+// payload, flags byte included if there is one.
+func WithFlaggedRecords() ExportJSONLOption {
+	return func(o *exportJSONLOptions) {
+		o.flagged = true
+	}
+}
+
+// ExportJSONL writes every chunk in the WAL to dst as one JSON object per
+// line: its segment, offset, size, sequence, write timestamp (if the WAL
+// was opened with WithTimestamps(true)), flags (if WithFlaggedRecords is
+// given), and decode's result for its payload -- for feeding a WAL's
+// contents into jq, BigQuery, or any other line-delimited-JSON pipeline.
+//
+// decode is called with each chunk's raw payload (after stripping the
+// flags byte, if WithFlaggedRecords is set) and its return value is
+// marshaled as the row's "payload" field; return the payload itself
+// (e.g. json.RawMessage(data) or string(data)) rather than a decoded Go
+// value if you don't want ExportJSONL's own json.Marshal call re-encoding
+// it.
+func (w *WAL) ExportJSONL(dst io.Writer, decode func([]byte) any, opts ...ExportJSONLOption) error {
+	var cfg exportJSONLOptions
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	enc := json.NewEncoder(dst)
+	r := w.NewReader()
+	for {
+		data, meta, err := r.NextWithMeta()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		rec := jsonlRecord{
+			Segment:     meta.SegmentId,
+			ChunkOffset: meta.ChunkOffset,
+			ChunkSize:   meta.ChunkSize,
+			Sequence:    meta.Sequence,
+		}
+		if !meta.WrittenAt.IsZero() {
+			writtenAt := meta.WrittenAt
+			rec.WrittenAt = &writtenAt
+		}
+		if cfg.flagged && len(data) > 0 {
+			flags := data[0]
+			rec.Flags = &flags
+			data = data[1:]
+		}
+		rec.Payload = decode(data)
+
+		if err := enc.Encode(rec); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package wal
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportJSONLWritesOneObjectPerChunk(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-exportjsonl-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithTimestamps(true))
+	require.Nil(t, err)
+	defer w.Close()
+	_, err = w.Write([]byte("hello"))
+	require.Nil(t, err)
+	_, err = w.Write([]byte("world"))
+	require.Nil(t, err)
+
+	var out bytes.Buffer
+	require.Nil(t, w.ExportJSONL(&out, func(b []byte) any { return string(b) }))
+
+	lines := bytes.Split(bytes.TrimSpace(out.Bytes()), []byte("\n"))
+	require.Len(t, lines, 2)
+
+	var rec jsonlRecord
+	require.Nil(t, json.Unmarshal(lines[0], &rec))
+	require.Equal(t, "hello", rec.Payload)
+	require.NotNil(t, rec.WrittenAt)
+	require.Nil(t, rec.Flags)
+}
+
+func TestExportJSONLOmitsWrittenAtWithoutTimestamps(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-exportjsonl-notime-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+	_, err = w.Write([]byte("hello"))
+	require.Nil(t, err)
+
+	var out bytes.Buffer
+	require.Nil(t, w.ExportJSONL(&out, func(b []byte) any { return string(b) }))
+
+	var raw map[string]any
+	require.Nil(t, json.Unmarshal(bytes.TrimSpace(out.Bytes()), &raw))
+	_, ok := raw["written_at"]
+	require.False(t, ok)
+}
+
+func TestExportJSONLWithFlaggedRecordsSplitsFlagsByte(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-exportjsonl-flags-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+	_, err = w.WriteWithFlags([]byte("hello"), 7)
+	require.Nil(t, err)
+
+	var out bytes.Buffer
+	require.Nil(t, w.ExportJSONL(&out, func(b []byte) any { return string(b) }, WithFlaggedRecords()))
+
+	var rec jsonlRecord
+	require.Nil(t, json.Unmarshal(bytes.TrimSpace(out.Bytes()), &rec))
+	require.Equal(t, "hello", rec.Payload)
+	require.NotNil(t, rec.Flags)
+	require.Equal(t, byte(7), *rec.Flags)
+}
@@ -0,0 +1,100 @@
+package wal
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadOnlyWALTailsSegmentsWrittenByAnotherHandle(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-external-tail-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	writer, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer writer.Close()
+
+	_, err = writer.Write([]byte("first"))
+	require.Nil(t, err)
+
+	reader, err := Open(WithDirPath(dir), WithReadOnly(true))
+	require.Nil(t, err)
+	defer reader.Close()
+
+	tr := reader.NewTailReader()
+
+	data, _, err := tr.Next(context.Background())
+	require.Nil(t, err)
+	require.Equal(t, []byte("first"), data)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		data, _, err := tr.Next(context.Background())
+		require.Nil(t, err)
+		require.Equal(t, []byte("second"), data)
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	_, err = writer.Write([]byte("second"))
+	require.Nil(t, err)
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("TailReader did not deliver the other process's write in time")
+	}
+}
+
+func TestReadOnlyWALPicksUpRotationFromAnotherHandle(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-external-tail-rotate-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	writer, err := Open(WithDirPath(dir), WithSegmentSize(64))
+	require.Nil(t, err)
+	defer writer.Close()
+
+	_, err = writer.Write([]byte("first"))
+	require.Nil(t, err)
+
+	reader, err := Open(WithDirPath(dir), WithReadOnly(true))
+	require.Nil(t, err)
+	defer reader.Close()
+
+	r := reader.NewReader()
+	_, _, err = r.Next()
+	require.Nil(t, err)
+
+	// Big enough to force rotateIfNeeded to seal the segment reader
+	// already knew about and start a new one writer-side.
+	_, err = writer.Write(make([]byte, 128))
+	require.Nil(t, err)
+	pos, err := writer.Write([]byte("after rotation"))
+	require.Nil(t, err)
+	require.NotEqual(t, pos.SegmentId, reader.activeSegment.id)
+
+	data, _, err := r.Next()
+	require.Nil(t, err)
+	require.Equal(t, make([]byte, 128), data)
+
+	data, _, err = r.Next()
+	require.Nil(t, err)
+	require.Equal(t, []byte("after rotation"), data)
+}
+
+func TestRefreshSegmentsRejectsAWritableWAL(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-external-tail-writable-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	require.ErrorIs(t, w.RefreshSegments(), ErrNotReadOnly)
+}
@@ -0,0 +1,15 @@
+//go:build linux
+
+package wal
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// fadviseDontNeed advises the kernel that f's pages are no longer needed
+// and can be evicted from the page cache. See Options.FadviseDontNeed.
+func fadviseDontNeed(f *os.File) error {
+	return unix.Fadvise(int(f.Fd()), 0, 0, unix.FADV_DONTNEED)
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package wal
+
+import "os"
+
+// fadviseDontNeed is a no-op on platforms without posix_fadvise. See
+// Options.FadviseDontNeed.
+func fadviseDontNeed(f *os.File) error {
+	return nil
+}
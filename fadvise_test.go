@@ -0,0 +1,57 @@
+package wal
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFadviseDontNeedOnSealedSegment(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-fadvise-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(1*KB), WithFadviseDontNeed(true))
+	require.Nil(t, err)
+	defer w.Close()
+
+	var positions []*ChunkPosition
+	for i := 0; i < 64; i++ {
+		pos, err := w.Write([]byte("fadvise-test-record-padding-to-force-rotation"))
+		require.Nil(t, err)
+		positions = append(positions, pos)
+	}
+
+	for i, pos := range positions {
+		data, err := w.Read(pos)
+		require.Nil(t, err)
+		require.Equal(t, []byte("fadvise-test-record-padding-to-force-rotation"), data, "record %d should still read correctly after its segment was fadvised", i)
+	}
+}
+
+func TestFadviseDontNeedOnReopenedSealedSegment(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-fadvise-reopen-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(1*KB))
+	require.Nil(t, err)
+	var positions []*ChunkPosition
+	for i := 0; i < 64; i++ {
+		pos, err := w.Write([]byte("fadvise-reopen-test-record-padding"))
+		require.Nil(t, err)
+		positions = append(positions, pos)
+	}
+	require.Nil(t, w.Close())
+
+	w2, err := Open(WithDirPath(dir), WithSegmentSize(1*KB), WithFadviseDontNeed(true))
+	require.Nil(t, err)
+	defer w2.Close()
+
+	for i, pos := range positions {
+		data, err := w2.Read(pos)
+		require.Nil(t, err)
+		require.Equal(t, []byte("fadvise-reopen-test-record-padding"), data, "record %d should still read correctly after its already-sealed segment was fadvised on open", i)
+	}
+}
@@ -0,0 +1,16 @@
+//go:build linux
+
+package wal
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// fdatasyncFile flushes f's data blocks to disk without waiting on
+// metadata that doesn't affect reading the data back. See
+// Options.Fdatasync.
+func fdatasyncFile(f *os.File) error {
+	return unix.Fdatasync(int(f.Fd()))
+}
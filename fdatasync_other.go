@@ -0,0 +1,11 @@
+//go:build !linux
+
+package wal
+
+import "os"
+
+// fdatasyncFile falls back to a full fsync on platforms without a
+// separate fdatasync syscall. See Options.Fdatasync.
+func fdatasyncFile(f *os.File) error {
+	return f.Sync()
+}
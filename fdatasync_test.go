@@ -0,0 +1,25 @@
+package wal
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFdatasyncWritesAreReadable(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-fdatasync-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSync(true), WithFdatasync(true))
+	require.Nil(t, err)
+	defer w.Close()
+
+	pos, err := w.Write([]byte("hello"))
+	require.Nil(t, err)
+
+	data, err := w.Read(pos)
+	require.Nil(t, err)
+	require.Equal(t, []byte("hello"), data)
+}
@@ -0,0 +1,145 @@
+package wal
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// fenceMarkerFileName is the file Open writes in DirPath when
+// Options.FencingToken is set, recording the highest token any writer has
+// claimed so far. A later Open with a higher token overwrites it, fencing
+// out whichever process is still holding the WAL open with the previous,
+// lower token -- see Options.FencingToken and checkFencingToken.
+const fenceMarkerFileName = "FENCE"
+
+// fenceMarkerMagic guards against trusting a marker torn by a crash
+// between claimFencingToken creating the file and fsyncing it.
+const fenceMarkerMagic = 0x57414c46 // "WALF"
+
+// fenceMarkerSize is the marker's fixed on-disk size: magic plus the
+// claimed token.
+const fenceMarkerSize = 4 + 8
+
+// ErrFenced is returned by Write, WriteAll, and WriteIfTail once this WAL
+// has noticed a later Open elsewhere claimed a higher Options.FencingToken
+// than its own -- see checkFencingToken. Unlike an *ErrPoisoned, closing
+// and reopening this WAL can't clear it: a higher token out there means
+// another process may already be appending to the same segments, so this
+// WAL is done for good. Open a fresh WAL with a higher token of your own
+// to take writer status back.
+var ErrFenced = fmt.Errorf("wal: a newer writer has claimed a higher fencing token, refusing further writes until the WAL is reopened")
+
+// encodeFenceMarker serializes token to fenceMarkerSize bytes.
+func encodeFenceMarker(token uint64) []byte {
+	buf := make([]byte, fenceMarkerSize)
+	binary.BigEndian.PutUint32(buf[0:4], fenceMarkerMagic)
+	binary.BigEndian.PutUint64(buf[4:12], token)
+	return buf
+}
+
+// decodeFenceMarker reverses encodeFenceMarker, reporting false if data
+// isn't exactly a well-formed, untorn marker.
+func decodeFenceMarker(data []byte) (token uint64, ok bool) {
+	if len(data) != fenceMarkerSize || binary.BigEndian.Uint32(data[0:4]) != fenceMarkerMagic {
+		return 0, false
+	}
+	return binary.BigEndian.Uint64(data[4:12]), true
+}
+
+// readFenceToken reads and decodes fenceMarkerFileName in dirPath. ok is
+// false, with a nil error, if the marker doesn't exist or isn't
+// well-formed -- either way, the caller should treat that as no token
+// having been claimed yet.
+func readFenceToken(fs FS, dirPath string) (token uint64, ok bool, err error) {
+	f, err := fs.OpenFile(filepath.Join(dirPath, fenceMarkerFileName), os.O_RDONLY, 0o644)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+	defer f.Close()
+
+	buf := make([]byte, fenceMarkerSize)
+	if _, err := f.ReadAt(buf, 0); err != nil {
+		return 0, false, nil
+	}
+	token, ok = decodeFenceMarker(buf)
+	return token, ok, nil
+}
+
+// writeFenceToken creates (or truncates) fenceMarkerFileName in dirPath
+// with token encoded into it, and fsyncs it so it's durable before
+// claimFencingToken returns.
+func writeFenceToken(fs FS, dirPath string, token uint64) error {
+	f, err := fs.OpenFile(filepath.Join(dirPath, fenceMarkerFileName), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(encodeFenceMarker(token)); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// claimFencingToken is Open's half of Options.FencingToken: it fails if
+// token isn't strictly higher than whatever's currently recorded in
+// dirPath's fence marker (0, if none has been written yet), and otherwise
+// claims writer status by overwriting the marker with it.
+func claimFencingToken(fs FS, dirPath string, token uint64) error {
+	current, _, err := readFenceToken(fs, dirPath)
+	if err != nil {
+		return err
+	}
+	if token <= current {
+		return fmt.Errorf("wal: Options.FencingToken %d is not higher than the current fencing token %d", token, current)
+	}
+	return writeFenceToken(fs, dirPath, token)
+}
+
+// startFencingTimer runs in its own goroutine for the lifetime of a WAL
+// opened with Options.FencingCheckInterval, re-reading dirPath's fence
+// marker every interval to notice a later Open elsewhere has claimed a
+// higher token -- the same polling approach startSegmentRotationTimer uses
+// for Options.SegmentRotationInterval, since there's no other way for this
+// process to learn that a separate process overwrote the marker. It exits
+// once the WAL is closed.
+func (w *WAL) startFencingTimer(interval time.Duration) {
+	w.fencingTimerDone = make(chan struct{})
+	go func() {
+		ticker := w.options.Clock.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C():
+				w.checkFencingToken()
+			case <-w.fencingTimerDone:
+				return
+			}
+		}
+	}()
+}
+
+// checkFencingToken re-reads dirPath's fence marker and sets w.fenced if
+// it now holds a token higher than Options.FencingToken, i.e. a later Open
+// elsewhere has taken over as writer. It swallows a read error the same
+// way rotateIfSegmentTooOld swallows rotateLocked's: there's no caller
+// here to return it to, and the next real Write will hit the same disk
+// error and report it properly.
+func (w *WAL) checkFencingToken() {
+	token, ok, err := readFenceToken(w.options.FS, w.options.DirPath)
+	if err != nil || !ok || token <= w.options.FencingToken {
+		return
+	}
+	w.mu.Lock()
+	w.fenced = true
+	w.mu.Unlock()
+}
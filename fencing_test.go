@@ -0,0 +1,98 @@
+package wal
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFencingTokenIsANoOpWhenUnset(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-fencing-noop-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("hello"))
+	require.Nil(t, err)
+}
+
+func TestOpenRejectsAFencingTokenNotHigherThanTheCurrentOne(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-fencing-reject-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithFencingToken(5))
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+
+	_, err = Open(WithDirPath(dir), WithFencingToken(5))
+	require.ErrorContains(t, err, "FencingToken")
+
+	_, err = Open(WithDirPath(dir), WithFencingToken(4))
+	require.ErrorContains(t, err, "FencingToken")
+
+	w2, err := Open(WithDirPath(dir), WithFencingToken(6))
+	require.Nil(t, err)
+	defer w2.Close()
+}
+
+func TestANewerOpenFencesOutThePreviousWriter(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-fencing-handoff-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w1, err := Open(WithDirPath(dir), WithFencingToken(1), WithFencingCheckInterval(5*time.Millisecond))
+	require.Nil(t, err)
+	defer w1.Close()
+
+	_, err = w1.Write([]byte("epoch one"))
+	require.Nil(t, err)
+
+	// Simulate w1's process being killed without a clean Close: its
+	// advisory directory lock disappears, but (unlike a real crash) its
+	// goroutines -- in particular, its fencing poller -- keep running, so
+	// this test can still observe ErrFenced instead of just losing track
+	// of w1 entirely.
+	require.Nil(t, w1.dirLock.Close())
+
+	w2, err := Open(WithDirPath(dir), WithFencingToken(2), WithFencingCheckInterval(5*time.Millisecond))
+	require.Nil(t, err)
+	defer w2.Close()
+
+	require.Eventually(t, func() bool {
+		_, err := w1.Write([]byte("epoch one, still?"))
+		return errors.Is(err, ErrFenced)
+	}, time.Second, 5*time.Millisecond)
+
+	_, err = w2.Write([]byte("epoch two"))
+	require.Nil(t, err)
+}
+
+func TestFencingCheckIntervalIsANoOpWithoutAFencingToken(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-fencing-interval-noop-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithFencingCheckInterval(5*time.Millisecond))
+	require.Nil(t, err)
+	defer w.Close()
+
+	time.Sleep(20 * time.Millisecond)
+	_, err = w.Write([]byte("hello"))
+	require.Nil(t, err)
+}
+
+func TestOpenRejectsNegativeFencingCheckInterval(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-fencing-validate-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	_, err = Open(WithDirPath(dir), WithFencingCheckInterval(-time.Second))
+	require.ErrorContains(t, err, "FencingCheckInterval")
+}
@@ -0,0 +1,146 @@
+package wal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// filePipeline keeps a small number of next-segment files pre-created,
+// pre-allocated to a fixed size, and locked in a background goroutine, so
+// that segment rotation on the hot write path only has to rename a ready
+// file into place instead of opening, allocating, and locking a new one
+// itself. It is modeled on etcd's wal/file_pipeline.go, including Recycle,
+// which lets Options.RecycleSegments feed a retired segment's file back in
+// as one of these ready files instead of deleting it.
+//
+// A filePipeline is created by a WAL when Options.SegmentPreallocate is
+// greater than 0, and is torn down when the WAL is closed.
+type filePipeline struct {
+	dir  string
+	ext  string
+	size int64
+
+	count int
+
+	filec chan *os.File
+	errc  chan error
+	donec chan struct{}
+}
+
+// newFilePipeline starts a filePipeline that creates files named
+// "pipeline-N<ext>" inside dir, each pre-allocated to size bytes, and
+// keeps up to cap ready files buffered in its channel.
+//
+// Files it creates are always opened without O_DIRECT, even when
+// Options.DirectIO is set: a preallocated file is handed off to
+// newSegmentFile as a plain *os.File, and giving it O_DIRECT here would
+// mean the very first unaligned chunk write to it -- before a WAL write
+// ever has a chance to react to the failure -- could fail outright. See
+// Options.DirectIO.
+func newFilePipeline(dir, ext string, size int64, cap int) *filePipeline {
+	fp := &filePipeline{
+		dir:   dir,
+		ext:   ext,
+		size:  size,
+		filec: make(chan *os.File, cap),
+		errc:  make(chan error, 1),
+		donec: make(chan struct{}),
+	}
+	go fp.run()
+	return fp
+}
+
+// Get returns the next pre-allocated, pre-locked segment file, blocking
+// until one is ready or the pipeline fails.
+func (fp *filePipeline) Get() (*os.File, error) {
+	select {
+	case f := <-fp.filec:
+		return f, nil
+	case err := <-fp.errc:
+		return nil, err
+	}
+}
+
+// Close stops the pipeline and unlinks any pre-allocated file that was
+// buffered but never handed out.
+func (fp *filePipeline) Close() error {
+	close(fp.donec)
+	for f := range fp.filec {
+		f.Close()
+		os.Remove(f.Name())
+	}
+	return <-fp.errc
+}
+
+// Recycle offers a retired segment's file for reuse as a future segment
+// instead of letting the caller delete it, for Options.RecycleSegments.
+// Like a file alloc creates, it is truncated and re-fallocated to the
+// pipeline's size -- wiping its old chunks, since a reader must never
+// mistake them for live data in whatever segment it's reused as -- and
+// then queued on filec under its current name exactly as alloc's own
+// file would be; newSegmentFile renames it into place either way.
+//
+// It returns false, leaving f open and unmodified for the caller to
+// close and remove itself, if filec already has as many files buffered
+// or in flight as the pipeline is configured to hold.
+func (fp *filePipeline) Recycle(f *os.File) bool {
+	if err := f.Truncate(0); err != nil {
+		return false
+	}
+	if err := lockFile(f); err != nil {
+		return false
+	}
+	if err := preallocate(f, fp.size); err != nil {
+		return false
+	}
+	select {
+	case fp.filec <- f:
+		return true
+	default:
+		return false
+	}
+}
+
+func (fp *filePipeline) alloc() (*os.File, error) {
+	// Name every file after a monotonically increasing counter rather
+	// than alternating between a fixed handful of names: with more than
+	// two files buffered or in flight (SegmentPreallocate > 2), reusing a
+	// name could collide with a file that was already handed out and is
+	// still being renamed into its final segment name.
+	path := filepath.Join(fp.dir, fmt.Sprintf("pipeline-%d%s", fp.count, fp.ext))
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := preallocate(f, fp.size); err != nil {
+		f.Close()
+		return nil, err
+	}
+	fp.count++
+	return f, nil
+}
+
+func (fp *filePipeline) run() {
+	defer close(fp.errc)
+	defer close(fp.filec)
+	for {
+		f, err := fp.alloc()
+		if err != nil {
+			fp.errc <- err
+			return
+		}
+		select {
+		case fp.filec <- f:
+		case <-fp.donec:
+			f.Close()
+			os.Remove(f.Name())
+			return
+		}
+	}
+}
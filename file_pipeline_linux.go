@@ -0,0 +1,39 @@
+//go:build linux
+
+package wal
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// preallocate reserves size bytes for f using fallocate, which is
+// effectively instantaneous and does not require writing zero blocks.
+func preallocate(f *os.File, size int64) error {
+	if size <= 0 {
+		return nil
+	}
+	if err := unix.Fallocate(int(f.Fd()), 0, 0, size); err != nil {
+		// Some filesystems (notably tmpfs on older kernels) don't support
+		// fallocate; fall back to the portable zero-fill path rather than
+		// failing the whole pipeline.
+		if err == unix.ENOTSUP || err == unix.EOPNOTSUPP {
+			return preallocateByWriting(f, size)
+		}
+		return err
+	}
+	return nil
+}
+
+// lockFile takes an advisory, exclusive fcntl lock on f that is released
+// automatically when f is closed.
+func lockFile(f *os.File) error {
+	flock := unix.Flock_t{
+		Type:   unix.F_WRLCK,
+		Whence: int16(os.SEEK_SET),
+		Start:  0,
+		Len:    0,
+	}
+	return unix.FcntlFlock(f.Fd(), unix.F_SETLK, &flock)
+}
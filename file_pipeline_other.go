@@ -0,0 +1,18 @@
+//go:build !linux
+
+package wal
+
+import "os"
+
+// preallocate reserves size bytes for f. Platforms without fallocate fall
+// back to writing zero blocks, which is slower but portable.
+func preallocate(f *os.File, size int64) error {
+	return preallocateByWriting(f, size)
+}
+
+// lockFile is a no-op on platforms without fcntl-style advisory locking
+// support in this package. The file pipeline still avoids handing out a
+// file that's in use by alternating names in filePipeline.alloc.
+func lockFile(f *os.File) error {
+	return nil
+}
@@ -0,0 +1,28 @@
+package wal
+
+import "os"
+
+// preallocateBlockSize is the chunk size used by preallocateByWriting. It
+// is large enough to make the zero-fill loop reasonably efficient without
+// holding a large buffer in memory.
+const preallocateBlockSize = 4 * MB
+
+// preallocateByWriting reserves size bytes for f by writing zero blocks and
+// seeking back to the start. It is the portable fallback used on platforms,
+// and filesystems, without a native preallocate syscall.
+func preallocateByWriting(f *os.File, size int64) error {
+	zeroes := make([]byte, preallocateBlockSize)
+	var written int64
+	for written < size {
+		n := int64(len(zeroes))
+		if remaining := size - written; remaining < n {
+			n = remaining
+		}
+		if _, err := f.WriteAt(zeroes[:n], written); err != nil {
+			return err
+		}
+		written += n
+	}
+	_, err := f.Seek(0, os.SEEK_SET)
+	return err
+}
@@ -0,0 +1,78 @@
+package wal
+
+import (
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteWithFlagsRoundTripsThroughReadWithFlags(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-flags-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"))
+	require.Nil(t, err)
+	defer w.Close()
+
+	pos, err := w.WriteWithFlags([]byte("tombstone record"), 0x7F)
+	require.Nil(t, err)
+	assert.Equal(t, uint32(len("tombstone record")+1), pos.ChunkSize)
+
+	data, flags, err := w.ReadWithFlags(pos)
+	require.Nil(t, err)
+	assert.Equal(t, byte(0x7F), flags)
+	assert.Equal(t, "tombstone record", string(data))
+}
+
+func TestReaderNextWithFlagsRoundTrips(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-flags-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.WriteWithFlags([]byte("first"), 1)
+	require.Nil(t, err)
+	_, err = w.WriteWithFlags([]byte("second"), 2)
+	require.Nil(t, err)
+
+	r := w.NewReader()
+
+	data, flags, _, err := r.NextWithFlags()
+	require.Nil(t, err)
+	assert.Equal(t, byte(1), flags)
+	assert.Equal(t, "first", string(data))
+
+	data, flags, _, err = r.NextWithFlags()
+	require.Nil(t, err)
+	assert.Equal(t, byte(2), flags)
+	assert.Equal(t, "second", string(data))
+
+	_, _, _, err = r.NextWithFlags()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestWriteWithFlagsSurvivesCompressionAndEncryption(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-flags-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	key := make([]byte, 32)
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"), WithCompression(CompressionSnappy), WithEncryption(key))
+	require.Nil(t, err)
+	defer w.Close()
+
+	pos, err := w.WriteWithFlags([]byte("schema v2 payload"), 0x02)
+	require.Nil(t, err)
+
+	data, flags, err := w.ReadWithFlags(pos)
+	require.Nil(t, err)
+	assert.Equal(t, byte(0x02), flags)
+	assert.Equal(t, "schema v2 payload", string(data))
+}
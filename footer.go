@@ -0,0 +1,104 @@
+package wal
+
+import "encoding/binary"
+
+// footerMagic marks the fixed-size trailer seal appends immediately after
+// a sealed segment's last chunk. Its presence lets openSegmentFile trust
+// the segment's logical end, chunk count, and sparse index straight off
+// disk instead of re-deriving them by reading every chunk -- previously
+// the only way to reconstruct them, and the reason Open's cost grew with
+// a directory's total size rather than just its active segment.
+const footerMagic = 0x57414c46 // "WALF"
+
+// footerHeaderSize is the fixed portion of a footer: everything but the
+// sparse index entries that follow it, whose count varies with how many
+// chunks the segment holds.
+const footerHeaderSize = 4 /*magic*/ + 8 /*offset*/ + 8 /*chunkCount*/ + 4 /*checksum*/ + 4 /*index entry count*/
+
+// footerIndexEntrySize is the serialized size, in bytes, of one
+// sparseIndexEntry within a footer.
+const footerIndexEntrySize = 8 /*sequence*/ + 8 /*offset*/
+
+// footer is the trailer seal writes after a segment's last chunk: enough
+// of the segment's own bookkeeping state for openSegmentFile to reload it
+// verbatim instead of rebuilding it by reading every chunk.
+type footer struct {
+	offset     int64  // the segment's logical end, same as what scanToLogicalEnd would have found
+	chunkCount int64  // number of chunks written to the segment
+	checksum   uint32 // checksum, under the segment's own Checksum algorithm, of its [segmentHeaderSize, segmentHeaderSize+offset) bytes
+	index      []sparseIndexEntry
+}
+
+// writeFooter appends f to fd at fd's current write offset, which the
+// caller (seal) has already positioned just past the segment's last
+// chunk. The index entries come first and the fixed-size header last, so
+// readFooter can locate that header from the file's total size alone
+// without already knowing how many index entries precede it.
+func writeFooter(fd File, f footer) error {
+	buf := make([]byte, len(f.index)*footerIndexEntrySize+footerHeaderSize)
+	for i, entry := range f.index {
+		start := i * footerIndexEntrySize
+		binary.BigEndian.PutUint64(buf[start:start+8], entry.sequence)
+		binary.BigEndian.PutUint64(buf[start+8:start+16], uint64(entry.offset))
+	}
+	head := buf[len(f.index)*footerIndexEntrySize:]
+	binary.BigEndian.PutUint32(head[0:4], footerMagic)
+	binary.BigEndian.PutUint64(head[4:12], uint64(f.offset))
+	binary.BigEndian.PutUint64(head[12:20], uint64(f.chunkCount))
+	binary.BigEndian.PutUint32(head[20:24], f.checksum)
+	binary.BigEndian.PutUint32(head[24:28], uint32(len(f.index)))
+	_, err := fd.Write(buf)
+	return err
+}
+
+// readFooter reads the footer at the very end of fd, whose total size is
+// fileSize, and reports whether one was found at all: an older segment
+// sealed before this feature existed, or one whose footer write never
+// made it to disk before a crash, has none, and the caller should fall
+// back to scanning the segment itself.
+func readFooter(fd File, fileSize int64) (footer, bool, error) {
+	if fileSize < footerHeaderSize {
+		return footer{}, false, nil
+	}
+	head := make([]byte, footerHeaderSize)
+	if _, err := fd.ReadAt(head, fileSize-footerHeaderSize); err != nil {
+		return footer{}, false, err
+	}
+	if binary.BigEndian.Uint32(head[0:4]) != footerMagic {
+		return footer{}, false, nil
+	}
+
+	f := footer{
+		offset:     int64(binary.BigEndian.Uint64(head[4:12])),
+		chunkCount: int64(binary.BigEndian.Uint64(head[12:20])),
+		checksum:   binary.BigEndian.Uint32(head[20:24]),
+	}
+	indexCount := int(binary.BigEndian.Uint32(head[24:28]))
+
+	// The footer's total size depends on indexCount, so the magic check
+	// above isn't enough on its own: cross-check that the file is exactly
+	// as long as a footer with this many index entries, appended right
+	// after offset bytes of chunk data, would make it. A torn footer
+	// write (a crash mid-append) fails this and falls back to scanning,
+	// the same as a missing footer.
+	wantSize := segmentHeaderSize + f.offset + footerHeaderSize + int64(indexCount)*footerIndexEntrySize
+	if wantSize != fileSize {
+		return footer{}, false, nil
+	}
+
+	if indexCount > 0 {
+		body := make([]byte, indexCount*footerIndexEntrySize)
+		if _, err := fd.ReadAt(body, fileSize-footerHeaderSize-int64(len(body))); err != nil {
+			return footer{}, false, err
+		}
+		f.index = make([]sparseIndexEntry, indexCount)
+		for i := range f.index {
+			start := i * footerIndexEntrySize
+			f.index[i] = sparseIndexEntry{
+				sequence: binary.BigEndian.Uint64(body[start : start+8]),
+				offset:   int64(binary.BigEndian.Uint64(body[start+8 : start+16])),
+			}
+		}
+	}
+	return f, true, nil
+}
@@ -0,0 +1,85 @@
+package wal
+
+import (
+	"io"
+	"os"
+)
+
+// File is the subset of *os.File's behavior a segment file needs. *os.File
+// already implements it; an FS backed by something other than a real OS
+// file (an in-memory filesystem for tests, a fault-injecting wrapper, ...)
+// needs to satisfy it explicitly.
+type File interface {
+	io.Reader
+	io.ReaderAt
+	io.WriterAt
+	io.Writer
+	io.Seeker
+	io.Closer
+	Sync() error
+	Stat() (os.FileInfo, error)
+	Name() string
+	Truncate(size int64) error
+}
+
+// FS abstracts the file operations segment files and segment listing need,
+// so a WAL can run on something other than the real OS filesystem: afero
+// for tests, a fault-injection wrapper, or another virtual filesystem. Set
+// it with WithFS; it defaults to the real OS filesystem.
+//
+// WithSegmentPreallocate's file pipeline, Repair, and Checkpoint are not
+// routed through FS yet -- they still operate on the real OS filesystem
+// directly -- so a custom FS is currently only compatible with the basic
+// Open/Write/Read/rotate path. Open's directory locking (see
+// ErrDirectoryLocked) is skipped for any FS other than the default, since
+// there's no second OS process to race with on an in-memory or otherwise
+// virtual filesystem.
+type FS interface {
+	// OpenFile opens (and, with O_CREATE, creates) the named file.
+	OpenFile(name string, flag int, perm os.FileMode) (File, error)
+	// Remove removes the named file.
+	Remove(name string) error
+	// ReadDir lists the named directory's entries.
+	ReadDir(name string) ([]os.DirEntry, error)
+	// MkdirAll creates the named directory, and any parents, if they don't
+	// already exist.
+	MkdirAll(path string, perm os.FileMode) error
+	// SyncDir fsyncs the named directory, so that a file just created,
+	// renamed into it, or removed from it is durable even if it isn't
+	// itself fsynced again afterward. A crash right after rotation, before
+	// this runs, can otherwise resurrect a segment file that was deleted or
+	// lose one that was just created, even though its own data was synced
+	// just fine.
+	SyncDir(path string) error
+}
+
+// osFS is the default FS, backed directly by the os package.
+type osFS struct{}
+
+func (osFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	return os.OpenFile(name, flag, perm)
+}
+
+func (osFS) Remove(name string) error { return os.Remove(name) }
+
+func (osFS) ReadDir(name string) ([]os.DirEntry, error) { return os.ReadDir(name) }
+
+func (osFS) MkdirAll(path string, perm os.FileMode) error { return os.MkdirAll(path, perm) }
+
+func (osFS) SyncDir(path string) error { return syncDir(path) }
+
+// syncDir opens path (a directory) and fsyncs it. It is a free function,
+// not a osFS method, so the real-OS-filesystem-only code paths that don't
+// carry an FS around -- newSegmentFile, segment.closeAndRemove -- can call
+// it directly.
+func syncDir(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
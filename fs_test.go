@@ -0,0 +1,26 @@
+package wal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithFSRunsEntirelyInMemory(t *testing.T) {
+	w, err := Open(WithDirPath("/mem"), WithFS(newMemFS()))
+	require.Nil(t, err)
+	defer w.Close()
+
+	pos, err := w.Write([]byte("hello from memory"))
+	require.Nil(t, err)
+
+	data, err := w.Read(pos)
+	require.Nil(t, err)
+	require.Equal(t, []byte("hello from memory"), data)
+}
+
+func TestWithFSNilRestoresDefault(t *testing.T) {
+	options := DefaultOptions
+	WithFS(nil)(&options)
+	require.Equal(t, osFS{}, options.FS)
+}
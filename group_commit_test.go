@@ -0,0 +1,56 @@
+package wal
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupCommitBatchesConcurrentSyncs(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-group-commit-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSync(true), WithGroupCommit(true))
+	require.Nil(t, err)
+	defer w.Close()
+
+	const writers = 32
+	var wg sync.WaitGroup
+	positions := make([]*ChunkPosition, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			pos, err := w.Write([]byte(fmt.Sprintf("record-%d", i)))
+			require.Nil(t, err)
+			positions[i] = pos
+		}(i)
+	}
+	wg.Wait()
+
+	for i, pos := range positions {
+		data, err := w.Read(pos)
+		require.Nil(t, err)
+		require.Equal(t, fmt.Sprintf("record-%d", i), string(data))
+	}
+}
+
+func TestGroupCommitSingleWriter(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-group-commit-solo-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSync(true), WithGroupCommit(true))
+	require.Nil(t, err)
+	defer w.Close()
+
+	pos, err := w.Write([]byte("hello"))
+	require.Nil(t, err)
+	data, err := w.Read(pos)
+	require.Nil(t, err)
+	require.Equal(t, "hello", string(data))
+}
@@ -0,0 +1,71 @@
+package wal
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenRejectsFileMissingMagicNumber(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-header-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	f, err := os.Create(segmentFileName(dir, ".SEG", 1, nil))
+	require.Nil(t, err)
+	_, err = f.Write(make([]byte, segmentHeaderSize))
+	require.Nil(t, err)
+	require.Nil(t, f.Close())
+
+	_, err = Open(WithDirPath(dir))
+	assert.ErrorIs(t, err, ErrNotASegmentFile)
+}
+
+func TestOpenRejectsNewerFormatVersion(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-header-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	f, err := os.Create(segmentFileName(dir, ".SEG", 1, nil))
+	require.Nil(t, err)
+	require.Nil(t, writeSegmentHeader(f, ModeAhead, ChecksumCRC32, CompressionNone, 0, 0, false))
+	_, err = f.WriteAt([]byte{segmentFormatVersion + 1}, versionByte)
+	require.Nil(t, err)
+	require.Nil(t, f.Close())
+
+	_, err = Open(WithDirPath(dir))
+	assert.ErrorIs(t, err, ErrUnsupportedFormatVersion)
+}
+
+func TestCompressionMismatchOnReopen(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-header-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithCompression(CompressionSnappy))
+	require.Nil(t, err)
+	_, err = w.Write([]byte("hello"))
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+
+	_, err = Open(WithDirPath(dir), WithCompression(CompressionZstd))
+	assert.ErrorIs(t, err, ErrCompressionMismatch)
+}
+
+func TestBlockSizeMismatchOnReopenIsNotAnError(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-header-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithBlockSize(64))
+	require.Nil(t, err)
+	_, err = w.Write([]byte("hello"))
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+
+	w2, err := Open(WithDirPath(dir), WithBlockSize(128))
+	require.Nil(t, err)
+	defer w2.Close()
+}
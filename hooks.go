@@ -0,0 +1,37 @@
+package wal
+
+// Hooks holds optional callbacks bracketing Write, WriteAll, Read, and
+// Sync, meant for wiring in distributed tracing (OpenTelemetry or
+// otherwise) without the WAL depending on a tracing library directly.
+//
+// Each Before hook's return value is passed to its matching After hook as
+// state, so a hook pair can carry a span (or anything else) between them:
+//
+//	hooks := wal.Hooks{
+//		BeforeSync: func() any {
+//			_, span := tracer.Start(ctx, "wal.Sync")
+//			return span
+//		},
+//		AfterSync: func(state any, err error) {
+//			span := state.(trace.Span)
+//			if err != nil {
+//				span.RecordError(err)
+//			}
+//			span.End()
+//		},
+//	}
+//
+// A nil hook is simply skipped. Set via WithHooks.
+type Hooks struct {
+	BeforeWrite func() any
+	AfterWrite  func(state any, pos *ChunkPosition, err error)
+
+	BeforeWriteAll func() any
+	AfterWriteAll  func(state any, positions []*ChunkPosition, err error)
+
+	BeforeRead func() any
+	AfterRead  func(state any, data []byte, err error)
+
+	BeforeSync func() any
+	AfterSync  func(state any, err error)
+}
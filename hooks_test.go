@@ -0,0 +1,83 @@
+package wal
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHooksBracketWriteReadSync(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-hooks-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	var events []string
+	hooks := Hooks{
+		BeforeWrite: func() any {
+			events = append(events, "before-write")
+			return "write-state"
+		},
+		AfterWrite: func(state any, pos *ChunkPosition, err error) {
+			require.Equal(t, "write-state", state)
+			require.Nil(t, err)
+			events = append(events, "after-write")
+		},
+		BeforeRead: func() any {
+			events = append(events, "before-read")
+			return "read-state"
+		},
+		AfterRead: func(state any, data []byte, err error) {
+			require.Equal(t, "read-state", state)
+			require.Nil(t, err)
+			events = append(events, "after-read")
+		},
+		BeforeSync: func() any {
+			events = append(events, "before-sync")
+			return "sync-state"
+		},
+		AfterSync: func(state any, err error) {
+			require.Equal(t, "sync-state", state)
+			require.Nil(t, err)
+			events = append(events, "after-sync")
+		},
+	}
+
+	w, err := Open(WithDirPath(dir), WithHooks(hooks))
+	require.Nil(t, err)
+	defer w.Close()
+
+	pos, err := w.Write([]byte("hello"))
+	require.Nil(t, err)
+	_, err = w.Read(pos)
+	require.Nil(t, err)
+	_, err = w.Sync()
+	require.Nil(t, err)
+
+	require.Equal(t, []string{
+		"before-write", "after-write",
+		"before-read", "after-read",
+		"before-sync", "after-sync",
+	}, events)
+}
+
+func TestHooksSeeWriteErrors(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-hooks-error-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	var gotErr error
+	hooks := Hooks{
+		AfterWrite: func(_ any, _ *ChunkPosition, err error) {
+			gotErr = err
+		},
+	}
+
+	w, err := Open(WithDirPath(dir), WithHooks(hooks))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.Write(nil)
+	require.Equal(t, ErrEmptyRecord, err)
+	require.Equal(t, ErrEmptyRecord, gotErr)
+}
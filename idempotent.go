@@ -0,0 +1,81 @@
+package wal
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+)
+
+// idempotencyPrefixSize is the size, in bytes, of the envelope
+// WriteIdempotent prepends onto data: the caller's id, then the wall-clock
+// time the chunk was written, each 8 bytes -- the same order
+// ReadIdempotent and Reader.NextIdempotent expect back.
+const idempotencyPrefixSize = 8 + 8
+
+// ErrIdempotencyWindowDisabled is returned by WriteIdempotent when the WAL
+// was opened without Options.IdempotencyWindow: there's no window to
+// deduplicate retries within, so a caller wanting that has to set one.
+var ErrIdempotencyWindowDisabled = fmt.Errorf("wal: WriteIdempotent requires Options.IdempotencyWindow to be set")
+
+// idempotencyEntry is what WriteIdempotent remembers, for as long as
+// Options.IdempotencyWindow allows, about one id it has already written:
+// the position the original write landed at, and when the window on it
+// closes.
+type idempotencyEntry struct {
+	pos       *ChunkPosition
+	expiresAt time.Time
+}
+
+// prefixIdempotency prepends id and writtenAt onto data, in the fixed
+// layout WriteIdempotent, ReadIdempotent, and Reader.NextIdempotent all
+// agree on.
+func prefixIdempotency(id uint64, writtenAt time.Time, data []byte) []byte {
+	prefixed := make([]byte, idempotencyPrefixSize+len(data))
+	binary.BigEndian.PutUint64(prefixed[:8], id)
+	binary.BigEndian.PutUint64(prefixed[8:idempotencyPrefixSize], uint64(writtenAt.UnixNano()))
+	copy(prefixed[idempotencyPrefixSize:], data)
+	return prefixed
+}
+
+// stripIdempotency reverses prefixIdempotency, splitting raw's leading
+// idempotencyPrefixSize bytes back into the id and write time WriteIdempotent
+// recorded them as.
+func stripIdempotency(raw []byte) (data []byte, id uint64, writtenAt time.Time) {
+	id = binary.BigEndian.Uint64(raw[:8])
+	writtenAt = time.Unix(0, int64(binary.BigEndian.Uint64(raw[8:idempotencyPrefixSize])))
+	return raw[idempotencyPrefixSize:], id, writtenAt
+}
+
+// rebuildIdempotencyWindow repopulates w.idempotencySeen from whatever
+// WriteIdempotent chunks are already on disk, so a retry landing right
+// after a restart still finds the id its previous attempt used before the
+// crash or close -- the "reconstructed on recovery" half of
+// Options.IdempotencyWindow's dedup window. It reads the whole log once,
+// oldest to newest, the same way VerifyAll and Dispatcher.Replay do,
+// letting a later write for the same id overwrite an earlier one the way
+// WriteIdempotent's own bookkeeping already would have as they happened.
+// Entries whose window has already closed are skipped rather than kept
+// around just to be evicted on first use.
+func (w *WAL) rebuildIdempotencyWindow() error {
+	now := time.Now()
+	r := w.NewReader()
+	for {
+		raw, pos, err := r.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if len(raw) < idempotencyPrefixSize {
+			continue
+		}
+		_, id, writtenAt := stripIdempotency(raw)
+		expiresAt := writtenAt.Add(w.options.IdempotencyWindow)
+		if expiresAt.Before(now) {
+			continue
+		}
+		w.idempotencySeen[id] = idempotencyEntry{pos: pos, expiresAt: expiresAt}
+	}
+}
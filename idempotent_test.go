@@ -0,0 +1,114 @@
+package wal
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteIdempotentDedupsWithinWindow(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-idempotent-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithIdempotencyWindow(time.Minute))
+	require.Nil(t, err)
+	defer w.Close()
+
+	first, err := w.WriteIdempotent(1, []byte("hello"))
+	require.Nil(t, err)
+
+	second, err := w.WriteIdempotent(1, []byte("hello, but different"))
+	require.Nil(t, err)
+	require.True(t, first.Equal(second), "retry within the window should return the original position")
+
+	data, id, err := w.ReadIdempotent(first)
+	require.Nil(t, err)
+	require.Equal(t, uint64(1), id)
+	require.Equal(t, []byte("hello"), data)
+}
+
+func TestWriteIdempotentWritesAgainAfterWindowExpires(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-idempotent-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithIdempotencyWindow(time.Millisecond))
+	require.Nil(t, err)
+	defer w.Close()
+
+	first, err := w.WriteIdempotent(1, []byte("hello"))
+	require.Nil(t, err)
+
+	time.Sleep(10 * time.Millisecond)
+
+	second, err := w.WriteIdempotent(1, []byte("hello again"))
+	require.Nil(t, err)
+	require.False(t, first.Equal(second), "retry after the window closed should append a new chunk")
+}
+
+func TestWriteIdempotentRejectsCallsWithoutAWindow(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-idempotent-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.WriteIdempotent(1, []byte("hello"))
+	require.True(t, errors.Is(err, ErrIdempotencyWindowDisabled))
+}
+
+// TestIdempotencyWindowReconstructedOnRecovery confirms a retry landing
+// right after a restart is still deduplicated against a write from before
+// it, i.e. the in-memory window is rebuilt from what's already on disk
+// rather than starting empty.
+func TestIdempotencyWindowReconstructedOnRecovery(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-idempotent-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithIdempotencyWindow(time.Minute))
+	require.Nil(t, err)
+	first, err := w.WriteIdempotent(1, []byte("hello"))
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+
+	w, err = Open(WithDirPath(dir), WithIdempotencyWindow(time.Minute))
+	require.Nil(t, err)
+	defer w.Close()
+
+	second, err := w.WriteIdempotent(1, []byte("hello, but different"))
+	require.Nil(t, err)
+	require.True(t, first.Equal(second), "retry right after reopening should still hit the reconstructed window")
+}
+
+// TestIdempotencyWindowSkipsExpiredEntriesOnRecovery confirms an id whose
+// window had already closed before the restart is not reconstructed, so a
+// call with it after reopening is treated as new rather than deduplicated
+// forever.
+func TestIdempotencyWindowSkipsExpiredEntriesOnRecovery(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-idempotent-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithIdempotencyWindow(time.Millisecond))
+	require.Nil(t, err)
+	first, err := w.WriteIdempotent(1, []byte("hello"))
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+
+	time.Sleep(10 * time.Millisecond)
+
+	w, err = Open(WithDirPath(dir), WithIdempotencyWindow(time.Millisecond))
+	require.Nil(t, err)
+	defer w.Close()
+
+	second, err := w.WriteIdempotent(1, []byte("hello again"))
+	require.Nil(t, err)
+	require.False(t, first.Equal(second))
+}
@@ -0,0 +1,110 @@
+package wal
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeKMSProvider stands in for a KMS/Vault/HSM-backed KeyProvider: it
+// fetches key material from a map rather than holding it, and counts calls
+// to prove the WAL doesn't cache the raw bytes itself.
+type fakeKMSProvider struct {
+	keys        map[uint32][]byte
+	currentID   uint32
+	currentCall int
+	getCall     int
+}
+
+func (p *fakeKMSProvider) CurrentKey() (id uint32, key []byte, err error) {
+	p.currentCall++
+	key, ok := p.keys[p.currentID]
+	if !ok {
+		return 0, nil, fmt.Errorf("fake KMS: no such key %d", p.currentID)
+	}
+	return p.currentID, key, nil
+}
+
+func (p *fakeKMSProvider) GetKey(id uint32) ([]byte, error) {
+	p.getCall++
+	key, ok := p.keys[id]
+	if !ok {
+		return nil, &ErrUnknownKeyID{KeyID: id}
+	}
+	return key, nil
+}
+
+func TestWriteReadWithKeyProvider(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-keyprovider-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	key1 := bytes.Repeat([]byte{0x11}, 32)
+	provider := &fakeKMSProvider{keys: map[uint32][]byte{1: key1}, currentID: 1}
+	w, err := Open(WithDirPath(dir), WithKeyProvider(provider))
+	require.Nil(t, err)
+	defer w.Close()
+
+	pos, err := w.Write([]byte("sensitive PII"))
+	require.Nil(t, err)
+	require.Greater(t, provider.currentCall, 0)
+
+	got, err := w.Read(pos)
+	require.Nil(t, err)
+	assert.Equal(t, []byte("sensitive PII"), got)
+	assert.Greater(t, provider.getCall, 0)
+}
+
+func TestKeyProviderRotationKeepsOldSegmentsReadable(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-keyprovider-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	key1 := bytes.Repeat([]byte{0x11}, 32)
+	key2 := bytes.Repeat([]byte{0x22}, 32)
+	provider := &fakeKMSProvider{keys: map[uint32][]byte{1: key1, 2: key2}, currentID: 1}
+	w, err := Open(WithDirPath(dir), WithKeyProvider(provider))
+	require.Nil(t, err)
+
+	oldPos, err := w.Write([]byte("under key one"))
+	require.Nil(t, err)
+
+	provider.currentID = 2
+	newPos, err := w.Write([]byte("under key two"))
+	require.Nil(t, err)
+
+	got, err := w.Read(oldPos)
+	require.Nil(t, err)
+	assert.Equal(t, []byte("under key one"), got)
+
+	got, err = w.Read(newPos)
+	require.Nil(t, err)
+	assert.Equal(t, []byte("under key two"), got)
+	require.Nil(t, w.Close())
+}
+
+func TestOpenRejectsEncryptionKeyAndKeyProviderTogether(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-keyprovider-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	key := bytes.Repeat([]byte{0x11}, 32)
+	provider := &fakeKMSProvider{keys: map[uint32][]byte{1: key}, currentID: 1}
+	_, err = Open(WithDirPath(dir), WithEncryption(key), WithKeyProvider(provider))
+	require.ErrorContains(t, err, "mutually exclusive")
+}
+
+func TestOpenRejectsKeyringAndKeyProviderTogether(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-keyprovider-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	key := bytes.Repeat([]byte{0x11}, 32)
+	provider := &fakeKMSProvider{keys: map[uint32][]byte{1: key}, currentID: 1}
+	_, err = Open(WithDirPath(dir), WithKeyring(map[uint32][]byte{1: key}), WithKeyProvider(provider))
+	require.ErrorContains(t, err, "mutually exclusive")
+}
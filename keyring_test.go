@@ -0,0 +1,164 @@
+package wal
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteReadWithKeyring(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-keyring-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	key1 := bytes.Repeat([]byte{0x11}, 32)
+	w, err := Open(WithDirPath(dir), WithKeyring(map[uint32][]byte{1: key1}))
+	require.Nil(t, err)
+	defer w.Close()
+
+	pos, err := w.Write([]byte("sensitive PII"))
+	require.Nil(t, err)
+
+	got, err := w.Read(pos)
+	require.Nil(t, err)
+	assert.Equal(t, []byte("sensitive PII"), got)
+}
+
+func TestKeyringRotationKeepsOldSegmentsReadable(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-keyring-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	key1 := bytes.Repeat([]byte{0x11}, 32)
+	w, err := Open(WithDirPath(dir), WithSegmentSize(32), WithKeyring(map[uint32][]byte{1: key1}))
+	require.Nil(t, err)
+
+	oldPos, err := w.Write([]byte("0123456789"))
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+
+	// Rotate in a new key under a higher ID; the old one stays in the
+	// keyring so the segment written under it is still readable.
+	key2 := bytes.Repeat([]byte{0x22}, 32)
+	w2, err := Open(WithDirPath(dir), WithSegmentSize(32), WithKeyring(map[uint32][]byte{1: key1, 2: key2}))
+	require.Nil(t, err)
+	defer w2.Close()
+
+	got, err := w2.Read(oldPos)
+	require.Nil(t, err)
+	assert.Equal(t, []byte("0123456789"), got)
+
+	newPos, err := w2.Write([]byte("abcdefghij"))
+	require.Nil(t, err)
+	got, err = w2.Read(newPos)
+	require.Nil(t, err)
+	assert.Equal(t, []byte("abcdefghij"), got)
+}
+
+func TestKeyringUsesHighestIDAsActiveKey(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-keyring-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	key1 := bytes.Repeat([]byte{0x11}, 32)
+	key5 := bytes.Repeat([]byte{0x55}, 32)
+	w, err := Open(WithDirPath(dir), WithKeyring(map[uint32][]byte{1: key1, 5: key5}))
+	require.Nil(t, err)
+	defer w.Close()
+
+	id, key, err := w.keyProvider.CurrentKey()
+	require.Nil(t, err)
+	assert.Equal(t, uint32(5), id)
+	assert.Equal(t, key5, key)
+}
+
+func TestReadFailsWithErrUnknownKeyIDAfterKeyIsDropped(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-keyring-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	key1 := bytes.Repeat([]byte{0x11}, 32)
+	w, err := Open(WithDirPath(dir), WithKeyring(map[uint32][]byte{1: key1}))
+	require.Nil(t, err)
+	pos, err := w.Write([]byte("sensitive PII"))
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+
+	key2 := bytes.Repeat([]byte{0x22}, 32)
+	w2, err := Open(WithDirPath(dir), WithKeyring(map[uint32][]byte{2: key2}))
+	require.Nil(t, err)
+	defer w2.Close()
+
+	_, err = w2.Read(pos)
+	var unknown *ErrUnknownKeyID
+	require.ErrorAs(t, err, &unknown)
+	assert.Equal(t, uint32(1), unknown.KeyID)
+}
+
+func TestOpenRejectsBothEncryptionKeyAndKeyring(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-keyring-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	key := bytes.Repeat([]byte{0x11}, 32)
+	_, err = Open(WithDirPath(dir), WithEncryption(key), WithKeyring(map[uint32][]byte{1: key}))
+	require.ErrorContains(t, err, "mutually exclusive")
+}
+
+func TestRewriteWithKeyMovesOldSegmentsOntoActiveKey(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-keyring-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	key1 := bytes.Repeat([]byte{0x11}, 32)
+	w, err := Open(WithDirPath(dir), WithSegmentSize(32), WithKeyring(map[uint32][]byte{1: key1}))
+	require.Nil(t, err)
+
+	var positions []*ChunkPosition
+	for i := 0; i < 6; i++ {
+		pos, err := w.Write([]byte("0123456789"))
+		require.Nil(t, err)
+		positions = append(positions, pos)
+	}
+	require.Nil(t, w.Close())
+
+	key2 := bytes.Repeat([]byte{0x22}, 32)
+	w2, err := Open(WithDirPath(dir), WithSegmentSize(32), WithKeyring(map[uint32][]byte{1: key1, 2: key2}))
+	require.Nil(t, err)
+
+	remap, err := w2.RewriteWithKey()
+	require.Nil(t, err)
+	require.NotEmpty(t, remap)
+	require.Nil(t, w2.Close())
+
+	// Every sealed segment is now decodable with key 2 alone.
+	w3, err := Open(WithDirPath(dir), WithSegmentSize(32), WithKeyring(map[uint32][]byte{2: key2}))
+	require.Nil(t, err)
+	defer w3.Close()
+
+	for _, pos := range positions {
+		newPos, ok := remap[*pos]
+		if !ok {
+			continue // the active segment, which RewriteWithKey never touches
+		}
+		got, err := w3.Read(newPos)
+		require.Nil(t, err)
+		assert.Equal(t, []byte("0123456789"), got)
+	}
+}
+
+func TestRewriteWithKeyRequiresKeyring(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-keyring-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.RewriteWithKey()
+	assert.Equal(t, ErrKeyringRequired, err)
+}
@@ -0,0 +1,41 @@
+package wal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// lockFileName is the advisory lock file Open creates, if it doesn't
+// already exist, directly in DirPath.
+const lockFileName = "LOCK"
+
+// ErrDirectoryLocked is returned by Open when another process already
+// holds DirPath's advisory lock, which usually means two processes
+// accidentally pointed at the same WAL directory. Opening both for
+// writing at once would corrupt the log, since neither would see the
+// other's segment rotations.
+var ErrDirectoryLocked = fmt.Errorf("wal: directory is already locked by another process")
+
+// acquireDirLock takes an advisory, exclusive flock on dir's LOCK file,
+// held for as long as the returned file stays open, and returns
+// ErrDirectoryLocked if another process (or another Open of the same
+// directory in this one) already holds it.
+//
+// This goes straight to the real OS filesystem rather than through FS:
+// locking has no meaning for an in-memory or otherwise virtual
+// filesystem, where there is no second OS process to race with.
+func acquireDirLock(dir string) (*os.File, error) {
+	f, err := os.OpenFile(filepath.Join(dir, lockFileName), os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if err := flockExclusive(f); err != nil {
+		f.Close()
+		if isLockConflict(err) {
+			return nil, ErrDirectoryLocked
+		}
+		return nil, err
+	}
+	return f, nil
+}
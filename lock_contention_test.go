@@ -0,0 +1,83 @@
+package wal
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// delaySyncFS wraps osFS so tests can make the active segment's fsync take
+// a while on demand, without needing a real disk to be slow.
+type delaySyncFS struct {
+	osFS
+	delay *time.Duration
+}
+
+func (fs delaySyncFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	f, err := fs.osFS.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return delaySyncFile{f.(*os.File), fs.delay}, nil
+}
+
+type delaySyncFile struct {
+	*os.File
+	delay *time.Duration
+}
+
+func (f delaySyncFile) Sync() error {
+	time.Sleep(*f.delay)
+	return f.File.Sync()
+}
+
+// TestReadDoesNotBlockBehindASlowInlineFsync exercises the contention
+// WAL.Read used to have with Write: Write holds mu for its entire inline
+// fsync when Options.Sync is true, and Read used to need the same lock
+// just to resolve which segment pos lived in. A Read for a chunk already
+// sealed into an older segment must not wait that out.
+func TestReadDoesNotBlockBehindASlowInlineFsync(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-lock-contention-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	var delay time.Duration
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"), WithSegmentSize(64), WithFS(delaySyncFS{delay: &delay}), WithSync(true))
+	require.Nil(t, err)
+	defer w.Close()
+
+	// Fill and seal a segment so pos resolves to an older segment, not the
+	// active one the slow Write below will be fsyncing.
+	for i := 0; i < 10; i++ {
+		_, err := w.Write([]byte("filler record"))
+		require.Nil(t, err)
+	}
+	pos, err := w.Write([]byte("sealed record"))
+	require.Nil(t, err)
+	_, err = w.Write([]byte("rotate past it"))
+	require.Nil(t, err)
+
+	delay = 300 * time.Millisecond
+	writeDone := make(chan error, 1)
+	go func() {
+		_, err := w.Write([]byte("slow fsync"))
+		writeDone <- err
+	}()
+
+	readDone := make(chan error, 1)
+	go func() {
+		_, err := w.Read(pos)
+		readDone <- err
+	}()
+
+	select {
+	case err := <-readDone:
+		require.Nil(t, err)
+	case <-time.After(150 * time.Millisecond):
+		t.Fatal("Read was blocked by a concurrent Write's inline fsync")
+	}
+
+	require.Nil(t, <-writeDone)
+}
@@ -0,0 +1,25 @@
+//go:build linux
+
+package wal
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// flockExclusive takes an exclusive, non-blocking flock(2) lock on f.
+// Unlike the fcntl locking lockFile uses for the file pipeline, flock
+// locks are scoped to the open file description rather than the
+// process, so two Opens of the same directory from within the same
+// process conflict exactly as two separate processes would.
+func flockExclusive(f *os.File) error {
+	return unix.Flock(int(f.Fd()), unix.LOCK_EX|unix.LOCK_NB)
+}
+
+// isLockConflict reports whether err, as returned by flockExclusive,
+// means the lock is already held rather than some other failure.
+func isLockConflict(err error) bool {
+	return errors.Is(err, unix.EWOULDBLOCK)
+}
@@ -0,0 +1,20 @@
+//go:build !linux
+
+package wal
+
+import "os"
+
+// flockExclusive is a no-op on platforms without flock-style advisory
+// locking support in this package: Open still works, but without the
+// protection against a second process opening the same directory that
+// Options.ReadOnly aside, see ErrDirectoryLocked.
+func flockExclusive(f *os.File) error {
+	return nil
+}
+
+// isLockConflict reports whether err, as returned by flockExclusive,
+// means the lock is already held. flockExclusive never fails on this
+// platform, so this never has anything to report.
+func isLockConflict(err error) bool {
+	return false
+}
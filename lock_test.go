@@ -0,0 +1,51 @@
+package wal
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenTwiceReturnsErrDirectoryLocked(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-lock-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w1, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w1.Close()
+
+	_, err = Open(WithDirPath(dir))
+	require.Equal(t, ErrDirectoryLocked, err)
+}
+
+func TestOpenAfterCloseReacquiresLock(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-lock-reopen-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w1, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	require.Nil(t, w1.Close())
+
+	w2, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w2.Close()
+}
+
+func TestReadOnlyOpenDoesNotTakeLock(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-lock-readonly-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+	_, err = w.Write([]byte("hello"))
+	require.Nil(t, err)
+
+	ro, err := Open(WithDirPath(dir), WithReadOnly(true))
+	require.Nil(t, err)
+	defer ro.Close()
+}
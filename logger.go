@@ -0,0 +1,26 @@
+package wal
+
+// Logger receives structured log records for background events a WAL
+// decides on its own -- segment rotation, retention deletions, recovery
+// truncation, fsync failure -- that a caller has no other way to find out
+// about short of polling Stats. Its method set matches *log/slog.Logger's
+// levelled methods, so a *slog.Logger can be passed to WithLogger directly
+// without an adapter.
+//
+// Implementations must be safe for concurrent use by multiple goroutines.
+type Logger interface {
+	Debug(msg string, args ...any)
+	Info(msg string, args ...any)
+	Warn(msg string, args ...any)
+	Error(msg string, args ...any)
+}
+
+// nopLogger is the default Logger used when none is configured. Every
+// method is a no-op so the WAL can log unconditionally without a nil check
+// on the hot path.
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...any) {}
+func (nopLogger) Info(string, ...any)  {}
+func (nopLogger) Warn(string, ...any)  {}
+func (nopLogger) Error(string, ...any) {}
@@ -0,0 +1,113 @@
+package wal
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNopLogger(t *testing.T) {
+	// All methods must be safe to call and must not panic when no logger is
+	// configured.
+	var l Logger = nopLogger{}
+	l.Debug("debug")
+	l.Info("info")
+	l.Warn("warn")
+	l.Error("error")
+}
+
+func TestSlogLoggerSatisfiesLogger(t *testing.T) {
+	// *slog.Logger's method set matches Logger's exactly, so it must be
+	// usable with WithLogger without an adapter.
+	var _ Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// recordingLogger captures every call it receives, for tests to assert the
+// WAL logs the background events it's supposed to.
+type recordingLogger struct {
+	mu   sync.Mutex
+	msgs []string
+}
+
+func (r *recordingLogger) Debug(msg string, _ ...any) { r.record(msg) }
+func (r *recordingLogger) Info(msg string, _ ...any)  { r.record(msg) }
+func (r *recordingLogger) Warn(msg string, _ ...any)  { r.record(msg) }
+func (r *recordingLogger) Error(msg string, _ ...any) { r.record(msg) }
+
+func (r *recordingLogger) record(msg string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.msgs = append(r.msgs, msg)
+}
+
+func (r *recordingLogger) contains(msg string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, m := range r.msgs {
+		if m == msg {
+			return true
+		}
+	}
+	return false
+}
+
+func TestWithLoggerDefaultsToNop(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-logger-default-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	require.IsType(t, nopLogger{}, w.options.Logger)
+}
+
+func TestLoggerLogsSegmentRotation(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-logger-rotate-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	logger := &recordingLogger{}
+	w, err := Open(WithDirPath(dir), WithSegmentSize(32), WithLogger(logger))
+	require.Nil(t, err)
+	defer w.Close()
+
+	for i := 0; i < 6; i++ {
+		_, err := w.Write([]byte("0123456789"))
+		require.Nil(t, err)
+	}
+
+	require.True(t, logger.contains("wal: segment rotated"))
+}
+
+func TestLoggerLogsRepairTruncation(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-logger-repair-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	_, err = w.Write([]byte("hello"))
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+
+	segPath, _, err := lastSegmentFile(dir, DefaultOptions.SegmentFileExt, nil)
+	require.Nil(t, err)
+	f, err := os.OpenFile(segPath, os.O_RDWR, 0o644)
+	require.Nil(t, err)
+	info, err := f.Stat()
+	require.Nil(t, err)
+	require.Nil(t, f.Truncate(info.Size()-1))
+	require.Nil(t, f.Close())
+
+	logger := &recordingLogger{}
+	dropped, err := Repair(dir, WithLogger(logger))
+	require.Nil(t, err)
+	require.Greater(t, dropped, int64(0))
+	require.True(t, logger.contains("wal: repair truncated a torn tail write"))
+}
@@ -0,0 +1,29 @@
+package wal
+
+// Madvise selects the madvise access-pattern hint applied to a segment's
+// mapping once Options.MmapRead has mapped it; see Options.Madvise.
+type Madvise int
+
+const (
+	// MadviseNormal applies no hint, leaving the kernel's default
+	// readahead behavior in place. It is the default.
+	MadviseNormal Madvise = iota
+
+	// MadviseRandom disables readahead, for workloads that look up chunks
+	// by scattered ChunkPosition (e.g. an index keyed on it) rather than
+	// scanning a segment in order -- readahead the kernel would otherwise
+	// do just wastes I/O on pages the next Read won't touch.
+	MadviseRandom
+
+	// MadviseSequential doubles the kernel's readahead window and drops
+	// pages behind the current read as soon as they're used, for a
+	// sequential replay via NewReader or NewTailReader over many sealed
+	// segments.
+	MadviseSequential
+
+	// MadviseWillNeed tells the kernel to start reading the whole mapping
+	// in now, for a workload about to scan a segment it knows it will
+	// need in full shortly (e.g. right after TierSegments or
+	// CompressSegments fetches it back locally).
+	MadviseWillNeed
+)
@@ -0,0 +1,22 @@
+//go:build linux
+
+package wal
+
+import "golang.org/x/sys/unix"
+
+// madvise applies advice's hint to data, a mapping mmapFile returned. See
+// Options.Madvise.
+func madvise(data []byte, advice Madvise) error {
+	var a int
+	switch advice {
+	case MadviseRandom:
+		a = unix.MADV_RANDOM
+	case MadviseSequential:
+		a = unix.MADV_SEQUENTIAL
+	case MadviseWillNeed:
+		a = unix.MADV_WILLNEED
+	default:
+		return nil
+	}
+	return unix.Madvise(data, a)
+}
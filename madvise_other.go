@@ -0,0 +1,10 @@
+//go:build !linux
+
+package wal
+
+// madvise is a no-op on platforms without madvise -- mmapFile already
+// fails on them, so Options.MmapRead never gets far enough to call this.
+// See Options.Madvise.
+func madvise(data []byte, advice Madvise) error {
+	return nil
+}
@@ -0,0 +1,35 @@
+package wal
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMadviseServesSealedSegmentsUnderEveryHint(t *testing.T) {
+	for _, advice := range []Madvise{MadviseNormal, MadviseRandom, MadviseSequential, MadviseWillNeed} {
+		dir, err := os.MkdirTemp("", "wal-madvise-test")
+		require.Nil(t, err)
+
+		w, err := Open(WithDirPath(dir), WithSegmentSize(64), WithMmapRead(true), WithMadvise(advice))
+		require.Nil(t, err)
+
+		var positions []*ChunkPosition
+		for i := 0; i < 10; i++ {
+			pos, err := w.Write([]byte{byte('a' + i)})
+			require.Nil(t, err)
+			positions = append(positions, pos)
+		}
+		require.Greater(t, len(w.olderSegments), 0)
+
+		for i, pos := range positions {
+			data, err := w.Read(pos)
+			require.Nil(t, err)
+			require.Equal(t, []byte{byte('a' + i)}, data)
+		}
+
+		require.Nil(t, w.Close())
+		os.RemoveAll(dir)
+	}
+}
@@ -0,0 +1,177 @@
+package wal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ManagerOptions configures a Manager.
+type ManagerOptions struct {
+	// Dir is the root directory under which every named WAL gets its own
+	// subdirectory, one per name passed to Manager.WAL. Manager creates it
+	// if it doesn't already exist.
+	Dir string
+
+	// Options lists the Option values every WAL the Manager opens is
+	// opened with. WithDirPath and WithBufferPool are always overridden --
+	// the former with the named WAL's own subdirectory of Dir, the latter
+	// with the Manager's single shared BufferPool -- so there's no need to
+	// set either here.
+	Options []Option
+
+	// SyncInterval, if positive, makes the Manager run one background
+	// goroutine that calls Sync on every WAL it currently owns, in turn,
+	// every interval, instead of each WAL running its own
+	// Options.SyncInterval goroutine and ticker. Leave Options.SyncInterval
+	// unset on Options when using this, or both will run. Zero disables
+	// it, same as Options.SyncInterval would.
+	SyncInterval time.Duration
+
+	// Clock supplies the ticker that drives SyncInterval, in place of the
+	// real wall clock. It defaults to the real wall clock; set it to a
+	// *FakeClock in a test to drive the syncer with Tick instead of
+	// sleeping.
+	Clock Clock
+
+	// WriteBufferSize sizes the Manager's single shared BufferPool, the
+	// same way Options.WriteBufferSize sizes a standalone WAL's. Every WAL
+	// the Manager opens draws scratch buffers from this one pool rather
+	// than each keeping its own, since hundreds of rarely-all-busy-at-once
+	// partitions have little use for hundreds of separate pools. Zero
+	// disables pooling, same as Options.WriteBufferSize left unset would.
+	WriteBufferSize int
+}
+
+// Manager owns many named WAL instances rooted under one directory,
+// sharing a single BufferPool and, if ManagerOptions.SyncInterval is set,
+// a single background Sync scheduler across all of them -- avoiding the
+// per-instance buffer pool and goroutine/ticker overhead of opening
+// hundreds of WALs, e.g. one per partition, by hand. Create one with
+// OpenManager.
+type Manager struct {
+	opts ManagerOptions
+	pool BufferPool
+
+	mu   sync.Mutex
+	wals map[string]*WAL
+
+	syncerDone chan struct{}
+}
+
+// OpenManager returns a Manager rooted at opts.Dir. It does not open any
+// WAL itself; call WAL for each name as it's needed, which creates that
+// name's subdirectory the first time it's asked for.
+func OpenManager(opts ManagerOptions) (*Manager, error) {
+	if err := os.MkdirAll(opts.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("wal: failed to create manager dir: %w", err)
+	}
+
+	if opts.Clock == nil {
+		opts.Clock = realClock{}
+	}
+
+	m := &Manager{
+		opts: opts,
+		pool: newSyncBufferPool(opts.WriteBufferSize),
+		wals: make(map[string]*WAL),
+	}
+	if opts.SyncInterval > 0 {
+		m.startSyncer(opts.SyncInterval)
+	}
+	return m, nil
+}
+
+// WAL returns the named WAL, opening it under its own subdirectory of
+// ManagerOptions.Dir the first time name is asked for, and returning the
+// same instance on every later call for that name.
+func (m *Manager) WAL(name string) (*WAL, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if w, ok := m.wals[name]; ok {
+		return w, nil
+	}
+
+	opts := append([]Option{}, m.opts.Options...)
+	opts = append(opts, WithDirPath(filepath.Join(m.opts.Dir, name)), WithBufferPool(m.pool))
+	w, err := Open(opts...)
+	if err != nil {
+		return nil, err
+	}
+	m.wals[name] = w
+	return w, nil
+}
+
+// Names returns the name of every WAL currently open under the Manager.
+func (m *Manager) Names() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	names := make([]string, 0, len(m.wals))
+	for name := range m.wals {
+		names = append(names, name)
+	}
+	return names
+}
+
+// Sync calls Sync on every WAL currently open under the Manager, stopping
+// at and returning the first error, if any, leaving the rest unsynced.
+func (m *Manager) Sync() error {
+	m.mu.Lock()
+	wals := make([]*WAL, 0, len(m.wals))
+	for _, w := range m.wals {
+		wals = append(wals, w)
+	}
+	m.mu.Unlock()
+
+	for _, w := range wals {
+		if _, err := w.Sync(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close stops the background Sync scheduler, if one was started, and
+// closes every WAL currently open under the Manager, continuing past any
+// individual Close error so that one stuck WAL doesn't leave the rest
+// open, and returning the first error encountered, if any.
+func (m *Manager) Close() error {
+	if m.syncerDone != nil {
+		close(m.syncerDone)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var firstErr error
+	for name, w := range m.wals {
+		if err := w.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		delete(m.wals, name)
+	}
+	return firstErr
+}
+
+// startSyncer runs in its own goroutine for the lifetime of the Manager,
+// calling Sync on every WAL it currently owns, in turn, every interval.
+// See ManagerOptions.SyncInterval.
+func (m *Manager) startSyncer(interval time.Duration) {
+	m.syncerDone = make(chan struct{})
+	go func() {
+		ticker := m.opts.Clock.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C():
+				_ = m.Sync()
+			case <-m.syncerDone:
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,99 @@
+package wal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManagerOpensOneWALPerNameUnderSharedRoot(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-manager-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	m, err := OpenManager(ManagerOptions{Dir: dir, Options: []Option{WithSegmentFileExt(".SEG")}})
+	require.Nil(t, err)
+	defer m.Close()
+
+	a, err := m.WAL("partition-a")
+	require.Nil(t, err)
+	b, err := m.WAL("partition-b")
+	require.Nil(t, err)
+
+	_, err = a.Write([]byte("a's record"))
+	require.Nil(t, err)
+	_, err = b.Write([]byte("b's record"))
+	require.Nil(t, err)
+
+	assert.DirExists(t, filepath.Join(dir, "partition-a"))
+	assert.DirExists(t, filepath.Join(dir, "partition-b"))
+
+	again, err := m.WAL("partition-a")
+	require.Nil(t, err)
+	assert.Same(t, a, again)
+
+	assert.ElementsMatch(t, []string{"partition-a", "partition-b"}, m.Names())
+}
+
+func TestManagerWALsShareOneBufferPool(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-manager-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	m, err := OpenManager(ManagerOptions{Dir: dir, Options: []Option{WithSegmentFileExt(".SEG")}})
+	require.Nil(t, err)
+	defer m.Close()
+
+	a, err := m.WAL("a")
+	require.Nil(t, err)
+	b, err := m.WAL("b")
+	require.Nil(t, err)
+
+	assert.Same(t, a.bufPool, b.bufPool)
+	assert.Same(t, m.pool, a.bufPool)
+}
+
+func TestManagerSyncIntervalSyncsEveryOwnedWAL(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-manager-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	m, err := OpenManager(ManagerOptions{
+		Dir:          dir,
+		Options:      []Option{WithSegmentFileExt(".SEG"), WithBytesPerSync(1)},
+		SyncInterval: 10 * time.Millisecond,
+	})
+	require.Nil(t, err)
+	defer m.Close()
+
+	w, err := m.WAL("partition")
+	require.Nil(t, err)
+	_, err = w.Write([]byte("unsynced without the scheduler"))
+	require.Nil(t, err)
+
+	require.Eventually(t, func() bool {
+		w.mu.RLock()
+		defer w.mu.RUnlock()
+		return w.bytesSinceSync == 0
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestManagerCloseClosesEveryOwnedWAL(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-manager-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	m, err := OpenManager(ManagerOptions{Dir: dir, Options: []Option{WithSegmentFileExt(".SEG")}})
+	require.Nil(t, err)
+
+	w, err := m.WAL("partition")
+	require.Nil(t, err)
+
+	require.Nil(t, m.Close())
+	_, err = w.Write([]byte("should fail, WAL is closed"))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,50 @@
+package wal
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteRejectsARecordOverMaxRecordSize(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-max-record-size-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithMaxRecordSize(4))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("hello"))
+	var tooLarge *ErrRecordTooLarge
+	require.True(t, errors.As(err, &tooLarge))
+	require.Equal(t, 5, tooLarge.Size)
+	require.Equal(t, int64(4), tooLarge.MaxSize)
+
+	_, err = w.Write([]byte("ok"))
+	require.Nil(t, err)
+}
+
+func TestPendingWritesRejectsAnOversizedRecordWithoutBufferingIt(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-max-record-size-pending-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithMaxRecordSize(4))
+	require.Nil(t, err)
+	defer w.Close()
+
+	require.Nil(t, w.PendingWrites([]byte("ab")))
+	err = w.PendingWrites([]byte("hello"))
+	var tooLarge *ErrRecordTooLarge
+	require.True(t, errors.As(err, &tooLarge))
+
+	positions, err := w.WriteAll()
+	require.Nil(t, err)
+	require.Equal(t, 1, len(positions))
+	data, err := w.Read(positions[0])
+	require.Nil(t, err)
+	require.Equal(t, "ab", string(data))
+}
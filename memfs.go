@@ -0,0 +1,172 @@
+package wal
+
+import (
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// OpenInMemory opens a WAL backed entirely by memory, with no segment files
+// ever touching disk: it is Open with an in-memory FS prepended to opts, so
+// unit tests that would otherwise pay for thousands of temp directories'
+// worth of real file I/O can skip it. Data does not survive process exit.
+//
+// See FS's doc comment for which parts of the WAL (the preallocation
+// pipeline, Repair, Checkpoint) still require a real OS filesystem and so
+// are not usable on a WAL opened this way.
+func OpenInMemory(opts ...Option) (*WAL, error) {
+	opts = append([]Option{WithFS(newMemFS())}, opts...)
+	return Open(opts...)
+}
+
+// memFS is an in-memory FS backing OpenInMemory.
+type memFS struct {
+	mu    sync.Mutex
+	files map[string]*memFile
+}
+
+func newMemFS() *memFS {
+	return &memFS{files: make(map[string]*memFile)}
+}
+
+func (fs *memFS) OpenFile(name string, flag int, _ os.FileMode) (File, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	f, ok := fs.files[name]
+	if !ok {
+		if flag&os.O_CREATE == 0 {
+			return nil, os.ErrNotExist
+		}
+		f = &memFile{name: name}
+		fs.files[name] = f
+	}
+	return f, nil
+}
+
+func (fs *memFS) Remove(name string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	delete(fs.files, name)
+	return nil
+}
+
+// ReadDir always reports no entries: memFS only ever backs a single WAL
+// whose segments are tracked in memory by the WAL itself, so Open's
+// directory scan for pre-existing segments on startup has nothing to find.
+func (fs *memFS) ReadDir(string) ([]os.DirEntry, error) { return nil, nil }
+
+func (fs *memFS) MkdirAll(string, os.FileMode) error { return nil }
+
+// SyncDir is a no-op: memFS has no real directory for a crash to leave in
+// an inconsistent state.
+func (fs *memFS) SyncDir(string) error { return nil }
+
+// memFile is the in-memory File backing memFS.
+type memFile struct {
+	mu   sync.Mutex
+	name string
+	data []byte
+	pos  int64
+}
+
+func (f *memFile) ReadAt(p []byte, off int64) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if off >= int64(len(f.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, f.data[off:])
+	if n < len(p) {
+		return n, io.ErrUnexpectedEOF
+	}
+	return n, nil
+}
+
+func (f *memFile) WriteAt(p []byte, off int64) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.writeAtLocked(p, off)
+}
+
+func (f *memFile) writeAtLocked(p []byte, off int64) (int, error) {
+	end := off + int64(len(p))
+	if end > int64(len(f.data)) {
+		grown := make([]byte, end)
+		copy(grown, f.data)
+		f.data = grown
+	}
+	copy(f.data[off:], p)
+	return len(p), nil
+}
+
+func (f *memFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n, err := f.writeAtLocked(p, f.pos)
+	f.pos += int64(n)
+	return n, err
+}
+
+func (f *memFile) Read(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.pos >= int64(len(f.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, f.data[f.pos:])
+	f.pos += int64(n)
+	return n, nil
+}
+
+func (f *memFile) Seek(offset int64, whence int) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch whence {
+	case io.SeekStart:
+		f.pos = offset
+	case io.SeekCurrent:
+		f.pos += offset
+	case io.SeekEnd:
+		f.pos = int64(len(f.data)) + offset
+	}
+	return f.pos, nil
+}
+
+func (f *memFile) Close() error { return nil }
+
+func (f *memFile) Sync() error { return nil }
+
+func (f *memFile) Stat() (os.FileInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return memFileInfo{name: f.name, size: int64(len(f.data))}, nil
+}
+
+func (f *memFile) Name() string { return f.name }
+
+func (f *memFile) Truncate(size int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if size <= int64(len(f.data)) {
+		f.data = f.data[:size]
+		return nil
+	}
+	grown := make([]byte, size)
+	copy(grown, f.data)
+	f.data = grown
+	return nil
+}
+
+type memFileInfo struct {
+	name string
+	size int64
+}
+
+func (i memFileInfo) Name() string       { return i.name }
+func (i memFileInfo) Size() int64        { return i.size }
+func (i memFileInfo) Mode() os.FileMode  { return 0o644 }
+func (i memFileInfo) ModTime() time.Time { return time.Time{} }
+func (i memFileInfo) IsDir() bool        { return false }
+func (i memFileInfo) Sys() any           { return nil }
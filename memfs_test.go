@@ -0,0 +1,35 @@
+package wal
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenInMemoryWriteReadIterate(t *testing.T) {
+	w, err := OpenInMemory()
+	require.Nil(t, err)
+	defer w.Close()
+
+	pos, err := w.Write([]byte("first"))
+	require.Nil(t, err)
+	_, err = w.Write([]byte("second"))
+	require.Nil(t, err)
+
+	data, err := w.Read(pos)
+	require.Nil(t, err)
+	require.Equal(t, []byte("first"), data)
+
+	r := w.NewReader()
+	var got []string
+	for {
+		data, _, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		require.Nil(t, err)
+		got = append(got, string(data))
+	}
+	require.Equal(t, []string{"first", "second"}, got)
+}
@@ -0,0 +1,48 @@
+package wal
+
+import "time"
+
+// ChunkMeta describes a chunk's physical framing, for callers that need
+// more than its payload -- replication tooling copying raw bytes between
+// WALs, or a debugger inspecting on-disk layout. Reader.NextWithMeta
+// returns one alongside the chunk's data.
+//
+// There is no Flags field: WriteWithFlags's flags byte lives inside a
+// chunk's payload, not its physical header (see WriteWithFlags's doc
+// comment), so NextWithMeta has no way to tell a flags-carrying chunk
+// apart from a plain one without risking misreading an ordinary record's
+// own leading byte as a flag. Call NextWithFlags directly on a Reader you
+// know is positioned at a chunk WriteWithFlags wrote.
+type ChunkMeta struct {
+	// SegmentId is the ID of the segment file the chunk lives in.
+	SegmentId SegmentID
+	// ChunkOffset is the chunk's byte offset within that segment.
+	ChunkOffset int64
+	// ChunkSize is the size, in bytes, of the chunk's payload (excluding
+	// its header).
+	ChunkSize uint32
+	// Sequence is the chunk's logical write order; see ChunkPosition.
+	Sequence uint64
+	// BlockNumber is ChunkOffset / Options.BlockSize, for callers migrating
+	// from a block-framed WAL design that still think in those terms --
+	// see Options.BlockSize's doc comment for why it isn't a real physical
+	// boundary in this package. It is 0 if Options.BlockSize is 0.
+	BlockNumber int64
+	// CRC is the checksum recorded in the chunk's header and verified
+	// against its payload when it was read.
+	CRC uint32
+	// WrittenAt is the time the chunk was written, if the WAL was opened
+	// with WithTimestamps(true); otherwise it is the zero time.Time.
+	WrittenAt time.Time
+}
+
+// Position returns meta's location as a ChunkPosition, suitable for a
+// later WAL.Read, WAL.ReadWithTimestamp, or NewReaderWithStart call.
+func (meta ChunkMeta) Position() *ChunkPosition {
+	return &ChunkPosition{
+		SegmentId:   meta.SegmentId,
+		ChunkOffset: meta.ChunkOffset,
+		ChunkSize:   meta.ChunkSize,
+		Sequence:    meta.Sequence,
+	}
+}
@@ -0,0 +1,137 @@
+package wal
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReaderNextWithMetaReportsPositionAndCRC(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-meta-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"))
+	require.Nil(t, err)
+	defer w.Close()
+
+	pos, err := w.Write([]byte("hello"))
+	require.Nil(t, err)
+
+	r := w.NewReader()
+	data, meta, err := r.NextWithMeta()
+	require.Nil(t, err)
+	assert.Equal(t, "hello", string(data))
+	assert.Equal(t, pos.SegmentId, meta.SegmentId)
+	assert.Equal(t, pos.ChunkOffset, meta.ChunkOffset)
+	assert.Equal(t, pos.ChunkSize, meta.ChunkSize)
+	assert.Equal(t, pos.Sequence, meta.Sequence)
+	assert.NotZero(t, meta.CRC)
+	assert.True(t, meta.WrittenAt.IsZero())
+}
+
+func TestReaderNextWithMetaDerivesBlockNumberFromBlockSize(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-meta-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"), WithBlockSize(32))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("hello"))
+	require.Nil(t, err)
+	pos2, err := w.Write([]byte("world"))
+	require.Nil(t, err)
+
+	r := w.NewReader()
+	_, _, err = r.NextWithMeta()
+	require.Nil(t, err)
+	_, meta2, err := r.NextWithMeta()
+	require.Nil(t, err)
+	assert.Equal(t, pos2.ChunkOffset/32, meta2.BlockNumber)
+}
+
+func TestReaderNextWithMetaBlockNumberZeroWithoutBlockSize(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-meta-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("hello"))
+	require.Nil(t, err)
+
+	r := w.NewReader()
+	_, meta, err := r.NextWithMeta()
+	require.Nil(t, err)
+	assert.Equal(t, int64(0), meta.BlockNumber)
+}
+
+func TestReaderNextWithMetaReportsTimestampWhenEnabled(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-meta-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"), WithTimestamps(true))
+	require.Nil(t, err)
+	defer w.Close()
+
+	before := time.Now()
+	_, err = w.Write([]byte("hello"))
+	require.Nil(t, err)
+	after := time.Now()
+
+	r := w.NewReader()
+	_, meta, err := r.NextWithMeta()
+	require.Nil(t, err)
+	assert.False(t, meta.WrittenAt.Before(before))
+	assert.False(t, meta.WrittenAt.After(after))
+}
+
+func TestReaderNextWithMetaSurfacesCRCCorruption(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-meta-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"))
+	require.Nil(t, err)
+	defer w.Close()
+
+	pos, err := w.Write([]byte("hello"))
+	require.Nil(t, err)
+
+	seg := w.segmentByID(pos.SegmentId)
+	_, err = seg.fd.WriteAt([]byte{0, 0, 0, 0}, segmentHeaderSize+4)
+	require.Nil(t, err)
+
+	r := w.NewReader()
+	_, _, err = r.NextWithMeta()
+	assert.ErrorIs(t, err, ErrInvalidCRC)
+}
+
+func TestChunkMetaPositionRoundTripsThroughRead(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-meta-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("hello"))
+	require.Nil(t, err)
+
+	r := w.NewReader()
+	_, meta, err := r.NextWithMeta()
+	require.Nil(t, err)
+
+	data, err := w.Read(meta.Position())
+	require.Nil(t, err)
+	assert.Equal(t, "hello", string(data))
+}
@@ -0,0 +1,234 @@
+package wal
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector receives instrumentation events emitted by a WAL as it writes,
+// reads, syncs, and rotates segments. WAL.Write, WAL.WriteAll, WAL.Read,
+// WAL.Sync, and segment rotation all report to the Collector configured via
+// WithMetricsCollector or WithMetricsRegisterer.
+//
+// Implementations must be safe for concurrent use by multiple goroutines.
+type Collector interface {
+	// ObserveFsyncDuration records how long a single fsync call took.
+	ObserveFsyncDuration(d time.Duration)
+
+	// AddBytesWritten adds n to the total number of bytes written to
+	// segment files.
+	AddBytesWritten(n int)
+
+	// IncChunksWritten increments the number of chunks successfully
+	// written to segment files.
+	IncChunksWritten()
+
+	// ObserveChunkReadLatency records how long a single WAL.Read call took
+	// to resolve a chunk.
+	ObserveChunkReadLatency(d time.Duration)
+
+	// SetActiveSegments reports the current number of segment files on
+	// disk, including the active segment.
+	SetActiveSegments(n int)
+
+	// SetSegmentSize reports the current size in bytes of the active
+	// segment.
+	SetSegmentSize(n int64)
+
+	// IncSegmentRotations increments the number of times the active
+	// segment has been rotated.
+	IncSegmentRotations()
+
+	// SetPendingWritesQueueDepth reports the number of writes batched by
+	// PendingWrites that have not yet been flushed by WriteAll.
+	SetPendingWritesQueueDepth(n int)
+
+	// IncCRCErrors increments the number of reads that failed because a
+	// chunk's CRC did not match its contents.
+	IncCRCErrors()
+
+	// IncIOErrors increments the number of reads or writes that failed
+	// with an I/O error other than a CRC mismatch.
+	IncIOErrors()
+
+	// IncRecordsRead increments the number of records a Watcher has
+	// delivered to its OnRecord callback.
+	IncRecordsRead()
+
+	// SetReadLagBytes reports how many bytes behind the active segment's
+	// current size a Watcher's read position is.
+	SetReadLagBytes(n int64)
+
+	// AddBytesDropped adds n to the total number of trailing bytes Repair
+	// (or WAL.Repair) has truncated from a torn-tail segment.
+	AddBytesDropped(n int64)
+}
+
+// nopCollector is the default Collector used when no metrics are
+// configured. Every method is a no-op so the WAL can report to it
+// unconditionally without a nil check on the hot path.
+type nopCollector struct{}
+
+func (nopCollector) ObserveFsyncDuration(time.Duration)    {}
+func (nopCollector) AddBytesWritten(int)                   {}
+func (nopCollector) IncChunksWritten()                     {}
+func (nopCollector) ObserveChunkReadLatency(time.Duration) {}
+func (nopCollector) SetActiveSegments(int)                 {}
+func (nopCollector) SetSegmentSize(int64)                  {}
+func (nopCollector) IncSegmentRotations()                  {}
+func (nopCollector) SetPendingWritesQueueDepth(int)        {}
+func (nopCollector) IncCRCErrors()                         {}
+func (nopCollector) IncIOErrors()                          {}
+func (nopCollector) IncRecordsRead()                       {}
+func (nopCollector) SetReadLagBytes(int64)                 {}
+func (nopCollector) AddBytesDropped(int64)                 {}
+
+// prometheusCollector is the Collector registered by WithMetricsRegisterer.
+type prometheusCollector struct {
+	fsyncDuration      prometheus.Histogram
+	bytesWritten       prometheus.Counter
+	chunksWritten      prometheus.Counter
+	chunkReadLatency   prometheus.Histogram
+	activeSegments     prometheus.Gauge
+	segmentSize        prometheus.Gauge
+	segmentRotations   prometheus.Counter
+	pendingWritesDepth prometheus.Gauge
+	crcErrors          prometheus.Counter
+	ioErrors           prometheus.Counter
+	recordsRead        prometheus.Counter
+	readLagBytes       prometheus.Gauge
+	bytesDropped       prometheus.Counter
+}
+
+func newPrometheusCollector(reg prometheus.Registerer) *prometheusCollector {
+	c := &prometheusCollector{
+		fsyncDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: "wal",
+			Name:      "fsync_duration_seconds",
+			Help:      "Duration of fsync calls made while writing or rotating segments.",
+			Buckets:   prometheus.DefBuckets,
+		}),
+		bytesWritten: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "wal",
+			Name:      "written_bytes_total",
+			Help:      "Total number of bytes written to WAL segments.",
+		}),
+		chunksWritten: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "wal",
+			Name:      "chunks_written_total",
+			Help:      "Total number of chunks written to WAL segments.",
+		}),
+		chunkReadLatency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: "wal",
+			Name:      "chunk_read_duration_seconds",
+			Help:      "Duration of Read calls resolving a single chunk.",
+			Buckets:   prometheus.DefBuckets,
+		}),
+		activeSegments: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "wal",
+			Name:      "active_segments",
+			Help:      "Number of segment files currently on disk.",
+		}),
+		segmentSize: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "wal",
+			Name:      "segment_size_bytes",
+			Help:      "Size in bytes of the current active segment.",
+		}),
+		segmentRotations: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "wal",
+			Name:      "segment_rotations_total",
+			Help:      "Total number of times the active segment has been rotated.",
+		}),
+		pendingWritesDepth: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "wal",
+			Name:      "pending_writes_queue_depth",
+			Help:      "Number of batched writes not yet flushed by WriteAll.",
+		}),
+		crcErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "wal",
+			Name:      "crc_errors_total",
+			Help:      "Total number of reads that failed CRC validation.",
+		}),
+		ioErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "wal",
+			Name:      "io_errors_total",
+			Help:      "Total number of I/O errors other than CRC mismatches.",
+		}),
+		recordsRead: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "wal",
+			Name:      "watcher_records_read_total",
+			Help:      "Total number of records a Watcher has delivered to OnRecord.",
+		}),
+		readLagBytes: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "wal",
+			Name:      "watcher_read_lag_bytes",
+			Help:      "Bytes behind the active segment's current size a Watcher's read position is.",
+		}),
+		bytesDropped: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "wal",
+			Name:      "repair_bytes_dropped_total",
+			Help:      "Total number of trailing bytes Repair has truncated from torn-tail segments.",
+		}),
+	}
+	if reg != nil {
+		reg.MustRegister(
+			c.fsyncDuration, c.bytesWritten, c.chunksWritten, c.chunkReadLatency,
+			c.activeSegments, c.segmentSize, c.segmentRotations, c.pendingWritesDepth,
+			c.crcErrors, c.ioErrors, c.recordsRead, c.readLagBytes, c.bytesDropped,
+		)
+	}
+	return c
+}
+
+func (c *prometheusCollector) ObserveFsyncDuration(d time.Duration) {
+	c.fsyncDuration.Observe(d.Seconds())
+}
+
+func (c *prometheusCollector) AddBytesWritten(n int) {
+	c.bytesWritten.Add(float64(n))
+}
+
+func (c *prometheusCollector) IncChunksWritten() {
+	c.chunksWritten.Inc()
+}
+
+func (c *prometheusCollector) ObserveChunkReadLatency(d time.Duration) {
+	c.chunkReadLatency.Observe(d.Seconds())
+}
+
+func (c *prometheusCollector) SetActiveSegments(n int) {
+	c.activeSegments.Set(float64(n))
+}
+
+func (c *prometheusCollector) SetSegmentSize(n int64) {
+	c.segmentSize.Set(float64(n))
+}
+
+func (c *prometheusCollector) IncSegmentRotations() {
+	c.segmentRotations.Inc()
+}
+
+func (c *prometheusCollector) SetPendingWritesQueueDepth(n int) {
+	c.pendingWritesDepth.Set(float64(n))
+}
+
+func (c *prometheusCollector) IncCRCErrors() {
+	c.crcErrors.Inc()
+}
+
+func (c *prometheusCollector) IncIOErrors() {
+	c.ioErrors.Inc()
+}
+
+func (c *prometheusCollector) IncRecordsRead() {
+	c.recordsRead.Inc()
+}
+
+func (c *prometheusCollector) SetReadLagBytes(n int64) {
+	c.readLagBytes.Set(float64(n))
+}
+
+func (c *prometheusCollector) AddBytesDropped(n int64) {
+	c.bytesDropped.Add(float64(n))
+}
@@ -0,0 +1,67 @@
+package wal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNopCollector(t *testing.T) {
+	// All methods must be safe to call and must not panic when no metrics
+	// backend is configured.
+	var c Collector = nopCollector{}
+	c.ObserveFsyncDuration(time.Millisecond)
+	c.AddBytesWritten(128)
+	c.IncChunksWritten()
+	c.ObserveChunkReadLatency(time.Microsecond)
+	c.SetActiveSegments(3)
+	c.SetSegmentSize(GB)
+	c.IncSegmentRotations()
+	c.SetPendingWritesQueueDepth(2)
+	c.IncCRCErrors()
+	c.IncIOErrors()
+	c.IncRecordsRead()
+	c.SetReadLagBytes(1024)
+	c.AddBytesDropped(64)
+}
+
+func TestPrometheusCollector(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	c := newPrometheusCollector(reg)
+
+	c.AddBytesWritten(10)
+	c.AddBytesWritten(20)
+	c.IncChunksWritten()
+	c.IncSegmentRotations()
+	c.SetActiveSegments(4)
+	c.IncCRCErrors()
+	c.IncRecordsRead()
+	c.SetReadLagBytes(512)
+	c.AddBytesDropped(48)
+
+	assert.Equal(t, float64(30), readCounter(t, c.bytesWritten))
+	assert.Equal(t, float64(1), readCounter(t, c.chunksWritten))
+	assert.Equal(t, float64(1), readCounter(t, c.segmentRotations))
+	assert.Equal(t, float64(4), readGauge(t, c.activeSegments))
+	assert.Equal(t, float64(1), readCounter(t, c.crcErrors))
+	assert.Equal(t, float64(1), readCounter(t, c.recordsRead))
+	assert.Equal(t, float64(512), readGauge(t, c.readLagBytes))
+	assert.Equal(t, float64(48), readCounter(t, c.bytesDropped))
+}
+
+func readCounter(t *testing.T, c prometheus.Counter) float64 {
+	t.Helper()
+	m := &dto.Metric{}
+	assert.Nil(t, c.Write(m))
+	return m.GetCounter().GetValue()
+}
+
+func readGauge(t *testing.T, g prometheus.Gauge) float64 {
+	t.Helper()
+	m := &dto.Metric{}
+	assert.Nil(t, g.Write(m))
+	return m.GetGauge().GetValue()
+}
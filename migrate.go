@@ -0,0 +1,199 @@
+package wal
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// RemapFileName is the name Migrate gives the file it writes into dstDir,
+// recording every migrated chunk's old ChunkPosition next to its new one,
+// in migration order. Read it back with ReadRemapFile.
+const RemapFileName = "MIGRATE-REMAP"
+
+// remapEntrySize is the serialized size, in bytes, of one remap file
+// entry: an old ChunkPosition (SegmentId, ChunkOffset, ChunkSize,
+// Sequence) followed by the new one it moved to, in the same layout.
+const remapEntrySize = 2 * (4 + 8 + 4 + 8)
+
+// Migrate rewrites every segment in srcDir into a fresh WAL at dstDir,
+// configured by newOpts the same way OpenWithOptions configures one --
+// typically to pick up a newer segmentFormatVersion or change
+// Options.Compression or Options.BlockSize, without touching srcDir.
+// newOpts.DirPath is overwritten with dstDir.
+//
+// Each source chunk is decoded with the checksum algorithm and
+// compression codec recorded in its own segment's header (see
+// Options.Compression's Open-time validation), not newOpts' -- so
+// changing Options.Compression in newOpts is exactly what drives the
+// migration, rather than being mistaken for the format srcDir is already
+// in.
+//
+// Record order is preserved, but SegmentId, ChunkOffset, and Sequence
+// are not: dstDir is a brand new WAL, filling segments according to
+// newOpts.SegmentSize and numbering chunks from its own 0, independent of
+// srcDir's layout. Migrate records every chunk's old position next to its
+// new one, in order, in a RemapFileName file under dstDir, for a caller
+// whose own index (e.g. rosedb's key->position map) needs updating to
+// match.
+//
+// Migrate does not support a srcDir written with Options.EncryptionKey:
+// there is nothing in a segment's header recording that the way there is
+// for Checksum and Compression, so an encrypted srcDir would migrate its
+// still-encrypted bytes as though they were already plaintext. Nor does
+// it special-case a mismatch between srcDir's and newOpts'
+// Options.Timestamps: the timestamp prefix that option adds lives inside
+// the payload Migrate copies across like any other data, so flipping it
+// between srcDir and dstDir shifts that payload by timestampPrefixSize
+// bytes instead of actually adding or removing the prefix.
+//
+// A srcDir written with Options.CompressionDict is supported, but unlike
+// Compression itself, the dictionary's bytes aren't recoverable from a
+// segment's header (only its ID is, for the same mismatch check Open
+// does) -- so newOpts.CompressionDict must be set to that same dictionary
+// for Migrate to decode srcDir's chunks, whether or not newOpts.Compression
+// keeps using it going forward.
+func Migrate(srcDir, dstDir string, newOpts Options) error {
+	newOpts.DirPath = dstDir
+	dst, err := OpenWithOptions(newOpts)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	srcExt := newOpts.SegmentFileExt
+	if srcExt == "" {
+		srcExt = DefaultOptions.SegmentFileExt
+	}
+	ids, paths, err := segmentPaths(osFS{}, srcDir, srcExt, newOpts.SegmentFileNameParseFunc)
+	if err != nil {
+		return err
+	}
+
+	remapFile, err := os.OpenFile(filepath.Join(dstDir, RemapFileName), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	defer remapFile.Close()
+
+	var seq uint64
+	for i, id := range ids {
+		seq, err = migrateSegmentFile(dst, paths[i], id, seq, remapFile, newOpts.CompressionDict)
+		if err != nil {
+			return err
+		}
+	}
+	return remapFile.Sync()
+}
+
+// migrateSegmentFile rewrites every chunk in the segment file at path,
+// whose first chunk has logical Sequence seq, into dst, appending each
+// one's old->new position pair to remapFile. It returns the Sequence the
+// next segment's first chunk should continue from. dict is newOpts'
+// CompressionDict, needed to decode a segment whose header records a
+// dictionary ID, since (unlike the codec itself) the dictionary's bytes
+// aren't recoverable from the header alone; see Migrate's doc comment.
+//
+// If path's segment was written with Options.ChainChecksums, its chunks'
+// checksums aren't verifiable chunk-by-chunk this way -- see
+// Options.ChainChecksums's doc comment -- so this skips the check
+// entirely rather than reporting every one of them as corrupt; dst gets a
+// fresh chain of its own from dst.Write regardless of what dst's own
+// ChainChecksums is set to.
+func migrateSegmentFile(dst *WAL, path string, id SegmentID, seq uint64, remapFile *os.File, dict []byte) (uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return seq, err
+	}
+	defer f.Close()
+
+	_, checksum, compression, dictID, _, chained, err := readSegmentHeader(f)
+	if err != nil {
+		return seq, err
+	}
+	if dictID != 0 {
+		gotID, err := zstdDictID(dict)
+		if err != nil {
+			return seq, err
+		}
+		if gotID != dictID {
+			return seq, ErrCompressionDictMismatch
+		}
+	}
+
+	var offset int64
+	entry := make([]byte, remapEntrySize)
+	for {
+		payload, err := readChunkAt(f, offset, checksum, !chained, id)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return seq, nil
+		}
+		if err != nil {
+			return seq, err
+		}
+		oldPos := &ChunkPosition{SegmentId: id, ChunkOffset: offset, ChunkSize: uint32(len(payload)), Sequence: seq}
+		offset += int64(chunkHeaderSize) + int64(len(payload))
+		seq++
+
+		data, err := decompressPayload(compression, payload, dict)
+		if err != nil {
+			return seq, err
+		}
+		newPos, err := dst.Write(data)
+		if err != nil {
+			return seq, err
+		}
+
+		putRemapEntry(entry, oldPos, newPos)
+		if _, err := remapFile.Write(entry); err != nil {
+			return seq, err
+		}
+	}
+}
+
+func putRemapEntry(buf []byte, old, new *ChunkPosition) {
+	binary.BigEndian.PutUint32(buf[0:4], old.SegmentId)
+	binary.BigEndian.PutUint64(buf[4:12], uint64(old.ChunkOffset))
+	binary.BigEndian.PutUint32(buf[12:16], old.ChunkSize)
+	binary.BigEndian.PutUint64(buf[16:24], old.Sequence)
+	binary.BigEndian.PutUint32(buf[24:28], new.SegmentId)
+	binary.BigEndian.PutUint64(buf[28:36], uint64(new.ChunkOffset))
+	binary.BigEndian.PutUint32(buf[36:40], new.ChunkSize)
+	binary.BigEndian.PutUint64(buf[40:48], new.Sequence)
+}
+
+// ReadRemapFile parses a RemapFileName file Migrate wrote, returning
+// every migrated chunk's old position mapped to its new one -- the
+// on-disk equivalent of the map Compact returns directly, for a caller
+// that ran Migrate out of process (e.g. through cmd/walmigrate) and now
+// needs to update its own index to match.
+func ReadRemapFile(path string) (map[ChunkPosition]*ChunkPosition, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data)%remapEntrySize != 0 {
+		return nil, fmt.Errorf("wal: remap file %s has length %d, not a multiple of the %d-byte entry size", path, len(data), remapEntrySize)
+	}
+
+	remap := make(map[ChunkPosition]*ChunkPosition, len(data)/remapEntrySize)
+	for i := 0; i < len(data); i += remapEntrySize {
+		entry := data[i : i+remapEntrySize]
+		oldPos := ChunkPosition{
+			SegmentId:   binary.BigEndian.Uint32(entry[0:4]),
+			ChunkOffset: int64(binary.BigEndian.Uint64(entry[4:12])),
+			ChunkSize:   binary.BigEndian.Uint32(entry[12:16]),
+			Sequence:    binary.BigEndian.Uint64(entry[16:24]),
+		}
+		newPos := &ChunkPosition{
+			SegmentId:   binary.BigEndian.Uint32(entry[24:28]),
+			ChunkOffset: int64(binary.BigEndian.Uint64(entry[28:36])),
+			ChunkSize:   binary.BigEndian.Uint32(entry[36:40]),
+			Sequence:    binary.BigEndian.Uint64(entry[40:48]),
+		}
+		remap[oldPos] = newPos
+	}
+	return remap, nil
+}
@@ -0,0 +1,119 @@
+package wal
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigratePreservesRecordOrderAndData(t *testing.T) {
+	srcDir, err := os.MkdirTemp("", "wal-migrate-src")
+	require.Nil(t, err)
+	defer os.RemoveAll(srcDir)
+	dstDir := filepath.Join(os.TempDir(), "wal-migrate-dst")
+	require.Nil(t, os.RemoveAll(dstDir))
+	defer os.RemoveAll(dstDir)
+
+	src, err := Open(WithDirPath(srcDir), WithSegmentFileExt(".SEG"), WithSegmentSize(64))
+	require.Nil(t, err)
+	var records [][]byte
+	for i := 0; i < 20; i++ {
+		data := []byte{byte(i), byte(i), byte(i)}
+		records = append(records, data)
+		_, err := src.Write(data)
+		require.Nil(t, err)
+	}
+	require.Nil(t, src.Close())
+
+	newOpts := DefaultOptions
+	newOpts.SegmentFileExt = ".SEG"
+	newOpts.Compression = CompressionSnappy
+	require.Nil(t, Migrate(srcDir, dstDir, newOpts))
+
+	dst, err := Open(WithDirPath(dstDir), WithSegmentFileExt(".SEG"), WithCompression(CompressionSnappy))
+	require.Nil(t, err)
+	defer dst.Close()
+
+	r := dst.NewReader()
+	for i, want := range records {
+		data, _, err := r.Next()
+		require.Nil(t, err, "record %d", i)
+		assert.Equal(t, want, data)
+	}
+	_, _, err = r.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestMigrateWritesRemapFile(t *testing.T) {
+	srcDir, err := os.MkdirTemp("", "wal-migrate-src")
+	require.Nil(t, err)
+	defer os.RemoveAll(srcDir)
+	dstDir := filepath.Join(os.TempDir(), "wal-migrate-dst")
+	require.Nil(t, os.RemoveAll(dstDir))
+	defer os.RemoveAll(dstDir)
+
+	src, err := Open(WithDirPath(srcDir), WithSegmentFileExt(".SEG"))
+	require.Nil(t, err)
+	pos1, err := src.Write([]byte("one"))
+	require.Nil(t, err)
+	pos2, err := src.Write([]byte("two"))
+	require.Nil(t, err)
+	require.Nil(t, src.Close())
+
+	newOpts := DefaultOptions
+	newOpts.SegmentFileExt = ".SEG"
+	require.Nil(t, Migrate(srcDir, dstDir, newOpts))
+
+	remap, err := ReadRemapFile(filepath.Join(dstDir, RemapFileName))
+	require.Nil(t, err)
+	require.Len(t, remap, 2)
+
+	dst, err := Open(WithDirPath(dstDir), WithSegmentFileExt(".SEG"))
+	require.Nil(t, err)
+	defer dst.Close()
+
+	newPos1, ok := remap[*pos1]
+	require.True(t, ok)
+	data, err := dst.Read(newPos1)
+	require.Nil(t, err)
+	assert.Equal(t, "one", string(data))
+
+	newPos2, ok := remap[*pos2]
+	require.True(t, ok)
+	data, err = dst.Read(newPos2)
+	require.Nil(t, err)
+	assert.Equal(t, "two", string(data))
+}
+
+func TestMigrateChangesCompressionAndBlockSize(t *testing.T) {
+	srcDir, err := os.MkdirTemp("", "wal-migrate-src")
+	require.Nil(t, err)
+	defer os.RemoveAll(srcDir)
+	dstDir := filepath.Join(os.TempDir(), "wal-migrate-dst")
+	require.Nil(t, os.RemoveAll(dstDir))
+	defer os.RemoveAll(dstDir)
+
+	src, err := Open(WithDirPath(srcDir), WithSegmentFileExt(".SEG"))
+	require.Nil(t, err)
+	_, err = src.Write([]byte("hello, migrate me please"))
+	require.Nil(t, err)
+	require.Nil(t, src.Close())
+
+	newOpts := DefaultOptions
+	newOpts.SegmentFileExt = ".SEG"
+	newOpts.Compression = CompressionZstd
+	newOpts.BlockSize = 32
+	require.Nil(t, Migrate(srcDir, dstDir, newOpts))
+
+	dst, err := Open(WithDirPath(dstDir), WithSegmentFileExt(".SEG"), WithCompression(CompressionZstd), WithBlockSize(32))
+	require.Nil(t, err)
+	defer dst.Close()
+
+	data, _, err := dst.NewReader().Next()
+	require.Nil(t, err)
+	assert.Equal(t, "hello, migrate me please", string(data))
+}
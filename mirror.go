@@ -0,0 +1,61 @@
+package wal
+
+// defaultMirrorLagBound is how many mirror writes Options.MirrorAsync
+// queues ahead of the background mirror writer, when
+// Options.MirrorLagBound isn't set, before mirrorWrite starts blocking --
+// the same bound WriteAsync's own asyncQueueDepth uses for the same
+// reason: backpressure instead of an unbounded, memory-growing queue.
+const defaultMirrorLagBound = 1024
+
+// mirrorWrite duplicates data, the same bytes just handed to Write, to
+// w.mirror, if Options.MirrorDir is set. With Options.MirrorAsync unset
+// (the default), it writes to the mirror inline and returns whatever error
+// that produced, so Write itself fails if the mirror does -- the whole
+// point of a mirror is protection against exactly this. With
+// Options.MirrorAsync set, it hands data to the background mirror writer
+// and returns immediately instead, trading that guarantee for a bounded
+// amount of lag (Options.MirrorLagBound) between what's durable on
+// options.DirPath and what's durable on options.MirrorDir; see MirrorErr
+// for how a caller notices an asynchronous mirror write failing.
+func (w *WAL) mirrorWrite(data []byte) error {
+	if w.mirror == nil {
+		return nil
+	}
+	if !w.options.MirrorAsync {
+		_, err := w.mirror.Write(data)
+		return err
+	}
+
+	w.mirrorMu.RLock()
+	defer w.mirrorMu.RUnlock()
+	if w.mirrorClosed {
+		return nil
+	}
+	w.mirrorQueue <- append([]byte(nil), data...)
+	return nil
+}
+
+// runMirror is the background goroutine Options.MirrorAsync starts to
+// drain mirrorQueue, applying each queued write to w.mirror one at a time
+// and in order, the same order Write appended them to the primary in.
+func (w *WAL) runMirror() {
+	defer w.mirrorWG.Done()
+	for data := range w.mirrorQueue {
+		if _, err := w.mirror.Write(data); err != nil {
+			w.mirrorErrMu.Lock()
+			w.mirrorErr = err
+			w.mirrorErrMu.Unlock()
+		}
+	}
+}
+
+// MirrorErr returns the error the most recent asynchronous mirror write
+// failed with, when Options.MirrorAsync is set, or nil if every mirrored
+// write so far has succeeded. It always returns nil when Options.MirrorDir
+// is unset or Options.MirrorAsync is false, since mirrorWrite surfaces a
+// synchronous mirror's errors directly from Write instead.
+func (w *WAL) MirrorErr() error {
+	w.mirrorErrMu.Lock()
+	defer w.mirrorErrMu.Unlock()
+	return w.mirrorErr
+}
@@ -0,0 +1,99 @@
+package wal
+
+import (
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMirrorDirReceivesEveryWrite(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-mirror-sync-primary-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	mirrorDir, err := os.MkdirTemp("", "wal-mirror-sync-mirror-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(mirrorDir)
+
+	w, err := Open(WithDirPath(dir), WithMirrorDir(mirrorDir))
+	require.Nil(t, err)
+
+	_, err = w.Write([]byte("one"))
+	require.Nil(t, err)
+	_, err = w.Write([]byte("two"))
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+
+	mirror, err := Open(WithDirPath(mirrorDir))
+	require.Nil(t, err)
+	defer mirror.Close()
+
+	r := mirror.NewReader()
+	var got []string
+	for {
+		data, _, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		require.Nil(t, err)
+		got = append(got, string(data))
+	}
+	require.Equal(t, []string{"one", "two"}, got)
+}
+
+func TestMirrorDirFailureFailsASynchronousWrite(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-mirror-fail-primary-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	mirrorDir, err := os.MkdirTemp("", "wal-mirror-fail-mirror-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(mirrorDir)
+
+	w, err := Open(WithDirPath(dir), WithMirrorDir(mirrorDir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	require.Nil(t, w.mirror.Close())
+
+	_, err = w.Write([]byte("one"))
+	require.Error(t, err)
+}
+
+func TestMirrorAsyncDoesNotBlockOnMirrorFailure(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-mirror-async-primary-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	mirrorDir, err := os.MkdirTemp("", "wal-mirror-async-mirror-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(mirrorDir)
+
+	w, err := Open(WithDirPath(dir), WithMirrorDir(mirrorDir), WithMirrorAsync(4))
+	require.Nil(t, err)
+	defer w.Close()
+
+	require.Nil(t, w.mirror.Close())
+
+	_, err = w.Write([]byte("one"))
+	require.Nil(t, err)
+
+	require.Eventually(t, func() bool {
+		return w.MirrorErr() != nil
+	}, time.Second, time.Millisecond)
+}
+
+func TestMirrorDirRejectsReadOnly(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-mirror-readonly-primary-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	_, err = w.Write([]byte("seed"))
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+
+	_, err = Open(WithDirPath(dir), WithMirrorDir("/tmp/wal-mirror-unused"), WithReadOnly(true))
+	require.Error(t, err)
+}
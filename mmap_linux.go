@@ -0,0 +1,15 @@
+//go:build linux
+
+package wal
+
+import "golang.org/x/sys/unix"
+
+// mmapFile maps the first size bytes of fd for reading.
+func mmapFile(fd uintptr, size int) ([]byte, error) {
+	return unix.Mmap(int(fd), 0, size, unix.PROT_READ, unix.MAP_SHARED)
+}
+
+// munmapFile reverses mmapFile.
+func munmapFile(data []byte) error {
+	return unix.Munmap(data)
+}
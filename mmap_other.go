@@ -0,0 +1,17 @@
+//go:build !linux
+
+package wal
+
+import "fmt"
+
+// mmapFile always fails on platforms this package doesn't know how to mmap
+// on, so Options.MmapRead silently falls back to pread-based reads there.
+func mmapFile(fd uintptr, size int) ([]byte, error) {
+	return nil, fmt.Errorf("wal: memory-mapped reads are not supported on this platform")
+}
+
+// munmapFile is never called with data from a failed mmapFile, so it has
+// nothing to do on this platform.
+func munmapFile(data []byte) error {
+	return nil
+}
@@ -0,0 +1,58 @@
+package wal
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMmapReadServesSealedSegments(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-mmap-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(64), WithMmapRead(true))
+	require.Nil(t, err)
+	defer w.Close()
+
+	var positions []*ChunkPosition
+	for i := 0; i < 10; i++ {
+		pos, err := w.Write([]byte{byte('a' + i)})
+		require.Nil(t, err)
+		positions = append(positions, pos)
+	}
+	require.Greater(t, len(w.olderSegments), 0)
+
+	for i, pos := range positions {
+		data, err := w.Read(pos)
+		require.Nil(t, err)
+		require.Equal(t, []byte{byte('a' + i)}, data)
+	}
+}
+
+func TestMmapReadSurvivesReopen(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-mmap-reopen-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(64), WithMmapRead(true))
+	require.Nil(t, err)
+	var positions []*ChunkPosition
+	for i := 0; i < 10; i++ {
+		pos, err := w.Write([]byte{byte('a' + i)})
+		require.Nil(t, err)
+		positions = append(positions, pos)
+	}
+	require.Nil(t, w.Close())
+
+	w2, err := Open(WithDirPath(dir), WithSegmentSize(64), WithMmapRead(true))
+	require.Nil(t, err)
+	defer w2.Close()
+
+	for i, pos := range positions[:len(positions)-1] {
+		data, err := w2.Read(pos)
+		require.Nil(t, err)
+		require.Equal(t, []byte{byte('a' + i)}, data)
+	}
+}
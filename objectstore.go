@@ -0,0 +1,21 @@
+package wal
+
+import "io"
+
+// ObjectStore is where Options.TieringAge uploads a sealed segment's
+// bytes once it's old enough to tier out, and where a Read fetches them
+// back from on a cache miss. A caller wanting S3 implements this against
+// whatever S3 SDK it already uses (or any other S3-compatible client);
+// WAL itself only ever deals in keys and streams, never a specific
+// object-storage API.
+type ObjectStore interface {
+	// Put uploads r's entire content under key, overwriting whatever was
+	// already stored there.
+	Put(key string, r io.Reader) error
+
+	// Get returns key's content. The caller must Close it.
+	Get(key string) (io.ReadCloser, error)
+
+	// Delete removes key. It is not an error for key to already be gone.
+	Delete(key string) error
+}
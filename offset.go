@@ -0,0 +1,148 @@
+package wal
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// offsetMagic prefixes a CommitOffset marker's payload so Offset can pick
+// it back out of the log when scanning -- the same discriminator trick
+// checkpointMagic uses for WriteCheckpoint, on the assumption nothing else
+// written to this WAL happens to start with these exact bytes.
+const offsetMagic = "WAL:OFST"
+
+// offsetMarkerFixedSize is the size, in bytes, of everything in an offset
+// marker besides the consumer name itself: offsetMagic, the name's length,
+// and pos's four fields.
+const offsetMarkerFixedSize = len(offsetMagic) + 4 + 4 + 8 + 4 + 8
+
+// encodeOffsetMarker lays out consumer and pos as a CommitOffset marker's
+// payload: offsetMagic, then consumer's length and bytes, then pos's
+// fields, each fixed-width so decodeOffsetMarker doesn't need a delimiter
+// to split them back apart.
+func encodeOffsetMarker(consumer string, pos *ChunkPosition) []byte {
+	raw := make([]byte, offsetMarkerFixedSize+len(consumer))
+	n := copy(raw, offsetMagic)
+	binary.BigEndian.PutUint32(raw[n:], uint32(len(consumer)))
+	n += 4
+	n += copy(raw[n:], consumer)
+	binary.BigEndian.PutUint32(raw[n:], pos.SegmentId)
+	n += 4
+	binary.BigEndian.PutUint64(raw[n:], uint64(pos.ChunkOffset))
+	n += 8
+	binary.BigEndian.PutUint32(raw[n:], pos.ChunkSize)
+	n += 4
+	binary.BigEndian.PutUint64(raw[n:], pos.Sequence)
+	return raw
+}
+
+// decodeOffsetMarker reports whether raw is a CommitOffset marker
+// encodeOffsetMarker produced, and if so, the consumer and position it
+// carries.
+func decodeOffsetMarker(raw []byte) (consumer string, pos *ChunkPosition, ok bool) {
+	if len(raw) < len(offsetMagic) || string(raw[:len(offsetMagic)]) != offsetMagic {
+		return "", nil, false
+	}
+	raw = raw[len(offsetMagic):]
+
+	if len(raw) < 4 {
+		return "", nil, false
+	}
+	consumerLen := binary.BigEndian.Uint32(raw[:4])
+	raw = raw[4:]
+
+	if uint64(len(raw)) != uint64(consumerLen)+8+4+8+4 {
+		return "", nil, false
+	}
+	consumer = string(raw[:consumerLen])
+	raw = raw[consumerLen:]
+
+	segmentID := binary.BigEndian.Uint32(raw[:4])
+	raw = raw[4:]
+	chunkOffset := int64(binary.BigEndian.Uint64(raw[:8]))
+	raw = raw[8:]
+	chunkSize := binary.BigEndian.Uint32(raw[:4])
+	raw = raw[4:]
+	sequence := binary.BigEndian.Uint64(raw[:8])
+
+	return consumer, &ChunkPosition{
+		SegmentId:   segmentID,
+		ChunkOffset: chunkOffset,
+		ChunkSize:   chunkSize,
+		Sequence:    sequence,
+	}, true
+}
+
+// IsOffsetMarker reports whether data is a record CommitOffset wrote. A
+// consumer that tails a WAL with a Watcher and also calls CommitOffset
+// against that same WAL -- as walcdc.Bridge does -- needs this to filter
+// its own bookkeeping records back out of what it hands to OnRecord,
+// since a Watcher otherwise delivers every chunk in the log, offset
+// markers included.
+func IsOffsetMarker(data []byte) bool {
+	_, _, ok := decodeOffsetMarker(data)
+	return ok
+}
+
+// CommitOffset appends a marker recording pos as consumer's current
+// resume point and returns its own position -- e.g. after a tailing
+// consumer has durably applied everything up to pos, so a later restart
+// picks back up there instead of replaying from the start of the log.
+// Offset returns the newest marker committed for a given consumer,
+// including after a restart, without the caller having to keep its own
+// store of resume points alongside the WAL.
+//
+// Like WriteCheckpoint, this assumes every chunk in the WAL was written
+// through Write, WriteAll, WriteCheckpoint, or CommitOffset itself, since
+// Offset's scan reads raw chunk bytes looking for offsetMagic.
+func (w *WAL) CommitOffset(consumer string, pos *ChunkPosition) (*ChunkPosition, error) {
+	marker, err := w.Write(encodeOffsetMarker(consumer, pos))
+	if err != nil {
+		return nil, err
+	}
+	w.mu.Lock()
+	w.offsets[consumer] = pos
+	w.mu.Unlock()
+	return marker, nil
+}
+
+// Offset returns the position consumer's newest CommitOffset call
+// recorded. The first call against a WAL scans the whole log for every
+// consumer's markers, to recover what a previous process committed; every
+// call after that, for any consumer, is served from memory. ok is false if
+// CommitOffset has never been called for consumer.
+func (w *WAL) Offset(consumer string) (pos *ChunkPosition, ok bool, err error) {
+	w.mu.Lock()
+	scanned := w.offsetsScanned
+	pos, ok = w.offsets[consumer]
+	w.mu.Unlock()
+	if scanned {
+		return pos, ok, nil
+	}
+
+	scannedOffsets := make(map[string]*ChunkPosition)
+	r := w.NewReader()
+	for {
+		data, _, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		if name, p, ok := decodeOffsetMarker(data); ok {
+			scannedOffsets[name] = p
+		}
+	}
+
+	w.mu.Lock()
+	if !w.offsetsScanned {
+		for name, p := range scannedOffsets {
+			w.offsets[name] = p
+		}
+		w.offsetsScanned = true
+	}
+	pos, ok = w.offsets[consumer]
+	w.mu.Unlock()
+	return pos, ok, nil
+}
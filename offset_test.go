@@ -0,0 +1,111 @@
+package wal
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOffsetReturnsNotFoundBeforeAnyCommitOffset(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-offset-missing-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("ordinary record"))
+	require.Nil(t, err)
+
+	pos, ok, err := w.Offset("consumer-a")
+	require.Nil(t, err)
+	require.False(t, ok)
+	require.Nil(t, pos)
+}
+
+func TestOffsetReturnsTheNewestCommitPerConsumer(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-offset-newest-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	recordA1, err := w.Write([]byte("a-1"))
+	require.Nil(t, err)
+	_, err = w.CommitOffset("consumer-a", recordA1)
+	require.Nil(t, err)
+
+	recordB1, err := w.Write([]byte("b-1"))
+	require.Nil(t, err)
+	_, err = w.CommitOffset("consumer-b", recordB1)
+	require.Nil(t, err)
+
+	recordA2, err := w.Write([]byte("a-2"))
+	require.Nil(t, err)
+	_, err = w.CommitOffset("consumer-a", recordA2)
+	require.Nil(t, err)
+
+	posA, ok, err := w.Offset("consumer-a")
+	require.Nil(t, err)
+	require.True(t, ok)
+	require.Equal(t, recordA2, posA)
+
+	posB, ok, err := w.Offset("consumer-b")
+	require.Nil(t, err)
+	require.True(t, ok)
+	require.Equal(t, recordB1, posB)
+}
+
+func TestOffsetRecoversAfterReopen(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-offset-reopen-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	record, err := w.Write([]byte("tailed record"))
+	require.Nil(t, err)
+	_, err = w.CommitOffset("consumer-a", record)
+	require.Nil(t, err)
+	_, err = w.Write([]byte("after"))
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+
+	w2, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w2.Close()
+
+	pos, ok, err := w2.Offset("consumer-a")
+	require.Nil(t, err)
+	require.True(t, ok)
+	require.Equal(t, record, pos)
+}
+
+func TestCommitOffsetUpdatesInMemoryStateWithoutRequiringAScan(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-offset-inmemory-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	// Force the lazy scan to run once, before any CommitOffset call, the
+	// same way a fresh reader tailing from Open onward might.
+	_, _, err = w.Offset("consumer-a")
+	require.Nil(t, err)
+
+	record, err := w.Write([]byte("tailed record"))
+	require.Nil(t, err)
+	_, err = w.CommitOffset("consumer-a", record)
+	require.Nil(t, err)
+
+	pos, ok, err := w.Offset("consumer-a")
+	require.Nil(t, err)
+	require.True(t, ok)
+	require.Equal(t, record, pos)
+}
@@ -0,0 +1,69 @@
+package wal
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenParallelismReadsAllSegments(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-open-parallelism-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(64))
+	require.Nil(t, err)
+	var positions []*ChunkPosition
+	for i := 0; i < 30; i++ {
+		pos, err := w.Write([]byte{byte(i), byte(i), byte(i)})
+		require.Nil(t, err)
+		positions = append(positions, pos)
+	}
+	require.Nil(t, w.Close())
+	require.Greater(t, len(w.olderSegments)+1, 3, "test needs several sealed segments to exercise parallel opening")
+
+	w2, err := Open(WithDirPath(dir), WithSegmentSize(64), WithOpenParallelism(4))
+	require.Nil(t, err)
+	defer w2.Close()
+
+	for i, pos := range positions {
+		data, err := w2.Read(pos)
+		require.Nil(t, err, "record %d", i)
+		assert.Equal(t, []byte{byte(i), byte(i), byte(i)}, data)
+	}
+}
+
+func TestOpenParallelismDefaultIsSerial(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-open-parallelism-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	assert.Zero(t, w.options.OpenParallelism)
+	require.Nil(t, w.Close())
+}
+
+func TestOpenParallelismSurfacesSegmentError(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-open-parallelism-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(64))
+	require.Nil(t, err)
+	for i := 0; i < 10; i++ {
+		_, err := w.Write([]byte{byte(i), byte(i), byte(i)})
+		require.Nil(t, err)
+	}
+	require.Nil(t, w.Close())
+
+	_, sealedPaths, err := segmentPaths(osFS{}, dir, ".SEG", nil)
+	require.Nil(t, err)
+	require.Greater(t, len(sealedPaths), 1)
+	require.Nil(t, os.Truncate(sealedPaths[0], segmentHeaderSize-1))
+
+	_, err = Open(WithDirPath(dir), WithOpenParallelism(4))
+	assert.NotNil(t, err)
+}
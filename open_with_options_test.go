@@ -0,0 +1,38 @@
+package wal
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenWithOptionsRoundTrip(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-open-with-options-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := OpenWithOptions(Options{DirPath: dir, SegmentSize: 64 * MB})
+	require.Nil(t, err)
+	defer w.Close()
+
+	pos, err := w.Write([]byte("hello"))
+	require.Nil(t, err)
+
+	got, err := w.Read(pos)
+	require.Nil(t, err)
+	require.Equal(t, []byte("hello"), got)
+}
+
+func TestOpenWithOptionsBackfillsZeroValuedFields(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-open-with-options-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := OpenWithOptions(Options{DirPath: dir})
+	require.Nil(t, err)
+	defer w.Close()
+
+	require.Equal(t, DefaultOptions.SegmentSize, w.options.SegmentSize)
+	require.Equal(t, DefaultOptions.SegmentFileExt, w.options.SegmentFileExt)
+}
@@ -1,6 +1,15 @@
 package wal
 
-import "os"
+import (
+	"crypto/ed25519"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
 
 type Option func(*Options)
 
@@ -19,6 +28,36 @@ type Options struct {
 	// Not a common usage for most users.
 	SegmentFileExt string
 
+	// SegmentFileNameFunc, when set, overrides the built-in zero-padded
+	// numeric naming scheme (%09d followed by SegmentFileExt) a segment
+	// file is named with, returning just the file's base name for id --
+	// e.g. to match an existing operational naming convention, or to let
+	// this WAL's segments coexist in a directory alongside files from
+	// something else that would otherwise collide with SegmentFileExt
+	// alone. It must be set together with SegmentFileNameParseFunc, its
+	// inverse, or Open returns an error; nil (the default) uses the
+	// built-in scheme for both.
+	SegmentFileNameFunc func(id SegmentID) string
+
+	// SegmentFileNameParseFunc is SegmentFileNameFunc's inverse: given a
+	// directory entry's base name, it returns the SegmentID encoded in it
+	// and true, or false if name isn't one of this WAL's segment files --
+	// e.g. it belongs to something else sharing the directory, or doesn't
+	// match the naming convention at all. It must be set together with
+	// SegmentFileNameFunc, or Open returns an error; nil (the default)
+	// uses the built-in scheme's parser.
+	SegmentFileNameParseFunc func(name string) (id SegmentID, ok bool)
+
+	// StartSegmentID is the SegmentID a brand new WAL -- one with no
+	// existing segment files in DirPath yet -- numbers its first segment
+	// with, instead of the default 1. It has no effect once DirPath
+	// already holds segments; their own IDs, not this option, pick up the
+	// numbering from there. Useful when a downstream system keys archives
+	// by segment ID and this WAL is replacing or continuing one built by
+	// Migrate, a restore, or some other external process whose numbering
+	// it needs to carry on rather than restart.
+	StartSegmentID SegmentID
+
 	// Sync is whether to synchronize writes through os buffer cache and down onto the actual disk.
 	// Setting sync is required for durability of a single write operation, but also results in slower writes.
 	//
@@ -31,6 +70,924 @@ type Options struct {
 
 	// BytesPerSync specifies the number of bytes to write before calling fsync.
 	BytesPerSync uint32
+
+	// SyncFileRange changes how BytesPerSync is enforced on Linux: instead
+	// of a blocking fsync every time BytesPerSync is crossed, it issues a
+	// sync_file_range hint that starts writeback of just the bytes
+	// appended since the last checkpoint and returns immediately, and
+	// falls back to a real fsync every
+	// syncFileRangeCheckpointInterval-th crossing to bound how far
+	// durability can drift behind. This smooths out the latency spikes a
+	// blocking fsync causes under sustained write bursts, at the cost of
+	// a wider (but bounded) data-loss window than BytesPerSync alone.
+	//
+	// Has no effect unless BytesPerSync is also set, and is silently
+	// equivalent to a plain fsync on platforms without a separate
+	// sync_file_range syscall or when the segment's fd isn't a real
+	// *os.File. It is false by default.
+	SyncFileRange bool
+
+	// Fdatasync makes Sync flush a segment's data blocks without also
+	// flushing metadata (mtime, size, and so on) that doesn't affect being
+	// able to read the data back. A WAL segment's size is already fixed
+	// once it's been written to -- see newSegmentFile's preallocation --
+	// so there's normally nothing useful in that metadata to lose, and
+	// skipping it roughly halves sync latency on ext4 and xfs.
+	//
+	// Only takes effect on platforms with a separate fdatasync syscall and
+	// when the segment's fd is a real *os.File; it's silently equivalent
+	// to a full fsync otherwise. It is false by default.
+	Fdatasync bool
+
+	// Checksum selects the algorithm used to detect a corrupted chunk. It
+	// is recorded in a segment's header when the segment is created, so an
+	// existing segment is always verified with the algorithm it was
+	// actually written with; Open returns ErrChecksumMismatch if an
+	// existing segment's recorded algorithm doesn't match this field.
+	// Defaults to ChecksumCRC32.
+	Checksum Checksum
+
+	// SkipReadVerification, when true, skips the checksum check Read,
+	// ReadInto, and Reader.Next normally perform on every chunk they
+	// return. Checksum verification runs on every read, not just at Open
+	// or Repair time, so recovering that CPU cost is worthwhile for a
+	// caller whose own layer above the WAL already validates content some
+	// other way; everyone else should leave this at its default of false,
+	// since a corrupted chunk then surfaces as whatever bytes happen to be
+	// on disk instead of ErrInvalidCRC. It has no effect on Repair or the
+	// package-level Verify, which always verify every chunk they scan
+	// regardless. Set it with WithVerifyOnRead(false).
+	SkipReadVerification bool
+
+	// VerifyOnOpen, when true, makes Open run VerifyAll against DirPath
+	// before doing anything else, failing with an *ErrVerifyOnOpenFailed
+	// reporting every defect found rather than opening a log that might
+	// be sitting on corruption RecoverStrict's usual tail-only check
+	// wouldn't catch. It defaults to false because a full scan costs
+	// time proportional to the whole log's size, not just its active
+	// segment; set it after restoring from a backup of uncertain quality,
+	// where finding out up front beats finding out one Read at a time.
+	// Set it with WithVerifyOnOpen.
+	VerifyOnOpen bool
+
+	// ChainChecksums, when true, folds each chunk's predecessor's checksum
+	// into its own, so deleting or reordering a chunk within a segment
+	// breaks the chain even though the tampered-with chunk's own bytes are
+	// never touched -- catching tampering, not just the bit rot a plain
+	// Checksum already detects. It is recorded in a segment's header when
+	// the segment is created, the same way Checksum is; Open returns
+	// ErrChainChecksumsMismatch if an existing segment's recorded setting
+	// doesn't match this field.
+	//
+	// Because a chunk's checksum then depends on the chunk before it,
+	// Read, ReadInto, and Reader.Next can no longer verify a chunk in
+	// isolation: only a sequential scan that has walked every earlier
+	// chunk in the segment -- Reader.Next from the start of a segment,
+	// Verify, VerifyAll, or Repair -- can. Read, ReadInto, and the
+	// segment-internal random-access path behind them skip their checksum
+	// check entirely when this is set, regardless of
+	// SkipReadVerification. It defaults to false. Set it with
+	// WithChainChecksums.
+	ChainChecksums bool
+
+	// MetricsCollector receives instrumentation events (fsync duration, bytes
+	// written, chunk read latency, segment rotations, and so on) emitted by
+	// the WAL. It defaults to a no-op collector; set it with
+	// WithMetricsRegisterer or WithMetricsCollector to enable observability.
+	MetricsCollector Collector
+
+	// Logger receives log records for background events the WAL decides on
+	// its own -- segment rotation, retention deletions, recovery
+	// truncation, fsync failure -- instead of staying silent about them or
+	// printing to stdout. It defaults to a no-op logger; set it with
+	// WithLogger, e.g. to a *slog.Logger, to see them.
+	Logger Logger
+
+	// Clock supplies the current time and the tickers that drive the
+	// sync-interval flusher (see Options.SyncInterval, Options.FlushInterval)
+	// and age-based retention (Options.MaxSegmentAge, DropExpiredSegments).
+	// It defaults to the real wall clock; set it with WithClock to a
+	// *FakeClock so a test can drive both with Tick instead of sleeping.
+	Clock Clock
+
+	// Mode selects the durability semantics the WAL is opened with. It
+	// defaults to ModeAhead; OpenWBL sets it to ModeBehind.
+	Mode Mode
+
+	// FlushInterval is how often a write-behind log (opened with OpenWBL)
+	// syncs its active segment in the background. Zero disables the
+	// background flusher; it has no effect on a log opened with Open.
+	FlushInterval time.Duration
+
+	// SyncInterval is how often a write-ahead log (the default Mode,
+	// opened with Open) syncs its active segment in the background,
+	// bounding how long a write made with Sync false can stay unflushed
+	// without paying fsync latency on every single write -- something
+	// between Sync false and Sync true. Zero disables the background
+	// syncer; it has no effect on a write-behind log opened with OpenWBL,
+	// which uses FlushInterval for the same purpose instead.
+	SyncInterval time.Duration
+
+	// Pipelined, when true, changes what Write itself does: instead of
+	// appending to the active segment inline, it queues the record in
+	// memory and returns the position it will occupy once a dedicated
+	// background goroutine (started by Open) actually writes and syncs
+	// it, batching everything queued by the time that goroutine gets to
+	// run. This trades the usual guarantee that Read can see a position
+	// the moment Write returns it for throughput under many concurrent
+	// producers -- Read, Checkpoint, and anything else that looks at
+	// segment files directly only sees a pipelined write once it has been
+	// flushed; WaitForSync and DurablePosition wait for exactly that. It
+	// is independent of Mode and of Options.Sync/GroupCommit, which this
+	// mode bypasses entirely in favor of its own batching. Off by
+	// default.
+	Pipelined bool
+
+	// MaxPendingBytes, when positive, caps how much data Write queued
+	// under Pipelined without the background flusher draining it yet.
+	// Once the cap is hit, Write blocks -- applying backpressure to the
+	// producer -- until something drains the queue: PipelineFlushInterval's
+	// ticker, a rotation forced by the next Write, or Close. It is
+	// disabled (0) by default, meaning a producer that outruns disk
+	// throughput can grow the queue unboundedly; it has no effect unless
+	// Pipelined is true. Pairing it with a positive PipelineFlushInterval
+	// is what actually relieves the backpressure it applies.
+	MaxPendingBytes int64
+
+	// PipelineFlushInterval bounds how long a write queued under
+	// Pipelined can sit unflushed: Open starts a background goroutine
+	// that drains the queue at least this often. Zero disables the
+	// interval-based drain, leaving rotation and Close as the only other
+	// things that do; it has no effect unless Pipelined is true.
+	PipelineFlushInterval time.Duration
+
+	// GroupCommit batches concurrent Write calls made with Sync true: a
+	// Write already releases the WAL lock once its own payload is appended
+	// and fsyncs the active segment unlocked rather than holding the lock
+	// for the fsync too, regardless of this option -- GroupCommit decides
+	// whether that unlocked fsync is done alone or shared. With GroupCommit
+	// on, a Write waits for a single fsync that also covers every other
+	// Write that reached the same point for the same segment, instead of
+	// calling fsync for itself alone. Throughput under many concurrent
+	// synchronous writers goes up because the number of fsyncs drops to
+	// roughly one per batch instead of one per write; a single writer sees
+	// no benefit, since there's nothing to batch with. It has no effect
+	// when Sync is false, or in ModeBehind, or on WriteAll, which already
+	// batches its own pending writes under one lock and one fsync.
+	GroupCommit bool
+
+	// WriteLinger, when positive, changes what a synchronous Write does
+	// while Pipelined is false: instead of appending (and, if Sync is set,
+	// fsyncing) its data the moment it's called, it joins whatever batch
+	// of other Write calls is currently accumulating, waits up to
+	// WriteLinger for more to join, then blocks until that batch's append
+	// and fsync both complete -- the same Kafka-style linger.ms trade a
+	// producer makes for bigger batches at the cost of latency. Unlike
+	// GroupCommit, which only coalesces writers that happen to already be
+	// racing for the same segment's fsync, WriteLinger proactively waits
+	// to accumulate joiners, and coalesces the append as well as the
+	// fsync, regardless of Sync or GroupCommit. Unlike Pipelined, a Write
+	// under WriteLinger still doesn't return until its data is actually
+	// durable and visible to Read. It has no effect when Pipelined is
+	// true, which bypasses this entirely in favor of its own batching; it
+	// is disabled (0) by default.
+	WriteLinger time.Duration
+
+	// SegmentPreallocate specifies how many next-segment files a
+	// background file pipeline should keep pre-created, pre-allocated to
+	// SegmentSize, and locked, ready for segment rotation to pick up.
+	// When 0 (the default), rotation creates each new segment file on
+	// the hot path instead.
+	SegmentPreallocate int
+
+	// RecycleSegments makes a retired sealed segment's file get handed
+	// back to the background file pipeline for reuse as a future segment
+	// -- renamed and overwritten in place -- instead of being deleted
+	// outright, the way etcd's WAL recycles its own segment files. Reusing
+	// the same inode skips the allocate-and-extend cost of creating a
+	// brand new file from scratch, and keeps Fdatasync's "nothing useful
+	// in the metadata to lose" assumption true for the file's entire
+	// lifetime rather than just from its first write onward. Has no
+	// effect unless SegmentPreallocate is also set, since there is no
+	// pipeline to hand a retired segment's file to otherwise. It is false
+	// by default.
+	RecycleSegments bool
+
+	// Preallocate makes every freshly created segment file -- the active
+	// segment on the hot path, not just the ones SegmentPreallocate's
+	// background pipeline hands out -- fallocate its full SegmentSize up
+	// front instead of growing one chunk write at a time. This avoids the
+	// filesystem metadata journaling that comes with extending a file's
+	// length on every append, and keeps a segment's blocks contiguous,
+	// which combined with Fdatasync meaningfully cuts write latency under
+	// sustained append load. It is false by default.
+	Preallocate bool
+
+	// RepairOnOpen specifies whether Open should attempt to repair the
+	// last segment automatically, via Repair, when it fails to load
+	// because of a torn tail write. It has no effect on corruption found
+	// anywhere other than the tail of the last segment; see
+	// ErrCorruptMidSegment. Equivalent to RecoveryMode's RecoverTail, and
+	// superseded by it: set RecoveryMode instead for RecoverSkipCorrupt's
+	// broader tolerance.
+	RepairOnOpen bool
+
+	// RecoveryMode controls how Open reacts to corruption found while
+	// loading the last segment, as a more expressive alternative to
+	// RepairOnOpen's plain on/off switch. It defaults to RecoverStrict,
+	// in which case RepairOnOpen's value is what actually applies; set it
+	// explicitly to take precedence over RepairOnOpen.
+	RecoveryMode RecoveryMode
+
+	// Compression selects the codec Write and WriteAll compress chunk
+	// payloads with before they hit disk; Read and Reader.Next decompress
+	// with the same codec. It defaults to CompressionNone. It is recorded
+	// in a segment's header when the segment is created, so Open returns
+	// ErrCompressionMismatch rather than letting an existing segment be
+	// read back with the wrong codec.
+	Compression CompressionCodec
+
+	// CompressionDict, when set via WithCompressionDict, is a zstd
+	// dictionary (in the format "zstd --train" or zstd.BuildDict produces)
+	// Write and WriteAll prime the compressor with, so records too small
+	// for zstd to find much repetition in on their own -- a 100-300 byte
+	// JSON blob or protobuf message, say -- still compress meaningfully
+	// against patterns learned from a training corpus of similar records.
+	// Read and Reader.Next need the same dictionary to decompress. It is
+	// only valid alongside Compression set to CompressionZstd, and the
+	// dictionary's own ID (not a hash WAL invents) is recorded in a
+	// segment's header when the segment is created, so Open returns
+	// ErrCompressionDictMismatch rather than letting an existing segment
+	// be read back with the wrong dictionary the same way
+	// ErrCompressionMismatch already guards against the wrong codec.
+	CompressionDict []byte
+
+	// Timestamps, when true, makes Write, WriteAll, and Batch prefix every
+	// chunk's payload with the time it was written, before compression and
+	// encryption -- the same trick WriteWithTTL already uses for its own
+	// expiry, just applied automatically to every chunk instead of only
+	// ones written through that call. Read and Reader.Next strip it back
+	// off transparently; ReadWithTimestamp and Reader.NextWithTimestamp
+	// additionally return it, for time-based retention, replication lag
+	// metrics, or point-in-time recovery. It defaults to false. A WAL must
+	// be reopened with the same value it was written with, or Read and
+	// Reader.Next will return garbage instead of the original data.
+	Timestamps bool
+
+	// SegmentRotationInterval, when positive, makes Open start a
+	// background goroutine that force-rotates the active segment -- the
+	// same rotation Rotate triggers on demand -- once it has been active
+	// at least this long, even if Options.SegmentSize is nowhere near
+	// full. Useful for time-aligned archival or per-day retention, where
+	// what matters is when a segment started, not how big it got. A quiet
+	// WAL with no writes still rotates on schedule, leaving empty
+	// segments behind; it is disabled (0) by default.
+	SegmentRotationInterval time.Duration
+
+	// MaxSegmentAge, when positive, makes rotation remove sealed segments
+	// older than this age, via the same refcounted retire Checkpoint uses,
+	// so readers already in flight against a to-be-removed segment finish
+	// cleanly instead of racing a closed fd. A segment's age is tracked
+	// from when it became the active segment, not from when its chunks
+	// were individually written, and -- because nothing in the segment
+	// header records it -- is only approximated by mtime across process
+	// restarts. It is disabled (0) by default.
+	MaxSegmentAge time.Duration
+
+	// MaxTotalSize, when positive, makes rotation remove the oldest sealed
+	// segments, via the same refcounted retire Checkpoint uses, whenever
+	// the sum of every segment file's size (including the active segment)
+	// exceeds this cap. The active segment is never removed, so the WAL
+	// can exceed MaxTotalSize by up to one segment's worth of data if
+	// MaxSegmentAge isn't also keeping it in check. It is disabled (0) by
+	// default.
+	MaxTotalSize int64
+
+	// DropExpiredSegments, when true, makes rotation remove sealed segments
+	// every one of whose chunks was written via WriteWithTTL and has since
+	// expired, via the same refcounted retire Checkpoint uses. A segment
+	// that also holds even one chunk written by Write, WriteWithFlags, or
+	// WriteAll -- which carry no expiry -- is never removed this way,
+	// since there would be no way to tell whether that chunk is still
+	// wanted. Tracked in memory only: unlike MaxSegmentAge, nothing
+	// approximates it from a segment's file across a restart, so a chunk's
+	// expiry tracking resets and a sealed segment is no longer eligible
+	// once the process that wrote it is gone. It is disabled (false) by
+	// default.
+	DropExpiredSegments bool
+
+	// PinnedSegmentPolicy controls what MaxSegmentAge, MaxTotalSize, and
+	// DropExpiredSegments do when the sealed segment they'd otherwise
+	// remove is pinned -- a WAL.Read or Reader.Next call is positioned in
+	// it right now. The default, PinnedSegmentSkip, leaves a pinned
+	// segment in place for this enforcement pass and retries it on the
+	// next one, so a Reader working through it at its own pace is never
+	// cut off mid-segment the way retiring it out from under an
+	// already-acquired reference would. See PinnedSegmentPolicy's values
+	// for the alternatives.
+	PinnedSegmentPolicy PinnedSegmentPolicy
+
+	// Limiter, when set, throttles Write, WriteWithFlags, WriteWithTTL,
+	// and WriteAll to however many bytes per second it allows -- so a
+	// background WAL rebuild or bulk import on a shared disk doesn't
+	// starve some other, latency-sensitive writer's fsyncs. See
+	// WithRateLimit for the common case of a fixed byte-per-second cap,
+	// or set this directly with a custom Limiter (e.g. one sharing a
+	// quota across several WALs). It is disabled (nil) by default.
+	Limiter Limiter
+
+	// MaxRecordSize, when positive, makes Write, WriteWithFlags,
+	// WriteWithTTL, and PendingWrites reject a record larger than this
+	// with ErrRecordTooLarge before compressing, encrypting, or buffering
+	// any of it, rather than letting it fail later -- inside WriteAll, for
+	// PendingWrites -- once the whole batch has already been staged. It is
+	// disabled (0) by default.
+	MaxRecordSize int64
+
+	// MaxPendingSize, when positive, makes PendingWrites reject a record
+	// with ErrPendingSizeTooLarge, leaving it out of the batch, once
+	// buffering it would put the combined size of every record staged
+	// since the last WriteAll over this cap -- the moment the cap is
+	// exceeded, before WriteAll is ever called, not after. It is
+	// disabled (0) by default.
+	MaxPendingSize int64
+
+	// FS is the filesystem segment files and segment listing are read and
+	// written through. It defaults to the real OS filesystem; set it with
+	// WithFS to run on afero, a fault-injection wrapper, or another
+	// virtual filesystem instead. See FS's doc comment for which parts of
+	// the WAL aren't routed through it yet.
+	FS FS
+
+	// OnSegmentSealed, when set, is called synchronously by rotation right
+	// after a segment has been synced and sealed, with its file path and
+	// ID, before the next write proceeds. It is meant for archiving a
+	// closed segment (e.g. uploading it to S3) before later deleting it
+	// locally via TruncateBefore or a retention policy; since it runs
+	// inline with rotation, a slow hook delays whichever Write or WriteAll
+	// call triggered the rotation.
+	OnSegmentSealed func(path string, id SegmentID)
+
+	// EncryptionKey, when set via WithEncryption, is a 32-byte AES-256 key
+	// Write and WriteAll encrypt chunk payloads with (AES-256-GCM) before
+	// they hit disk; Read and Reader.Next decrypt with the same key. It is
+	// empty by default, leaving chunk payloads unencrypted. A WAL must be
+	// reopened with the same key it was written with. Mutually exclusive
+	// with Keyring and KeyProvider.
+	EncryptionKey []byte
+
+	// Keyring, when set via WithKeyring, is a set of AES-256 keys indexed
+	// by an ID the caller assigns them, for encryption that can be
+	// rotated without losing the ability to read what came before the
+	// rotation. New chunks are encrypted (AES-256-GCM) with the key whose
+	// ID is highest in the map -- so rotating in a new key is just adding
+	// it under a higher ID -- and tagged with that ID; Read and
+	// Reader.Next look the ID back up in Keyring to decrypt, so a segment
+	// written under an older key stays readable as long as that key
+	// remains in the map. See RewriteWithKey to re-encrypt old segments
+	// under the current key and let a retired one finally be dropped from
+	// Keyring. It is sugar for a KeyProvider that always returns this same
+	// fixed set of keys; use KeyProvider directly if the keys themselves
+	// need to come from somewhere the WAL shouldn't hold onto them for its
+	// whole lifetime. Mutually exclusive with EncryptionKey and
+	// KeyProvider.
+	Keyring map[uint32][]byte
+
+	// KeyProvider, when set via WithKeyProvider, supplies AES-256 keys the
+	// same way Keyring does -- an ID-tagged key for new writes, and any
+	// previously-used key looked up by its ID for reads -- but on demand
+	// rather than as a fixed map handed to Open once, so the keys
+	// themselves can live in AWS KMS, HashiCorp Vault, an HSM, or anywhere
+	// else that shouldn't hand the WAL key material to hold onto for its
+	// whole lifetime. See the KeyProvider interface. Mutually exclusive
+	// with EncryptionKey and Keyring.
+	KeyProvider KeyProvider
+
+	// OnSyncError, when set, is called synchronously the first time an
+	// fsync fails, with the error that failed it. After that, the WAL
+	// stops accepting writes (see ErrWALPoisoned) rather than risk
+	// silently losing data that looks durable but never reached disk --
+	// the "fsyncgate" failure mode. A WAL in this state must be closed and
+	// reopened (after addressing whatever made fsync fail) before it will
+	// accept writes again.
+	OnSyncError func(err error)
+
+	// DiskFullReserve, when positive, makes Open preallocate a headroom
+	// file of this many bytes in DirPath, released the first time a
+	// write or sync reports the disk full -- see ErrDiskFull -- so
+	// there's still room left for a Checkpoint or
+	// TruncateBefore/TruncateAfter to run afterward and reclaim space for
+	// real. It is disabled (0) by default.
+	DiskFullReserve int64
+
+	// OnDiskFull, when set, is called synchronously the first time a
+	// write or sync reports the disk full, with the *ErrIOFailed it
+	// produced, so the application can shed load or alert instead of
+	// retrying the same write in a crash loop. Unlike OnSyncError, the
+	// WAL is not poisoned purely because of this: once space frees up
+	// (releasing DiskFullReserve's reserve, if set, should help), writes
+	// can succeed again -- except a disk-full fsync still poisons the WAL
+	// the same as any other fsync failure would (see OnSyncError).
+	OnDiskFull func(err error)
+
+	// SlowSyncThreshold, when positive, makes every fsync that takes at
+	// least this long trigger OnSlowSync. It is disabled (0) by default.
+	SlowSyncThreshold time.Duration
+
+	// OnSlowSync, when set, is called synchronously right after an fsync
+	// that took at least SlowSyncThreshold, with how long it actually
+	// took. Disk stalls are the most common WAL production incident, and
+	// this is meant to surface them (alerting, logging, shedding load)
+	// as they happen rather than only after the fact from
+	// Stats.FsyncDurationHistogram. It has no effect if SlowSyncThreshold
+	// is 0.
+	OnSlowSync func(d time.Duration)
+
+	// OnRotate, when set, is called synchronously by rotation right after
+	// a new segment becomes active, with the sealed segment's ID and the
+	// new active segment's ID. It differs from OnSegmentSealed in when it
+	// fires (after the new segment exists, not right after the old one is
+	// sealed) and what it's given (both IDs, not the sealed segment's
+	// path); use it to record segment boundaries in an external index or
+	// to trigger a Checkpoint at rotation time.
+	OnRotate func(oldID, newID SegmentID)
+
+	// OnDurable, when set, is called synchronously every time the fsync
+	// frontier WaitForSync and DurablePosition track advances, with the
+	// new frontier position. A pipelined producer can use it to ack
+	// clients as their writes become durable without polling
+	// DurablePosition or calling WaitForSync per write. It does not fire
+	// once per Write: a single fsync that covers several writes reports
+	// only the latest position, since everything at or before it is
+	// durable too.
+	OnDurable func(pos *ChunkPosition)
+
+	// Hooks holds optional callbacks bracketing Write, WriteAll, Read, and
+	// Sync, meant for integrating distributed tracing. It is unset (every
+	// callback nil) by default. See Hooks's doc comment.
+	Hooks Hooks
+
+	// ReadOnly opens the WAL without taking write ownership of DirPath, so
+	// a second process can inspect a log a writer already has open: Open
+	// returns an error if DirPath has no segments yet instead of creating
+	// one, segment files are opened O_RDONLY, and Write, WriteAll, Sync,
+	// Checkpoint, Repair, TruncateAfter, and TruncateBefore all fail with
+	// ErrReadOnly. Reading and iterating (Read, NewReader,
+	// NewReaderWithStart, NewTailReader) are unaffected. It is false by
+	// default.
+	ReadOnly bool
+
+	// MmapRead memory-maps a segment's file once it's sealed (or, for a
+	// segment already sealed by a previous run, once Open finds it), and
+	// serves Read from that mapping instead of a pread syscall per chunk.
+	// Worthwhile for random-read-heavy workloads against a log with many
+	// sealed segments; skipped for the active segment, since remapping it
+	// on every rotation would cost more than it saves.
+	//
+	// Only takes effect when FS is the default, real-filesystem one, and
+	// only on platforms this package knows how to mmap on; it's silently a
+	// no-op otherwise. It is false by default.
+	MmapRead bool
+
+	// Madvise selects the madvise access-pattern hint applied to a
+	// segment's mapping when Options.MmapRead maps it, tuning the
+	// kernel's readahead to match how this WAL is actually read:
+	// MadviseRandom for a random-read-heavy index workload,
+	// MadviseSequential for a workload that mostly replays segments in
+	// order via NewReader or NewTailReader. It has no effect unless
+	// MmapRead is also set. It defaults to MadviseNormal (no hint).
+	Madvise Madvise
+
+	// FadviseDontNeed issues a posix_fadvise(POSIX_FADV_DONTNEED) hint
+	// against a segment's file once it's sealed (or, for a segment already
+	// sealed by a previous run, once Open finds it), so the pages that
+	// segment's write path populated in the OS page cache are dropped
+	// instead of sitting there evicting hotter, more recently touched data
+	// -- worthwhile for a log whose multi-GB history vastly exceeds what
+	// the rest of the database's working set needs cached. Leave it off
+	// for a workload that expects to re-read recently sealed segments
+	// soon, since a dropped page just has to be faulted back in from disk
+	// on the next Read against it; it composes fine with Options.MmapRead,
+	// which re-faults the same way on its next access.
+	//
+	// Only takes effect when a segment's fd is a real *os.File and on
+	// platforms this package knows how to fadvise on; it's silently a
+	// no-op otherwise. It is false by default.
+	FadviseDontNeed bool
+
+	// DirectIO opens segment files with O_DIRECT, so large sequential WAL
+	// writes bypass the OS page cache instead of evicting whatever the
+	// read path has cached there. Segment chunks aren't padded to the
+	// filesystem's logical block size, so this package can't guarantee
+	// every read or write lands aligned the way O_DIRECT strictly
+	// requires; when the kernel or filesystem rejects that, the affected
+	// fd transparently falls back to buffered I/O instead of failing the
+	// caller. It has no effect on segments created via
+	// Options.SegmentPreallocate's background pipeline -- see
+	// newFilePipeline. Treat it as a best-effort hint, not a durability or
+	// performance guarantee, and only takes effect when FS is the
+	// default, real-filesystem one, and on platforms this package knows
+	// how to open files with O_DIRECT on. It is false by default.
+	DirectIO bool
+
+	// IOUring requests an io_uring-based write path on Linux, batching
+	// chunk writes and fsyncs from the pending-writes queue into a single
+	// submission instead of one syscall per write.
+	//
+	// This is currently recorded but not yet wired to an actual
+	// io_uring-backed path: doing that safely needs either a vetted
+	// io_uring library dependency or hand-written submission/completion
+	// ring handling with kernel-ABI structs this package doesn't define
+	// today, and getting either wrong risks corrupting writes rather than
+	// just being slow. Until one of those lands, setting IOUring is a
+	// no-op and every write still goes through the normal buffered path.
+	// It is false by default.
+	IOUring bool
+
+	// BufferPool supplies the scratch buffers Write and WriteAll compress
+	// chunk payloads into, in place of a fresh allocation every call; it is
+	// used only when Compression is not CompressionNone, since that's the
+	// step that would otherwise allocate on every write. Set it to plug in
+	// a custom pool; leave it nil (the default) along with WriteBufferSize
+	// to disable pooling entirely and allocate fresh buffers as before. If
+	// nil but WriteBufferSize is positive, Open builds the default
+	// sync.Pool-backed implementation itself.
+	BufferPool BufferPool
+
+	// WriteBufferSize sizes the buffers the default BufferPool's buffers
+	// start at, and -- when BufferPool itself is left nil -- is what tells
+	// Open to build that default pool at all; see Options.BufferPool. It
+	// has no effect once a custom BufferPool is set. It is 0 (pooling
+	// disabled) by default.
+	//
+	// Despite the similar name, this is unrelated to
+	// Options.SegmentWriteBufferSize: that one batches multiple Write calls'
+	// bytes into fewer file-write syscalls, while this one only avoids
+	// reallocating the scratch buffer Write compresses into.
+	WriteBufferSize int
+
+	// SegmentWriteBufferSize accumulates the bytes Write and WriteBatch
+	// append to the active segment in memory, flushing them to the file in
+	// one syscall once the buffer would reach this many bytes, instead of
+	// every call reaching the file on its own. That turns a small-record
+	// workload's many tiny writes into fewer, larger ones, at the cost of a
+	// wider window in which a crash (not a clean Close) loses records a
+	// caller already got a ChunkPosition back for, on top of whatever
+	// window Options.Sync and Options.BytesPerSync already leave. Read,
+	// ReadWithCRC, and ReadInto are unaffected: they see a buffered-but-
+	// unflushed record the same as an already-flushed one, and Sync, seal,
+	// truncate, and Close all flush the buffer first, so neither read-your-
+	// own-writes consistency nor Options.Sync's durability promise is
+	// weakened by enabling this. It is 0 (buffering disabled, every Write
+	// and WriteBatch reaches the file immediately) by default.
+	//
+	// Despite the similar name, this is unrelated to Options.WriteBufferSize;
+	// see its doc comment.
+	SegmentWriteBufferSize int
+
+	// BlockSize is recorded for callers migrating from a WAL design (e.g.
+	// etcd's or LevelDB's) that packs records into fixed-size blocks,
+	// splitting a record that doesn't fit into FIRST/MIDDLE/LAST chunks
+	// across consecutive blocks. This package has no such block framing:
+	// every Write is its own single length+checksum-prefixed chunk,
+	// regardless of how small or large it is relative to BlockSize, so
+	// there is no chunk-splitting overhead to reduce or padding to shrink
+	// in the first place. Setting it is currently a no-op recorded here
+	// only so that migrating code has somewhere to put the value; it does
+	// not change segment layout, space efficiency, or how a record of any
+	// size is written. It is 0 by default. It is recorded in a segment's
+	// header when the segment is created purely for that round-trip, not
+	// validated against an existing segment's recorded value the way
+	// Checksum and Compression are, since -- per the above -- nothing
+	// about how a segment was written actually depends on it.
+	BlockSize int
+
+	// BlockCache sizes, in bytes, an in-memory sharded LRU cache of decoded
+	// chunk payloads that Read and ReadWithTimestamp consult before
+	// touching the segment file at all -- for a rosedb-style workload whose
+	// random reads keep landing on the same hot keys, this turns most of
+	// them into a map lookup instead of a pread (or mmap page fault under
+	// Options.MmapRead). It is 0 (disabled) by default. Its current memory
+	// usage is reported in Stats.BlockCacheBytes.
+	BlockCache int
+
+	// OpenParallelism bounds how many already-sealed segments Open scans
+	// concurrently while recovering a directory, instead of one at a time.
+	// Each sealed segment's chunk scan (or footer load) and, with
+	// Options.MmapRead, its mmap setup are independent of every other
+	// segment's, so a directory with many sealed segments opens in
+	// roughly 1/OpenParallelism the wall-clock time, up to core count. It
+	// has no effect on the active segment, which Open always opens last
+	// and alone. Values below 1, including the 0 default, open segments
+	// one at a time, same as before this option existed.
+	OpenParallelism int
+
+	// CompressionParallelism bounds how many of WriteAll's pending batch
+	// entries are compressed concurrently, instead of one at a time, when
+	// Options.Compression is set. compressPayload builds a fresh, unshared
+	// encoder per call, so entries compress independently of each other;
+	// for a large batch of small-to-medium records this turns WriteAll's
+	// single-threaded compression pass -- otherwise the dominant cost of a
+	// multi-MB batch -- into roughly 1/CompressionParallelism the
+	// wall-clock time, up to core count. It has no effect on Write, which
+	// only ever compresses the one record it was given, or when
+	// Compression is CompressionNone. Values below 1, including the 0
+	// default, compress entries one at a time, same as before this option
+	// existed.
+	CompressionParallelism int
+
+	// ChunkIndex, when true, makes every segment write a dense, per-segment
+	// chunk-index sidecar file as it's sealed (see WAL.ReadNth), mapping
+	// each chunk's ordinal within that segment straight to its offset and
+	// size. This is deliberately separate from the sparse, WAL-global-
+	// sequence-keyed index ReadBySequence already uses off a segment's own
+	// footer: that one trades density for a bounded post-lookup scan, and
+	// lives inside the segment file it describes. ChunkIndex's sidecar is
+	// O(1) and lives in its own file, so it survives independently of
+	// whatever external index (e.g. a key/value store's hint file) its
+	// caller normally relies on, and can rebuild that index's lookups by
+	// segment-local ordinal even after losing it. It is false (disabled)
+	// by default; segments sealed before it was enabled, or whose sidecar
+	// write never made it to disk, fall back to a linear scan in ReadNth.
+	ChunkIndex bool
+
+	// SegmentSigningKey, when set via WithSegmentSigning, is an Ed25519
+	// private key seal signs each segment's whole-segment digest with
+	// (see footer.checksum) as it's rotated out of being active, writing
+	// the signature to a .SIG sidecar (see signSegmentDigest). This gives
+	// a compliance-grade audit log cryptographic tamper evidence: anyone
+	// holding the corresponding public key can confirm a sealed segment's
+	// bytes are exactly what this process wrote, not just that they pass a
+	// checksum an attacker who edited the segment could have recomputed
+	// too. It is nil (disabled) by default. Compact and RewriteWithKey
+	// also sign the replacement segments they build, since both reseal
+	// under this same key. See Options.SegmentVerifyKey to check
+	// signatures back.
+	SegmentSigningKey ed25519.PrivateKey
+
+	// SegmentVerifyKey, when set via WithSegmentVerification, is the
+	// Ed25519 public key Open checks every sealed segment's .SIG sidecar
+	// against before returning, failing with *ErrSegmentSignatureInvalid
+	// if any segment was signed under a different key or has been
+	// tampered with since, or ErrSegmentSignatureMissing if
+	// Options.SegmentSigningKey wasn't set (or hadn't been, yet) when a
+	// sealed segment was written. It works independently of
+	// SegmentSigningKey, so a reader that only ever needs to verify --
+	// and should never hold the private key that could forge a signature
+	// -- can set this alone. See also VerifySignatures, which
+	// cmd/walverify runs the same check with, and which this option is
+	// sugar for calling from Open.
+	SegmentVerifyKey ed25519.PublicKey
+
+	// IdempotencyWindow, when positive, is how long WriteIdempotent
+	// remembers a given id for: a call with an id it has already seen
+	// inside this window returns the original write's position instead of
+	// appending a duplicate chunk, so an at-least-once producer that times
+	// out waiting for an ack and retries doesn't leave two copies of the
+	// same record in the log. The window is tracked in memory and
+	// reconstructed by scanning the log when Open finds it set (see
+	// rebuildIdempotencyWindow), so a retry landing right after a restart
+	// is still deduplicated against a write from before it. It is 0
+	// (disabled) by default, in which case WriteIdempotent returns
+	// ErrIdempotencyWindowDisabled.
+	//
+	// Like Options.Timestamps, this only makes sense if every chunk in the
+	// WAL was written through WriteIdempotent -- Open's reconstruction scan
+	// reads every chunk's leading 16 bytes as an id and a write time
+	// regardless of how it was actually written; see WriteWithFlags's doc
+	// comment for the same ambiguity. That scan also costs time proportional
+	// to the whole log's size, the same trade-off Options.VerifyOnOpen
+	// makes, so set it knowing every Open pays for it, not just the first.
+	IdempotencyWindow time.Duration
+
+	// TokenSealKey, when set via WithTokenSealing, is the HMAC-SHA256 key
+	// SealPosition seals a ChunkPosition into an opaque token with, and
+	// UnsealPosition verifies one against. It is nil (disabled) by
+	// default, in which case both methods return ErrTokenSealingDisabled.
+	// Unlike SegmentSigningKey and SegmentVerifyKey, there is no
+	// fixed-size requirement -- any nonempty key HMAC accepts is valid --
+	// but it should be as unpredictable as any other shared secret, since
+	// anyone holding it can mint a token for any position, including one
+	// pointing into another tenant's records.
+	TokenSealKey []byte
+
+	// MirrorDir, when set via WithMirrorDir, is a second directory every
+	// Write (and anything built on top of it -- WriteWithFlags,
+	// WriteWithTTL, WriteAsync, and so on) also writes to, ideally on a
+	// different disk than DirPath, so a single-disk failure doesn't cost
+	// both copies. It's opened as its own WAL with the rest of these
+	// Options applied to it too (aside from the mirroring options
+	// themselves), the same as DirPath's, and Close closes it alongside
+	// DirPath's.
+	//
+	// This is not replication: there's no cross-process protocol, no
+	// follower catching up after being offline, and no way to promote the
+	// mirror if DirPath's disk is the one that fails -- a caller wanting
+	// that needs Replicator instead. It only ever protects against losing
+	// DirPath's disk while this process is still running, by keeping an
+	// independent, up-to-date-or-nearly-so copy next to it.
+	//
+	// It is "" (disabled) by default. It's incompatible with
+	// Options.ReadOnly, since a read-only WAL never calls Write.
+	//
+	// Mirroring only covers Write and what's built on it; WriteAll and
+	// Batch.Commit's writeBatch path bypass it entirely, so a WAL that
+	// relies on those for its writes gets no mirror coverage from them.
+	MirrorDir string
+
+	// MirrorAsync, when true, hands each write to a background goroutine
+	// for MirrorDir instead of writing to it inline. A synchronous mirror
+	// (the default) makes Write fail if the mirror write does, since
+	// keeping both copies in lockstep is the whole point; an asynchronous
+	// one instead lets the mirror fall up to MirrorLagBound writes behind
+	// DirPath before Write starts blocking on it, trading that guarantee
+	// for never letting a slow or struggling mirror disk slow down every
+	// write to the primary. See MirrorErr for how a caller notices an
+	// asynchronous mirror write failing.
+	MirrorAsync bool
+
+	// MirrorLagBound caps how many writes MirrorAsync may queue ahead of
+	// the background mirror writer before a further Write call blocks
+	// waiting for room, bounding how far MirrorDir can fall behind DirPath
+	// instead of letting the queue -- and the lag it represents -- grow
+	// without limit. It has no effect unless MirrorAsync is also set. 0
+	// (the default) uses defaultMirrorLagBound.
+	MirrorLagBound int
+
+	// ObjectStore is where TierSegments uploads a sealed segment's bytes
+	// once it's older than TieringAge, and where a Read fetches them back
+	// from on a cache miss. Required if TieringAge is set.
+	ObjectStore ObjectStore
+
+	// TieringAge is how old a sealed segment must be -- by the same
+	// createdAt MaxSegmentAge already compares against -- before
+	// TierSegments uploads it to ObjectStore and removes its local copy,
+	// so a WAL whose history vastly exceeds local disk capacity can keep
+	// only its recent segments on the fast path. A Read against a tiered
+	// segment still works afterward, just slower: it's fetched back into
+	// TieringCacheDir first. It is 0 (disabled) by default; enabling it
+	// requires ObjectStore and TieringCacheDir to be set.
+	TieringAge time.Duration
+
+	// TieringCacheDir is where a tiered segment's bytes are restored to
+	// when a Read needs them again. Required if TieringAge is set; must
+	// differ from DirPath.
+	TieringCacheDir string
+
+	// TieringCacheSize bounds how many bytes of fetched segments
+	// TieringCacheDir keeps at once; the least recently used one is
+	// evicted -- its local copy removed, requiring a re-fetch next time
+	// it's read -- once a fetch would push the cache past this. 0 (the
+	// default) leaves the cache unbounded.
+	TieringCacheSize int64
+
+	// SealedSegmentCompression selects the codec CompressSegments rewrites
+	// a sealed segment's file to, as a whole, once it's done being the
+	// active segment -- independent of Compression, which (if set at all)
+	// already compresses each chunk's payload individually as Write
+	// appends it. Leaving Compression at CompressionNone and setting this
+	// instead keeps the active segment's writes uncompressed for speed
+	// while still shrinking the long historical tail CompressSegments has
+	// had a chance to reach. It is CompressionNone (disabled) by default;
+	// enabling it requires SealedSegmentCompressionCacheDir to be set.
+	SealedSegmentCompression CompressionCodec
+
+	// SealedSegmentCompressionCacheDir is where a compressed segment's
+	// bytes are decompressed to when a Read needs them again. Required if
+	// SealedSegmentCompression is set; must differ from DirPath.
+	SealedSegmentCompressionCacheDir string
+
+	// SealedSegmentCompressionCacheSize bounds how many bytes of
+	// decompressed segments SealedSegmentCompressionCacheDir keeps at
+	// once, the same way TieringCacheSize bounds TieringCacheDir. 0 (the
+	// default) leaves the cache unbounded.
+	SealedSegmentCompressionCacheSize int64
+
+	// DiskSoftWatermark, when positive, makes rotation call OnDiskWatermark
+	// once the combined size of every segment file (the same total
+	// MaxTotalSize's enforcement already computes) is at or above it, so an
+	// application gets a chance to checkpoint or truncate before disk usage
+	// gets bad enough to hit DiskHardWatermark. It is 0 (disabled) by
+	// default.
+	DiskSoftWatermark int64
+
+	// DiskHardWatermark, when positive, makes Write and WriteAll fail fast
+	// with ErrDiskQuotaExceeded, without touching disk, once the same total
+	// rotation computes for DiskSoftWatermark is at or above it -- instead
+	// of continuing to fill the disk until the OS itself refuses a write
+	// (see DiskFullReserve, OnDiskFull). Like DiskSoftWatermark, this total
+	// is only recomputed at rotation, so a burst of writes to the still-
+	// active segment can push actual usage up to one segment's worth past
+	// this cap before the next rotation notices and starts rejecting
+	// writes -- the same slop MaxTotalSize already tolerates. It is 0
+	// (disabled) by default. See EvictOldestOnFull for an alternative to
+	// this fail-fast policy.
+	DiskHardWatermark int64
+
+	// OnDiskWatermark, when set, is called synchronously by rotation with
+	// the current total on-disk size, once that total is at or above
+	// DiskSoftWatermark. It has no effect if DiskSoftWatermark is 0.
+	OnDiskWatermark func(usedBytes int64)
+
+	// EvictOldestOnFull, when true, makes rotation retire the oldest
+	// sealed segments -- same order and same PinnedSegmentPolicy handling
+	// as MaxTotalSize's enforcement -- instead of setting DiskHardWatermark
+	// fail-fast, once total on-disk size is at or above DiskHardWatermark,
+	// giving ring-buffer semantics: the WAL keeps accepting writes and
+	// just drops its oldest history to stay under the cap, which suits a
+	// metrics/telemetry buffer more than a durability log. If eviction
+	// still can't get back under DiskHardWatermark (every remaining
+	// segment pinned, or the active segment alone exceeds it), Write and
+	// WriteAll fall back to ErrDiskQuotaExceeded same as without this set.
+	// Requires DiskHardWatermark to be set; false (disabled) by default.
+	EvictOldestOnFull bool
+
+	// OnSegmentEvicted, when set, is called once per segment
+	// EvictOldestOnFull retires, with that segment's ID, after it's been
+	// retired. It has no effect if EvictOldestOnFull is false.
+	OnSegmentEvicted func(id SegmentID)
+
+	// FencingToken, when positive, makes Open claim writer status for this
+	// process: it compares FencingToken against the token recorded in
+	// DirPath's fence marker, fails Open if it isn't strictly higher, and
+	// otherwise overwrites the marker with it. A later Open, from another
+	// process pointed at the same DirPath with a higher token still, fences
+	// this WAL out in turn -- see FencingCheckInterval for how this WAL
+	// notices. Write, WriteAll, and WriteIfTail then fail with ErrFenced,
+	// the same way they'd fail with an *ErrPoisoned after a failed fsync,
+	// except reopening with a higher token of your own can't clear it: once
+	// fenced, this WAL is done for good, since a higher token out there
+	// means another process may already be appending to the same segments.
+	// It is 0 (disabled) by default, which skips the claim entirely -- two
+	// processes opening the same DirPath both unfenced can still race.
+	FencingToken uint64
+
+	// FencingCheckInterval, when positive, makes Open start a background
+	// goroutine that re-reads DirPath's fence marker every interval, so a
+	// writer fenced out by a later Open notices and stops accepting writes
+	// without needing a write of its own to find out. It has no effect if
+	// FencingToken is 0. Left at 0 (the default), a fenced-out writer only
+	// ever finds out from a later Open rejecting its own restart, not from
+	// the one still running -- fine for the pod-restart-after-crash case
+	// the request this shipped for cares about, not for detecting an
+	// overlapping double-write while both are still alive.
+	FencingCheckInterval time.Duration
+
+	// AutoCompaction, when set via WithAutoCompaction, starts a background
+	// goroutine that periodically estimates how much of the log is dead
+	// (via AutoCompactionConfig.LiveFilter) and calls Compact itself once
+	// that exceeds AutoCompactionConfig.GarbageRatioThreshold, throttled to
+	// AutoCompactionConfig.ThrottleBytesPerSec and paused while a write is
+	// still recent -- see AutoCompactionConfig -- so a caller gets ongoing
+	// garbage collection without hand-rolling its own cron job around
+	// Compact. It is nil (disabled) by default; incompatible with
+	// Options.ReadOnly, since a read-only WAL never calls Compact.
+	AutoCompaction *AutoCompactionConfig
+}
+
+// AutoCompactionConfig configures the background scheduler
+// Options.AutoCompaction starts. LiveFilter is required; every other field
+// defaults to what DefaultAutoCompactionConfig sets when left zero-valued.
+type AutoCompactionConfig struct {
+	// LiveFilter is passed straight through to Compact as its own
+	// liveFilter argument, once per triggered compaction; see Compact's
+	// doc comment for what it's called with and expected to return. It is
+	// also used, sampled rather than exhaustively, to estimate the
+	// garbage ratio GarbageRatioThreshold is compared against. Required.
+	LiveFilter func(pos *ChunkPosition, data []byte) bool
+
+	// CheckInterval is how often the background goroutine samples the
+	// garbage ratio to decide whether to compact. Zero uses
+	// DefaultAutoCompactionConfig.CheckInterval.
+	CheckInterval time.Duration
+
+	// GarbageRatioThreshold is the fraction, in [0, 1], of sampled bytes
+	// LiveFilter must report dead before a compaction is triggered. Zero
+	// uses DefaultAutoCompactionConfig.GarbageRatioThreshold.
+	GarbageRatioThreshold float64
+
+	// ThrottleBytesPerSec, when positive, caps how fast a triggered
+	// compaction relocates live chunks, so it competes less aggressively
+	// with foreground writers for disk bandwidth than a plain Compact
+	// call would. Zero (the default) throttles a triggered compaction the
+	// same as an explicit Compact call: not at all.
+	ThrottleBytesPerSec int64
+
+	// WritePressureWindow, when positive, makes the background goroutine
+	// pause a triggered compaction -- between chunks, never mid-chunk --
+	// while a write has completed within this window, so a compaction
+	// never runs Compact's disk I/O against a WAL that's currently busy
+	// serving foreground writes. Zero uses
+	// DefaultAutoCompactionConfig.WritePressureWindow.
+	WritePressureWindow time.Duration
+}
+
+// DefaultAutoCompactionConfig supplies WithAutoCompaction's zero-valued
+// fields, the same way DefaultOptions does for Options.
+var DefaultAutoCompactionConfig = AutoCompactionConfig{
+	CheckInterval:         time.Minute,
+	GarbageRatioThreshold: 0.5,
+	WritePressureWindow:   2 * time.Second,
 }
 
 const (
@@ -41,11 +998,16 @@ const (
 )
 
 var DefaultOptions = Options{
-	DirPath:        os.TempDir(),
-	SegmentSize:    GB,
-	SegmentFileExt: ".SEG",
-	Sync:           false,
-	BytesPerSync:   0,
+	DirPath:          os.TempDir(),
+	SegmentSize:      GB,
+	SegmentFileExt:   ".SEG",
+	StartSegmentID:   1,
+	Sync:             false,
+	BytesPerSync:     0,
+	FS:               osFS{},
+	MetricsCollector: nopCollector{},
+	Logger:           nopLogger{},
+	Clock:            realClock{},
 }
 
 // WithDirPath sets the directory path where the WAL segment files will be stored.
@@ -69,6 +1031,24 @@ func WithSegmentFileExt(ext string) Option {
 	}
 }
 
+// WithSegmentFileNaming sets nameFunc and parseFunc, overriding the
+// built-in zero-padded numeric scheme segment files are named and parsed
+// with. See Options.SegmentFileNameFunc and Options.SegmentFileNameParseFunc.
+func WithSegmentFileNaming(nameFunc func(id SegmentID) string, parseFunc func(name string) (SegmentID, bool)) Option {
+	return func(o *Options) {
+		o.SegmentFileNameFunc = nameFunc
+		o.SegmentFileNameParseFunc = parseFunc
+	}
+}
+
+// WithStartSegmentID sets the SegmentID a brand new WAL's first segment is
+// numbered with. See Options.StartSegmentID.
+func WithStartSegmentID(id SegmentID) Option {
+	return func(o *Options) {
+		o.StartSegmentID = id
+	}
+}
+
 // WithSync sets the whether to synchronize writes through os buffer cache and down onto the actual disk.
 func WithSync(sync bool) Option {
 	return func(o *Options) {
@@ -82,3 +1062,913 @@ func WithBytesPerSync(bytesPerSync uint32) Option {
 		o.BytesPerSync = bytesPerSync
 	}
 }
+
+// WithFdatasync sets Options.Fdatasync.
+func WithFdatasync(fdatasync bool) Option {
+	return func(o *Options) {
+		o.Fdatasync = fdatasync
+	}
+}
+
+// WithChecksum sets Options.Checksum.
+func WithChecksum(checksum Checksum) Option {
+	return func(o *Options) {
+		o.Checksum = checksum
+	}
+}
+
+// WithVerifyOnRead sets whether Read, ReadInto, and Reader.Next verify each
+// chunk's checksum as they read it back. It defaults to true; pass false to
+// skip that check on the read path when whatever consumes the WAL's
+// records already validates their content some other way. See
+// Options.SkipReadVerification, the field this negates. Repair and the
+// package-level Verify always verify regardless of this setting.
+func WithVerifyOnRead(verify bool) Option {
+	return func(o *Options) {
+		o.SkipReadVerification = !verify
+	}
+}
+
+// WithVerifyOnOpen sets Options.VerifyOnOpen: whether Open runs a full,
+// VerifyAll-style scan of every segment before doing anything else,
+// rather than just checking the active segment's tail the way
+// RecoverStrict normally does.
+func WithVerifyOnOpen(verify bool) Option {
+	return func(o *Options) {
+		o.VerifyOnOpen = verify
+	}
+}
+
+// WithChainChecksums sets Options.ChainChecksums: whether each chunk's
+// checksum folds in the one before it, so a deleted or reordered chunk
+// breaks the chain even when its own bytes look untouched. It defaults to
+// false.
+func WithChainChecksums(chain bool) Option {
+	return func(o *Options) {
+		o.ChainChecksums = chain
+	}
+}
+
+// WithSyncFileRange sets Options.SyncFileRange.
+func WithSyncFileRange(syncFileRange bool) Option {
+	return func(o *Options) {
+		o.SyncFileRange = syncFileRange
+	}
+}
+
+// WithMode sets the durability mode the WAL is opened with. Most callers
+// should use Open (ModeAhead) or OpenWBL (ModeBehind) instead of setting
+// this directly.
+func WithMode(mode Mode) Option {
+	return func(o *Options) {
+		o.Mode = mode
+	}
+}
+
+// WithFlushInterval sets how often a write-behind log (opened with
+// OpenWBL) syncs its active segment in the background.
+func WithFlushInterval(d time.Duration) Option {
+	return func(o *Options) {
+		o.FlushInterval = d
+	}
+}
+
+// WithSyncInterval sets how often a write-ahead log syncs its active
+// segment in the background. See Options.SyncInterval.
+func WithSyncInterval(d time.Duration) Option {
+	return func(o *Options) {
+		o.SyncInterval = d
+	}
+}
+
+// WithPipelined enables Pipelined mode: Write queues instead of writing
+// inline, and Open starts a background goroutine to flush the queue in
+// batches. See Options.Pipelined.
+func WithPipelined(pipelined bool) Option {
+	return func(o *Options) {
+		o.Pipelined = pipelined
+	}
+}
+
+// WithMaxPendingBytes sets how much data Write can queue under Pipelined
+// before it starts blocking for the background flusher to catch up. Pass
+// 0 (the default) to disable the cap. See Options.MaxPendingBytes.
+func WithMaxPendingBytes(n int64) Option {
+	return func(o *Options) {
+		o.MaxPendingBytes = n
+	}
+}
+
+// WithPipelineFlushInterval sets how often the background goroutine
+// Pipelined mode starts drains the queue. See Options.PipelineFlushInterval.
+func WithPipelineFlushInterval(d time.Duration) Option {
+	return func(o *Options) {
+		o.PipelineFlushInterval = d
+	}
+}
+
+// WithGroupCommit enables batching fsyncs across concurrent synchronous
+// writers. See Options.GroupCommit.
+func WithGroupCommit(groupCommit bool) Option {
+	return func(o *Options) {
+		o.GroupCommit = groupCommit
+	}
+}
+
+// WithWriteLinger makes a synchronous Write wait up to d to batch its
+// append and fsync with other Write calls that join the same window,
+// instead of doing both immediately. Pass 0 (the default) to disable it.
+// See Options.WriteLinger.
+func WithWriteLinger(d time.Duration) Option {
+	return func(o *Options) {
+		o.WriteLinger = d
+	}
+}
+
+// WithSegmentPreallocate sets how many next-segment files the background
+// file pipeline keeps pre-created, pre-allocated, and locked ahead of the
+// active segment, so that segment rotation only has to rename a file
+// instead of creating and allocating one on the hot path. Pass 0 (the
+// default) to disable the pipeline and create segment files on demand.
+func WithSegmentPreallocate(count int) Option {
+	return func(o *Options) {
+		o.SegmentPreallocate = count
+	}
+}
+
+// WithPreallocate sets whether a freshly created segment file fallocates
+// its full SegmentSize up front rather than growing on every append. See
+// Options.Preallocate.
+func WithPreallocate(preallocate bool) Option {
+	return func(o *Options) {
+		o.Preallocate = preallocate
+	}
+}
+
+// WithRecycleSegments sets whether a retired sealed segment's file is
+// handed back to the background file pipeline for reuse instead of being
+// deleted. See Options.RecycleSegments; it has no effect unless
+// WithSegmentPreallocate is also set to a positive count.
+func WithRecycleSegments(recycle bool) Option {
+	return func(o *Options) {
+		o.RecycleSegments = recycle
+	}
+}
+
+// WithRepairOnOpen sets whether Open should attempt an automatic Repair
+// when the last segment fails to load because of a torn tail write.
+func WithRepairOnOpen(repair bool) Option {
+	return func(o *Options) {
+		o.RepairOnOpen = repair
+	}
+}
+
+// WithRecoveryMode sets how Open reacts to corruption found while loading
+// the last segment; see RecoverStrict, RecoverTail, and RecoverSkipCorrupt.
+func WithRecoveryMode(mode RecoveryMode) Option {
+	return func(o *Options) {
+		o.RecoveryMode = mode
+	}
+}
+
+// WithCompression sets the codec Write and WriteAll compress chunk payloads
+// with before they hit disk. The WAL must be reopened with the same codec
+// it was written with.
+func WithCompression(codec CompressionCodec) Option {
+	return func(o *Options) {
+		o.Compression = codec
+	}
+}
+
+// WithCompressionDict sets the zstd dictionary Write and WriteAll prime the
+// compressor with; see Options.CompressionDict. It only takes effect
+// alongside WithCompression(CompressionZstd); Open returns
+// ErrInvalidCompressionDict if dict isn't in the format zstd.BuildDict (or
+// "zstd --train") produces.
+func WithCompressionDict(dict []byte) Option {
+	return func(o *Options) {
+		o.CompressionDict = dict
+	}
+}
+
+// WithTimestamps makes Write, WriteAll, and Batch record the time each
+// chunk was written, retrievable with ReadWithTimestamp or
+// Reader.NextWithTimestamp. The WAL must be reopened with the same value it
+// was written with.
+func WithTimestamps(enabled bool) Option {
+	return func(o *Options) {
+		o.Timestamps = enabled
+	}
+}
+
+// WithSegmentRotationInterval sets how long the active segment may stay
+// active before a background goroutine force-rotates it regardless of
+// Options.SegmentSize. Pass 0 (the default) to disable time-based
+// rotation.
+func WithSegmentRotationInterval(d time.Duration) Option {
+	return func(o *Options) {
+		o.SegmentRotationInterval = d
+	}
+}
+
+// WithMaxSegmentAge sets how old a sealed segment may get, measured from
+// when it became the active segment, before rotation removes it
+// automatically. Pass 0 (the default) to disable age-based retention.
+func WithMaxSegmentAge(d time.Duration) Option {
+	return func(o *Options) {
+		o.MaxSegmentAge = d
+	}
+}
+
+// WithMaxTotalSize sets the cap on the combined size of every segment file,
+// above which rotation removes the oldest sealed segments automatically.
+// Pass 0 (the default) to disable size-based retention.
+func WithMaxTotalSize(bytes int64) Option {
+	return func(o *Options) {
+		o.MaxTotalSize = bytes
+	}
+}
+
+// WithDropExpiredSegments enables or disables removing a sealed segment
+// automatically once every chunk WriteWithTTL wrote into it has expired.
+// Pass false (the default) to disable it. See Options.DropExpiredSegments.
+func WithDropExpiredSegments(drop bool) Option {
+	return func(o *Options) {
+		o.DropExpiredSegments = drop
+	}
+}
+
+// WithPinnedSegmentPolicy sets what a retention pass does when the
+// segment it wants to remove is pinned. See Options.PinnedSegmentPolicy.
+func WithPinnedSegmentPolicy(policy PinnedSegmentPolicy) Option {
+	return func(o *Options) {
+		o.PinnedSegmentPolicy = policy
+	}
+}
+
+// WithRateLimit installs the default token-bucket Limiter, capping writes
+// at bytesPerSec with a burst of up to one second's worth of that rate.
+// Pass 0 (the default) to disable throttling. For anything other than a
+// fixed byte-per-second cap -- a quota shared across several WALs, say --
+// use WithLimiter instead.
+func WithRateLimit(bytesPerSec int64) Option {
+	return func(o *Options) {
+		if bytesPerSec <= 0 {
+			o.Limiter = nil
+			return
+		}
+		o.Limiter = newTokenBucketLimiter(bytesPerSec)
+	}
+}
+
+// WithLimiter sets a custom Limiter the write path throttles against, in
+// place of the token bucket WithRateLimit installs. Pass nil to disable
+// throttling. See Options.Limiter.
+func WithLimiter(limiter Limiter) Option {
+	return func(o *Options) {
+		o.Limiter = limiter
+	}
+}
+
+// WithMaxRecordSize sets the largest record Write, WriteWithFlags,
+// WriteWithTTL, and PendingWrites will accept, in bytes, measured before
+// compression or encryption -- so for WriteWithFlags and WriteWithTTL,
+// after their flags byte or expiry prefix has been added, the same as
+// what ends up framed as the chunk's payload. Pass 0 (the default) to
+// disable the check. See Options.MaxRecordSize.
+func WithMaxRecordSize(n int64) Option {
+	return func(o *Options) {
+		o.MaxRecordSize = n
+	}
+}
+
+// WithMaxPendingSize sets the largest combined size PendingWrites will
+// let the not-yet-flushed batch grow to. Pass 0 (the default) to disable
+// the check. See Options.MaxPendingSize.
+func WithMaxPendingSize(n int64) Option {
+	return func(o *Options) {
+		o.MaxPendingSize = n
+	}
+}
+
+// WithFS sets the filesystem segment files and segment listing are read
+// and written through, in place of the default (the real OS filesystem).
+// See FS's doc comment for which parts of the WAL aren't routed through it
+// yet. Pass nil to restore the default.
+func WithFS(fs FS) Option {
+	return func(o *Options) {
+		if fs == nil {
+			fs = osFS{}
+		}
+		o.FS = fs
+	}
+}
+
+// WithOnSegmentSealed sets a hook called synchronously, with a sealed
+// segment's file path and ID, right after rotation seals it. Use it to
+// archive closed segments (e.g. to S3) before deleting them locally.
+func WithOnSegmentSealed(fn func(path string, id SegmentID)) Option {
+	return func(o *Options) {
+		o.OnSegmentSealed = fn
+	}
+}
+
+// WithEncryption sets the AES-256 key (must be 32 bytes) Write and WriteAll
+// encrypt chunk payloads with before they hit disk. The WAL must be
+// reopened with the same key it was written with; Open returns
+// ErrInvalidEncryptionKey if key is not 32 bytes.
+func WithEncryption(key []byte) Option {
+	return func(o *Options) {
+		o.EncryptionKey = key
+	}
+}
+
+// WithKeyring sets the keyring new chunks are encrypted under, indexed by
+// caller-assigned key ID -- the highest ID present is the one new writes
+// use. See Options.Keyring; Open returns ErrInvalidEncryptionKey if any
+// key in keyring is not 32 bytes.
+func WithKeyring(keyring map[uint32][]byte) Option {
+	return func(o *Options) {
+		o.Keyring = keyring
+	}
+}
+
+// WithKeyProvider sets the KeyProvider new chunks are encrypted under and
+// old ones decrypted with. See Options.KeyProvider.
+func WithKeyProvider(provider KeyProvider) Option {
+	return func(o *Options) {
+		o.KeyProvider = provider
+	}
+}
+
+// WithOnSyncError sets a hook called synchronously the first time an
+// fsync fails. See Options.OnSyncError.
+func WithOnSyncError(fn func(err error)) Option {
+	return func(o *Options) {
+		o.OnSyncError = fn
+	}
+}
+
+// WithDiskFullReserve makes Open preallocate a headroom file of bytes
+// bytes in DirPath, released the first time a write or sync reports the
+// disk full. See Options.DiskFullReserve.
+func WithDiskFullReserve(bytes int64) Option {
+	return func(o *Options) {
+		o.DiskFullReserve = bytes
+	}
+}
+
+// WithOnDiskFull sets a hook called synchronously the first time a write
+// or sync reports the disk full. See Options.OnDiskFull.
+func WithOnDiskFull(fn func(err error)) Option {
+	return func(o *Options) {
+		o.OnDiskFull = fn
+	}
+}
+
+// WithOnRotate sets a hook called synchronously with the sealed and new
+// active segment's IDs right after segment rotation. See
+// Options.OnRotate.
+func WithOnRotate(fn func(oldID, newID SegmentID)) Option {
+	return func(o *Options) {
+		o.OnRotate = fn
+	}
+}
+
+// WithSlowSyncThreshold sets Options.SlowSyncThreshold, the fsync duration
+// at or above which OnSlowSync fires. Pass 0 (the default) to disable it.
+func WithSlowSyncThreshold(d time.Duration) Option {
+	return func(o *Options) {
+		o.SlowSyncThreshold = d
+	}
+}
+
+// WithOnSlowSync sets a hook called synchronously right after an fsync
+// that took at least Options.SlowSyncThreshold. See Options.OnSlowSync.
+func WithOnSlowSync(fn func(d time.Duration)) Option {
+	return func(o *Options) {
+		o.OnSlowSync = fn
+	}
+}
+
+// WithOnDurable sets a hook called synchronously every time the fsync
+// frontier advances, with the new durable position. See Options.OnDurable.
+func WithOnDurable(fn func(pos *ChunkPosition)) Option {
+	return func(o *Options) {
+		o.OnDurable = fn
+	}
+}
+
+// WithHooks sets the callbacks bracketing Write, WriteAll, Read, and Sync.
+// See Hooks's doc comment.
+func WithHooks(hooks Hooks) Option {
+	return func(o *Options) {
+		o.Hooks = hooks
+	}
+}
+
+// WithReadOnly sets whether Open should open the WAL for reading only,
+// without taking write ownership of DirPath. See Options.ReadOnly.
+func WithReadOnly(readOnly bool) Option {
+	return func(o *Options) {
+		o.ReadOnly = readOnly
+	}
+}
+
+// WithMmapRead sets Options.MmapRead.
+func WithMmapRead(mmapRead bool) Option {
+	return func(o *Options) {
+		o.MmapRead = mmapRead
+	}
+}
+
+// WithMadvise sets Options.Madvise.
+func WithMadvise(advice Madvise) Option {
+	return func(o *Options) {
+		o.Madvise = advice
+	}
+}
+
+// WithFadviseDontNeed sets Options.FadviseDontNeed.
+func WithFadviseDontNeed(fadviseDontNeed bool) Option {
+	return func(o *Options) {
+		o.FadviseDontNeed = fadviseDontNeed
+	}
+}
+
+// WithDirectIO sets Options.DirectIO.
+func WithDirectIO(directIO bool) Option {
+	return func(o *Options) {
+		o.DirectIO = directIO
+	}
+}
+
+// WithIOUring sets Options.IOUring. See its doc comment: this is a
+// forward-compatibility placeholder today, not a functional write path.
+func WithIOUring(ioUring bool) Option {
+	return func(o *Options) {
+		o.IOUring = ioUring
+	}
+}
+
+// WithBlockSize sets Options.BlockSize. It is currently a no-op: see the
+// field's doc comment for why this package has nothing resembling a fixed
+// block size to configure.
+func WithBlockSize(n int) Option {
+	return func(o *Options) {
+		o.BlockSize = n
+	}
+}
+
+// WithBlockCache sets Options.BlockCache, the byte budget for the
+// in-memory cache Read and ReadWithTimestamp consult before the segment
+// file. Pass 0 (the default) to disable it.
+func WithBlockCache(bytes int) Option {
+	return func(o *Options) {
+		o.BlockCache = bytes
+	}
+}
+
+// WithOpenParallelism sets Options.OpenParallelism, the number of
+// already-sealed segments Open scans concurrently during recovery. n < 1
+// (including the 0 default) opens them one at a time.
+func WithOpenParallelism(n int) Option {
+	return func(o *Options) {
+		o.OpenParallelism = n
+	}
+}
+
+// WithCompressionParallelism sets Options.CompressionParallelism.
+func WithCompressionParallelism(n int) Option {
+	return func(o *Options) {
+		o.CompressionParallelism = n
+	}
+}
+
+// WithChunkIndex sets Options.ChunkIndex: whether every segment writes a
+// dense chunk-index sidecar when sealed, enabling WAL.ReadNth's O(1) path.
+func WithChunkIndex(enable bool) Option {
+	return func(o *Options) {
+		o.ChunkIndex = enable
+	}
+}
+
+// WithSegmentSigning sets the Ed25519 private key seal signs each sealed
+// segment's digest with; Open returns ErrInvalidSigningKey if key is not
+// ed25519.PrivateKeySize bytes. See Options.SegmentSigningKey.
+func WithSegmentSigning(key ed25519.PrivateKey) Option {
+	return func(o *Options) {
+		o.SegmentSigningKey = key
+	}
+}
+
+// WithSegmentVerification sets the Ed25519 public key Open checks every
+// sealed segment's signature against before returning; Open returns
+// ErrInvalidSigningKey if pub is not ed25519.PublicKeySize bytes. See
+// Options.SegmentVerifyKey.
+func WithSegmentVerification(pub ed25519.PublicKey) Option {
+	return func(o *Options) {
+		o.SegmentVerifyKey = pub
+	}
+}
+
+// WithIdempotencyWindow sets Options.IdempotencyWindow, how long
+// WriteIdempotent remembers an id for before treating a repeat of it as a
+// new write. Pass 0 (the default) to disable WriteIdempotent entirely.
+func WithIdempotencyWindow(d time.Duration) Option {
+	return func(o *Options) {
+		o.IdempotencyWindow = d
+	}
+}
+
+// WithTokenSealing sets Options.TokenSealKey, the HMAC-SHA256 key
+// SealPosition and UnsealPosition seal and verify opaque position tokens
+// with. Pass nil (the default) to disable both methods.
+func WithTokenSealing(key []byte) Option {
+	return func(o *Options) {
+		o.TokenSealKey = key
+	}
+}
+
+// WithMirrorDir sets Options.MirrorDir, a second directory Open mirrors
+// every write to. Pass "" (the default) to disable mirroring.
+func WithMirrorDir(dir string) Option {
+	return func(o *Options) {
+		o.MirrorDir = dir
+	}
+}
+
+// WithMirrorAsync sets Options.MirrorAsync and Options.MirrorLagBound
+// together, switching Options.MirrorDir from a synchronous mirror to an
+// asynchronous one bounded by lagBound queued writes. Pass 0 for lagBound
+// to use defaultMirrorLagBound. It has no effect unless Options.MirrorDir
+// is also set.
+func WithMirrorAsync(lagBound int) Option {
+	return func(o *Options) {
+		o.MirrorAsync = true
+		o.MirrorLagBound = lagBound
+	}
+}
+
+// WithTiering sets Options.ObjectStore, Options.TieringAge,
+// Options.TieringCacheDir, and Options.TieringCacheSize together, the
+// options TierSegments and a Read against a tiered segment need. Pass 0
+// for age (the default, leaving tiering disabled) to skip it.
+func WithTiering(store ObjectStore, age time.Duration, cacheDir string, cacheSize int64) Option {
+	return func(o *Options) {
+		o.ObjectStore = store
+		o.TieringAge = age
+		o.TieringCacheDir = cacheDir
+		o.TieringCacheSize = cacheSize
+	}
+}
+
+// WithSealedSegmentCompression sets Options.SealedSegmentCompression,
+// Options.SealedSegmentCompressionCacheDir, and
+// Options.SealedSegmentCompressionCacheSize together, the options
+// CompressSegments and a Read against a compressed segment need. Pass
+// CompressionNone for codec (the default, leaving it disabled) to skip it.
+func WithSealedSegmentCompression(codec CompressionCodec, cacheDir string, cacheSize int64) Option {
+	return func(o *Options) {
+		o.SealedSegmentCompression = codec
+		o.SealedSegmentCompressionCacheDir = cacheDir
+		o.SealedSegmentCompressionCacheSize = cacheSize
+	}
+}
+
+// WithAutoCompaction sets Options.AutoCompaction, starting a background
+// scheduler that calls Compact itself once cfg's estimated garbage ratio
+// crosses cfg.GarbageRatioThreshold. cfg.LiveFilter is required; every
+// other field defaults to DefaultAutoCompactionConfig's value when left
+// zero-valued. See AutoCompactionConfig.
+func WithAutoCompaction(cfg AutoCompactionConfig) Option {
+	return func(o *Options) {
+		o.AutoCompaction = &cfg
+	}
+}
+
+// WithDiskWatermarks sets Options.DiskSoftWatermark and
+// Options.DiskHardWatermark: crossing softBytes of total on-disk usage
+// calls OnDiskWatermark (see WithOnDiskWatermark), and crossing hardBytes
+// makes Write and WriteAll fail fast with ErrDiskQuotaExceeded. Pass 0 for
+// either to disable it.
+func WithDiskWatermarks(softBytes, hardBytes int64) Option {
+	return func(o *Options) {
+		o.DiskSoftWatermark = softBytes
+		o.DiskHardWatermark = hardBytes
+	}
+}
+
+// WithOnDiskWatermark sets a hook called synchronously by rotation, with
+// the current total on-disk size, once it's at or above
+// Options.DiskSoftWatermark. See Options.OnDiskWatermark.
+func WithOnDiskWatermark(fn func(usedBytes int64)) Option {
+	return func(o *Options) {
+		o.OnDiskWatermark = fn
+	}
+}
+
+// WithEvictOldestOnFull sets Options.EvictOldestOnFull: pass true to have
+// rotation retire the oldest sealed segments instead of failing writes
+// once Options.DiskHardWatermark is reached. See Options.EvictOldestOnFull.
+func WithEvictOldestOnFull(enabled bool) Option {
+	return func(o *Options) {
+		o.EvictOldestOnFull = enabled
+	}
+}
+
+// WithOnSegmentEvicted sets a hook called once per segment
+// Options.EvictOldestOnFull retires, with that segment's ID. See
+// Options.OnSegmentEvicted.
+func WithOnSegmentEvicted(fn func(id SegmentID)) Option {
+	return func(o *Options) {
+		o.OnSegmentEvicted = fn
+	}
+}
+
+// WithFencingToken sets Options.FencingToken, claiming writer status for
+// this Open against DirPath's fence marker. See Options.FencingToken.
+func WithFencingToken(token uint64) Option {
+	return func(o *Options) {
+		o.FencingToken = token
+	}
+}
+
+// WithFencingCheckInterval sets how often a background goroutine re-reads
+// DirPath's fence marker to notice this WAL has been fenced out. See
+// Options.FencingCheckInterval.
+func WithFencingCheckInterval(d time.Duration) Option {
+	return func(o *Options) {
+		o.FencingCheckInterval = d
+	}
+}
+
+// WithBufferPool sets a custom BufferPool that Write and WriteAll borrow
+// compression scratch buffers from instead of allocating fresh ones. Pass
+// nil (the default) to use Open's own sync.Pool-backed implementation when
+// WriteBufferSize is positive, or to disable pooling entirely when it
+// isn't. See Options.BufferPool.
+func WithBufferPool(pool BufferPool) Option {
+	return func(o *Options) {
+		o.BufferPool = pool
+	}
+}
+
+// WithWriteBufferSize sets the capacity the default BufferPool's buffers
+// start at, and enables that default pool if no custom BufferPool has been
+// set. Pass 0 (the default) to disable pooling. See Options.WriteBufferSize.
+func WithWriteBufferSize(n int) Option {
+	return func(o *Options) {
+		o.WriteBufferSize = n
+	}
+}
+
+// WithSegmentWriteBufferSize sets how many bytes Write and WriteBatch
+// accumulate in memory before flushing them to the active segment's file in
+// one syscall. Pass 0 (the default) to write every call through
+// immediately, the same as before this option existed. See
+// Options.SegmentWriteBufferSize.
+func WithSegmentWriteBufferSize(n int) Option {
+	return func(o *Options) {
+		o.SegmentWriteBufferSize = n
+	}
+}
+
+// WithMetricsCollector sets a custom Collector that the WAL reports
+// instrumentation events to. Pass nil to disable metrics collection.
+//
+// Use this if you want to wire the WAL into a metrics system other than
+// Prometheus; for Prometheus, WithMetricsRegisterer is more convenient.
+func WithMetricsCollector(collector Collector) Option {
+	return func(o *Options) {
+		if collector == nil {
+			collector = nopCollector{}
+		}
+		o.MetricsCollector = collector
+	}
+}
+
+// WithLogger sets a custom Logger the WAL reports background events
+// (segment rotation, retention deletions, recovery truncation, fsync
+// failure) to. Pass nil to disable logging.
+//
+// l's method set only needs to match Logger's, so a *log/slog.Logger can
+// be passed directly.
+func WithLogger(l Logger) Option {
+	return func(o *Options) {
+		if l == nil {
+			l = nopLogger{}
+		}
+		o.Logger = l
+	}
+}
+
+// WithClock sets a custom Clock the WAL reads the time from and requests
+// tickers from, in place of the real wall clock. Pass a *FakeClock in
+// tests to drive the sync-interval flusher and age-based retention with
+// Tick instead of sleeping. Pass nil to restore the real wall clock.
+func WithClock(c Clock) Option {
+	return func(o *Options) {
+		if c == nil {
+			c = realClock{}
+		}
+		o.Clock = c
+	}
+}
+
+// WithMetricsRegisterer registers the WAL's built-in set of Prometheus
+// metrics (fsync duration, bytes written, chunks written, chunk read
+// latency, active segment count, current segment size, segment rotations,
+// and pending-writes queue depth) with reg, and reports to them as the WAL
+// is used.
+func WithMetricsRegisterer(reg prometheus.Registerer) Option {
+	return func(o *Options) {
+		o.MetricsCollector = newPrometheusCollector(reg)
+	}
+}
+
+// applyDefaults backfills any zero-valued field with DefaultOptions'
+// value for it, the same defaults a functional Option would layer on top
+// of when building Options for Open.
+func (options *Options) applyDefaults() {
+	if options.DirPath == "" {
+		options.DirPath = DefaultOptions.DirPath
+	}
+	if options.SegmentSize == 0 {
+		options.SegmentSize = DefaultOptions.SegmentSize
+	}
+	if options.SegmentFileExt == "" {
+		options.SegmentFileExt = DefaultOptions.SegmentFileExt
+	}
+	if options.StartSegmentID == 0 {
+		options.StartSegmentID = DefaultOptions.StartSegmentID
+	}
+	if options.FS == nil {
+		options.FS = DefaultOptions.FS
+	}
+	if options.MetricsCollector == nil {
+		options.MetricsCollector = DefaultOptions.MetricsCollector
+	}
+	if options.Logger == nil {
+		options.Logger = DefaultOptions.Logger
+	}
+	if options.Clock == nil {
+		options.Clock = DefaultOptions.Clock
+	}
+}
+
+// validate checks options for mistakes that would otherwise only surface
+// much later as confusing failures at rotation or read time, and names the
+// offending field in its error.
+func (options *Options) validate() error {
+	if options.SegmentSize <= 0 {
+		return fmt.Errorf("wal: invalid Options.SegmentSize %d: must be positive", options.SegmentSize)
+	}
+	if options.BlockSize < 0 {
+		return fmt.Errorf("wal: invalid Options.BlockSize %d: must not be negative", options.BlockSize)
+	}
+	if options.BlockSize > 0 && int64(options.BlockSize) > options.SegmentSize {
+		return fmt.Errorf("wal: invalid Options.BlockSize %d: larger than Options.SegmentSize %d", options.BlockSize, options.SegmentSize)
+	}
+	if options.BlockCache < 0 {
+		return fmt.Errorf("wal: invalid Options.BlockCache %d: must not be negative", options.BlockCache)
+	}
+	if !strings.HasPrefix(options.SegmentFileExt, ".") {
+		return fmt.Errorf("wal: invalid Options.SegmentFileExt %q: must start with '.'", options.SegmentFileExt)
+	}
+	if (options.SegmentFileNameFunc == nil) != (options.SegmentFileNameParseFunc == nil) {
+		return fmt.Errorf("wal: Options.SegmentFileNameFunc and Options.SegmentFileNameParseFunc must be set together")
+	}
+	if options.BytesPerSync > 0 && int64(options.BytesPerSync) > options.SegmentSize {
+		return fmt.Errorf("wal: invalid Options.BytesPerSync %d: larger than Options.SegmentSize %d", options.BytesPerSync, options.SegmentSize)
+	}
+	if options.DiskFullReserve < 0 {
+		return fmt.Errorf("wal: invalid Options.DiskFullReserve %d: must not be negative", options.DiskFullReserve)
+	}
+	if options.SlowSyncThreshold < 0 {
+		return fmt.Errorf("wal: invalid Options.SlowSyncThreshold %s: must not be negative", options.SlowSyncThreshold)
+	}
+	if options.IdempotencyWindow < 0 {
+		return fmt.Errorf("wal: invalid Options.IdempotencyWindow %s: must not be negative", options.IdempotencyWindow)
+	}
+	if options.MirrorLagBound < 0 {
+		return fmt.Errorf("wal: invalid Options.MirrorLagBound %d: must not be negative", options.MirrorLagBound)
+	}
+	if options.WriteLinger < 0 {
+		return fmt.Errorf("wal: invalid Options.WriteLinger %s: must not be negative", options.WriteLinger)
+	}
+	if options.MirrorDir != "" && options.ReadOnly {
+		return fmt.Errorf("wal: Options.MirrorDir is incompatible with Options.ReadOnly")
+	}
+	if options.MirrorDir == "" && options.MirrorAsync {
+		return fmt.Errorf("wal: Options.MirrorAsync requires Options.MirrorDir to be set")
+	}
+	if options.MirrorDir != "" && options.MirrorDir == options.DirPath {
+		return fmt.Errorf("wal: Options.MirrorDir must differ from Options.DirPath")
+	}
+	if options.TieringAge < 0 {
+		return fmt.Errorf("wal: invalid Options.TieringAge %s: must not be negative", options.TieringAge)
+	}
+	if options.TieringAge > 0 && options.ObjectStore == nil {
+		return fmt.Errorf("wal: Options.TieringAge requires Options.ObjectStore to be set")
+	}
+	if options.TieringAge > 0 && options.TieringCacheDir == "" {
+		return fmt.Errorf("wal: Options.TieringAge requires Options.TieringCacheDir to be set")
+	}
+	if options.TieringCacheDir != "" && options.TieringCacheDir == options.DirPath {
+		return fmt.Errorf("wal: Options.TieringCacheDir must differ from Options.DirPath")
+	}
+	if options.TieringCacheSize < 0 {
+		return fmt.Errorf("wal: invalid Options.TieringCacheSize %d: must not be negative", options.TieringCacheSize)
+	}
+	if options.SealedSegmentCompression != CompressionNone && options.SealedSegmentCompressionCacheDir == "" {
+		return fmt.Errorf("wal: Options.SealedSegmentCompression requires Options.SealedSegmentCompressionCacheDir to be set")
+	}
+	if options.SealedSegmentCompressionCacheDir != "" && options.SealedSegmentCompressionCacheDir == options.DirPath {
+		return fmt.Errorf("wal: Options.SealedSegmentCompressionCacheDir must differ from Options.DirPath")
+	}
+	if options.SealedSegmentCompressionCacheSize < 0 {
+		return fmt.Errorf("wal: invalid Options.SealedSegmentCompressionCacheSize %d: must not be negative", options.SealedSegmentCompressionCacheSize)
+	}
+	if options.DiskSoftWatermark < 0 {
+		return fmt.Errorf("wal: invalid Options.DiskSoftWatermark %d: must not be negative", options.DiskSoftWatermark)
+	}
+	if options.DiskHardWatermark < 0 {
+		return fmt.Errorf("wal: invalid Options.DiskHardWatermark %d: must not be negative", options.DiskHardWatermark)
+	}
+	if options.DiskSoftWatermark > 0 && options.DiskHardWatermark > 0 && options.DiskHardWatermark < options.DiskSoftWatermark {
+		return fmt.Errorf("wal: invalid Options.DiskHardWatermark %d: must not be smaller than Options.DiskSoftWatermark %d", options.DiskHardWatermark, options.DiskSoftWatermark)
+	}
+	if options.EvictOldestOnFull && options.DiskHardWatermark <= 0 {
+		return fmt.Errorf("wal: Options.EvictOldestOnFull requires Options.DiskHardWatermark to be set")
+	}
+	if options.FencingCheckInterval < 0 {
+		return fmt.Errorf("wal: invalid Options.FencingCheckInterval %s: must not be negative", options.FencingCheckInterval)
+	}
+	if options.AutoCompaction != nil {
+		if options.AutoCompaction.LiveFilter == nil {
+			return fmt.Errorf("wal: Options.AutoCompaction requires AutoCompactionConfig.LiveFilter to be set")
+		}
+		if options.AutoCompaction.CheckInterval < 0 {
+			return fmt.Errorf("wal: invalid AutoCompactionConfig.CheckInterval %s: must not be negative", options.AutoCompaction.CheckInterval)
+		}
+		if options.AutoCompaction.GarbageRatioThreshold < 0 || options.AutoCompaction.GarbageRatioThreshold > 1 {
+			return fmt.Errorf("wal: invalid AutoCompactionConfig.GarbageRatioThreshold %f: must be in [0, 1]", options.AutoCompaction.GarbageRatioThreshold)
+		}
+		if options.AutoCompaction.ThrottleBytesPerSec < 0 {
+			return fmt.Errorf("wal: invalid AutoCompactionConfig.ThrottleBytesPerSec %d: must not be negative", options.AutoCompaction.ThrottleBytesPerSec)
+		}
+		if options.AutoCompaction.WritePressureWindow < 0 {
+			return fmt.Errorf("wal: invalid AutoCompactionConfig.WritePressureWindow %s: must not be negative", options.AutoCompaction.WritePressureWindow)
+		}
+		if options.ReadOnly {
+			return fmt.Errorf("wal: Options.AutoCompaction is incompatible with Options.ReadOnly")
+		}
+	}
+	keySources := 0
+	if len(options.EncryptionKey) > 0 {
+		keySources++
+	}
+	if len(options.Keyring) > 0 {
+		keySources++
+	}
+	if options.KeyProvider != nil {
+		keySources++
+	}
+	if keySources > 1 {
+		return fmt.Errorf("wal: Options.EncryptionKey, Options.Keyring, and Options.KeyProvider are mutually exclusive")
+	}
+	if len(options.SegmentSigningKey) > 0 && len(options.SegmentSigningKey) != ed25519.PrivateKeySize {
+		return fmt.Errorf("wal: invalid Options.SegmentSigningKey: must be %d bytes, got %d: %w", ed25519.PrivateKeySize, len(options.SegmentSigningKey), ErrInvalidSigningKey)
+	}
+	if len(options.SegmentVerifyKey) > 0 && len(options.SegmentVerifyKey) != ed25519.PublicKeySize {
+		return fmt.Errorf("wal: invalid Options.SegmentVerifyKey: must be %d bytes, got %d: %w", ed25519.PublicKeySize, len(options.SegmentVerifyKey), ErrInvalidSigningKey)
+	}
+	if len(options.CompressionDict) > 0 {
+		if options.Compression != CompressionZstd {
+			return fmt.Errorf("wal: Options.CompressionDict requires Options.Compression to be CompressionZstd")
+		}
+		if _, err := zstdDictID(options.CompressionDict); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkDirWritable verifies options.DirPath -- which MkdirAll must already
+// have created -- can actually be written to, by probing it with a
+// throwaway file. It's a no-op for a read-only WAL, which never writes to
+// DirPath.
+func checkDirWritable(options *Options) error {
+	if options.ReadOnly {
+		return nil
+	}
+	probe := filepath.Join(options.DirPath, ".wal-writable-check")
+	f, err := options.FS.OpenFile(probe, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("wal: Options.DirPath %q is not writable: %w", options.DirPath, err)
+	}
+	f.Close()
+	options.FS.Remove(probe)
+	return nil
+}
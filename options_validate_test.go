@@ -0,0 +1,70 @@
+package wal
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenRejectsInvalidSegmentSize(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-validate-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	_, err = Open(WithDirPath(dir), WithSegmentSize(0))
+	require.ErrorContains(t, err, "Options.SegmentSize")
+}
+
+func TestOpenRejectsSegmentFileExtWithoutDot(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-validate-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	_, err = Open(WithDirPath(dir), WithSegmentFileExt("SEG"))
+	require.ErrorContains(t, err, "Options.SegmentFileExt")
+}
+
+func TestOpenRejectsBlockSizeLargerThanSegmentSize(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-validate-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	_, err = Open(WithDirPath(dir), WithSegmentSize(1024), WithBlockSize(2048))
+	require.ErrorContains(t, err, "Options.BlockSize")
+}
+
+func TestOpenRejectsBytesPerSyncLargerThanSegmentSize(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-validate-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	_, err = Open(WithDirPath(dir), WithSegmentSize(1024), WithBytesPerSync(2048))
+	require.ErrorContains(t, err, "Options.BytesPerSync")
+}
+
+func TestOpenRejectsSegmentFileNameFuncWithoutParseFunc(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-validate-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	_, err = Open(WithDirPath(dir), WithSegmentFileNaming(func(id SegmentID) string {
+		return "seg"
+	}, nil))
+	require.ErrorContains(t, err, "Options.SegmentFileNameFunc")
+}
+
+func TestOpenRejectsUnwritableDirPath(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-validate-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	require.Nil(t, os.Chmod(dir, 0o555))
+	defer os.Chmod(dir, 0o755)
+
+	if os.Geteuid() == 0 {
+		t.Skip("running as root: directory permissions don't block writes")
+	}
+
+	_, err = Open(WithDirPath(dir))
+	require.ErrorContains(t, err, "not writable")
+}
@@ -0,0 +1,112 @@
+package wal
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPinnedSegmentSkipLeavesAPinnedSegmentInPlace(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-pinned-segment-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(32), WithMaxSegmentAge(20*time.Millisecond))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("first-segment"))
+	require.Nil(t, err)
+	time.Sleep(40 * time.Millisecond)
+
+	w.mu.RLock()
+	pinned := w.activeSegment
+	w.mu.RUnlock()
+	require.True(t, pinned.acquire())
+
+	// Rotation, forced by this write exceeding SegmentSize, would normally
+	// sweep away the now-stale first segment, but it's pinned.
+	_, err = w.Write([]byte("second-segment-forces-rotation"))
+	require.Nil(t, err)
+
+	w.mu.RLock()
+	_, stillThere := w.olderSegments[pinned.id]
+	w.mu.RUnlock()
+	require.True(t, stillThere, "a pinned segment was retired under the default PinnedSegmentSkip policy")
+
+	pinned.release()
+
+	// Now that it's unpinned, the next rotation's retention pass retires it.
+	_, err = w.Write([]byte("third-segment-forces-another-rotation"))
+	require.Nil(t, err)
+
+	w.mu.RLock()
+	_, stillThere = w.olderSegments[pinned.id]
+	w.mu.RUnlock()
+	require.False(t, stillThere)
+}
+
+func TestPinnedSegmentBlockWaitsForRelease(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-pinned-segment-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(32), WithMaxSegmentAge(20*time.Millisecond), WithPinnedSegmentPolicy(PinnedSegmentBlock))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("first-segment"))
+	require.Nil(t, err)
+	time.Sleep(40 * time.Millisecond)
+
+	w.mu.RLock()
+	pinned := w.activeSegment
+	w.mu.RUnlock()
+	require.True(t, pinned.acquire())
+
+	released := make(chan struct{})
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		pinned.release()
+		close(released)
+	}()
+
+	_, err = w.Write([]byte("second-segment-forces-rotation"))
+	require.Nil(t, err)
+	<-released
+
+	w.mu.RLock()
+	_, stillThere := w.olderSegments[pinned.id]
+	w.mu.RUnlock()
+	require.False(t, stillThere, "PinnedSegmentBlock should have retired the segment once it was released")
+}
+
+func TestPinnedSegmentFailReturnsErrSegmentPinned(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-pinned-segment-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(32), WithMaxSegmentAge(20*time.Millisecond), WithPinnedSegmentPolicy(PinnedSegmentFail))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("first-segment"))
+	require.Nil(t, err)
+	time.Sleep(40 * time.Millisecond)
+
+	w.mu.RLock()
+	pinned := w.activeSegment
+	w.mu.RUnlock()
+	require.True(t, pinned.acquire())
+	defer pinned.release()
+
+	_, err = w.Write([]byte("second-segment-forces-rotation"))
+	require.Equal(t, ErrSegmentPinned, err)
+
+	w.mu.RLock()
+	_, stillThere := w.olderSegments[pinned.id]
+	w.mu.RUnlock()
+	require.True(t, stillThere)
+}
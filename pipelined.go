@@ -0,0 +1,204 @@
+package wal
+
+import "time"
+
+// queuedWrite is one record accepted by Write under Options.Pipelined,
+// waiting for the background flusher to actually write it. payload is
+// already timestamp-prefixed, compressed, and encrypted -- exactly the
+// bytes that will be appended as-is -- so the flusher has nothing left to
+// do but batch them onto disk.
+type queuedWrite struct {
+	payload []byte
+	pos     *ChunkPosition
+}
+
+// writeQueued implements Write under Options.Pipelined: it does every
+// transform Write would normally do inline (validation, compression,
+// encryption) and then queues the result instead of appending it to the
+// active segment itself, blocking only if Options.MaxPendingBytes would be
+// exceeded. The returned position is exact -- the background flusher
+// writes queued entries in the same order, to the same offsets, that were
+// reserved here -- but nothing outside this WAL (Read, a Reader, another
+// process) can see it until the flusher drains it; see Options.Pipelined.
+func (w *WAL) writeQueued(data []byte) (*ChunkPosition, error) {
+	if len(data) == 0 {
+		return nil, ErrEmptyRecord
+	}
+	if w.options.MaxRecordSize > 0 && int64(len(data)) > w.options.MaxRecordSize {
+		return nil, &ErrRecordTooLarge{Size: len(data), MaxSize: w.options.MaxRecordSize}
+	}
+	logicalLen := len(data)
+	data = prefixTimestamp(w.options.Timestamps, data)
+
+	var scratch []byte
+	if w.bufPool != nil && w.options.Compression != CompressionNone {
+		scratch = w.bufPool.Get()
+		defer w.bufPool.Put(scratch)
+	}
+	payload, err := compressPayload(w.options.Compression, data, scratch, w.options.CompressionDict)
+	if err != nil {
+		return nil, err
+	}
+	payload, err = w.encryptChunk(payload)
+	if err != nil {
+		return nil, err
+	}
+	chunkSize := int64(chunkHeaderSize) + int64(len(payload))
+
+	backoff := DefaultWatcherOptions.MinBackoff
+	for {
+		w.mu.Lock()
+		if w.closed {
+			w.mu.Unlock()
+			return nil, ErrClosed
+		}
+		if w.syncErr != nil {
+			cause := w.syncErr
+			w.mu.Unlock()
+			return nil, &ErrPoisoned{Cause: cause}
+		}
+		if w.fenced {
+			w.mu.Unlock()
+			return nil, ErrFenced
+		}
+		if w.options.MaxPendingBytes <= 0 || w.writeQueueBytes+chunkSize <= w.options.MaxPendingBytes {
+			break
+		}
+		w.mu.Unlock()
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > DefaultWatcherOptions.MaxBackoff {
+			backoff = DefaultWatcherOptions.MaxBackoff
+		}
+	}
+	defer w.mu.Unlock()
+
+	if err := w.rotateQueuedIfNeeded(chunkSize); err != nil {
+		return nil, err
+	}
+
+	pos := &ChunkPosition{
+		SegmentId:   w.activeSegment.id,
+		ChunkOffset: w.activeSegment.Size() + w.writeQueueBytes,
+		ChunkSize:   uint32(len(payload)),
+		Sequence:    w.nextSeq,
+	}
+	w.nextSeq++
+	w.lastPos = pos
+	w.writeQueue = append(w.writeQueue, queuedWrite{payload: payload, pos: pos})
+	w.writeQueueBytes += chunkSize
+	w.logicalBytesWritten += uint64(logicalLen)
+
+	return pos, nil
+}
+
+// rotateQueuedIfNeeded is rotateIfNeeded's Options.Pipelined counterpart:
+// activeSegment.Size() alone doesn't account for what's queued but not
+// yet written, so it drains the queue first whenever the reservation
+// about to be made would, combined with what's already on disk, exceed
+// Options.SegmentSize -- putting activeSegment.Size() back in sync with
+// reality before deferring to the real rotateIfNeeded. mu must already be
+// held.
+func (w *WAL) rotateQueuedIfNeeded(nextChunkSize int64) error {
+	if w.activeSegment.Size()+w.writeQueueBytes+nextChunkSize <= w.options.SegmentSize {
+		return nil
+	}
+	if err := w.drainWriteQueueLocked(); err != nil {
+		return err
+	}
+	return w.rotateIfNeeded(nextChunkSize)
+}
+
+// drainWriteQueueLocked writes and syncs everything currently queued, in
+// one batch. mu must already be held. It is a no-op if the queue is
+// empty, so the background flusher calling it on every tick or nudge
+// costs nothing once it has caught up.
+func (w *WAL) drainWriteQueueLocked() error {
+	if len(w.writeQueue) == 0 {
+		return nil
+	}
+	queue := w.writeQueue
+	w.writeQueue = nil
+	w.writeQueueBytes = 0
+
+	payloads := make([][]byte, len(queue))
+	for i, q := range queue {
+		payloads[i] = q.payload
+	}
+
+	if w.options.Limiter != nil {
+		total := 0
+		for _, p := range payloads {
+			total += chunkHeaderSize + len(p)
+		}
+		if err := w.options.Limiter.WaitN(total); err != nil {
+			return err
+		}
+	}
+
+	if _, err := w.activeSegment.WriteBatch(payloads); err != nil {
+		w.options.MetricsCollector.IncIOErrors()
+		// Unlike a synchronous Write's own failed append, every position
+		// in this batch was already handed back to its caller as a
+		// success before the write actually happened -- there's no
+		// caller left to return this error to for any but the last one
+		// queued. Poisoning, the same as a failed fsync does, is the
+		// only honest response: further writes fail fast instead of
+		// silently growing a queue that can never be written out.
+		err = w.handleIOErrorLocked(err, w.activeSegment.id, w.activeSegment.Size())
+		w.poisonLocked(err)
+		return err
+	}
+	for _, q := range queue {
+		w.activeSegment.recordSequence(q.pos.Sequence, q.pos.ChunkOffset)
+		w.options.MetricsCollector.AddBytesWritten(len(q.payload))
+		w.options.MetricsCollector.IncChunksWritten()
+		w.bytesWritten += uint64(len(q.payload))
+		w.chunksWritten++
+		w.bytesSinceSync += uint32(chunkHeaderSize + len(q.payload))
+	}
+	w.options.MetricsCollector.SetSegmentSize(w.activeSegment.Size())
+
+	return w.sync()
+}
+
+// startWriteQueueFlusher runs in its own goroutine for the lifetime of a
+// WAL opened with Options.Pipelined, draining the write queue every
+// interval (if positive). A Write blocked on Options.MaxPendingBytes is
+// relieved by whichever drains the queue first -- this ticker, Close, or
+// a rotation pulling the queue down to make room for the new active
+// segment -- so a zero PipelineFlushInterval leaves MaxPendingBytes
+// backpressure with nothing to rely on but those. It exits once the WAL
+// is closed, after Close has drained the queue one last time itself.
+func (w *WAL) startWriteQueueFlusher(interval time.Duration) {
+	w.writeQueueDone = make(chan struct{})
+	go func() {
+		var tickerC <-chan time.Time
+		if interval > 0 {
+			ticker := w.options.Clock.NewTicker(interval)
+			defer ticker.Stop()
+			tickerC = ticker.C()
+		}
+		for {
+			select {
+			case <-tickerC:
+				w.flushWriteQueue()
+			case <-w.writeQueueDone:
+				return
+			}
+		}
+	}()
+}
+
+// flushWriteQueue is the background flusher's one unit of work: take mu,
+// drain whatever is queued, release it. It swallows drainWriteQueueLocked's
+// error -- the same failure already poisoned the WAL via poisonLocked (or
+// handleIOErrorLocked) for the next Write to report -- rather than having
+// nowhere to send it from a goroutine with no caller to return it to.
+func (w *WAL) flushWriteQueue() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return
+	}
+	_ = w.drainWriteQueueLocked()
+}
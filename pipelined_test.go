@@ -0,0 +1,161 @@
+package wal
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPipelinedWriteReturnsExactPositionAndDefersIt(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-pipelined-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithPipelined(true))
+	require.Nil(t, err)
+
+	pos, err := w.Write([]byte("hello"))
+	require.Nil(t, err)
+	assert.Equal(t, SegmentID(1), pos.SegmentId)
+	assert.EqualValues(t, 0, pos.ChunkOffset)
+
+	// Not yet drained: reading it back fails, unlike a normal Write.
+	_, err = w.Read(pos)
+	require.NotNil(t, err)
+
+	require.Nil(t, w.Close())
+
+	w2, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w2.Close()
+	data, err := w2.Read(pos)
+	require.Nil(t, err)
+	assert.Equal(t, []byte("hello"), data)
+}
+
+func TestPipelinedWriteBatchesMultipleRecords(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-pipelined-batch-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithPipelined(true))
+	require.Nil(t, err)
+	defer w.Close()
+
+	var positions []*ChunkPosition
+	for i := 0; i < 5; i++ {
+		pos, err := w.Write([]byte{byte('a' + i)})
+		require.Nil(t, err)
+		positions = append(positions, pos)
+	}
+
+	w.mu.Lock()
+	require.Nil(t, w.drainWriteQueueLocked())
+	w.mu.Unlock()
+
+	for i, pos := range positions {
+		data, err := w.Read(pos)
+		require.Nil(t, err)
+		assert.Equal(t, []byte{byte('a' + i)}, data)
+	}
+}
+
+func TestPipelinedMaxPendingBytesAppliesBackpressure(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-pipelined-backpressure-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithPipelined(true), WithMaxPendingBytes(int64(chunkHeaderSize)+5))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("hello"))
+	require.Nil(t, err)
+
+	blocked := make(chan struct{})
+	go func() {
+		_, err := w.Write([]byte("world"))
+		require.Nil(t, err)
+		close(blocked)
+	}()
+
+	select {
+	case <-blocked:
+		t.Fatal("second write should have blocked on MaxPendingBytes")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	w.mu.Lock()
+	require.Nil(t, w.drainWriteQueueLocked())
+	w.mu.Unlock()
+
+	select {
+	case <-blocked:
+	case <-time.After(time.Second):
+		t.Fatal("second write never unblocked after drain")
+	}
+}
+
+func TestPipelinedFlushIntervalDrainsInBackground(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-pipelined-interval-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithPipelined(true), WithPipelineFlushInterval(10*time.Millisecond))
+	require.Nil(t, err)
+	defer w.Close()
+
+	pos, err := w.Write([]byte("hello"))
+	require.Nil(t, err)
+
+	require.Eventually(t, func() bool {
+		_, err := w.Read(pos)
+		return err == nil
+	}, time.Second, time.Millisecond)
+}
+
+func TestPipelinedRotationDrainsFirst(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-pipelined-rotate-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithPipelined(true), WithSegmentSize(32))
+	require.Nil(t, err)
+	defer w.Close()
+
+	pos1, err := w.Write([]byte("hello"))
+	require.Nil(t, err)
+
+	for i := 0; i < 4; i++ {
+		_, err = w.Write([]byte("pad-it-past-one-segment"))
+		require.Nil(t, err)
+	}
+
+	require.Greater(t, w.activeSegment.id, pos1.SegmentId)
+	data, err := w.Read(pos1)
+	require.Nil(t, err)
+	assert.Equal(t, []byte("hello"), data)
+}
+
+func TestPipelinedCloseDrainsOutstandingWrites(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-pipelined-close-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithPipelined(true))
+	require.Nil(t, err)
+
+	pos, err := w.Write([]byte("hello"))
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+
+	w2, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w2.Close()
+	data, err := w2.Read(pos)
+	require.Nil(t, err)
+	assert.Equal(t, []byte("hello"), data)
+}
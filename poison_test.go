@@ -0,0 +1,97 @@
+package wal
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// failSyncFS wraps osFS so tests can make the active segment's fsync fail
+// on demand, without needing a real disk failure.
+type failSyncFS struct {
+	osFS
+	fail *bool
+}
+
+func (fs failSyncFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	f, err := fs.osFS.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return failSyncFile{f.(*os.File), fs.fail}, nil
+}
+
+type failSyncFile struct {
+	*os.File
+	fail *bool
+}
+
+var errSimulatedFsyncFailure = errors.New("simulated fsync failure")
+
+func (f failSyncFile) Sync() error {
+	if *f.fail {
+		return errSimulatedFsyncFailure
+	}
+	return f.File.Sync()
+}
+
+func TestSyncFailurePoisonsFurtherWrites(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-poison-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	fail := false
+	var gotErr error
+	w, err := Open(
+		WithDirPath(dir),
+		WithFS(failSyncFS{fail: &fail}),
+		WithOnSyncError(func(err error) { gotErr = err }),
+	)
+	require.Nil(t, err)
+	defer w.Close()
+
+	helloPos, err := w.Write([]byte("hello"))
+	require.Nil(t, err)
+
+	fail = true
+	_, err = w.Sync()
+	require.Equal(t, errSimulatedFsyncFailure, err)
+	require.Equal(t, errSimulatedFsyncFailure, gotErr)
+
+	_, err = w.Write([]byte("world"))
+	require.True(t, errors.Is(err, ErrWALPoisoned))
+	var poisoned *ErrPoisoned
+	require.True(t, errors.As(err, &poisoned))
+	require.Equal(t, errSimulatedFsyncFailure, poisoned.Cause)
+
+	w.PendingWrites([]byte("world"))
+	_, err = w.WriteAll()
+	require.True(t, errors.Is(err, ErrWALPoisoned))
+
+	require.False(t, w.Healthy())
+	require.Equal(t, errSimulatedFsyncFailure, w.Err())
+
+	// Poisoning only refuses writes; reads of already-durable data still
+	// succeed.
+	data, err := w.Read(helloPos)
+	require.Nil(t, err)
+	require.Equal(t, "hello", string(data))
+}
+
+func TestSyncFailureDoesNotPoisonOtherWALs(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-poison-isolated-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	require.True(t, w.Healthy())
+	require.Nil(t, w.Err())
+
+	_, err = w.Write([]byte("hello"))
+	require.Nil(t, err)
+}
@@ -0,0 +1,39 @@
+package wal
+
+// LastPosition returns the position of the most recently written chunk, or
+// nil if nothing has ever been written to this WAL, including in a
+// previous process if it's been reopened. Replication code that wants to
+// know the write frontier can call this instead of writing and discarding
+// a dummy record just to learn it.
+func (w *WAL) LastPosition() *ChunkPosition {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.lastPos
+}
+
+// DurablePosition returns the position of the most recent write known to
+// have survived an fsync, or nil if none has yet. Pass a position returned
+// by Write (or LastPosition) to WaitForSync to block until it reaches this
+// frontier.
+func (w *WAL) DurablePosition() *ChunkPosition {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.durablePos
+}
+
+// NextPosition returns an estimate of where the next chunk written to this
+// WAL would land. It's only an estimate, not a reservation: nothing stops
+// a concurrent Write from landing there first, or from growing the active
+// segment past SegmentSize and rotating before the next real write
+// happens, so don't treat the result as stable once the lock is released.
+// ChunkSize on the result is always 0, since no chunk has actually been
+// framed yet.
+func (w *WAL) NextPosition() *ChunkPosition {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return &ChunkPosition{
+		SegmentId:   w.activeSegment.id,
+		ChunkOffset: w.activeSegment.Size(),
+		Sequence:    w.nextSeq,
+	}
+}
@@ -0,0 +1,78 @@
+package wal
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLastPositionIsNilForAnEmptyWAL(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-last-position-empty-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	require.Nil(t, w.LastPosition())
+}
+
+func TestLastPositionTracksTheMostRecentWrite(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-last-position-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(32))
+	require.Nil(t, err)
+	defer w.Close()
+
+	var last *ChunkPosition
+	for i := 0; i < 6; i++ {
+		last, err = w.Write([]byte("0123456789"))
+		require.Nil(t, err)
+		require.Equal(t, last, w.LastPosition())
+	}
+}
+
+func TestLastPositionRecoversAfterReopen(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-last-position-reopen-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(32))
+	require.Nil(t, err)
+	var last *ChunkPosition
+	for i := 0; i < 6; i++ {
+		last, err = w.Write([]byte("0123456789"))
+		require.Nil(t, err)
+	}
+	require.Nil(t, w.Close())
+
+	w2, err := Open(WithDirPath(dir), WithSegmentSize(32))
+	require.Nil(t, err)
+	defer w2.Close()
+	require.Equal(t, last, w2.LastPosition())
+}
+
+func TestNextPositionMatchesWhereTheNextWriteLands(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-next-position-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("first"))
+	require.Nil(t, err)
+
+	estimate := w.NextPosition()
+	actual, err := w.Write([]byte("second"))
+	require.Nil(t, err)
+
+	require.Equal(t, actual.SegmentId, estimate.SegmentId)
+	require.Equal(t, actual.ChunkOffset, estimate.ChunkOffset)
+	require.Equal(t, actual.Sequence, estimate.Sequence)
+}
@@ -0,0 +1,86 @@
+package wal
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSealPositionRoundTrip(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-seal-roundtrip-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithTokenSealing([]byte("a-shared-secret")))
+	require.Nil(t, err)
+	defer w.Close()
+
+	pos, err := w.Write([]byte("record"))
+	require.Nil(t, err)
+
+	token, err := w.SealPosition(pos)
+	require.Nil(t, err)
+
+	got, err := w.UnsealPosition(token)
+	require.Nil(t, err)
+	require.Equal(t, pos, got)
+}
+
+func TestSealPositionRequiresTokenSealKey(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-seal-disabled-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	pos, err := w.Write([]byte("record"))
+	require.Nil(t, err)
+
+	_, err = w.SealPosition(pos)
+	require.True(t, errors.Is(err, ErrTokenSealingDisabled))
+
+	_, err = w.UnsealPosition("anything")
+	require.True(t, errors.Is(err, ErrTokenSealingDisabled))
+}
+
+func TestUnsealPositionRejectsTokenFromADifferentKey(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-seal-wrongkey-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w1, err := Open(WithDirPath(dir), WithTokenSealing([]byte("key-one")))
+	require.Nil(t, err)
+	pos, err := w1.Write([]byte("record"))
+	require.Nil(t, err)
+	token, err := w1.SealPosition(pos)
+	require.Nil(t, err)
+	require.Nil(t, w1.Close())
+
+	dir2, err := os.MkdirTemp("", "wal-seal-wrongkey-test-2")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir2)
+
+	w2, err := Open(WithDirPath(dir2), WithTokenSealing([]byte("key-two")))
+	require.Nil(t, err)
+	defer w2.Close()
+
+	_, err = w2.UnsealPosition(token)
+	require.True(t, errors.Is(err, ErrInvalidToken))
+}
+
+func TestUnsealPositionRejectsGarbage(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-seal-garbage-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithTokenSealing([]byte("a-shared-secret")))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.UnsealPosition("not-a-real-token")
+	require.True(t, errors.Is(err, ErrInvalidToken))
+}
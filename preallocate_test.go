@@ -0,0 +1,58 @@
+package wal
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithPreallocateSizesHotPathSegment(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-preallocate-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"), WithSegmentSize(64*KB), WithPreallocate(true))
+	require.Nil(t, err)
+	defer w.Close()
+
+	info, err := w.activeSegment.fd.Stat()
+	require.Nil(t, err)
+	assert.Equal(t, int64(64*KB), info.Size())
+}
+
+func TestWithoutPreallocateGrowsSegmentOnDemand(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-preallocate-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"), WithSegmentSize(64*KB))
+	require.Nil(t, err)
+	defer w.Close()
+
+	info, err := w.activeSegment.fd.Stat()
+	require.Nil(t, err)
+	assert.Equal(t, int64(segmentHeaderSize), info.Size())
+}
+
+func TestWithPreallocateSegmentStillReadsBackCorrectly(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-preallocate-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"), WithSegmentSize(64*KB), WithPreallocate(true))
+	require.Nil(t, err)
+
+	pos, err := w.Write([]byte("hello"))
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+
+	w, err = Open(WithDirPath(dir), WithSegmentFileExt(".SEG"), WithSegmentSize(64*KB), WithPreallocate(true))
+	require.Nil(t, err)
+	defer w.Close()
+
+	data, err := w.Read(pos)
+	require.Nil(t, err)
+	assert.Equal(t, []byte("hello"), data)
+}
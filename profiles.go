@@ -0,0 +1,61 @@
+package wal
+
+import "time"
+
+// ProfileDurable returns Options tuned to prioritize a Write's durability
+// guarantee over its throughput or latency: every Write fsyncs before
+// returning (Options.Sync), with Options.GroupCommit on so concurrent
+// synchronous writers share that fsync instead of each paying for their
+// own. Every field not set here keeps DefaultOptions' value; set DirPath
+// (and anything else you want to override) on the returned Options
+// before passing it to OpenWithOptions, the same way you would for any
+// other Options value.
+func ProfileDurable() Options {
+	options := DefaultOptions
+	options.Sync = true
+	options.GroupCommit = true
+	return options
+}
+
+// ProfileHighThroughput returns Options tuned to prioritize sustained
+// write throughput over a single Write's own latency or durability:
+// Options.Pipelined queues and batches writes in the background instead
+// of appending (and fsyncing) each one inline, Options.SegmentPreallocate
+// keeps a next segment file ready so rotation never creates or allocates
+// one on the hot path, and Options.SegmentWriteBufferSize,
+// Options.CompressionParallelism, and Options.OpenParallelism each turn a
+// cost that would otherwise be paid per-call, per-entry, or per-segment
+// into one done in fewer, larger, or more parallel steps instead. See
+// ProfileDurable's doc comment for how to layer your own overrides on top
+// of the returned Options.
+func ProfileHighThroughput() Options {
+	options := DefaultOptions
+	options.Pipelined = true
+	options.MaxPendingBytes = 16 * MB
+	options.PipelineFlushInterval = 5 * time.Millisecond
+	options.SegmentPreallocate = 2
+	options.SegmentWriteBufferSize = 256 * KB
+	options.CompressionParallelism = 4
+	options.OpenParallelism = 4
+	return options
+}
+
+// ProfileLowLatency returns Options tuned to keep a single Write's own
+// latency as low as possible, at the cost of the batching ProfileDurable
+// and ProfileHighThroughput both rely on: Options.Sync stays off so Write
+// never blocks on an fsync of its own, and Options.Pipelined and
+// Options.WriteLinger stay disabled so a lone writer is never made to
+// wait on a queued or lingered batch that may never fill. Every field is
+// set explicitly here, even where it matches DefaultOptions' own value,
+// so this profile's guarantees hold even if DefaultOptions changes later.
+// See ProfileDurable's doc comment for how to layer your own overrides on
+// top of the returned Options.
+func ProfileLowLatency() Options {
+	options := DefaultOptions
+	options.Sync = false
+	options.GroupCommit = false
+	options.Pipelined = false
+	options.WriteLinger = 0
+	options.SegmentWriteBufferSize = 0
+	return options
+}
@@ -0,0 +1,80 @@
+package wal
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfileDurableOpensAndRoundTrips(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-profile-durable-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	options := ProfileDurable()
+	options.DirPath = dir
+	w, err := OpenWithOptions(options)
+	require.Nil(t, err)
+	defer w.Close()
+
+	require.True(t, w.options.Sync)
+	require.True(t, w.options.GroupCommit)
+
+	pos, err := w.Write([]byte("durable"))
+	require.Nil(t, err)
+	got, err := w.Read(pos)
+	require.Nil(t, err)
+	require.Equal(t, []byte("durable"), got)
+}
+
+func TestProfileHighThroughputOpensAndRoundTrips(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-profile-high-throughput-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	options := ProfileHighThroughput()
+	options.DirPath = dir
+	w, err := OpenWithOptions(options)
+	require.Nil(t, err)
+
+	require.True(t, w.options.Pipelined)
+	require.Greater(t, w.options.SegmentPreallocate, 0)
+
+	pos, err := w.Write([]byte("throughput"))
+	require.Nil(t, err)
+
+	// Pipelined, like ProfileHighThroughput sets it, only makes a write
+	// visible once it's been drained -- by Close here, same as
+	// TestPipelinedWriteReturnsExactPositionAndDefersIt.
+	require.Nil(t, w.Close())
+
+	w2, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w2.Close()
+	got, err := w2.Read(pos)
+	require.Nil(t, err)
+	require.Equal(t, []byte("throughput"), got)
+}
+
+func TestProfileLowLatencyOpensAndRoundTrips(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-profile-low-latency-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	options := ProfileLowLatency()
+	options.DirPath = dir
+	w, err := OpenWithOptions(options)
+	require.Nil(t, err)
+	defer w.Close()
+
+	require.False(t, w.options.Sync)
+	require.False(t, w.options.Pipelined)
+	require.Zero(t, w.options.WriteLinger)
+
+	pos, err := w.Write([]byte("low-latency"))
+	require.Nil(t, err)
+	got, err := w.Read(pos)
+	require.Nil(t, err)
+	require.Equal(t, []byte("low-latency"), got)
+}
@@ -0,0 +1,86 @@
+package wal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPurgeResetsToAnEmptyLogWithAFreshFirstSegment(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-purge-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(32))
+	require.Nil(t, err)
+	defer w.Close()
+
+	for i := 0; i < 6; i++ {
+		_, err := w.Write([]byte("0123456789"))
+		require.Nil(t, err)
+	}
+	require.NotEmpty(t, w.olderSegments)
+
+	require.Nil(t, w.Purge())
+
+	w.mu.RLock()
+	require.Empty(t, w.olderSegments)
+	require.Equal(t, SegmentID(1), w.activeSegment.id)
+	require.Equal(t, int64(0), w.activeSegment.Size())
+	w.mu.RUnlock()
+
+	entries, err := os.ReadDir(dir)
+	require.Nil(t, err)
+	var segmentFiles int
+	for _, e := range entries {
+		if filepath.Ext(e.Name()) == ".SEG" {
+			segmentFiles++
+		}
+	}
+	require.Equal(t, 1, segmentFiles)
+
+	pos, err := w.Write([]byte("after-purge"))
+	require.Nil(t, err)
+	data, err := w.Read(pos)
+	require.Nil(t, err)
+	require.Equal(t, []byte("after-purge"), data)
+}
+
+func TestPurgeUsesStartSegmentID(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-purge-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(32), WithStartSegmentID(50))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("0123456789"))
+	require.Nil(t, err)
+
+	require.Nil(t, w.Purge())
+
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	require.Equal(t, SegmentID(50), w.activeSegment.id)
+}
+
+func TestPurgeRejectsReadOnlyWAL(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-purge-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	_, err = w.Write([]byte("hello"))
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+
+	ro, err := Open(WithDirPath(dir), WithReadOnly(true))
+	require.Nil(t, err)
+	defer ro.Close()
+
+	require.Equal(t, ErrReadOnly, ro.Purge())
+}
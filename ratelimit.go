@@ -0,0 +1,68 @@
+package wal
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter is the interface a custom write-path throttle must satisfy for
+// WithLimiter. WaitN blocks until n bytes -- a chunk's header plus
+// payload, for Write, or a whole run of them written in one segment
+// append, for WriteAll -- are allowed to be written, or returns an error
+// if they never will be. Implementations must be safe for concurrent
+// use; WithRateLimit installs a token-bucket one.
+type Limiter interface {
+	WaitN(n int) error
+}
+
+// tokenBucketLimiter is the default Limiter WithRateLimit installs: a
+// classic token bucket that refills at bytesPerSec and never holds more
+// than one second's worth of tokens, so a burst after an idle stretch is
+// capped rather than let through in one go.
+type tokenBucketLimiter struct {
+	mu          sync.Mutex
+	bytesPerSec float64
+	tokens      float64
+	last        time.Time
+}
+
+func newTokenBucketLimiter(bytesPerSec int64) *tokenBucketLimiter {
+	return &tokenBucketLimiter{
+		bytesPerSec: float64(bytesPerSec),
+		tokens:      float64(bytesPerSec),
+		last:        time.Now(),
+	}
+}
+
+func (l *tokenBucketLimiter) WaitN(n int) error {
+	l.mu.Lock()
+	l.refillLocked()
+
+	need := float64(n) - l.tokens
+	if need <= 0 {
+		l.tokens -= float64(n)
+		l.mu.Unlock()
+		return nil
+	}
+	l.tokens = 0
+	wait := time.Duration(need / l.bytesPerSec * float64(time.Second))
+	l.mu.Unlock()
+
+	time.Sleep(wait)
+
+	l.mu.Lock()
+	l.refillLocked()
+	l.mu.Unlock()
+	return nil
+}
+
+// refillLocked tops up tokens for the time elapsed since the last call,
+// capped at one second's worth. l.mu must already be held.
+func (l *tokenBucketLimiter) refillLocked() {
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.bytesPerSec
+	if l.tokens > l.bytesPerSec {
+		l.tokens = l.bytesPerSec
+	}
+	l.last = now
+}
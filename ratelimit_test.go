@@ -0,0 +1,56 @@
+package wal
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimitThrottlesWrites(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-ratelimit-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithRateLimit(64))
+	require.Nil(t, err)
+	defer w.Close()
+
+	// The bucket starts full with one second's worth of tokens, so the
+	// first write of this size is free; the second has to wait for a
+	// refill.
+	start := time.Now()
+	_, err = w.Write(make([]byte, 64))
+	require.Nil(t, err)
+	_, err = w.Write(make([]byte, 64))
+	require.Nil(t, err)
+	require.GreaterOrEqual(t, time.Since(start), 500*time.Millisecond)
+}
+
+type countingLimiter struct {
+	calls int
+	bytes int
+}
+
+func (l *countingLimiter) WaitN(n int) error {
+	l.calls++
+	l.bytes += n
+	return nil
+}
+
+func TestWithLimiterInstallsACustomLimiter(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-custom-limiter-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	limiter := &countingLimiter{}
+	w, err := Open(WithDirPath(dir), WithLimiter(limiter))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("hello"))
+	require.Nil(t, err)
+	require.Equal(t, 1, limiter.calls)
+	require.Equal(t, chunkHeaderSize+5, limiter.bytes)
+}
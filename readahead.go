@@ -0,0 +1,50 @@
+package wal
+
+// ReaderOption configures a Reader returned by WAL.NewReader and its
+// siblings.
+type ReaderOption func(*Reader)
+
+// readaheadBlockSize is the size, in bytes, of one "block" WithReadahead
+// counts in. It's unrelated to Options.BlockSize, which this package never
+// enforces as a real physical boundary; see that field's doc comment. It's
+// sized to match a typical OS readahead unit.
+const readaheadBlockSize = 4096
+
+// WithReadahead configures a Reader to prefetch, in a background
+// goroutine, the next n*readaheadBlockSize bytes of whatever segment it is
+// currently reading -- each time its read position crosses into a region
+// it hasn't already triggered a prefetch for. That overlaps the next
+// stretch of disk latency with this call's own decoding work, which on a
+// spinning disk can roughly double a sequential scan's throughput; against
+// an already-warm page cache, or a segment read through Options.MmapRead
+// (already resident in memory), it's a no-op beyond the bookkeeping. n is
+// 0 (disabled) by default.
+func WithReadahead(n int) ReaderOption {
+	return func(r *Reader) {
+		r.readahead = n
+	}
+}
+
+// maybePrefetch issues a background prefetch starting at offset in seg, if
+// Reader.readahead is positive and offset has advanced into territory the
+// last prefetch didn't already cover. It pins seg for the background
+// read's duration with its own acquire/release, independent of whatever
+// acquire the caller is already holding.
+func (r *Reader) maybePrefetch(seg *segment, offset int64) {
+	if r.readahead <= 0 {
+		return
+	}
+	if seg.id == r.prefetchSegID && offset < r.prefetchOffset {
+		return
+	}
+	window := int64(r.readahead) * readaheadBlockSize
+	if !seg.acquire() {
+		return
+	}
+	r.prefetchSegID = seg.id
+	r.prefetchOffset = offset + window
+	go func() {
+		defer seg.release()
+		seg.prefetch(offset, window)
+	}()
+}
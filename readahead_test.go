@@ -0,0 +1,70 @@
+package wal
+
+import (
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReaderWithReadaheadReadsSameData(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-readahead-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	var want [][]byte
+	for i := 0; i < 50; i++ {
+		data := []byte{byte(i), byte(i), byte(i)}
+		want = append(want, data)
+		_, err := w.Write(data)
+		require.Nil(t, err)
+	}
+
+	r := w.NewReader(WithReadahead(2))
+	for i, exp := range want {
+		data, _, err := r.Next()
+		require.Nil(t, err, "record %d", i)
+		assert.Equal(t, exp, data)
+	}
+	_, _, err = r.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestReadaheadDisabledByDefault(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-readahead-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("hello"))
+	require.Nil(t, err)
+
+	r := w.NewReader()
+	assert.Zero(t, r.readahead)
+}
+
+func TestSegmentPrefetchClipsToSegmentSize(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-readahead-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("hello"))
+	require.Nil(t, err)
+
+	// Should not panic or error even when the requested window runs well
+	// past what's actually been written.
+	w.activeSegment.prefetch(0, 1<<20)
+}
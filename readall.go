@@ -0,0 +1,67 @@
+package wal
+
+import (
+	"errors"
+	"sort"
+)
+
+// ReadAll reads the chunk at every position in positions, returning their
+// data in the same order as positions. Positions that share a segment are
+// grouped and read in one pass over that segment -- resolving and acquiring
+// it once -- rather than paying Read's per-call segment lookup for each of
+// them, which matters when positions is in the tens of thousands and
+// scattered, e.g. during compaction.
+func (w *WAL) ReadAll(positions []*ChunkPosition) ([][]byte, error) {
+	type indexedPos struct {
+		resultIdx int
+		pos       *ChunkPosition
+	}
+	bySegment := make(map[SegmentID][]indexedPos)
+	for i, pos := range positions {
+		bySegment[pos.SegmentId] = append(bySegment[pos.SegmentId], indexedPos{i, pos})
+	}
+
+	results := make([][]byte, len(positions))
+	for segID, group := range bySegment {
+		sort.Slice(group, func(i, j int) bool {
+			return group[i].pos.ChunkOffset < group[j].pos.ChunkOffset
+		})
+
+		w.mu.RLock()
+		seg := w.segmentByID(segID)
+		// acquire has to happen under the same RLock that resolved seg: see
+		// the same comment on Read.
+		acquired := seg != nil && seg.acquire()
+		w.mu.RUnlock()
+		if !acquired {
+			return nil, errSegmentNotFound(segID)
+		}
+
+		for _, item := range group {
+			data, err := seg.Read(item.pos.ChunkOffset)
+			if err != nil {
+				seg.release()
+				if errors.Is(err, ErrInvalidCRC) {
+					w.options.MetricsCollector.IncCRCErrors()
+				} else {
+					w.options.MetricsCollector.IncIOErrors()
+				}
+				return nil, err
+			}
+			data, err = w.decryptChunk(data)
+			if err != nil {
+				seg.release()
+				return nil, err
+			}
+			decoded, err := decompressPayload(w.options.Compression, data, w.options.CompressionDict)
+			if err != nil {
+				seg.release()
+				return nil, err
+			}
+			decoded, _ = stripTimestamp(w.options.Timestamps, decoded)
+			results[item.resultIdx] = decoded
+		}
+		seg.release()
+	}
+	return results, nil
+}
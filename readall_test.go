@@ -0,0 +1,55 @@
+package wal
+
+import (
+	"math/rand"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadAllMatchesInputOrder(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-readall-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(64))
+	require.Nil(t, err)
+	defer w.Close()
+
+	var positions []*ChunkPosition
+	for i := 0; i < 20; i++ {
+		pos, err := w.Write([]byte{byte(i)})
+		require.Nil(t, err)
+		positions = append(positions, pos)
+	}
+
+	shuffled := make([]*ChunkPosition, len(positions))
+	copy(shuffled, positions)
+	rand.New(rand.NewSource(1)).Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+
+	data, err := w.ReadAll(shuffled)
+	require.Nil(t, err)
+	require.Len(t, data, len(shuffled))
+	for i, pos := range shuffled {
+		require.Equal(t, []byte{byte(pos.Sequence)}, data[i])
+	}
+}
+
+func TestReadAllSegmentNotFound(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-readall-missing-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("hello"))
+	require.Nil(t, err)
+
+	_, err = w.ReadAll([]*ChunkPosition{{SegmentId: 999, ChunkOffset: 0, ChunkSize: 5}})
+	require.Error(t, err)
+}
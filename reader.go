@@ -0,0 +1,528 @@
+package wal
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"time"
+)
+
+// Reader iterates a WAL's chunks in order across all of its segments,
+// oldest to newest, including the active segment. Obtain one with
+// WAL.NewReader or WAL.NewReaderWithStart.
+//
+// A Reader that reaches the end of the active segment returns io.EOF
+// rather than blocking; Watcher is built on top of Reader to retry past
+// that until more data, or a new segment, appears.
+type Reader struct {
+	w        *WAL
+	segments []*segment
+	segIdx   int
+	offset   int64
+	nextSeq  uint64
+	end      *ChunkPosition // set by NewReaderWithRange; nil means read to the end of the log
+	filter   func(data []byte) bool
+
+	// batchPending accumulates records for batches NextBatch has seen the
+	// start of but not yet resolved, keyed by batch ID. It's a field on
+	// Reader, not a local inside NextBatch, because a batch Prepare'd by
+	// Batch.Prepare can have arbitrarily many other chunks -- including
+	// other batches' -- interleaved after it before it's finally resolved,
+	// possibly several NextBatch calls later.
+	batchPending map[uint64]*pendingBatch
+
+	// readahead, prefetchSegID, and prefetchOffset implement WithReadahead;
+	// see maybePrefetch.
+	readahead      int
+	prefetchSegID  SegmentID
+	prefetchOffset int64
+
+	// prevChecksum and chainUnknown implement chain verification for
+	// Options.ChainChecksums: prevChecksum is the checksum chained into
+	// whatever chunk r reads next, and chainUnknown is true whenever r has
+	// just jumped to a position it can't be sure prevChecksum is right for
+	// -- via Seek, NewReaderWithStart, NewReaderWithRange with a non-nil
+	// start, or SkipToSegment -- so the next chunk's chain link is skipped
+	// rather than checked against a guess. advanceToNextSegment always
+	// knows the right answer (0, since each segment's chain starts fresh),
+	// so it clears chainUnknown itself instead of leaving this to guess.
+	prevChecksum uint32
+	chainUnknown bool
+}
+
+// pendingBatch accumulates the records and positions of a batch NextBatch
+// has seen the start of but not yet resolved with a commit or abort
+// marker.
+type pendingBatch struct {
+	records   [][]byte
+	positions []*ChunkPosition
+}
+
+// NewReader returns a Reader starting from the first chunk in the WAL.
+func (w *WAL) NewReader(opts ...ReaderOption) *Reader {
+	r := &Reader{w: w, segments: w.orderedSegments()}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// NewReaderWithStart returns a Reader that resumes just after pos, which
+// must have been returned by a previous Write, WriteAll, or Reader.Next
+// call on this WAL.
+func (w *WAL) NewReaderWithStart(pos *ChunkPosition, opts ...ReaderOption) (*Reader, error) {
+	r := w.NewReader(opts...)
+	segIdx := r.indexOfSegment(pos.SegmentId)
+	if segIdx < 0 {
+		return nil, errSegmentNotFound(pos.SegmentId)
+	}
+	r.segIdx = segIdx
+	r.offset = pos.ChunkOffset + int64(chunkHeaderSize) + int64(pos.ChunkSize)
+	r.nextSeq = pos.Sequence + 1
+	r.chainUnknown = true
+	return r, nil
+}
+
+// NewReaderWithRange returns a Reader that yields only chunks in the
+// half-open range [start, end): start is included, end is not. A nil start
+// begins at the first chunk in the WAL; a nil end reads through to the end
+// of the currently-written data, same as NewReader.
+func (w *WAL) NewReaderWithRange(start, end *ChunkPosition, opts ...ReaderOption) (*Reader, error) {
+	r := w.NewReader(opts...)
+	if start != nil {
+		segIdx := r.indexOfSegment(start.SegmentId)
+		if segIdx < 0 {
+			return nil, errSegmentNotFound(start.SegmentId)
+		}
+		r.segIdx = segIdx
+		r.offset = start.ChunkOffset
+		r.nextSeq = start.Sequence
+		r.chainUnknown = true
+	}
+	r.end = end
+	return r, nil
+}
+
+// Seek repositions r to resume just after pos, which must have been
+// returned by a previous Write, WriteAll, or Reader.Next call on this WAL --
+// the same contract NewReaderWithStart has, but applied to an existing
+// Reader so a caller can jump forward or backward without losing its
+// filter, readahead, or end-of-range state. It searches r.segments from the
+// start rather than only forward from the reader's current position, so a
+// backward jump works the same as a forward one. It returns
+// errSegmentNotFound(pos.SegmentId) if no segment in the WAL (as of r's
+// last advance) matches.
+//
+// Any batch NextBatch had accumulated but not yet resolved is discarded,
+// since a jump elsewhere in the log makes it impossible to tell whether
+// that batch's commit or abort marker still lies ahead.
+func (r *Reader) Seek(pos *ChunkPosition) error {
+	segIdx := r.indexOfSegment(pos.SegmentId)
+	if segIdx < 0 {
+		return errSegmentNotFound(pos.SegmentId)
+	}
+	r.segIdx = segIdx
+	r.offset = pos.ChunkOffset + int64(chunkHeaderSize) + int64(pos.ChunkSize)
+	r.nextSeq = pos.Sequence + 1
+	r.batchPending = nil
+	r.chainUnknown = true
+	return nil
+}
+
+// SkipToSegment repositions r to the start of the segment with the given
+// id, without reading through whatever precedes it the way repeatedly
+// calling Next would. Unlike Seek, it needs no ChunkPosition already read
+// out of the chunk being resumed from.
+//
+// It recovers that first chunk's true Sequence from the segment's sparse
+// index (see segment.recordSequence) instead of counting chunks, so
+// positions Next returns afterward still carry a Sequence usable with
+// ReadBySequence. If the segment has no chunks yet, there is nothing for
+// the index to report; Next will return io.EOF immediately, the same as it
+// would for an empty active segment reached the normal way.
+//
+// It returns errSegmentNotFound(id) if no segment in the WAL (as of r's
+// last advance) matches.
+func (r *Reader) SkipToSegment(id SegmentID) error {
+	segIdx := r.indexOfSegment(id)
+	if segIdx < 0 {
+		return errSegmentNotFound(id)
+	}
+	r.segIdx = segIdx
+	r.offset = 0
+	r.nextSeq = 0
+	if index := r.segments[segIdx].sparseIndexSnapshot(); len(index) > 0 {
+		r.nextSeq = index[0].sequence
+	}
+	r.batchPending = nil
+	// Unlike Seek and NewReaderWithStart, this always lands at the very
+	// start of a segment, whose chain (like every segment's) starts fresh
+	// at 0 -- so, unlike them, there's no need to skip the next chunk's
+	// chain check.
+	r.prevChecksum = 0
+	r.chainUnknown = false
+	return nil
+}
+
+// indexOfSegment returns the position within r.segments of the segment with
+// the given id, or -1 if it isn't there.
+func (r *Reader) indexOfSegment(id SegmentID) int {
+	for i, seg := range r.segments {
+		if seg.id == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// NewReaderWithFilter returns a Reader starting from the first chunk in the
+// WAL that skips past any chunk for which fn returns false, without paying
+// the cost of returning it to the caller first. Useful for skipping records
+// a caller would otherwise just discard, e.g. another tenant's keys.
+func (w *WAL) NewReaderWithFilter(fn func(data []byte) bool, opts ...ReaderOption) *Reader {
+	r := w.NewReader(opts...)
+	r.filter = fn
+	return r
+}
+
+// nextDecoded returns the next chunk's data, decrypted and decompressed
+// but still carrying its Options.Timestamps prefix, if any, and position,
+// or io.EOF once the reader has caught up with the end of the currently-
+// written data (or, for a Reader from NewReaderWithRange, the end of its
+// range). Next and NextWithTimestamp both build on this, splitting that
+// prefix back off differently; neither applies r.filter until after doing
+// so, since a filter is meant to see the caller's own data, not the
+// envelope layered on top of it.
+func (r *Reader) nextDecoded() ([]byte, *ChunkPosition, error) {
+	data, pos, _, err := r.nextDecodedWithCRC()
+	return data, pos, err
+}
+
+// nextDecodedWithCRC is nextDecoded, but also returns the checksum recorded
+// in the chunk's header, for NextWithMeta.
+func (r *Reader) nextDecodedWithCRC() ([]byte, *ChunkPosition, uint32, error) {
+	for {
+		if r.segIdx >= len(r.segments) {
+			return nil, nil, 0, io.EOF
+		}
+		seg := r.segments[r.segIdx]
+
+		if r.end != nil {
+			current := &ChunkPosition{SegmentId: seg.id, ChunkOffset: r.offset}
+			if !current.Before(r.end) {
+				return nil, nil, 0, io.EOF
+			}
+		}
+
+		if !seg.acquire() {
+			// seg has been retired by a concurrent Checkpoint: it's been
+			// folded into a checkpoint file, so there's nothing left to
+			// read from it directly. Move on exactly as if its Read had
+			// come up short.
+			if !r.advanceToNextSegment(seg.id) {
+				return nil, nil, 0, io.EOF
+			}
+			continue
+		}
+		data, crc, err := seg.ReadWithCRC(r.offset)
+		seg.release()
+		if err != nil {
+			if errors.Is(err, ErrInvalidCRC) {
+				// Real corruption, not just "not written yet": surface it
+				// rather than silently skipping to the next segment.
+				r.w.options.MetricsCollector.IncCRCErrors()
+				return nil, nil, 0, err
+			}
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				// A short read past whatever has been written so far:
+				// this segment has nothing more (yet), try the next one.
+				if !r.advanceToNextSegment(seg.id) {
+					return nil, nil, 0, io.EOF
+				}
+				continue
+			}
+			// A genuine I/O error (e.g. permission denied, disk failure):
+			// surface it rather than silently treating it the same as
+			// "nothing written here yet" and skipping ahead.
+			r.w.options.MetricsCollector.IncIOErrors()
+			return nil, nil, 0, err
+		}
+
+		// Options.ChainChecksums means seg.ReadWithCRC above skipped its
+		// own checksum check -- it has no way to know the checksum chained
+		// into this chunk -- leaving it to this sequential scan instead,
+		// which does. chainUnknown is true right after a jump into the
+		// middle of a segment (Seek, NewReaderWithStart, ...), where this
+		// scan doesn't know it either; it's cleared as soon as one chunk
+		// has been read, so every chunk after the jump is still checked.
+		if r.w.options.ChainChecksums {
+			if r.chainUnknown {
+				r.chainUnknown = false
+			} else if !verifyChainedChecksum(seg.checksum, r.prevChecksum, data, crc) {
+				r.w.options.MetricsCollector.IncCRCErrors()
+				return nil, nil, 0, &ErrCorrupted{SegmentID: seg.id, ChunkOffset: r.offset, Reason: ErrInvalidCRC}
+			}
+			r.prevChecksum = crc
+		}
+
+		pos := &ChunkPosition{SegmentId: seg.id, ChunkOffset: r.offset, ChunkSize: uint32(len(data)), Sequence: r.nextSeq}
+		r.nextSeq++
+		r.offset += int64(chunkHeaderSize) + int64(len(data))
+		r.maybePrefetch(seg, r.offset)
+		data, err = r.w.decryptChunk(data)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		decoded, err := decompressPayload(r.w.options.Compression, data, r.w.options.CompressionDict)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		return decoded, pos, crc, nil
+	}
+}
+
+// Next returns the next chunk's data and position, or io.EOF once the
+// reader has caught up with the end of the currently-written data (or, for
+// a Reader from NewReaderWithRange, the end of its range).
+func (r *Reader) Next() ([]byte, *ChunkPosition, error) {
+	for {
+		decoded, pos, err := r.nextDecoded()
+		if err != nil {
+			return nil, nil, err
+		}
+		data, _ := stripTimestamp(r.w.options.Timestamps, decoded)
+		if r.filter != nil && !r.filter(data) {
+			continue
+		}
+		return data, pos, nil
+	}
+}
+
+// NextWithTimestamp is Next, but also returns the time the chunk was
+// written, if the WAL was opened with WithTimestamps(true) -- see
+// Options.Timestamps's doc comment for the mismatched-options caveat it
+// shares with Compression and EncryptionKey. It returns a zero time.Time
+// if Options.Timestamps is false.
+func (r *Reader) NextWithTimestamp() (data []byte, writtenAt time.Time, pos *ChunkPosition, err error) {
+	for {
+		decoded, pos, err := r.nextDecoded()
+		if err != nil {
+			return nil, time.Time{}, nil, err
+		}
+		data, writtenAt := stripTimestamp(r.w.options.Timestamps, decoded)
+		if r.filter != nil && !r.filter(data) {
+			continue
+		}
+		return data, writtenAt, pos, nil
+	}
+}
+
+// NextWithMeta is Next, but also returns the chunk's physical framing --
+// its segment, offset, verified CRC, and (if enabled) write timestamp --
+// as a ChunkMeta, for replication and debugging tools that need more than
+// the payload. See ChunkMeta's doc comment for why it has no Flags field.
+func (r *Reader) NextWithMeta() (data []byte, meta ChunkMeta, err error) {
+	for {
+		decoded, pos, crc, err := r.nextDecodedWithCRC()
+		if err != nil {
+			return nil, ChunkMeta{}, err
+		}
+		data, writtenAt := stripTimestamp(r.w.options.Timestamps, decoded)
+		if r.filter != nil && !r.filter(data) {
+			continue
+		}
+		var blockNumber int64
+		if r.w.options.BlockSize > 0 {
+			blockNumber = pos.ChunkOffset / int64(r.w.options.BlockSize)
+		}
+		return data, ChunkMeta{
+			SegmentId:   pos.SegmentId,
+			ChunkOffset: pos.ChunkOffset,
+			ChunkSize:   pos.ChunkSize,
+			Sequence:    pos.Sequence,
+			BlockNumber: blockNumber,
+			CRC:         crc,
+			WrittenAt:   writtenAt,
+		}, nil
+	}
+}
+
+// NextWithFlags is Next, but also returns the flags byte WriteWithFlags
+// reserved for the chunk. Only call it on a Reader positioned at a chunk
+// WriteWithFlags wrote; see WriteWithFlags's doc comment for why calling
+// it on one Write wrote instead misreads data's own leading byte as
+// flags.
+func (r *Reader) NextWithFlags() (data []byte, flags byte, pos *ChunkPosition, err error) {
+	raw, pos, err := r.Next()
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	return raw[1:], raw[0], pos, nil
+}
+
+// NextWithTTL is Next, but also returns the expiry WriteWithTTL reserved
+// for the chunk, regardless of whether it has already passed -- see
+// NextUnexpired to have that checked for you. Only call it on a Reader
+// positioned at a chunk WriteWithTTL wrote; see NextWithFlags's doc
+// comment for why calling it on one Write wrote instead misreads data's
+// own leading bytes as an expiry.
+func (r *Reader) NextWithTTL() (data []byte, expiresAt time.Time, pos *ChunkPosition, err error) {
+	raw, pos, err := r.Next()
+	if err != nil {
+		return nil, time.Time{}, nil, err
+	}
+	return raw[8:], time.Unix(0, int64(binary.BigEndian.Uint64(raw[:8]))), pos, nil
+}
+
+// NextUnexpired is NextWithTTL, but silently skips past any chunk whose
+// expiry has already passed instead of returning it, so a caller that
+// only ever wants live records doesn't have to filter them out itself the
+// way WAL.ReadUnexpired leaves to its own caller.
+func (r *Reader) NextUnexpired() (data []byte, expiresAt time.Time, pos *ChunkPosition, err error) {
+	for {
+		data, expiresAt, pos, err = r.NextWithTTL()
+		if err != nil || !expiresAt.Before(time.Now()) {
+			return data, expiresAt, pos, err
+		}
+	}
+}
+
+// NextIdempotent is Next, but also returns the id WriteIdempotent reserved
+// for the chunk. Only call it on a Reader positioned at a chunk
+// WriteIdempotent wrote; see NextWithFlags's doc comment for why calling it
+// on one Write wrote instead misreads data's own leading bytes as id and
+// write time.
+func (r *Reader) NextIdempotent() (data []byte, id uint64, pos *ChunkPosition, err error) {
+	raw, pos, err := r.Next()
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	data, id, _ = stripIdempotency(raw)
+	return data, id, pos, nil
+}
+
+// NextBatch returns the next atomic batch's records and positions, or
+// io.EOF once the reader has caught up with the end of the currently-
+// written data. Only call it on a Reader positioned at a chunk a Batch
+// wrote; see NextWithFlags's doc comment for why calling it on a Write or
+// WriteAll chunk instead misreads data's own leading bytes as batch
+// framing.
+//
+// A batch that never reached its commit marker -- one Batch.Commit was
+// still writing when the process crashed, a lone marker left over from an
+// empty one, or one still sitting in Batch.Prepare's in-doubt window -- is
+// skipped silently rather than returned piecemeal: its records are held
+// back and the scan moves on to whatever chunk comes after them. A batch
+// explicitly resolved with Batch.Abort is dropped the same way. See
+// WAL.InDoubtBatches to find prepared batches awaiting resolution instead
+// of silently skipping them.
+func (r *Reader) NextBatch() (records [][]byte, positions []*ChunkPosition, err error) {
+	if r.batchPending == nil {
+		r.batchPending = make(map[uint64]*pendingBatch)
+	}
+	for {
+		raw, pos, err := r.Next()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		id, flag, data := decodeBatchFrame(raw)
+		switch flag {
+		case batchFlagPrepare:
+			// Metadata only, not a terminal state: the records gathered
+			// under id so far stay pending until a later commit or abort
+			// marker resolves them.
+		case batchFlagAbort:
+			delete(r.batchPending, id)
+		case batchFlagCommit:
+			if batch, ok := r.batchPending[id]; ok {
+				delete(r.batchPending, id)
+				return batch.records, batch.positions, nil
+			}
+			// A lone marker: nothing accumulated under id, keep scanning.
+		default: // batchFlagRecord
+			batch := r.batchPending[id]
+			if batch == nil {
+				batch = &pendingBatch{}
+				r.batchPending[id] = batch
+			}
+			batch.records = append(batch.records, data)
+			batch.positions = append(batch.positions, pos)
+		}
+	}
+}
+
+// Progress reports how far r has advanced through the WAL, in payload
+// bytes (including each chunk's framing, excluding every segment's
+// header): readBytes is what r has consumed so far, and totalBytes is the
+// combined size of every segment r knows about as of its last advance
+// (see advanceToNextSegment). For a WAL still being written to, both
+// numbers can grow between one call and the next -- this is meant to
+// drive a percentage or ETA during a long replay (e.g. rebuilding an
+// index), not to stand in for an exact byte count: totalBytes undercounts
+// a WAL whose tail has rotated into segments r hasn't reached yet.
+func (r *Reader) Progress() (readBytes, totalBytes int64) {
+	r.w.mu.RLock()
+	defer r.w.mu.RUnlock()
+	for i, seg := range r.segments {
+		size := seg.Size()
+		totalBytes += size
+		if i < r.segIdx {
+			readBytes += size
+		}
+	}
+	readBytes += r.offset
+	return readBytes, totalBytes
+}
+
+// advanceToNextSegment moves the reader on to the segment after
+// segID -- either the next one already in r.segments, or a newer one the
+// WAL has since rotated into -- resetting the read offset. It reports
+// whether it found one.
+//
+// For a WAL opened with WithReadOnly, segmentsAfter only ever reflects
+// segments this process already knew about as of Open or the last
+// RefreshSegments call: it's never told about a segment some other
+// process created or rotated into since. So if segmentsAfter comes up
+// empty on a read-only WAL, fall back to RefreshSegments to rescan the
+// directory before giving up -- this is what lets a Reader (and Watcher
+// and TailReader, both built on it) keep tailing a WAL another process is
+// actively writing to.
+func (r *Reader) advanceToNextSegment(segID SegmentID) bool {
+	if r.segIdx+1 < len(r.segments) {
+		r.segIdx++
+		r.landAtSegmentStart()
+		return true
+	}
+	if newer := r.w.segmentsAfter(segID); len(newer) > 0 {
+		r.segments = append(r.segments, newer...)
+		r.segIdx++
+		r.landAtSegmentStart()
+		return true
+	}
+	if r.w.options.ReadOnly {
+		r.w.mu.Lock()
+		err := r.w.refreshSegmentsLocked()
+		r.w.mu.Unlock()
+		if err == nil {
+			if newer := r.w.segmentsAfter(segID); len(newer) > 0 {
+				r.segments = append(r.segments, newer...)
+				r.segIdx++
+				r.landAtSegmentStart()
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// landAtSegmentStart resets r.offset to the start of r's now-current
+// segment, along with the chain-verification state that goes with it --
+// every segment's chain starts fresh at 0, so unlike a Seek or
+// NewReaderWithStart into the middle of one, there's nothing uncertain
+// about it.
+func (r *Reader) landAtSegmentStart() {
+	r.offset = 0
+	r.prevChecksum = 0
+	r.chainUnknown = false
+}
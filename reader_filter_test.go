@@ -0,0 +1,42 @@
+package wal
+
+import (
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReaderWithFilterSkipsRejectedChunks(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-reader-filter-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	for i := 0; i < 10; i++ {
+		_, err := w.Write([]byte{byte('a' + i)})
+		require.Nil(t, err)
+	}
+
+	r := w.NewReaderWithFilter(func(data []byte) bool {
+		return data[0]%2 == 0
+	})
+
+	var got []byte
+	for {
+		data, _, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		require.Nil(t, err)
+		got = append(got, data...)
+	}
+	for _, b := range got {
+		require.Zero(t, b%2)
+	}
+	require.NotEmpty(t, got)
+}
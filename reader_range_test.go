@@ -0,0 +1,69 @@
+package wal
+
+import (
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReaderWithRangeYieldsHalfOpenRange(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-reader-range-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(64))
+	require.Nil(t, err)
+	defer w.Close()
+
+	var positions []*ChunkPosition
+	for i := 0; i < 10; i++ {
+		pos, err := w.Write([]byte{byte('a' + i)})
+		require.Nil(t, err)
+		positions = append(positions, pos)
+	}
+
+	r, err := w.NewReaderWithRange(positions[2], positions[7])
+	require.Nil(t, err)
+
+	var got []byte
+	for {
+		data, _, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		require.Nil(t, err)
+		got = append(got, data...)
+	}
+	require.Equal(t, []byte("cdefg"), got)
+}
+
+func TestReaderWithRangeNilStartAndEnd(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-reader-range-nil-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	for i := 0; i < 3; i++ {
+		_, err := w.Write([]byte{byte('a' + i)})
+		require.Nil(t, err)
+	}
+
+	r, err := w.NewReaderWithRange(nil, nil)
+	require.Nil(t, err)
+
+	var got []byte
+	for {
+		data, _, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		require.Nil(t, err)
+		got = append(got, data...)
+	}
+	require.Equal(t, []byte("abc"), got)
+}
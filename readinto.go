@@ -0,0 +1,48 @@
+package wal
+
+import (
+	"errors"
+)
+
+// ReadInto is Read, but appends the chunk's payload to buf and returns the
+// resulting slice instead of allocating a fresh one.
+//
+// This only actually avoids an allocation when the WAL has neither
+// compression nor encryption enabled: the compression codecs and the
+// encryption library this package uses don't accept a caller-owned
+// destination buffer, so those paths still allocate their own output and
+// ReadInto just appends it to buf afterwards.
+func (w *WAL) ReadInto(pos *ChunkPosition, buf []byte) ([]byte, error) {
+	if w.gcm == nil && w.keyProvider == nil && w.options.Compression == CompressionNone {
+		return w.readRawInto(pos, buf)
+	}
+	data, err := w.Read(pos)
+	if err != nil {
+		return nil, err
+	}
+	return append(buf, data...), nil
+}
+
+func (w *WAL) readRawInto(pos *ChunkPosition, buf []byte) ([]byte, error) {
+	w.mu.RLock()
+	seg := w.segmentByID(pos.SegmentId)
+	// acquire has to happen under the same RLock that resolved seg: see the
+	// same comment on Read.
+	acquired := seg != nil && seg.acquire()
+	w.mu.RUnlock()
+	if !acquired {
+		return nil, errSegmentNotFound(pos.SegmentId)
+	}
+	defer seg.release()
+
+	data, err := seg.ReadInto(pos.ChunkOffset, buf)
+	if err != nil {
+		if errors.Is(err, ErrInvalidCRC) {
+			w.options.MetricsCollector.IncCRCErrors()
+		} else {
+			w.options.MetricsCollector.IncIOErrors()
+		}
+		return nil, err
+	}
+	return data, nil
+}
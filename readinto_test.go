@@ -0,0 +1,48 @@
+package wal
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadIntoAppendsToBuffer(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-readinto-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	pos, err := w.Write([]byte("hello"))
+	require.Nil(t, err)
+
+	buf := make([]byte, 0, 64)
+	buf, err = w.ReadInto(pos, buf)
+	require.Nil(t, err)
+	require.Equal(t, []byte("hello"), buf)
+
+	prefixed := append([]byte("prefix:"), buf...)
+	buf2, err := w.ReadInto(pos, []byte("prefix:"))
+	require.Nil(t, err)
+	require.Equal(t, prefixed, buf2)
+}
+
+func TestReadIntoWithCompressionFallsBackButStillAppends(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-readinto-compressed-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithCompression(CompressionSnappy))
+	require.Nil(t, err)
+	defer w.Close()
+
+	pos, err := w.Write([]byte("hello"))
+	require.Nil(t, err)
+
+	buf, err := w.ReadInto(pos, []byte("prefix:"))
+	require.Nil(t, err)
+	require.Equal(t, []byte("prefix:hello"), buf)
+}
@@ -0,0 +1,77 @@
+package wal
+
+import (
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenReadOnlyErrorsOnEmptyDir(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-readonly-empty-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	_, err = Open(WithDirPath(dir), WithReadOnly(true))
+	require.NotNil(t, err)
+}
+
+func TestOpenReadOnlyReadsAlongsideWriter(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-readonly-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	writer, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer writer.Close()
+
+	pos, err := writer.Write([]byte("hello"))
+	require.Nil(t, err)
+	_, err = writer.Sync()
+	require.Nil(t, err)
+
+	reader, err := Open(WithDirPath(dir), WithReadOnly(true))
+	require.Nil(t, err)
+	defer reader.Close()
+
+	data, err := reader.Read(pos)
+	require.Nil(t, err)
+	require.Equal(t, []byte("hello"), data)
+
+	r := reader.NewReader()
+	_, _, err = r.Next()
+	require.Nil(t, err)
+	_, _, err = r.Next()
+	require.Equal(t, io.EOF, err)
+}
+
+func TestOpenReadOnlyRejectsMutation(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-readonly-mutate-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	pos, err := w.Write([]byte("hello"))
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+
+	ro, err := Open(WithDirPath(dir), WithReadOnly(true))
+	require.Nil(t, err)
+	defer ro.Close()
+
+	_, err = ro.Write([]byte("nope"))
+	require.Equal(t, ErrReadOnly, err)
+
+	_, err = ro.WriteAll()
+	require.Equal(t, ErrReadOnly, err)
+
+	_, err = ro.Sync()
+	require.Equal(t, ErrReadOnly, err)
+	require.Equal(t, ErrReadOnly, ro.TruncateAfter(pos))
+	require.Equal(t, ErrReadOnly, ro.TruncateBefore(pos))
+
+	_, err = ro.Repair()
+	require.Equal(t, ErrReadOnly, err)
+}
@@ -0,0 +1,68 @@
+package wal
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecyclePipelineDefaultsToDisabled(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-recycle-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"), WithSegmentPreallocate(1))
+	require.Nil(t, err)
+	defer w.Close()
+
+	assert.Nil(t, w.recyclePipeline(), "RecycleSegments defaults to false")
+}
+
+func TestRecyclePipelineUsesBackgroundPipelineWhenEnabled(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-recycle-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"), WithSegmentPreallocate(1), WithRecycleSegments(true))
+	require.Nil(t, err)
+	defer w.Close()
+
+	assert.Same(t, w.pipeline, w.recyclePipeline())
+}
+
+func TestRecycleSegmentsWithoutPipelineIsANoop(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-recycle-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"), WithRecycleSegments(true))
+	require.Nil(t, err)
+	defer w.Close()
+
+	assert.Nil(t, w.recyclePipeline(), "RecycleSegments has no pipeline to hand a retired segment to without SegmentPreallocate")
+}
+
+func TestTruncateBeforeRecyclesRetiredSegment(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-recycle-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"), WithSegmentSize(64), WithSegmentPreallocate(1), WithRecycleSegments(true))
+	require.Nil(t, err)
+	defer w.Close()
+
+	var last *ChunkPosition
+	for i := 0; i < 20; i++ {
+		last, err = w.Write([]byte{byte(i)})
+		require.Nil(t, err)
+	}
+	require.Greater(t, last.SegmentId, SegmentID(1), "enough writes to have rotated past segment 1")
+
+	retiredPath := segmentFileName(dir, ".SEG", 1, nil)
+	require.Nil(t, w.TruncateBefore(last))
+
+	_, err = os.Stat(retiredPath)
+	assert.True(t, os.IsNotExist(err), "segment 1's original path should no longer exist once retired")
+}
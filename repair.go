@@ -0,0 +1,320 @@
+package wal
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ErrCorruptMidSegment is returned by Repair when the first unreadable
+// chunk it finds is not a torn tail write: a well-formed-looking chunk
+// header follows it, which means the corruption sits inside the segment
+// rather than in an incomplete append at the very end. Repair refuses to
+// truncate past this point, since doing so would silently drop records
+// that otherwise look intact, unless it was called with RecoverSkipCorrupt.
+// Callers that get ErrCorruptMidSegment should consider rebuilding the log
+// from a checkpoint or a replica instead of repairing it further.
+var ErrCorruptMidSegment = errors.New("wal: corruption found mid-segment, refusing to truncate past it")
+
+// RecoveryMode controls how Open and Repair react to corruption found
+// while scanning a segment.
+type RecoveryMode byte
+
+const (
+	// RecoverStrict is the default: a corrupt or torn chunk makes Open
+	// return an error rather than attempting any repair itself. Options.
+	// RepairOnOpen is equivalent to RecoverTail when RecoveryMode is left
+	// at this default.
+	RecoverStrict RecoveryMode = iota
+
+	// RecoverTail truncates a torn tail write on the active segment -- a
+	// header with no payload, or a payload cut short, the ordinary result
+	// of a crash mid-append -- the same way WithRepairOnOpen has always
+	// worked. Open only checks the active segment's last chunk to decide
+	// whether to do this (see openSegmentFile's verifyTail), so corruption
+	// further back surfaces as whatever read error found it rather than
+	// being repaired.
+	RecoverTail
+
+	// RecoverSkipCorrupt extends RecoverTail to also tolerate corruption
+	// anywhere in the active segment, not just its last chunk: Open scans
+	// the whole segment up front, and for any chunk whose checksum no
+	// longer matches its payload, rewrites just that checksum to match
+	// whatever payload is actually on disk and keeps going, rather than
+	// dropping or shifting anything -- which would invalidate every
+	// ChunkPosition recorded past that point. The record's original
+	// content is gone either way; this only buys back the ability to open
+	// the log and read everything around it.
+	RecoverSkipCorrupt
+)
+
+// effectiveRecoveryMode resolves RecoveryMode and the older, boolean
+// RepairOnOpen into the single mode Open and Repair act on: an explicit
+// RecoveryMode takes precedence, and RepairOnOpen is equivalent to
+// RecoverTail when RecoveryMode was left at its RecoverStrict default.
+func effectiveRecoveryMode(o Options) RecoveryMode {
+	if o.RecoveryMode != RecoverStrict {
+		return o.RecoveryMode
+	}
+	if o.RepairOnOpen {
+		return RecoverTail
+	}
+	return RecoverStrict
+}
+
+// Repair scans the last segment file in dirPath forward, chunk by chunk,
+// for the first chunk that fails to decode because of a CRC mismatch, a
+// short read, or a length that would run past the end of the file. If
+// nothing decodable follows that chunk, it is a torn tail write: Repair
+// truncates the segment there and returns the number of bytes it dropped.
+// If a decodable chunk is found after it, Repair returns
+// ErrCorruptMidSegment and leaves the file untouched -- unless it was
+// called with WithRecoveryMode(RecoverSkipCorrupt), in which case it
+// patches that chunk's checksum in place and keeps going instead.
+//
+// Repair operates on a closed WAL; call it before Open, or set
+// WithRepairOnOpen or WithRecoveryMode so Open calls it automatically
+// when the last segment fails to load.
+func Repair(dirPath string, opts ...Option) (truncatedBytes int64, err error) {
+	options := DefaultOptions
+	options.DirPath = dirPath
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	path, segID, err := lastSegmentFile(options.DirPath, options.SegmentFileExt, options.SegmentFileNameParseFunc)
+	if err != nil {
+		return 0, err
+	}
+	if path == "" {
+		return 0, nil
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR, 0o644)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	// Read the segment's own recorded checksum algorithm rather than
+	// trusting options.Checksum: Repair runs against the file directly, so
+	// it should verify chunks the same way the segment was actually
+	// written, regardless of what the caller's options say.
+	_, checksum, _, _, _, chained, err := readSegmentHeader(f)
+	if err != nil {
+		return 0, err
+	}
+
+	goodSize, err := repairScan(f, info.Size(), checksum, chained, effectiveRecoveryMode(options), segID)
+	if err != nil {
+		return 0, err
+	}
+
+	dropped := info.Size() - goodSize
+	if dropped == 0 {
+		return 0, nil
+	}
+	if err := f.Truncate(goodSize); err != nil {
+		return 0, err
+	}
+	options.MetricsCollector.AddBytesDropped(dropped)
+	options.Logger.Warn("wal: repair truncated a torn tail write", "segment", segID, "bytesDropped", dropped)
+	return dropped, nil
+}
+
+// Repair scans w's last segment for a torn tail write, truncates it if one
+// is found, and reopens the segment for appends. It reports how many bytes
+// were dropped. Callers should stop writing to w until Repair returns.
+//
+// See the package-level Repair for the scanning and error semantics.
+func (w *WAL) Repair() (truncatedBytes int64, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.options.ReadOnly {
+		return 0, ErrReadOnly
+	}
+
+	activeID := w.activeSegment.id
+	if err := w.activeSegment.Close(); err != nil {
+		return 0, err
+	}
+
+	dropped, err := Repair(w.options.DirPath,
+		WithSegmentFileExt(w.options.SegmentFileExt),
+		WithSegmentFileNaming(w.options.SegmentFileNameFunc, w.options.SegmentFileNameParseFunc),
+		WithMetricsCollector(w.options.MetricsCollector),
+		WithLogger(w.options.Logger),
+		WithRecoveryMode(effectiveRecoveryMode(w.options)),
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	seg, err := openSegmentFile(w.options.FS, w.options.DirPath, w.options.SegmentFileExt, activeID, w.options.Mode, false, false, w.options.DirectIO, w.options.Fdatasync, w.options.Preallocate, w.options.Checksum, !w.options.SkipReadVerification, w.options.ChainChecksums, w.options.Compression, w.dictID, w.options.BlockSize, w.options.ChunkIndex, w.options.SegmentSigningKey, w.options.SegmentSize, nil, w.options.Clock, w.options.SegmentFileNameFunc, w.options.SegmentWriteBufferSize)
+	if err != nil {
+		return dropped, err
+	}
+	w.activeSegment = seg
+	w.publishSegmentsLocked()
+
+	if dropped > 0 {
+		w.options.MetricsCollector.IncIOErrors()
+	}
+	return dropped, nil
+}
+
+// lastSegmentFile returns the path and ID of the highest-numbered segment
+// file in dir, or path == "" if dir contains none. parseFunc, if non-nil,
+// is Options.SegmentFileNameParseFunc; otherwise an entry is a segment
+// file if it ends in ext and the rest of its name parses as the built-in
+// zero-padded-number scheme.
+func lastSegmentFile(dir, ext string, parseFunc func(string) (SegmentID, bool)) (path string, id SegmentID, err error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", 0, err
+	}
+
+	highest := -1
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		var segID int
+		if parseFunc != nil {
+			parsedID, ok := parseFunc(e.Name())
+			if !ok {
+				continue
+			}
+			segID = int(parsedID)
+		} else {
+			if filepath.Ext(e.Name()) != ext {
+				continue
+			}
+			if _, err := fmt.Sscanf(filepath.Base(e.Name()), "%d"+ext, &segID); err != nil {
+				continue
+			}
+		}
+		if segID > highest {
+			highest = segID
+			path = filepath.Join(dir, e.Name())
+			id = SegmentID(segID)
+		}
+	}
+	return path, id, nil
+}
+
+// repairScan walks f's chunks, starting just after the segment header, and
+// returns the file offset up to which every chunk decoded cleanly. fileSize
+// is f's total size, passed in so repairScan can bound-check a chunk's
+// length field against it before trusting it with an allocation. checksum
+// is the algorithm the segment was created with; chained is
+// Options.ChainChecksums, also read off the segment's own header; see
+// readSegmentHeader. mode controls what happens on a checksum mismatch
+// found mid-segment: see RecoverSkipCorrupt, which chained rules out --
+// see the check below. segID identifies the segment f belongs to, for the
+// ErrCorrupted that wraps ErrCorruptMidSegment.
+func repairScan(f *os.File, fileSize int64, checksum Checksum, chained bool, mode RecoveryMode, segID SegmentID) (int64, error) {
+	r := io.NewSectionReader(f, segmentHeaderSize, 1<<62)
+	header := make([]byte, chunkHeaderSize)
+
+	var offset int64
+	var prevChecksum uint32
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				// Clean EOF, or too few bytes left for another header:
+				// either way there's nothing left to repair.
+				return segmentHeaderSize + offset, nil
+			}
+			return 0, err
+		}
+
+		length := binary.BigEndian.Uint32(header[:4])
+		if length == 0 {
+			// A zero-length header marks the start of unwritten,
+			// pre-allocated space (see newSegmentFile) rather than a
+			// legitimate empty chunk -- Write rejects those. Nothing past
+			// here needs repairing.
+			return segmentHeaderSize + offset, nil
+		}
+		wantChecksum := binary.BigEndian.Uint32(header[4:])
+
+		// Bound-check length against what's actually left in the file
+		// before trusting it with an allocation: a corrupted length field
+		// (e.g. a flipped bit) can otherwise read as billions of bytes and
+		// make Repair allocate wildly before io.ReadFull ever gets a
+		// chance to fail on its own.
+		remaining := fileSize - (segmentHeaderSize + offset + int64(chunkHeaderSize))
+		if int64(length) > remaining {
+			// Not enough bytes left for the declared payload: a torn tail
+			// write, same as a short read would report.
+			return segmentHeaderSize + offset, nil
+		}
+
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				// The header was flushed but the payload wasn't: a torn
+				// tail write.
+				return segmentHeaderSize + offset, nil
+			}
+			return 0, err
+		}
+
+		valid := verifyChecksum(checksum, payload, wantChecksum)
+		if chained {
+			valid = verifyChainedChecksum(checksum, prevChecksum, payload, wantChecksum)
+		}
+		if !valid {
+			if mode == RecoverSkipCorrupt {
+				if chained {
+					// Rewriting just this chunk's checksum, the way the
+					// unchained case does below, would leave every chunk
+					// chained after it mismatching a chain link that can
+					// never be reconstructed -- its predecessor's true,
+					// pre-corruption checksum is gone. There's nothing
+					// honest to patch here, so refuse rather than produce
+					// a segment whose chain looks broken from this point
+					// on for no reason a later Verify could explain.
+					return 0, &ErrCorrupted{SegmentID: segID, ChunkOffset: offset, Reason: ErrCorruptMidSegment}
+				}
+				// Rewrite just this chunk's checksum to match whatever
+				// payload is actually on disk: its original content is
+				// already gone, but leaving its length untouched keeps
+				// every later offset -- and every ChunkPosition already
+				// recorded against them -- exactly where it was.
+				fixed := make([]byte, 4)
+				binary.BigEndian.PutUint32(fixed, checksumPayload(checksum, payload))
+				if _, err := f.WriteAt(fixed, segmentHeaderSize+offset+4); err != nil {
+					return 0, err
+				}
+				offset += int64(chunkHeaderSize) + int64(length)
+				continue
+			}
+			if nextChunkHeaderLooksIntact(r) {
+				return 0, &ErrCorrupted{SegmentID: segID, ChunkOffset: offset, Reason: ErrCorruptMidSegment}
+			}
+			return segmentHeaderSize + offset, nil
+		}
+
+		prevChecksum = wantChecksum
+		offset += int64(chunkHeaderSize) + int64(length)
+	}
+}
+
+// nextChunkHeaderLooksIntact reports whether a full chunk header can still
+// be read from r, which is used to tell a torn tail write (nothing
+// readable follows) apart from mid-segment corruption (something does).
+func nextChunkHeaderLooksIntact(r *io.SectionReader) bool {
+	header := make([]byte, chunkHeaderSize)
+	n, err := io.ReadFull(r, header)
+	return err == nil && n == chunkHeaderSize
+}
@@ -0,0 +1,125 @@
+package wal
+
+import "sync"
+
+// Follower is a pluggable transport a Replicator streams chunks to. Send is
+// called once per chunk, in order; a non-nil error stops the Replicator
+// from tailing for that follower, the same way OnRecord returning an error
+// stops a Watcher.
+type Follower interface {
+	Send(chunk *RawChunk) error
+}
+
+// RawChunk is one chunk streamed to a Follower: its position, its payload
+// exactly as Read would return it, and the checksum algorithm and value it
+// was written with, so a follower can verify what it receives
+// independently instead of trusting the primary to have checked it
+// already.
+type RawChunk struct {
+	Position *ChunkPosition
+	Data     []byte
+	Algo     Checksum
+	Checksum uint32
+}
+
+// Replicator tails a live WAL and streams every chunk written to it -- past
+// and future -- to a set of registered followers, each over its own
+// Follower and resumable independently of the others. It is a thin wrapper
+// around Watcher: each registered follower gets its own Watcher under the
+// hood, so a slow or stalled follower never holds up delivery to the rest.
+//
+// This is meant for primary/standby setups: a standby registers itself as
+// a Follower, tracks the position of the last chunk it has durably applied,
+// and passes that back in as from after a restart to resume without
+// replaying what it already has.
+type Replicator struct {
+	wal *WAL
+
+	mu        sync.Mutex
+	followers map[string]*Watcher
+}
+
+// NewReplicator returns a Replicator over w. There is nothing to stream
+// until a follower is registered with Register.
+func NewReplicator(w *WAL) *Replicator {
+	return &Replicator{
+		wal:       w,
+		followers: make(map[string]*Watcher),
+	}
+}
+
+// Register starts streaming chunks to follower under name, resuming just
+// after from (or from the beginning of the log if from is nil), and begins
+// tailing in the background. Registering a name that is already
+// registered stops its existing follower first, the same as Unregister
+// would.
+func (r *Replicator) Register(name string, follower Follower, from *ChunkPosition) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if existing, ok := r.followers[name]; ok {
+		existing.Stop()
+	}
+
+	watcher := NewWatcher(r.wal, WatcherOptions{From: from}, func(pos *ChunkPosition, data []byte) error {
+		return follower.Send(&RawChunk{
+			Position: pos,
+			Data:     data,
+			Algo:     r.wal.options.Checksum,
+			Checksum: checksumPayload(r.wal.options.Checksum, data),
+		})
+	})
+	watcher.Start()
+	r.followers[name] = watcher
+}
+
+// Unregister stops streaming to the follower registered under name, if
+// any, and waits for its tailing goroutine to exit.
+func (r *Replicator) Unregister(name string) {
+	r.mu.Lock()
+	watcher, ok := r.followers[name]
+	delete(r.followers, name)
+	r.mu.Unlock()
+	if ok {
+		watcher.Stop()
+	}
+}
+
+// Position returns the position of the last chunk delivered to the
+// follower registered under name, suitable for passing as Register's from
+// argument to resume later. It reports false if name isn't registered.
+func (r *Replicator) Position(name string) (*ChunkPosition, bool) {
+	watcher, ok := r.followerLocked(name)
+	if !ok {
+		return nil, false
+	}
+	return watcher.Position(), true
+}
+
+// Err returns the error that stopped the follower registered under name,
+// if it stopped on its own rather than via Unregister; see Watcher.Err. It
+// reports false if name isn't registered.
+func (r *Replicator) Err(name string) (error, bool) {
+	watcher, ok := r.followerLocked(name)
+	if !ok {
+		return nil, false
+	}
+	return watcher.Err(), true
+}
+
+func (r *Replicator) followerLocked(name string) (*Watcher, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	watcher, ok := r.followers[name]
+	return watcher, ok
+}
+
+// Stop stops every registered follower and waits for all of them to exit.
+func (r *Replicator) Stop() {
+	r.mu.Lock()
+	followers := r.followers
+	r.followers = make(map[string]*Watcher)
+	r.mu.Unlock()
+	for _, watcher := range followers {
+		watcher.Stop()
+	}
+}
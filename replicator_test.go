@@ -0,0 +1,141 @@
+package wal
+
+import (
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeFollower is a Follower that records every RawChunk it receives, for
+// tests to assert against.
+type fakeFollower struct {
+	mu     sync.Mutex
+	chunks []*RawChunk
+}
+
+func (f *fakeFollower) Send(chunk *RawChunk) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.chunks = append(f.chunks, chunk)
+	return nil
+}
+
+func (f *fakeFollower) received() []*RawChunk {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]*RawChunk(nil), f.chunks...)
+}
+
+func TestReplicatorStreamsRawChunksToAFollower(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-replicator-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	r := NewReplicator(w)
+	follower := &fakeFollower{}
+	r.Register("standby-1", follower, nil)
+	defer r.Stop()
+
+	pos, err := w.Write([]byte("hello"))
+	require.Nil(t, err)
+
+	require.Eventually(t, func() bool {
+		return len(follower.received()) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	chunk := follower.received()[0]
+	assert.Equal(t, pos, chunk.Position)
+	assert.Equal(t, "hello", string(chunk.Data))
+	assert.Equal(t, checksumPayload(w.options.Checksum, []byte("hello")), chunk.Checksum)
+}
+
+func TestReplicatorResumesAFollowerFromItsLastPosition(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-replicator-resume-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("first"))
+	require.Nil(t, err)
+
+	r := NewReplicator(w)
+	follower := &fakeFollower{}
+	r.Register("standby-1", follower, nil)
+
+	require.Eventually(t, func() bool {
+		return len(follower.received()) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	resumeFrom, ok := r.Position("standby-1")
+	require.True(t, ok)
+	r.Unregister("standby-1")
+
+	_, err = w.Write([]byte("second"))
+	require.Nil(t, err)
+
+	resumed := &fakeFollower{}
+	r.Register("standby-1", resumed, resumeFrom)
+	defer r.Stop()
+
+	require.Eventually(t, func() bool {
+		return len(resumed.received()) == 1
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "second", string(resumed.received()[0].Data))
+}
+
+func TestReplicatorTracksMultipleFollowersIndependently(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-replicator-multi-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	r := NewReplicator(w)
+	fast := &fakeFollower{}
+	slow := &fakeFollower{
+		// Unregistered before it ever starts tailing, below, so it never
+		// receives anything; used only to confirm it doesn't affect fast.
+	}
+	r.Register("fast", fast, nil)
+	r.Register("slow", slow, nil)
+	r.Unregister("slow")
+	defer r.Stop()
+
+	_, err = w.Write([]byte("only for fast"))
+	require.Nil(t, err)
+
+	require.Eventually(t, func() bool {
+		return len(fast.received()) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	_, ok := r.Position("slow")
+	require.False(t, ok)
+	assert.Empty(t, slow.received())
+}
+
+func TestReplicatorErrReportsUnknownFollower(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-replicator-err-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	r := NewReplicator(w)
+	_, ok := r.Err("nope")
+	require.False(t, ok)
+}
@@ -0,0 +1,213 @@
+package wal
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Restore unpacks a backup Backup or BackupSince wrote at backupPath into
+// dirPath, creating it if it doesn't exist, verifying every chunk's
+// checksum as it copies and failing with an *ErrCorrupted at the first one
+// that doesn't match instead of leaving dirPath in a state Open might
+// choke on later. opts configures which files in backupPath count as
+// segments, the same way they'd configure a later Open of dirPath (see
+// WithSegmentFileExt).
+//
+// Call it once per backup taken, in the order they were taken: first with
+// the directory from a Backup (or an equivalent BackupSince(nil, ...))
+// call, which populates dirPath from scratch, then again with each
+// subsequent BackupSince directory, which only has the segments written
+// since the position that call started from (see BackupSince) -- Restore
+// copies those in full and appends their incremental fragment, if any,
+// onto the copy of its segment a previous Restore call already made.
+// Restoring an incremental backup's directory before dirPath already has
+// a full copy of the segment its fragment belongs to fails: there's
+// nothing yet for the fragment to be appended onto.
+func Restore(backupPath, dirPath string, opts ...Option) error {
+	options := DefaultOptions
+	options.DirPath = dirPath
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	entries, err := os.ReadDir(backupPath)
+	if err != nil {
+		return err
+	}
+
+	ids, paths, err := segmentPaths(osFS{}, backupPath, options.SegmentFileExt, options.SegmentFileNameParseFunc)
+	if err != nil {
+		return err
+	}
+
+	fragments := make(map[SegmentID]string)
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), incrementalSuffix) {
+			continue
+		}
+		base := strings.TrimSuffix(e.Name(), incrementalSuffix)
+		if filepath.Ext(base) != options.SegmentFileExt {
+			continue
+		}
+		var id SegmentID
+		if _, serr := fmt.Sscanf(base, "%d"+options.SegmentFileExt, &id); serr != nil {
+			continue
+		}
+		fragments[id] = filepath.Join(backupPath, e.Name())
+	}
+
+	if len(ids) == 0 && len(fragments) == 0 {
+		return fmt.Errorf("wal: no segments found in %q to restore", backupPath)
+	}
+
+	if err := os.MkdirAll(dirPath, 0o755); err != nil {
+		return err
+	}
+
+	for i, id := range ids {
+		dstPath := segmentFileName(dirPath, options.SegmentFileExt, id, options.SegmentFileNameFunc)
+		if err := restoreFullSegment(paths[i], dstPath, id); err != nil {
+			return err
+		}
+	}
+	for id, fragmentPath := range fragments {
+		dstPath := segmentFileName(dirPath, options.SegmentFileExt, id, options.SegmentFileNameFunc)
+		if err := applyFragment(fragmentPath, dstPath, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreFullSegment validates and copies one segment file wholesale from
+// a backup at srcPath into dstPath, stamping dstPath with the same mode,
+// checksum algorithm, chain-checksums setting, compression codec, and
+// block size srcPath's own header recorded.
+func restoreFullSegment(srcPath, dstPath string, id SegmentID) error {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	mode, checksum, compression, dictID, blockSize, chained, err := readSegmentHeader(src)
+	if err != nil {
+		return err
+	}
+	if _, err := src.Seek(segmentHeaderSize, io.SeekStart); err != nil {
+		return err
+	}
+
+	dst, err := os.OpenFile(dstPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if err := writeSegmentHeader(dst, mode, checksum, compression, dictID, blockSize, chained); err != nil {
+		dst.Close()
+		return err
+	}
+	if _, err := dst.Seek(segmentHeaderSize, io.SeekStart); err != nil {
+		dst.Close()
+		return err
+	}
+
+	// dst is a fresh file, so its chain (if any) starts at the same seed
+	// -- 0 -- src's did.
+	if err := copyValidatedChunks(dst, src, checksum, chained, 0, id); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := dst.Sync(); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+// applyFragment validates and appends fragmentPath -- a .inc file
+// BackupSince wrote for the segment straddling its starting position --
+// onto dstPath, which must already exist with that segment's earlier
+// bytes in place from a previous Restore call. It reads dstPath's own
+// header to learn which checksum algorithm (and chain-checksums setting)
+// to validate the fragment's chunks with, since a fragment has no header
+// of its own.
+func applyFragment(fragmentPath, dstPath string, id SegmentID) error {
+	dst, err := os.OpenFile(dstPath, os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	_, checksum, _, _, _, chained, err := readSegmentHeader(dst)
+	if err != nil {
+		return err
+	}
+
+	var prevChecksum uint32
+	if chained {
+		// dst already carries whatever a previous Restore call copied
+		// into it; the fragment's first chunk chains onto the last of
+		// those, not onto 0 the way a fresh segment's first chunk would.
+		// scanToLogicalEnd repositions dst via Seek, but dst is opened
+		// O_APPEND, so that has no effect on where the fragment's bytes
+		// actually land.
+		_, lastChunkStart, lastChecksum, _, err := scanToLogicalEnd(dst)
+		if err != nil {
+			return err
+		}
+		if lastChunkStart >= 0 {
+			prevChecksum = lastChecksum
+		}
+	}
+
+	fragment, err := os.Open(fragmentPath)
+	if err != nil {
+		return err
+	}
+	defer fragment.Close()
+
+	if err := copyValidatedChunks(dst, fragment, checksum, chained, prevChecksum, id); err != nil {
+		return err
+	}
+	return dst.Sync()
+}
+
+// copyValidatedChunks reads every chunk from src -- positioned just past
+// a segment header for a full segment, or at the start of a fragment --
+// validating its checksum and re-appending it to dst exactly as
+// appendChunk would have written it originally. chained and prev are
+// Options.ChainChecksums and the checksum chained into the first chunk
+// src has to offer -- 0 for a fresh segment, or whatever dst's existing
+// tail chunk's checksum was for a fragment continuing one. It stops
+// cleanly at the end of what src actually has, or returns an *ErrCorrupted
+// identifying id and the first corrupt or malformed chunk's offset within
+// src (for a fragment, that's relative to the fragment's own start, not to
+// the full segment it belongs to).
+func copyValidatedChunks(dst File, src io.Reader, checksum Checksum, chained bool, prev uint32, id SegmentID) error {
+	var offset int64
+	for {
+		payload, wantChecksum, err := readChunkRaw(src)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return &ErrCorrupted{SegmentID: id, ChunkOffset: offset, Reason: err}
+		}
+		valid := verifyChecksum(checksum, payload, wantChecksum)
+		if chained {
+			valid = verifyChainedChecksum(checksum, prev, payload, wantChecksum)
+		}
+		if !valid {
+			return &ErrCorrupted{SegmentID: id, ChunkOffset: offset, Reason: ErrInvalidCRC}
+		}
+		sum, err := appendChunk(dst, payload, checksum, chained, prev)
+		if err != nil {
+			return err
+		}
+		prev = sum
+		offset += int64(chunkHeaderSize) + int64(len(payload))
+	}
+}
@@ -0,0 +1,167 @@
+package wal
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRestoreFromAFullBackup(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-restore-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(64))
+	require.Nil(t, err)
+	defer w.Close()
+
+	for i := 0; i < 20; i++ {
+		_, err := w.Write(bytes.Repeat([]byte{byte('a' + i)}, 8))
+		require.Nil(t, err)
+	}
+
+	backupDir, err := os.MkdirTemp("", "wal-restore-backup")
+	require.Nil(t, err)
+	defer os.RemoveAll(backupDir)
+	_, err = w.Backup(backupDir)
+	require.Nil(t, err)
+
+	restoredDir, err := os.MkdirTemp("", "wal-restore-dst")
+	require.Nil(t, err)
+	defer os.RemoveAll(restoredDir)
+	require.Nil(t, os.RemoveAll(restoredDir))
+
+	require.Nil(t, Restore(backupDir, restoredDir))
+
+	restored, err := Open(WithDirPath(restoredDir), WithReadOnly(true))
+	require.Nil(t, err)
+	defer restored.Close()
+
+	r := restored.NewReader()
+	var count int
+	for {
+		data, _, err := r.Next()
+		if err != nil {
+			break
+		}
+		require.Equal(t, bytes.Repeat([]byte{byte('a' + count)}, 8), data)
+		count++
+	}
+	require.Equal(t, 20, count)
+}
+
+func TestRestoreAppliesAnIncrementalBackupOntoAPreviousRestore(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-restore-inc-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(64))
+	require.Nil(t, err)
+	defer w.Close()
+
+	var marker *ChunkPosition
+	for i := 0; i < 10; i++ {
+		pos, err := w.Write(bytes.Repeat([]byte{byte('a' + i)}, 8))
+		require.Nil(t, err)
+		marker = pos
+	}
+
+	fullBackupDir, err := os.MkdirTemp("", "wal-restore-inc-full")
+	require.Nil(t, err)
+	defer os.RemoveAll(fullBackupDir)
+	_, err = w.Backup(fullBackupDir)
+	require.Nil(t, err)
+
+	restoredDir, err := os.MkdirTemp("", "wal-restore-inc-dst")
+	require.Nil(t, err)
+	require.Nil(t, os.RemoveAll(restoredDir))
+	defer os.RemoveAll(restoredDir)
+	require.Nil(t, Restore(fullBackupDir, restoredDir))
+
+	for i := 10; i < 20; i++ {
+		_, err := w.Write(bytes.Repeat([]byte{byte('a' + i)}, 8))
+		require.Nil(t, err)
+	}
+
+	incBackupDir, err := os.MkdirTemp("", "wal-restore-inc-delta")
+	require.Nil(t, err)
+	defer os.RemoveAll(incBackupDir)
+	result, err := w.BackupSince(marker, incBackupDir)
+	require.Nil(t, err)
+	require.True(t, result.FragmentCopied)
+
+	require.Nil(t, Restore(incBackupDir, restoredDir))
+
+	restored, err := Open(WithDirPath(restoredDir), WithReadOnly(true))
+	require.Nil(t, err)
+	defer restored.Close()
+
+	r := restored.NewReader()
+	var count int
+	for {
+		data, _, err := r.Next()
+		if err != nil {
+			break
+		}
+		require.Equal(t, bytes.Repeat([]byte{byte('a' + count)}, 8), data)
+		count++
+	}
+	require.Equal(t, 20, count)
+}
+
+func TestRestoreDetectsCorruption(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-restore-corrupt-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	_, err = w.Write([]byte("hello"))
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+
+	backupDir, err := os.MkdirTemp("", "wal-restore-corrupt-backup")
+	require.Nil(t, err)
+	defer os.RemoveAll(backupDir)
+
+	w2, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	_, err = w2.Backup(backupDir)
+	require.Nil(t, err)
+	require.Nil(t, w2.Close())
+
+	_, paths, err := segmentPaths(osFS{}, backupDir, DefaultOptions.SegmentFileExt, nil)
+	require.Nil(t, err)
+	require.Len(t, paths, 1)
+	f, err := os.OpenFile(paths[0], os.O_RDWR, 0o644)
+	require.Nil(t, err)
+	_, err = f.WriteAt([]byte{0xFF}, segmentHeaderSize+chunkHeaderSize)
+	require.Nil(t, err)
+	require.Nil(t, f.Close())
+
+	restoredDir, err := os.MkdirTemp("", "wal-restore-corrupt-dst")
+	require.Nil(t, err)
+	defer os.RemoveAll(restoredDir)
+	require.Nil(t, os.RemoveAll(restoredDir))
+
+	err = Restore(backupDir, restoredDir)
+	var corrupted *ErrCorrupted
+	require.True(t, errors.As(err, &corrupted))
+}
+
+func TestRestoreNoSegments(t *testing.T) {
+	backupDir, err := os.MkdirTemp("", "wal-restore-empty-backup")
+	require.Nil(t, err)
+	defer os.RemoveAll(backupDir)
+
+	restoredDir, err := os.MkdirTemp("", "wal-restore-empty-dst")
+	require.Nil(t, err)
+	defer os.RemoveAll(restoredDir)
+	require.Nil(t, os.RemoveAll(restoredDir))
+
+	err = Restore(backupDir, restoredDir)
+	require.NotNil(t, err)
+}
@@ -0,0 +1,61 @@
+package wal
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaxSegmentAgeRemovesOldSealedSegments(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-retention-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(32), WithMaxSegmentAge(20*time.Millisecond))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("first-segment"))
+	require.Nil(t, err)
+
+	time.Sleep(40 * time.Millisecond)
+
+	// Rotation (triggered by this write exceeding SegmentSize) should sweep
+	// away the now-stale first segment.
+	_, err = w.Write([]byte("second-segment-forces-rotation"))
+	require.Nil(t, err)
+
+	w.mu.RLock()
+	numOlder := len(w.olderSegments)
+	w.mu.RUnlock()
+	require.Equal(t, 0, numOlder)
+}
+
+func TestMaxTotalSizeRemovesOldestSealedSegments(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-retention-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(32), WithMaxTotalSize(40))
+	require.Nil(t, err)
+	defer w.Close()
+
+	for i := 0; i < 6; i++ {
+		_, err = w.Write([]byte("0123456789"))
+		require.Nil(t, err)
+	}
+
+	w.mu.RLock()
+	total := w.activeSegment.Size()
+	for _, seg := range w.olderSegments {
+		total += seg.Size()
+	}
+	w.mu.RUnlock()
+	// Retention is enforced as each segment rotates, before the new active
+	// segment has taken on any data of its own, so the final total can
+	// exceed MaxTotalSize by up to one more segment's worth of growth in
+	// the (never-evicted) active segment.
+	require.LessOrEqual(t, total, int64(40+32))
+}
@@ -0,0 +1,91 @@
+package wal
+
+import "io"
+
+// ReverseReader iterates a WAL's chunks newest to oldest, across all of its
+// segments. Obtain one with WAL.NewReverseReader.
+//
+// Unlike Reader, which only ever needs to look forward, ReverseReader has
+// to know a segment's full set of chunk positions before it can hand back
+// its last one, since chunks aren't framed with anything that would let a
+// reader walk backwards from the end of a file. It pays for that by
+// scanning a segment's raw headers once, the first time iteration reaches
+// it, and holding the resulting positions in memory until it moves on to
+// the next one.
+type ReverseReader struct {
+	w          *WAL
+	segments   []*segment
+	cumulative []uint64 // cumulative[i] is the number of chunks in segments[:i], for Sequence
+	segIdx     int
+	positions  []*ChunkPosition
+	idx        int
+}
+
+// NewReverseReader returns a ReverseReader starting from the last chunk
+// currently in the WAL.
+func (w *WAL) NewReverseReader() *ReverseReader {
+	segments := w.orderedSegments()
+	cumulative := make([]uint64, len(segments))
+	var total uint64
+	for i, seg := range segments {
+		cumulative[i] = total
+		total += uint64(seg.chunkCountSnapshot())
+	}
+	return &ReverseReader{w: w, segments: segments, cumulative: cumulative, segIdx: len(segments) - 1, idx: -1}
+}
+
+// Next returns the next chunk's data and position, walking backwards from
+// the newest chunk, or io.EOF once it has reached the first chunk in the
+// WAL.
+func (r *ReverseReader) Next() ([]byte, *ChunkPosition, error) {
+	for {
+		if r.idx < 0 {
+			if r.segIdx < 0 {
+				return nil, nil, io.EOF
+			}
+			positions, err := positionsInSegment(r.segments[r.segIdx], r.cumulative[r.segIdx])
+			if err != nil {
+				return nil, nil, err
+			}
+			r.positions = positions
+			r.idx = len(positions) - 1
+			r.segIdx--
+			continue
+		}
+
+		pos := r.positions[r.idx]
+		r.idx--
+		data, err := r.w.Read(pos)
+		if err != nil {
+			return nil, nil, err
+		}
+		return data, pos, nil
+	}
+}
+
+// positionsInSegment returns the position of every chunk currently in seg,
+// oldest first, with Sequence continuing on from precedingChunks (the
+// number of chunks in every segment before it).
+func positionsInSegment(seg *segment, precedingChunks uint64) ([]*ChunkPosition, error) {
+	var positions []*ChunkPosition
+	var offset int64
+	seq := precedingChunks
+	for {
+		data, err := seg.Read(offset)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		positions = append(positions, &ChunkPosition{
+			SegmentId:   seg.id,
+			ChunkOffset: offset,
+			ChunkSize:   uint32(len(data)),
+			Sequence:    seq,
+		})
+		seq++
+		offset += int64(chunkHeaderSize) + int64(len(data))
+	}
+	return positions, nil
+}
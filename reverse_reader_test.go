@@ -0,0 +1,77 @@
+package wal
+
+import (
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReverseReaderIteratesNewestToOldest(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-reverse-reader-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(64))
+	require.Nil(t, err)
+	defer w.Close()
+
+	for i := 0; i < 10; i++ {
+		_, err := w.Write([]byte{byte('a' + i)})
+		require.Nil(t, err)
+	}
+
+	r := w.NewReverseReader()
+	var got []byte
+	for {
+		data, _, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		require.Nil(t, err)
+		got = append(got, data...)
+	}
+	require.Equal(t, []byte("jihgfedcba"), got)
+}
+
+func TestReverseReaderReportsSequenceDescending(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-reverse-reader-seq-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(64))
+	require.Nil(t, err)
+	defer w.Close()
+
+	for i := 0; i < 10; i++ {
+		_, err := w.Write([]byte{byte('a' + i)})
+		require.Nil(t, err)
+	}
+
+	r := w.NewReverseReader()
+	var seqs []uint64
+	for {
+		_, pos, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		require.Nil(t, err)
+		seqs = append(seqs, pos.Sequence)
+	}
+	require.Equal(t, []uint64{9, 8, 7, 6, 5, 4, 3, 2, 1, 0}, seqs)
+}
+
+func TestReverseReaderOnEmptyWAL(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-reverse-reader-empty-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	r := w.NewReverseReader()
+	_, _, err = r.Next()
+	require.Equal(t, io.EOF, err)
+}
@@ -0,0 +1,180 @@
+package wal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ErrKeyringRequired is returned by RewriteWithKey when the WAL wasn't
+// opened with Options.Keyring or Options.KeyProvider -- there's no notion
+// of a "current key" to rewrite onto without one.
+var ErrKeyringRequired = fmt.Errorf("wal: RewriteWithKey requires Options.Keyring or Options.KeyProvider")
+
+// RewriteWithKey re-encrypts every sealed segment's chunks under the
+// KeyProvider's (or Options.Keyring's) current key, so a key retired from a
+// later WithKeyring or KeyProvider call -- dropped from the map, or
+// superseded by one CurrentKey now returns instead -- can safely be
+// forgotten once every segment encrypted under it has been rewritten. Every
+// chunk survives; only its ciphertext and recorded key ID change.
+//
+// It follows the same shape as Compact -- rewriting sealed segments in
+// place under the SegmentId they already have, returning how every
+// chunk's position changed keyed by its old position so a caller's own
+// index can be updated to match -- and shares its constraints: only
+// sealed segments are touched (the active segment is still being
+// appended to under whatever key was current when it was created), and a
+// second RewriteWithKey, Compact, or Checkpoint call is serialized
+// against this one by checkpointMu.
+//
+// It returns ErrKeyringRequired if the WAL wasn't opened with
+// Options.Keyring or Options.KeyProvider, and ErrReadOnly for a read-only
+// WAL.
+func (w *WAL) RewriteWithKey() (map[ChunkPosition]*ChunkPosition, error) {
+	if w.options.ReadOnly {
+		return nil, ErrReadOnly
+	}
+	if w.keyProvider == nil {
+		return nil, ErrKeyringRequired
+	}
+
+	w.checkpointMu.Lock()
+	defer w.checkpointMu.Unlock()
+
+	w.mu.RLock()
+	sealed := w.sealedSegmentsLocked()
+	w.mu.RUnlock()
+	if len(sealed) == 0 {
+		return map[ChunkPosition]*ChunkPosition{}, nil
+	}
+
+	remap := make(map[ChunkPosition]*ChunkPosition)
+	replacements := make(map[SegmentID]*segment, len(sealed))
+	originals := make(map[SegmentID]*segment, len(sealed))
+	originalPaths := make(map[SegmentID]string, len(sealed))
+
+	var preceding uint64
+	for _, seg := range sealed {
+		chunkCount := seg.chunkCountSnapshot()
+		originals[seg.id] = seg
+		originalPaths[seg.id] = seg.path
+		newSeg, err := rekeySegment(w, seg, preceding, remap)
+		if err != nil {
+			rollbackCompact(replacements, originals, originalPaths)
+			return nil, err
+		}
+		replacements[seg.id] = newSeg
+		preceding += uint64(chunkCount)
+	}
+
+	w.mu.Lock()
+	for id, newSeg := range replacements {
+		old := w.olderSegments[id]
+		old.retire(w.recyclePipeline())
+		w.olderSegments[id] = newSeg
+	}
+	w.options.MetricsCollector.SetActiveSegments(len(w.olderSegments) + 1)
+	w.publishSegmentsLocked()
+	w.mu.Unlock()
+
+	return remap, nil
+}
+
+// rekeyedChunk is one chunk rekeySegment has decrypted with its old key
+// and re-encrypted with the keyring's current active key.
+type rekeyedChunk struct {
+	oldPos  *ChunkPosition
+	payload []byte
+}
+
+// rekeySegment rewrites one sealed segment, re-encrypting every chunk's
+// payload while leaving its chunk count, order, and Sequence numbering
+// untouched. It follows the same rename-aside-then-replace approach
+// compactSegment does, and for the same reason: seg's own already-open
+// fd keeps serving any read already in flight against it (see
+// segment.retire) while seg.path is updated to point at the aside file,
+// so retire's eventual cleanup removes the old content rather than the
+// replacement that has taken its place.
+func rekeySegment(w *WAL, seg *segment, precedingChunks uint64, remap map[ChunkPosition]*ChunkPosition) (*segment, error) {
+	var chunks []rekeyedChunk
+	var offset int64
+	seq := precedingChunks
+	for {
+		data, err := seg.Read(offset)
+		if err == ErrInvalidCRC {
+			return nil, err
+		}
+		if err != nil {
+			break // io.EOF or io.ErrUnexpectedEOF: nothing more to read
+		}
+		oldPos := &ChunkPosition{SegmentId: seg.id, ChunkOffset: offset, ChunkSize: uint32(len(data)), Sequence: seq}
+		decrypted, err := w.decryptChunk(data)
+		if err != nil {
+			return nil, err
+		}
+		rekeyed, err := w.encryptChunk(decrypted)
+		if err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, rekeyedChunk{oldPos: oldPos, payload: rekeyed})
+		seq++
+		offset += int64(chunkHeaderSize) + int64(len(data))
+	}
+
+	originalPath := seg.path
+	stalePath := originalPath + ".stale"
+	if err := os.Rename(originalPath, stalePath); err != nil {
+		return nil, err
+	}
+	seg.path = stalePath
+
+	newSeg, err := buildRekeyedSegment(w, seg.id, chunks, remap)
+	if err != nil {
+		os.Rename(stalePath, originalPath)
+		seg.path = originalPath
+		return nil, err
+	}
+	return newSeg, nil
+}
+
+// buildRekeyedSegment writes chunks' re-encrypted payloads into a brand
+// new segment file and renames it into segID's now-vacated canonical
+// path, preserving every chunk's original Sequence and recording its
+// (possibly unchanged) new position in remap.
+func buildRekeyedSegment(w *WAL, segID SegmentID, chunks []rekeyedChunk, remap map[ChunkPosition]*ChunkPosition) (newSeg *segment, err error) {
+	tmpPath := filepath.Join(w.options.DirPath, fmt.Sprintf("rekey-%d%s", segID, w.options.SegmentFileExt))
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	newSeg, err = newSegmentFile(tmp, w.options.DirPath, w.options.SegmentFileExt, segID, w.options.Mode, w.options.Fdatasync, w.options.Checksum, !w.options.SkipReadVerification, w.options.ChainChecksums, w.options.Compression, w.dictID, w.options.BlockSize, w.options.ChunkIndex, w.options.SegmentSigningKey, w.options.Clock, w.options.SegmentFileNameFunc, w.options.SegmentWriteBufferSize)
+	if err != nil {
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			newSeg.Close()
+			os.Remove(newSeg.path)
+			newSeg = nil
+		}
+	}()
+
+	for _, c := range chunks {
+		var newPos *ChunkPosition
+		if newPos, err = newSeg.Write(c.payload); err != nil {
+			return nil, err
+		}
+		w.rewrittenBytes.Add(uint64(chunkHeaderSize + len(c.payload)))
+		newPos.Sequence = c.oldPos.Sequence
+		newSeg.recordSequence(newPos.Sequence, newPos.ChunkOffset)
+		remap[*c.oldPos] = newPos
+	}
+	if err = newSeg.Sync(); err != nil {
+		return nil, err
+	}
+	if err = newSeg.seal(); err != nil {
+		return nil, err
+	}
+	return newSeg, nil
+}
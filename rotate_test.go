@@ -0,0 +1,83 @@
+package wal
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithOnRotateFiresWithBothSegmentIDs(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-onrotate-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	type rotation struct{ oldID, newID SegmentID }
+	var rotations []rotation
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(32), WithOnRotate(func(oldID, newID SegmentID) {
+		rotations = append(rotations, rotation{oldID, newID})
+	}))
+	require.Nil(t, err)
+	defer w.Close()
+
+	for i := 0; i < 6; i++ {
+		_, err := w.Write([]byte("0123456789"))
+		require.Nil(t, err)
+	}
+
+	require.NotEmpty(t, rotations)
+	for _, r := range rotations {
+		require.Equal(t, r.oldID+1, r.newID)
+	}
+}
+
+func TestRotateSealsRegardlessOfSize(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-rotate-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	pos, err := w.Write([]byte("hello"))
+	require.Nil(t, err)
+
+	newID, err := w.Rotate()
+	require.Nil(t, err)
+	assert.Equal(t, pos.SegmentId+1, newID)
+	assert.Equal(t, newID, w.activeSegment.id)
+	_, ok := w.olderSegments[pos.SegmentId]
+	assert.True(t, ok)
+
+	// The newly active segment is a clean cut point: nothing has been
+	// written to it yet.
+	assert.EqualValues(t, 0, w.activeSegment.Size())
+
+	data, err := w.Read(pos)
+	require.Nil(t, err)
+	assert.Equal(t, []byte("hello"), data)
+}
+
+func TestRotateDrainsPipelinedQueueFirst(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-rotate-pipelined-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithPipelined(true))
+	require.Nil(t, err)
+	defer w.Close()
+
+	pos, err := w.Write([]byte("hello"))
+	require.Nil(t, err)
+
+	newID, err := w.Rotate()
+	require.Nil(t, err)
+	assert.Equal(t, pos.SegmentId+1, newID)
+
+	data, err := w.Read(pos)
+	require.Nil(t, err)
+	assert.Equal(t, []byte("hello"), data)
+}
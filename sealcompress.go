@@ -0,0 +1,169 @@
+package wal
+
+import (
+	"fmt"
+	"os"
+)
+
+// sealedCompressionExt is appended to a sealed segment's on-disk filename
+// by compressSegment, marking that the file holds the whole original
+// segment file's bytes compressed as one unit under
+// Options.SealedSegmentCompression, rather than the chunk-framed format
+// segment.Read expects -- so it's never mistaken for an ordinary segment
+// file by a directory scan.
+const sealedCompressionExt = ".zst"
+
+// compressedSegmentPath returns where compressSegment writes id's
+// compressed file, alongside its original in Options.DirPath.
+func compressedSegmentPath(w *WAL, id SegmentID) string {
+	return segmentFileName(w.options.DirPath, w.options.SegmentFileExt, id, w.options.SegmentFileNameFunc) + sealedCompressionExt
+}
+
+// CompressSegments rewrites every sealed segment not yet compressed to a
+// single Options.SealedSegmentCompression-compressed file, replacing its
+// original: unlike Compression, which (if set at all) already compresses
+// each chunk's payload individually as Write appends it, this compresses
+// a sealed segment's entire file as one unit, once it's done changing,
+// typically shrinking it further still. A Read against a compressed
+// segment still works afterward -- see fetchCompressedSegment -- just
+// slower, and only once it's been decompressed back into
+// Options.SealedSegmentCompressionCacheDir.
+//
+// Like TierSegments, CompressSegments only runs when called, not
+// automatically on rotation: compressing a whole segment file is more CPU
+// work than rotateIfNeeded's callers expect Write to do inline. A caller
+// wanting this on a schedule should call CompressSegments periodically
+// itself. It is serialized against Compact, Checkpoint, and TierSegments
+// by checkpointMu, since all four change what's in w.olderSegments.
+//
+// CompressSegments is a no-op, returning (0, nil), if
+// Options.SealedSegmentCompression is not set. It returns the number of
+// segments it successfully compressed, stopping and returning whatever
+// error it hit as soon as one compression or local removal fails, leaving
+// every segment it hasn't reached yet alone.
+func (w *WAL) CompressSegments() (int, error) {
+	if w.options.SealedSegmentCompression == CompressionNone {
+		return 0, nil
+	}
+
+	w.checkpointMu.Lock()
+	defer w.checkpointMu.Unlock()
+
+	w.mu.RLock()
+	var candidates []*segment
+	for id, seg := range w.olderSegments {
+		if _, tiered := w.tieredSegments[id]; tiered {
+			continue
+		}
+		if _, compressed := w.compressedSegments[id]; compressed {
+			continue
+		}
+		candidates = append(candidates, seg)
+	}
+	w.mu.RUnlock()
+
+	var done int
+	for _, seg := range candidates {
+		ok, err := w.compressSegment(seg)
+		if err != nil {
+			return done, err
+		}
+		if ok {
+			done++
+		}
+	}
+	return done, nil
+}
+
+// compressSegment reads seg's whole file, compresses it with
+// Options.SealedSegmentCompression, and, if that succeeds, writes the
+// result alongside seg's original file, removes seg from w.olderSegments,
+// deletes the original, and records its size in w.compressedSegments so a
+// later Read still knows to look for it there. It reports false, with a
+// nil error, if seg is pinned by an in-flight Read or Reader.Next under
+// Options.PinnedSegmentPolicy's PinnedSegmentSkip -- see
+// resolvePinnedSegmentLocked -- since removing its original file out from
+// under that read would be exactly what pinning exists to prevent.
+func (w *WAL) compressSegment(seg *segment) (bool, error) {
+	raw, err := os.ReadFile(seg.path)
+	if err != nil {
+		return false, err
+	}
+	compressed, err := compressPayload(w.options.SealedSegmentCompression, raw, nil, nil)
+	if err != nil {
+		return false, err
+	}
+
+	compressedPath := compressedSegmentPath(w, seg.id)
+	if err := os.WriteFile(compressedPath, compressed, 0o644); err != nil {
+		return false, err
+	}
+	if err := w.options.FS.SyncDir(w.options.DirPath); err != nil {
+		os.Remove(compressedPath)
+		return false, err
+	}
+
+	w.mu.Lock()
+	retire, err := w.resolvePinnedSegmentLocked(seg)
+	if err != nil || !retire {
+		w.mu.Unlock()
+		os.Remove(compressedPath)
+		return false, err
+	}
+	delete(w.olderSegments, seg.id)
+	if w.compressedSegments == nil {
+		w.compressedSegments = make(map[SegmentID]int64)
+	}
+	w.compressedSegments[seg.id] = seg.Size()
+	w.options.MetricsCollector.SetActiveSegments(len(w.olderSegments) + 1)
+	w.publishSegmentsLocked()
+	w.mu.Unlock()
+
+	seg.retire(nil)
+	return true, nil
+}
+
+// fetchCompressedSegment returns a *segment for id backed by a local copy
+// of what CompressSegments compressed, decompressing that copy into
+// Options.SealedSegmentCompressionCacheDir first if
+// w.compressedSegmentCache doesn't already have it open. It returns (nil,
+// nil), without touching disk beyond the check itself, if id was never
+// compressed.
+func (w *WAL) fetchCompressedSegment(id SegmentID) (*segment, error) {
+	w.mu.RLock()
+	_, compressed := w.compressedSegments[id]
+	w.mu.RUnlock()
+	if !compressed {
+		return nil, nil
+	}
+	return w.compressedSegmentCache.get(id)
+}
+
+// decompressSegment reads id's file compressSegment wrote, decompresses
+// it into Options.SealedSegmentCompressionCacheDir, and reopens the
+// result as a read-only segment.
+func (w *WAL) decompressSegment(id SegmentID) (*segment, error) {
+	compressed, err := os.ReadFile(compressedSegmentPath(w, id))
+	if err != nil {
+		return nil, err
+	}
+	raw, err := decompressPayload(w.options.SealedSegmentCompression, compressed, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	path := segmentFileName(w.options.SealedSegmentCompressionCacheDir, w.options.SegmentFileExt, id, w.options.SegmentFileNameFunc)
+	tmpPath := fmt.Sprintf("%s.decompressing", path)
+	if err := os.WriteFile(tmpPath, raw, 0o644); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return nil, err
+	}
+
+	return openSegmentFile(w.options.FS, w.options.SealedSegmentCompressionCacheDir, w.options.SegmentFileExt, id, w.options.Mode,
+		false, true, false, false, false, w.options.Checksum, !w.options.SkipReadVerification, w.options.ChainChecksums,
+		w.options.Compression, w.dictID, w.options.BlockSize, w.options.ChunkIndex, w.options.SegmentSigningKey,
+		w.options.SegmentSize, nil, w.options.Clock, w.options.SegmentFileNameFunc, w.options.SegmentWriteBufferSize)
+}
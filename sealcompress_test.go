@@ -0,0 +1,99 @@
+package wal
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompressSegmentsRewritesSealedSegmentsAndRemovesTheOriginal(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-sealcompress-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	cacheDir, err := os.MkdirTemp("", "wal-sealcompress-cache-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(cacheDir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(32),
+		WithSealedSegmentCompression(CompressionZstd, cacheDir, 0))
+	require.Nil(t, err)
+	defer w.Close()
+
+	pos, err := w.Write([]byte("a sealed record"))
+	require.Nil(t, err)
+	_, err = w.Write([]byte("second segment, to force a rotation"))
+	require.Nil(t, err)
+
+	n, err := w.CompressSegments()
+	require.Nil(t, err)
+	require.Equal(t, 1, n)
+
+	_, err = os.Stat(segmentFileName(dir, w.options.SegmentFileExt, pos.SegmentId, nil))
+	require.True(t, os.IsNotExist(err))
+	_, err = os.Stat(compressedSegmentPath(w, pos.SegmentId))
+	require.Nil(t, err)
+
+	data, err := w.Read(pos)
+	require.Nil(t, err)
+	require.Equal(t, "a sealed record", string(data))
+
+	// A second Read should hit the decompressed-segment cache instead of
+	// decompressing again.
+	data, err = w.Read(pos)
+	require.Nil(t, err)
+	require.Equal(t, "a sealed record", string(data))
+}
+
+func TestCompressSegmentsIsANoOpWithoutSealedSegmentCompression(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-sealcompress-disabled-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("record"))
+	require.Nil(t, err)
+
+	n, err := w.CompressSegments()
+	require.Nil(t, err)
+	require.Equal(t, 0, n)
+}
+
+func TestCompressSegmentsSkipsSegmentsAlreadyTiered(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-sealcompress-tiered-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	tieringCacheDir, err := os.MkdirTemp("", "wal-sealcompress-tiering-cache-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(tieringCacheDir)
+	compressCacheDir, err := os.MkdirTemp("", "wal-sealcompress-compress-cache-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(compressCacheDir)
+
+	clock := NewFakeClock(time.Now())
+	store := newMemObjectStore()
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(32), WithClock(clock),
+		WithTiering(store, time.Minute, tieringCacheDir, 0),
+		WithSealedSegmentCompression(CompressionZstd, compressCacheDir, 0))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("tiered record"))
+	require.Nil(t, err)
+	_, err = w.Write([]byte("second segment, to force a rotation"))
+	require.Nil(t, err)
+
+	clock.Tick(2 * time.Minute)
+	n, err := w.TierSegments()
+	require.Nil(t, err)
+	require.Equal(t, 1, n)
+
+	n, err = w.CompressSegments()
+	require.Nil(t, err)
+	require.Equal(t, 0, n)
+}
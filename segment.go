@@ -1,12 +1,14 @@
 package wal
 
 import (
+	"crypto/ed25519"
 	"encoding/binary"
 	"fmt"
-	"hash/crc32"
 	"io"
 	"os"
 	"path/filepath"
+	"sync"
+	"time"
 )
 
 // SegmentID identifies a segment file by its position in the log, starting
@@ -25,129 +27,1536 @@ type ChunkPosition struct {
 	// ChunkSize is the size, in bytes, of the chunk's payload (excluding
 	// its header).
 	ChunkSize uint32
+	// Sequence is the chunk's logical write order, assigned by WAL.write
+	// and resumed across a restart from the existing chunks already on
+	// disk (see indexExistingChunks). Use it for in-process ordering and
+	// dedup, not as a stable replication offset, since nothing stops a
+	// future Repair or Checkpoint from renumbering what's left.
+	Sequence uint64
 }
 
-// chunkHeaderSize is the size, in bytes, of the length+crc32 header that
+// chunkHeaderSize is the size, in bytes, of the length+checksum header that
 // precedes every chunk's payload in a segment file.
 const chunkHeaderSize = 4 + 4
 
+// segmentHeaderSize is the number of bytes reserved at the start of every
+// segment file for metadata: the magic number at magicOffset, the format
+// version byte at versionByte, the mode byte at modeByte, the checksum
+// algorithm byte at checksumByte, the compression codec byte at
+// compressionByte, the block size field at blockSizeOffset, and the
+// compression dictionary ID field at dictIDOffset.
+const segmentHeaderSize = 16
+
+// segmentMagicNumber is written at magicOffset in every segment file's
+// header. openSegmentFile checks it before trusting anything else in the
+// header, so opening a file that isn't one of this package's segments --
+// the wrong directory, a leftover from an unrelated program, or a file
+// truncated before its header ever made it to disk -- fails with a clear
+// ErrNotASegmentFile instead of misreading its bytes as chunks.
+const segmentMagicNumber uint32 = 0x57414c30
+
+// segmentFormatVersion is the current segment header format. Open rejects
+// a segment recording a newer version than this with
+// ErrUnsupportedFormatVersion, rather than misinterpreting header fields a
+// future version might repurpose.
+const segmentFormatVersion byte = 1
+
+// magicOffset is the offset, within a segment file's header, of the
+// 4-byte big-endian field recording segmentMagicNumber.
+const magicOffset = 0
+
+// versionByte is the offset, within a segment file's header, of the byte
+// recording segmentFormatVersion.
+const versionByte = 4
+
 // ErrInvalidCRC is returned by segment.Read (and, by extension,
-// Reader.Next) when a chunk's CRC does not match its payload.
+// Reader.Next) when a chunk's checksum does not match its payload.
 var ErrInvalidCRC = fmt.Errorf("wal: chunk has invalid crc")
 
+// ErrCorrupted wraps an error found while reading or repairing a chunk with
+// the location it was found at, so a caller can log or excise that exact
+// region instead of just being told "invalid crc" with nothing to act on.
+// Reason is ErrInvalidCRC for a checksum mismatch, or an io error (e.g.
+// io.ErrUnexpectedEOF) for a short read; errors.Is(err, ErrInvalidCRC) and
+// similar still work against an *ErrCorrupted via Unwrap.
+type ErrCorrupted struct {
+	SegmentID   SegmentID
+	ChunkOffset int64
+	Reason      error
+}
+
+func (e *ErrCorrupted) Error() string {
+	return fmt.Sprintf("wal: corrupted chunk in segment %d at offset %d: %v", e.SegmentID, e.ChunkOffset, e.Reason)
+}
+
+func (e *ErrCorrupted) Unwrap() error {
+	return e.Reason
+}
+
+// Is reports whether target is ErrCorruptedData, so
+// errors.Is(err, ErrCorruptedData) matches any *ErrCorrupted regardless
+// of its Reason, alongside the more specific matches Unwrap already
+// gives errors.Is against Reason itself.
+func (e *ErrCorrupted) Is(target error) bool {
+	return target == ErrCorruptedData
+}
+
+// ErrEmptyRecord is returned by Write (and, by extension, WriteAll) for a
+// zero-length record. A chunk header of all zero bytes is the sentinel a
+// segment's reader uses to recognize unwritten, pre-allocated space past
+// its logical end (see newSegmentFile); a genuine empty record would be
+// indistinguishable from that, so it isn't allowed.
+var ErrEmptyRecord = fmt.Errorf("wal: empty records are not supported")
+
+// ErrRecordTooLarge is returned by Write, WriteWithFlags, WriteWithTTL,
+// and PendingWrites when Options.MaxRecordSize is set and the record
+// passed in is larger than it.
+type ErrRecordTooLarge struct {
+	Size    int
+	MaxSize int64
+}
+
+func (e *ErrRecordTooLarge) Error() string {
+	return fmt.Sprintf("wal: record of %d bytes exceeds MaxRecordSize of %d bytes", e.Size, e.MaxSize)
+}
+
+// Unwrap returns ErrValueTooLarge, so errors.Is(err, ErrValueTooLarge)
+// matches any *ErrRecordTooLarge without needing errors.As to get at its
+// Size and MaxSize fields.
+func (e *ErrRecordTooLarge) Unwrap() error {
+	return ErrValueTooLarge
+}
+
+// ErrRecordExpired is returned by WAL.ReadUnexpired, alongside the record's
+// data, once its WriteWithTTL-assigned expiry has passed.
+var ErrRecordExpired = fmt.Errorf("wal: record has expired")
+
 // segment is a single append-only file making up part of a WAL. Chunks are
-// framed as a fixed-size header (payload length + CRC32) followed by the
-// payload.
+// framed as a fixed-size header (payload length + checksum) followed by the
+// payload; the file itself starts with a segmentHeaderSize-byte header
+// recording the Mode and Checksum algorithm it was created under.
 type segment struct {
-	id     SegmentID
-	fd     *os.File
-	path   string
-	offset int64 // bytes written so far, i.e. the next chunk's ChunkOffset
+	id        SegmentID
+	fd        File
+	path      string
+	offset    int64     // bytes written so far, i.e. the next chunk's ChunkOffset
+	createdAt time.Time // when this segment file started being the active segment; used by age-based retention
+	fdatasync bool      // Options.Fdatasync; see Sync
+	checksum  Checksum  // recorded in this segment's header; see openSegmentFile
+
+	// verifyOnRead is Options.SkipReadVerification, inverted: whether Read,
+	// ReadWithCRC, and ReadInto check each chunk's checksum as they read
+	// it. See WithVerifyOnRead. It has no bearing on openSegmentFile's own
+	// tail check or on Repair/Verify, which always verify regardless.
+	verifyOnRead bool
+
+	// chainChecksums is Options.ChainChecksums: whether each chunk's
+	// checksum folds in the checksum of the chunk written immediately
+	// before it, so Read, ReadWithCRC, and ReadInto can no longer verify a
+	// chunk on their own -- see WithChainChecksums, and readChunkAt's verify
+	// parameter, which Read forces off whenever this is set.
+	chainChecksums bool
+
+	// lastChecksum is the checksum most recently written to this segment,
+	// chained into the next chunk Write or WriteBatch appends when
+	// chainChecksums is set. Like offset, it is unsynchronized: only the
+	// single writer goroutine ever appends to a segment.
+	lastChecksum uint32
+
+	// chunkIndex is Options.ChunkIndex: whether seal should write this
+	// segment's dense chunk-index sidecar once it's rotated out of being
+	// active. See WAL.ReadNth.
+	chunkIndex bool
+
+	// signingKey is Options.SegmentSigningKey: when set, seal signs this
+	// segment's footer digest and writes the signature to a .SIG sidecar
+	// once it's rotated out of being active. See signSegmentDigest.
+	signingKey ed25519.PrivateKey
+
+	// footerLoaded is set by openSegmentFile when this segment's chunkCount
+	// and index below came from a trusted on-disk footer (see seal) rather
+	// than from scanning its chunks. indexExistingChunks uses it to skip
+	// redoing that work.
+	footerLoaded bool
+
+	// mu guards refs, retired, chunkCount, index, and mmap. Checkpoint
+	// retires a sealed segment (see retire) once it has folded the segment
+	// into a new checkpoint file; without this, closing and removing the
+	// segment's fd right then could race with a WAL.Read or Reader.Next
+	// call already in flight against it, which resolved the segment before
+	// Checkpoint got there but hadn't done its actual file I/O yet.
+	// chunkCount, index, and mmap are piggybacked onto the same mutex since
+	// they're updated alongside offset and read alongside refs.
+	mu         sync.Mutex
+	refs       int
+	retired    bool
+	chunkCount int64
+	index      []sparseIndexEntry
+	mmap       []byte // non-nil once enableMmapRead has mapped this (sealed) segment's file for reading
+
+	// ttlWriteCount and maxExpiresAt track WriteWithTTL chunks written into
+	// this segment, for Options.DropExpiredSegments; see allExpired. Piggybacked
+	// onto mu alongside chunkCount, which they're compared against.
+	ttlWriteCount int64
+	maxExpiresAt  time.Time
+
+	// recyclePipeline is set by retire, to the WAL's file pipeline when
+	// Options.RecycleSegments is on, or nil otherwise. closeAndRemove
+	// offers it this segment's fd for reuse as a future segment instead of
+	// deleting the file, once every in-flight acquire has released it.
+	recyclePipeline *filePipeline
+
+	// writeBufCap is Options.SegmentWriteBufferSize: how many bytes of
+	// framed chunks Write and WriteBatch accumulate in writeBuf before
+	// flushWriteBuffer sends them to fd. 0 disables buffering -- every
+	// Write and WriteBatch reaches fd immediately, exactly as before this
+	// existed.
+	writeBufCap int
+
+	// writeBuf holds framed chunks (see frameChunk) Write and WriteBatch
+	// have appended but flushWriteBuffer hasn't yet sent to fd, once
+	// writeBufCap is positive. It covers the offset range
+	// [offset-len(writeBuf), offset). Piggybacked onto mu, like mmap, so
+	// Read, ReadWithCRC, and ReadInto -- which, like mmap's readers, can
+	// run concurrently with the single writer goroutine -- can still find
+	// a chunk that hasn't reached fd yet; see readBuffered.
+	writeBuf []byte
 }
 
-func segmentFileName(dirPath, ext string, id SegmentID) string {
+// sparseIndexInterval is how many chunks separate consecutive entries in a
+// segment's sparse index. One entry per chunk would make ReadBySequence O(1)
+// but cost as much memory as the chunks themselves; one entry every
+// sparseIndexInterval chunks bounds ReadBySequence's post-lookup scan to at
+// most that many chunks while keeping the index itself tiny.
+const sparseIndexInterval = 32
+
+// sparseIndexEntry records that the chunk assigned sequence was the first
+// chunk of a sparseIndexInterval-sized bucket, starting at offset within its
+// segment.
+type sparseIndexEntry struct {
+	sequence uint64
+	offset   int64
+}
+
+// recordSequence extends the segment's sparse index with the chunk just
+// written at offset and assigned seq, if it falls on a bucket boundary.
+func (s *segment) recordSequence(seq uint64, offset int64) {
+	s.mu.Lock()
+	if s.chunkCount%sparseIndexInterval == 0 {
+		s.index = append(s.index, sparseIndexEntry{sequence: seq, offset: offset})
+	}
+	s.chunkCount++
+	s.mu.Unlock()
+}
+
+// sparseIndexSnapshot returns the segment's sparse index as it stands right
+// now. The returned slice is never mutated in place -- only appended to --
+// so it's safe to read after this call returns without further locking.
+func (s *segment) sparseIndexSnapshot() []sparseIndexEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.index
+}
+
+// chunkCountSnapshot returns how many chunks have been written to the
+// segment so far.
+func (s *segment) chunkCountSnapshot() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.chunkCount
+}
+
+// recordTTLWrite extends the segment's expiry tracking for a chunk just
+// written into it via WriteWithTTL, expiring at expiresAt. See allExpired.
+func (s *segment) recordTTLWrite(expiresAt time.Time) {
+	s.mu.Lock()
+	s.ttlWriteCount++
+	if expiresAt.After(s.maxExpiresAt) {
+		s.maxExpiresAt = expiresAt
+	}
+	s.mu.Unlock()
+}
+
+// allExpired reports whether every chunk ever written to this segment came
+// in through WriteWithTTL and all of their expiries have passed as of now.
+// A segment that also received a plain Write, WriteWithFlags, or WriteAll
+// chunk never qualifies, since those carry no expiry to check at all --
+// ttlWriteCount short of chunkCount is proof at least one such chunk
+// exists, without needing to know which one.
+func (s *segment) allExpired(now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ttlWriteCount > 0 && s.ttlWriteCount == s.chunkCount && s.maxExpiresAt.Before(now)
+}
+
+// trustedActiveTail carries a shutdownManifest's record of the active
+// segment's logical end across to openSegmentFile; see its trusted
+// parameter.
+type trustedActiveTail struct {
+	offset     int64
+	chunkCount int64
+	firstSeq   uint64 // sequence number of this segment's first chunk
+}
+
+// segmentFileName returns id's segment file path within dirPath, via
+// nameFunc if non-nil (see Options.SegmentFileNameFunc), or the built-in
+// zero-padded-number-plus-ext scheme otherwise.
+func segmentFileName(dirPath, ext string, id SegmentID, nameFunc func(SegmentID) string) string {
+	if nameFunc != nil {
+		return filepath.Join(dirPath, nameFunc(id))
+	}
 	return filepath.Join(dirPath, fmt.Sprintf("%09d%s", id, ext))
 }
 
 // openSegmentFile opens the segment file with the given id in dirPath,
-// creating it if it doesn't exist yet.
-func openSegmentFile(dirPath, ext string, id SegmentID) (*segment, error) {
-	path := segmentFileName(dirPath, ext, id)
+// creating it (and stamping its header) if it doesn't exist yet. If the
+// file already exists, its header must start with segmentMagicNumber and
+// record a format version this build supports, or ErrNotASegmentFile or
+// ErrUnsupportedFormatVersion is returned; its recorded mode must match
+// want, or ErrModeMismatch is returned; its recorded checksum algorithm
+// must match checksum, or ErrChecksumMismatch is returned; its recorded
+// compression codec must match compression, or ErrCompressionMismatch is
+// returned; its recorded compression dictionary ID must match dictID, or
+// ErrCompressionDictMismatch is returned; and its recorded chainChecksums
+// flag must match chainChecksums, or ErrChainChecksumsMismatch is
+// returned. blockSize is recorded in the header but, per
+// Options.BlockSize's doc comment, never checked against an existing
+// segment's.
+//
+// verifyTail should be true only for the active segment of a log being
+// opened after a previous run: it CRC-checks the one chunk scanToLogicalEnd
+// landed on as the logical end, which is the only chunk an unclean
+// shutdown could have left mid-write without also making it fail
+// scanToLogicalEnd's cheaper length-only scan. Segments other than the
+// active one were already sealed (and so fully, cleanly written) before
+// this run started, so there's nothing for them to gain from the same
+// check -- and no reason to pay for CRC-checksumming their full contents.
+//
+// A sealed segment normally carries a footer (see seal) recording its
+// offset, chunk count, and sparse index; when one is present and
+// verifyTail is false, openSegmentFile trusts it instead of scanning, so
+// opening a directory costs time proportional to its active segment, not
+// its whole history. Absent a usable footer, it falls back to
+// scanToLogicalEnd exactly as before this existed.
+//
+// readOnly opens the file O_RDONLY instead of creating it, for
+// Options.ReadOnly; the file must already exist.
+//
+// directIO opens the file with O_DIRECT, for Options.DirectIO; it only
+// takes effect when fs is the default, real-filesystem one, since O_DIRECT
+// has no meaning for a custom FS.
+//
+// fdatasync is Options.Fdatasync, stored on the returned segment for Sync
+// to use.
+//
+// checksum is Options.Checksum. A freshly created segment records it in
+// its header; an existing one must already have been created with it.
+//
+// chainChecksums is Options.ChainChecksums, stored in the header alongside
+// checksum. A freshly created segment records it there; an existing one
+// must already have been created with it, and whenever it is set, the
+// trusted-footer and shutdown-manifest fast paths below are skipped in
+// favor of scanning, since neither carries the chain state (the checksum
+// chained into the segment's last chunk) a chained segment's tail check --
+// or its next Write -- needs.
+//
+// doPreallocate and segmentSize are Options.Preallocate and
+// Options.SegmentSize: when doPreallocate is true and the file is being
+// created new, it is fallocated to segmentSize bytes before its header is
+// written, the same way a filePipeline-provided file already is (see
+// newSegmentFile). It only takes effect when fd turns out to be a plain
+// *os.File, which rules out a custom FS and a directIO-wrapped
+// *directIOFile, neither of which preallocate knows how to size.
+//
+// trusted, if non-nil, is only consulted when verifyTail is also true: a
+// shutdownManifest-derived hint that the previous run closed this exact
+// segment cleanly at trusted.offset, which lets Open trust that instead of
+// scanning for it (see scanToLogicalEnd) or CRC-checking its tail chunk
+// (see verifyTail's doc comment) -- the same trade sealed segments already
+// make off a footer, extended to the still-active one. Pass nil to always
+// scan, the same as before trusted existed.
+//
+// chunkIndex is Options.ChunkIndex, stored on the returned segment so a
+// later seal knows whether to write this segment's dense chunk-index
+// sidecar; see WAL.ReadNth.
+//
+// signingKey is Options.SegmentSigningKey, stored on the returned segment
+// so a later seal knows whether to sign its footer digest; see
+// signSegmentDigest.
+//
+// nameFunc is Options.SegmentFileNameFunc; see segmentFileName.
+//
+// writeBufferSize is Options.SegmentWriteBufferSize, stored on the
+// returned segment as writeBufCap; see flushWriteBuffer.
+func openSegmentFile(fs FS, dirPath, ext string, id SegmentID, want Mode, verifyTail, readOnly, directIO, fdatasync, doPreallocate bool, checksum Checksum, verifyOnRead bool, chainChecksums bool, compression CompressionCodec, dictID uint32, blockSize int, chunkIndex bool, signingKey ed25519.PrivateKey, segmentSize int64, trusted *trustedActiveTail, clock Clock, nameFunc func(SegmentID) string, writeBufferSize int) (*segment, error) {
+	path := segmentFileName(dirPath, ext, id, nameFunc)
+
+	flag := os.O_CREATE | os.O_RDWR
+	if readOnly {
+		flag = os.O_RDONLY
+	}
 
-	fd, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+	var fd File
+	var err error
+	if _, ok := fs.(osFS); ok && directIO {
+		fd, err = openFileDirectIO(path, flag, 0o644)
+	} else {
+		fd, err = fs.OpenFile(path, flag, 0o644)
+	}
 	if err != nil {
 		return nil, err
 	}
 
+	// A segment file freshly created by the O_CREATE above is empty;
+	// anything else -- including one sized but not yet header-stamped by a
+	// filePipeline (see newSegmentFile, which always stamps it itself
+	// before handing it to the WAL) -- already has at least a header.
 	info, err := fd.Stat()
 	if err != nil {
 		fd.Close()
 		return nil, err
 	}
-	if _, err := fd.Seek(info.Size(), io.SeekStart); err != nil {
-		fd.Close()
+	isNew := info.Size() == 0
+
+	if isNew && doPreallocate {
+		if osFile, ok := fd.(*os.File); ok {
+			if err := preallocate(osFile, segmentSize); err != nil {
+				fd.Close()
+				return nil, err
+			}
+		}
+	}
+
+	if isNew {
+		if err := writeSegmentHeader(fd, want, checksum, compression, dictID, blockSize, chainChecksums); err != nil {
+			fd.Close()
+			return nil, err
+		}
+		// A crash right after this, before the directory entry itself is
+		// durable, could otherwise make the segment vanish on the next
+		// Open even though its header made it to disk.
+		if err := fs.SyncDir(dirPath); err != nil {
+			fd.Close()
+			return nil, err
+		}
+	} else {
+		gotMode, gotChecksum, gotCompression, gotDictID, _, gotChainChecksums, err := readSegmentHeader(fd)
+		if err != nil {
+			fd.Close()
+			return nil, err
+		}
+		if gotMode != want {
+			fd.Close()
+			return nil, ErrModeMismatch
+		}
+		if gotChecksum != checksum {
+			fd.Close()
+			return nil, ErrChecksumMismatch
+		}
+		if gotCompression != compression {
+			fd.Close()
+			return nil, ErrCompressionMismatch
+		}
+		if gotDictID != dictID {
+			fd.Close()
+			return nil, ErrCompressionDictMismatch
+		}
+		if gotChainChecksums != chainChecksums {
+			fd.Close()
+			return nil, ErrChainChecksumsMismatch
+		}
+	}
+
+	// A sealed segment (verifyTail is false for exactly this case, never
+	// for a freshly created one) may carry a footer seal wrote when it was
+	// rotated out of being active: trust it instead of scanning, which is
+	// what makes Open's cost independent of how much has already been
+	// sealed away. Only the active segment -- opened with verifyTail true
+	// -- and a sealed one with no footer (older than this feature, or one
+	// whose footer write never made it to disk) fall through to the scan
+	// below.
+	var offset, lastChunkStart int64
+	var lastChecksum, prevOfLastChecksum uint32
+	var chunkCount int64
+	var index []sparseIndexEntry
+	footerLoaded := false
+	switch {
+	case trusted != nil && verifyTail && !isNew && !chainChecksums:
+		// The active segment carries no footer of its own -- writing one
+		// would mean truncating its preallocated tail, undoing the whole
+		// point of WithSegmentPreallocate -- so trust comes from the
+		// shutdown manifest instead, sanity-checked against the file's
+		// actual size before lastChunkStart's CRC check below is skipped.
+		if segmentHeaderSize+trusted.offset <= info.Size() {
+			offset, chunkCount = trusted.offset, trusted.chunkCount
+			if chunkCount > 0 {
+				index = []sparseIndexEntry{{sequence: trusted.firstSeq, offset: 0}}
+			}
+			lastChunkStart = -1
+			footerLoaded = true
+			if _, err := fd.Seek(segmentHeaderSize+offset, io.SeekStart); err != nil {
+				fd.Close()
+				return nil, err
+			}
+		}
+	case !isNew && !verifyTail && !chainChecksums:
+		if ft, ok, ferr := readFooter(fd, info.Size()); ferr == nil && ok {
+			offset, chunkCount, index = ft.offset, ft.chunkCount, ft.index
+			lastChunkStart = -1
+			footerLoaded = true
+		}
+	}
+
+	if !footerLoaded {
+		// writeSegmentHeader and readSegmentHeader both use ReadAt/WriteAt,
+		// which don't move the file's write offset. A segment's physical
+		// size can be larger than its logical content if it was handed out
+		// by a filePipeline already sized to SegmentSize (see
+		// newSegmentFile), so scan forward for the true end of its chunks
+		// instead of trusting the file's stat size, and leave fd positioned
+		// there for the sequential Writes in appendChunk to land in the
+		// right place.
+		offset, lastChunkStart, lastChecksum, prevOfLastChecksum, err = scanToLogicalEnd(fd)
+		if err != nil {
+			fd.Close()
+			return nil, err
+		}
+	}
+
+	if verifyTail && lastChunkStart >= 0 {
+		// Always verified, regardless of verifyOnRead (Options.
+		// SkipReadVerification only trades away redundant checksum checks
+		// on Read/ReadInto/Reader.Next; a torn tail write is exactly the
+		// kind of corruption Open needs to detect regardless of that
+		// setting).
+		if chainChecksums {
+			payload, gotChecksum, err := readChunkAtWithCRC(fd, lastChunkStart, checksum, false, id)
+			if err != nil {
+				fd.Close()
+				return nil, fmt.Errorf("wal: segment %d has a torn tail write: %w", id, err)
+			}
+			if !verifyChainedChecksum(checksum, prevOfLastChecksum, payload, gotChecksum) {
+				fd.Close()
+				return nil, fmt.Errorf("wal: segment %d has a torn tail write: %w", id, &ErrCorrupted{SegmentID: id, ChunkOffset: lastChunkStart, Reason: ErrInvalidCRC})
+			}
+		} else if _, err := readChunkAt(fd, lastChunkStart, checksum, true, id); err != nil {
+			fd.Close()
+			return nil, fmt.Errorf("wal: segment %d has a torn tail write: %w", id, err)
+		}
+	}
+
+	createdAt := clock.Now()
+	if !isNew {
+		// Best-effort: approximate the original creation time from the
+		// file's mtime across process restarts, since nothing in the
+		// segment header records it. This drifts every time the segment is
+		// appended to, so age-based retention (see WithMaxSegmentAge) is
+		// only as accurate as the last write to a segment made before this
+		// process started.
+		if info, err := fd.Stat(); err == nil {
+			createdAt = info.ModTime()
+		}
+	}
+
+	seg := &segment{id: id, fd: fd, path: path, offset: offset, createdAt: createdAt, fdatasync: fdatasync, checksum: checksum, verifyOnRead: verifyOnRead, chainChecksums: chainChecksums, lastChecksum: lastChecksum, chunkIndex: chunkIndex, signingKey: signingKey, footerLoaded: footerLoaded, writeBufCap: writeBufferSize}
+	if footerLoaded {
+		seg.chunkCount = chunkCount
+		seg.index = index
+	}
+	return seg, nil
+}
+
+// newSegmentFile installs f (typically handed out by a filePipeline,
+// already sized to SegmentSize) as the segment file with the given id,
+// stamping it with mode, checksum, compression, and blockSize. f keeps
+// whatever size the
+// filePipeline pre-allocated it to -- it is not truncated back down to
+// just the header, since that would give up the whole point of
+// pre-allocating it in the first place. The chunk-reading paths
+// (segment.Read, readChunk) treat the zero-filled space past the last real
+// chunk as the end of the segment's data, the same way they'd treat a torn
+// tail write's missing payload.
+//
+// chunkIndex and signingKey are Options.ChunkIndex and
+// Options.SegmentSigningKey, stored on the returned segment the same way
+// openSegmentFile does; see its doc comment. clock supplies createdAt.
+// nameFunc is Options.SegmentFileNameFunc; see segmentFileName. writeBufferSize
+// is Options.SegmentWriteBufferSize; see openSegmentFile.
+func newSegmentFile(f *os.File, dirPath, ext string, id SegmentID, mode Mode, fdatasync bool, checksum Checksum, verifyOnRead bool, chainChecksums bool, compression CompressionCodec, dictID uint32, blockSize int, chunkIndex bool, signingKey ed25519.PrivateKey, clock Clock, nameFunc func(SegmentID) string, writeBufferSize int) (*segment, error) {
+	path := segmentFileName(dirPath, ext, id, nameFunc)
+	if err := os.Rename(f.Name(), path); err != nil {
+		return nil, err
+	}
+	// The rename is what makes this the segment named id; without fsyncing
+	// dirPath afterward, a crash could leave the directory entry pointing
+	// at the old pipeline-N name instead, or not pointing anywhere at all.
+	if err := syncDir(dirPath); err != nil {
+		return nil, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
 		return nil, err
 	}
+	if err := writeSegmentHeader(f, mode, checksum, compression, dictID, blockSize, chainChecksums); err != nil {
+		return nil, err
+	}
+	if _, err := f.Seek(segmentHeaderSize, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return &segment{id: id, fd: f, path: path, offset: 0, createdAt: clock.Now(), fdatasync: fdatasync, checksum: checksum, verifyOnRead: verifyOnRead, chainChecksums: chainChecksums, chunkIndex: chunkIndex, signingKey: signingKey, writeBufCap: writeBufferSize}, nil
+}
+
+// checksumByte is the offset, within a segment file's header, of the byte
+// that records the Checksum algorithm the segment was created under; see
+// modeByte for the analogous byte recording Mode. Checksum only occupies
+// this byte's low bits (its values run 0-3); chainedChecksumFlag borrows
+// one of the spare high bits to record Options.ChainChecksums instead of
+// growing the header to fit it.
+const checksumByte = 6
+
+// chainedChecksumFlag is the bit within checksumByte that records
+// Options.ChainChecksums, independent of whichever Checksum algorithm the
+// rest of the byte records.
+const chainedChecksumFlag byte = 0x80
+
+// compressionByte is the offset, within a segment file's header, of the
+// byte that records the CompressionCodec the segment was created under;
+// see modeByte for the analogous byte recording Mode.
+const compressionByte = 7
+
+// blockSizeOffset is the offset, within a segment file's header, of the
+// 4-byte big-endian field that records Options.BlockSize at the time the
+// segment was created. It is recorded only so a segment's header is a
+// complete picture of the options it was written with -- see
+// Options.BlockSize's doc comment for why a mismatch here is not an error
+// the way a Mode, Checksum, or CompressionCodec mismatch is.
+const blockSizeOffset = 8
+
+// dictIDOffset is the offset, within a segment file's header, of the
+// 4-byte big-endian field that records the ID of the zstd dictionary (see
+// Options.CompressionDict) the segment was created with, or 0 if none was
+// set. Like Checksum and CompressionCodec, a mismatch here is an error --
+// see ErrCompressionDictMismatch -- since decompressing with the wrong
+// dictionary (or none at all) doesn't fail cleanly the way a wrong
+// encryption key does; it can silently return garbage.
+const dictIDOffset = 12
 
-	return &segment{id: id, fd: fd, path: path, offset: info.Size()}, nil
+// ErrNotASegmentFile is returned by Open when a file in the WAL's
+// directory matching its segment naming pattern doesn't start with
+// segmentMagicNumber -- almost always a sign the directory also holds an
+// unrelated file, or one of this package's segments got truncated before
+// its header reached disk.
+var ErrNotASegmentFile = fmt.Errorf("wal: file does not start with the WAL segment magic number")
+
+// ErrUnsupportedFormatVersion is returned by Open when a segment's header
+// records a format version newer than this build of the package knows how
+// to read.
+var ErrUnsupportedFormatVersion = fmt.Errorf("wal: segment was written with a newer segment format version than this build supports")
+
+// ErrChecksumMismatch is returned by Open when a segment's header records
+// a different Checksum algorithm than the one the log is being opened
+// with.
+var ErrChecksumMismatch = fmt.Errorf("wal: segment was created with a different checksum algorithm than the log is being opened with")
+
+// ErrCompressionMismatch is returned by Open when a segment's header
+// records a different CompressionCodec than the one the log is being
+// opened with -- see CompressionCodec's doc comment for why reading a
+// mismatched segment without this check would silently return garbage
+// instead.
+var ErrCompressionMismatch = fmt.Errorf("wal: segment was created with a different compression codec than the log is being opened with")
+
+// ErrCompressionDictMismatch is returned by Open when a segment's header
+// records a different Options.CompressionDict (by the dictionary's own ID;
+// see dictIDOffset) than the one the log is being opened with -- unlike a
+// wrong encryption key, decompressing with the wrong zstd dictionary (or
+// none at all) does not reliably fail, so this check exists for the same
+// reason ErrCompressionMismatch does.
+var ErrCompressionDictMismatch = fmt.Errorf("wal: segment was created with a different compression dictionary than the log is being opened with")
+
+// ErrChainChecksumsMismatch is returned by Open when a segment's header
+// records a different Options.ChainChecksums setting than the log is being
+// opened with -- reading a chained segment's chunks as unchained, or vice
+// versa, would otherwise report every chunk past the first as corrupt.
+var ErrChainChecksumsMismatch = fmt.Errorf("wal: segment was created with a different ChainChecksums setting than the log is being opened with")
+
+func writeSegmentHeader(fd File, mode Mode, checksum Checksum, compression CompressionCodec, dictID uint32, blockSize int, chainChecksums bool) error {
+	header := make([]byte, segmentHeaderSize)
+	binary.BigEndian.PutUint32(header[magicOffset:], segmentMagicNumber)
+	header[versionByte] = segmentFormatVersion
+	header[modeByte] = byte(mode)
+	header[checksumByte] = byte(checksum)
+	if chainChecksums {
+		header[checksumByte] |= chainedChecksumFlag
+	}
+	header[compressionByte] = byte(compression)
+	binary.BigEndian.PutUint32(header[blockSizeOffset:], uint32(blockSize))
+	binary.BigEndian.PutUint32(header[dictIDOffset:], dictID)
+	_, err := fd.WriteAt(header, 0)
+	return err
+}
+
+func readSegmentHeader(fd File) (Mode, Checksum, CompressionCodec, uint32, int, bool, error) {
+	header := make([]byte, segmentHeaderSize)
+	if _, err := fd.ReadAt(header, 0); err != nil {
+		return 0, 0, 0, 0, 0, false, err
+	}
+	if binary.BigEndian.Uint32(header[magicOffset:]) != segmentMagicNumber {
+		return 0, 0, 0, 0, 0, false, ErrNotASegmentFile
+	}
+	if header[versionByte] > segmentFormatVersion {
+		return 0, 0, 0, 0, 0, false, ErrUnsupportedFormatVersion
+	}
+	blockSize := int(binary.BigEndian.Uint32(header[blockSizeOffset:]))
+	dictID := binary.BigEndian.Uint32(header[dictIDOffset:])
+	chainChecksums := header[checksumByte]&chainedChecksumFlag != 0
+	checksum := Checksum(header[checksumByte] &^ chainedChecksumFlag)
+	return Mode(header[modeByte]), checksum, CompressionCodec(header[compressionByte]), dictID, blockSize, chainChecksums, nil
+}
+
+// scanToLogicalEnd scans fd's chunks, starting just past the segment
+// header, to find the offset just past the last fully-written chunk. A
+// zero-length chunk header (the sentinel for unwritten, pre-allocated
+// space -- see newSegmentFile) or a length that would run past fd's
+// physical size (a torn tail write) both end the scan there, exactly as
+// they would for a freshly-opened segment that has never been
+// pre-allocated. It leaves fd positioned at the returned offset, ready
+// for appendChunk to pick up from.
+//
+// It also returns lastChunkStart, the offset of the last chunk the scan
+// accepted (-1 if the segment has no chunks at all): this scan only checks
+// chunk lengths, not CRCs, so that one chunk is the only part of the
+// result a caller might still want to verify further (see
+// openSegmentFile's verifyTail).
+//
+// lastChecksum and prevChecksum are the checksum fields recorded on,
+// respectively, the last chunk accepted and the one before it (0 if there
+// is no such chunk) -- read off the same chunk headers this scan already
+// has to read for their length fields, at no extra I/O cost. A chained
+// segment's openSegmentFile needs both: lastChecksum to seed the next
+// Write, and prevChecksum to verify the tail chunk's own chain link.
+func scanToLogicalEnd(fd File) (offset int64, lastChunkStart int64, lastChecksum uint32, prevChecksum uint32, err error) {
+	info, err := fd.Stat()
+	if err != nil {
+		return 0, -1, 0, 0, err
+	}
+	size := info.Size()
+
+	header := make([]byte, chunkHeaderSize)
+	lastChunkStart = -1
+	for {
+		fileOffset := segmentHeaderSize + offset
+		if fileOffset+int64(chunkHeaderSize) > size {
+			break
+		}
+		if _, err := fd.ReadAt(header, fileOffset); err != nil {
+			return 0, -1, 0, 0, err
+		}
+		length := int64(binary.BigEndian.Uint32(header[:4]))
+		if length == 0 {
+			break
+		}
+		if fileOffset+int64(chunkHeaderSize)+length > size {
+			break
+		}
+		lastChunkStart = offset
+		prevChecksum = lastChecksum
+		lastChecksum = binary.BigEndian.Uint32(header[4:])
+		offset += int64(chunkHeaderSize) + length
+	}
+
+	if _, err := fd.Seek(segmentHeaderSize+offset, io.SeekStart); err != nil {
+		return 0, -1, 0, 0, err
+	}
+	return offset, lastChunkStart, lastChecksum, prevChecksum, nil
 }
 
 // Write appends data to the segment as a single chunk and returns its
 // position. It does not fsync; callers control durability via Sync.
+//
+// If writeBufCap is positive (Options.SegmentWriteBufferSize), the framed
+// chunk is accumulated in writeBuf instead of reaching fd right away; see
+// flushWriteBuffer.
 func (s *segment) Write(data []byte) (*ChunkPosition, error) {
-	if err := appendChunk(s.fd, data); err != nil {
+	pos := &ChunkPosition{SegmentId: s.id, ChunkOffset: s.offset, ChunkSize: uint32(len(data))}
+
+	if s.writeBufCap <= 0 {
+		sum, err := appendChunk(s.fd, data, s.checksum, s.chainChecksums, s.lastChecksum)
+		if err != nil {
+			return nil, err
+		}
+		s.offset += int64(chunkHeaderSize) + int64(len(data))
+		s.lastChecksum = sum
+		return pos, nil
+	}
+
+	framed, sum := frameChunk(data, s.checksum, s.chainChecksums, s.lastChecksum)
+	if err := s.bufferFramed(framed); err != nil {
 		return nil, err
 	}
-	pos := &ChunkPosition{SegmentId: s.id, ChunkOffset: s.offset, ChunkSize: uint32(len(data))}
-	s.offset += int64(chunkHeaderSize) + int64(len(data))
+	s.offset += int64(len(framed))
+	s.lastChecksum = sum
 	return pos, nil
 }
 
+// WriteBatch appends payloads to the segment as consecutive chunks in a
+// single writev syscall (see appendChunks) instead of one appendChunk call
+// per payload, and returns each chunk's position in payloads' order. It
+// does not fsync; callers control durability via Sync.
+//
+// If writeBufCap is positive (Options.SegmentWriteBufferSize), every
+// payload's framed chunk is accumulated in writeBuf instead of reaching fd
+// right away, the same as Write; see flushWriteBuffer.
+func (s *segment) WriteBatch(payloads [][]byte) ([]*ChunkPosition, error) {
+	positions := make([]*ChunkPosition, len(payloads))
+	offset := s.offset
+	for i, payload := range payloads {
+		positions[i] = &ChunkPosition{SegmentId: s.id, ChunkOffset: offset, ChunkSize: uint32(len(payload))}
+		offset += int64(chunkHeaderSize) + int64(len(payload))
+	}
+
+	if s.writeBufCap <= 0 {
+		sum, err := appendChunks(s.fd, payloads, s.checksum, s.chainChecksums, s.lastChecksum)
+		if err != nil {
+			return nil, err
+		}
+		s.offset = offset
+		s.lastChecksum = sum
+		return positions, nil
+	}
+
+	framed := make([]byte, 0, offset-s.offset)
+	sum := s.lastChecksum
+	for _, payload := range payloads {
+		var f []byte
+		f, sum = frameChunk(payload, s.checksum, s.chainChecksums, sum)
+		framed = append(framed, f...)
+	}
+	if err := s.bufferFramed(framed); err != nil {
+		return nil, err
+	}
+	s.offset = offset
+	s.lastChecksum = sum
+	return positions, nil
+}
+
+// frameChunk frames payload exactly as appendChunk writes it to fd --
+// header (length, checksum) followed by payload -- but returns the bytes
+// instead of writing them, for Write and WriteBatch to accumulate in
+// writeBuf. chained and prev are chainChecksums and lastChecksum, as
+// appendChunk takes them.
+func frameChunk(payload []byte, checksum Checksum, chained bool, prev uint32) ([]byte, uint32) {
+	sum := checksumPayload(checksum, payload)
+	if chained {
+		sum = chainedChecksumPayload(checksum, prev, payload)
+	}
+	framed := make([]byte, chunkHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(framed[:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(framed[4:chunkHeaderSize], sum)
+	copy(framed[chunkHeaderSize:], payload)
+	return framed, sum
+}
+
+// bufferFramed appends framed -- one or more chunks already framed by
+// frameChunk, back to back -- to writeBuf, guarded by mu so Read,
+// ReadWithCRC, and ReadInto can see it too (see readBuffered), flushing
+// once writeBuf would reach writeBufCap.
+func (s *segment) bufferFramed(framed []byte) error {
+	s.mu.Lock()
+	s.writeBuf = append(s.writeBuf, framed...)
+	full := len(s.writeBuf) >= s.writeBufCap
+	s.mu.Unlock()
+
+	if full {
+		return s.flushWriteBuffer()
+	}
+	return nil
+}
+
+// flushWriteBuffer sends whatever Write or WriteBatch has accumulated in
+// writeBuf to fd in a single Write call, and is a no-op if nothing is
+// buffered. mu is held for the whole call, including the fd.Write itself,
+// not just released snapshot-then-reacquired: Options.Sync with
+// Options.GroupCommit off (deferSolo) or on (deferGroup) calls this, via
+// Sync, from syncSegmentUnlocked after the writer that triggered it has
+// already released w.mu -- so a second writer's Write/WriteBatch can reach
+// bufferFramed on this very segment while a flush from the first is still
+// in flight. Holding mu across fd.Write serializes the two: bufferFramed
+// can't append to writeBuf out from under an in-progress flush, and two
+// concurrent flushes can't issue unordered, interleaving fd.Write calls
+// against fd's shared file offset. It also protects Read, ReadWithCRC, and
+// ReadInto reading writeBuf the same way mu already does around mmap.
+// Sync, seal, truncate, and Close all call this before doing their own
+// work, so none of them can act on a segment that still has data sitting
+// only in memory.
+func (s *segment) flushWriteBuffer() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.writeBuf) == 0 {
+		return nil
+	}
+	n, err := s.fd.Write(s.writeBuf)
+	// fd.Write may have written a prefix of writeBuf before failing -- drop
+	// only that prefix, not the whole buffer, so a retried flush doesn't
+	// resend bytes that already reached the file and duplicate them.
+	s.writeBuf = s.writeBuf[:copy(s.writeBuf, s.writeBuf[n:])]
+	return err
+}
+
+// readBuffered looks for the chunk at offset in writeBuf -- the tail of
+// this segment's data Write or WriteBatch has buffered in memory but not
+// yet flushed to fd (see Options.SegmentWriteBufferSize) -- so Read,
+// ReadWithCRC, and ReadInto see their own unflushed writes instead of
+// returning a premature io.EOF. ok is false if offset isn't covered by
+// what's currently buffered, meaning the caller should fall back to its
+// normal fd/mmap path.
+func (s *segment) readBuffered(offset int64, verify bool) (payload []byte, checksum uint32, ok bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bufStart := s.offset - int64(len(s.writeBuf))
+	if len(s.writeBuf) == 0 || offset < bufStart {
+		return nil, 0, false, nil
+	}
+	payload, checksum, err = readChunkFromBufferWithCRC(s.writeBuf, offset-bufStart, s.checksum, verify, s.id)
+	return payload, checksum, true, err
+}
+
 // Read returns the chunk at the given offset (as recorded in a
 // ChunkPosition returned by Write).
 func (s *segment) Read(offset int64) ([]byte, error) {
+	data, _, err := s.ReadWithCRC(offset)
+	return data, err
+}
+
+// ReadWithCRC is Read, but also returns the checksum recorded in the
+// chunk's header, for Reader.NextWithMeta.
+func (s *segment) ReadWithCRC(offset int64) ([]byte, uint32, error) {
+	// A chained segment's checksum covers the chunk before it too, which
+	// this random-access read has no way to know here -- see
+	// Options.ChainChecksums -- so the checksum check is skipped
+	// regardless of verifyOnRead; only a sequential scan (Reader.Next,
+	// Verify, VerifyAll, Repair) can verify a chained chunk.
+	verify := s.verifyOnRead && !s.chainChecksums
+
+	if s.writeBufCap > 0 {
+		if payload, sum, ok, err := s.readBuffered(offset, verify); ok {
+			return payload, sum, err
+		}
+	}
+
+	s.mu.Lock()
+	mmap := s.mmap
+	s.mu.Unlock()
+	if mmap != nil {
+		return readChunkFromMmapWithCRC(mmap, offset, s.checksum, verify, s.id)
+	}
+	return readChunkAtWithCRC(s.fd, offset, s.checksum, verify, s.id)
+}
+
+// readNthByScanning locates the ordinal n within seg by walking its chunks
+// from the start, for WAL.ReadNth's fallback when seg has no chunk-index
+// sidecar (see readNthChunkIndexEntry). It acquires seg itself, the same
+// way readDecoded does, so it can't race a concurrent Checkpoint retiring
+// it out from under the scan.
+func readNthByScanning(seg *segment, n int) (*ChunkPosition, error) {
+	if !seg.acquire() {
+		return nil, errSegmentNotFound(seg.id)
+	}
+	defer seg.release()
+
+	var offset int64
+	for i := 0; ; i++ {
+		data, err := seg.Read(offset)
+		if err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return nil, io.EOF
+			}
+			return nil, err
+		}
+		if i == n {
+			return &ChunkPosition{SegmentId: seg.id, ChunkOffset: offset, ChunkSize: uint32(len(data))}, nil
+		}
+		offset += int64(chunkHeaderSize) + int64(len(data))
+	}
+}
+
+// prefetch issues a background read of up to n bytes starting at offset,
+// discarding the result, purely to warm the OS page cache ahead of a
+// sequential Reader; see WithReadahead. It's a no-op once this segment's
+// content is already mmap'd (enableMmapRead), since that's already
+// resident in memory.
+func (s *segment) prefetch(offset, n int64) {
+	s.mu.Lock()
+	mmapped := s.mmap != nil
+	s.mu.Unlock()
+	if mmapped || n <= 0 {
+		return
+	}
+	if remaining := s.Size() - offset; remaining < n {
+		n = remaining
+	}
+	if n <= 0 {
+		return
+	}
+	buf := make([]byte, n)
+	_, _ = s.fd.ReadAt(buf, offset)
+}
+
+// enableMmapRead memory-maps the segment's current file content for
+// reading, if fd is a real *os.File -- it won't be for a custom FS, which
+// this feature doesn't support. Call it only once a segment is sealed:
+// mapping an actively-written file would need remapping every time it
+// grows, which costs more than the syscalls mmap is meant to save.
+//
+// advice is applied to the new mapping via madvise before it's published,
+// tuning the kernel's readahead for however this segment is expected to
+// be read; see Options.Madvise. MadviseNormal applies no hint.
+func (s *segment) enableMmapRead(advice Madvise) error {
+	osFd, ok := s.fd.(*os.File)
+	if !ok {
+		return nil
+	}
+	info, err := osFd.Stat()
+	if err != nil {
+		return err
+	}
+	data, err := mmapFile(osFd.Fd(), int(info.Size()))
+	if err != nil {
+		return err
+	}
+	if err := madvise(data, advice); err != nil {
+		munmapFile(data)
+		return err
+	}
+	s.mu.Lock()
+	s.mmap = data
+	s.mu.Unlock()
+	return nil
+}
+
+// disableMmapRead unmaps the segment's file, if enableMmapRead had mapped
+// it. Close and closeAndRemove call this before giving up the fd, since an
+// mmap outlives the fd it was created from being closed.
+func (s *segment) disableMmapRead() error {
+	s.mu.Lock()
+	data := s.mmap
+	s.mmap = nil
+	s.mu.Unlock()
+	if data == nil {
+		return nil
+	}
+	return munmapFile(data)
+}
+
+// dropPageCache hints to the OS that the segment's whole file can be
+// evicted from the page cache, if fd is a real *os.File -- it won't be
+// for a custom FS, which this feature doesn't support. Call it only once
+// a segment is sealed, the same as enableMmapRead: fadvising away an
+// actively-written file's pages would just force them straight back in
+// on the write path's next append. See Options.FadviseDontNeed.
+func (s *segment) dropPageCache() error {
+	osFd, ok := s.fd.(*os.File)
+	if !ok {
+		return nil
+	}
+	return fadviseDontNeed(osFd)
+}
+
+// ReadInto is Read, but appends the chunk's payload to buf instead of
+// allocating a fresh slice for it.
+func (s *segment) ReadInto(offset int64, buf []byte) ([]byte, error) {
+	verify := s.verifyOnRead && !s.chainChecksums
+	if s.writeBufCap > 0 {
+		if payload, _, ok, err := s.readBuffered(offset, verify); ok {
+			if err != nil {
+				return buf, err
+			}
+			return append(buf, payload...), nil
+		}
+	}
+	return readChunkAtInto(s.fd, offset, buf, s.checksum, verify, s.id)
+}
+
+// readChunkAt reads the chunk at the given segment-relative offset (as
+// recorded in a ChunkPosition) from fd via ReadAt, without disturbing fd's
+// sequential write position. checksum is the algorithm the segment was
+// created with; see openSegmentFile. verify is false only when called on
+// behalf of Options.SkipReadVerification, skipping the checksum check
+// below entirely; every other caller always passes true. segID identifies
+// the segment fd belongs to, for the ErrCorrupted a checksum mismatch is
+// reported with.
+func readChunkAt(fd File, offset int64, checksum Checksum, verify bool, segID SegmentID) ([]byte, error) {
+	data, _, err := readChunkAtWithCRC(fd, offset, checksum, verify, segID)
+	return data, err
+}
+
+// readChunkAtWithCRC is readChunkAt, but also returns the checksum recorded
+// in the chunk's header, for Reader.NextWithMeta.
+func readChunkAtWithCRC(fd File, offset int64, checksum Checksum, verify bool, segID SegmentID) ([]byte, uint32, error) {
+	fileOffset := offset + segmentHeaderSize
+
 	header := make([]byte, chunkHeaderSize)
-	if _, err := s.fd.ReadAt(header, offset); err != nil {
-		return nil, err
+	if _, err := fd.ReadAt(header, fileOffset); err != nil {
+		return nil, 0, err
 	}
 	length := binary.BigEndian.Uint32(header[:4])
-	wantCRC := binary.BigEndian.Uint32(header[4:])
+	if length == 0 {
+		// Zero-length header: either unwritten space a filePipeline
+		// pre-allocated past this segment's logical end (see
+		// newSegmentFile), or -- since Write rejects empty records --
+		// there's simply nothing written here yet. Either way, treat it
+		// the same as reading past the end of what's been flushed so far.
+		return nil, 0, io.EOF
+	}
+	wantChecksum := binary.BigEndian.Uint32(header[4:])
 
 	payload := make([]byte, length)
-	if _, err := s.fd.ReadAt(payload, offset+int64(chunkHeaderSize)); err != nil {
+	if _, err := fd.ReadAt(payload, fileOffset+int64(chunkHeaderSize)); err != nil {
+		return nil, 0, err
+	}
+	if verify && !verifyChecksum(checksum, payload, wantChecksum) {
+		return nil, 0, &ErrCorrupted{SegmentID: segID, ChunkOffset: offset, Reason: ErrInvalidCRC}
+	}
+	return payload, wantChecksum, nil
+}
+
+// readChunkAtInto is readChunkAt, but appends the payload to buf instead of
+// allocating a fresh slice for it.
+func readChunkAtInto(fd File, offset int64, buf []byte, checksum Checksum, verify bool, segID SegmentID) ([]byte, error) {
+	fileOffset := offset + segmentHeaderSize
+
+	header := make([]byte, chunkHeaderSize)
+	if _, err := fd.ReadAt(header, fileOffset); err != nil {
 		return nil, err
 	}
-	if crc32.ChecksumIEEE(payload) != wantCRC {
-		return nil, ErrInvalidCRC
+	length := binary.BigEndian.Uint32(header[:4])
+	if length == 0 {
+		return nil, io.EOF
 	}
-	return payload, nil
+	wantChecksum := binary.BigEndian.Uint32(header[4:])
+
+	start := len(buf)
+	buf = append(buf, make([]byte, length)...)
+	payload := buf[start:]
+	if _, err := fd.ReadAt(payload, fileOffset+int64(chunkHeaderSize)); err != nil {
+		return buf[:start], err
+	}
+	if verify && !verifyChecksum(checksum, payload, wantChecksum) {
+		return buf[:start], &ErrCorrupted{SegmentID: segID, ChunkOffset: offset, Reason: ErrInvalidCRC}
+	}
+	return buf, nil
+}
+
+// readChunkFromMmap is readChunkAt, but reads the chunk's header and
+// payload by slicing into data -- the segment's mmap'd file content --
+// instead of issuing pread syscalls.
+func readChunkFromMmap(data []byte, offset int64, checksum Checksum, verify bool, segID SegmentID) ([]byte, error) {
+	out, _, err := readChunkFromMmapWithCRC(data, offset, checksum, verify, segID)
+	return out, err
+}
+
+// readChunkFromMmapWithCRC is readChunkFromMmap, but also returns the
+// checksum recorded in the chunk's header, for Reader.NextWithMeta.
+func readChunkFromMmapWithCRC(data []byte, offset int64, checksum Checksum, verify bool, segID SegmentID) ([]byte, uint32, error) {
+	fileOffset := offset + segmentHeaderSize
+	if fileOffset+int64(chunkHeaderSize) > int64(len(data)) {
+		return nil, 0, io.EOF
+	}
+	header := data[fileOffset : fileOffset+int64(chunkHeaderSize)]
+	length := binary.BigEndian.Uint32(header[:4])
+	if length == 0 {
+		return nil, 0, io.EOF
+	}
+	wantChecksum := binary.BigEndian.Uint32(header[4:])
+
+	payloadStart := fileOffset + int64(chunkHeaderSize)
+	payloadEnd := payloadStart + int64(length)
+	if payloadEnd > int64(len(data)) {
+		return nil, 0, io.ErrUnexpectedEOF
+	}
+	payload := data[payloadStart:payloadEnd]
+	if verify && !verifyChecksum(checksum, payload, wantChecksum) {
+		return nil, 0, &ErrCorrupted{SegmentID: segID, ChunkOffset: offset, Reason: ErrInvalidCRC}
+	}
+	// Copy out rather than returning a slice of the mmap directly: callers
+	// may hold onto it well past this call, and the mapping disappears as
+	// soon as the segment is retired or the WAL is closed.
+	out := make([]byte, len(payload))
+	copy(out, payload)
+	return out, wantChecksum, nil
+}
+
+// readChunkFromBufferWithCRC is readChunkFromMmapWithCRC, but for
+// segment.writeBuf instead of a segment's mmap: buf holds only framed
+// chunks, with no segmentHeaderSize prefix, so relOffset is the chunk's
+// ChunkOffset relative to where writeBuf starts (see readBuffered), not an
+// absolute file offset.
+func readChunkFromBufferWithCRC(buf []byte, relOffset int64, checksum Checksum, verify bool, segID SegmentID) ([]byte, uint32, error) {
+	if relOffset+int64(chunkHeaderSize) > int64(len(buf)) {
+		return nil, 0, io.EOF
+	}
+	header := buf[relOffset : relOffset+int64(chunkHeaderSize)]
+	length := binary.BigEndian.Uint32(header[:4])
+	if length == 0 {
+		return nil, 0, io.EOF
+	}
+	wantChecksum := binary.BigEndian.Uint32(header[4:])
+
+	payloadStart := relOffset + int64(chunkHeaderSize)
+	payloadEnd := payloadStart + int64(length)
+	if payloadEnd > int64(len(buf)) {
+		return nil, 0, io.ErrUnexpectedEOF
+	}
+	payload := buf[payloadStart:payloadEnd]
+	if verify && !verifyChecksum(checksum, payload, wantChecksum) {
+		return nil, 0, &ErrCorrupted{SegmentID: segID, ChunkOffset: relOffset, Reason: ErrInvalidCRC}
+	}
+	out := make([]byte, len(payload))
+	copy(out, payload)
+	return out, wantChecksum, nil
+}
+
+// truncate trims the segment's file down to newOffset bytes of chunk data
+// (i.e. segmentHeaderSize+newOffset bytes of physical file), discarding
+// anything written past that point, and repositions fd for further
+// sequential Writes to land right after it.
+func (s *segment) truncate(newOffset int64) error {
+	// Flushed first (a no-op unless Options.SegmentWriteBufferSize is set
+	// and something is buffered) so fd actually has every byte up to the
+	// old s.offset for Truncate to cut down from; otherwise truncating to
+	// newOffset < s.offset here could leave writeBuf holding bytes for an
+	// offset range this segment no longer claims to have.
+	if err := s.flushWriteBuffer(); err != nil {
+		return err
+	}
+	if err := s.fd.Truncate(segmentHeaderSize + newOffset); err != nil {
+		return err
+	}
+	if _, err := s.fd.Seek(segmentHeaderSize+newOffset, io.SeekStart); err != nil {
+		return err
+	}
+	s.offset = newOffset
+	return nil
+}
+
+// seal finalizes a segment once rotateIfNeeded has rotated it out of
+// being the active one: it drops any trailing pre-allocated space past
+// the segment's logical end (see newSegmentFile), now that nothing will
+// ever be appended to it again, and appends a footer recording its
+// offset, chunk count, sparse index, and a whole-segment checksum, so a
+// later openSegmentFile can reload it without rescanning its chunks. The
+// caller must have already synced the segment's real data; seal syncs
+// again itself to cover the footer.
+//
+// If s.chunkIndex is set (Options.ChunkIndex), seal also writes this
+// segment's dense chunk-index sidecar (see writeChunkIndex), enabling
+// WAL.ReadNth's O(1) path for it.
+//
+// If s.signingKey is set (Options.SegmentSigningKey), seal also signs the
+// footer's whole-segment checksum and writes the signature to a .SIG
+// sidecar (see signSegmentDigest), giving VerifySignatures and
+// Options.SegmentVerifyKey something to check tamper evidence against.
+func (s *segment) seal() error {
+	if err := s.flushWriteBuffer(); err != nil {
+		return err
+	}
+	if err := s.fd.Truncate(segmentHeaderSize + s.offset); err != nil {
+		return err
+	}
+	if _, err := s.fd.Seek(segmentHeaderSize+s.offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	payload := make([]byte, s.offset)
+	if _, err := s.fd.ReadAt(payload, segmentHeaderSize); err != nil {
+		return err
+	}
+
+	index := s.sparseIndexSnapshot()
+	f := footer{
+		offset:     s.offset,
+		chunkCount: s.chunkCountSnapshot(),
+		checksum:   checksumPayload(s.checksum, payload),
+		index:      index,
+	}
+	if err := writeFooter(s.fd, f); err != nil {
+		return err
+	}
+
+	if s.chunkIndex && f.chunkCount > 0 {
+		entries, err := scanChunkIndexEntries(payload)
+		if err != nil {
+			return err
+		}
+		if err := writeChunkIndex(s.path, index[0].sequence, entries); err != nil {
+			return err
+		}
+	}
+
+	if s.signingKey != nil {
+		if err := signSegmentDigest(s.path, s.id, f.checksum, s.signingKey); err != nil {
+			return err
+		}
+	}
+
+	return s.fd.Sync()
 }
 
-// Sync flushes the segment's data to stable storage.
+// Sync flushes the segment's data to stable storage. If Options.Fdatasync
+// is set and the segment's fd is a real *os.File, this flushes only data
+// blocks, skipping metadata a WAL segment's reader never depends on; it
+// falls back to a full fsync otherwise. It flushes writeBuf first (see
+// flushWriteBuffer), a no-op unless Options.SegmentWriteBufferSize is set
+// and something is buffered, since fsyncing fd can't make durable what
+// hasn't reached fd yet.
 func (s *segment) Sync() error {
+	if err := s.flushWriteBuffer(); err != nil {
+		return err
+	}
+	if s.fdatasync {
+		if osFd, ok := s.fd.(*os.File); ok {
+			return fdatasyncFile(osFd)
+		}
+	}
 	return s.fd.Sync()
 }
 
-// Size returns the number of bytes written to the segment so far.
+// syncRange asks the kernel to start writing back the segment's
+// [offset, offset+nbytes) byte range without waiting for that writeback
+// to finish, for Options.SyncFileRange. It's a no-op hint, not a fsync:
+// if the segment's fd isn't a real *os.File, or this platform has no
+// sync_file_range syscall, it falls back to a full, blocking Sync instead
+// of silently skipping the flush altogether. Either way it flushes
+// writeBuf first, the same as Sync, so the range it asks the kernel to
+// write back has actually reached fd.
+func (s *segment) syncRange(offset, nbytes int64) error {
+	if nbytes <= 0 {
+		return nil
+	}
+	osFd, ok := s.fd.(*os.File)
+	if !ok {
+		return s.Sync()
+	}
+	if err := s.flushWriteBuffer(); err != nil {
+		return err
+	}
+	return syncFileRange(osFd, offset+segmentHeaderSize, nbytes)
+}
+
+// Size returns the number of payload bytes written to the segment so far,
+// not counting the segment header.
 func (s *segment) Size() int64 {
 	return s.offset
 }
 
-// Close closes the segment's underlying file.
+// Close closes the segment's underlying file. It flushes writeBuf first
+// (see flushWriteBuffer), a no-op unless Options.SegmentWriteBufferSize is
+// set and something is buffered, so closing never silently drops data
+// that only ever existed in memory.
 func (s *segment) Close() error {
+	if err := s.flushWriteBuffer(); err != nil {
+		return err
+	}
+	if err := s.disableMmapRead(); err != nil {
+		return err
+	}
 	return s.fd.Close()
 }
 
+// acquire reserves a reference on the segment so a concurrent retire (see
+// retire) won't close its fd out from under the caller's I/O. It reports
+// false if the segment has already been retired -- its data has been
+// folded into a checkpoint and the segment itself is gone, so the caller
+// should treat it as having nothing left to read.
+func (s *segment) acquire() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.retired {
+		return false
+	}
+	s.refs++
+	return true
+}
+
+// release gives up a reference taken by acquire, finishing a retire that
+// was deferred because reads were still in flight.
+func (s *segment) release() {
+	s.mu.Lock()
+	s.refs--
+	finish := s.retired && s.refs == 0
+	s.mu.Unlock()
+	if finish {
+		s.closeAndRemove()
+	}
+}
+
+// pinned reports whether some in-flight WAL.Read or Reader.Next call
+// currently holds a reference on the segment via acquire. See
+// Options.PinnedSegmentPolicy.
+func (s *segment) pinned() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.refs > 0
+}
+
+// retire marks the segment as compacted away: once every acquire call
+// already in flight has released it, its fd is closed and its file
+// removed (or, if pipeline is non-nil, handed to it for reuse as a future
+// segment instead -- see filePipeline.Recycle). If none are in flight
+// right now, that happens immediately. pipeline should only be passed
+// when Options.RecycleSegments is set; it has no effect otherwise.
+func (s *segment) retire(pipeline *filePipeline) {
+	s.mu.Lock()
+	s.retired = true
+	s.recyclePipeline = pipeline
+	finish := s.refs == 0
+	s.mu.Unlock()
+	if finish {
+		s.closeAndRemove()
+	}
+}
+
+func (s *segment) closeAndRemove() {
+	s.disableMmapRead()
+	if s.recyclePipeline != nil {
+		if osFile, ok := s.fd.(*os.File); ok && s.recyclePipeline.Recycle(osFile) {
+			return
+		}
+	}
+	s.fd.Close()
+	os.Remove(s.path)
+	// Best-effort: retire has no caller left to report an error to, and the
+	// failure mode -- a removed segment's directory entry resurfacing
+	// after a crash -- is the same risk every call to os.Remove in this
+	// package already carries without this fsync.
+	syncDir(filepath.Dir(s.path))
+}
+
 // appendChunk writes payload to fd, framed as a chunk, at fd's current
-// write offset.
-func appendChunk(fd *os.File, payload []byte) error {
+// write offset. If chained is true (Options.ChainChecksums), the chunk's
+// checksum folds in prev -- the checksum returned by the call that wrote
+// the chunk immediately before it, or 0 for a segment's first chunk --
+// instead of covering payload alone. It returns the checksum actually
+// written, for the caller to thread through as prev for the next chunk.
+func appendChunk(fd File, payload []byte, checksum Checksum, chained bool, prev uint32) (uint32, error) {
+	sum := checksumPayload(checksum, payload)
+	if chained {
+		sum = chainedChecksumPayload(checksum, prev, payload)
+	}
 	header := make([]byte, chunkHeaderSize)
 	binary.BigEndian.PutUint32(header[:4], uint32(len(payload)))
-	binary.BigEndian.PutUint32(header[4:], crc32.ChecksumIEEE(payload))
+	binary.BigEndian.PutUint32(header[4:], sum)
 	if _, err := fd.Write(header); err != nil {
+		return 0, err
+	}
+	if _, err := fd.Write(payload); err != nil {
+		return 0, err
+	}
+	return sum, nil
+}
+
+// writeStreamChunk frames payload exactly as appendChunk does and writes
+// it to w. Unlike appendChunk, w only needs to be an io.Writer, not a
+// File -- for WAL.Export, whose destination (a pipe, a socket, an HTTP
+// body) is typically not seekable.
+func writeStreamChunk(w io.Writer, payload []byte, checksum Checksum) error {
+	header := make([]byte, chunkHeaderSize)
+	binary.BigEndian.PutUint32(header[:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(header[4:], checksumPayload(checksum, payload))
+	if _, err := w.Write(header); err != nil {
 		return err
 	}
-	_, err := fd.Write(payload)
+	_, err := w.Write(payload)
 	return err
 }
 
-// readChunk reads one length-prefixed, CRC-checked chunk from the current
-// position of f, advancing it past the chunk. It returns io.EOF once f is
+// appendChunks frames each of payloads as its own chunk -- a header
+// followed by the payload, exactly as appendChunk would write one at a
+// time, chaining each onto the last the same way when chained is true --
+// and writes every resulting header and payload to fd in a single writev
+// call when fd is a real *os.File on a platform this package knows how to
+// vector-write on (see writevFile), instead of one Write syscall per
+// header and per payload. It falls back to looping appendChunk otherwise.
+// It returns the checksum written to the last of payloads (or prev
+// unchanged if payloads is empty), for the caller to thread through as
+// prev for whatever is appended next.
+func appendChunks(fd File, payloads [][]byte, checksum Checksum, chained bool, prev uint32) (uint32, error) {
+	osFd, ok := fd.(*os.File)
+	if !ok {
+		return appendChunksOneByOne(fd, payloads, checksum, chained, prev)
+	}
+
+	bufs := make([][]byte, 0, len(payloads)*2)
+	for _, payload := range payloads {
+		sum := checksumPayload(checksum, payload)
+		if chained {
+			sum = chainedChecksumPayload(checksum, prev, payload)
+		}
+		header := make([]byte, chunkHeaderSize)
+		binary.BigEndian.PutUint32(header[:4], uint32(len(payload)))
+		binary.BigEndian.PutUint32(header[4:], sum)
+		bufs = append(bufs, header, payload)
+		prev = sum
+	}
+	if err := writevFile(osFd, bufs); err != nil {
+		return 0, err
+	}
+	return prev, nil
+}
+
+// appendChunksOneByOne is appendChunks' portable fallback for a custom FS,
+// which writevFile can't vector-write on.
+func appendChunksOneByOne(fd File, payloads [][]byte, checksum Checksum, chained bool, prev uint32) (uint32, error) {
+	for _, payload := range payloads {
+		sum, err := appendChunk(fd, payload, checksum, chained, prev)
+		if err != nil {
+			return 0, err
+		}
+		prev = sum
+	}
+	return prev, nil
+}
+
+// readChunk reads one length-prefixed, checksummed chunk from the current
+// position of f, advancing it past the chunk, and verifies it as an
+// unchained chunk (see readChunkRaw if the caller needs to verify it some
+// other way instead, e.g. as a chained chunk). It returns io.EOF once f is
 // exhausted between chunks.
-func readChunk(f *os.File) ([]byte, error) {
+func readChunk(f io.Reader, checksum Checksum) ([]byte, error) {
+	payload, wantChecksum, err := readChunkRaw(f)
+	if err != nil {
+		return nil, err
+	}
+	if !verifyChecksum(checksum, payload, wantChecksum) {
+		return nil, ErrInvalidCRC
+	}
+	return payload, nil
+}
+
+// readChunkRaw reads one length-prefixed chunk from the current position
+// of f, advancing it past the chunk, without verifying its checksum --
+// just returning it alongside the payload for the caller to check itself.
+// It returns io.EOF once f is exhausted between chunks, the same as
+// readChunk.
+func readChunkRaw(f io.Reader) ([]byte, uint32, error) {
 	header := make([]byte, chunkHeaderSize)
 	if _, err := io.ReadFull(f, header); err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	length := binary.BigEndian.Uint32(header[:4])
-	wantCRC := binary.BigEndian.Uint32(header[4:])
+	if length == 0 {
+		// A zero-length header marks the end of what's actually been
+		// written, whether that's pre-allocated padding past a segment's
+		// logical end (see newSegmentFile) or simply the tail of a
+		// checkpoint file -- since Write rejects empty records, there's no
+		// legitimate zero-length chunk to distinguish it from.
+		return nil, 0, io.EOF
+	}
+	wantChecksum := binary.BigEndian.Uint32(header[4:])
 
 	payload := make([]byte, length)
 	if _, err := io.ReadFull(f, payload); err != nil {
-		return nil, err
-	}
-	if crc32.ChecksumIEEE(payload) != wantCRC {
-		return nil, ErrInvalidCRC
+		return nil, 0, err
 	}
-	return payload, nil
+	return payload, wantChecksum, nil
 }
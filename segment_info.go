@@ -0,0 +1,95 @@
+package wal
+
+// SegmentInfo describes one segment's current state, as returned by
+// Segments. It's a point-in-time snapshot: a concurrent Write, rotation,
+// Checkpoint, or Truncate can make it stale the moment Segments returns.
+type SegmentInfo struct {
+	// ID is the segment's SegmentID.
+	ID SegmentID
+
+	// Size is the number of payload bytes written to the segment so far,
+	// not counting its header.
+	Size int64
+
+	// ChunkCount is how many chunks the segment holds.
+	ChunkCount int64
+
+	// First and Last are the positions of the segment's first and last
+	// chunk, or nil if it holds none yet (only possible for a freshly
+	// rotated active segment with nothing written to it).
+	First *ChunkPosition
+	Last  *ChunkPosition
+
+	// Sealed is false only for the currently active segment: every other
+	// segment Segments returns is sealed and will never be appended to
+	// again.
+	Sealed bool
+
+	// PayloadBytes is Size minus every chunk's framing overhead: the
+	// number of bytes actually occupied by callers' own data.
+	PayloadBytes int64
+
+	// FramingOverheadBytes is ChunkCount * chunkHeaderSize, the only
+	// per-record overhead this package has. There's no block padding to
+	// report alongside it, because chunks aren't packed into fixed-size
+	// blocks in the first place; see Options.BlockSize for why tuning it
+	// doesn't change this number.
+	FramingOverheadBytes int64
+
+	// UnusedPreallocatedBytes is how much of the segment's on-disk file
+	// is pre-allocated (see Options.Preallocate and
+	// Options.SegmentPreallocate) but holds neither a chunk nor the
+	// segment footer yet. It's always 0 for a sealed segment, since seal
+	// truncates this away once nothing will ever be appended to it
+	// again, so it's only ever worth checking on the active segment.
+	UnusedPreallocatedBytes int64
+}
+
+// Segments returns a snapshot of every segment this WAL currently holds,
+// oldest first, for introspection tools like retention dashboards or the
+// CLI commands under cmd/ -- anything that would otherwise have to
+// hand-roll its own scan of the directory's segment bookkeeping.
+//
+// Unlike Stats, which is a free, purely in-memory snapshot, Segments reads
+// each segment's chunks to find its exact First and Last position, so
+// avoid calling it from a hot path. If that read fails for a particular
+// segment, First and Last are simply left nil for it rather than failing
+// the whole call -- ID, Size, ChunkCount, Sealed, PayloadBytes, and
+// FramingOverheadBytes need no I/O at all. UnusedPreallocatedBytes needs a
+// Stat of the segment's file; if that fails it's simply left 0.
+func (w *WAL) Segments() []SegmentInfo {
+	w.mu.RLock()
+	segments := w.orderedSegmentsLocked()
+	activeID := w.activeSegment.id
+	w.mu.RUnlock()
+
+	infos := make([]SegmentInfo, len(segments))
+	var preceding uint64
+	for i, seg := range segments {
+		chunkCount := seg.chunkCountSnapshot()
+		size := seg.Size()
+		framingOverhead := int64(chunkCount) * chunkHeaderSize
+		info := SegmentInfo{
+			ID:                   seg.id,
+			Size:                 size,
+			ChunkCount:           chunkCount,
+			Sealed:               seg.id != activeID,
+			PayloadBytes:         size - framingOverhead,
+			FramingOverheadBytes: framingOverhead,
+		}
+		if positions, err := positionsInSegment(seg, preceding); err == nil && len(positions) > 0 {
+			info.First = positions[0]
+			info.Last = positions[len(positions)-1]
+		}
+		if !info.Sealed {
+			if fi, err := seg.fd.Stat(); err == nil {
+				if unused := fi.Size() - (segmentHeaderSize + size); unused > 0 {
+					info.UnusedPreallocatedBytes = unused
+				}
+			}
+		}
+		preceding += uint64(chunkCount)
+		infos[i] = info
+	}
+	return infos
+}
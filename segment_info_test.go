@@ -0,0 +1,106 @@
+package wal
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSegmentsReportsSizeChunkCountAndSealedStatus(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-segments-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(32))
+	require.Nil(t, err)
+	defer w.Close()
+
+	var positions []*ChunkPosition
+	for i := 0; i < 6; i++ {
+		pos, err := w.Write([]byte("0123456789"))
+		require.Nil(t, err)
+		positions = append(positions, pos)
+	}
+	require.Greater(t, len(w.olderSegments), 0)
+
+	infos := w.Segments()
+	require.Len(t, infos, len(w.olderSegments)+1)
+
+	var sealedCount, activeCount int
+	for _, info := range infos {
+		require.Greater(t, info.ChunkCount, int64(0))
+		require.NotNil(t, info.First)
+		require.NotNil(t, info.Last)
+		require.Equal(t, info.ID, info.First.SegmentId)
+		if info.Sealed {
+			sealedCount++
+		} else {
+			activeCount++
+			require.Equal(t, w.activeSegment.id, info.ID)
+		}
+	}
+	require.Equal(t, len(w.olderSegments), sealedCount)
+	require.Equal(t, 1, activeCount)
+
+	require.Equal(t, positions[0], infos[0].First)
+	require.Equal(t, positions[len(positions)-1], infos[len(infos)-1].Last)
+}
+
+func TestSegmentsReportsPayloadAndFramingOverhead(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-segments-overhead-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	for i := 0; i < 4; i++ {
+		_, err := w.Write([]byte("0123456789"))
+		require.Nil(t, err)
+	}
+
+	infos := w.Segments()
+	require.Len(t, infos, 1)
+	info := infos[0]
+
+	require.Equal(t, info.ChunkCount*chunkHeaderSize, info.FramingOverheadBytes)
+	require.Equal(t, info.Size-info.FramingOverheadBytes, info.PayloadBytes)
+	require.Equal(t, int64(40), info.PayloadBytes)
+}
+
+func TestSegmentsReportsUnusedPreallocatedBytesOnlyForTheActiveSegment(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-segments-preallocate-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(4096), WithPreallocate(true))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("a small record"))
+	require.Nil(t, err)
+
+	infos := w.Segments()
+	require.Len(t, infos, 1)
+	require.False(t, infos[0].Sealed)
+	require.Greater(t, infos[0].UnusedPreallocatedBytes, int64(0))
+}
+
+func TestSegmentsOnAFreshWAL(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-segments-fresh-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	infos := w.Segments()
+	require.Len(t, infos, 1)
+	require.Equal(t, int64(0), infos[0].ChunkCount)
+	require.Nil(t, infos[0].First)
+	require.Nil(t, infos[0].Last)
+	require.False(t, infos[0].Sealed)
+}
@@ -0,0 +1,75 @@
+package wal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func customSegmentName(id SegmentID) string {
+	return fmt.Sprintf("custom-%d.wal", id)
+}
+
+func parseCustomSegmentName(name string) (SegmentID, bool) {
+	rest, ok := strings.CutPrefix(name, "custom-")
+	if !ok {
+		return 0, false
+	}
+	rest, ok = strings.CutSuffix(rest, ".wal")
+	if !ok {
+		return 0, false
+	}
+	id, err := strconv.Atoi(rest)
+	if err != nil {
+		return 0, false
+	}
+	return SegmentID(id), true
+}
+
+func TestWithSegmentFileNamingUsesCustomScheme(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-segment-naming-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	naming := WithSegmentFileNaming(customSegmentName, parseCustomSegmentName)
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(32), naming)
+	require.Nil(t, err)
+
+	pos1, err := w.Write([]byte("first-segment"))
+	require.Nil(t, err)
+	_, err = w.Write([]byte("second-segment-forces-rotation"))
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+
+	entries, err := os.ReadDir(dir)
+	require.Nil(t, err)
+	var sawCustomName bool
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), "custom-") {
+			sawCustomName = true
+			continue
+		}
+		require.NotEqual(t, ".SEG", filepath.Ext(e.Name()), "a built-in-named segment file leaked into a WAL using custom naming: %s", e.Name())
+	}
+	require.True(t, sawCustomName, "no segment file used the custom naming scheme")
+
+	w2, err := Open(WithDirPath(dir), WithSegmentSize(32), naming)
+	require.Nil(t, err)
+	defer w2.Close()
+
+	data, err := w2.Read(pos1)
+	require.Nil(t, err)
+	require.Equal(t, []byte("first-segment"), data)
+
+	pos3, err := w2.Write([]byte("third-segment"))
+	require.Nil(t, err)
+	data, err = w2.Read(pos3)
+	require.Nil(t, err)
+	require.Equal(t, []byte("third-segment"), data)
+}
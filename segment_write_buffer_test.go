@@ -0,0 +1,210 @@
+package wal
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSegmentWriteBufferSizeDefaultsToDisabled(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-segment-write-buffer-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"))
+	require.Nil(t, err)
+	defer w.Close()
+
+	assert.Equal(t, 0, w.activeSegment.writeBufCap, "SegmentWriteBufferSize defaults to 0, leaving buffering disabled")
+
+	pos, err := w.Write([]byte("unbuffered"))
+	require.Nil(t, err)
+	assert.Empty(t, w.activeSegment.writeBuf, "disabled buffering should never accumulate anything")
+
+	got, err := w.Read(pos)
+	require.Nil(t, err)
+	assert.Equal(t, []byte("unbuffered"), got)
+}
+
+func TestWithSegmentWriteBufferSizeAccumulatesBelowCap(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-segment-write-buffer-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"), WithSegmentWriteBufferSize(1*KB))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("small record"))
+	require.Nil(t, err)
+
+	assert.NotEmpty(t, w.activeSegment.writeBuf, "a write under the cap should still be sitting in memory")
+
+	info, err := os.Stat(w.activeSegment.path)
+	require.Nil(t, err)
+	assert.Equal(t, int64(segmentHeaderSize), info.Size(), "an unflushed write shouldn't have reached the file yet")
+}
+
+func TestSegmentWriteBufferFlushesOnceCapIsReached(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-segment-write-buffer-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"), WithSegmentWriteBufferSize(16))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("0123456789abcdef"))
+	require.Nil(t, err)
+
+	assert.Empty(t, w.activeSegment.writeBuf, "a write that fills the cap should flush immediately")
+
+	info, err := os.Stat(w.activeSegment.path)
+	require.Nil(t, err)
+	assert.Greater(t, info.Size(), int64(segmentHeaderSize))
+}
+
+func TestSegmentWriteBufferReadsBackOwnUnflushedWrite(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-segment-write-buffer-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"), WithSegmentWriteBufferSize(1*KB))
+	require.Nil(t, err)
+	defer w.Close()
+
+	want := []byte("read-your-own-writes through the buffer")
+	pos, err := w.Write(want)
+	require.Nil(t, err)
+	require.NotEmpty(t, w.activeSegment.writeBuf)
+
+	got, err := w.Read(pos)
+	require.Nil(t, err)
+	assert.Equal(t, want, got)
+}
+
+func TestSegmentWriteBufferReadsBackUnflushedBatch(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-segment-write-buffer-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"), WithSegmentWriteBufferSize(1*KB))
+	require.Nil(t, err)
+	defer w.Close()
+
+	want := [][]byte{[]byte("first"), []byte("second"), []byte("third")}
+	for _, data := range want {
+		require.Nil(t, w.PendingWrites(data))
+	}
+	positions, err := w.WriteAll()
+	require.Nil(t, err)
+	require.NotEmpty(t, w.activeSegment.writeBuf)
+
+	for i, data := range want {
+		got, err := w.Read(positions[i])
+		require.Nil(t, err)
+		assert.Equal(t, data, got)
+	}
+}
+
+func TestSegmentWriteBufferFlushesBeforeSync(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-segment-write-buffer-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"), WithSegmentWriteBufferSize(1*KB))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("flush me before fsync"))
+	require.Nil(t, err)
+	require.NotEmpty(t, w.activeSegment.writeBuf)
+
+	_, err = w.Sync()
+	require.Nil(t, err)
+	assert.Empty(t, w.activeSegment.writeBuf, "Sync must flush writeBuf before fsyncing, or the fsync covers nothing new")
+}
+
+func TestSegmentWriteBufferSurvivesCloseAndReopen(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-segment-write-buffer-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"), WithSegmentWriteBufferSize(1*KB))
+	require.Nil(t, err)
+
+	want := []byte("buffered record surviving a graceful close")
+	_, err = w.Write(want)
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+
+	w2, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"), WithSegmentWriteBufferSize(1*KB))
+	require.Nil(t, err)
+	defer w2.Close()
+
+	reader := w2.NewReader()
+	data, _, err := reader.Next()
+	require.Nil(t, err)
+	assert.Equal(t, want, data)
+}
+
+func TestSegmentWriteBufferConcurrentReadsDontRaceTheWriter(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-segment-write-buffer-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"), WithSegmentWriteBufferSize(64))
+	require.Nil(t, err)
+	defer w.Close()
+
+	const writes = 200
+	positions := make([]*ChunkPosition, 0, writes)
+	var mu sync.Mutex
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < writes; i++ {
+			pos, err := w.Write([]byte(fmt.Sprintf("record-%d", i)))
+			require.Nil(t, err)
+			mu.Lock()
+			positions = append(positions, pos)
+			mu.Unlock()
+		}
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				mu.Lock()
+				n := len(positions)
+				var pos *ChunkPosition
+				if n > 0 {
+					pos = positions[n-1]
+				}
+				mu.Unlock()
+				if pos != nil {
+					_, _ = w.Read(pos)
+				}
+			}
+		}
+	}()
+
+	wg.Wait()
+	close(done)
+
+	for i, pos := range positions {
+		got, err := w.Read(pos)
+		require.Nil(t, err)
+		assert.Equal(t, []byte(fmt.Sprintf("record-%d", i)), got)
+	}
+}
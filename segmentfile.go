@@ -0,0 +1,101 @@
+package wal
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// SegmentFile is a read-only view of a single segment file, opened
+// independently of the WAL directory it came from by OpenSegmentFile. It
+// supports exactly what inspecting one file in isolation needs -- reading
+// its header and iterating its chunks -- not the rest of *WAL's surface
+// (Write, Checkpoint, Compact, and so on all assume a whole directory of
+// segments to coordinate, not one file handed over on its own).
+type SegmentFile struct {
+	fd          *os.File
+	id          SegmentID
+	checksum    Checksum
+	chained     bool
+	compression CompressionCodec
+
+	offset  int64
+	nextSeq uint64
+}
+
+// OpenSegmentFile opens the segment file at path read-only and reads its
+// header, without requiring the rest of the WAL directory it came from --
+// for a support team handed a single .SEG file with no other context.
+// Close it when done.
+//
+// Its chunks' payloads are decompressed according to the codec recorded in
+// the segment's own header, the same as a Reader's would be. They are not
+// decrypted or un-timestamped, since WithEncryptionKey and WithTimestamps
+// leave no trace in the segment file itself to recover that context from.
+// Nor are they decompressed correctly if the segment was written with
+// Options.CompressionDict: OpenSegmentFile has no way to be handed the
+// dictionary's bytes, only its ID (see dictIDOffset), so Next returns
+// whatever error decompressing without that dictionary produces. Nor,
+// similarly, does Next verify a segment written with Options.ChainChecksums
+// -- doing so chunk-by-chunk the way Next reads them would need the
+// checksum chained into each one, which this single-file, no-directory
+// tool has no earlier scan state to supply -- so it returns every chunk
+// unverified instead of misreporting all of them as corrupt.
+func OpenSegmentFile(path string) (*SegmentFile, error) {
+	fd, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	_, checksum, compression, _, _, chained, err := readSegmentHeader(fd)
+	if err != nil {
+		fd.Close()
+		return nil, err
+	}
+
+	// Best-effort: segmentFileName's zero-padded-number scheme is a
+	// convention, not something the file's own bytes record, so a renamed
+	// file just gets SegmentID 0 back.
+	var id SegmentID
+	fmt.Sscanf(filepath.Base(path), "%d"+filepath.Ext(path), &id)
+
+	return &SegmentFile{fd: fd, id: id, checksum: checksum, chained: chained, compression: compression}, nil
+}
+
+// ID returns the segment's ID, parsed from path's filename by
+// OpenSegmentFile. It is 0 if path didn't follow segmentFileName's
+// zero-padded-number-plus-extension scheme.
+func (s *SegmentFile) ID() SegmentID {
+	return s.id
+}
+
+// Next returns the next chunk's data and position, or io.EOF once it has
+// read every chunk the file contains -- the same contract as Reader.Next,
+// except ChunkPosition.Sequence numbers only this file's own chunks from
+// zero, since a SegmentFile has no visibility into any segment besides
+// this one.
+func (s *SegmentFile) Next() ([]byte, *ChunkPosition, error) {
+	data, err := readChunkAt(s.fd, s.offset, s.checksum, !s.chained, s.id)
+	if err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = io.EOF
+		}
+		return nil, nil, err
+	}
+
+	pos := &ChunkPosition{SegmentId: s.id, ChunkOffset: s.offset, ChunkSize: uint32(len(data)), Sequence: s.nextSeq}
+	s.nextSeq++
+	s.offset += int64(chunkHeaderSize) + int64(len(data))
+
+	decoded, err := decompressPayload(s.compression, data, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return decoded, pos, nil
+}
+
+// Close closes the underlying file.
+func (s *SegmentFile) Close() error {
+	return s.fd.Close()
+}
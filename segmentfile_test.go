@@ -0,0 +1,112 @@
+package wal
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenSegmentFileIteratesChunks(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-segmentfile-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(1024))
+	require.Nil(t, err)
+
+	want := [][]byte{[]byte("first"), []byte("second"), []byte("third")}
+	for _, p := range want {
+		_, err := w.Write(p)
+		require.Nil(t, err)
+	}
+	require.Nil(t, w.Close())
+
+	segPath, _, err := lastSegmentFile(dir, DefaultOptions.SegmentFileExt, nil)
+	require.Nil(t, err)
+
+	sf, err := OpenSegmentFile(segPath)
+	require.Nil(t, err)
+	defer sf.Close()
+
+	require.Equal(t, SegmentID(1), sf.ID())
+
+	var got [][]byte
+	var seqs []uint64
+	for {
+		data, pos, err := sf.Next()
+		if err == io.EOF {
+			break
+		}
+		require.Nil(t, err)
+		got = append(got, data)
+		seqs = append(seqs, pos.Sequence)
+		require.Equal(t, SegmentID(1), pos.SegmentId)
+	}
+
+	require.Equal(t, want, got)
+	require.Equal(t, []uint64{0, 1, 2}, seqs)
+}
+
+func TestOpenSegmentFileIDFromUnrecognizedPath(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-segmentfile-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	_, err = w.Write([]byte("hello"))
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+
+	segPath, _, err := lastSegmentFile(dir, DefaultOptions.SegmentFileExt, nil)
+	require.Nil(t, err)
+
+	renamed := filepath.Join(dir, "exported.bin")
+	require.Nil(t, os.Rename(segPath, renamed))
+
+	sf, err := OpenSegmentFile(renamed)
+	require.Nil(t, err)
+	defer sf.Close()
+
+	require.Equal(t, SegmentID(0), sf.ID())
+
+	data, _, err := sf.Next()
+	require.Nil(t, err)
+	require.Equal(t, []byte("hello"), data)
+}
+
+func TestOpenSegmentFileStopsAtTornTailWithoutError(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-segmentfile-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	_, err = w.Write([]byte("whole"))
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+
+	segPath, _, err := lastSegmentFile(dir, DefaultOptions.SegmentFileExt, nil)
+	require.Nil(t, err)
+
+	f, err := os.OpenFile(segPath, os.O_RDWR, 0o644)
+	require.Nil(t, err)
+	require.Nil(t, f.Truncate(segmentHeaderSize+chunkHeaderSize+int64(len("whole"))+4))
+	_, err = f.WriteAt([]byte{0, 0, 0, 9}, segmentHeaderSize+int64(chunkHeaderSize)+int64(len("whole")))
+	require.Nil(t, err)
+	require.Nil(t, f.Close())
+
+	sf, err := OpenSegmentFile(segPath)
+	require.Nil(t, err)
+	defer sf.Close()
+
+	data, _, err := sf.Next()
+	require.Nil(t, err)
+	require.Equal(t, []byte("whole"), data)
+
+	_, _, err = sf.Next()
+	require.Equal(t, io.EOF, err)
+}
@@ -0,0 +1,87 @@
+package wal
+
+import (
+	"io"
+	"sort"
+)
+
+// indexExistingChunks walks every segment, assigning each chunk already on
+// disk the sequence number it would have been given when it was written,
+// and feeds it to seg.recordSequence so every segment's sparse index is
+// populated before Open returns -- not just the ones built up going
+// forward. It returns the count of chunks found, i.e. the sequence number
+// Open should resume at. It reads raw chunk headers only, skipping
+// decompression and decryption, since only size and position are needed.
+//
+// A segment whose footerLoaded is set (see segment.seal) already has its
+// chunkCount and sparse index populated from a trusted on-disk footer, so
+// it's skipped entirely rather than read chunk by chunk -- this is what
+// keeps Open's cost independent of how much of the log has already been
+// sealed away.
+func indexExistingChunks(segments []*segment) (uint64, error) {
+	var seq uint64
+	for _, seg := range segments {
+		if seg.footerLoaded {
+			if chunkCount := seg.chunkCountSnapshot(); chunkCount > 0 {
+				seq = seg.sparseIndexSnapshot()[0].sequence + uint64(chunkCount)
+			}
+			continue
+		}
+
+		var offset int64
+		for {
+			data, err := seg.Read(offset)
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				break
+			}
+			if err != nil {
+				return 0, err
+			}
+			seg.recordSequence(seq, offset)
+			seq++
+			offset += int64(chunkHeaderSize) + int64(len(data))
+		}
+	}
+	return seq, nil
+}
+
+// ReadBySequence returns the chunk assigned the given sequence number by
+// Write or WriteAll. Sequence numbers are assigned in write order starting
+// at 0 the first time a directory is used as a WAL, so this is equivalent
+// to (but doesn't require tracking) "the Nth chunk ever written here".
+//
+// It uses each segment's sparse index to jump close to seq, then scans at
+// most sparseIndexInterval chunks to land on it exactly.
+func (w *WAL) ReadBySequence(seq uint64) ([]byte, error) {
+	r := w.readerFromSparseIndex(seq)
+	for {
+		data, pos, err := r.Next()
+		if err != nil {
+			return nil, err
+		}
+		if pos.Sequence == seq {
+			return data, nil
+		}
+		if pos.Sequence > seq {
+			return nil, io.EOF
+		}
+	}
+}
+
+// readerFromSparseIndex returns a Reader positioned at the latest indexed
+// chunk at or before seq, across all of the WAL's segments -- the starting
+// point ReadBySequence then scans forward from.
+func (w *WAL) readerFromSparseIndex(seq uint64) *Reader {
+	segments := w.orderedSegments()
+	r := &Reader{w: w, segments: segments}
+	for i, seg := range segments {
+		index := seg.sparseIndexSnapshot()
+		if len(index) == 0 || index[0].sequence > seq {
+			break
+		}
+		j := sort.Search(len(index), func(j int) bool { return index[j].sequence > seq })
+		entry := index[j-1]
+		r.segIdx, r.offset, r.nextSeq = i, entry.offset, entry.sequence
+	}
+	return r
+}
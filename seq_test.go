@@ -0,0 +1,73 @@
+package wal
+
+import (
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteAssignsMonotonicSequence(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-seq-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	var positions []*ChunkPosition
+	for i := 0; i < 5; i++ {
+		pos, err := w.Write([]byte("record"))
+		require.Nil(t, err)
+		positions = append(positions, pos)
+	}
+	for i, pos := range positions {
+		require.Equal(t, uint64(i), pos.Sequence)
+	}
+}
+
+func TestReadBySequence(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-seq-read-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	for i := 0; i < 5; i++ {
+		_, err := w.Write([]byte{byte('a' + i)})
+		require.Nil(t, err)
+	}
+
+	data, err := w.ReadBySequence(2)
+	require.Nil(t, err)
+	require.Equal(t, []byte("c"), data)
+
+	_, err = w.ReadBySequence(100)
+	require.Equal(t, io.EOF, err)
+}
+
+func TestSequenceResumesAcrossReopen(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-seq-reopen-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	for i := 0; i < 3; i++ {
+		_, err := w.Write([]byte("record"))
+		require.Nil(t, err)
+	}
+	require.Nil(t, w.Close())
+
+	w2, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w2.Close()
+
+	pos, err := w2.Write([]byte("record"))
+	require.Nil(t, err)
+	require.Equal(t, uint64(3), pos.Sequence)
+}
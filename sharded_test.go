@@ -0,0 +1,70 @@
+package wal
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShardedWALWriteRoundTripsThroughRead(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-sharded-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	sw, err := OpenShardedWAL(ShardedWALOptions{Dir: dir, Shards: 4, Options: []Option{WithSegmentFileExt(".SEG")}})
+	require.Nil(t, err)
+	defer sw.Close()
+
+	pos, err := sw.Write([]byte("key-1"), []byte("hello"))
+	require.Nil(t, err)
+
+	data, err := sw.Read(pos)
+	require.Nil(t, err)
+	assert.Equal(t, []byte("hello"), data)
+}
+
+func TestShardedWALSameKeyAlwaysLandsOnSameShard(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-sharded-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	sw, err := OpenShardedWAL(ShardedWALOptions{Dir: dir, Shards: 8, Options: []Option{WithSegmentFileExt(".SEG")}})
+	require.Nil(t, err)
+	defer sw.Close()
+
+	first, err := sw.Write([]byte("same-key"), []byte("one"))
+	require.Nil(t, err)
+	second, err := sw.Write([]byte("same-key"), []byte("two"))
+	require.Nil(t, err)
+
+	assert.Equal(t, first.Shard, second.Shard)
+}
+
+func TestShardedWALWriteRoundRobinSpreadsAcrossShards(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-sharded-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	sw, err := OpenShardedWAL(ShardedWALOptions{Dir: dir, Shards: 4, Options: []Option{WithSegmentFileExt(".SEG")}})
+	require.Nil(t, err)
+	defer sw.Close()
+
+	seen := make(map[int]bool)
+	for i := 0; i < 8; i++ {
+		pos, err := sw.WriteRoundRobin([]byte("record"))
+		require.Nil(t, err)
+		seen[pos.Shard] = true
+	}
+	assert.Equal(t, 4, len(seen))
+}
+
+func TestOpenShardedWALRejectsZeroShards(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-sharded-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	_, err = OpenShardedWAL(ShardedWALOptions{Dir: dir, Shards: 0})
+	assert.NotNil(t, err)
+}
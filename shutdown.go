@@ -0,0 +1,137 @@
+package wal
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+)
+
+// shutdownMarkerFileName is the file Close writes in DirPath, after its
+// final fsync, recording the exact end-of-log a clean shutdown left
+// behind. Open reads it, if present, to trust the active segment's
+// logical end instead of re-deriving it by scanning every chunk in it --
+// removing it immediately afterward, so it only ever means "the previous
+// run of this WAL closed cleanly" for as long as no new run has started
+// since.
+const shutdownMarkerFileName = "CLOSED"
+
+// shutdownMarkerMagic guards against trusting a marker truncated or torn
+// by a crash between Close creating the file and fsyncing it.
+const shutdownMarkerMagic = 0x57414c43 // "WALC"
+
+// shutdownMarkerSize is the marker's fixed on-disk size: magic, the active
+// segment's ID, its logical end and chunk count, the WAL-wide next
+// sequence number, and its last chunk's position (zeroed, with
+// hasLastPos 0, if nothing had been written yet).
+const shutdownMarkerSize = 4 + 4 + 8 + 8 + 8 + 1 + 4 + 8 + 4 + 8
+
+// shutdownManifest is what shutdownMarkerFileName encodes: exactly the
+// state Open would otherwise have to reconstruct by scanning the active
+// segment and walking backwards from its end.
+type shutdownManifest struct {
+	activeSegmentID SegmentID
+	offset          int64 // activeSegment's logical end; see segment.offset
+	chunkCount      int64
+	nextSeq         uint64
+	lastPos         *ChunkPosition
+}
+
+// encodeShutdownManifest serializes m to shutdownMarkerSize bytes.
+func encodeShutdownManifest(m shutdownManifest) []byte {
+	buf := make([]byte, shutdownMarkerSize)
+	binary.BigEndian.PutUint32(buf[0:4], shutdownMarkerMagic)
+	binary.BigEndian.PutUint32(buf[4:8], uint32(m.activeSegmentID))
+	binary.BigEndian.PutUint64(buf[8:16], uint64(m.offset))
+	binary.BigEndian.PutUint64(buf[16:24], uint64(m.chunkCount))
+	binary.BigEndian.PutUint64(buf[24:32], m.nextSeq)
+	if m.lastPos != nil {
+		buf[32] = 1
+		binary.BigEndian.PutUint32(buf[33:37], uint32(m.lastPos.SegmentId))
+		binary.BigEndian.PutUint64(buf[37:45], uint64(m.lastPos.ChunkOffset))
+		binary.BigEndian.PutUint32(buf[45:49], m.lastPos.ChunkSize)
+		binary.BigEndian.PutUint64(buf[49:57], m.lastPos.Sequence)
+	}
+	return buf
+}
+
+// decodeShutdownManifest reverses encodeShutdownManifest, reporting false
+// if data isn't exactly a well-formed, untorn marker.
+func decodeShutdownManifest(data []byte) (shutdownManifest, bool) {
+	if len(data) != shutdownMarkerSize || binary.BigEndian.Uint32(data[0:4]) != shutdownMarkerMagic {
+		return shutdownManifest{}, false
+	}
+	m := shutdownManifest{
+		activeSegmentID: SegmentID(binary.BigEndian.Uint32(data[4:8])),
+		offset:          int64(binary.BigEndian.Uint64(data[8:16])),
+		chunkCount:      int64(binary.BigEndian.Uint64(data[16:24])),
+		nextSeq:         binary.BigEndian.Uint64(data[24:32]),
+	}
+	if data[32] == 1 {
+		m.lastPos = &ChunkPosition{
+			SegmentId:   SegmentID(binary.BigEndian.Uint32(data[33:37])),
+			ChunkOffset: int64(binary.BigEndian.Uint64(data[37:45])),
+			ChunkSize:   binary.BigEndian.Uint32(data[45:49]),
+			Sequence:    binary.BigEndian.Uint64(data[49:57]),
+		}
+	}
+	return m, true
+}
+
+// writeShutdownMarker creates (or truncates) shutdownMarkerFileName in
+// dirPath with m encoded into it, and fsyncs it so it's durable before
+// Close returns.
+func writeShutdownMarker(fs FS, dirPath string, m shutdownManifest) error {
+	f, err := fs.OpenFile(filepath.Join(dirPath, shutdownMarkerFileName), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(encodeShutdownManifest(m)); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// readShutdownMarker reads and decodes shutdownMarkerFileName in dirPath.
+// ok is false, with a nil error, if the marker doesn't exist or isn't a
+// well-formed marker -- either way, the caller should fall back to its
+// normal recovery scan.
+func readShutdownMarker(fs FS, dirPath string) (m shutdownManifest, ok bool, err error) {
+	f, err := fs.OpenFile(filepath.Join(dirPath, shutdownMarkerFileName), os.O_RDONLY, 0o644)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return shutdownManifest{}, false, nil
+		}
+		return shutdownManifest{}, false, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return shutdownManifest{}, false, err
+	}
+	if info.Size() != shutdownMarkerSize {
+		return shutdownManifest{}, false, nil
+	}
+	buf := make([]byte, shutdownMarkerSize)
+	if _, err := f.ReadAt(buf, 0); err != nil {
+		return shutdownManifest{}, false, err
+	}
+	m, ok = decodeShutdownManifest(buf)
+	return m, ok, nil
+}
+
+// removeShutdownMarker deletes shutdownMarkerFileName in dirPath, if
+// present, so a stale marker from a previous clean run never lingers into
+// this one.
+func removeShutdownMarker(fs FS, dirPath string) error {
+	err := fs.Remove(filepath.Join(dirPath, shutdownMarkerFileName))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package wal
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenTrustsCleanShutdownManifest(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-clean-shutdown-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+
+	var positions []*ChunkPosition
+	for i := 0; i < 50; i++ {
+		pos, err := w.Write([]byte(fmt.Sprintf("record-%d", i)))
+		require.Nil(t, err)
+		positions = append(positions, pos)
+	}
+	lastPos := w.lastPos
+	nextSeq := w.nextSeq
+	require.Nil(t, w.Close())
+
+	w2, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w2.Close()
+
+	require.True(t, w2.activeSegment.footerLoaded, "expected the active segment to be trusted from the shutdown manifest")
+	require.Equal(t, nextSeq, w2.nextSeq)
+	require.Equal(t, lastPos, w2.lastPos)
+
+	for i, pos := range positions {
+		data, err := w2.Read(pos)
+		require.Nil(t, err)
+		require.Equal(t, fmt.Sprintf("record-%d", i), string(data))
+	}
+
+	// Writes after the trusted reopen land right after the old tail, not
+	// overlapping it.
+	pos, err := w2.Write([]byte("record-50"))
+	require.Nil(t, err)
+	data, err := w2.Read(pos)
+	require.Nil(t, err)
+	require.Equal(t, "record-50", string(data))
+}
+
+func TestOpenFallsBackToScanWithoutCleanShutdown(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-unclean-shutdown-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	_, err = w.Write([]byte("hello"))
+	require.Nil(t, err)
+	require.Nil(t, w.activeSegment.Sync())
+	// Release the directory lock without calling Close, so no shutdown
+	// marker is ever written -- simulating a crash.
+	require.Nil(t, w.dirLock.Close())
+
+	w2, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w2.Close()
+
+	require.False(t, w2.activeSegment.footerLoaded)
+	data, err := w2.Read(&ChunkPosition{SegmentId: 1, ChunkOffset: 0, ChunkSize: uint32(len("hello"))})
+	require.Nil(t, err)
+	require.Equal(t, "hello", string(data))
+}
+
+func TestOpenIgnoresManifestForADifferentActiveSegment(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-stale-shutdown-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	_, err = w.Write([]byte("hello"))
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+
+	// Tamper with the manifest to claim a segment ID that doesn't exist.
+	m, ok, err := readShutdownMarker(osFS{}, dir)
+	require.Nil(t, err)
+	require.True(t, ok)
+	m.activeSegmentID = 99
+	require.Nil(t, writeShutdownMarker(osFS{}, dir, m))
+
+	w2, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w2.Close()
+
+	require.False(t, w2.activeSegment.footerLoaded)
+	data, err := w2.Read(&ChunkPosition{SegmentId: 1, ChunkOffset: 0, ChunkSize: uint32(len("hello"))})
+	require.Nil(t, err)
+	require.Equal(t, "hello", string(data))
+}
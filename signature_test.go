@@ -0,0 +1,156 @@
+package wal
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSegmentSigningRoundTripVerifiesOnOpen(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-signing-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.Nil(t, err)
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(32), WithSegmentSigning(priv))
+	require.Nil(t, err)
+	for i := 0; i < 6; i++ {
+		_, err := w.Write([]byte("0123456789"))
+		require.Nil(t, err)
+	}
+	require.Nil(t, w.Close())
+
+	w, err = Open(WithDirPath(dir), WithSegmentSize(32), WithSegmentVerification(pub))
+	require.Nil(t, err)
+	defer w.Close()
+
+	require.Greater(t, len(w.olderSegments), 0, "test needs at least one sealed, signed segment")
+}
+
+func TestSegmentSigningOnOpenFailsUnderWrongKey(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-signing-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.Nil(t, err)
+	otherPub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.Nil(t, err)
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(32), WithSegmentSigning(priv))
+	require.Nil(t, err)
+	for i := 0; i < 6; i++ {
+		_, err := w.Write([]byte("0123456789"))
+		require.Nil(t, err)
+	}
+	require.Nil(t, w.Close())
+
+	_, err = Open(WithDirPath(dir), WithSegmentSize(32), WithSegmentVerification(otherPub))
+	var invalid *ErrSegmentSignatureInvalid
+	require.ErrorAs(t, err, &invalid)
+}
+
+func TestSegmentSigningOnOpenFailsWhenUnsigned(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-signing-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.Nil(t, err)
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(32))
+	require.Nil(t, err)
+	for i := 0; i < 6; i++ {
+		_, err := w.Write([]byte("0123456789"))
+		require.Nil(t, err)
+	}
+	require.Nil(t, w.Close())
+
+	_, err = Open(WithDirPath(dir), WithSegmentSize(32), WithSegmentVerification(pub))
+	require.ErrorIs(t, err, ErrSegmentSignatureMissing)
+}
+
+// TestVerifySignaturesSkipsActiveSegment confirms VerifySignatures treats
+// the still-active segment as nothing to check, rather than as missing a
+// signature: seal is what signs a segment, and it hasn't run for the
+// active one yet.
+func TestVerifySignaturesSkipsActiveSegment(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-signing-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.Nil(t, err)
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(32), WithSegmentSigning(priv))
+	require.Nil(t, err)
+	defer w.Close()
+	for i := 0; i < 6; i++ {
+		_, err := w.Write([]byte("0123456789"))
+		require.Nil(t, err)
+	}
+	require.Greater(t, len(w.olderSegments), 0, "test needs at least one sealed segment")
+	_, err = w.Sync()
+	require.Nil(t, err)
+
+	result, err := VerifySignatures(dir, pub, WithSegmentSize(32))
+	require.Nil(t, err)
+	require.Equal(t, len(w.olderSegments), result.SegmentsChecked)
+}
+
+// TestCompactResignsReplacementSegment confirms Compact's replacement
+// segment -- built fresh and sealed under the original segment's path --
+// carries a signature that verifies against its own (different) digest,
+// not a stale one left over from the segment it replaced.
+func TestCompactResignsReplacementSegment(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-signing-compact-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.Nil(t, err)
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(32), WithSegmentSigning(priv))
+	require.Nil(t, err)
+	defer w.Close()
+
+	dead, err := w.Write([]byte("garbage value"))
+	require.Nil(t, err)
+	for i := 0; i < 3; i++ {
+		_, err := w.Write([]byte("filler to force rotation"))
+		require.Nil(t, err)
+	}
+	require.Greater(t, len(w.olderSegments), 0)
+
+	_, err = w.Compact(func(pos *ChunkPosition, data []byte) bool {
+		return !pos.Equal(dead)
+	}, nil)
+	require.Nil(t, err)
+
+	_, err = VerifySignatures(dir, pub, WithSegmentSize(32))
+	require.Nil(t, err)
+}
+
+func TestWithSegmentSigningRejectsWrongSizedKey(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-signing-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	_, err = Open(WithDirPath(dir), WithSegmentSigning([]byte("too short")))
+	require.True(t, errors.Is(err, ErrInvalidSigningKey))
+}
+
+func TestWithSegmentVerificationRejectsWrongSizedKey(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-signing-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	_, err = Open(WithDirPath(dir), WithSegmentVerification([]byte("too short")))
+	require.True(t, errors.Is(err, ErrInvalidSigningKey))
+}
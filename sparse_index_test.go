@@ -0,0 +1,53 @@
+package wal
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSparseIndexAcceleratesReadBySequenceAcrossSegments(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-sparse-index-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(64))
+	require.Nil(t, err)
+	defer w.Close()
+
+	const n = sparseIndexInterval*3 + 5
+	for i := 0; i < n; i++ {
+		_, err := w.Write([]byte{byte(i)})
+		require.Nil(t, err)
+	}
+
+	for i := 0; i < n; i++ {
+		data, err := w.ReadBySequence(uint64(i))
+		require.Nil(t, err)
+		require.Equal(t, []byte{byte(i)}, data)
+	}
+}
+
+func TestSparseIndexRebuiltOnReopen(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-sparse-index-reopen-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(64))
+	require.Nil(t, err)
+	const n = sparseIndexInterval*2 + 3
+	for i := 0; i < n; i++ {
+		_, err := w.Write([]byte{byte(i)})
+		require.Nil(t, err)
+	}
+	require.Nil(t, w.Close())
+
+	w2, err := Open(WithDirPath(dir), WithSegmentSize(64))
+	require.Nil(t, err)
+	defer w2.Close()
+
+	data, err := w2.ReadBySequence(uint64(n - 1))
+	require.Nil(t, err)
+	require.Equal(t, []byte{byte(n - 1)}, data)
+}
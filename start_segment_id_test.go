@@ -0,0 +1,43 @@
+package wal
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithStartSegmentIDNumbersTheFirstSegment(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-start-segment-id-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithStartSegmentID(42))
+	require.Nil(t, err)
+	defer w.Close()
+
+	pos, err := w.Write([]byte("hello"))
+	require.Nil(t, err)
+	require.Equal(t, SegmentID(42), pos.SegmentId)
+}
+
+func TestWithStartSegmentIDHasNoEffectOnAnExistingWAL(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-start-segment-id-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	pos, err := w.Write([]byte("hello"))
+	require.Nil(t, err)
+	require.Equal(t, SegmentID(1), pos.SegmentId)
+	require.Nil(t, w.Close())
+
+	w2, err := Open(WithDirPath(dir), WithStartSegmentID(99))
+	require.Nil(t, err)
+	defer w2.Close()
+
+	pos2, err := w2.Write([]byte("world"))
+	require.Nil(t, err)
+	require.Equal(t, SegmentID(1), pos2.SegmentId)
+}
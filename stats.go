@@ -0,0 +1,205 @@
+package wal
+
+import (
+	"sort"
+	"time"
+)
+
+// statsFsyncSamples bounds how many of the most recent fsync durations
+// Stats computes its percentiles from, so a long-running WAL doesn't keep
+// every fsync duration it has ever observed in memory.
+const statsFsyncSamples = 256
+
+// fsyncHistogramBucketCount is the number of buckets in
+// fsyncHistogramBounds, and so in every WAL's fsyncHistogram field.
+const fsyncHistogramBucketCount = 17
+
+// fsyncHistogramBounds are the upper bounds, in ascending order, of
+// Stats.FsyncDurationHistogram's buckets: doubling from 1ms up to just
+// over a minute, the last one catching anything slower. Unlike
+// fsyncDurations' fixed-size sample window, every fsync this WAL has ever
+// made (not just the most recent statsFsyncSamples) lands in exactly one
+// of these for as long as the WAL stays open, at the cost of losing
+// individual durations -- the tradeoff an HDR-style histogram makes for
+// tracking a long tail cheaply instead of sampling it.
+var fsyncHistogramBounds = [fsyncHistogramBucketCount]time.Duration{
+	time.Millisecond,
+	2 * time.Millisecond,
+	4 * time.Millisecond,
+	8 * time.Millisecond,
+	16 * time.Millisecond,
+	32 * time.Millisecond,
+	64 * time.Millisecond,
+	128 * time.Millisecond,
+	256 * time.Millisecond,
+	512 * time.Millisecond,
+	1024 * time.Millisecond,
+	2048 * time.Millisecond,
+	4096 * time.Millisecond,
+	8192 * time.Millisecond,
+	16384 * time.Millisecond,
+	32768 * time.Millisecond,
+	time.Duration(1<<63 - 1), // catches anything slower than the rest
+}
+
+// FsyncHistogramBucket is one bucket of Stats.FsyncDurationHistogram: the
+// number of recorded fsyncs that took no longer than UpperBound.
+type FsyncHistogramBucket struct {
+	UpperBound time.Duration
+	Count      uint64
+}
+
+// Stats is a point-in-time snapshot of a WAL's activity since it was
+// opened, returned by WAL.Stats.
+type Stats struct {
+	// BytesWritten is the total number of payload bytes Write and WriteAll
+	// have appended to segment files.
+	BytesWritten uint64
+
+	// ChunksWritten is the total number of chunks Write and WriteAll have
+	// appended.
+	ChunksWritten uint64
+
+	// SegmentCount is the number of segment files currently on disk,
+	// including the active segment.
+	SegmentCount int
+
+	// FsyncCount is the total number of fsync calls made, whether by an
+	// explicit Sync or one Write made implicitly under Options.Sync or
+	// BytesPerSync.
+	FsyncCount uint64
+
+	// FsyncDurationP50, FsyncDurationP90, and FsyncDurationP99 are
+	// percentiles of the most recent statsFsyncSamples fsync durations.
+	// They are zero if no fsync has happened yet.
+	FsyncDurationP50 time.Duration
+	FsyncDurationP90 time.Duration
+	FsyncDurationP99 time.Duration
+
+	// PendingWrites is the number of writes buffered by PendingWrites that
+	// WriteAll has not yet flushed.
+	PendingWrites int
+
+	// BlockCacheBytes is the current memory usage of Options.BlockCache's
+	// cache, in bytes. It is 0 if Options.BlockCache is 0.
+	BlockCacheBytes uint64
+
+	// LogicalBytesWritten is the total number of record bytes passed to
+	// Write and WriteAll, before compression, encryption, and the
+	// Options.Timestamps prefix -- i.e. what the caller actually asked to
+	// persist, as opposed to BytesWritten's on-disk payload bytes.
+	LogicalBytesWritten uint64
+
+	// RewrittenBytes is the total number of on-disk bytes (chunk headers
+	// included) Compact has rewritten into replacement segments.
+	RewrittenBytes uint64
+
+	// WriteAmplification is the ratio of total bytes actually written to
+	// segment files -- BytesWritten's payload bytes, plus one
+	// chunkHeaderSize per chunk, plus RewrittenBytes -- to
+	// LogicalBytesWritten. It is always >= 1 (a chunk's header alone
+	// guarantees that), growing with small records relative to
+	// chunkHeaderSize or repeated Compact rewrites; it is 0 if nothing has
+	// been written yet. Options.BlockSize contributes nothing here, since
+	// it never pads a chunk's on-disk size -- see its own doc comment.
+	WriteAmplification float64
+
+	// FsyncDurationHistogram buckets every fsync this WAL has made since
+	// it was opened by duration, in ascending UpperBound order -- see
+	// fsyncHistogramBounds. Unlike FsyncDurationP50/P90/P99, which only
+	// see the most recent statsFsyncSamples fsyncs, this covers the WAL's
+	// entire lifetime, at the cost of individual durations within a
+	// bucket. Use Options.OnSlowSync instead to react to a slow fsync as
+	// it happens rather than finding it here after the fact.
+	FsyncDurationHistogram []FsyncHistogramBucket
+
+	// TieringCacheHits and TieringCacheMisses count every fetchTieredSegment
+	// lookup against Options.TieringCacheDir's local cache of downloaded
+	// segments: a hit found the segment already cached, a miss had to
+	// download it from Options.ObjectStore (or wait for another goroutine's
+	// concurrent download of the same segment to finish -- see
+	// tieringCache.get). Both are always 0 if Options.TieringAge is not
+	// set.
+	TieringCacheHits   uint64
+	TieringCacheMisses uint64
+}
+
+// Stats returns a snapshot of the WAL's activity. For Prometheus-style
+// instrumentation instead, see WithMetricsRegisterer.
+func (w *WAL) Stats() Stats {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	stats := Stats{
+		BytesWritten:  w.bytesWritten,
+		ChunksWritten: w.chunksWritten,
+		SegmentCount:  len(w.olderSegments) + 1,
+		FsyncCount:    w.fsyncCount,
+		PendingWrites: len(w.pendingWrites),
+	}
+	stats.FsyncDurationP50, stats.FsyncDurationP90, stats.FsyncDurationP99 = w.fsyncPercentilesLocked()
+	if w.blockCache != nil {
+		stats.BlockCacheBytes = w.blockCache.bytes()
+	}
+	if w.tieringCache != nil {
+		stats.TieringCacheHits, stats.TieringCacheMisses = w.tieringCache.hitsAndMisses()
+	}
+
+	stats.LogicalBytesWritten = w.logicalBytesWritten
+	stats.RewrittenBytes = w.rewrittenBytes.Load()
+	if stats.LogicalBytesWritten > 0 {
+		onDisk := stats.BytesWritten + stats.ChunksWritten*uint64(chunkHeaderSize) + stats.RewrittenBytes
+		stats.WriteAmplification = float64(onDisk) / float64(stats.LogicalBytesWritten)
+	}
+
+	stats.FsyncDurationHistogram = make([]FsyncHistogramBucket, fsyncHistogramBucketCount)
+	for i, bound := range fsyncHistogramBounds {
+		stats.FsyncDurationHistogram[i] = FsyncHistogramBucket{UpperBound: bound, Count: w.fsyncHistogram[i]}
+	}
+	return stats
+}
+
+// recordFsyncDurationLocked adds d to the bounded window of recent fsync
+// durations Stats computes percentiles from, and to the cumulative
+// fsyncHistogram. mu must already be held.
+func (w *WAL) recordFsyncDurationLocked(d time.Duration) {
+	if len(w.fsyncDurations) < statsFsyncSamples {
+		w.fsyncDurations = append(w.fsyncDurations, d)
+	} else {
+		w.fsyncDurations[w.fsyncDurationsNext] = d
+		w.fsyncDurationsNext = (w.fsyncDurationsNext + 1) % statsFsyncSamples
+	}
+
+	for i, bound := range fsyncHistogramBounds {
+		if d <= bound {
+			w.fsyncHistogram[i]++
+			break
+		}
+	}
+}
+
+// reportSlowSyncLocked calls Options.OnSlowSync if d is at least
+// Options.SlowSyncThreshold. mu must already be held, the same as
+// Options.OnSyncError's poisonLocked.
+func (w *WAL) reportSlowSyncLocked(d time.Duration) {
+	if w.options.SlowSyncThreshold > 0 && d >= w.options.SlowSyncThreshold && w.options.OnSlowSync != nil {
+		w.options.OnSlowSync(d)
+	}
+}
+
+// fsyncPercentilesLocked returns the p50, p90, and p99 of the bounded
+// window of recent fsync durations. mu must already be held.
+func (w *WAL) fsyncPercentilesLocked() (p50, p90, p99 time.Duration) {
+	if len(w.fsyncDurations) == 0 {
+		return 0, 0, 0
+	}
+	sorted := make([]time.Duration, len(w.fsyncDurations))
+	copy(sorted, w.fsyncDurations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	percentile := func(p float64) time.Duration {
+		idx := int(p * float64(len(sorted)-1))
+		return sorted[idx]
+	}
+	return percentile(0.5), percentile(0.9), percentile(0.99)
+}
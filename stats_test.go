@@ -0,0 +1,170 @@
+package wal
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatsTracksWritesFsyncsAndSegments(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-stats-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(32), WithSync(true))
+	require.Nil(t, err)
+	defer w.Close()
+
+	for i := 0; i < 6; i++ {
+		_, err := w.Write([]byte("0123456789"))
+		require.Nil(t, err)
+	}
+
+	stats := w.Stats()
+	require.EqualValues(t, 6, stats.ChunksWritten)
+	require.EqualValues(t, 60, stats.BytesWritten)
+	require.EqualValues(t, 6, stats.FsyncCount)
+	require.Greater(t, stats.SegmentCount, 1)
+	require.GreaterOrEqual(t, stats.FsyncDurationP99, stats.FsyncDurationP50)
+}
+
+func TestStatsReportsPendingWrites(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-stats-pending-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	w.PendingWrites([]byte("a"))
+	w.PendingWrites([]byte("b"))
+	require.Equal(t, 2, w.Stats().PendingWrites)
+
+	_, err = w.WriteAll()
+	require.Nil(t, err)
+	require.Equal(t, 0, w.Stats().PendingWrites)
+}
+
+func TestStatsTracksWriteAmplification(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-stats-amplification-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	stats := w.Stats()
+	require.Zero(t, stats.LogicalBytesWritten)
+	require.Zero(t, stats.WriteAmplification)
+
+	_, err = w.Write([]byte("0123456789"))
+	require.Nil(t, err)
+
+	stats = w.Stats()
+	require.EqualValues(t, 10, stats.LogicalBytesWritten)
+	require.EqualValues(t, 10, stats.BytesWritten)
+	// A record's header alone guarantees on-disk bytes exceed its logical
+	// size, so the ratio is always > 1 for a non-empty record.
+	require.Greater(t, stats.WriteAmplification, 1.0)
+}
+
+func TestStatsTracksRewrittenBytesFromCompact(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-stats-rewrite-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(32))
+	require.Nil(t, err)
+	defer w.Close()
+
+	var positions []*ChunkPosition
+	for i := 0; i < 6; i++ {
+		pos, err := w.Write([]byte("0123456789"))
+		require.Nil(t, err)
+		positions = append(positions, pos)
+	}
+
+	_, err = w.Compact(func(pos *ChunkPosition, data []byte) bool {
+		return pos.Compare(positions[0]) == 0
+	}, nil)
+	require.Nil(t, err)
+
+	require.Greater(t, w.Stats().RewrittenBytes, uint64(0))
+}
+
+func TestStatsFsyncDurationHistogramCountsEverySync(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-stats-histogram-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSync(true))
+	require.Nil(t, err)
+	defer w.Close()
+
+	for i := 0; i < 5; i++ {
+		_, err := w.Write([]byte("0123456789"))
+		require.Nil(t, err)
+	}
+
+	hist := w.Stats().FsyncDurationHistogram
+	require.Len(t, hist, fsyncHistogramBucketCount)
+
+	var total uint64
+	for _, bucket := range hist {
+		total += bucket.Count
+	}
+	require.EqualValues(t, 5, total)
+
+	// Bucket bounds must be strictly ascending, with the last one able to
+	// catch anything.
+	for i := 1; i < len(hist); i++ {
+		require.Greater(t, hist[i].UpperBound, hist[i-1].UpperBound)
+	}
+}
+
+func TestOnSlowSyncFiresAboveThreshold(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-slow-sync-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	var calls []time.Duration
+	w, err := Open(WithDirPath(dir), WithSync(true),
+		WithSlowSyncThreshold(time.Nanosecond),
+		WithOnSlowSync(func(d time.Duration) {
+			calls = append(calls, d)
+		}),
+	)
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("hello"))
+	require.Nil(t, err)
+
+	require.Len(t, calls, 1)
+	require.Greater(t, calls[0], time.Duration(0))
+}
+
+func TestOnSlowSyncDoesNotFireBelowThreshold(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-slow-sync-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	var calls []time.Duration
+	w, err := Open(WithDirPath(dir), WithSync(true),
+		WithSlowSyncThreshold(time.Hour),
+		WithOnSlowSync(func(d time.Duration) {
+			calls = append(calls, d)
+		}),
+	)
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("hello"))
+	require.Nil(t, err)
+
+	require.Empty(t, calls)
+}
@@ -0,0 +1,76 @@
+package wal
+
+import "io"
+
+// Export streams every record up to, but not including, upTo (or the
+// whole log if upTo is nil) to dst as one leading byte naming the
+// checksum algorithm used, followed by each record framed the same way a
+// segment file frames a chunk -- a 4-byte length, a 4-byte checksum, then
+// the payload -- but without a segment header, since dst is meant to be
+// piped somewhere (over ssh, into an HTTP request body) and reconstituted
+// with Ingest at the other end, not opened as a segment itself.
+//
+// Records are read through Reader.Next, the same as CopyTo, so they
+// arrive on dst already decoded: any WriteWithFlags, WriteWithTTL, or
+// Batch framing a record carries comes along as opaque payload bytes,
+// but this WAL's own compression and encryption do not, and it's
+// whatever Ingest's caller does with the payload that determines whether
+// they're re-applied.
+func (w *WAL) Export(dst io.Writer, upTo *ChunkPosition) error {
+	if _, err := dst.Write([]byte{byte(w.options.Checksum)}); err != nil {
+		return err
+	}
+
+	r, err := w.NewReaderWithRange(nil, upTo)
+	if err != nil {
+		return err
+	}
+
+	for {
+		data, _, err := r.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := writeStreamChunk(dst, data, w.options.Checksum); err != nil {
+			return err
+		}
+	}
+}
+
+// Ingest reads a stream Export wrote from r and appends each record it
+// finds to w with Write, preserving the order and boundaries they had at
+// the source, closing the loop for backup/replication piping over ssh or
+// HTTP. It returns how many records it appended before reaching the end
+// of r, and, same as Restore, fails with an *ErrCorrupted identifying the
+// offending record's offset within r rather than appending a truncated
+// or mismatched one.
+func (w *WAL) Ingest(r io.Reader) (int, error) {
+	var algo [1]byte
+	if _, err := io.ReadFull(r, algo[:]); err != nil {
+		return 0, err
+	}
+	checksum := Checksum(algo[0])
+
+	var count int
+	var offset int64
+	for {
+		payload, err := readChunk(r, checksum)
+		if err == io.EOF {
+			return count, nil
+		}
+		if err != nil {
+			w.mu.RLock()
+			id := w.activeSegment.id
+			w.mu.RUnlock()
+			return count, &ErrCorrupted{SegmentID: id, ChunkOffset: offset, Reason: err}
+		}
+		if _, err := w.Write(payload); err != nil {
+			return count, err
+		}
+		offset += int64(chunkHeaderSize) + int64(len(payload))
+		count++
+	}
+}
@@ -0,0 +1,30 @@
+package wal
+
+// StreamReader adapts a Reader to the io.Reader interface, concatenating
+// every chunk's payload in order so the whole log can be piped into
+// io.Copy -- gzip, a network connection, a backup tool, and so on.
+type StreamReader struct {
+	r   *Reader
+	buf []byte
+}
+
+// NewStreamReader returns a StreamReader starting from the first chunk in
+// the WAL.
+func NewStreamReader(w *WAL) *StreamReader {
+	return &StreamReader{r: w.NewReader()}
+}
+
+// Read implements io.Reader. It returns io.EOF once the reader has caught
+// up with the end of the currently-written data, exactly like Reader.Next.
+func (s *StreamReader) Read(p []byte) (n int, err error) {
+	for len(s.buf) == 0 {
+		data, _, err := s.r.Next()
+		if err != nil {
+			return 0, err
+		}
+		s.buf = data
+	}
+	n = copy(p, s.buf)
+	s.buf = s.buf[n:]
+	return n, nil
+}
@@ -0,0 +1,57 @@
+package wal
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamReaderConcatenatesAllChunks(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-stream-reader-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(64))
+	require.Nil(t, err)
+	defer w.Close()
+
+	for i := 0; i < 10; i++ {
+		_, err := w.Write([]byte{byte('a' + i)})
+		require.Nil(t, err)
+	}
+
+	var buf bytes.Buffer
+	n, err := io.Copy(&buf, NewStreamReader(w))
+	require.Nil(t, err)
+	require.Equal(t, int64(10), n)
+	require.Equal(t, []byte("abcdefghij"), buf.Bytes())
+}
+
+func TestStreamReaderReadsIntoSmallBuffer(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-stream-reader-small-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("hello world"))
+	require.Nil(t, err)
+
+	sr := NewStreamReader(w)
+	p := make([]byte, 4)
+	var got []byte
+	for {
+		n, err := sr.Read(p)
+		got = append(got, p[:n]...)
+		if err == io.EOF {
+			break
+		}
+		require.Nil(t, err)
+	}
+	require.Equal(t, []byte("hello world"), got)
+}
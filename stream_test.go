@@ -0,0 +1,131 @@
+package wal
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportIngestRoundTrip(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-export-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(64))
+	require.Nil(t, err)
+	defer w.Close()
+
+	for i := 0; i < 20; i++ {
+		_, err := w.Write(bytes.Repeat([]byte{byte('a' + i)}, 8))
+		require.Nil(t, err)
+	}
+
+	var buf bytes.Buffer
+	require.Nil(t, w.Export(&buf, nil))
+
+	dstDir, err := os.MkdirTemp("", "wal-ingest-dst")
+	require.Nil(t, err)
+	defer os.RemoveAll(dstDir)
+
+	dst, err := Open(WithDirPath(dstDir))
+	require.Nil(t, err)
+	defer dst.Close()
+
+	count, err := dst.Ingest(&buf)
+	require.Nil(t, err)
+	require.Equal(t, 20, count)
+
+	r := dst.NewReader()
+	var got int
+	for {
+		data, _, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		require.Nil(t, err)
+		require.Equal(t, bytes.Repeat([]byte{byte('a' + got)}, 8), data)
+		got++
+	}
+	require.Equal(t, 20, got)
+}
+
+func TestExportStopsAtUpTo(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-export-upto-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	var stopAt *ChunkPosition
+	for i := 0; i < 5; i++ {
+		pos, err := w.Write([]byte{byte('a' + i)})
+		require.Nil(t, err)
+		if i == 2 {
+			stopAt = pos
+		}
+	}
+
+	var buf bytes.Buffer
+	require.Nil(t, w.Export(&buf, stopAt))
+
+	dstDir, err := os.MkdirTemp("", "wal-export-upto-dst")
+	require.Nil(t, err)
+	defer os.RemoveAll(dstDir)
+
+	dst, err := Open(WithDirPath(dstDir))
+	require.Nil(t, err)
+	defer dst.Close()
+
+	count, err := dst.Ingest(&buf)
+	require.Nil(t, err)
+	require.Equal(t, 2, count)
+
+	r := dst.NewReader()
+	var got []byte
+	for {
+		data, _, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		require.Nil(t, err)
+		got = append(got, data...)
+	}
+	require.Equal(t, "ab", string(got))
+}
+
+func TestIngestDetectsCorruption(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-ingest-corrupt-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("hello"))
+	require.Nil(t, err)
+
+	var buf bytes.Buffer
+	require.Nil(t, w.Export(&buf, nil))
+
+	corrupted := buf.Bytes()
+	corrupted[1+chunkHeaderSize] ^= 0xFF
+
+	dstDir, err := os.MkdirTemp("", "wal-ingest-corrupt-dst")
+	require.Nil(t, err)
+	defer os.RemoveAll(dstDir)
+
+	dst, err := Open(WithDirPath(dstDir))
+	require.Nil(t, err)
+	defer dst.Close()
+
+	_, err = dst.Ingest(bytes.NewReader(corrupted))
+	var errCorrupted *ErrCorrupted
+	require.True(t, errors.As(err, &errCorrupted))
+}
@@ -0,0 +1,105 @@
+package wal
+
+import "encoding/binary"
+
+// streamMagic prefixes a WriteToStream record's payload so
+// NewReaderForStream's filter can tell it apart from an ordinary chunk --
+// e.g. one written with Write -- while scanning the whole log, the same
+// discriminator trick checkpointMagic and offsetMagic use for their own
+// marker records.
+const streamMagic = "WAL:STRM"
+
+// encodeStreamRecord lays out stream and data as WriteToStream's payload:
+// streamMagic, then a 4-byte length for stream, then stream itself, then
+// data.
+func encodeStreamRecord(stream string, data []byte) []byte {
+	raw := make([]byte, len(streamMagic)+4+len(stream)+len(data))
+	n := copy(raw, streamMagic)
+	binary.BigEndian.PutUint32(raw[n:], uint32(len(stream)))
+	n += 4
+	n += copy(raw[n:], stream)
+	copy(raw[n:], data)
+	return raw
+}
+
+// decodeStreamRecord reports whether raw is a record encodeStreamRecord
+// produced, and if so, the stream name and data it carries. Unlike
+// decodeCheckpointMarker and decodeOffsetMarker, which only ever see their
+// own marker records mixed in among an otherwise-known record shape, this
+// runs as NewReaderForStream's filter against every chunk in the WAL,
+// including ones written by Write or another envelope entirely, so it must
+// reject anything that isn't a well-formed streamMagic record rather than
+// trusting its length field.
+func decodeStreamRecord(raw []byte) (stream string, data []byte, ok bool) {
+	if len(raw) < len(streamMagic) || string(raw[:len(streamMagic)]) != streamMagic {
+		return "", nil, false
+	}
+	raw = raw[len(streamMagic):]
+
+	if len(raw) < 4 {
+		return "", nil, false
+	}
+	streamLen := binary.BigEndian.Uint32(raw[:4])
+	raw = raw[4:]
+
+	if uint64(len(raw)) < uint64(streamLen) {
+		return "", nil, false
+	}
+	return string(raw[:streamLen]), raw[streamLen:], true
+}
+
+// WriteToStream is Write, but tags data with a stream name so
+// NewReaderForStream can pick it back out again -- e.g. to multiplex many
+// logical logs (topics, tenants, partitions) through one physical WAL and
+// amortize its fsyncs across all of them, instead of running a separate WAL
+// per stream. A chunk written this way must be read back with
+// ReadFromStream or Reader.NextFromStream, not Read or Reader.Next, which
+// would otherwise return the tag as part of data instead of stripping it --
+// see WriteWithFlags's doc comment for why.
+//
+// Compact doesn't yet understand streams: it retains or drops a whole
+// segment based on what its own filter and Options.DropExpiredSegments say
+// about the chunks in it, regardless of which streams they're tagged for.
+// Per-stream retention would need Compact's filter to be handed the stream
+// name alongside each chunk's data, which it isn't today.
+func (w *WAL) WriteToStream(stream string, data []byte) (*ChunkPosition, error) {
+	return w.Write(encodeStreamRecord(stream, data))
+}
+
+// ReadFromStream is Read, but also returns the stream name WriteToStream
+// reserved for pos. Only call it for a position WriteToStream returned; see
+// WriteToStream's doc comment for why.
+func (w *WAL) ReadFromStream(pos *ChunkPosition) (data []byte, stream string, err error) {
+	raw, err := w.Read(pos)
+	if err != nil {
+		return nil, "", err
+	}
+	stream, data, _ = decodeStreamRecord(raw)
+	return data, stream, nil
+}
+
+// NewReaderForStream returns a Reader that skips every chunk not tagged
+// for stream by WriteToStream -- including chunks written some other way
+// entirely -- so a subscriber only ever sees the one logical stream it
+// asked for even though the WAL underneath is shared with every other
+// stream. Call NextFromStream on it, not Next, to get back each matching
+// chunk's data with its stream tag already stripped.
+func (w *WAL) NewReaderForStream(stream string, opts ...ReaderOption) *Reader {
+	return w.NewReaderWithFilter(func(raw []byte) bool {
+		name, _, ok := decodeStreamRecord(raw)
+		return ok && name == stream
+	}, opts...)
+}
+
+// NextFromStream is Next, but also returns the stream name WriteToStream
+// reserved for the chunk. Only call it on a Reader positioned at a chunk
+// WriteToStream wrote -- NewReaderForStream's Reader always is, having
+// already filtered out everything else.
+func (r *Reader) NextFromStream() (data []byte, stream string, pos *ChunkPosition, err error) {
+	raw, pos, err := r.Next()
+	if err != nil {
+		return nil, "", nil, err
+	}
+	stream, data, _ = decodeStreamRecord(raw)
+	return data, stream, pos, nil
+}
@@ -0,0 +1,57 @@
+package wal
+
+import (
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewReaderForStreamOnlySeesItsOwnStream(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-stream-filter-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.WriteToStream("orders", []byte("order-1"))
+	require.Nil(t, err)
+	_, err = w.WriteToStream("payments", []byte("payment-1"))
+	require.Nil(t, err)
+	_, err = w.WriteToStream("orders", []byte("order-2"))
+	require.Nil(t, err)
+
+	r := w.NewReaderForStream("orders")
+	var got []string
+	for {
+		data, stream, _, err := r.NextFromStream()
+		if err == io.EOF {
+			break
+		}
+		require.Nil(t, err)
+		require.Equal(t, "orders", stream)
+		got = append(got, string(data))
+	}
+	require.Equal(t, []string{"order-1", "order-2"}, got)
+}
+
+func TestReadFromStreamReturnsTheStreamName(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-stream-read-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	pos, err := w.WriteToStream("orders", []byte("order-1"))
+	require.Nil(t, err)
+
+	data, stream, err := w.ReadFromStream(pos)
+	require.Nil(t, err)
+	require.Equal(t, "orders", stream)
+	require.Equal(t, []byte("order-1"), data)
+}
@@ -0,0 +1,16 @@
+//go:build linux
+
+package wal
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// syncFileRange starts writeback of f's [offset, offset+nbytes) byte
+// range and returns without waiting for it to complete. See
+// Options.SyncFileRange.
+func syncFileRange(f *os.File, offset, nbytes int64) error {
+	return unix.SyncFileRange(int(f.Fd()), offset, nbytes, unix.SYNC_FILE_RANGE_WRITE)
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package wal
+
+import "os"
+
+// syncFileRange falls back to a full, blocking fsync on platforms without
+// a separate sync_file_range syscall. See Options.SyncFileRange.
+func syncFileRange(f *os.File, offset, nbytes int64) error {
+	return f.Sync()
+}
@@ -0,0 +1,49 @@
+package wal
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyncFileRangeKeepsWritesDurable(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-sync-file-range-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithBytesPerSync(16), WithSyncFileRange(true))
+	require.Nil(t, err)
+	defer w.Close()
+
+	var positions []*ChunkPosition
+	for i := 0; i < 64; i++ {
+		pos, err := w.Write([]byte{byte('a' + i%26)})
+		require.Nil(t, err)
+		positions = append(positions, pos)
+	}
+	_, err = w.Sync()
+	require.Nil(t, err)
+
+	for i, pos := range positions {
+		data, err := w.Read(pos)
+		require.Nil(t, err)
+		require.Equal(t, []byte{byte('a' + i%26)}, data)
+	}
+}
+
+func TestSyncFileRangeForcesPeriodicFullSync(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-sync-file-range-checkpoint-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithBytesPerSync(1), WithSyncFileRange(true))
+	require.Nil(t, err)
+	defer w.Close()
+
+	for i := 0; i < syncFileRangeCheckpointInterval; i++ {
+		_, err := w.Write([]byte("x"))
+		require.Nil(t, err)
+	}
+	require.Equal(t, 0, w.syncFileRangeCount)
+}
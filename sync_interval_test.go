@@ -0,0 +1,38 @@
+package wal
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyncIntervalFlushesInBackground(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-sync-interval-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSyncInterval(10*time.Millisecond))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("hello"))
+	require.Nil(t, err)
+
+	require.Eventually(t, func() bool {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		return w.bytesSinceSync == 0
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestSyncIntervalStopsOnClose(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-sync-interval-close-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSyncInterval(time.Millisecond))
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+}
@@ -0,0 +1,37 @@
+package wal
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// countingSyncDirFS wraps osFS so tests can observe how many times the
+// segment directory's own fsync (see FS.SyncDir) fires, without needing to
+// inspect timing on a real disk.
+type countingSyncDirFS struct {
+	osFS
+	calls *int
+}
+
+func (fs countingSyncDirFS) SyncDir(path string) error {
+	*fs.calls++
+	return fs.osFS.SyncDir(path)
+}
+
+func TestOpenAndRotateSyncTheSegmentDirectory(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-syncdir-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	calls := 0
+	w, err := Open(WithDirPath(dir), WithFS(countingSyncDirFS{calls: &calls}), WithSegmentSize(64))
+	require.Nil(t, err)
+	defer w.Close()
+	require.Equal(t, 1, calls, "expected creating the first segment to fsync its directory")
+
+	_, err = w.Write(make([]byte, 100))
+	require.Nil(t, err)
+	require.Equal(t, 2, calls, "expected rotating into a new segment to fsync its directory")
+}
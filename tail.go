@@ -0,0 +1,73 @@
+package wal
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// TailReader is a Reader that blocks, instead of returning io.EOF, when it
+// catches up with the end of the currently-written data. It is the
+// pull-based counterpart to Watcher's callback-based tailing: useful when a
+// caller wants to drive its own loop (e.g. to interleave tailing with other
+// work) rather than hand a callback to a background goroutine.
+//
+// It reuses Watcher's backoff constants so the two behave the same way when
+// they catch up with a writer still in the middle of a Write.
+type TailReader struct {
+	r       *Reader
+	backoff time.Duration
+	opts    WatcherOptions
+}
+
+// NewTailReader returns a TailReader starting from the first chunk in the
+// WAL. Use NewTailReaderWithStart to resume from a given position instead.
+func (w *WAL) NewTailReader() *TailReader {
+	return w.newTailReader(w.NewReader())
+}
+
+// NewTailReaderWithStart returns a TailReader that resumes just after pos,
+// which must have been returned by a previous Write, WriteAll, or Next call
+// on this WAL.
+func (w *WAL) NewTailReaderWithStart(pos *ChunkPosition) (*TailReader, error) {
+	r, err := w.NewReaderWithStart(pos)
+	if err != nil {
+		return nil, err
+	}
+	return w.newTailReader(r), nil
+}
+
+func (w *WAL) newTailReader(r *Reader) *TailReader {
+	opts := DefaultWatcherOptions
+	return &TailReader{r: r, backoff: opts.MinBackoff, opts: opts}
+}
+
+// Next returns the next chunk's data and position, blocking with
+// exponential backoff (capped at WatcherOptions.MaxBackoff) while the
+// reader is caught up with the end of the currently-written data. It only
+// returns an error for a genuine failure -- corruption or an I/O error --
+// never for having nothing new yet.
+//
+// Next returns ctx's error if ctx is done before a new chunk arrives.
+func (t *TailReader) Next(ctx context.Context) ([]byte, *ChunkPosition, error) {
+	for {
+		data, pos, err := t.r.Next()
+		if err == nil {
+			t.backoff = t.opts.MinBackoff
+			return data, pos, nil
+		}
+		if !errors.Is(err, io.EOF) {
+			return nil, nil, err
+		}
+
+		select {
+		case <-time.After(t.backoff):
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+		if t.backoff *= 2; t.backoff > t.opts.MaxBackoff {
+			t.backoff = t.opts.MaxBackoff
+		}
+	}
+}
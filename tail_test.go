@@ -0,0 +1,65 @@
+package wal
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTailReaderBlocksThenDeliversNewChunk(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-tail-reader-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("first"))
+	require.Nil(t, err)
+
+	tr := w.NewTailReader()
+
+	data, _, err := tr.Next(context.Background())
+	require.Nil(t, err)
+	require.Equal(t, []byte("first"), data)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		data, _, err := tr.Next(context.Background())
+		require.Nil(t, err)
+		require.Equal(t, []byte("second"), data)
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	_, err = w.Write([]byte("second"))
+	require.Nil(t, err)
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("TailReader did not deliver the new chunk in time")
+	}
+}
+
+func TestTailReaderRespectsContextCancellation(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-tail-reader-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	tr := w.NewTailReader()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, _, err = tr.Next(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
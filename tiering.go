@@ -0,0 +1,307 @@
+package wal
+
+import (
+	"container/list"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// tieringObjectKey is the ObjectStore key TierSegments uploads a sealed
+// segment's file under, and fetchTieredSegment downloads it back from.
+func tieringObjectKey(id SegmentID) string {
+	return fmt.Sprintf("segment-%09d", id)
+}
+
+// TierSegments uploads every sealed segment older than Options.TieringAge
+// (by the same createdAt Options.MaxSegmentAge already compares against)
+// to Options.ObjectStore and removes its local copy, so a WAL whose
+// history vastly exceeds local disk capacity can keep only its recent
+// segments on the fast path. A Read against a tiered segment still works
+// afterward -- see fetchTieredSegment -- just slower, and only once it's
+// been fetched back into Options.TieringCacheDir.
+//
+// Unlike Options.MaxSegmentAge, which is enforced automatically on
+// rotation, TierSegments only runs when called: uploading a segment is
+// exactly the kind of blocking network I/O rotateIfNeeded's callers don't
+// expect Write to do. A caller wanting this on a schedule should call
+// TierSegments periodically itself, the same way it would call Compact or
+// Checkpoint. It is serialized against Compact and Checkpoint by
+// checkpointMu, since all three change what's in w.olderSegments.
+//
+// TierSegments is a no-op, returning (0, nil), if Options.TieringAge is
+// not set. It returns the number of segments it successfully tiered,
+// stopping and returning whatever error it hit as soon as one upload or
+// local removal fails, leaving every segment it hasn't reached yet alone.
+func (w *WAL) TierSegments() (int, error) {
+	if w.options.TieringAge <= 0 {
+		return 0, nil
+	}
+
+	w.checkpointMu.Lock()
+	defer w.checkpointMu.Unlock()
+
+	cutoff := w.options.Clock.Now().Add(-w.options.TieringAge)
+	w.mu.RLock()
+	var candidates []*segment
+	for _, seg := range w.olderSegments {
+		if seg.createdAt.Before(cutoff) {
+			candidates = append(candidates, seg)
+		}
+	}
+	w.mu.RUnlock()
+
+	var tiered int
+	for _, seg := range candidates {
+		ok, err := w.tierSegment(seg)
+		if err != nil {
+			return tiered, err
+		}
+		if ok {
+			tiered++
+		}
+	}
+	return tiered, nil
+}
+
+// tierSegment uploads seg's file to Options.ObjectStore and, if that
+// succeeds, removes it from w.olderSegments and deletes its local file,
+// recording its size in w.tieredSegments so a later Read still knows to
+// look for it there. It reports false, with a nil error, if seg is
+// pinned by an in-flight Read or Reader.Next under
+// Options.PinnedSegmentPolicy's PinnedSegmentSkip -- see
+// resolvePinnedSegmentLocked -- since removing its local file out from
+// under that read would be exactly what pinning exists to prevent.
+func (w *WAL) tierSegment(seg *segment) (bool, error) {
+	f, err := os.Open(seg.path)
+	if err != nil {
+		return false, err
+	}
+	err = w.options.ObjectStore.Put(tieringObjectKey(seg.id), f)
+	f.Close()
+	if err != nil {
+		return false, err
+	}
+
+	w.mu.Lock()
+	retire, err := w.resolvePinnedSegmentLocked(seg)
+	if err != nil || !retire {
+		w.mu.Unlock()
+		return false, err
+	}
+	delete(w.olderSegments, seg.id)
+	if w.tieredSegments == nil {
+		w.tieredSegments = make(map[SegmentID]int64)
+	}
+	w.tieredSegments[seg.id] = seg.Size()
+	w.options.MetricsCollector.SetActiveSegments(len(w.olderSegments) + 1)
+	w.publishSegmentsLocked()
+	w.mu.Unlock()
+
+	seg.retire(nil)
+	return true, nil
+}
+
+// fetchTieredSegment returns a *segment for id backed by a local copy of
+// what TierSegments uploaded to Options.ObjectStore, fetching that copy
+// into Options.TieringCacheDir first if w.tieringCache doesn't already
+// have it open (see downloadTieredSegment). It returns (nil, nil), without
+// touching ObjectStore, if id was never tiered.
+func (w *WAL) fetchTieredSegment(id SegmentID) (*segment, error) {
+	w.mu.RLock()
+	_, tiered := w.tieredSegments[id]
+	w.mu.RUnlock()
+	if !tiered {
+		return nil, nil
+	}
+	return w.tieringCache.get(id)
+}
+
+// fetchRemoteOrCompressedSegment is readDecoded's fallback once
+// segmentByIDFast has failed to resolve id against the currently published
+// segments: id is either gone for good, tiered away by TierSegments, or
+// rewritten to a compressed on-disk representation by CompressSegments.
+// It returns (nil, nil), with no error, if id was never tiered or
+// compressed -- readDecoded treats that the same as errSegmentNotFound.
+func (w *WAL) fetchRemoteOrCompressedSegment(id SegmentID) (*segment, error) {
+	seg, err := w.fetchTieredSegment(id)
+	if err != nil || seg != nil {
+		return seg, err
+	}
+	return w.fetchCompressedSegment(id)
+}
+
+// downloadTieredSegment fetches id's bytes from Options.ObjectStore into
+// Options.TieringCacheDir and reopens the result as a read-only segment.
+func (w *WAL) downloadTieredSegment(id SegmentID) (*segment, error) {
+	rc, err := w.options.ObjectStore.Get(tieringObjectKey(id))
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	path := segmentFileName(w.options.TieringCacheDir, w.options.SegmentFileExt, id, w.options.SegmentFileNameFunc)
+	tmpPath := path + ".fetching"
+	tmp, err := os.Create(tmpPath)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(tmp, rc); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return nil, err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return nil, err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return nil, err
+	}
+
+	return openSegmentFile(w.options.FS, w.options.TieringCacheDir, w.options.SegmentFileExt, id, w.options.Mode,
+		false, true, false, false, false, w.options.Checksum, !w.options.SkipReadVerification, w.options.ChainChecksums,
+		w.options.Compression, w.dictID, w.options.BlockSize, w.options.ChunkIndex, w.options.SegmentSigningKey,
+		w.options.SegmentSize, nil, w.options.Clock, w.options.SegmentFileNameFunc, w.options.SegmentWriteBufferSize)
+}
+
+// segmentFetchCacheEntry is one segment a segmentFetchCache currently has
+// fetched and open, tracked in order's list so the least recently used one
+// can be found in evictLocked.
+type segmentFetchCacheEntry struct {
+	id  SegmentID
+	seg *segment
+}
+
+// segmentFetchCache is the local, size-bounded cache behind both
+// fetchTieredSegment and fetchCompressedSegment: full local segment files,
+// reopened as ordinary segments so Read can use segment.Read unchanged,
+// fetched via whatever fetch a cache was constructed with -- downloading
+// from Options.ObjectStore for a tiered segment, or decompressing a local
+// file for one Options.SealedSegmentCompression rewrote. The least
+// recently used entry is evicted -- its local copy removed via
+// segment.retire, requiring a re-fetch next time it's needed -- once a
+// fetch would push the cache past its configured byte bound.
+//
+// inflight deduplicates concurrent gets for the same id, the way
+// singleflight.Group does, so two Reads landing in the same segment at
+// once fetch it once between them instead of racing two redundant fetches.
+type segmentFetchCache struct {
+	mu       sync.Mutex
+	maxBytes int64
+	size     int64
+	order    *list.List
+	elems    map[SegmentID]*list.Element
+	inflight map[SegmentID]*segmentFetch
+	fetch    func(SegmentID) (*segment, error)
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+// segmentFetch is one in-flight fetch call that other concurrent get calls
+// for the same id wait on instead of starting their own, reported to each
+// waiter via done once it completes.
+type segmentFetch struct {
+	done chan struct{}
+	seg  *segment
+	err  error
+}
+
+// newSegmentFetchCache returns an empty segmentFetchCache bounded at
+// maxBytes (or unbounded if maxBytes is 0), fetching a cache miss via
+// fetch.
+func newSegmentFetchCache(maxBytes int64, fetch func(SegmentID) (*segment, error)) *segmentFetchCache {
+	return &segmentFetchCache{
+		maxBytes: maxBytes,
+		order:    list.New(),
+		elems:    make(map[SegmentID]*list.Element),
+		inflight: make(map[SegmentID]*segmentFetch),
+		fetch:    fetch,
+	}
+}
+
+// get returns id's cached segment, calling fetch to obtain and open it
+// first on a cache miss -- coalescing onto another goroutine's already
+// in-flight fetch of the same id, if there is one, rather than starting a
+// redundant one. It counts every call that found id already cached as a
+// hit, and every other call (whether it fetches or coalesces onto a
+// concurrent fetch) as a miss; see Stats.TieringCacheHits.
+func (c *segmentFetchCache) get(id SegmentID) (*segment, error) {
+	c.mu.Lock()
+	if elem, ok := c.elems[id]; ok {
+		c.order.MoveToBack(elem)
+		seg := elem.Value.(*segmentFetchCacheEntry).seg
+		c.mu.Unlock()
+		c.hits.Add(1)
+		return seg, nil
+	}
+
+	if f, ok := c.inflight[id]; ok {
+		c.mu.Unlock()
+		c.misses.Add(1)
+		<-f.done
+		return f.seg, f.err
+	}
+
+	f := &segmentFetch{done: make(chan struct{})}
+	c.inflight[id] = f
+	c.mu.Unlock()
+	c.misses.Add(1)
+
+	f.seg, f.err = c.fetch(id)
+
+	c.mu.Lock()
+	delete(c.inflight, id)
+	if f.err == nil {
+		elem := c.order.PushBack(&segmentFetchCacheEntry{id: id, seg: f.seg})
+		c.elems[id] = elem
+		c.size += f.seg.Size()
+		c.evictLocked()
+	}
+	c.mu.Unlock()
+	close(f.done)
+
+	return f.seg, f.err
+}
+
+// hitsAndMisses returns the running totals get has counted so far, for
+// Stats.
+func (c *segmentFetchCache) hitsAndMisses() (hits, misses uint64) {
+	return c.hits.Load(), c.misses.Load()
+}
+
+// evictLocked removes entries in least-recently-used order until c.size
+// is back at or below c.maxBytes. c.mu must be held. It never evicts the
+// only remaining entry, even if that single entry alone exceeds
+// maxBytes, since leaving the cache empty right after a fetch would just
+// force an immediate re-fetch on the very next Read of it.
+func (c *segmentFetchCache) evictLocked() {
+	if c.maxBytes <= 0 {
+		return
+	}
+	for c.size > c.maxBytes && c.order.Len() > 1 {
+		front := c.order.Front()
+		entry := front.Value.(*segmentFetchCacheEntry)
+		c.order.Remove(front)
+		delete(c.elems, entry.id)
+		c.size -= entry.seg.Size()
+		entry.seg.retire(nil)
+	}
+}
+
+// closeAll retires every segment a segmentFetchCache currently has open,
+// for WAL.Close.
+func (c *segmentFetchCache) closeAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, elem := range c.elems {
+		elem.Value.(*segmentFetchCacheEntry).seg.retire(nil)
+	}
+	c.order.Init()
+	c.elems = make(map[SegmentID]*list.Element)
+	c.size = 0
+}
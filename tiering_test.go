@@ -0,0 +1,239 @@
+package wal
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// memObjectStore is an in-memory ObjectStore fake for exercising
+// TierSegments and fetchTieredSegment without a real S3-compatible
+// backend. gets counts every Get call, and, if block is non-nil, each Get
+// waits on it before returning -- letting a test hold multiple concurrent
+// fetches open at once to exercise tieringCache's download deduplication.
+type memObjectStore struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	gets    int
+	block   <-chan struct{}
+}
+
+func newMemObjectStore() *memObjectStore {
+	return &memObjectStore{objects: make(map[string][]byte)}
+}
+
+func (m *memObjectStore) Put(key string, r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.objects[key] = data
+	return nil
+}
+
+func (m *memObjectStore) Get(key string) (io.ReadCloser, error) {
+	m.mu.Lock()
+	m.gets++
+	data, ok := m.objects[key]
+	block := m.block
+	m.mu.Unlock()
+	if block != nil {
+		<-block
+	}
+	if !ok {
+		return nil, errors.New("memObjectStore: no such key")
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (m *memObjectStore) getCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.gets
+}
+
+func (m *memObjectStore) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.objects, key)
+	return nil
+}
+
+func TestTierSegmentsUploadsAndRemovesSealedSegmentsPastTieringAge(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-tiering-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	cacheDir, err := os.MkdirTemp("", "wal-tiering-cache-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(cacheDir)
+
+	clock := NewFakeClock(time.Now())
+	store := newMemObjectStore()
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(32), WithClock(clock),
+		WithTiering(store, time.Minute, cacheDir, 0))
+	require.Nil(t, err)
+	defer w.Close()
+
+	pos, err := w.Write([]byte("tiered record"))
+	require.Nil(t, err)
+	_, err = w.Write([]byte("second segment, to force a rotation"))
+	require.Nil(t, err)
+	require.Greater(t, pos.SegmentId, SegmentID(0)) // sanity: not the only segment
+
+	clock.Tick(2 * time.Minute)
+
+	n, err := w.TierSegments()
+	require.Nil(t, err)
+	require.Equal(t, 1, n)
+
+	_, stillLocal := os.Stat(segmentFileName(dir, w.options.SegmentFileExt, pos.SegmentId, nil))
+	require.True(t, os.IsNotExist(stillLocal))
+
+	data, err := w.Read(pos)
+	require.Nil(t, err)
+	require.Equal(t, "tiered record", string(data))
+}
+
+func TestReadFetchesATieredSegmentBackFromTheObjectStore(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-tiering-fetch-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	cacheDir, err := os.MkdirTemp("", "wal-tiering-fetch-cache-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(cacheDir)
+
+	clock := NewFakeClock(time.Now())
+	store := newMemObjectStore()
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(32), WithClock(clock),
+		WithTiering(store, time.Minute, cacheDir, 0))
+	require.Nil(t, err)
+	defer w.Close()
+
+	pos, err := w.Write([]byte("tiered record"))
+	require.Nil(t, err)
+	_, err = w.Write([]byte("second segment, to force a rotation"))
+	require.Nil(t, err)
+
+	clock.Tick(2 * time.Minute)
+	_, err = w.TierSegments()
+	require.Nil(t, err)
+
+	data, err := w.Read(pos)
+	require.Nil(t, err)
+	require.Equal(t, "tiered record", string(data))
+
+	// A second Read should hit tieringCache instead of fetching again.
+	data, err = w.Read(pos)
+	require.Nil(t, err)
+	require.Equal(t, "tiered record", string(data))
+}
+
+func TestTierSegmentsIsANoOpWithoutTieringAge(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-tiering-disabled-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("record"))
+	require.Nil(t, err)
+
+	n, err := w.TierSegments()
+	require.Nil(t, err)
+	require.Equal(t, 0, n)
+}
+
+func TestStatsReportsTieringCacheHitsAndMisses(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-tiering-stats-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	cacheDir, err := os.MkdirTemp("", "wal-tiering-stats-cache-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(cacheDir)
+
+	clock := NewFakeClock(time.Now())
+	store := newMemObjectStore()
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(32), WithClock(clock),
+		WithTiering(store, time.Minute, cacheDir, 0))
+	require.Nil(t, err)
+	defer w.Close()
+
+	pos, err := w.Write([]byte("tiered record"))
+	require.Nil(t, err)
+	_, err = w.Write([]byte("second segment, to force a rotation"))
+	require.Nil(t, err)
+
+	clock.Tick(2 * time.Minute)
+	_, err = w.TierSegments()
+	require.Nil(t, err)
+
+	_, err = w.Read(pos)
+	require.Nil(t, err)
+	_, err = w.Read(pos)
+	require.Nil(t, err)
+
+	stats := w.Stats()
+	require.EqualValues(t, 1, stats.TieringCacheMisses)
+	require.EqualValues(t, 1, stats.TieringCacheHits)
+}
+
+func TestFetchTieredSegmentDeduplicatesConcurrentDownloads(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-tiering-singleflight-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	cacheDir, err := os.MkdirTemp("", "wal-tiering-singleflight-cache-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(cacheDir)
+
+	clock := NewFakeClock(time.Now())
+	store := newMemObjectStore()
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(32), WithClock(clock),
+		WithTiering(store, time.Minute, cacheDir, 0))
+	require.Nil(t, err)
+	defer w.Close()
+
+	pos, err := w.Write([]byte("tiered record"))
+	require.Nil(t, err)
+	_, err = w.Write([]byte("second segment, to force a rotation"))
+	require.Nil(t, err)
+
+	clock.Tick(2 * time.Minute)
+	_, err = w.TierSegments()
+	require.Nil(t, err)
+
+	block := make(chan struct{})
+	store.mu.Lock()
+	store.block = block
+	store.mu.Unlock()
+
+	const concurrency = 8
+	var wg sync.WaitGroup
+	wg.Add(concurrency)
+	for i := 0; i < concurrency; i++ {
+		go func() {
+			defer wg.Done()
+			data, err := w.Read(pos)
+			require.Nil(t, err)
+			require.Equal(t, "tiered record", string(data))
+		}()
+	}
+
+	close(block)
+	wg.Wait()
+
+	require.Equal(t, 1, store.getCount())
+}
@@ -0,0 +1,34 @@
+package wal
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+// timestampPrefixSize is the size, in bytes, of the UnixNano write
+// timestamp Options.Timestamps prefixes onto every chunk payload.
+const timestampPrefixSize = 8
+
+// prefixTimestamp prepends the current time to data if timestamps is set --
+// the same envelope WriteWithTTL already prepends its own expiry with,
+// just applied unconditionally rather than per call. It returns data
+// unmodified otherwise.
+func prefixTimestamp(timestamps bool, data []byte) []byte {
+	if !timestamps {
+		return data
+	}
+	prefixed := make([]byte, timestampPrefixSize+len(data))
+	binary.BigEndian.PutUint64(prefixed[:timestampPrefixSize], uint64(time.Now().UnixNano()))
+	copy(prefixed[timestampPrefixSize:], data)
+	return prefixed
+}
+
+// stripTimestamp reverses prefixTimestamp, splitting data's leading
+// timestampPrefixSize bytes back off as a time.Time. It returns data
+// unmodified with a zero time.Time if timestamps is not set.
+func stripTimestamp(timestamps bool, data []byte) ([]byte, time.Time) {
+	if !timestamps {
+		return data, time.Time{}
+	}
+	return data[timestampPrefixSize:], time.Unix(0, int64(binary.BigEndian.Uint64(data[:timestampPrefixSize])))
+}
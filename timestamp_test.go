@@ -0,0 +1,119 @@
+package wal
+
+import (
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteWithTimestampsRoundTripsThroughReadWithTimestamp(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-timestamps-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithTimestamps(true))
+	require.Nil(t, err)
+	defer w.Close()
+
+	before := time.Now()
+	pos, err := w.Write([]byte("hello"))
+	require.Nil(t, err)
+	after := time.Now()
+
+	data, writtenAt, err := w.ReadWithTimestamp(pos)
+	require.Nil(t, err)
+	assert.Equal(t, "hello", string(data))
+	assert.False(t, writtenAt.Before(before))
+	assert.False(t, writtenAt.After(after))
+
+	// Plain Read still returns exactly what was written, with no prefix
+	// leaking through.
+	plain, err := w.Read(pos)
+	require.Nil(t, err)
+	assert.Equal(t, "hello", string(plain))
+}
+
+func TestReaderNextWithTimestampMatchesWriteWithTimestamps(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-timestamps-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithTimestamps(true))
+	require.Nil(t, err)
+	defer w.Close()
+
+	before := time.Now()
+	_, err = w.Write([]byte("hello"))
+	require.Nil(t, err)
+
+	r := w.NewReader()
+	data, writtenAt, _, err := r.NextWithTimestamp()
+	require.Nil(t, err)
+	assert.Equal(t, "hello", string(data))
+	assert.False(t, writtenAt.Before(before))
+
+	plainData, _, err := r.Next()
+	assert.Equal(t, io.EOF, err)
+	assert.Nil(t, plainData)
+}
+
+func TestTimestampsDisabledReturnsZeroTime(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-timestamps-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	pos, err := w.Write([]byte("hello"))
+	require.Nil(t, err)
+
+	data, writtenAt, err := w.ReadWithTimestamp(pos)
+	require.Nil(t, err)
+	assert.Equal(t, "hello", string(data))
+	assert.True(t, writtenAt.IsZero())
+}
+
+func TestTimestampsSurviveCompressionAndEncryption(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-timestamps-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	key := make([]byte, 32)
+	w, err := Open(WithDirPath(dir), WithTimestamps(true), WithCompression(CompressionSnappy), WithEncryption(key))
+	require.Nil(t, err)
+	defer w.Close()
+
+	pos, err := w.Write([]byte("hello, encrypted and compressed"))
+	require.Nil(t, err)
+
+	data, writtenAt, err := w.ReadWithTimestamp(pos)
+	require.Nil(t, err)
+	assert.Equal(t, "hello, encrypted and compressed", string(data))
+	assert.False(t, writtenAt.IsZero())
+}
+
+func TestReadAllStripsTimestampsToo(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-timestamps-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithTimestamps(true))
+	require.Nil(t, err)
+	defer w.Close()
+
+	pos1, err := w.Write([]byte("one"))
+	require.Nil(t, err)
+	pos2, err := w.Write([]byte("two"))
+	require.Nil(t, err)
+
+	results, err := w.ReadAll([]*ChunkPosition{pos1, pos2})
+	require.Nil(t, err)
+	assert.Equal(t, "one", string(results[0]))
+	assert.Equal(t, "two", string(results[1]))
+}
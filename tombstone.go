@@ -0,0 +1,101 @@
+package wal
+
+import (
+	"io"
+	"os"
+)
+
+// tombstoneMagic prefixes every record Delete writes, the same way
+// checkpointMagic prefixes a checkpoint marker (see checkpoint_record.go).
+// A record starting with these 8 bytes is WAL bookkeeping, not application
+// data: Checkpoint recognizes it and drops it, along with the record it
+// targets, while compacting.
+const tombstoneMagic = "WAL:DEL0"
+
+// encodeTombstoneMarker renders target as tombstoneMagic followed by its
+// MarshalText encoding.
+func encodeTombstoneMarker(target *ChunkPosition) ([]byte, error) {
+	text, err := target.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	marker := make([]byte, 0, len(tombstoneMagic)+len(text))
+	marker = append(marker, tombstoneMagic...)
+	marker = append(marker, text...)
+	return marker, nil
+}
+
+// decodeTombstoneMarker reports whether raw is a tombstone written by
+// Delete, and if so, the position it targets.
+func decodeTombstoneMarker(raw []byte) (target *ChunkPosition, ok bool) {
+	if len(raw) <= len(tombstoneMagic) || string(raw[:len(tombstoneMagic)]) != tombstoneMagic {
+		return nil, false
+	}
+	target = &ChunkPosition{}
+	if err := target.UnmarshalText(raw[len(tombstoneMagic):]); err != nil {
+		return nil, false
+	}
+	return target, true
+}
+
+// chunkKey identifies a chunk by segment and offset, the two fields
+// ChunkPosition.Equal actually compares, for use as a map key where a full
+// ChunkPosition would be both overkill and wrong (ChunkSize and Sequence
+// don't participate in chunk identity).
+type chunkKey struct {
+	segmentID   SegmentID
+	chunkOffset int64
+}
+
+// Delete writes a tombstone record referencing target, the position of an
+// earlier record this WAL no longer needs -- the log still only ever
+// grows by appending, so this doesn't remove target's bytes itself. The
+// next Checkpoint recognizes the tombstone and drops both it and target
+// while compacting, the same way a WiscKey-style value log reclaims space
+// for overwritten or deleted values, letting rosedb use this WAL as one.
+//
+// Delete only pays off for a target still in a sealed segment: Checkpoint
+// matches tombstones against the sealed segments it's compacting in that
+// same run, not against an already-written checkpoint file, which has no
+// way to recover a record's original position (see Checkpoint). Calling
+// Delete for a target that's already been folded into a checkpoint still
+// writes the tombstone, but it's consumed, unresolved, the next time
+// Checkpoint runs -- calling Delete promptly, before target's segment is
+// compacted, is what makes the space reclaimable.
+func (w *WAL) Delete(target *ChunkPosition) (*ChunkPosition, error) {
+	marker, err := encodeTombstoneMarker(target)
+	if err != nil {
+		return nil, err
+	}
+	return w.Write(marker)
+}
+
+// tombstoneTargets scans segs for Delete's tombstone markers and returns
+// the positions they target, keyed by segment and offset so Checkpoint's
+// real compaction pass can recognize a targeted record on sight instead
+// of re-decoding the tombstone that named it for every candidate record.
+func tombstoneTargets(segs []*segment) (map[chunkKey]bool, error) {
+	targets := make(map[chunkKey]bool)
+	for _, seg := range segs {
+		f, err := os.Open(seg.path)
+		if err != nil {
+			return nil, err
+		}
+		r := io.NewSectionReader(f, segmentHeaderSize, seg.offset)
+		for {
+			record, err := readChunk(r, seg.checksum)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				f.Close()
+				return nil, err
+			}
+			if target, ok := decodeTombstoneMarker(record); ok {
+				targets[chunkKey{target.SegmentId, target.ChunkOffset}] = true
+			}
+		}
+		f.Close()
+	}
+	return targets, nil
+}
@@ -0,0 +1,118 @@
+package wal
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckpointDropsATombstoneAndItsTarget(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-delete-checkpoint-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(32))
+	require.Nil(t, err)
+	defer w.Close()
+
+	target, err := w.Write([]byte("overwritten value"))
+	require.Nil(t, err)
+	_, err = w.Write([]byte("live value"))
+	require.Nil(t, err)
+	_, err = w.Delete(target)
+	require.Nil(t, err)
+
+	// Force the segment holding target, kept, and the tombstone to seal.
+	for i := 0; i < 3; i++ {
+		_, err := w.Write([]byte("filler to force rotation"))
+		require.Nil(t, err)
+	}
+	require.Greater(t, len(w.olderSegments), 0)
+
+	var seen [][]byte
+	result, err := w.Checkpoint(context.Background(), func(record []byte) ([]byte, bool) {
+		seen = append(seen, record)
+		return record, false
+	})
+	require.Nil(t, err)
+	require.Equal(t, 2, result.RecordsDropped) // the tombstone itself and the target it named
+
+	for _, record := range seen {
+		require.NotEqual(t, "overwritten value", string(record))
+		_, isTombstone := decodeTombstoneMarker(record)
+		require.False(t, isTombstone)
+	}
+
+	var foundKept bool
+	for _, record := range seen {
+		if string(record) == "live value" {
+			foundKept = true
+		}
+	}
+	require.True(t, foundKept)
+
+	it, err := w.NewCheckpointIterator()
+	require.Nil(t, err)
+	defer it.Close()
+	var replayed []string
+	for {
+		record, err := it.Next()
+		if err != nil {
+			break
+		}
+		replayed = append(replayed, string(record))
+	}
+	require.NotContains(t, replayed, "overwritten value")
+	require.Contains(t, replayed, "live value")
+}
+
+func TestDeleteOfAnAlreadyCheckpointedTargetIsConsumedUnresolved(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-delete-already-checkpointed-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(32))
+	require.Nil(t, err)
+	defer w.Close()
+
+	target, err := w.Write([]byte("already folded in"))
+	require.Nil(t, err)
+	for i := 0; i < 3; i++ {
+		_, err := w.Write([]byte("filler to force rotation"))
+		require.Nil(t, err)
+	}
+	require.Greater(t, len(w.olderSegments), 0)
+
+	_, err = w.Checkpoint(context.Background(), func(record []byte) ([]byte, bool) {
+		return record, false
+	})
+	require.Nil(t, err)
+
+	_, err = w.Delete(target)
+	require.Nil(t, err)
+	for i := 0; i < 3; i++ {
+		_, err := w.Write([]byte("more filler to force rotation"))
+		require.Nil(t, err)
+	}
+
+	result, err := w.Checkpoint(context.Background(), func(record []byte) ([]byte, bool) {
+		return record, false
+	})
+	require.Nil(t, err)
+	require.Greater(t, result.RecordsDropped, 0)
+
+	it, err := w.NewCheckpointIterator()
+	require.Nil(t, err)
+	defer it.Close()
+	var replayed []string
+	for {
+		record, err := it.Next()
+		if err != nil {
+			break
+		}
+		replayed = append(replayed, string(record))
+	}
+	require.Contains(t, replayed, "already folded in")
+}
@@ -0,0 +1,95 @@
+package wal
+
+// TruncateAfter discards every chunk written after pos: every segment with
+// an ID greater than pos.SegmentId is removed outright, and pos's own
+// segment is trimmed to end right after pos's chunk and promoted back to
+// the active segment if it wasn't already. It is meant for Raft-style log
+// rollback after a leadership change, where writes further ahead than a
+// new leader's log must be discarded rather than replayed.
+//
+// Any Reader or Watcher positioned past pos will see an error or premature
+// EOF rather than the discarded data; callers that run readers concurrently
+// should stop them before calling TruncateAfter.
+func (w *WAL) TruncateAfter(pos *ChunkPosition) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.options.ReadOnly {
+		return ErrReadOnly
+	}
+
+	target := w.segmentByID(pos.SegmentId)
+	if target == nil {
+		return errSegmentNotFound(pos.SegmentId)
+	}
+
+	if target.id != w.activeSegment.id {
+		// Discard every segment newer than target, including the current
+		// active one, then promote target back to active.
+		var toRetire []*segment
+		for id, seg := range w.olderSegments {
+			if id > target.id {
+				toRetire = append(toRetire, seg)
+				delete(w.olderSegments, id)
+			}
+		}
+		toRetire = append(toRetire, w.activeSegment)
+		delete(w.olderSegments, target.id)
+		w.activeSegment = target
+
+		for _, seg := range toRetire {
+			seg.retire(w.recyclePipeline())
+		}
+		w.publishSegmentsLocked()
+	}
+
+	newEnd := pos.ChunkOffset + int64(chunkHeaderSize) + int64(pos.ChunkSize)
+	if err := target.truncate(newEnd); err != nil {
+		return err
+	}
+
+	w.options.MetricsCollector.SetActiveSegments(len(w.olderSegments) + 1)
+	w.options.MetricsCollector.SetSegmentSize(w.activeSegment.Size())
+	return nil
+}
+
+// TruncateBefore reclaims the space of every segment entirely older than
+// pos, for callers (e.g. a state machine that has checkpointed up to pos)
+// that no longer need to replay anything before it. It is segment-granular:
+// pos's own segment is kept in full, even the part before pos, since
+// chunks don't have a stable identity once their segment is trimmed out
+// from under them the way TruncateAfter's tail trim can get away with for
+// a segment about to become (or stay) active.
+//
+// Any Reader or Watcher still positioned before pos's segment will see an
+// error rather than the reclaimed data; callers that run readers
+// concurrently should checkpoint them past pos before calling
+// TruncateBefore.
+func (w *WAL) TruncateBefore(pos *ChunkPosition) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.options.ReadOnly {
+		return ErrReadOnly
+	}
+
+	if w.segmentByID(pos.SegmentId) == nil {
+		return errSegmentNotFound(pos.SegmentId)
+	}
+
+	var toRetire []*segment
+	for id, seg := range w.olderSegments {
+		if id < pos.SegmentId {
+			toRetire = append(toRetire, seg)
+			delete(w.olderSegments, id)
+		}
+	}
+	if len(toRetire) == 0 {
+		return nil
+	}
+
+	w.options.MetricsCollector.SetActiveSegments(len(w.olderSegments) + 1)
+	for _, seg := range toRetire {
+		seg.retire(w.recyclePipeline())
+	}
+	w.publishSegmentsLocked()
+	return nil
+}
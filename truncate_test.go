@@ -0,0 +1,77 @@
+package wal
+
+import (
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTruncateAfterDiscardsLaterChunksAndSegments(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-truncate-after-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(32))
+	require.Nil(t, err)
+	defer w.Close()
+
+	var positions []*ChunkPosition
+	for i := 0; i < 6; i++ {
+		pos, err := w.Write([]byte("0123456789"))
+		require.Nil(t, err)
+		positions = append(positions, pos)
+	}
+	require.Greater(t, len(w.olderSegments), 0)
+
+	keep := positions[2]
+	require.Nil(t, w.TruncateAfter(keep))
+
+	r := w.NewReader()
+	var read []*ChunkPosition
+	for {
+		_, pos, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		require.Nil(t, err)
+		read = append(read, pos)
+	}
+	require.Len(t, read, 3)
+	require.Equal(t, keep, read[len(read)-1])
+
+	// The log should still be writable after truncation.
+	_, err = w.Write([]byte("fresh-write"))
+	require.Nil(t, err)
+}
+
+func TestTruncateBeforeReclaimsOlderSegments(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-truncate-before-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(32))
+	require.Nil(t, err)
+	defer w.Close()
+
+	var positions []*ChunkPosition
+	for i := 0; i < 6; i++ {
+		pos, err := w.Write([]byte("0123456789"))
+		require.Nil(t, err)
+		positions = append(positions, pos)
+	}
+
+	keepFrom := positions[len(positions)-1]
+	require.Nil(t, w.TruncateBefore(keepFrom))
+
+	w.mu.RLock()
+	for id := range w.olderSegments {
+		require.GreaterOrEqual(t, id, keepFrom.SegmentId)
+	}
+	w.mu.RUnlock()
+
+	data, err := w.Read(keepFrom)
+	require.Nil(t, err)
+	require.Equal(t, []byte("0123456789"), data)
+}
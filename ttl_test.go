@@ -0,0 +1,134 @@
+package wal
+
+import (
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteWithTTLRoundTripsThroughReadWithTTL(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-ttl-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"))
+	require.Nil(t, err)
+	defer w.Close()
+
+	before := time.Now()
+	pos, err := w.WriteWithTTL([]byte("session token"), time.Hour)
+	require.Nil(t, err)
+
+	data, expiresAt, err := w.ReadWithTTL(pos)
+	require.Nil(t, err)
+	assert.Equal(t, "session token", string(data))
+	assert.True(t, expiresAt.After(before.Add(time.Hour-time.Second)))
+}
+
+func TestReadUnexpiredFlagsAnExpiredRecordWithoutHidingIt(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-ttl-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"))
+	require.Nil(t, err)
+	defer w.Close()
+
+	pos, err := w.WriteWithTTL([]byte("stale"), -time.Second)
+	require.Nil(t, err)
+
+	data, err := w.ReadUnexpired(pos)
+	assert.Equal(t, ErrRecordExpired, err)
+	assert.Equal(t, "stale", string(data))
+
+	pos2, err := w.WriteWithTTL([]byte("fresh"), time.Hour)
+	require.Nil(t, err)
+	data, err = w.ReadUnexpired(pos2)
+	require.Nil(t, err)
+	assert.Equal(t, "fresh", string(data))
+}
+
+func TestReaderNextUnexpiredSkipsPastExpiredRecords(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-ttl-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.WriteWithTTL([]byte("stale"), -time.Second)
+	require.Nil(t, err)
+	_, err = w.WriteWithTTL([]byte("fresh"), time.Hour)
+	require.Nil(t, err)
+
+	r := w.NewReader()
+	data, _, _, err := r.NextUnexpired()
+	require.Nil(t, err)
+	assert.Equal(t, "fresh", string(data))
+
+	_, _, _, err = r.NextUnexpired()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestDropExpiredSegmentsRetiresASegmentOnlyOnceEveryChunkInItHasExpired(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-ttl-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"), WithSegmentSize(64), WithDropExpiredSegments(true))
+	require.Nil(t, err)
+	defer w.Close()
+
+	// Fill and seal the first segment with already-expired TTL chunks.
+	firstPos, err := w.WriteWithTTL([]byte{0}, -time.Second)
+	require.Nil(t, err)
+	firstSegID := firstPos.SegmentId
+	for i := 1; i < 10; i++ {
+		_, err := w.WriteWithTTL([]byte{byte(i)}, -time.Second)
+		require.Nil(t, err)
+	}
+
+	// Force rotation past the first segment, which retires it.
+	for i := 0; i < 10; i++ {
+		_, err := w.WriteWithTTL([]byte{byte(i)}, time.Hour)
+		require.Nil(t, err)
+	}
+
+	w.mu.RLock()
+	_, stillPresent := w.olderSegments[firstSegID]
+	w.mu.RUnlock()
+	assert.False(t, stillPresent, "segment made entirely of expired TTL chunks should have been retired")
+}
+
+func TestDropExpiredSegmentsKeepsASegmentWithAnyNonTTLChunk(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-ttl-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"), WithSegmentSize(64), WithDropExpiredSegments(true))
+	require.Nil(t, err)
+	defer w.Close()
+
+	keepPos, err := w.Write([]byte("keep me forever"))
+	require.Nil(t, err)
+	firstSegID := keepPos.SegmentId
+	for i := 0; i < 9; i++ {
+		_, err := w.WriteWithTTL([]byte{byte(i)}, -time.Second)
+		require.Nil(t, err)
+	}
+
+	for i := 0; i < 10; i++ {
+		_, err := w.WriteWithTTL([]byte{byte(i)}, time.Hour)
+		require.Nil(t, err)
+	}
+
+	w.mu.RLock()
+	_, stillPresent := w.olderSegments[firstSegID]
+	w.mu.RUnlock()
+	assert.True(t, stillPresent, "segment holding a non-TTL chunk must never be auto-dropped")
+}
@@ -0,0 +1,42 @@
+package wal
+
+// Typed wraps a WAL with a Codec so callers can append and retrieve values
+// of T directly, instead of hand-rolling the same marshal-then-Write and
+// Read-then-unmarshal calls around every record themselves.
+type Typed[T any] struct {
+	w     *WAL
+	codec Codec[T]
+}
+
+// NewTyped returns a Typed[T] that encodes and decodes records through
+// codec on top of w. Multiple Typed values, including ones over different
+// T or Codec, can wrap the same WAL safely, the same way multiple plain
+// Write/Read callers can.
+func NewTyped[T any](w *WAL, codec Codec[T]) *Typed[T] {
+	return &Typed[T]{w: w, codec: codec}
+}
+
+// WriteRecord encodes v with the Typed's Codec and appends it to the WAL
+// as a single chunk, the same way Write does for raw bytes.
+func (t *Typed[T]) WriteRecord(v T) (*ChunkPosition, error) {
+	data, err := t.codec.Encode(v)
+	if err != nil {
+		return nil, err
+	}
+	return t.w.Write(data)
+}
+
+// ReadRecord reads the chunk at pos and decodes it with the Typed's Codec.
+// pos must have come from this Typed's WriteRecord (or another Typed[T]
+// using a compatible Codec and T) -- reading a chunk written as raw bytes,
+// or with a different Codec, is decode's to fail or misinterpret, the same
+// as calling ReadWithFlags on a chunk Write wrote.
+func (t *Typed[T]) ReadRecord(pos *ChunkPosition) (T, error) {
+	var v T
+	data, err := t.w.Read(pos)
+	if err != nil {
+		return v, err
+	}
+	err = t.codec.Decode(data, &v)
+	return v, err
+}
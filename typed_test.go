@@ -0,0 +1,53 @@
+package wal
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type typedTestRecord struct {
+	Key   string
+	Value int
+}
+
+func TestTypedWriteRecordRoundTripsThroughReadRecord(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-typed-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"))
+	require.Nil(t, err)
+	defer w.Close()
+
+	typed := NewTyped[typedTestRecord](w, GobCodec[typedTestRecord]{})
+
+	pos, err := typed.WriteRecord(typedTestRecord{Key: "a", Value: 1})
+	require.Nil(t, err)
+
+	got, err := typed.ReadRecord(pos)
+	require.Nil(t, err)
+	assert.Equal(t, typedTestRecord{Key: "a", Value: 1}, got)
+}
+
+func TestTypedOverPointerTypeAllocatesOnDecode(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-typed-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"))
+	require.Nil(t, err)
+	defer w.Close()
+
+	typed := NewTyped[*typedTestRecord](w, GobCodec[*typedTestRecord]{})
+
+	pos, err := typed.WriteRecord(&typedTestRecord{Key: "b", Value: 2})
+	require.Nil(t, err)
+
+	got, err := typed.ReadRecord(pos)
+	require.Nil(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, &typedTestRecord{Key: "b", Value: 2}, got)
+}
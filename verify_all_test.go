@@ -0,0 +1,73 @@
+package wal
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyAllReportsEveryDefect(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-verify-all-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"))
+	require.Nil(t, err)
+	pos1, err := w.Write([]byte("one"))
+	require.Nil(t, err)
+	pos2, err := w.Write([]byte("two"))
+	require.Nil(t, err)
+	_, err = w.Write([]byte("three"))
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+
+	corruptChunkCRC(t, dir, pos1)
+	corruptChunkCRC(t, dir, pos2)
+
+	report, err := VerifyAll(dir, WithSegmentFileExt(".SEG"))
+	require.Nil(t, err)
+	require.Equal(t, 1, report.SegmentsScanned)
+	require.Len(t, report.Defects, 2)
+	require.Equal(t, pos1.ChunkOffset, report.Defects[0].ChunkOffset)
+	require.Equal(t, pos2.ChunkOffset, report.Defects[1].ChunkOffset)
+	require.ErrorIs(t, report.Defects[0].Reason, ErrInvalidCRC)
+}
+
+func TestVerifyAllCleanLogHasNoDefects(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-verify-all-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"))
+	require.Nil(t, err)
+	_, err = w.Write([]byte("hello"))
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+
+	report, err := VerifyAll(dir, WithSegmentFileExt(".SEG"))
+	require.Nil(t, err)
+	require.Empty(t, report.Defects)
+	require.Equal(t, 1, report.ChunksScanned)
+}
+
+func TestWithVerifyOnOpenFailsOpenOnCorruption(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-verify-all-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"))
+	require.Nil(t, err)
+	pos, err := w.Write([]byte("hello"))
+	require.Nil(t, err)
+	_, err = w.Write([]byte("world"))
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+
+	corruptChunkCRC(t, dir, pos)
+
+	_, err = Open(WithDirPath(dir), WithSegmentFileExt(".SEG"), WithVerifyOnOpen(true))
+	var failed *ErrVerifyOnOpenFailed
+	require.ErrorAs(t, err, &failed)
+	require.Len(t, failed.Defects, 1)
+}
@@ -0,0 +1,93 @@
+package wal
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// corruptChunkCRC flips a byte in the on-disk CRC of the chunk at pos,
+// simulating bit rot that leaves the chunk's length field (and so its
+// framing) intact but its payload no longer matching its checksum.
+func corruptChunkCRC(t *testing.T, dir string, pos *ChunkPosition) {
+	t.Helper()
+	path := segmentFileName(dir, ".SEG", pos.SegmentId, nil)
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	require.Nil(t, err)
+	defer f.Close()
+
+	_, err = f.WriteAt([]byte{0xff}, segmentHeaderSize+pos.ChunkOffset+4)
+	require.Nil(t, err)
+}
+
+func TestVerifyOnReadDefaultCatchesCorruption(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-verify-on-read-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"))
+	require.Nil(t, err)
+	pos, err := w.Write([]byte("hello"))
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+
+	corruptChunkCRC(t, dir, pos)
+
+	w, err = Open(WithDirPath(dir), WithSegmentFileExt(".SEG"))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.Read(pos)
+	require.ErrorIs(t, err, ErrInvalidCRC)
+}
+
+func TestWithVerifyOnReadFalseSkipsCorruption(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-verify-on-read-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"))
+	require.Nil(t, err)
+	pos, err := w.Write([]byte("hello"))
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+
+	corruptChunkCRC(t, dir, pos)
+
+	w, err = Open(WithDirPath(dir), WithSegmentFileExt(".SEG"), WithVerifyOnRead(false))
+	require.Nil(t, err)
+	defer w.Close()
+
+	got, err := w.Read(pos)
+	require.Nil(t, err)
+	require.Equal(t, []byte("hello"), got)
+}
+
+// TestWithVerifyOnReadFalseStillRepairedAndVerified confirms that skipping
+// checksum checks on the read path has no bearing on Repair or Verify,
+// both of which scan every chunk directly rather than going through a
+// *segment's own verifyOnRead.
+func TestWithVerifyOnReadFalseStillRepairedAndVerified(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-verify-on-read-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"), WithVerifyOnRead(false))
+	require.Nil(t, err)
+	pos, err := w.Write([]byte("hello"))
+	require.Nil(t, err)
+	_, err = w.Write([]byte("world")) // a trailing chunk, so the corrupt one isn't mistaken for a torn tail write
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+
+	corruptChunkCRC(t, dir, pos)
+
+	_, err = Verify(dir, WithSegmentFileExt(".SEG"))
+	var corrupted *ErrCorrupted
+	require.ErrorAs(t, err, &corrupted)
+	require.ErrorIs(t, corrupted.Reason, ErrInvalidCRC)
+
+	_, err = Repair(dir, WithSegmentFileExt(".SEG"))
+	require.ErrorIs(t, err, ErrCorruptMidSegment)
+}
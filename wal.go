@@ -1,11 +1,17 @@
 package wal
 
 import (
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"sort"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // WAL is an append-only write-ahead log made up of fixed-size segment
@@ -15,199 +21,2190 @@ import (
 type WAL struct {
 	mu            sync.RWMutex
 	options       Options
+	gcm           cipher.AEAD // built from options.EncryptionKey by Open; nil if encryption is disabled or a KeyProvider is used instead
+	keyProvider   KeyProvider // built from options.KeyProvider, or wraps options.Keyring, by Open; nil if neither is set
+	dictID        uint32      // options.CompressionDict's own dictionary ID, extracted by Open; 0 if unset
+	bufPool       BufferPool  // built from options.BufferPool/WriteBufferSize by Open; nil if pooling is disabled
+	dirLock       *os.File    // holds DirPath's advisory lock for as long as the WAL is open; nil if ReadOnly or FS isn't the real OS filesystem
 	activeSegment *segment
 	olderSegments map[SegmentID]*segment
 
+	// blockCache caches decoded chunk payloads for readDecoded, built from
+	// options.BlockCache by open; nil if Options.BlockCache is 0.
+	blockCache *blockCache
+
+	// segmentsView is a copy-on-write snapshot of activeSegment and
+	// olderSegments, published by publishSegmentsLocked after every change
+	// to either. Read consults it via atomic.Pointer.Load instead of
+	// taking mu, so it never waits behind a concurrent Write's inline
+	// fsync just to find out which segment pos lives in -- the segment's
+	// own acquire/release refcount (see segment.acquire) is what actually
+	// keeps that lookup safe against a concurrent Checkpoint or Truncate
+	// retiring the segment, same as it always has.
+	segmentsView atomic.Pointer[segmentsSnapshot]
+
 	pendingWrites [][]byte
 	pendingSize   int64
 
 	bytesSinceSync uint32
 
-	closed bool
+	// syncFileRangeOffset and syncFileRangeCount track progress for
+	// Options.SyncFileRange; see syncFileRangeLocked.
+	syncFileRangeOffset int64
+	syncFileRangeCount  int
+
+	// nextSeq is the sequence number the next chunk written will be
+	// assigned; see ChunkPosition.Sequence. Open resumes it from the
+	// existing chunks' own sequence numbers (see indexExistingChunks), so
+	// it survives a restart like everything else already on disk -- but
+	// use it for in-process ordering and dedup, not as a stable
+	// replication offset, since nothing stops a future Repair or
+	// Checkpoint from renumbering what's left.
+	nextSeq uint64
+
+	// lastPos is the position of the most recently written chunk, or nil if
+	// nothing has been written yet; see LastPosition. Open recovers it from
+	// the existing chunks already on disk the same way it resumes nextSeq.
+	lastPos *ChunkPosition
+
+	// durablePos is the position of the most recent write known to have
+	// survived an fsync, or nil if none has yet; see DurablePosition and
+	// WaitForSync. Unlike lastPos, Open does not recover it across a
+	// restart -- everything on disk from a previous, cleanly closed run is
+	// durable by definition, and WaitForSync's isDurableLocked already
+	// treats any segment older than the active one that way without
+	// needing durablePos to say so.
+	durablePos *ChunkPosition
+
+	// nextBatchSeq is the batch ID the next Batch.Commit will tag its
+	// chunks with; see Batch. Seeded from the wall clock at Open, not
+	// reset to 0, so a batch ID never collides with one a previous run of
+	// this WAL may have left an uncommitted tail of on disk -- see
+	// NewBatch.
+	nextBatchSeq uint64
+
+	// syncErr is set to the first fsync failure this WAL has seen, and
+	// makes every subsequent write fail fast with ErrWALPoisoned instead
+	// of risking more data that never reaches disk. See Options.OnSyncError.
+	syncErr error
+
+	// Running totals and a bounded window of recent fsync durations behind
+	// Stats; all guarded by mu like everything else they're derived from.
+	bytesWritten        uint64
+	logicalBytesWritten uint64 // sum of record lengths passed to Write/WriteAll, before compression, encryption, and the timestamp prefix; see Stats.WriteAmplification
+	chunksWritten       uint64
+	fsyncCount          uint64
+	fsyncDurations      []time.Duration
+	fsyncDurationsNext  int
+	fsyncHistogram      [fsyncHistogramBucketCount]uint64 // see Stats.FsyncDurationHistogram
+
+	// rewrittenBytes counts on-disk bytes (header included) Compact and
+	// RewriteWithKey have rewritten into replacement segments, for
+	// Stats.WriteAmplification. Both run under checkpointMu rather than mu
+	// (see Compact's doc comment), so this is updated atomically instead
+	// of alongside bytesWritten.
+	rewrittenBytes atomic.Uint64
+
+	// lastWriteNano is the UnixNano of the most recent write's completion.
+	// It is atomic, rather than guarded by mu like the rest of write's
+	// bookkeeping, so waitOutWritePressure can poll it from the
+	// AutoCompactor's own goroutine without contending for mu against a
+	// busy foreground writer -- the exact contention Options.AutoCompaction
+	// exists to avoid adding to.
+	lastWriteNano atomic.Int64
+
+	pipeline    *filePipeline
+	flusherDone chan struct{}
+	flushReq    chan struct{}
+
+	// rotationTimerDone signals the background goroutine started for
+	// Options.SegmentRotationInterval to exit; see
+	// startSegmentRotationTimer.
+	rotationTimerDone chan struct{}
+
+	// fencingTimerDone signals the background goroutine started for
+	// Options.FencingCheckInterval to exit; see startFencingTimer.
+	fencingTimerDone chan struct{}
+
+	// writeQueue and writeQueueBytes back Options.Pipelined: Write
+	// appends to this queue and returns the position it will occupy
+	// instead of writing inline, and writeQueueDone's goroutine (started
+	// by Open) drains it in batches. Every entry belongs to activeSegment
+	// -- rotateQueuedIfNeeded always drains the queue before rotating --
+	// so the next entry's offset is always activeSegment.Size() plus
+	// writeQueueBytes. Guarded by mu, same as activeSegment itself.
+	writeQueue      []queuedWrite
+	writeQueueBytes int64
+	writeQueueDone  chan struct{}
+
+	// groupMu, groupPending, and groupLeading implement Options.GroupCommit;
+	// see requestGroupSync. Each is keyed by SegmentID, since group commit
+	// only coalesces writers fsyncing the same segment: a writer that lands
+	// in a new segment after a rotation gets its own batch there.
+	groupMu      sync.Mutex
+	groupPending map[SegmentID][]chan error
+	groupLeading map[SegmentID]bool
+
+	// writeLingerBatch is the batch Options.WriteLinger is currently
+	// accumulating joiners into, guarded by mu the same as writeQueue is;
+	// see joinWriteLinger. nil whenever no batch is in flight.
+	writeLingerBatch *writeLingerBatch
+
+	// checkpointMu serializes Checkpoint, Compact, and Backup/BackupSince
+	// calls, in any combination. All of them read-and-rewrite (or, for
+	// Backup, just read) the sealed segments with mu unlocked so none of
+	// them blocks Write, Read, or Sync for their duration; this keeps two
+	// such calls from racing on the same sealed segments (and, for two
+	// Checkpoint calls, the same checkpoint files) instead.
+	checkpointMu sync.Mutex
+
+	// asyncMu, asyncJobs, and asyncDone back WriteAsync; see
+	// startAsyncWriter. asyncJobs and asyncDone stay nil until the first
+	// WriteAsync call, so a WAL that never uses it doesn't pay for an idle
+	// goroutine. asyncMu guards their creation and, together with
+	// asyncClosed, keeps a WriteAsync racing against Close from ever
+	// sending on asyncJobs after (or while) Close closes it.
+	asyncMu     sync.RWMutex
+	asyncClosed bool
+	asyncJobs   chan asyncJob
+	asyncDone   chan struct{}
+
+	// lastCheckpointPos, lastCheckpointMeta, and checkpointScanned back
+	// LastCheckpoint; see its doc comment.
+	lastCheckpointPos  *ChunkPosition
+	lastCheckpointMeta []byte
+	checkpointScanned  bool
+
+	// offsets and offsetsScanned back CommitOffset and Offset, the same
+	// way lastCheckpointPos and checkpointScanned back WriteCheckpoint and
+	// LastCheckpoint: reconstructed from the log by Offset's first call
+	// against this WAL rather than eagerly by Open, since nothing needs
+	// them until the first consumer asks.
+	offsets        map[string]*ChunkPosition
+	offsetsScanned bool
+
+	// barrierPositions and barriersScanned back Barriers and
+	// ReadersAfterBarrier, the same way offsets and offsetsScanned back
+	// Offset: reconstructed from the log on first use rather than eagerly
+	// by Open.
+	barrierPositions []*ChunkPosition
+	barriersScanned  bool
+
+	// watchMu, watchers, and nextWatchID back Watch; see its doc comment.
+	watchMu     sync.Mutex
+	watchers    map[int]chan *ChunkPosition
+	nextWatchID int
+
+	closed bool
+
+	// reserveReleased tracks whether releaseReserveLocked has already
+	// freed Options.DiskFullReserve's headroom file this run; see
+	// handleIOErrorLocked.
+	reserveReleased bool
+
+	// diskQuotaExceeded is set by enforceDiskWatermarksLocked, at rotation,
+	// once total on-disk size is at or above Options.DiskHardWatermark, and
+	// cleared once a later rotation finds it back below. write and
+	// writeBatch check it instead of recomputing that total on every call.
+	diskQuotaExceeded bool
+
+	// fenced is set by checkFencingToken once a later Open has claimed a
+	// higher Options.FencingToken than this WAL's, and never cleared: write
+	// and writeQueued check it the same way they check syncErr, so this
+	// writer stops accepting writes for good rather than risk a second
+	// process appending to the same segments concurrently. See ErrFenced.
+	fenced bool
+
+	// idempotencyMu and idempotencySeen back WriteIdempotent's dedup
+	// window; see recordIdempotentWriteLocked. Nil when
+	// Options.IdempotencyWindow is 0, since WriteIdempotent refuses to run
+	// at all in that case.
+	idempotencyMu   sync.Mutex
+	idempotencySeen map[uint64]idempotencyEntry
+
+	// mirror is a second, independent WAL rooted at Options.MirrorDir that
+	// every Write (and anything built on top of it, like WriteWithTTL or
+	// WriteAsync) also writes to, so a single-disk failure that takes out
+	// options.DirPath doesn't take the mirror with it. Nil unless
+	// Options.MirrorDir is set. mirrorQueue, mirrorWG, mirrorMu, and
+	// mirrorClosed are only used when Options.MirrorAsync is also set; see
+	// mirrorWrite.
+	mirror       *WAL
+	mirrorMu     sync.RWMutex
+	mirrorClosed bool
+	mirrorQueue  chan []byte
+	mirrorWG     sync.WaitGroup
+
+	// mirrorErrMu and mirrorErr back MirrorErr, recording the most recent
+	// error an asynchronous mirror write failed with.
+	mirrorErrMu sync.Mutex
+	mirrorErr   error
+
+	// tieredSegments records, for every segment TierSegments has uploaded
+	// to Options.ObjectStore and removed the local copy of, the size it
+	// reported at the time -- enough for enforceMaxTotalSizeLocked's
+	// accounting without needing the segment's (now gone) local file.
+	// Guarded by mu, the same as olderSegments. Nil unless
+	// Options.TieringAge is set.
+	tieredSegments map[SegmentID]int64
+
+	// tieringCache is the local, size-bounded cache fetchTieredSegment
+	// fills in on a Read that lands in a tiered segment. Nil unless
+	// Options.TieringAge is set.
+	tieringCache *segmentFetchCache
+
+	// compressedSegments records, for every sealed segment CompressSegments
+	// has rewritten to a compressed on-disk representation, the size it
+	// reported before compression -- mirroring tieredSegments. Guarded by
+	// mu, the same as olderSegments. Nil unless
+	// Options.SealedSegmentCompression is set.
+	compressedSegments map[SegmentID]int64
+
+	// compressedSegmentCache is the local, size-bounded cache
+	// fetchCompressedSegment fills in on a Read that lands in a segment
+	// CompressSegments rewrote, mirroring tieringCache. Nil unless
+	// Options.SealedSegmentCompression is set.
+	compressedSegmentCache *segmentFetchCache
+
+	// autoCompactDone and autoCompactWG stop and wait out
+	// runAutoCompaction, the background goroutine Options.AutoCompaction
+	// starts, the same way mirrorQueue and mirrorWG do for runMirror.
+	// autoCompactErrMu and autoCompactErr back AutoCompactionErr. Nil
+	// unless Options.AutoCompaction is set.
+	autoCompactDone  chan struct{}
+	autoCompactWG    sync.WaitGroup
+	autoCompactErrMu sync.Mutex
+	autoCompactErr   error
+}
+
+// Open opens the WAL in options.DirPath, creating the directory and a
+// first segment if neither exists yet.
+func Open(opts ...Option) (*WAL, error) {
+	options := DefaultOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return open(options)
+}
+
+// OpenWithOptions opens the WAL the same way Open does, but takes a
+// ready-made Options struct instead of a slice of functional options --
+// useful when configuration is loaded from YAML/JSON and can be unmarshaled
+// straight into an Options value rather than built up as a slice of
+// closures. Any zero-valued field is backfilled from DefaultOptions, the
+// same defaults WithDirPath, WithSegmentSize, and friends apply on top of
+// when Open is used instead.
+func OpenWithOptions(options Options) (*WAL, error) {
+	options.applyDefaults()
+	return open(options)
+}
+
+// openOlderSegments opens every already-sealed segment named by ids,
+// returning them keyed by ID. With options.OpenParallelism > 1, they're
+// opened concurrently across a bounded worker pool instead of one at a
+// time -- each segment's chunk scan (or footer load) and, with
+// Options.MmapRead, its mmap setup only touch that segment's own file, so
+// nothing about doing several at once needs coordination beyond collecting
+// their results.
+func openOlderSegments(options Options, ids []SegmentID) (map[SegmentID]*segment, error) {
+	parallelism := options.OpenParallelism
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	// validate already rejected a malformed CompressionDict, so the only
+	// error zstdDictID can return here is for an empty dict, in which case
+	// dictID is correctly left at its zero value.
+	var dictID uint32
+	if len(options.CompressionDict) > 0 {
+		dictID, _ = zstdDictID(options.CompressionDict)
+	}
+
+	segs := make([]*segment, len(ids))
+	errs := make([]error, len(ids))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, parallelism)
+	for i, id := range ids {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, id SegmentID) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			seg, err := openSegmentFile(options.FS, options.DirPath, options.SegmentFileExt, id, options.Mode, false, options.ReadOnly, options.DirectIO, options.Fdatasync, options.Preallocate, options.Checksum, !options.SkipReadVerification, options.ChainChecksums, options.Compression, dictID, options.BlockSize, options.ChunkIndex, options.SegmentSigningKey, options.SegmentSize, nil, options.Clock, options.SegmentFileNameFunc, options.SegmentWriteBufferSize)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			if options.MmapRead {
+				if err := seg.enableMmapRead(options.Madvise); err != nil {
+					errs[i] = err
+					return
+				}
+			}
+			if options.FadviseDontNeed {
+				if err := seg.dropPageCache(); err != nil {
+					errs[i] = err
+					return
+				}
+			}
+			segs[i] = seg
+		}(i, id)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	older := make(map[SegmentID]*segment, len(ids))
+	for i, id := range ids {
+		older[id] = segs[i]
+	}
+	return older, nil
+}
+
+func open(options Options) (*WAL, error) {
+	if err := options.validate(); err != nil {
+		return nil, err
+	}
+
+	var gcm cipher.AEAD
+	if len(options.EncryptionKey) > 0 {
+		var err error
+		gcm, err = newGCM(options.EncryptionKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var dictID uint32
+	if len(options.CompressionDict) > 0 {
+		var err error
+		dictID, err = zstdDictID(options.CompressionDict)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	keyProvider := options.KeyProvider
+	if keyProvider == nil && len(options.Keyring) > 0 {
+		var err error
+		keyProvider, err = newMapKeyProvider(options.Keyring)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := options.FS.MkdirAll(options.DirPath, 0o755); err != nil {
+		return nil, err
+	}
+	if err := checkDirWritable(&options); err != nil {
+		return nil, err
+	}
+
+	// Read the previous run's clean-shutdown manifest, if it left one,
+	// before removing it: if it names the segment that's about to become
+	// active again, its recorded offset lets that segment skip straight
+	// past the scan and CRC check below instead of paying for them. Once
+	// read, it's stale the moment this run starts writing, so it's
+	// removed right away rather than left around for a crash to trust by
+	// mistake.
+	shutdownManifest, haveShutdownManifest, err := readShutdownMarker(options.FS, options.DirPath)
+	if err != nil {
+		return nil, err
+	}
+	if !options.ReadOnly {
+		if err := removeShutdownMarker(options.FS, options.DirPath); err != nil {
+			return nil, err
+		}
+	}
+
+	// dirLock is released by the deferred close below unless Open succeeds,
+	// in which case it's handed off to w.dirLock for Close to release
+	// instead.
+	var dirLock *os.File
+	if _, isOSFS := options.FS.(osFS); isOSFS && !options.ReadOnly {
+		var err error
+		dirLock, err = acquireDirLock(options.DirPath)
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			if dirLock != nil {
+				dirLock.Close()
+			}
+		}()
+	}
+
+	ids, _, err := segmentPaths(options.FS, options.DirPath, options.SegmentFileExt, options.SegmentFileNameParseFunc)
+	if err != nil {
+		return nil, err
+	}
+	if options.ReadOnly && len(ids) == 0 {
+		return nil, fmt.Errorf("wal: no segments found in %q to open read-only", options.DirPath)
+	}
+
+	if options.VerifyOnOpen {
+		report, verr := VerifyAll(options.DirPath,
+			WithSegmentFileExt(options.SegmentFileExt),
+			WithSegmentFileNaming(options.SegmentFileNameFunc, options.SegmentFileNameParseFunc),
+		)
+		if verr != nil {
+			return nil, verr
+		}
+		if len(report.Defects) > 0 {
+			return nil, &ErrVerifyOnOpenFailed{Defects: report.Defects}
+		}
+	}
+
+	if options.SegmentVerifyKey != nil {
+		if _, verr := VerifySignatures(options.DirPath, options.SegmentVerifyKey,
+			WithSegmentFileExt(options.SegmentFileExt),
+			WithSegmentFileNaming(options.SegmentFileNameFunc, options.SegmentFileNameParseFunc),
+		); verr != nil {
+			return nil, verr
+		}
+	}
+
+	bufPool := options.BufferPool
+	if bufPool == nil && options.WriteBufferSize > 0 {
+		bufPool = newSyncBufferPool(options.WriteBufferSize)
+	}
+
+	w := &WAL{
+		options:       options,
+		gcm:           gcm,
+		keyProvider:   keyProvider,
+		dictID:        dictID,
+		bufPool:       bufPool,
+		olderSegments: make(map[SegmentID]*segment),
+		groupPending:  make(map[SegmentID][]chan error),
+		groupLeading:  make(map[SegmentID]bool),
+		watchers:      make(map[int]chan *ChunkPosition),
+		offsets:       make(map[string]*ChunkPosition),
+	}
+	if options.IdempotencyWindow > 0 {
+		w.idempotencySeen = make(map[uint64]idempotencyEntry)
+	}
+	if options.BlockCache > 0 {
+		w.blockCache = newBlockCache(int64(options.BlockCache))
+	}
+
+	if len(ids) == 0 {
+		seg, err := openSegmentFile(options.FS, options.DirPath, options.SegmentFileExt, options.StartSegmentID, options.Mode, false, false, options.DirectIO, options.Fdatasync, options.Preallocate, options.Checksum, !options.SkipReadVerification, options.ChainChecksums, options.Compression, dictID, options.BlockSize, options.ChunkIndex, options.SegmentSigningKey, options.SegmentSize, nil, options.Clock, options.SegmentFileNameFunc, options.SegmentWriteBufferSize)
+		if err != nil {
+			return nil, err
+		}
+		w.activeSegment = seg
+	} else {
+		// Segments other than the active one were already sealed by a
+		// previous, clean run of rotateIfNeeded -- only the active
+		// segment can have been left mid-write by an unclean shutdown, so
+		// it's the only one opened with verifyTail.
+		older, olderErr := openOlderSegments(options, ids[:len(ids)-1])
+		if olderErr != nil {
+			return nil, olderErr
+		}
+		w.olderSegments = older
+
+		activeID := ids[len(ids)-1]
+		mode := effectiveRecoveryMode(options)
+
+		var trusted *trustedActiveTail
+		if haveShutdownManifest && shutdownManifest.activeSegmentID == activeID {
+			trusted = &trustedActiveTail{
+				offset:     shutdownManifest.offset,
+				chunkCount: shutdownManifest.chunkCount,
+				firstSeq:   shutdownManifest.nextSeq - uint64(shutdownManifest.chunkCount),
+			}
+		}
+
+		var seg *segment
+		var err error
+		if mode == RecoverSkipCorrupt && !options.ReadOnly {
+			// RecoverTail's verifyTail only CRC-checks the segment's very
+			// last chunk, which is enough to catch a torn tail write but
+			// not corruption further back. RecoverSkipCorrupt promises to
+			// heal that too, so run Repair unconditionally instead of
+			// waiting for verifyTail to notice anything is wrong.
+			dropped, rerr := Repair(options.DirPath,
+				WithSegmentFileExt(options.SegmentFileExt),
+				WithSegmentFileNaming(options.SegmentFileNameFunc, options.SegmentFileNameParseFunc),
+				WithMetricsCollector(options.MetricsCollector),
+				WithRecoveryMode(mode),
+			)
+			if rerr != nil {
+				return nil, rerr
+			}
+			if dropped > 0 {
+				options.MetricsCollector.IncIOErrors()
+			}
+			seg, err = openSegmentFile(options.FS, options.DirPath, options.SegmentFileExt, activeID, options.Mode, false, false, options.DirectIO, options.Fdatasync, options.Preallocate, options.Checksum, !options.SkipReadVerification, options.ChainChecksums, options.Compression, dictID, options.BlockSize, options.ChunkIndex, options.SegmentSigningKey, options.SegmentSize, nil, options.Clock, options.SegmentFileNameFunc, options.SegmentWriteBufferSize)
+		} else {
+			seg, err = openSegmentFile(options.FS, options.DirPath, options.SegmentFileExt, activeID, options.Mode, true, options.ReadOnly, options.DirectIO, options.Fdatasync, options.Preallocate, options.Checksum, !options.SkipReadVerification, options.ChainChecksums, options.Compression, dictID, options.BlockSize, options.ChunkIndex, options.SegmentSigningKey, options.SegmentSize, trusted, options.Clock, options.SegmentFileNameFunc, options.SegmentWriteBufferSize)
+			if err != nil && mode != RecoverStrict && !options.ReadOnly {
+				dropped, rerr := Repair(options.DirPath,
+					WithSegmentFileExt(options.SegmentFileExt),
+					WithSegmentFileNaming(options.SegmentFileNameFunc, options.SegmentFileNameParseFunc),
+					WithMetricsCollector(options.MetricsCollector),
+					WithRecoveryMode(mode),
+				)
+				if rerr != nil {
+					return nil, rerr
+				}
+				if dropped > 0 {
+					options.MetricsCollector.IncIOErrors()
+				}
+				seg, err = openSegmentFile(options.FS, options.DirPath, options.SegmentFileExt, activeID, options.Mode, false, false, options.DirectIO, options.Fdatasync, options.Preallocate, options.Checksum, !options.SkipReadVerification, options.ChainChecksums, options.Compression, dictID, options.BlockSize, options.ChunkIndex, options.SegmentSigningKey, options.SegmentSize, nil, options.Clock, options.SegmentFileNameFunc, options.SegmentWriteBufferSize)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		w.activeSegment = seg
+	}
+
+	if options.SegmentPreallocate > 0 && !options.ReadOnly {
+		w.pipeline = newFilePipeline(options.DirPath, options.SegmentFileExt+".next", options.SegmentSize, options.SegmentPreallocate)
+	}
+
+	if options.DiskFullReserve > 0 && !options.ReadOnly {
+		if err := createReserve(options.DirPath, options.DiskFullReserve); err != nil {
+			return nil, err
+		}
+	}
+
+	if options.Mode == ModeAhead && options.SyncInterval > 0 && !options.ReadOnly {
+		w.startFlusher(options.SyncInterval)
+	}
+
+	if options.Pipelined && !options.ReadOnly {
+		w.startWriteQueueFlusher(options.PipelineFlushInterval)
+	}
+
+	if options.SegmentRotationInterval > 0 && !options.ReadOnly {
+		w.startSegmentRotationTimer(options.SegmentRotationInterval)
+	}
+
+	if options.FencingToken > 0 && !options.ReadOnly {
+		if err := claimFencingToken(options.FS, options.DirPath, options.FencingToken); err != nil {
+			return nil, err
+		}
+		if options.FencingCheckInterval > 0 {
+			w.startFencingTimer(options.FencingCheckInterval)
+		}
+	}
+
+	w.options.MetricsCollector.SetActiveSegments(len(w.olderSegments) + 1)
+	w.options.MetricsCollector.SetSegmentSize(w.activeSegment.Size())
+
+	nextSeq, err := indexExistingChunks(w.orderedSegmentsLocked())
+	if err != nil {
+		return nil, err
+	}
+	w.nextSeq = nextSeq
+	w.nextBatchSeq = uint64(time.Now().UnixNano())
+	w.publishSegmentsLocked()
+
+	if haveShutdownManifest && w.activeSegment.footerLoaded && shutdownManifest.activeSegmentID == w.activeSegment.id {
+		// The manifest's own trusted offset already got the active segment
+		// past the scan above; reuse its recorded last position too,
+		// rather than have NewReverseReader redo the same scan just to find
+		// it again.
+		w.lastPos = shutdownManifest.lastPos
+	} else if _, pos, err := w.NewReverseReader().Next(); err == nil {
+		w.lastPos = pos
+	} else if err != io.EOF {
+		return nil, err
+	}
+
+	if options.IdempotencyWindow > 0 {
+		if err := w.rebuildIdempotencyWindow(); err != nil {
+			return nil, err
+		}
+	}
+
+	if options.MirrorDir != "" && !options.ReadOnly {
+		mirrorOptions := options
+		mirrorOptions.DirPath = options.MirrorDir
+		mirrorOptions.MirrorDir = ""
+		mirrorOptions.MirrorAsync = false
+		mirrorOptions.MirrorLagBound = 0
+		mirror, merr := OpenWithOptions(mirrorOptions)
+		if merr != nil {
+			return nil, merr
+		}
+		w.mirror = mirror
+		if options.MirrorAsync {
+			lagBound := options.MirrorLagBound
+			if lagBound <= 0 {
+				lagBound = defaultMirrorLagBound
+			}
+			w.mirrorQueue = make(chan []byte, lagBound)
+			w.mirrorWG.Add(1)
+			go w.runMirror()
+		}
+	}
+
+	if options.TieringAge > 0 {
+		if err := os.MkdirAll(options.TieringCacheDir, 0o755); err != nil {
+			return nil, err
+		}
+		w.tieringCache = newSegmentFetchCache(options.TieringCacheSize, w.downloadTieredSegment)
+	}
+
+	if options.SealedSegmentCompression != CompressionNone {
+		if err := os.MkdirAll(options.SealedSegmentCompressionCacheDir, 0o755); err != nil {
+			return nil, err
+		}
+		w.compressedSegmentCache = newSegmentFetchCache(options.SealedSegmentCompressionCacheSize, w.decompressSegment)
+	}
+
+	if options.AutoCompaction != nil {
+		w.autoCompactDone = make(chan struct{})
+		w.autoCompactWG.Add(1)
+		go w.runAutoCompaction(options.AutoCompaction)
+	}
+
+	w.dirLock, dirLock = dirLock, nil
+	return w, nil
+}
+
+// ErrReadOnly is returned by Write, WriteAll, Sync, Checkpoint, Repair,
+// TruncateAfter, and TruncateBefore on a WAL opened with WithReadOnly.
+var ErrReadOnly = fmt.Errorf("wal: write operations are disabled on a WAL opened with WithReadOnly")
+
+// ErrWALPoisoned is the sentinel an *ErrPoisoned unwraps to, so
+// errors.Is(err, ErrWALPoisoned) identifies a poisoned WAL from Write or
+// WriteAll without needing errors.As to get at the underlying Cause.
+var ErrWALPoisoned = fmt.Errorf("wal: a previous fsync failed, refusing further writes until the WAL is reopened")
+
+// ErrPoisoned is returned by Write and WriteAll once an fsync has failed:
+// see Options.OnSyncError. Cause is the fsync error that poisoned the
+// WAL. Close and reopen the WAL to clear it; Healthy and Err report the
+// same state without attempting a write.
+type ErrPoisoned struct {
+	Cause error
+}
+
+func (e *ErrPoisoned) Error() string {
+	return fmt.Sprintf("wal: a previous fsync failed (%v), refusing further writes until the WAL is reopened", e.Cause)
+}
+
+// Unwrap returns ErrWALPoisoned, so errors.Is(err, ErrWALPoisoned) matches
+// any *ErrPoisoned without needing errors.As to get at Cause.
+func (e *ErrPoisoned) Unwrap() error {
+	return ErrWALPoisoned
+}
+
+// ErrDiskQuotaExceeded is returned by Write and WriteAll once total
+// on-disk size is at or above Options.DiskHardWatermark, rather than let
+// them keep filling the disk. Unlike ErrWALPoisoned, it clears itself,
+// the next time rotation notices usage has dropped back down -- e.g.
+// after a Checkpoint or Compact freed up space in response to
+// Options.OnDiskWatermark.
+var ErrDiskQuotaExceeded = fmt.Errorf("wal: total on-disk size at or above Options.DiskHardWatermark")
+
+// poisonLocked records err as the reason the WAL refuses further writes,
+// and reports it via Options.OnSyncError, the first time an fsync fails.
+// mu must already be held.
+func (w *WAL) poisonLocked(err error) {
+	if w.syncErr != nil {
+		return
+	}
+	w.syncErr = err
+	w.options.Logger.Error("wal: fsync failed, refusing further writes until reopened", "error", err)
+	if w.options.OnSyncError != nil {
+		w.options.OnSyncError(err)
+	}
+}
+
+// Err returns the fsync error that poisoned the WAL (see ErrPoisoned), or
+// nil if it's still healthy. Unlike the error Write and WriteAll return,
+// this is the raw cause, not wrapped in an *ErrPoisoned. Reads are
+// unaffected by a poisoned WAL; only Write, WriteAll, and their pipelined
+// equivalents are.
+func (w *WAL) Err() error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.syncErr
+}
+
+// Healthy reports whether the WAL is still accepting writes, i.e.
+// whether Err returns nil.
+func (w *WAL) Healthy() bool {
+	return w.Err() == nil
+}
+
+// Write appends data to the WAL as a single chunk and returns its
+// position. Depending on Options.Sync and Options.BytesPerSync, it may
+// fsync the active segment before returning. With Options.Pipelined, it
+// queues data and returns instead -- see Options.Pipelined.
+func (w *WAL) Write(data []byte) (pos *ChunkPosition, err error) {
+	var state any
+	if w.options.Hooks.BeforeWrite != nil {
+		state = w.options.Hooks.BeforeWrite()
+	}
+	defer func() {
+		if w.options.Hooks.AfterWrite != nil {
+			w.options.Hooks.AfterWrite(state, pos, err)
+		}
+		if err == nil {
+			w.notifyWatchers(pos)
+			if mirrErr := w.mirrorWrite(data); mirrErr != nil {
+				err = mirrErr
+			}
+		}
+	}()
+
+	if w.options.Pipelined {
+		pos, err = w.writeQueued(data)
+		return pos, err
+	}
+
+	if w.options.WriteLinger > 0 {
+		pos, err = w.joinWriteLinger(data)
+		return pos, err
+	}
+
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil, ErrClosed
+	}
+	if w.options.ReadOnly {
+		w.mu.Unlock()
+		return nil, ErrReadOnly
+	}
+	var seg *segment
+	var kind deferKind
+	pos, kind, err = w.write(data, true)
+	if kind != deferNone {
+		seg = w.activeSegment
+	}
+	w.mu.Unlock()
+	if err != nil || kind == deferNone {
+		return pos, err
+	}
+	if kind == deferGroup {
+		return pos, w.requestGroupSync(seg)
+	}
+	return pos, w.syncSegmentUnlocked(seg)
+}
+
+// WriteWithFlags is Write, but reserves a single user-controlled byte that
+// travels alongside data -- e.g. to tag a record as a checkpoint marker or
+// a tombstone -- without the caller having to build an envelope of their
+// own for it. flags is retrievable again with ReadWithFlags or
+// Reader.NextWithFlags.
+//
+// A chunk written this way must be read back with one of those, not Read
+// or Reader.Next, which would otherwise return flags as data's leading
+// byte instead of stripping it -- the same convention Options.Compression
+// and Options.EncryptionKey already require of a WAL reopened with
+// different settings than it was written with. pos.ChunkSize for a chunk
+// written this way is one byte larger than len(data), to account for
+// flags.
+func (w *WAL) WriteWithFlags(data []byte, flags byte) (*ChunkPosition, error) {
+	return w.Write(append([]byte{flags}, data...))
+}
+
+// WriteWithTTL is Write, but prefixes data with an expiry timestamp, ttl
+// from now, that ReadWithTTL, ReadUnexpired, or Reader.NextWithTTL can read
+// back -- e.g. for a cache-like record that should stop being honored after
+// a while without the caller having to track its expiry separately. See
+// WriteWithFlags's doc comment for why a chunk written this way must be
+// read back with one of those, not Read or Reader.Next.
+//
+// If Options.DropExpiredSegments is set, a sealed segment made up entirely
+// of expired WriteWithTTL chunks is retired the next time rotation runs;
+// see Options.DropExpiredSegments for what disqualifies a segment from
+// that.
+func (w *WAL) WriteWithTTL(data []byte, ttl time.Duration) (*ChunkPosition, error) {
+	expiresAt := time.Now().Add(ttl)
+	var prefix [8]byte
+	binary.BigEndian.PutUint64(prefix[:], uint64(expiresAt.UnixNano()))
+
+	pos, err := w.Write(append(prefix[:], data...))
+	if err != nil {
+		return nil, err
+	}
+
+	w.mu.RLock()
+	seg := w.segmentByID(pos.SegmentId)
+	w.mu.RUnlock()
+	if seg != nil {
+		seg.recordTTLWrite(expiresAt)
+	}
+	return pos, nil
+}
+
+// WriteIdempotent is Write, but deduplicates retries of the same id within
+// Options.IdempotencyWindow: a call with an id already seen inside the
+// window returns the original write's position without appending a new
+// chunk, so an at-least-once producer that times out waiting for an ack
+// and retries doesn't leave two copies of the same record in the log. Once
+// the window on an id closes, a further call with it is treated as new.
+//
+// It returns ErrIdempotencyWindowDisabled if Options.IdempotencyWindow is
+// not set -- there's no window to deduplicate within otherwise. It
+// prefixes data with id and the write's timestamp so the window survives a
+// restart (see rebuildIdempotencyWindow); a chunk written this way must be
+// read back with ReadIdempotent or Reader.NextIdempotent, not Read or
+// Reader.Next -- see WriteWithFlags's doc comment for why.
+//
+// The dedup check and the write it guards aren't atomic together, so two
+// calls with the same id that genuinely race -- rather than one retrying
+// after the other has already returned -- can both go on to append a
+// chunk; it's retries after a timeout this is meant to collapse, not
+// concurrent callers racing on purpose.
+func (w *WAL) WriteIdempotent(id uint64, data []byte) (*ChunkPosition, error) {
+	if w.options.IdempotencyWindow <= 0 {
+		return nil, ErrIdempotencyWindowDisabled
+	}
+
+	now := time.Now()
+	w.idempotencyMu.Lock()
+	if entry, ok := w.idempotencySeen[id]; ok && entry.expiresAt.After(now) {
+		w.idempotencyMu.Unlock()
+		return entry.pos, nil
+	}
+	w.idempotencyMu.Unlock()
+
+	pos, err := w.Write(prefixIdempotency(id, now, data))
+	if err != nil {
+		return nil, err
+	}
+
+	w.idempotencyMu.Lock()
+	w.idempotencySeen[id] = idempotencyEntry{pos: pos, expiresAt: now.Add(w.options.IdempotencyWindow)}
+	w.idempotencyMu.Unlock()
+	return pos, nil
+}
+
+// WriteRaw is Write, but first verifies data against checksum, computed
+// under algo the same way Replicator's RawChunk carries one for a record it
+// relayed -- e.g. a walrepl client applying a chunk a remote WAL sent it
+// over the network, which wants to catch corruption in transit rather than
+// trust the bytes it received. It returns ErrInvalidCRC, without appending
+// anything, if the check fails.
+func (w *WAL) WriteRaw(data []byte, algo Checksum, checksum uint32) (*ChunkPosition, error) {
+	if !verifyChecksum(algo, data, checksum) {
+		return nil, ErrInvalidCRC
+	}
+	return w.Write(data)
+}
+
+// requestGroupSync implements Options.GroupCommit: it joins the queue of
+// writers waiting on seg's next fsync, becoming the leader that actually
+// calls seg.Sync if no other writer is already leading for this segment.
+//
+// A writer only joins the queue after its own payload write has already
+// completed under w.mu (see write and Write), so when the leader snapshots
+// and clears the queue right before calling seg.Sync, every writer in that
+// snapshot is guaranteed to have its data on disk once that Sync returns --
+// the fsync call itself always starts after their writes, never races with
+// one still in flight. A writer that arrives after the snapshot was taken
+// simply waits for the next round instead of being told it's durable early.
+func (w *WAL) requestGroupSync(seg *segment) error {
+	id := seg.id
+	ch := make(chan error, 1)
+
+	w.groupMu.Lock()
+	w.groupPending[id] = append(w.groupPending[id], ch)
+	if w.groupLeading[id] {
+		w.groupMu.Unlock()
+		return <-ch
+	}
+	w.groupLeading[id] = true
+	w.groupMu.Unlock()
+
+	for {
+		w.groupMu.Lock()
+		batch := w.groupPending[id]
+		w.groupPending[id] = nil
+		w.groupMu.Unlock()
+
+		syncErr := w.syncSegmentUnlocked(seg)
+		for _, c := range batch {
+			c <- syncErr
+		}
+
+		w.groupMu.Lock()
+		if len(w.groupPending[id]) == 0 {
+			delete(w.groupLeading, id)
+			w.groupMu.Unlock()
+			break
+		}
+		w.groupMu.Unlock()
+	}
+	return <-ch
+}
+
+// syncSegmentUnlocked fsyncs seg and records the result -- metrics, the
+// fsync count and duration history, bytesSinceSync, the durable frontier
+// WaitForSync polls, or poisoning the WAL on failure -- the same
+// bookkeeping w.sync does for an inline fsync. Unlike w.sync, it takes
+// w.mu itself for just that bookkeeping rather than assuming it's already
+// held, so it can be called after a writer has released the lock: this is
+// what lets deferKind's deferGroup and deferSolo fsync the active segment
+// without holding w.mu for the fsync itself, the same way
+// requestGroupSync's batched leader does.
+func (w *WAL) syncSegmentUnlocked(seg *segment) error {
+	// Taken before seg.Sync() starts, under an RLock rather than seg's own
+	// unsynchronized Size(): every append to seg happens under w.mu.Lock()
+	// (see write), so this is a safe, if possibly stale, lower bound on
+	// what the fsync about to run will actually cover -- unlike reading
+	// w.lastPos again afterward, which could already reflect a write that
+	// arrived after seg.Sync() started and isn't guaranteed durable yet.
+	w.mu.RLock()
+	syncedThrough := w.lastPos
+	w.mu.RUnlock()
+
+	start := time.Now()
+	err := seg.Sync()
+	d := time.Since(start)
+
+	w.mu.Lock()
+	if err != nil {
+		w.options.MetricsCollector.IncIOErrors()
+		w.poisonLocked(err)
+	} else {
+		w.options.MetricsCollector.ObserveFsyncDuration(d)
+		w.fsyncCount++
+		w.recordFsyncDurationLocked(d)
+		w.reportSlowSyncLocked(d)
+		if seg == w.activeSegment {
+			w.bytesSinceSync = 0
+			w.advanceDurableLocked(syncedThrough)
+		}
+	}
+	w.mu.Unlock()
+	return err
+}
+
+// deferKind reports whether write deferred the active segment's fsync
+// until after w.mu is released, and if so, by which mechanism. See write.
+type deferKind int8
+
+const (
+	// deferNone means write already fsynced inline, if at all, before
+	// returning -- there is nothing left for the caller to do.
+	deferNone deferKind = iota
+
+	// deferGroup means the caller must call requestGroupSync on the
+	// returned segment: Options.GroupCommit is on, so the fsync may be
+	// batched with other writers targeting the same segment.
+	deferGroup
+
+	// deferSolo means the caller must call syncSegmentUnlocked on the
+	// returned segment: Options.Sync is on but GroupCommit is off, so the
+	// fsync happens alone, outside w.mu, but without joining a queue.
+	deferSolo
+)
+
+// write appends data to the WAL as a single chunk. w.mu must already be
+// held. allowGroupCommit is true only from Write, never from WriteAll's
+// loop: deferring the fsync needs to release w.mu between a writer's own
+// append and its fsync so other writers can append concurrently, which
+// WriteAll's single-lock-for-the-whole-batch call doesn't allow for -- so
+// it always gets the older, inline-fsync behavior instead. When write
+// returns a deferKind other than deferNone, the caller is responsible for
+// fsyncing the returned segment (via requestGroupSync or
+// syncSegmentUnlocked, matching the returned kind) once it has released
+// w.mu.
+func (w *WAL) write(data []byte, allowGroupCommit bool) (pos *ChunkPosition, kind deferKind, err error) {
+	if w.syncErr != nil {
+		return nil, deferNone, &ErrPoisoned{Cause: w.syncErr}
+	}
+	if w.fenced {
+		return nil, deferNone, ErrFenced
+	}
+	if w.diskQuotaExceeded {
+		return nil, deferNone, ErrDiskQuotaExceeded
+	}
+	if len(data) == 0 {
+		return nil, deferNone, ErrEmptyRecord
+	}
+	if w.options.MaxRecordSize > 0 && int64(len(data)) > w.options.MaxRecordSize {
+		return nil, deferNone, &ErrRecordTooLarge{Size: len(data), MaxSize: w.options.MaxRecordSize}
+	}
+	logicalLen := len(data)
+	data = prefixTimestamp(w.options.Timestamps, data)
+
+	var scratch []byte
+	if w.bufPool != nil && w.options.Compression != CompressionNone {
+		scratch = w.bufPool.Get()
+		defer w.bufPool.Put(scratch)
+	}
+
+	payload, err := compressPayload(w.options.Compression, data, scratch, w.options.CompressionDict)
+	if err != nil {
+		return nil, deferNone, err
+	}
+	payload, err = w.encryptChunk(payload)
+	if err != nil {
+		return nil, deferNone, err
+	}
+
+	if err := w.rotateIfNeeded(int64(chunkHeaderSize) + int64(len(payload))); err != nil {
+		return nil, deferNone, err
+	}
+
+	if w.options.Limiter != nil {
+		if err := w.options.Limiter.WaitN(chunkHeaderSize + len(payload)); err != nil {
+			return nil, deferNone, err
+		}
+	}
+
+	pos, err = w.activeSegment.Write(payload)
+	if err != nil {
+		w.options.MetricsCollector.IncIOErrors()
+		return nil, deferNone, w.handleIOErrorLocked(err, w.activeSegment.id, w.activeSegment.Size())
+	}
+	pos.Sequence = w.nextSeq
+	w.nextSeq++
+	w.activeSegment.recordSequence(pos.Sequence, pos.ChunkOffset)
+	w.lastPos = pos
+
+	w.options.MetricsCollector.AddBytesWritten(len(payload))
+	w.options.MetricsCollector.IncChunksWritten()
+	w.options.MetricsCollector.SetSegmentSize(w.activeSegment.Size())
+	w.bytesWritten += uint64(len(payload))
+	w.logicalBytesWritten += uint64(logicalLen)
+	w.chunksWritten++
+	w.lastWriteNano.Store(w.options.Clock.Now().UnixNano())
+
+	w.bytesSinceSync += uint32(chunkHeaderSize + len(payload))
+	switch w.options.Mode {
+	case ModeAhead:
+		switch {
+		case w.options.Sync && allowGroupCommit && w.options.GroupCommit:
+			kind = deferGroup
+		case w.options.Sync && allowGroupCommit:
+			kind = deferSolo
+		case w.options.Sync:
+			if err := w.sync(); err != nil {
+				return nil, deferNone, err
+			}
+		case w.options.BytesPerSync > 0 && w.bytesSinceSync >= w.options.BytesPerSync:
+			if w.options.SyncFileRange {
+				if err := w.syncFileRangeLocked(); err != nil {
+					return nil, deferNone, err
+				}
+			} else if err := w.sync(); err != nil {
+				return nil, deferNone, err
+			}
+		}
+	case ModeBehind:
+		// Never fsync inline: that's the point of write-behind mode.
+		// Once BytesPerSync has been crossed, nudge the background
+		// flusher to catch up instead of leaving it to FlushInterval
+		// alone, which would otherwise be the only thing honoring
+		// BytesPerSync in this mode.
+		if w.options.BytesPerSync > 0 && w.bytesSinceSync >= w.options.BytesPerSync && w.flushReq != nil {
+			select {
+			case w.flushReq <- struct{}{}:
+			default:
+			}
+		}
+	}
+	return pos, kind, nil
+}
+
+// PendingWrites buffers data to be written by the next call to WriteAll,
+// without touching the segment files yet. If Options.MaxRecordSize is set
+// and data is larger than it, PendingWrites returns an *ErrRecordTooLarge
+// and leaves data out of the batch, instead of letting WriteAll discover
+// the oversized record only after every other one already buffered has
+// been staged for writing. Likewise, if Options.MaxPendingSize is set and
+// buffering data would put the batch over it, PendingWrites returns
+// ErrPendingSizeTooLarge and leaves data out rather than growing the
+// batch unbounded until WriteAll is finally called.
+func (w *WAL) PendingWrites(data []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return ErrClosed
+	}
+	if w.options.MaxRecordSize > 0 && int64(len(data)) > w.options.MaxRecordSize {
+		return &ErrRecordTooLarge{Size: len(data), MaxSize: w.options.MaxRecordSize}
+	}
+	if w.options.MaxPendingSize > 0 && w.pendingSize+int64(len(data)) > w.options.MaxPendingSize {
+		return ErrPendingSizeTooLarge
+	}
+	w.pendingWrites = append(w.pendingWrites, data)
+	w.pendingSize += int64(len(data))
+	w.options.MetricsCollector.SetPendingWritesQueueDepth(len(w.pendingWrites))
+	return nil
+}
+
+// WriteAll flushes every write buffered by PendingWrites, in the order
+// they were added, and returns their positions.
+func (w *WAL) WriteAll() (positions []*ChunkPosition, err error) {
+	var state any
+	if w.options.Hooks.BeforeWriteAll != nil {
+		state = w.options.Hooks.BeforeWriteAll()
+	}
+	defer func() {
+		if w.options.Hooks.AfterWriteAll != nil {
+			w.options.Hooks.AfterWriteAll(state, positions, err)
+		}
+		if err == nil {
+			for _, pos := range positions {
+				w.notifyWatchers(pos)
+			}
+		}
+	}()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return nil, ErrClosed
+	}
+	if w.options.ReadOnly {
+		return nil, ErrReadOnly
+	}
+
+	writes := w.pendingWrites
+	w.pendingWrites = nil
+	w.pendingSize = 0
+	w.options.MetricsCollector.SetPendingWritesQueueDepth(0)
+
+	positions, err = w.writeBatch(writes)
+	return positions, err
+}
+
+// writeBatch is WriteAll's write path: it compresses and encrypts every
+// entry in datas up front, then appends them to segments in runs that
+// share a single writev syscall per segment (see segment.WriteBatch)
+// instead of the two Write syscalls per chunk that w.write's header-then-
+// payload appendChunk makes, for every chunk that fits -- a run ends, and
+// a new one starts on whatever segment rotateIfNeeded rotates to, exactly
+// where a lone w.write call would have rotated.
+func (w *WAL) writeBatch(datas [][]byte) ([]*ChunkPosition, error) {
+	if w.syncErr != nil {
+		return nil, &ErrPoisoned{Cause: w.syncErr}
+	}
+	if w.fenced {
+		return nil, ErrFenced
+	}
+	if w.diskQuotaExceeded {
+		return nil, ErrDiskQuotaExceeded
+	}
+
+	prefixed := make([][]byte, len(datas))
+	logicalLens := make([]int, len(datas))
+	for i, data := range datas {
+		if len(data) == 0 {
+			return nil, ErrEmptyRecord
+		}
+		logicalLens[i] = len(data)
+		prefixed[i] = prefixTimestamp(w.options.Timestamps, data)
+	}
+
+	compressed, err := w.compressBatch(prefixed)
+	if err != nil {
+		return nil, err
+	}
+
+	payloads := make([][]byte, len(datas))
+	for i, data := range compressed {
+		payload, err := w.encryptChunk(data)
+		if err != nil {
+			return nil, err
+		}
+		payloads[i] = payload
+	}
+
+	positions := make([]*ChunkPosition, 0, len(payloads))
+	for i := 0; i < len(payloads); {
+		if err := w.rotateIfNeeded(int64(chunkHeaderSize) + int64(len(payloads[i]))); err != nil {
+			return positions, err
+		}
+
+		segSize := w.activeSegment.Size() + int64(chunkHeaderSize) + int64(len(payloads[i]))
+		j := i + 1
+		for j < len(payloads) {
+			chunkSize := int64(chunkHeaderSize) + int64(len(payloads[j]))
+			if segSize+chunkSize > w.options.SegmentSize {
+				break
+			}
+			segSize += chunkSize
+			j++
+		}
+		batch := payloads[i:j]
+
+		if w.options.Limiter != nil {
+			batchBytes := 0
+			for _, p := range batch {
+				batchBytes += chunkHeaderSize + len(p)
+			}
+			if err := w.options.Limiter.WaitN(batchBytes); err != nil {
+				return positions, err
+			}
+		}
+
+		segPositions, err := w.activeSegment.WriteBatch(batch)
+		if err != nil {
+			w.options.MetricsCollector.IncIOErrors()
+			return positions, w.handleIOErrorLocked(err, w.activeSegment.id, w.activeSegment.Size())
+		}
+		for k, pos := range segPositions {
+			pos.Sequence = w.nextSeq
+			w.nextSeq++
+			w.activeSegment.recordSequence(pos.Sequence, pos.ChunkOffset)
+			w.lastPos = pos
+			w.bytesWritten += uint64(len(batch[k]))
+			w.logicalBytesWritten += uint64(logicalLens[i+k])
+			w.chunksWritten++
+			w.options.MetricsCollector.AddBytesWritten(len(batch[k]))
+			w.options.MetricsCollector.IncChunksWritten()
+		}
+		w.options.MetricsCollector.SetSegmentSize(w.activeSegment.Size())
+		w.lastWriteNano.Store(w.options.Clock.Now().UnixNano())
+		positions = append(positions, segPositions...)
+		i = j
+	}
+	return positions, nil
+}
+
+// compressBatch compresses every entry in datas under Options.Compression,
+// in parallel across Options.CompressionParallelism goroutines when it's
+// set above 1 and there's more than one entry to split across them --
+// compressPayload builds a fresh, unshared encoder per call, so entries
+// compress independently of each other. Below that threshold, or with
+// Compression at CompressionNone (where compressPayload is a cheap
+// passthrough anyway), it compresses entries one at a time in the calling
+// goroutine, the same as before this option existed.
+func (w *WAL) compressBatch(datas [][]byte) ([][]byte, error) {
+	out := make([][]byte, len(datas))
+	if w.options.Compression == CompressionNone || w.options.CompressionParallelism < 2 || len(datas) < 2 {
+		for i, data := range datas {
+			payload, err := compressPayload(w.options.Compression, data, nil, w.options.CompressionDict)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = payload
+		}
+		return out, nil
+	}
+
+	errs := make([]error, len(datas))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, w.options.CompressionParallelism)
+	for i, data := range datas {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, data []byte) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			payload, err := compressPayload(w.options.Compression, data, nil, w.options.CompressionDict)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			out[i] = payload
+		}(i, data)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// readDecoded returns the chunk at pos, decrypted and decompressed but
+// still carrying its Options.Timestamps prefix, if any -- Read and
+// ReadWithTimestamp both build on this, splitting that prefix back off
+// differently.
+func (w *WAL) readDecoded(pos *ChunkPosition) (data []byte, err error) {
+	if w.blockCache != nil {
+		if cached, ok := w.blockCache.get(pos.SegmentId, pos.ChunkOffset); ok {
+			return cached, nil
+		}
+	}
+
+	// No w.mu here at all, by design: resolving pos.SegmentId only needs
+	// the latest published segmentsView, and acquire() is what actually
+	// keeps the segment pinned against a concurrent Checkpoint or
+	// Truncate retiring it -- see segmentsView's doc comment. Taking mu
+	// just for this lookup would make every Read wait out a concurrent
+	// Write's inline fsync for no reason, since nothing here touches the
+	// state that fsync protects.
+	seg := w.segmentByIDFast(pos.SegmentId)
+	acquired := seg != nil && seg.acquire()
+
+	if !acquired {
+		var ferr error
+		seg, ferr = w.fetchRemoteOrCompressedSegment(pos.SegmentId)
+		if ferr != nil {
+			return nil, ferr
+		}
+		if seg == nil || !seg.acquire() {
+			return nil, errSegmentNotFound(pos.SegmentId)
+		}
+	}
+	defer seg.release()
+
+	start := time.Now()
+	data, err = seg.Read(pos.ChunkOffset)
+	w.options.MetricsCollector.ObserveChunkReadLatency(time.Since(start))
+	if err != nil {
+		if errors.Is(err, ErrInvalidCRC) {
+			w.options.MetricsCollector.IncCRCErrors()
+		} else {
+			w.options.MetricsCollector.IncIOErrors()
+		}
+		return nil, err
+	}
+	data, err = w.decryptChunk(data)
+	if err != nil {
+		return nil, err
+	}
+	data, err = decompressPayload(w.options.Compression, data, w.options.CompressionDict)
+	if err != nil {
+		return nil, err
+	}
+	if w.blockCache != nil {
+		w.blockCache.put(pos.SegmentId, pos.ChunkOffset, data)
+	}
+	return data, nil
+}
+
+// Read returns the chunk at pos.
+func (w *WAL) Read(pos *ChunkPosition) (data []byte, err error) {
+	var state any
+	if w.options.Hooks.BeforeRead != nil {
+		state = w.options.Hooks.BeforeRead()
+	}
+	defer func() {
+		if w.options.Hooks.AfterRead != nil {
+			w.options.Hooks.AfterRead(state, data, err)
+		}
+	}()
+
+	decoded, err := w.readDecoded(pos)
+	if err != nil {
+		return nil, err
+	}
+	data, _ = stripTimestamp(w.options.Timestamps, decoded)
+	return data, nil
+}
+
+// ReadWithTimestamp is Read, but also returns the time the chunk at pos
+// was written, if the WAL was opened with WithTimestamps(true) -- see
+// Options.Timestamps's doc comment for the mismatched-options caveat it
+// shares with Compression and EncryptionKey. It returns a zero time.Time
+// if Options.Timestamps is false.
+func (w *WAL) ReadWithTimestamp(pos *ChunkPosition) (data []byte, writtenAt time.Time, err error) {
+	var state any
+	if w.options.Hooks.BeforeRead != nil {
+		state = w.options.Hooks.BeforeRead()
+	}
+	defer func() {
+		if w.options.Hooks.AfterRead != nil {
+			w.options.Hooks.AfterRead(state, data, err)
+		}
+	}()
+
+	decoded, err := w.readDecoded(pos)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	data, writtenAt = stripTimestamp(w.options.Timestamps, decoded)
+	return data, writtenAt, nil
+}
+
+// ReadWithFlags is Read, but also returns the flags byte WriteWithFlags
+// reserved for pos. Only call it for a position WriteWithFlags returned;
+// see WriteWithFlags's doc comment for why calling it for one Write
+// returned instead misreads data's own leading byte as flags.
+func (w *WAL) ReadWithFlags(pos *ChunkPosition) (data []byte, flags byte, err error) {
+	raw, err := w.Read(pos)
+	if err != nil {
+		return nil, 0, err
+	}
+	return raw[1:], raw[0], nil
+}
+
+// ReadWithTTL is Read, but also returns the expiry WriteWithTTL reserved
+// for pos, regardless of whether it has already passed -- see
+// ReadUnexpired to have that checked for you. Only call it for a position
+// WriteWithTTL returned; see WriteWithFlags's doc comment for why calling
+// it for one Write returned instead misreads data's own leading bytes as
+// an expiry.
+func (w *WAL) ReadWithTTL(pos *ChunkPosition) (data []byte, expiresAt time.Time, err error) {
+	raw, err := w.Read(pos)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return raw[8:], time.Unix(0, int64(binary.BigEndian.Uint64(raw[:8]))), nil
+}
+
+// ReadUnexpired is ReadWithTTL, but returns ErrRecordExpired alongside data
+// once expiresAt has passed, leaving it to the caller to decide whether an
+// expired record is still worth using rather than silently hiding it the
+// way Reader.NextUnexpired skips it.
+func (w *WAL) ReadUnexpired(pos *ChunkPosition) (data []byte, err error) {
+	data, expiresAt, err := w.ReadWithTTL(pos)
+	if err != nil {
+		return nil, err
+	}
+	if expiresAt.Before(time.Now()) {
+		return data, ErrRecordExpired
+	}
+	return data, nil
+}
+
+// ReadIdempotent is Read, but also returns the id WriteIdempotent reserved
+// for pos. Only call it for a position WriteIdempotent returned; see
+// WriteWithFlags's doc comment for why calling it for one Write returned
+// instead misreads data's own leading bytes as id and write time.
+func (w *WAL) ReadIdempotent(pos *ChunkPosition) (data []byte, id uint64, err error) {
+	raw, err := w.Read(pos)
+	if err != nil {
+		return nil, 0, err
+	}
+	data, id, _ = stripIdempotency(raw)
+	return data, id, nil
+}
+
+// ReadNth returns the chunk at ordinal n (0-based) within segmentID, i.e.
+// the nth chunk Write appended to that segment, regardless of how many
+// other segments came before or after it.
+//
+// With Options.ChunkIndex set, segmentID's sealed chunk-index sidecar (see
+// writeChunkIndex) makes this O(1); without it, or for the still-active
+// segment (never sealed, so never indexed), it falls back to scanning
+// segmentID's chunks from the start. It returns io.EOF if n is past the
+// last chunk segmentID actually holds.
+func (w *WAL) ReadNth(segmentID SegmentID, n int) ([]byte, error) {
+	if n < 0 {
+		return nil, fmt.Errorf("wal: invalid ReadNth index %d: must not be negative", n)
+	}
+	seg := w.segmentByIDFast(segmentID)
+	if seg == nil {
+		return nil, errSegmentNotFound(segmentID)
+	}
+
+	entry, baseSeq, _, ok, err := readNthChunkIndexEntry(chunkIndexFileName(seg.path), n)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	var pos *ChunkPosition
+	if ok {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		pos = &ChunkPosition{SegmentId: segmentID, ChunkOffset: entry.offset, ChunkSize: entry.size, Sequence: baseSeq + uint64(n)}
+	} else {
+		pos, err = readNthByScanning(seg, n)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	decoded, err := w.readDecoded(pos)
+	if err != nil {
+		return nil, err
+	}
+	data, _ := stripTimestamp(w.options.Timestamps, decoded)
+	return data, nil
+}
+
+// Sync flushes the active segment to stable storage and returns the
+// position of the most recent write now guaranteed durable -- nil if
+// nothing has been written yet. Pass it to WaitForSync from elsewhere to
+// confirm a write is durable without forcing another fsync.
+func (w *WAL) Sync() (pos *ChunkPosition, err error) {
+	var state any
+	if w.options.Hooks.BeforeSync != nil {
+		state = w.options.Hooks.BeforeSync()
+	}
+	defer func() {
+		if w.options.Hooks.AfterSync != nil {
+			w.options.Hooks.AfterSync(state, err)
+		}
+	}()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return nil, ErrClosed
+	}
+	if w.options.ReadOnly {
+		return nil, ErrReadOnly
+	}
+	if err := w.sync(); err != nil {
+		return nil, err
+	}
+	return w.durablePos, nil
+}
+
+func (w *WAL) sync() error {
+	start := time.Now()
+	if err := w.activeSegment.Sync(); err != nil {
+		w.options.MetricsCollector.IncIOErrors()
+		err = w.handleIOErrorLocked(err, w.activeSegment.id, w.activeSegment.Size())
+		w.poisonLocked(err)
+		return err
+	}
+	d := time.Since(start)
+	w.options.MetricsCollector.ObserveFsyncDuration(d)
+	w.fsyncCount++
+	w.recordFsyncDurationLocked(d)
+	w.reportSlowSyncLocked(d)
+	w.bytesSinceSync = 0
+	w.syncFileRangeOffset = w.activeSegment.Size()
+	w.syncFileRangeCount = 0
+	w.advanceDurableLocked(w.lastPos)
+	return nil
+}
+
+// syncFileRangeCheckpointInterval bounds how many consecutive
+// BytesPerSync crossings syncFileRangeLocked can serve with a
+// sync_file_range hint instead of a real fsync: sync_file_range starts
+// writeback but -- unlike fsync -- doesn't wait for it to finish, update
+// file metadata, or imply any ordering guarantee, so relying on it forever
+// would slowly widen the durability window BytesPerSync is meant to
+// bound. Every this many hints, syncFileRangeLocked forces a real fsync
+// instead to re-establish a durability checkpoint.
+const syncFileRangeCheckpointInterval = 16
+
+// syncFileRangeLocked implements Options.SyncFileRange: instead of a
+// blocking fsync every time BytesPerSync is crossed, it asks the kernel to
+// start writing back just the bytes appended since the last checkpoint
+// and returns immediately, smoothing out the latency spikes a blocking
+// fsync causes under sustained write bursts. See
+// syncFileRangeCheckpointInterval for how it stays bounded.
+func (w *WAL) syncFileRangeLocked() error {
+	w.syncFileRangeCount++
+	if w.syncFileRangeCount >= syncFileRangeCheckpointInterval {
+		return w.sync()
+	}
+
+	start := w.syncFileRangeOffset
+	end := w.activeSegment.Size()
+	if err := w.activeSegment.syncRange(start, end-start); err != nil {
+		w.options.MetricsCollector.IncIOErrors()
+		err = w.handleIOErrorLocked(err, w.activeSegment.id, end)
+		w.poisonLocked(err)
+		return err
+	}
+	w.syncFileRangeOffset = end
+	w.bytesSinceSync = 0
+	return nil
+}
+
+// Close waits for any outstanding WriteAsync calls to finish, performs a
+// final fsync, flushes and closes every segment file, and -- unless
+// ReadOnly -- records a clean-shutdown marker (see shutdownMarkerFileName)
+// before releasing DirPath's lock. Once Close returns, every subsequent
+// Write, WriteAll, PendingWrites, WriteContext, and Sync call fails fast
+// with ErrClosed instead of touching a closed segment file.
+func (w *WAL) Close() error {
+	// Stop new sends to the async writer and drain it, if one was ever
+	// started, before taking mu below: its goroutine calls Write, which
+	// takes mu itself, so waiting for it to exit while already holding mu
+	// would deadlock. asyncMu's write lock excludes any WriteAsync call
+	// concurrently sending on asyncJobs, so closing it here can never race
+	// with a send; see WriteAsync.
+	w.asyncMu.Lock()
+	w.asyncClosed = true
+	asyncJobs, asyncDone := w.asyncJobs, w.asyncDone
+	w.asyncMu.Unlock()
+	if asyncJobs != nil {
+		close(asyncJobs)
+		<-asyncDone
+	}
+
+	// Same reasoning as asyncJobs above, for mirrorQueue: mirrorMu's write
+	// lock excludes any in-flight mirrorWrite call from sending on
+	// mirrorQueue after (or while) it's closed here.
+	w.mirrorMu.Lock()
+	w.mirrorClosed = true
+	w.mirrorMu.Unlock()
+	if w.mirrorQueue != nil {
+		close(w.mirrorQueue)
+		w.mirrorWG.Wait()
+	}
+
+	// Same reasoning again for autoCompactDone: runAutoCompaction's
+	// triggered Compact call takes mu itself, so it must be stopped and
+	// waited out before mu is taken below, or Close would deadlock against
+	// its own goroutine.
+	if w.autoCompactDone != nil {
+		close(w.autoCompactDone)
+		w.autoCompactWG.Wait()
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	if w.flusherDone != nil {
+		close(w.flusherDone)
+	}
+	if w.writeQueueDone != nil {
+		close(w.writeQueueDone)
+	}
+	if w.rotationTimerDone != nil {
+		close(w.rotationTimerDone)
+	}
+	if w.fencingTimerDone != nil {
+		close(w.fencingTimerDone)
+	}
+	if err := w.drainWriteQueueLocked(); err != nil {
+		return err
+	}
+	if err := w.drainWriteLingerLocked(); err != nil {
+		return err
+	}
+	if w.pipeline != nil {
+		if err := w.pipeline.Close(); err != nil {
+			return err
+		}
+	}
+
+	if err := w.activeSegment.Sync(); err != nil {
+		return err
+	}
+
+	manifest := shutdownManifest{
+		activeSegmentID: w.activeSegment.id,
+		offset:          w.activeSegment.Size(),
+		chunkCount:      w.activeSegment.chunkCountSnapshot(),
+		nextSeq:         w.nextSeq,
+		lastPos:         w.lastPos,
+	}
+
+	if err := w.activeSegment.Close(); err != nil {
+		return err
+	}
+	for _, seg := range w.olderSegments {
+		if err := seg.Close(); err != nil {
+			return err
+		}
+	}
+
+	if !w.options.ReadOnly {
+		if err := writeShutdownMarker(w.options.FS, w.options.DirPath, manifest); err != nil {
+			return err
+		}
+	}
+
+	if w.dirLock != nil {
+		if err := w.dirLock.Close(); err != nil {
+			return err
+		}
+	}
+
+	if w.mirror != nil {
+		if err := w.mirror.Close(); err != nil {
+			return err
+		}
+	}
+	if w.tieringCache != nil {
+		w.tieringCache.closeAll()
+	}
+	if w.compressedSegmentCache != nil {
+		w.compressedSegmentCache.closeAll()
+	}
+	return nil
+}
+
+// rotateIfNeeded seals the active segment and opens the next one if
+// appending nextChunkSize bytes to the active segment would exceed
+// Options.SegmentSize.
+func (w *WAL) rotateIfNeeded(nextChunkSize int64) error {
+	if w.activeSegment.Size()+nextChunkSize <= w.options.SegmentSize {
+		return nil
+	}
+	return w.rotateLocked()
+}
+
+// Rotate force-seals the active segment and opens a new one, returning
+// the new segment's ID, regardless of how much of Options.SegmentSize it
+// has used -- the explicit cut point rotateIfNeeded only ever makes on
+// its own once a chunk is about to overflow it. Operational workflows
+// that care about segment boundaries themselves -- cutting right before
+// a backup, aligning segments to the hour, exercising retention in a
+// test -- call this instead of writing padding to force rotateIfNeeded's
+// hand.
+//
+// Under Options.Pipelined, Rotate drains whatever is queued first, the
+// same way a threshold-triggered rotation does, so nothing queued against
+// the sealed segment's reserved offsets is lost.
+func (w *WAL) Rotate() (SegmentID, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, ErrClosed
+	}
+	if w.options.ReadOnly {
+		return 0, ErrReadOnly
+	}
+	if w.options.Pipelined {
+		if err := w.drainWriteQueueLocked(); err != nil {
+			return 0, err
+		}
+	}
+	if err := w.rotateLocked(); err != nil {
+		return 0, err
+	}
+	return w.activeSegment.id, nil
 }
 
-// Open opens the WAL in options.DirPath, creating the directory and a
-// first segment if neither exists yet.
-func Open(opts ...Option) (*WAL, error) {
-	options := DefaultOptions
-	for _, opt := range opts {
-		opt(&options)
+// Purge atomically discards every segment -- the active one and every
+// sealed one -- and resets w to an empty log with a fresh first segment,
+// numbered Options.StartSegmentID, without closing w. Any ChunkPosition
+// recorded before the call is invalid afterward; reading one back is a
+// caller bug, not something Purge detects.
+//
+// Unlike Delete, which only marks a single record as gone until the next
+// Checkpoint compacts it away, Purge removes every segment file from disk
+// immediately -- but still waits out any Read or Reader.Next already in
+// flight against them, the same way Checkpoint's sealed-segment cleanup
+// does, so a concurrent reader never sees a closed fd.
+//
+// Purge runs under checkpointMu as well as mu, the same ordering Compact
+// and Checkpoint use, since it mutates the same segment bookkeeping they
+// do.
+func (w *WAL) Purge() error {
+	w.checkpointMu.Lock()
+	defer w.checkpointMu.Unlock()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return ErrClosed
+	}
+	if w.options.ReadOnly {
+		return ErrReadOnly
+	}
+	if w.options.Pipelined {
+		if err := w.drainWriteQueueLocked(); err != nil {
+			return err
+		}
 	}
 
-	if err := os.MkdirAll(options.DirPath, 0o755); err != nil {
-		return nil, err
+	pipeline := w.recyclePipeline()
+	for id, seg := range w.olderSegments {
+		seg.retire(pipeline)
+		delete(w.olderSegments, id)
 	}
+	w.activeSegment.retire(pipeline)
 
-	ids, _, err := segmentPaths(options.DirPath, options.SegmentFileExt)
+	next, err := w.nextSegment(w.options.StartSegmentID)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	w.activeSegment = next
+	w.bytesSinceSync = 0
+	w.syncFileRangeOffset = 0
+	w.syncFileRangeCount = 0
 
-	w := &WAL{
-		options:       options,
-		olderSegments: make(map[SegmentID]*segment),
+	w.options.MetricsCollector.SetActiveSegments(1)
+	w.options.MetricsCollector.SetSegmentSize(w.activeSegment.Size())
+	w.options.Logger.Info("wal: purged", "active", w.activeSegment.id)
+
+	w.publishSegmentsLocked()
+	return nil
+}
+
+// rotateLocked is rotateIfNeeded's and Rotate's shared tail: seal the
+// active segment unconditionally and open the next one. w.mu must
+// already be held.
+func (w *WAL) rotateLocked() error {
+	if err := w.activeSegment.Sync(); err != nil {
+		w.poisonLocked(err)
+		return err
+	}
+	sealed := w.activeSegment
+	sealedID := sealed.id
+	if err := sealed.seal(); err != nil {
+		return err
 	}
+	w.olderSegments[sealedID] = sealed
 
-	if len(ids) == 0 {
-		seg, err := openSegmentFile(options.DirPath, options.SegmentFileExt, 1)
-		if err != nil {
-			return nil, err
+	if w.options.MmapRead {
+		if err := sealed.enableMmapRead(w.options.Madvise); err != nil {
+			return err
 		}
-		w.activeSegment = seg
-	} else {
-		for _, id := range ids[:len(ids)-1] {
-			seg, err := openSegmentFile(options.DirPath, options.SegmentFileExt, id)
-			if err != nil {
-				return nil, err
-			}
-			w.olderSegments[id] = seg
+	}
+	if w.options.FadviseDontNeed {
+		if err := sealed.dropPageCache(); err != nil {
+			return err
 		}
+	}
 
-		activeID := ids[len(ids)-1]
-		seg, err := openSegmentFile(options.DirPath, options.SegmentFileExt, activeID)
-		if err != nil {
-			return nil, err
-		}
-		w.activeSegment = seg
+	if w.options.OnSegmentSealed != nil {
+		w.options.OnSegmentSealed(sealed.path, sealedID)
 	}
 
-	return w, nil
-}
+	nextID := sealedID + 1
+	next, err := w.nextSegment(nextID)
+	if err != nil {
+		return err
+	}
+	w.activeSegment = next
+	w.bytesSinceSync = 0
+	w.syncFileRangeOffset = 0
+	w.syncFileRangeCount = 0
 
-// Write appends data to the WAL as a single chunk and returns its
-// position. Depending on Options.Sync and Options.BytesPerSync, it may
-// fsync the active segment before returning.
-func (w *WAL) Write(data []byte) (*ChunkPosition, error) {
-	w.mu.Lock()
-	defer w.mu.Unlock()
-	return w.write(data)
-}
+	w.options.MetricsCollector.IncSegmentRotations()
+	w.options.MetricsCollector.SetActiveSegments(len(w.olderSegments) + 1)
+	w.options.MetricsCollector.SetSegmentSize(w.activeSegment.Size())
 
-func (w *WAL) write(data []byte) (*ChunkPosition, error) {
-	if err := w.rotateIfNeeded(int64(chunkHeaderSize) + int64(len(data))); err != nil {
-		return nil, err
+	if w.options.OnRotate != nil {
+		w.options.OnRotate(sealedID, nextID)
 	}
 
-	pos, err := w.activeSegment.Write(data)
-	if err != nil {
-		return nil, err
+	w.options.Logger.Info("wal: segment rotated", "sealed", sealedID, "active", nextID)
+
+	ageErr := w.enforceMaxSegmentAgeLocked()
+	sizeErr := w.enforceMaxTotalSizeLocked()
+	ttlErr := w.enforceExpiredSegmentsLocked()
+	w.enforceDiskWatermarksLocked()
+	w.publishSegmentsLocked()
+	if ageErr != nil {
+		return ageErr
+	}
+	if sizeErr != nil {
+		return sizeErr
 	}
+	return ttlErr
+}
 
-	w.bytesSinceSync += uint32(chunkHeaderSize + len(data))
-	needSync := w.options.Sync || (w.options.BytesPerSync > 0 && w.bytesSinceSync >= w.options.BytesPerSync)
-	if needSync {
-		if err := w.sync(); err != nil {
-			return nil, err
-		}
+// startSegmentRotationTimer runs in its own goroutine for the lifetime of
+// a WAL opened with Options.SegmentRotationInterval, waking up at a
+// fraction of the interval to check whether the active segment has been
+// active long enough to force-rotate -- the same polling-with-a-finer-
+// grained-ticker approach Options.Pipelined's backpressure loop uses,
+// since a single ticker fixed to the interval itself would only catch a
+// segment that became active at the exact moment Open started this timer.
+// It exits once the WAL is closed.
+func (w *WAL) startSegmentRotationTimer(interval time.Duration) {
+	w.rotationTimerDone = make(chan struct{})
+	tick := interval / 10
+	if tick <= 0 {
+		tick = interval
 	}
-	return pos, nil
+	go func() {
+		ticker := w.options.Clock.NewTicker(tick)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C():
+				w.rotateIfSegmentTooOld(interval)
+			case <-w.rotationTimerDone:
+				return
+			}
+		}
+	}()
 }
 
-// PendingWrites buffers data to be written by the next call to WriteAll,
-// without touching the segment files yet.
-func (w *WAL) PendingWrites(data []byte) {
+// rotateIfSegmentTooOld force-rotates the active segment if it has been
+// active at least interval. It swallows rotateLocked's error the same way
+// flushWriteQueue swallows drainWriteQueueLocked's: the failure already
+// poisoned the WAL for the next Write to report, and there's no caller
+// here to return it to.
+func (w *WAL) rotateIfSegmentTooOld(interval time.Duration) {
 	w.mu.Lock()
 	defer w.mu.Unlock()
-	w.pendingWrites = append(w.pendingWrites, data)
-	w.pendingSize += int64(len(data))
+	if w.closed {
+		return
+	}
+	if w.options.Clock.Now().Sub(w.activeSegment.createdAt) < interval {
+		return
+	}
+	if w.options.Pipelined {
+		if err := w.drainWriteQueueLocked(); err != nil {
+			return
+		}
+	}
+	_ = w.rotateLocked()
 }
 
-// WriteAll flushes every write buffered by PendingWrites, in the order
-// they were added, and returns their positions.
-func (w *WAL) WriteAll() ([]*ChunkPosition, error) {
-	w.mu.Lock()
-	defer w.mu.Unlock()
+// PinnedSegmentPolicy controls what a retention pass does when the sealed
+// segment it wants to remove is pinned -- see Options.PinnedSegmentPolicy.
+type PinnedSegmentPolicy byte
 
-	writes := w.pendingWrites
-	w.pendingWrites = nil
-	w.pendingSize = 0
+const (
+	// PinnedSegmentSkip is the default: a pinned segment is left alone for
+	// this retention pass and reconsidered on the next one, once whatever
+	// held it has moved on.
+	PinnedSegmentSkip PinnedSegmentPolicy = iota
 
-	positions := make([]*ChunkPosition, 0, len(writes))
-	for _, data := range writes {
-		pos, err := w.write(data)
-		if err != nil {
-			return positions, err
+	// PinnedSegmentBlock waits for a pinned segment to become unpinned
+	// before removing it, holding w.mu for the duration -- so no Write,
+	// Read, or Rotate on this WAL makes progress until whatever is
+	// pinning the segment releases it. Only appropriate when retention
+	// must keep strictly to its schedule and a momentarily stalled WAL is
+	// an acceptable price for that.
+	PinnedSegmentBlock
+
+	// PinnedSegmentFail aborts the retention pass with ErrSegmentPinned
+	// as soon as it finds a pinned segment, leaving that segment and
+	// every candidate after it in place. The error surfaces from whatever
+	// triggered the pass -- Write (via rotateIfNeeded) or Rotate -- the
+	// same way a failed fsync or segment-open does.
+	PinnedSegmentFail
+)
+
+// ErrSegmentPinned is returned by a retention pass under
+// PinnedSegmentFail when the sealed segment it wants to remove has a
+// WAL.Read or Reader.Next call positioned in it.
+var ErrSegmentPinned = fmt.Errorf("wal: refusing to remove a segment a Reader is still positioned in")
+
+// resolvePinnedSegmentLocked decides, per Options.PinnedSegmentPolicy,
+// whether seg -- already chosen as a retention candidate -- should be
+// retired now. It reports false with a nil error under PinnedSegmentSkip
+// when seg is pinned, meaning the caller should leave it alone this pass;
+// ErrSegmentPinned under PinnedSegmentFail; or true once seg is confirmed
+// unpinned, blocking under PinnedSegmentBlock until it is. w.mu must be
+// held throughout, including while PinnedSegmentBlock waits.
+func (w *WAL) resolvePinnedSegmentLocked(seg *segment) (bool, error) {
+	if !seg.pinned() {
+		return true, nil
+	}
+	switch w.options.PinnedSegmentPolicy {
+	case PinnedSegmentBlock:
+		for seg.pinned() {
+			time.Sleep(time.Millisecond)
 		}
-		positions = append(positions, pos)
+		return true, nil
+	case PinnedSegmentFail:
+		return false, ErrSegmentPinned
+	default: // PinnedSegmentSkip
+		return false, nil
 	}
-	return positions, nil
 }
 
-// Read returns the chunk at pos.
-func (w *WAL) Read(pos *ChunkPosition) ([]byte, error) {
-	w.mu.RLock()
-	seg := w.segmentByID(pos.SegmentId)
-	w.mu.RUnlock()
-
-	if seg == nil {
-		return nil, fmt.Errorf("wal: segment %d not found", pos.SegmentId)
+// enforceMaxSegmentAgeLocked retires every sealed segment older than
+// Options.MaxSegmentAge. w.mu must be held; it is a no-op if MaxSegmentAge
+// is disabled (<= 0).
+func (w *WAL) enforceMaxSegmentAgeLocked() error {
+	if w.options.MaxSegmentAge <= 0 {
+		return nil
 	}
-	return seg.Read(pos.ChunkOffset)
-}
 
-// Sync flushes the active segment to stable storage.
-func (w *WAL) Sync() error {
-	w.mu.Lock()
-	defer w.mu.Unlock()
-	return w.sync()
-}
+	cutoff := w.options.Clock.Now().Add(-w.options.MaxSegmentAge)
+	var expired []*segment
+	for id, seg := range w.olderSegments {
+		if !seg.createdAt.Before(cutoff) {
+			continue
+		}
+		retire, err := w.resolvePinnedSegmentLocked(seg)
+		if err != nil {
+			return err
+		}
+		if !retire {
+			continue
+		}
+		expired = append(expired, seg)
+		delete(w.olderSegments, id)
+	}
+	if len(expired) == 0 {
+		return nil
+	}
 
-func (w *WAL) sync() error {
-	if err := w.activeSegment.Sync(); err != nil {
-		return err
+	w.options.MetricsCollector.SetActiveSegments(len(w.olderSegments) + 1)
+	w.options.Logger.Info("wal: retired segments past MaxSegmentAge", "count", len(expired))
+	for _, seg := range expired {
+		seg.retire(w.recyclePipeline())
 	}
-	w.bytesSinceSync = 0
 	return nil
 }
 
-// Close flushes and closes every segment file.
-func (w *WAL) Close() error {
-	w.mu.Lock()
-	defer w.mu.Unlock()
-
-	if w.closed {
+// enforceMaxTotalSizeLocked retires the oldest sealed segments, never the
+// active one, until the combined size of every segment file is at or below
+// Options.MaxTotalSize. w.mu must be held; it is a no-op if MaxTotalSize is
+// disabled (<= 0).
+func (w *WAL) enforceMaxTotalSizeLocked() error {
+	if w.options.MaxTotalSize <= 0 {
 		return nil
 	}
-	w.closed = true
 
-	if err := w.activeSegment.Sync(); err != nil {
-		return err
+	total := w.activeSegment.Size()
+	for _, seg := range w.olderSegments {
+		total += seg.Size()
 	}
-	if err := w.activeSegment.Close(); err != nil {
-		return err
+	if total <= w.options.MaxTotalSize {
+		return nil
 	}
-	for _, seg := range w.olderSegments {
-		if err := seg.Close(); err != nil {
+
+	var expired []*segment
+	for _, seg := range w.orderedSegmentsLocked() {
+		if total <= w.options.MaxTotalSize || seg.id == w.activeSegment.id {
+			break
+		}
+		retire, err := w.resolvePinnedSegmentLocked(seg)
+		if err != nil {
 			return err
 		}
+		if !retire {
+			continue
+		}
+		expired = append(expired, seg)
+		total -= seg.Size()
+		delete(w.olderSegments, seg.id)
+	}
+	if len(expired) == 0 {
+		return nil
+	}
+
+	w.options.MetricsCollector.SetActiveSegments(len(w.olderSegments) + 1)
+	w.options.Logger.Info("wal: retired segments past MaxTotalSize", "count", len(expired))
+	for _, seg := range expired {
+		seg.retire(w.recyclePipeline())
 	}
 	return nil
 }
 
-// rotateIfNeeded seals the active segment and opens the next one if
-// appending nextChunkSize bytes to the active segment would exceed
-// Options.SegmentSize.
-func (w *WAL) rotateIfNeeded(nextChunkSize int64) error {
-	if w.activeSegment.Size()+nextChunkSize <= w.options.SegmentSize {
+// enforceDiskWatermarksLocked computes the same total on-disk size
+// enforceMaxTotalSizeLocked does, calls Options.OnDiskWatermark if it's at
+// or above Options.DiskSoftWatermark, and then either evicts oldest
+// segments (Options.EvictOldestOnFull) or updates diskQuotaExceeded (the
+// default) to reflect whether total is at or above
+// Options.DiskHardWatermark -- write and writeBatch check that flag
+// instead of recomputing this sum on every call. It's called from
+// rotateLocked, and also from Checkpoint and Compact so that reclaiming
+// space through either of those clears diskQuotaExceeded again without
+// waiting on a rotation that write and writeBatch would themselves be
+// refusing to reach. w.mu must be held; it is a no-op if both watermarks
+// are disabled (<= 0).
+func (w *WAL) enforceDiskWatermarksLocked() {
+	if w.options.DiskSoftWatermark <= 0 && w.options.DiskHardWatermark <= 0 {
+		return
+	}
+
+	total := w.activeSegment.Size()
+	for _, seg := range w.olderSegments {
+		total += seg.Size()
+	}
+
+	if w.options.DiskSoftWatermark > 0 && total >= w.options.DiskSoftWatermark && w.options.OnDiskWatermark != nil {
+		w.options.OnDiskWatermark(total)
+	}
+
+	overHardWatermark := w.options.DiskHardWatermark > 0 && total >= w.options.DiskHardWatermark
+	if overHardWatermark && w.options.EvictOldestOnFull {
+		total = w.evictOldestSegmentsLocked(total)
+		overHardWatermark = total >= w.options.DiskHardWatermark
+	}
+	w.diskQuotaExceeded = overHardWatermark
+}
+
+// evictOldestSegmentsLocked retires sealed segments, oldest first -- the
+// same ordering and PinnedSegmentPolicy handling as
+// enforceMaxTotalSizeLocked -- until total drops back below
+// Options.DiskHardWatermark, calling Options.OnSegmentEvicted once per
+// segment retired this way. It returns the resulting total, which may
+// still be at or above DiskHardWatermark if every remaining sealed
+// segment is pinned or the active segment alone exceeds it. w.mu must be
+// held.
+func (w *WAL) evictOldestSegmentsLocked(total int64) int64 {
+	var evicted []*segment
+	for _, seg := range w.orderedSegmentsLocked() {
+		if total < w.options.DiskHardWatermark || seg.id == w.activeSegment.id {
+			break
+		}
+		retire, err := w.resolvePinnedSegmentLocked(seg)
+		if err != nil || !retire {
+			continue
+		}
+		evicted = append(evicted, seg)
+		total -= seg.Size()
+		delete(w.olderSegments, seg.id)
+	}
+	if len(evicted) == 0 {
+		return total
+	}
+
+	w.options.MetricsCollector.SetActiveSegments(len(w.olderSegments) + 1)
+	w.options.Logger.Info("wal: evicted oldest segments past DiskHardWatermark", "count", len(evicted))
+	for _, seg := range evicted {
+		seg.retire(w.recyclePipeline())
+		if w.options.OnSegmentEvicted != nil {
+			w.options.OnSegmentEvicted(seg.id)
+		}
+	}
+	return total
+}
+
+// enforceExpiredSegmentsLocked retires every sealed segment all of whose
+// chunks were written via WriteWithTTL and have since expired. w.mu must be
+// held; it is a no-op if Options.DropExpiredSegments is disabled.
+func (w *WAL) enforceExpiredSegmentsLocked() error {
+	if !w.options.DropExpiredSegments {
 		return nil
 	}
 
-	if err := w.activeSegment.Sync(); err != nil {
-		return err
+	now := w.options.Clock.Now()
+	var expired []*segment
+	for id, seg := range w.olderSegments {
+		if !seg.allExpired(now) {
+			continue
+		}
+		retire, err := w.resolvePinnedSegmentLocked(seg)
+		if err != nil {
+			return err
+		}
+		if !retire {
+			continue
+		}
+		expired = append(expired, seg)
+		delete(w.olderSegments, id)
+	}
+	if len(expired) == 0 {
+		return nil
 	}
-	sealedID := w.activeSegment.id
-	w.olderSegments[sealedID] = w.activeSegment
 
-	nextID := sealedID + 1
-	next, err := openSegmentFile(w.options.DirPath, w.options.SegmentFileExt, nextID)
-	if err != nil {
-		return err
+	w.options.MetricsCollector.SetActiveSegments(len(w.olderSegments) + 1)
+	w.options.Logger.Info("wal: retired segments with all chunks expired", "count", len(expired))
+	for _, seg := range expired {
+		seg.retire(w.recyclePipeline())
 	}
-	w.activeSegment = next
-	w.bytesSinceSync = 0
 	return nil
 }
 
+// nextSegment returns a fresh segment file with the given id, taken from
+// the file pipeline if one is configured, or created on the hot path
+// otherwise.
+func (w *WAL) nextSegment(id SegmentID) (*segment, error) {
+	if w.pipeline == nil {
+		return openSegmentFile(w.options.FS, w.options.DirPath, w.options.SegmentFileExt, id, w.options.Mode, false, false, w.options.DirectIO, w.options.Fdatasync, w.options.Preallocate, w.options.Checksum, !w.options.SkipReadVerification, w.options.ChainChecksums, w.options.Compression, w.dictID, w.options.BlockSize, w.options.ChunkIndex, w.options.SegmentSigningKey, w.options.SegmentSize, nil, w.options.Clock, w.options.SegmentFileNameFunc, w.options.SegmentWriteBufferSize)
+	}
+	f, err := w.pipeline.Get()
+	if err != nil {
+		return nil, err
+	}
+	return newSegmentFile(f, w.options.DirPath, w.options.SegmentFileExt, id, w.options.Mode, w.options.Fdatasync, w.options.Checksum, !w.options.SkipReadVerification, w.options.ChainChecksums, w.options.Compression, w.dictID, w.options.BlockSize, w.options.ChunkIndex, w.options.SegmentSigningKey, w.options.Clock, w.options.SegmentFileNameFunc, w.options.SegmentWriteBufferSize)
+}
+
+// recyclePipeline returns the file pipeline a retired segment's file
+// should be handed to for reuse, for Options.RecycleSegments, or nil if
+// recycling is disabled or there is no pipeline to hand it to.
+func (w *WAL) recyclePipeline() *filePipeline {
+	if !w.options.RecycleSegments {
+		return nil
+	}
+	return w.pipeline
+}
+
 func (w *WAL) segmentByID(id SegmentID) *segment {
 	if w.activeSegment.id == id {
 		return w.activeSegment
@@ -215,6 +2212,46 @@ func (w *WAL) segmentByID(id SegmentID) *segment {
 	return w.olderSegments[id]
 }
 
+// segmentsSnapshot is an immutable copy of activeSegment and olderSegments
+// at some point in time; see WAL.segmentsView.
+type segmentsSnapshot struct {
+	active *segment
+	older  map[SegmentID]*segment
+}
+
+// publishSegmentsLocked copies activeSegment and olderSegments into a
+// fresh segmentsSnapshot and atomically swaps it into segmentsView. Call
+// it, with mu's write lock held, after any change to either field.
+func (w *WAL) publishSegmentsLocked() {
+	older := make(map[SegmentID]*segment, len(w.olderSegments))
+	for id, seg := range w.olderSegments {
+		older[id] = seg
+	}
+	w.segmentsView.Store(&segmentsSnapshot{active: w.activeSegment, older: older})
+}
+
+// segmentByIDFast is segmentByID, but reads from the latest snapshot
+// publishSegmentsLocked stored instead of the live fields, so it can be
+// called without holding mu at all. See WAL.segmentsView.
+func (w *WAL) segmentByIDFast(id SegmentID) *segment {
+	view := w.segmentsView.Load()
+	if view == nil {
+		return nil
+	}
+	if view.active != nil && view.active.id == id {
+		return view.active
+	}
+	return view.older[id]
+}
+
+// orderedSegments returns every segment the WAL knows about, oldest to
+// newest, including the active one.
+func (w *WAL) orderedSegments() []*segment {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.orderedSegmentsLocked()
+}
+
 // orderedSegmentsLocked returns every segment the WAL knows about, oldest
 // to newest, including the active one. w.mu must be held.
 func (w *WAL) orderedSegmentsLocked() []*segment {
@@ -227,10 +2264,107 @@ func (w *WAL) orderedSegmentsLocked() []*segment {
 	return segs
 }
 
-// segmentPaths returns the IDs and paths of every segment file in dir with
-// the given extension, sorted ascending by ID.
-func segmentPaths(dir, ext string) ([]SegmentID, []string, error) {
-	entries, err := os.ReadDir(dir)
+// segmentsAfter returns every segment with an ID greater than id, oldest
+// to newest, including the active segment if it qualifies. It is used by
+// Reader to pick up newly rotated segments while tailing.
+func (w *WAL) segmentsAfter(id SegmentID) []*segment {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	var segs []*segment
+	for _, seg := range w.olderSegments {
+		if seg.id > id {
+			segs = append(segs, seg)
+		}
+	}
+	if w.activeSegment.id > id {
+		segs = append(segs, w.activeSegment)
+	}
+	sort.Slice(segs, func(i, j int) bool { return segs[i].id < segs[j].id })
+	return segs
+}
+
+// ErrNotReadOnly is returned by RefreshSegments when called on a WAL not
+// opened with WithReadOnly: a writer already learns about its own
+// rotations as it makes them (see rotateIfNeeded) and has no need to
+// rescan the directory for segments some other process might have
+// created.
+var ErrNotReadOnly = fmt.Errorf("wal: RefreshSegments only applies to a WAL opened with WithReadOnly")
+
+// RefreshSegments rescans DirPath for segment files a writer in another
+// process has created or rotated in since w was opened, or since the last
+// RefreshSegments call, and folds any it finds into w's own segment list --
+// the read-only counterpart to how rotateIfNeeded updates a writer's own
+// view of its segments.
+//
+// Reader (and Watcher and TailReader, both built on it) already call this
+// for you once they run out of segments they already knew about, so a
+// read-only WAL tailing a directory another process is actively writing
+// to picks up new segments on its own; call it directly only if you're
+// driving reads some other way. It returns ErrNotReadOnly on a WAL that
+// isn't read-only, since such a WAL's own writes are the only source of
+// new segments it needs to know about.
+func (w *WAL) RefreshSegments() error {
+	if !w.options.ReadOnly {
+		return ErrNotReadOnly
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.refreshSegmentsLocked()
+}
+
+// refreshSegmentsLocked is RefreshSegments without the ReadOnly check, for
+// Reader.advanceToNextSegment to call directly. w.mu must be held.
+func (w *WAL) refreshSegmentsLocked() error {
+	ids, _, err := segmentPaths(w.options.FS, w.options.DirPath, w.options.SegmentFileExt, w.options.SegmentFileNameParseFunc)
+	if err != nil {
+		return err
+	}
+
+	currentActiveID := w.activeSegment.id
+	var newIDs []SegmentID
+	for _, id := range ids {
+		if id > currentActiveID {
+			newIDs = append(newIDs, id)
+		}
+	}
+	if len(newIDs) == 0 {
+		return nil
+	}
+
+	// The writer has sealed what used to be our active segment and moved
+	// on to at least one new one: demote it into olderSegments (no need to
+	// reopen it -- its fd still reads the chunks it had when we opened it
+	// just fine) and open every newly-appeared ID the same way Open treats
+	// segments it finds at startup -- sealed (verifyTail false) except for
+	// the new highest, which may still be mid-write.
+	w.olderSegments[currentActiveID] = w.activeSegment
+	for _, id := range newIDs[:len(newIDs)-1] {
+		seg, err := openSegmentFile(w.options.FS, w.options.DirPath, w.options.SegmentFileExt, id, w.options.Mode, false, true, w.options.DirectIO, w.options.Fdatasync, w.options.Preallocate, w.options.Checksum, !w.options.SkipReadVerification, w.options.ChainChecksums, w.options.Compression, w.dictID, w.options.BlockSize, w.options.ChunkIndex, w.options.SegmentSigningKey, w.options.SegmentSize, nil, w.options.Clock, w.options.SegmentFileNameFunc, w.options.SegmentWriteBufferSize)
+		if err != nil {
+			return err
+		}
+		w.olderSegments[id] = seg
+	}
+
+	activeID := newIDs[len(newIDs)-1]
+	seg, err := openSegmentFile(w.options.FS, w.options.DirPath, w.options.SegmentFileExt, activeID, w.options.Mode, true, true, w.options.DirectIO, w.options.Fdatasync, w.options.Preallocate, w.options.Checksum, !w.options.SkipReadVerification, w.options.ChainChecksums, w.options.Compression, w.dictID, w.options.BlockSize, w.options.ChunkIndex, w.options.SegmentSigningKey, w.options.SegmentSize, nil, w.options.Clock, w.options.SegmentFileNameFunc, w.options.SegmentWriteBufferSize)
+	if err != nil {
+		return err
+	}
+	w.activeSegment = seg
+
+	w.options.MetricsCollector.SetActiveSegments(len(w.olderSegments) + 1)
+	w.publishSegmentsLocked()
+	return nil
+}
+
+// segmentPaths returns the IDs and paths of every segment file in dir,
+// sorted ascending by ID. parseFunc, if non-nil, is Options.SegmentFileNameParseFunc;
+// otherwise an entry is a segment file if it ends in ext and the rest of
+// its name parses as the built-in zero-padded-number scheme.
+func segmentPaths(fs FS, dir, ext string, parseFunc func(string) (SegmentID, bool)) ([]SegmentID, []string, error) {
+	entries, err := fs.ReadDir(dir)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -241,12 +2375,22 @@ func segmentPaths(dir, ext string) ([]SegmentID, []string, error) {
 	}
 	var segs []seg
 	for _, e := range entries {
-		if e.IsDir() || filepath.Ext(e.Name()) != ext {
+		if e.IsDir() {
 			continue
 		}
 		var id SegmentID
-		if _, err := fmt.Sscanf(e.Name(), "%d"+ext, &id); err != nil {
-			continue
+		if parseFunc != nil {
+			var ok bool
+			if id, ok = parseFunc(e.Name()); !ok {
+				continue
+			}
+		} else {
+			if filepath.Ext(e.Name()) != ext {
+				continue
+			}
+			if _, err := fmt.Sscanf(e.Name(), "%d"+ext, &id); err != nil {
+				continue
+			}
 		}
 		segs = append(segs, seg{id, filepath.Join(dir, e.Name())})
 	}
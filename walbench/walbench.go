@@ -0,0 +1,203 @@
+// Package walbench is a reusable load-generation and measurement harness
+// for wal: it writes and reads records against a real WAL for a fixed
+// duration, with knobs for record size distribution, concurrency, sync
+// mode, and read/write mix, and reports throughput and latency
+// percentiles. It complements, rather than replaces, the go test -bench
+// microbenchmarks under benchmark/: those compare specific code paths
+// (e.g. segment rotation with and without preallocation) the way Go's own
+// tooling expects, while walbench answers the different question a
+// capacity-planning operator asks -- "what throughput and tail latency
+// does this WAL give me under this workload shape on this hardware" --
+// as a duration-based run with its own percentile report, not a
+// testing.B loop.
+package walbench
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rosedblabs/wal"
+)
+
+// Config configures a Run.
+type Config struct {
+	// Dir is the WAL directory to benchmark against. It's created if it
+	// doesn't exist; Run does not remove it afterward.
+	Dir string
+
+	// SegmentSize is the WAL's segment size. Zero uses wal.DefaultOptions'.
+	SegmentSize int64
+
+	// Sync is passed straight through as wal.WithSync.
+	Sync bool
+
+	// Concurrency is the number of goroutines issuing operations
+	// concurrently. Must be at least 1.
+	Concurrency int
+
+	// Duration is how long the timed run lasts, not counting Warmup.
+	Duration time.Duration
+
+	// MinRecordSize and MaxRecordSize bound a uniform distribution each
+	// write's payload size is drawn from. Equal values write a fixed size.
+	MinRecordSize int
+	MaxRecordSize int
+
+	// ReadFraction is the probability, in [0,1], that a given operation is
+	// a read rather than a write. Reads sample uniformly at random from
+	// the positions Warmup wrote, not from writes made during the timed
+	// run itself, so read latency isn't skewed by contention over a
+	// shared, growing position list.
+	ReadFraction float64
+
+	// Warmup is how many records to write, before the timed run starts,
+	// for ReadFraction's reads to sample from. Ignored if ReadFraction is
+	// 0.
+	Warmup int
+}
+
+// Percentiles summarizes a sorted set of operation latencies.
+type Percentiles struct {
+	P50, P90, P99, P999, Max time.Duration
+}
+
+// Result is a Run's report.
+type Result struct {
+	Duration time.Duration
+
+	WriteOps     uint64
+	WriteBytes   uint64
+	WriteLatency Percentiles
+
+	ReadOps     uint64
+	ReadLatency Percentiles
+}
+
+// Run opens a WAL at cfg.Dir, optionally warms it up with cfg.Warmup
+// records, then drives cfg.Concurrency goroutines issuing reads and
+// writes against it for cfg.Duration, and returns the resulting
+// throughput and latency percentiles.
+func Run(cfg Config) (*Result, error) {
+	if cfg.Concurrency < 1 {
+		cfg.Concurrency = 1
+	}
+
+	opts := []wal.Option{wal.WithDirPath(cfg.Dir), wal.WithSync(cfg.Sync)}
+	if cfg.SegmentSize > 0 {
+		opts = append(opts, wal.WithSegmentSize(cfg.SegmentSize))
+	}
+	w, err := wal.Open(opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer w.Close()
+
+	var warmupPositions []*wal.ChunkPosition
+	for i := 0; i < cfg.Warmup; i++ {
+		pos, err := w.Write(randomPayload(cfg.MinRecordSize, cfg.MaxRecordSize))
+		if err != nil {
+			return nil, err
+		}
+		warmupPositions = append(warmupPositions, pos)
+	}
+
+	var wg sync.WaitGroup
+	results := make([]workerResult, cfg.Concurrency)
+	deadline := time.Now().Add(cfg.Duration)
+	for i := 0; i < cfg.Concurrency; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = runWorker(w, cfg, warmupPositions, deadline)
+		}(i)
+	}
+	wg.Wait()
+
+	return mergeResults(cfg.Duration, results), nil
+}
+
+// workerResult is one goroutine's share of a Run's measurements.
+type workerResult struct {
+	writeOps, writeBytes uint64
+	writeLatencies       []time.Duration
+	readOps              uint64
+	readLatencies        []time.Duration
+}
+
+func runWorker(w *wal.WAL, cfg Config, warmupPositions []*wal.ChunkPosition, deadline time.Time) workerResult {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	var res workerResult
+	for time.Now().Before(deadline) {
+		if len(warmupPositions) > 0 && rng.Float64() < cfg.ReadFraction {
+			pos := warmupPositions[rng.Intn(len(warmupPositions))]
+			start := time.Now()
+			_, err := w.Read(pos)
+			if err != nil {
+				continue
+			}
+			res.readOps++
+			res.readLatencies = append(res.readLatencies, time.Since(start))
+			continue
+		}
+
+		data := randomPayloadWithRand(rng, cfg.MinRecordSize, cfg.MaxRecordSize)
+		start := time.Now()
+		_, err := w.Write(data)
+		if err != nil {
+			continue
+		}
+		res.writeOps++
+		res.writeBytes += uint64(len(data))
+		res.writeLatencies = append(res.writeLatencies, time.Since(start))
+	}
+	return res
+}
+
+func mergeResults(duration time.Duration, workers []workerResult) *Result {
+	r := &Result{Duration: duration}
+	var writeLatencies, readLatencies []time.Duration
+	for _, wr := range workers {
+		r.WriteOps += wr.writeOps
+		r.WriteBytes += wr.writeBytes
+		r.ReadOps += wr.readOps
+		writeLatencies = append(writeLatencies, wr.writeLatencies...)
+		readLatencies = append(readLatencies, wr.readLatencies...)
+	}
+	r.WriteLatency = percentilesOf(writeLatencies)
+	r.ReadLatency = percentilesOf(readLatencies)
+	return r
+}
+
+func percentilesOf(latencies []time.Duration) Percentiles {
+	if len(latencies) == 0 {
+		return Percentiles{}
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	at := func(p float64) time.Duration {
+		idx := int(p * float64(len(latencies)-1))
+		return latencies[idx]
+	}
+	return Percentiles{
+		P50:  at(0.50),
+		P90:  at(0.90),
+		P99:  at(0.99),
+		P999: at(0.999),
+		Max:  latencies[len(latencies)-1],
+	}
+}
+
+func randomPayload(min, max int) []byte {
+	return randomPayloadWithRand(rand.New(rand.NewSource(time.Now().UnixNano())), min, max)
+}
+
+func randomPayloadWithRand(rng *rand.Rand, min, max int) []byte {
+	size := min
+	if max > min {
+		size += rng.Intn(max - min + 1)
+	}
+	data := make([]byte, size)
+	rng.Read(data)
+	return data
+}
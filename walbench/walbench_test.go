@@ -0,0 +1,47 @@
+package walbench
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunReportsWriteThroughputAndLatency(t *testing.T) {
+	dir, err := os.MkdirTemp("", "walbench-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	result, err := Run(Config{
+		Dir:           dir,
+		Concurrency:   2,
+		Duration:      50 * time.Millisecond,
+		MinRecordSize: 16,
+		MaxRecordSize: 64,
+	})
+	require.Nil(t, err)
+	require.Greater(t, result.WriteOps, uint64(0))
+	require.Greater(t, result.WriteBytes, uint64(0))
+	require.Greater(t, result.WriteLatency.P50, time.Duration(0))
+	require.Equal(t, uint64(0), result.ReadOps)
+}
+
+func TestRunWithReadFractionServesReadsFromWarmup(t *testing.T) {
+	dir, err := os.MkdirTemp("", "walbench-read-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	result, err := Run(Config{
+		Dir:           dir,
+		Concurrency:   2,
+		Duration:      50 * time.Millisecond,
+		MinRecordSize: 16,
+		MaxRecordSize: 16,
+		ReadFraction:  1,
+		Warmup:        100,
+	})
+	require.Nil(t, err)
+	require.Greater(t, result.ReadOps, uint64(0))
+	require.Equal(t, uint64(0), result.WriteOps)
+}
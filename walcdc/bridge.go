@@ -0,0 +1,114 @@
+// Package walcdc bridges a WAL to a change-data-capture consumer: it
+// tails the log with a wal.Watcher and delivers every record to a user
+// Sink, committing each delivered position back into the WAL itself (via
+// wal.WAL.CommitOffset) so a Bridge restarted after a crash resumes where
+// it left off instead of replaying the whole log from the start.
+package walcdc
+
+import (
+	"sync"
+
+	"github.com/rosedblabs/wal"
+)
+
+// Sink receives every record a Bridge tails, in order. A Sink that
+// returns an error stops the Bridge -- see Bridge.Err.
+type Sink interface {
+	Handle(pos *wal.ChunkPosition, data []byte) error
+}
+
+// Bridge tails a WAL and delivers every record to a Sink, committing the
+// delivered position back into the WAL via CommitOffset after each
+// successful Handle call. That makes delivery at-least-once, not
+// exactly-once: if a Bridge stops (a crash, not a clean Stop) after Handle
+// returns but before its CommitOffset finishes, the next Bridge for the
+// same consumer name redelivers that record, since the commit recording
+// it as done never landed. A Sink must tolerate being handed the same
+// record more than once.
+type Bridge struct {
+	w        *wal.WAL
+	consumer string
+	sink     Sink
+	watcher  *wal.Watcher
+
+	mu      sync.Mutex
+	sinkErr error
+}
+
+// NewBridge returns a Bridge that delivers every record in w to sink,
+// resuming after consumer's last committed position -- or from the start
+// of the log if consumer has never committed one. opts configures the
+// underlying wal.Watcher the same way it would NewWatcher directly,
+// except opts.From is overwritten with consumer's committed position.
+// Call Start to begin tailing.
+func NewBridge(w *wal.WAL, consumer string, sink Sink, opts wal.WatcherOptions) (*Bridge, error) {
+	pos, ok, err := w.Offset(consumer)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		opts.From = pos
+	}
+
+	b := &Bridge{w: w, consumer: consumer, sink: sink}
+	b.watcher = wal.NewWatcher(w, opts, b.onRecord)
+	return b, nil
+}
+
+// onRecord is the wal.OnRecord callback NewBridge hands the Watcher: it
+// delivers the record to sink and, only once that succeeds, commits pos
+// as consumer's new resume point. It silently skips records CommitOffset
+// itself wrote -- including ones written for a different consumer against
+// the same WAL -- since the Watcher has no way to tell those apart from
+// ordinary data on its own and a Sink should never see its Bridge's own
+// bookkeeping.
+func (b *Bridge) onRecord(pos *wal.ChunkPosition, data []byte) error {
+	if wal.IsOffsetMarker(data) {
+		return nil
+	}
+
+	err := b.sink.Handle(pos, data)
+	if err == nil {
+		_, err = b.w.CommitOffset(b.consumer, pos)
+	}
+	if err != nil {
+		b.mu.Lock()
+		b.sinkErr = err
+		b.mu.Unlock()
+	}
+	return err
+}
+
+// Start begins tailing in a background goroutine; see wal.Watcher.Start.
+func (b *Bridge) Start() {
+	b.watcher.Start()
+}
+
+// Stop signals the Bridge to stop and blocks until it does; see
+// wal.Watcher.Stop.
+func (b *Bridge) Stop() {
+	b.watcher.Stop()
+}
+
+// Err returns the error that stopped the Bridge, if it stopped on its own
+// rather than via Stop. Unlike wal.Watcher.Err, this also reports a Sink
+// or CommitOffset failure from onRecord: Watcher.Err only ever reports a
+// Reader failure, since it assumes an OnRecord caller already has its own
+// error directly -- but the Bridge's OnRecord is onRecord, internal to
+// this package, so Bridge surfaces it here instead.
+func (b *Bridge) Err() error {
+	b.mu.Lock()
+	sinkErr := b.sinkErr
+	b.mu.Unlock()
+	if sinkErr != nil {
+		return sinkErr
+	}
+	return b.watcher.Err()
+}
+
+// Position returns the position of the last record delivered to Sink,
+// whether or not the CommitOffset for it has completed; see
+// wal.Watcher.Position.
+func (b *Bridge) Position() *wal.ChunkPosition {
+	return b.watcher.Position()
+}
@@ -0,0 +1,145 @@
+package walcdc
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rosedblabs/wal"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingSink collects every record handed to it, optionally failing
+// the first N calls before succeeding, to exercise at-least-once retry.
+type recordingSink struct {
+	mu      sync.Mutex
+	records [][]byte
+	failN   int
+}
+
+func (s *recordingSink) Handle(pos *wal.ChunkPosition, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failN > 0 {
+		s.failN--
+		return errors.New("sink temporarily unavailable")
+	}
+	cp := append([]byte(nil), data...)
+	s.records = append(s.records, cp)
+	return nil
+}
+
+func (s *recordingSink) snapshot() [][]byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([][]byte(nil), s.records...)
+}
+
+func TestBridgeDeliversEveryRecordInOrder(t *testing.T) {
+	dir, err := os.MkdirTemp("", "walcdc-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := wal.Open(wal.WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	for _, s := range []string{"one", "two", "three"} {
+		_, err := w.Write([]byte(s))
+		require.Nil(t, err)
+	}
+
+	sink := &recordingSink{}
+	b, err := NewBridge(w, "consumer-a", sink, wal.WatcherOptions{})
+	require.Nil(t, err)
+	b.Start()
+	defer b.Stop()
+
+	require.Eventually(t, func() bool {
+		return len(sink.snapshot()) == 3
+	}, time.Second, 5*time.Millisecond)
+
+	got := sink.snapshot()
+	require.Equal(t, []byte("one"), got[0])
+	require.Equal(t, []byte("two"), got[1])
+	require.Equal(t, []byte("three"), got[2])
+}
+
+func TestBridgeResumesAfterCommittedPositionOnRestart(t *testing.T) {
+	dir, err := os.MkdirTemp("", "walcdc-resume-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := wal.Open(wal.WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	for _, s := range []string{"one", "two"} {
+		_, err := w.Write([]byte(s))
+		require.Nil(t, err)
+	}
+
+	sink1 := &recordingSink{}
+	b1, err := NewBridge(w, "consumer-b", sink1, wal.WatcherOptions{})
+	require.Nil(t, err)
+	b1.Start()
+	require.Eventually(t, func() bool {
+		return len(sink1.snapshot()) == 2
+	}, time.Second, 5*time.Millisecond)
+	b1.Stop()
+	require.Nil(t, b1.Err())
+
+	_, err = w.Write([]byte("three"))
+	require.Nil(t, err)
+
+	sink2 := &recordingSink{}
+	b2, err := NewBridge(w, "consumer-b", sink2, wal.WatcherOptions{})
+	require.Nil(t, err)
+	b2.Start()
+	defer b2.Stop()
+
+	require.Eventually(t, func() bool {
+		return len(sink2.snapshot()) == 1
+	}, time.Second, 5*time.Millisecond)
+	require.Equal(t, []byte("three"), sink2.snapshot()[0])
+}
+
+func TestBridgeRedeliversARecordTheSinkFailedUncommitted(t *testing.T) {
+	dir, err := os.MkdirTemp("", "walcdc-redeliver-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := wal.Open(wal.WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("stubborn"))
+	require.Nil(t, err)
+
+	sink := &recordingSink{failN: 1}
+	b, err := NewBridge(w, "consumer-c", sink, wal.WatcherOptions{
+		MinBackoff: time.Millisecond,
+		MaxBackoff: 5 * time.Millisecond,
+	})
+	require.Nil(t, err)
+	b.Start()
+	require.Eventually(t, func() bool {
+		return b.Err() != nil
+	}, time.Second, 5*time.Millisecond)
+	b.Stop()
+
+	require.Empty(t, sink.snapshot())
+
+	sink2 := &recordingSink{}
+	b2, err := NewBridge(w, "consumer-c", sink2, wal.WatcherOptions{})
+	require.Nil(t, err)
+	b2.Start()
+	defer b2.Stop()
+
+	require.Eventually(t, func() bool {
+		return len(sink2.snapshot()) == 1
+	}, time.Second, 5*time.Millisecond)
+	require.Equal(t, []byte("stubborn"), sink2.snapshot()[0])
+}
@@ -0,0 +1,86 @@
+package waletcd
+
+import "google.golang.org/protobuf/encoding/protowire"
+
+// Entry mirrors the fields of etcd raft's raftpb.Entry that an entryType
+// wal record carries: a log position (Term, Index), a type discriminator
+// (Type -- 0 for a normal entry, 1 for a conf change, 3 for a V2 conf
+// change, per raftpb.EntryType), and the caller's own payload (Data).
+// Import and Export trade in this local type instead of raftpb.Entry
+// itself so this package doesn't have to depend on go.etcd.io/etcd/raft,
+// which would drag in the rest of etcd's consensus engine for what's
+// otherwise a three-field, stable wire format.
+type Entry struct {
+	Term  uint64
+	Index uint64
+	Type  int32
+	Data  []byte
+}
+
+// marshalEntry encodes e the way etcd raft's generated raftpb.Entry.Marshal
+// would: field numbers 1 (Type), 2 (Term), 3 (Index), 4 (Data).
+func marshalEntry(e Entry) []byte {
+	var buf []byte
+	buf = protowire.AppendTag(buf, 1, protowire.VarintType)
+	buf = protowire.AppendVarint(buf, uint64(e.Type))
+	buf = protowire.AppendTag(buf, 2, protowire.VarintType)
+	buf = protowire.AppendVarint(buf, e.Term)
+	buf = protowire.AppendTag(buf, 3, protowire.VarintType)
+	buf = protowire.AppendVarint(buf, e.Index)
+	if len(e.Data) > 0 {
+		buf = protowire.AppendTag(buf, 4, protowire.BytesType)
+		buf = protowire.AppendBytes(buf, e.Data)
+	}
+	return buf
+}
+
+// unmarshalEntry reverses marshalEntry, skipping any field it doesn't
+// recognize.
+func unmarshalEntry(data []byte) (Entry, error) {
+	var e Entry
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return Entry{}, protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		switch num {
+		case 1:
+			v, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return Entry{}, protowire.ParseError(n)
+			}
+			e.Type = int32(v)
+			data = data[n:]
+		case 2:
+			v, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return Entry{}, protowire.ParseError(n)
+			}
+			e.Term = v
+			data = data[n:]
+		case 3:
+			v, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return Entry{}, protowire.ParseError(n)
+			}
+			e.Index = v
+			data = data[n:]
+		case 4:
+			v, n := protowire.ConsumeBytes(data)
+			if n < 0 {
+				return Entry{}, protowire.ParseError(n)
+			}
+			e.Data = v
+			data = data[n:]
+		default:
+			n := protowire.ConsumeFieldValue(num, typ, data)
+			if n < 0 {
+				return Entry{}, protowire.ParseError(n)
+			}
+			data = data[n:]
+		}
+	}
+	return e, nil
+}
@@ -0,0 +1,127 @@
+package waletcd
+
+import (
+	"hash/crc32"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// frameSizeBytes is the size, in bytes, of the length field preceding
+// every record in an etcd WAL file -- a little-endian int64 with the
+// record's length packed into its lower 56 bits and, when the record
+// needed padding to the next 8-byte boundary, the pad byte count packed
+// into the top 3 bits of its highest byte. This mirrors etcd's own
+// wal/decoder.go and wal/encoder.go exactly, since it's the on-disk format
+// Import and Export need to stay byte-compatible with, not a format this
+// package gets to choose.
+const frameSizeBytes = 8
+
+// etcd wal record types. These match the unexported constants etcd's own
+// wal package assigns these same values, in this same order, starting
+// from 1 (see wal/wal.go) -- Import only ever looks for entryType, but
+// needs to recognize the others to skip their frames and fold their bytes
+// into the running CRC correctly.
+const (
+	metadataType int64 = iota + 1
+	entryType
+	stateType
+	crcType
+	snapshotType
+)
+
+// crcTable is the CRC-32 polynomial etcd's wal package hashes every
+// record's Data against (see etcd's pkg/crc, which just wraps
+// hash/crc32 with a settable starting value).
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// record is the subset of etcd's walpb.Record this package needs: enough
+// to read any record's frame, and for entryType records, decode the
+// raftpb.Entry inside its Data.
+type record struct {
+	Type int64
+	Crc  uint32
+	Data []byte
+}
+
+// marshalRecord encodes r the way etcd's generated walpb.Record.Marshal
+// would: a tiny fixed-shape protobuf message, field numbers 1 (Type), 2
+// (Crc), 3 (Data). Using protowire directly instead of a generated
+// message avoids depending on gogo/protobuf or etcd's own walpb package
+// just for this.
+func marshalRecord(r record) []byte {
+	var buf []byte
+	buf = protowire.AppendTag(buf, 1, protowire.VarintType)
+	buf = protowire.AppendVarint(buf, uint64(r.Type))
+	buf = protowire.AppendTag(buf, 2, protowire.VarintType)
+	buf = protowire.AppendVarint(buf, uint64(r.Crc))
+	if len(r.Data) > 0 {
+		buf = protowire.AppendTag(buf, 3, protowire.BytesType)
+		buf = protowire.AppendBytes(buf, r.Data)
+	}
+	return buf
+}
+
+// unmarshalRecord reverses marshalRecord, tolerating (by skipping) any
+// field it doesn't recognize, the way proto3 unmarshaling does.
+func unmarshalRecord(data []byte) (record, error) {
+	var r record
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return record{}, protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		switch num {
+		case 1:
+			v, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return record{}, protowire.ParseError(n)
+			}
+			r.Type = int64(v)
+			data = data[n:]
+		case 2:
+			v, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return record{}, protowire.ParseError(n)
+			}
+			r.Crc = uint32(v)
+			data = data[n:]
+		case 3:
+			v, n := protowire.ConsumeBytes(data)
+			if n < 0 {
+				return record{}, protowire.ParseError(n)
+			}
+			r.Data = v
+			data = data[n:]
+		default:
+			n := protowire.ConsumeFieldValue(num, typ, data)
+			if n < 0 {
+				return record{}, protowire.ParseError(n)
+			}
+			data = data[n:]
+		}
+	}
+	return r, nil
+}
+
+// encodeFrameSize packs dataBytes' length and the padding needed to reach
+// the next 8-byte boundary into a single frame length field, the same
+// layout etcd's encoder.go produces.
+func encodeFrameSize(dataBytes int) (lenField uint64, padBytes int) {
+	lenField = uint64(dataBytes)
+	padBytes = (8 - (dataBytes % 8)) % 8
+	if padBytes != 0 {
+		lenField |= uint64(0x80|padBytes) << 56
+	}
+	return lenField, padBytes
+}
+
+// decodeFrameSize reverses encodeFrameSize.
+func decodeFrameSize(lenField int64) (recBytes int64, padBytes int64) {
+	recBytes = int64(uint64(lenField) &^ (uint64(0xff) << 56))
+	if lenField < 0 {
+		padBytes = int64((uint64(lenField) >> 56) & 0x7)
+	}
+	return recBytes, padBytes
+}
@@ -0,0 +1,224 @@
+// Package waletcd converts between this WAL's format and etcd's wal
+// package's on-disk format (go.etcd.io/etcd/server/v3/wal), so a project
+// migrating off (or onto) etcd's raft log storage can carry its history
+// across without hand-writing a one-off parser for etcd's record framing.
+//
+// It does not import go.etcd.io/etcd/server/v3: that module's wal package
+// pulls in the rest of etcd's raft consensus engine (and, transitively,
+// zap, gogo/protobuf, and etcd's own bbolt-adjacent tooling) just to
+// decode a three-field protobuf record, which is a poor trade for a
+// library whose only other protobuf use is the already-required
+// google.golang.org/protobuf. Instead, this package hand-decodes etcd's
+// wal record frame and the raftpb.Entry inside it directly off
+// google.golang.org/protobuf/encoding/protowire, against the same wire
+// layout etcd's generated code produces -- see record.go and entry.go.
+//
+// Import and Export only carry raft log entries' Data payloads across, in
+// order; they don't preserve or reconstruct an etcd WAL's HardState,
+// snapshots, or original Term/Index numbering. That's a deliberate scope
+// limit, not an oversight: this library addresses records by
+// ChunkPosition, not by raft log index, so there's nothing on this side
+// for an imported entry's Index or Term to usefully attach to, and a
+// snapshot or HardState on its own (without the raft engine that produced
+// it) isn't data a caller migrating storage engines needs carried
+// forward. Export resynthesizes Term and Index (starting at 1, counting
+// up by one per record) only because etcd's own wal.Open requires some
+// value to be present in every entryType record's frame.
+package waletcd
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/rosedblabs/wal"
+)
+
+// walFileName formats an etcd wal segment's file name the way etcd's own
+// wal.walName does: a hex sequence number and a hex index, both
+// zero-padded to 16 digits.
+func walFileName(seq, index uint64) string {
+	return fmt.Sprintf("%016x-%016x.wal", seq, index)
+}
+
+// etcdWALFileNames returns every "*.wal" file name directly inside dir,
+// in the order etcd itself reads them back: ascending by the sequence
+// number encoded in the name, not by directory listing order.
+func etcdWALFileNames(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	seqs := make(map[string]uint64)
+	for _, entry := range entries {
+		var seq, index uint64
+		if _, err := fmt.Sscanf(entry.Name(), "%016x-%016x.wal", &seq, &index); err != nil {
+			continue
+		}
+		names = append(names, entry.Name())
+		seqs[entry.Name()] = seq
+	}
+	sort.Slice(names, func(i, j int) bool { return seqs[names[i]] < seqs[names[j]] })
+	return names, nil
+}
+
+// Import reads every entryType record out of the etcd wal directory at
+// etcdDir, in the order etcd itself would replay them, and appends each
+// one's Entry.Data to dst with dst.Write. It returns how many entries
+// were imported.
+//
+// Import verifies every record's CRC the same way etcd's own decoder
+// does -- chained across records and across files, resetting at each
+// crcType record -- and stops with an error on the first mismatch, rather
+// than importing a possibly-torn tail silently.
+func Import(etcdDir string, dst *wal.WAL) (int, error) {
+	names, err := etcdWALFileNames(etcdDir)
+	if err != nil {
+		return 0, err
+	}
+
+	var runningCRC uint32
+	var count int
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(etcdDir, name))
+		if err != nil {
+			return count, err
+		}
+
+		recs, newCRC, err := decodeFrames(data, runningCRC)
+		if err != nil {
+			return count, fmt.Errorf("waletcd: decoding %s: %w", name, err)
+		}
+		runningCRC = newCRC
+
+		for _, rec := range recs {
+			if rec.Type != entryType {
+				continue
+			}
+			entry, err := unmarshalEntry(rec.Data)
+			if err != nil {
+				return count, fmt.Errorf("waletcd: decoding entry in %s: %w", name, err)
+			}
+			if _, err := dst.Write(entry.Data); err != nil {
+				return count, err
+			}
+			count++
+		}
+	}
+	return count, nil
+}
+
+// decodeFrames decodes every record framed in data -- a single etcd wal
+// file's bytes -- starting the CRC chain from crc (the value left over
+// from whatever file, if any, preceded this one), and returns the chain's
+// value at the end of data for the next file to continue from. A zero
+// length field, or running out of bytes mid-frame, ends decoding at that
+// point without error: it means data has run into the file's unused
+// preallocated tail, the same as io.EOF does for etcd's own decoder.
+func decodeFrames(data []byte, crc uint32) ([]record, uint32, error) {
+	var recs []record
+	for len(data) >= frameSizeBytes {
+		lenField := int64(binary.LittleEndian.Uint64(data[:frameSizeBytes]))
+		if lenField == 0 {
+			break
+		}
+		data = data[frameSizeBytes:]
+
+		recBytes, padBytes := decodeFrameSize(lenField)
+		total := recBytes + padBytes
+		if int64(len(data)) < total {
+			break
+		}
+		raw := data[:recBytes]
+		data = data[total:]
+
+		rec, err := unmarshalRecord(raw)
+		if err != nil {
+			return recs, crc, err
+		}
+
+		if rec.Type == crcType {
+			crc = rec.Crc
+		} else {
+			crc = crc32.Update(crc, crcTable, rec.Data)
+			if rec.Crc != crc {
+				return recs, crc, fmt.Errorf("waletcd: record crc %d does not match computed crc %d", rec.Crc, crc)
+			}
+		}
+		recs = append(recs, rec)
+	}
+	return recs, crc, nil
+}
+
+// Export reads every record out of src, in order, and writes each one's
+// data as a freshly-numbered raft log entry into a single etcd wal
+// segment file it creates under dstDir (which must not already exist).
+// The result opens with etcd's own wal.Open, but see the package doc
+// comment for what it deliberately leaves out: a HardState, any
+// snapshots, and src's original record positions.
+func Export(src *wal.WAL, dstDir string) error {
+	if err := os.MkdirAll(dstDir, 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(filepath.Join(dstDir, walFileName(0, 0)), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var crc uint32
+	if err := writeFrame(f, record{Type: metadataType}); err != nil {
+		return err
+	}
+	if err := writeFrame(f, record{Type: crcType, Crc: crc}); err != nil {
+		return err
+	}
+
+	var index uint64
+	r := src.NewReader()
+	for {
+		data, _, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		index++
+
+		entryData := marshalEntry(Entry{Term: 1, Index: index, Data: data})
+		crc = crc32.Update(crc, crcTable, entryData)
+		if err := writeFrame(f, record{Type: entryType, Crc: crc, Data: entryData}); err != nil {
+			return err
+		}
+	}
+
+	return f.Sync()
+}
+
+// writeFrame appends rec to f in etcd's on-disk frame layout: an 8-byte
+// little-endian length field (packing in the padding byte count needed
+// to reach the next 8-byte boundary), followed by rec's marshaled bytes
+// and that padding.
+func writeFrame(f *os.File, rec record) error {
+	data := marshalRecord(rec)
+	lenField, padBytes := encodeFrameSize(len(data))
+
+	var lenBuf [frameSizeBytes]byte
+	binary.LittleEndian.PutUint64(lenBuf[:], lenField)
+	if _, err := f.Write(lenBuf[:]); err != nil {
+		return err
+	}
+	if padBytes > 0 {
+		data = append(data, make([]byte, padBytes)...)
+	}
+	_, err := f.Write(data)
+	return err
+}
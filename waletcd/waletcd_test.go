@@ -0,0 +1,94 @@
+package waletcd
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rosedblabs/wal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportThenImportRoundTripsEntryData(t *testing.T) {
+	srcDir, err := os.MkdirTemp("", "waletcd-src-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(srcDir)
+
+	src, err := wal.Open(wal.WithDirPath(srcDir))
+	require.Nil(t, err)
+	want := [][]byte{[]byte("one"), []byte("two"), []byte("three")}
+	for _, data := range want {
+		_, err := src.Write(data)
+		require.Nil(t, err)
+	}
+
+	etcdDir := filepath.Join(os.TempDir(), "waletcd-export-test")
+	require.Nil(t, os.RemoveAll(etcdDir))
+	defer os.RemoveAll(etcdDir)
+	require.Nil(t, Export(src, etcdDir))
+	require.Nil(t, src.Close())
+
+	dstDir, err := os.MkdirTemp("", "waletcd-dst-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dstDir)
+	dst, err := wal.Open(wal.WithDirPath(dstDir))
+	require.Nil(t, err)
+	defer dst.Close()
+
+	n, err := Import(etcdDir, dst)
+	require.Nil(t, err)
+	require.Equal(t, len(want), n)
+
+	var got [][]byte
+	r := dst.NewReader()
+	for {
+		data, _, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		require.Nil(t, err)
+		got = append(got, data)
+	}
+	require.Equal(t, want, got)
+}
+
+func TestImportRejectsACorruptRecordCRC(t *testing.T) {
+	etcdDir := filepath.Join(os.TempDir(), "waletcd-corrupt-test")
+	require.Nil(t, os.RemoveAll(etcdDir))
+	defer os.RemoveAll(etcdDir)
+	require.Nil(t, os.MkdirAll(etcdDir, 0o755))
+
+	f, err := os.OpenFile(filepath.Join(etcdDir, walFileName(0, 0)), os.O_CREATE|os.O_WRONLY, 0o644)
+	require.Nil(t, err)
+	require.Nil(t, writeFrame(f, record{Type: metadataType}))
+	require.Nil(t, writeFrame(f, record{Type: crcType}))
+	entryData := marshalEntry(Entry{Term: 1, Index: 1, Data: []byte("hello")})
+	require.Nil(t, writeFrame(f, record{Type: entryType, Crc: 0xdeadbeef, Data: entryData}))
+	require.Nil(t, f.Close())
+
+	dir, err := os.MkdirTemp("", "waletcd-corrupt-dst-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	dst, err := wal.Open(wal.WithDirPath(dir))
+	require.Nil(t, err)
+	defer dst.Close()
+
+	_, err = Import(etcdDir, dst)
+	require.NotNil(t, err)
+	require.ErrorContains(t, err, "crc")
+}
+
+func TestEntryMarshalUnmarshalRoundTrips(t *testing.T) {
+	e := Entry{Term: 7, Index: 42, Type: 1, Data: []byte("payload")}
+	got, err := unmarshalEntry(marshalEntry(e))
+	require.Nil(t, err)
+	require.Equal(t, e, got)
+}
+
+func TestRecordMarshalUnmarshalRoundTrips(t *testing.T) {
+	r := record{Type: entryType, Crc: 123, Data: []byte("data")}
+	got, err := unmarshalRecord(marshalRecord(r))
+	require.Nil(t, err)
+	require.Equal(t, r, got)
+}
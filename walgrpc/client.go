@@ -0,0 +1,58 @@
+package walgrpc
+
+import (
+	"context"
+
+	"github.com/rosedblabs/wal"
+	"google.golang.org/grpc"
+)
+
+// Client wraps a generated WALClient with wal.ChunkPosition in place of
+// Position, so a caller talks to a remote WAL the same way it would talk
+// to a local one.
+type Client struct {
+	WALClient
+}
+
+// NewClient returns a Client that issues RPCs over cc.
+func NewClient(cc grpc.ClientConnInterface) *Client {
+	return &Client{WALClient: NewWALClient(cc)}
+}
+
+// Append appends data to the remote WAL and returns its position.
+func (c *Client) Append(ctx context.Context, data []byte) (*wal.ChunkPosition, error) {
+	resp, err := c.WALClient.Append(ctx, &AppendRequest{Data: data})
+	if err != nil {
+		return nil, err
+	}
+	return positionFromProto(resp.GetPosition()), nil
+}
+
+// Read reads back the record at pos from the remote WAL.
+func (c *Client) Read(ctx context.Context, pos *wal.ChunkPosition) ([]byte, error) {
+	resp, err := c.WALClient.Read(ctx, &ReadRequest{Position: positionToProto(pos)})
+	if err != nil {
+		return nil, err
+	}
+	return resp.GetData(), nil
+}
+
+// Tail streams every record written to the remote WAL, past and future,
+// starting just after from (or from the beginning of the log if from is
+// nil), delivering each to onRecord in order until ctx is done or onRecord
+// returns a non-nil error.
+func (c *Client) Tail(ctx context.Context, from *wal.ChunkPosition, onRecord func(pos *wal.ChunkPosition, data []byte) error) error {
+	stream, err := c.WALClient.Tail(ctx, &TailRequest{From: positionToProto(from)})
+	if err != nil {
+		return err
+	}
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		if err := onRecord(positionFromProto(resp.GetPosition()), resp.GetData()); err != nil {
+			return err
+		}
+	}
+}
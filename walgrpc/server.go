@@ -0,0 +1,98 @@
+// Package walgrpc serves a wal.WAL to remote clients over gRPC: Append and
+// Read give request/response access to individual records, and Tail
+// streams every record written to the log, past and future, the same way
+// wal.Watcher does in-process. It exists so that a thin writer running in
+// a sidecar, or any other out-of-process caller, doesn't have to invent
+// its own wire format for positions and records.
+package walgrpc
+
+import (
+	"context"
+
+	"github.com/rosedblabs/wal"
+)
+
+// Server implements WALServer over a wal.WAL.
+type Server struct {
+	UnimplementedWALServer
+
+	wal *wal.WAL
+}
+
+// NewServer returns a Server backed by w.
+func NewServer(w *wal.WAL) *Server {
+	return &Server{wal: w}
+}
+
+// Append implements WALServer.
+func (s *Server) Append(ctx context.Context, req *AppendRequest) (*AppendResponse, error) {
+	pos, err := s.wal.Write(req.GetData())
+	if err != nil {
+		return nil, err
+	}
+	return &AppendResponse{Position: positionToProto(pos)}, nil
+}
+
+// Read implements WALServer.
+func (s *Server) Read(ctx context.Context, req *ReadRequest) (*ReadResponse, error) {
+	data, err := s.wal.Read(positionFromProto(req.GetPosition()))
+	if err != nil {
+		return nil, err
+	}
+	return &ReadResponse{Data: data}, nil
+}
+
+// Tail implements WALServer, streaming every record written to the WAL --
+// past and future -- to stream in order, starting just after req.From (or
+// from the beginning of the log if req.From is unset). It returns once
+// stream's context is done or a genuine read error occurs; it never
+// returns on its own just because it has caught up with the tail.
+func (s *Server) Tail(req *TailRequest, stream WAL_TailServer) error {
+	var tail *wal.TailReader
+	var err error
+	if from := req.GetFrom(); from != nil {
+		tail, err = s.wal.NewTailReaderWithStart(positionFromProto(from))
+	} else {
+		tail = s.wal.NewTailReader()
+	}
+	if err != nil {
+		return err
+	}
+
+	ctx := stream.Context()
+	for {
+		data, pos, err := tail.Next(ctx)
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(&TailResponse{Position: positionToProto(pos), Data: data}); err != nil {
+			return err
+		}
+	}
+}
+
+// positionToProto converts a wal.ChunkPosition to its wire form.
+func positionToProto(pos *wal.ChunkPosition) *Position {
+	if pos == nil {
+		return nil
+	}
+	return &Position{
+		SegmentId:   pos.SegmentId,
+		ChunkOffset: pos.ChunkOffset,
+		ChunkSize:   pos.ChunkSize,
+		Sequence:    pos.Sequence,
+	}
+}
+
+// positionFromProto converts a Position back to a wal.ChunkPosition.
+func positionFromProto(pos *Position) *wal.ChunkPosition {
+	if pos == nil {
+		return nil
+	}
+	return &wal.ChunkPosition{
+		SegmentId:   pos.GetSegmentId(),
+		ChunkOffset: pos.GetChunkOffset(),
+		ChunkSize:   pos.GetChunkSize(),
+		Sequence:    pos.GetSequence(),
+	}
+}
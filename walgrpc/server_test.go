@@ -0,0 +1,87 @@
+package walgrpc_test
+
+import (
+	"context"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/rosedblabs/wal"
+	"github.com/rosedblabs/wal/walgrpc"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func startTestServer(t *testing.T, w *wal.WAL) *walgrpc.Client {
+	t.Helper()
+
+	lis := bufconn.Listen(1024 * 1024)
+	srv := grpc.NewServer()
+	walgrpc.RegisterWALServer(srv, walgrpc.NewServer(w))
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	cc, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.Nil(t, err)
+	t.Cleanup(func() { cc.Close() })
+
+	return walgrpc.NewClient(cc)
+}
+
+func TestClientAppendsAndReadsThroughTheServer(t *testing.T) {
+	dir, err := os.MkdirTemp("", "walgrpc-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := wal.Open(wal.WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	client := startTestServer(t, w)
+	ctx := context.Background()
+
+	pos, err := client.Append(ctx, []byte("hello"))
+	require.Nil(t, err)
+
+	data, err := client.Read(ctx, pos)
+	require.Nil(t, err)
+	require.Equal(t, "hello", string(data))
+}
+
+func TestClientTailsRecordsWrittenAfterTheStreamStarts(t *testing.T) {
+	dir, err := os.MkdirTemp("", "walgrpc-tail-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := wal.Open(wal.WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	client := startTestServer(t, w)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	received := make(chan string, 1)
+	go client.Tail(ctx, nil, func(pos *wal.ChunkPosition, data []byte) error {
+		received <- string(data)
+		return nil
+	})
+
+	_, err = client.Append(ctx, []byte("tailed value"))
+	require.Nil(t, err)
+
+	select {
+	case got := <-received:
+		require.Equal(t, "tailed value", got)
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the tail stream to deliver a record")
+	}
+}
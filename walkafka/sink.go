@@ -0,0 +1,50 @@
+// Package walkafka implements a walcdc.Sink that mirrors a WAL into a
+// Kafka topic. It is a separate package from walcdc itself, mirroring
+// walmsgpack and walgrpc, so that depending on a CDC bridge doesn't also
+// pull in github.com/segmentio/kafka-go for callers who don't need Kafka.
+package walkafka
+
+import (
+	"context"
+
+	"github.com/rosedblabs/wal"
+	kafka "github.com/segmentio/kafka-go"
+)
+
+// Sink is a walcdc.Sink that writes every record handed to it as a Kafka
+// message on Writer's configured topic, keyed by pos's text encoding so a
+// downstream consumer that wants to dedup against at-least-once redelivery
+// can do so on the key.
+type Sink struct {
+	Writer *kafka.Writer
+}
+
+// NewSink returns a Sink that produces to topic on the brokers at addrs,
+// using kafka-go's default round-robin partitioning. Callers that want
+// different write settings (batching, compression, required acks) can
+// build a Sink directly around their own *kafka.Writer instead.
+func NewSink(addrs []string, topic string) *Sink {
+	return &Sink{Writer: &kafka.Writer{
+		Addr:     kafka.TCP(addrs...),
+		Topic:    topic,
+		Balancer: &kafka.LeastBytes{},
+	}}
+}
+
+// Handle implements walcdc.Sink.
+func (s *Sink) Handle(pos *wal.ChunkPosition, data []byte) error {
+	key, err := pos.MarshalText()
+	if err != nil {
+		return err
+	}
+	return s.Writer.WriteMessages(context.Background(), kafka.Message{
+		Key:   key,
+		Value: data,
+	})
+}
+
+// Close flushes any buffered messages and closes the underlying Kafka
+// connection.
+func (s *Sink) Close() error {
+	return s.Writer.Close()
+}
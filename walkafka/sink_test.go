@@ -0,0 +1,15 @@
+package walkafka
+
+import (
+	"testing"
+
+	"github.com/rosedblabs/wal/walcdc"
+	"github.com/stretchr/testify/require"
+)
+
+var _ walcdc.Sink = (*Sink)(nil)
+
+func TestNewSinkConfiguresWriterForTopic(t *testing.T) {
+	s := NewSink([]string{"localhost:9092"}, "wal-events")
+	require.Equal(t, "wal-events", s.Writer.Topic)
+}
@@ -0,0 +1,22 @@
+// Package walmsgpack implements wal.Codec over MessagePack, for records
+// that want a compact, schema-less wire format without gob's requirement
+// that both ends share the same Go types. It is a separate package from
+// wal itself, mirroring walprom, so that depending on wal.Typed doesn't
+// also pull in github.com/vmihailenco/msgpack for callers who don't need
+// it.
+package walmsgpack
+
+import "github.com/vmihailenco/msgpack/v5"
+
+// Codec is a wal.Codec that encodes and decodes T with MessagePack.
+type Codec[T any] struct{}
+
+// Encode implements wal.Codec.
+func (Codec[T]) Encode(v T) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+// Decode implements wal.Codec.
+func (Codec[T]) Decode(data []byte, v *T) error {
+	return msgpack.Unmarshal(data, v)
+}
@@ -0,0 +1,35 @@
+package walmsgpack_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/rosedblabs/wal"
+	"github.com/rosedblabs/wal/walmsgpack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type record struct {
+	Key   string
+	Value int
+}
+
+func TestCodecRoundTripsThroughTyped(t *testing.T) {
+	dir, err := os.MkdirTemp("", "walmsgpack-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := wal.Open(wal.WithDirPath(dir), wal.WithSegmentFileExt(".SEG"))
+	require.Nil(t, err)
+	defer w.Close()
+
+	typed := wal.NewTyped[record](w, walmsgpack.Codec[record]{})
+
+	pos, err := typed.WriteRecord(record{Key: "a", Value: 1})
+	require.Nil(t, err)
+
+	got, err := typed.ReadRecord(pos)
+	require.Nil(t, err)
+	assert.Equal(t, record{Key: "a", Value: 1}, got)
+}
@@ -0,0 +1,36 @@
+// Package walnats implements a walcdc.Sink that mirrors a WAL into a NATS
+// JetStream subject. It is a separate package from walcdc itself,
+// mirroring walmsgpack and walgrpc, so that depending on a CDC bridge
+// doesn't also pull in github.com/nats-io/nats.go for callers who don't
+// need NATS.
+package walnats
+
+import (
+	"github.com/nats-io/nats.go"
+	"github.com/rosedblabs/wal"
+)
+
+// Sink is a walcdc.Sink that publishes every record handed to it to
+// Subject on JS, tagging each publish with pos's text encoding as the
+// JetStream message ID (via nats.MsgId) so JetStream's own duplicate
+// window rejects a redelivery caused by walcdc.Bridge's at-least-once
+// semantics instead of the subscriber seeing it twice.
+type Sink struct {
+	JS      nats.JetStreamContext
+	Subject string
+}
+
+// NewSink returns a Sink that publishes to subject through js.
+func NewSink(js nats.JetStreamContext, subject string) *Sink {
+	return &Sink{JS: js, Subject: subject}
+}
+
+// Handle implements walcdc.Sink.
+func (s *Sink) Handle(pos *wal.ChunkPosition, data []byte) error {
+	id, err := pos.MarshalText()
+	if err != nil {
+		return err
+	}
+	_, err = s.JS.Publish(s.Subject, data, nats.MsgId(string(id)))
+	return err
+}
@@ -0,0 +1,15 @@
+package walnats
+
+import (
+	"testing"
+
+	"github.com/rosedblabs/wal/walcdc"
+	"github.com/stretchr/testify/require"
+)
+
+var _ walcdc.Sink = (*Sink)(nil)
+
+func TestNewSinkSetsSubject(t *testing.T) {
+	s := NewSink(nil, "wal.events")
+	require.Equal(t, "wal.events", s.Subject)
+}
@@ -0,0 +1,37 @@
+package walprom
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rosedblabs/wal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollectorReportsWALStats(t *testing.T) {
+	dir, err := os.MkdirTemp("", "walprom-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := wal.Open(wal.WithDirPath(dir), wal.WithSync(true))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("hello"))
+	require.Nil(t, err)
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(NewCollector(w))
+
+	require.Equal(t, 8, testutil.CollectAndCount(reg))
+
+	expected := `
+# HELP wal_stats_bytes_written_total Total number of payload bytes written to WAL segments.
+# TYPE wal_stats_bytes_written_total counter
+wal_stats_bytes_written_total 5
+`
+	require.Nil(t, testutil.GatherAndCompare(reg, strings.NewReader(expected), "wal_stats_bytes_written_total"))
+}
@@ -0,0 +1,37 @@
+// Package walproto implements wal.Codec over protocol buffers, for
+// applications that already define their records as generated protobuf
+// messages and would rather not also hand-write a gob or msgpack mapping
+// for them. It is a separate package from wal itself, mirroring walprom,
+// so that depending on wal.Typed doesn't also pull in
+// google.golang.org/protobuf for callers who don't need it.
+package walproto
+
+import (
+	"reflect"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Codec is a wal.Codec that encodes and decodes T with protocol buffers'
+// own wire format. T must be a generated message type (a pointer
+// implementing proto.Message), not the message's value type.
+type Codec[T proto.Message] struct{}
+
+// Encode implements wal.Codec.
+func (Codec[T]) Encode(v T) ([]byte, error) {
+	return proto.Marshal(v)
+}
+
+// Decode implements wal.Codec. Unlike gob, proto.Unmarshal needs an
+// already-allocated message to unmarshal into rather than allocating one
+// itself, so Decode allocates a fresh T via reflection before handing it
+// off -- the zero value *v started as, typically nil, is never itself
+// passed to proto.Unmarshal.
+func (Codec[T]) Decode(data []byte, v *T) error {
+	msg := reflect.New(reflect.TypeOf(*v).Elem()).Interface().(T)
+	if err := proto.Unmarshal(data, msg); err != nil {
+		return err
+	}
+	*v = msg
+	return nil
+}
@@ -0,0 +1,33 @@
+package walproto_test
+
+import (
+	"os"
+	"testing"
+
+	io_prometheus_client "github.com/prometheus/client_model/go"
+	"github.com/rosedblabs/wal"
+	"github.com/rosedblabs/wal/walproto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodecRoundTripsThroughTyped(t *testing.T) {
+	dir, err := os.MkdirTemp("", "walproto-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := wal.Open(wal.WithDirPath(dir), wal.WithSegmentFileExt(".SEG"))
+	require.Nil(t, err)
+	defer w.Close()
+
+	typed := wal.NewTyped[*io_prometheus_client.LabelPair](w, walproto.Codec[*io_prometheus_client.LabelPair]{})
+
+	name, value := "env", "prod"
+	pos, err := typed.WriteRecord(&io_prometheus_client.LabelPair{Name: &name, Value: &value})
+	require.Nil(t, err)
+
+	got, err := typed.ReadRecord(pos)
+	require.Nil(t, err)
+	assert.Equal(t, "env", got.GetName())
+	assert.Equal(t, "prod", got.GetValue())
+}
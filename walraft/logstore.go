@@ -0,0 +1,190 @@
+// Package walraft implements hashicorp/raft's raft.LogStore and
+// raft.StableStore on top of a *wal.WAL each, the same way walgrpc and
+// walmsgpack each wrap the library for one specific external integration,
+// so a caller backing hashicorp/raft with this library doesn't have to
+// write its own index-to-position mapping and StoreLogs batching.
+package walraft
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/hashicorp/raft"
+	"github.com/rosedblabs/wal"
+	"github.com/rosedblabs/wal/walmsgpack"
+)
+
+// LogStore implements raft.LogStore on top of a *wal.WAL: every raft.Log
+// is appended as a single MessagePack-encoded record, and an in-memory
+// index maps each log's Index to the wal.ChunkPosition it landed at, so
+// GetLog doesn't have to scan the log to find an entry.
+type LogStore struct {
+	w     *wal.WAL
+	codec walmsgpack.Codec[raft.Log]
+
+	mu       sync.RWMutex
+	index    map[uint64]*wal.ChunkPosition
+	firstIdx uint64
+	lastIdx  uint64
+}
+
+// NewLogStore returns a LogStore backed by w, rebuilding its index by
+// scanning w's entire log. Unlike wal.Offset's lazily-scanned-on-first-use
+// approach, this happens unconditionally, up front: raft calls FirstIndex
+// and LastIndex as soon as it starts, before this LogStore would otherwise
+// have a chance to scan on demand.
+//
+// w must not be used for anything other than this LogStore's records --
+// GetLog, FirstIndex, and LastIndex all assume every chunk in it decodes
+// as a raft.Log.
+func NewLogStore(w *wal.WAL) (*LogStore, error) {
+	s := &LogStore{
+		w:     w,
+		index: make(map[uint64]*wal.ChunkPosition),
+	}
+
+	r := w.NewReader()
+	for {
+		data, pos, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var log raft.Log
+		if err := s.codec.Decode(data, &log); err != nil {
+			return nil, fmt.Errorf("walraft: decoding log entry: %w", err)
+		}
+		s.index[log.Index] = pos
+		if s.firstIdx == 0 || log.Index < s.firstIdx {
+			s.firstIdx = log.Index
+		}
+		if log.Index > s.lastIdx {
+			s.lastIdx = log.Index
+		}
+	}
+	return s, nil
+}
+
+// FirstIndex returns the lowest Index currently stored, or 0 if the log is
+// empty.
+func (s *LogStore) FirstIndex() (uint64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.firstIdx, nil
+}
+
+// LastIndex returns the highest Index currently stored, or 0 if the log is
+// empty.
+func (s *LogStore) LastIndex() (uint64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.lastIdx, nil
+}
+
+// GetLog decodes the log entry at index into log. It returns
+// raft.ErrLogNotFound if index isn't currently in the store, whether
+// because it was never written or because DeleteRange has since removed
+// it.
+func (s *LogStore) GetLog(index uint64, log *raft.Log) error {
+	s.mu.RLock()
+	pos, ok := s.index[index]
+	s.mu.RUnlock()
+	if !ok {
+		return raft.ErrLogNotFound
+	}
+
+	data, err := s.w.Read(pos)
+	if err != nil {
+		return err
+	}
+	return s.codec.Decode(data, log)
+}
+
+// StoreLog is StoreLogs for a single entry.
+func (s *LogStore) StoreLog(log *raft.Log) error {
+	return s.StoreLogs([]*raft.Log{log})
+}
+
+// StoreLogs appends every entry in logs to the underlying WAL in a single
+// PendingWrites/WriteAll batch -- one disk write for the whole slice,
+// however many entries raft handed over together, instead of one wal.Write
+// (and one round of fsync bookkeeping) per entry.
+func (s *LogStore) StoreLogs(logs []*raft.Log) error {
+	if len(logs) == 0 {
+		return nil
+	}
+
+	for _, log := range logs {
+		data, err := s.codec.Encode(*log)
+		if err != nil {
+			return fmt.Errorf("walraft: encoding log entry at index %d: %w", log.Index, err)
+		}
+		if err := s.w.PendingWrites(data); err != nil {
+			return err
+		}
+	}
+	positions, err := s.w.WriteAll()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, log := range logs {
+		s.index[log.Index] = positions[i]
+		if s.firstIdx == 0 || log.Index < s.firstIdx {
+			s.firstIdx = log.Index
+		}
+		if log.Index > s.lastIdx {
+			s.lastIdx = log.Index
+		}
+	}
+	return nil
+}
+
+// DeleteRange removes every log between min and max, inclusive, from the
+// index -- GetLog no longer finds them, and FirstIndex/LastIndex no longer
+// count them -- and writes a wal.Delete tombstone for each. Like wal.Delete
+// itself, this doesn't touch anything on disk directly, since the
+// underlying WAL is append-only: a later Checkpoint on it reclaims the
+// space, the same as for any other caller of wal.Delete. Raft calls this
+// both to trim the log behind a new snapshot and to drop a conflicting
+// tail after a leader election, and neither needs the space back
+// immediately.
+func (s *LogStore) DeleteRange(min, max uint64) error {
+	s.mu.Lock()
+	var targets []*wal.ChunkPosition
+	for i := min; i <= max; i++ {
+		if pos, ok := s.index[i]; ok {
+			targets = append(targets, pos)
+			delete(s.index, i)
+		}
+	}
+	s.recomputeBoundsLocked()
+	s.mu.Unlock()
+
+	for _, pos := range targets {
+		if _, err := s.w.Delete(pos); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recomputeBoundsLocked recomputes firstIdx and lastIdx from what's left
+// in index, after DeleteRange has removed some entries from it. s.mu must
+// be held.
+func (s *LogStore) recomputeBoundsLocked() {
+	s.firstIdx, s.lastIdx = 0, 0
+	for index := range s.index {
+		if s.firstIdx == 0 || index < s.firstIdx {
+			s.firstIdx = index
+		}
+		if index > s.lastIdx {
+			s.lastIdx = index
+		}
+	}
+}
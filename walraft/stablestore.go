@@ -0,0 +1,161 @@
+package walraft
+
+import (
+	"encoding/binary"
+	"io"
+	"sync"
+
+	"github.com/rosedblabs/wal"
+)
+
+// stableMagic prefixes a StableStore marker's payload, the same
+// discriminator trick offsetMagic uses for CommitOffset, on the assumption
+// nothing else written to the backing WAL happens to start with these
+// exact bytes.
+const stableMagic = "WAL:STBL"
+
+// encodeStableMarker lays out key and val as a StableStore marker's
+// payload: stableMagic, then key's length and bytes, then val itself.
+func encodeStableMarker(key, val []byte) []byte {
+	raw := make([]byte, len(stableMagic)+4+len(key)+len(val))
+	n := copy(raw, stableMagic)
+	binary.BigEndian.PutUint32(raw[n:], uint32(len(key)))
+	n += 4
+	n += copy(raw[n:], key)
+	copy(raw[n:], val)
+	return raw
+}
+
+// decodeStableMarker reports whether raw is a StableStore marker
+// encodeStableMarker produced, and if so, the key and value it carries.
+func decodeStableMarker(raw []byte) (key, val []byte, ok bool) {
+	if len(raw) < len(stableMagic) || string(raw[:len(stableMagic)]) != stableMagic {
+		return nil, nil, false
+	}
+	raw = raw[len(stableMagic):]
+
+	if len(raw) < 4 {
+		return nil, nil, false
+	}
+	keyLen := binary.BigEndian.Uint32(raw[:4])
+	raw = raw[4:]
+
+	if uint64(len(raw)) < uint64(keyLen) {
+		return nil, nil, false
+	}
+	return raw[:keyLen], raw[keyLen:], true
+}
+
+// StableStore implements raft.StableStore on top of a *wal.WAL: every Set
+// appends a marker recording the key/value pair, and an in-memory map
+// mirrors raft.StableStore's key/value semantics -- later Sets for the
+// same key simply shadow earlier ones, with no compaction of the earlier
+// markers (a later Checkpoint on the backing WAL reclaims them, the same
+// as for any other record type).
+//
+// w must not be used for anything other than this StableStore's records --
+// Get's scan assumes every chunk in it that isn't a StableStore marker can
+// simply be skipped, which holds as long as nothing else writes chunks
+// that happen to start with stableMagic.
+type StableStore struct {
+	w *wal.WAL
+
+	mu      sync.Mutex
+	values  map[string][]byte
+	scanned bool
+}
+
+// NewStableStore returns a StableStore backed by w. Like wal.Offset, it
+// doesn't scan w's log until the first Get or GetUint64 that needs to.
+func NewStableStore(w *wal.WAL) *StableStore {
+	return &StableStore{
+		w:      w,
+		values: make(map[string][]byte),
+	}
+}
+
+// Set records val as key's current value.
+func (s *StableStore) Set(key, val []byte) error {
+	if _, err := s.w.Write(encodeStableMarker(key, val)); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.values[string(key)] = val
+	s.mu.Unlock()
+	return nil
+}
+
+// Get returns key's current value, or an empty slice if it was never Set,
+// per raft.StableStore's documented contract -- unlike wal.Offset's ok
+// return, a miss here is not an error.
+func (s *StableStore) Get(key []byte) ([]byte, error) {
+	if err := s.ensureScanned(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	val := s.values[string(key)]
+	s.mu.Unlock()
+	return val, nil
+}
+
+// SetUint64 is Set for a uint64 value, the encoding raft uses for its own
+// bookkeeping keys (CurrentTerm, LastVoteTerm, and so on).
+func (s *StableStore) SetUint64(key []byte, val uint64) error {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, val)
+	return s.Set(key, buf)
+}
+
+// GetUint64 is Get for a uint64 value. It returns 0, per raft.StableStore's
+// documented contract, if key was never Set or its value isn't a
+// well-formed 8-byte uint64.
+func (s *StableStore) GetUint64(key []byte) (uint64, error) {
+	val, err := s.Get(key)
+	if err != nil {
+		return 0, err
+	}
+	if len(val) != 8 {
+		return 0, nil
+	}
+	return binary.BigEndian.Uint64(val), nil
+}
+
+// ensureScanned is Offset's scan, adapted for StableStore: the first call
+// scans the whole log for every key's markers and merges them into
+// s.values, unconditionally overwriting anything already there the same
+// way Offset's merge does, on the same reasoning -- nothing else appends
+// to this WAL concurrently with the scan in practice, so the two can't
+// actually disagree.
+func (s *StableStore) ensureScanned() error {
+	s.mu.Lock()
+	scanned := s.scanned
+	s.mu.Unlock()
+	if scanned {
+		return nil
+	}
+
+	scannedValues := make(map[string][]byte)
+	r := s.w.NewReader()
+	for {
+		data, _, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if key, val, ok := decodeStableMarker(data); ok {
+			scannedValues[string(key)] = val
+		}
+	}
+
+	s.mu.Lock()
+	if !s.scanned {
+		for key, val := range scannedValues {
+			s.values[key] = val
+		}
+		s.scanned = true
+	}
+	s.mu.Unlock()
+	return nil
+}
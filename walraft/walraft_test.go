@@ -0,0 +1,191 @@
+package walraft
+
+import (
+	"os"
+	"testing"
+
+	"github.com/hashicorp/raft"
+	"github.com/rosedblabs/wal"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	_ raft.LogStore    = (*LogStore)(nil)
+	_ raft.StableStore = (*StableStore)(nil)
+)
+
+func openTestWAL(t *testing.T) *wal.WAL {
+	dir, err := os.MkdirTemp("", "walraft-test")
+	require.Nil(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	w, err := wal.Open(wal.WithDirPath(dir))
+	require.Nil(t, err)
+	t.Cleanup(func() { w.Close() })
+	return w
+}
+
+func TestLogStoreStoreLogsAndGetLogRoundTrip(t *testing.T) {
+	w := openTestWAL(t)
+	s, err := NewLogStore(w)
+	require.Nil(t, err)
+
+	logs := []*raft.Log{
+		{Index: 1, Term: 1, Type: raft.LogCommand, Data: []byte("one")},
+		{Index: 2, Term: 1, Type: raft.LogCommand, Data: []byte("two")},
+		{Index: 3, Term: 2, Type: raft.LogCommand, Data: []byte("three")},
+	}
+	require.Nil(t, s.StoreLogs(logs))
+
+	var got raft.Log
+	require.Nil(t, s.GetLog(2, &got))
+	require.Equal(t, uint64(2), got.Index)
+	require.Equal(t, uint64(1), got.Term)
+	require.Equal(t, []byte("two"), got.Data)
+
+	first, err := s.FirstIndex()
+	require.Nil(t, err)
+	require.Equal(t, uint64(1), first)
+
+	last, err := s.LastIndex()
+	require.Nil(t, err)
+	require.Equal(t, uint64(3), last)
+}
+
+func TestLogStoreStoreLogDelegatesToStoreLogs(t *testing.T) {
+	w := openTestWAL(t)
+	s, err := NewLogStore(w)
+	require.Nil(t, err)
+
+	require.Nil(t, s.StoreLog(&raft.Log{Index: 1, Term: 1, Data: []byte("solo")}))
+
+	var got raft.Log
+	require.Nil(t, s.GetLog(1, &got))
+	require.Equal(t, []byte("solo"), got.Data)
+}
+
+func TestLogStoreGetLogReturnsErrLogNotFound(t *testing.T) {
+	w := openTestWAL(t)
+	s, err := NewLogStore(w)
+	require.Nil(t, err)
+
+	var got raft.Log
+	require.ErrorIs(t, s.GetLog(99, &got), raft.ErrLogNotFound)
+}
+
+func TestLogStoreDeleteRangeRemovesEntriesFromView(t *testing.T) {
+	w := openTestWAL(t)
+	s, err := NewLogStore(w)
+	require.Nil(t, err)
+
+	require.Nil(t, s.StoreLogs([]*raft.Log{
+		{Index: 1, Data: []byte("a")},
+		{Index: 2, Data: []byte("b")},
+		{Index: 3, Data: []byte("c")},
+		{Index: 4, Data: []byte("d")},
+	}))
+
+	require.Nil(t, s.DeleteRange(2, 3))
+
+	var got raft.Log
+	require.ErrorIs(t, s.GetLog(2, &got), raft.ErrLogNotFound)
+	require.ErrorIs(t, s.GetLog(3, &got), raft.ErrLogNotFound)
+	require.Nil(t, s.GetLog(1, &got))
+	require.Nil(t, s.GetLog(4, &got))
+
+	first, err := s.FirstIndex()
+	require.Nil(t, err)
+	require.Equal(t, uint64(1), first)
+	last, err := s.LastIndex()
+	require.Nil(t, err)
+	require.Equal(t, uint64(4), last)
+}
+
+func TestLogStoreRecoversIndexFromExistingWAL(t *testing.T) {
+	dir, err := os.MkdirTemp("", "walraft-recover-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := wal.Open(wal.WithDirPath(dir))
+	require.Nil(t, err)
+	s, err := NewLogStore(w)
+	require.Nil(t, err)
+	require.Nil(t, s.StoreLogs([]*raft.Log{
+		{Index: 1, Data: []byte("a")},
+		{Index: 2, Data: []byte("b")},
+	}))
+	require.Nil(t, w.Close())
+
+	w2, err := wal.Open(wal.WithDirPath(dir))
+	require.Nil(t, err)
+	defer w2.Close()
+	s2, err := NewLogStore(w2)
+	require.Nil(t, err)
+
+	last, err := s2.LastIndex()
+	require.Nil(t, err)
+	require.Equal(t, uint64(2), last)
+
+	var got raft.Log
+	require.Nil(t, s2.GetLog(1, &got))
+	require.Equal(t, []byte("a"), got.Data)
+}
+
+func TestStableStoreSetGetRoundTrip(t *testing.T) {
+	w := openTestWAL(t)
+	s := NewStableStore(w)
+
+	require.Nil(t, s.Set([]byte("CurrentTerm"), []byte("term-value")))
+	val, err := s.Get([]byte("CurrentTerm"))
+	require.Nil(t, err)
+	require.Equal(t, []byte("term-value"), val)
+}
+
+func TestStableStoreGetOnMissingKeyReturnsEmptyNotError(t *testing.T) {
+	w := openTestWAL(t)
+	s := NewStableStore(w)
+
+	val, err := s.Get([]byte("missing"))
+	require.Nil(t, err)
+	require.Empty(t, val)
+}
+
+func TestStableStoreSetUint64GetUint64RoundTrip(t *testing.T) {
+	w := openTestWAL(t)
+	s := NewStableStore(w)
+
+	require.Nil(t, s.SetUint64([]byte("LastVoteTerm"), 42))
+	got, err := s.GetUint64([]byte("LastVoteTerm"))
+	require.Nil(t, err)
+	require.Equal(t, uint64(42), got)
+}
+
+func TestStableStoreGetUint64OnMissingKeyReturnsZero(t *testing.T) {
+	w := openTestWAL(t)
+	s := NewStableStore(w)
+
+	got, err := s.GetUint64([]byte("missing"))
+	require.Nil(t, err)
+	require.Equal(t, uint64(0), got)
+}
+
+func TestStableStoreRecoversFromExistingWALOnReopen(t *testing.T) {
+	dir, err := os.MkdirTemp("", "walraft-stable-recover-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := wal.Open(wal.WithDirPath(dir))
+	require.Nil(t, err)
+	s := NewStableStore(w)
+	require.Nil(t, s.Set([]byte("key"), []byte("value")))
+	require.Nil(t, w.Close())
+
+	w2, err := wal.Open(wal.WithDirPath(dir))
+	require.Nil(t, err)
+	defer w2.Close()
+	s2 := NewStableStore(w2)
+
+	val, err := s2.Get([]byte("key"))
+	require.Nil(t, err)
+	require.Equal(t, []byte("value"), val)
+}
@@ -0,0 +1,23 @@
+package walrepl
+
+import (
+	"hash/crc32"
+
+	"github.com/rosedblabs/wal"
+)
+
+// frameChecksumAlgo is the checksum algorithm every chunkFrame is stamped
+// with, independent of whatever Options.Checksum the server's own WAL
+// uses on disk -- a chunkFrame's checksum only needs to catch corruption
+// introduced by the network round trip, not reproduce the source WAL's
+// storage format.
+const frameChecksumAlgo = wal.ChecksumCRC32C
+
+// castagnoliTable backs frameChecksumAlgo, computed once and reused the
+// same way checksum.go's own castagnoliTable is.
+var castagnoliTable = crc32.MakeTable(crc32.Castagnoli)
+
+// checksumData computes data's checksum under frameChecksumAlgo.
+func checksumData(data []byte) uint32 {
+	return crc32.Checksum(data, castagnoliTable)
+}
@@ -0,0 +1,102 @@
+package walrepl
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/rosedblabs/wal"
+)
+
+// minBackoff and maxBackoff bound Client.Run's reconnect delay, the same
+// values Watcher's own DefaultWatcherOptions uses for its retry loop.
+const (
+	minBackoff = 10 * time.Millisecond
+	maxBackoff = time.Second
+)
+
+// Client connects to a Server, applies every chunk it relays to a local
+// wal.WAL with WriteRaw, and reconnects with backoff on any connection
+// error, resuming from the last chunk it successfully applied rather than
+// restarting from the beginning of the log.
+type Client struct {
+	wal  *wal.WAL
+	addr string
+
+	// last is the remote Server's position for the last chunk this Client
+	// applied -- the position embedded in the chunk frame it received,
+	// not the position WriteRaw returned, since the local WAL renumbers
+	// SegmentId, ChunkOffset, and Sequence independently of the remote
+	// one. It's what Run sends as its next resume request's From.
+	last    wal.ChunkPosition
+	hasLast bool
+}
+
+// NewClient returns a Client that applies records from addr to w.
+func NewClient(w *wal.WAL, addr string) *Client {
+	return &Client{wal: w, addr: addr}
+}
+
+// Run connects to c's Server and applies chunks to c's WAL until ctx is
+// done, reconnecting with exponential backoff whenever the connection
+// drops or a chunk fails to apply, and resuming each time from the last
+// chunk successfully applied. It only returns once ctx is done, reporting
+// ctx's error.
+func (c *Client) Run(ctx context.Context) error {
+	backoff := minBackoff
+	for {
+		if err := c.runOnce(ctx); err != nil {
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			if backoff *= 2; backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+			continue
+		}
+		backoff = minBackoff
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
+
+// runOnce dials addr once, streams chunks from it, and applies each to
+// c.wal until the connection fails or ctx is done. A nil return means ctx
+// is done; any other return is a connection or apply error worth backing
+// off and retrying for.
+func (c *Client) runOnce(ctx context.Context) error {
+	dialer := net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	req := resumeRequest{HasFrom: c.hasLast, From: c.last}
+	if err := writeResumeRequest(conn, req); err != nil {
+		return err
+	}
+
+	for {
+		frame, err := readChunkFrame(conn)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		if _, err := c.wal.WriteRaw(frame.Data, frame.Algo, frame.Checksum); err != nil {
+			return err
+		}
+		c.last, c.hasLast = frame.Position, true
+	}
+}
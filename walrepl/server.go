@@ -0,0 +1,81 @@
+package walrepl
+
+import (
+	"context"
+	"net"
+
+	"github.com/rosedblabs/wal"
+)
+
+// Server relays every chunk written to a wal.WAL to whichever Clients
+// connect to it, resuming each one from whatever position it asks for in
+// its resume request.
+type Server struct {
+	wal *wal.WAL
+}
+
+// NewServer returns a Server that relays w's records.
+func NewServer(w *wal.WAL) *Server {
+	return &Server{wal: w}
+}
+
+// Serve accepts connections on lis until it returns an error -- including
+// when lis is closed, which Serve reports as its own return, the same way
+// net/http.Server.Serve does -- handling each one in its own goroutine.
+func (s *Server) Serve(lis net.Listener) error {
+	for {
+		conn, err := lis.Accept()
+		if err != nil {
+			return err
+		}
+		go s.handle(conn)
+	}
+}
+
+// handle serves one client connection until it disconnects or a read or
+// write on it fails.
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+
+	req, err := readResumeRequest(conn)
+	if err != nil {
+		return
+	}
+
+	var tail *wal.TailReader
+	if req.HasFrom {
+		tail, err = s.wal.NewTailReaderWithStart(&req.From)
+		if err != nil {
+			return
+		}
+	} else {
+		tail = s.wal.NewTailReader()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		// Next blocks indefinitely once it catches up with the tail, so it
+		// needs conn's own disconnect to unblock it; a single throwaway
+		// byte read is enough to notice that without speaking any real
+		// protocol back from the client.
+		conn.Read(make([]byte, 1))
+		cancel()
+	}()
+
+	for {
+		data, pos, err := tail.Next(ctx)
+		if err != nil {
+			return
+		}
+		frame := chunkFrame{
+			Position: *pos,
+			Algo:     frameChecksumAlgo,
+			Checksum: checksumData(data),
+			Data:     data,
+		}
+		if err := writeChunkFrame(conn, frame); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package walrepl_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/rosedblabs/wal"
+	"github.com/rosedblabs/wal/walrepl"
+	"github.com/stretchr/testify/require"
+)
+
+func openTestWAL(t *testing.T, name string) *wal.WAL {
+	t.Helper()
+	dir, err := os.MkdirTemp("", name)
+	require.Nil(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	w, err := wal.Open(wal.WithDirPath(dir))
+	require.Nil(t, err)
+	t.Cleanup(func() { w.Close() })
+	return w
+}
+
+func readAll(t *testing.T, w *wal.WAL) []string {
+	t.Helper()
+	r := w.NewReader()
+	var got []string
+	for {
+		data, _, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		require.Nil(t, err)
+		got = append(got, string(data))
+	}
+	return got
+}
+
+func TestClientAppliesRecordsRelayedByTheServer(t *testing.T) {
+	primary := openTestWAL(t, "walrepl-primary-test")
+	replica := openTestWAL(t, "walrepl-replica-test")
+
+	_, err := primary.Write([]byte("one"))
+	require.Nil(t, err)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	t.Cleanup(func() { lis.Close() })
+
+	srv := walrepl.NewServer(primary)
+	go srv.Serve(lis)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	client := walrepl.NewClient(replica, lis.Addr().String())
+	go client.Run(ctx)
+
+	_, err = primary.Write([]byte("two"))
+	require.Nil(t, err)
+
+	require.Eventually(t, func() bool {
+		return len(readAll(t, replica)) == 2
+	}, 2*time.Second, 10*time.Millisecond)
+
+	require.Equal(t, []string{"one", "two"}, readAll(t, replica))
+}
+
+func TestClientResumesFromTheLastAppliedRemotePositionAfterAReconnect(t *testing.T) {
+	primary := openTestWAL(t, "walrepl-resume-primary-test")
+	replica := openTestWAL(t, "walrepl-resume-replica-test")
+
+	_, err := primary.Write([]byte("one"))
+	require.Nil(t, err)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	addr := lis.Addr().String()
+
+	srv := walrepl.NewServer(primary)
+	go srv.Serve(lis)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	client := walrepl.NewClient(replica, addr)
+	go client.Run(ctx)
+
+	require.Eventually(t, func() bool {
+		return len(readAll(t, replica)) == 1
+	}, 2*time.Second, 10*time.Millisecond)
+
+	// Kill the server's listener and everything it's already accepted,
+	// forcing the client to notice a broken connection and reconnect.
+	require.Nil(t, lis.Close())
+
+	lis2, err := net.Listen("tcp", addr)
+	require.Nil(t, err)
+	t.Cleanup(func() { lis2.Close() })
+	srv2 := walrepl.NewServer(primary)
+	go srv2.Serve(lis2)
+
+	_, err = primary.Write([]byte("two"))
+	require.Nil(t, err)
+
+	require.Eventually(t, func() bool {
+		return len(readAll(t, replica)) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.Equal(t, []string{"one", "two"}, readAll(t, replica))
+}
@@ -0,0 +1,303 @@
+// Package waltest provides fault-injecting wal.FS wrappers for exercising
+// crash recovery and degraded-storage behavior without actually crashing
+// the test process or running against real flaky hardware. FaultFS arms
+// one-shot faults (a short write, a torn tail write, a failed fsync, a
+// hard crash at a specific byte offset) precisely before the operation
+// meant to trip them; SimFS instead wraps any wal.FS with a continuous,
+// statistical per-operation latency, jitter, and error rate, for capacity
+// tests that want to emulate a slower or less reliable backend.
+package waltest
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/rosedblabs/wal"
+)
+
+// ErrSimulatedCrash is returned by every FaultFS operation once a
+// CrashAfter fault has fired. It stands in for the process having been
+// killed: nothing past the triggering write happened, including the
+// fsync or close a graceful shutdown would otherwise have made.
+var ErrSimulatedCrash = errors.New("waltest: simulated crash")
+
+// FaultFS wraps the real OS filesystem and lets a test inject faults into
+// writes and fsyncs made through it, keyed by file path. It implements
+// wal.FS, so pass it to wal.WithFS like any other FS.
+//
+// FaultFS is safe for concurrent use, but the faults it injects are meant
+// to be arranged before the operation that should trip them, not raced
+// against it.
+type FaultFS struct {
+	mu sync.Mutex
+
+	// shortWrites holds, per path, the byte count the next Write or
+	// WriteAt should be truncated to. One-shot: consumed on first use.
+	shortWrites map[string]int
+
+	// tornWrites holds, per path, the file size to truncate to right
+	// after the next Write or WriteAt completes -- as if a page the
+	// write's own return value claims landed never actually made it to
+	// disk. One-shot: consumed on first use.
+	tornWrites map[string]int64
+
+	// fsyncErrors holds, per path, how many more Sync calls should fail,
+	// and with what error.
+	fsyncErrors map[string]int
+	fsyncErr    map[string]error
+
+	// crashAfter holds, per path, the cumulative byte count at which a
+	// Write or WriteAt should trigger a crash: only the bytes up to the
+	// threshold are actually persisted, and every FaultFS operation
+	// thereafter returns ErrSimulatedCrash.
+	crashAfter map[string]int64
+	written    map[string]int64
+	crashed    bool
+}
+
+// NewFaultFS returns a FaultFS with no faults armed.
+func NewFaultFS() *FaultFS {
+	return &FaultFS{
+		shortWrites: make(map[string]int),
+		tornWrites:  make(map[string]int64),
+		fsyncErrors: make(map[string]int),
+		fsyncErr:    make(map[string]error),
+		crashAfter:  make(map[string]int64),
+		written:     make(map[string]int64),
+	}
+}
+
+// InjectShortWrite arranges for the next Write or WriteAt to path to
+// report only n bytes written (and to actually write only that many),
+// the same as a real short write syscall can do under disk pressure.
+func (fs *FaultFS) InjectShortWrite(path string, n int) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.shortWrites[path] = n
+}
+
+// InjectTornWrite arranges for the next Write or WriteAt to path to
+// truncate the file to keepBytes immediately afterward, as if everything
+// past keepBytes was still in the page cache -- not yet on disk -- when
+// the process died. keepBytes may fall in the middle of the write that
+// triggers it, the same as a real torn page can.
+func (fs *FaultFS) InjectTornWrite(path string, keepBytes int64) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.tornWrites[path] = keepBytes
+}
+
+// InjectFsyncError arranges for the next n calls to Sync on path to fail
+// with err, the same as Options.OnSyncError exists to react to.
+func (fs *FaultFS) InjectFsyncError(path string, n int, err error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.fsyncErrors[path] = n
+	fs.fsyncErr[path] = err
+}
+
+// CrashAfter arranges for the Write or WriteAt to path that pushes its
+// cumulative bytes written at or past afterBytes to persist only up to
+// that threshold, then for every later FaultFS operation -- on any path,
+// not just this one -- to fail with ErrSimulatedCrash, standing in for the
+// process being killed. Reopen a fresh WAL against the same directory
+// (through a new FaultFS, or a plain wal.Open) to test recovery.
+func (fs *FaultFS) CrashAfter(path string, afterBytes int64) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.crashAfter[path] = afterBytes
+}
+
+// Crashed reports whether a CrashAfter fault has fired.
+func (fs *FaultFS) Crashed() bool {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.crashed
+}
+
+func (fs *FaultFS) crashedErr() error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if fs.crashed {
+		return ErrSimulatedCrash
+	}
+	return nil
+}
+
+func (fs *FaultFS) OpenFile(name string, flag int, perm os.FileMode) (wal.File, error) {
+	if err := fs.crashedErr(); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return &faultFile{fs: fs, path: name, File: f}, nil
+}
+
+func (fs *FaultFS) Remove(name string) error {
+	if err := fs.crashedErr(); err != nil {
+		return err
+	}
+	return os.Remove(name)
+}
+
+func (fs *FaultFS) ReadDir(name string) ([]os.DirEntry, error) {
+	if err := fs.crashedErr(); err != nil {
+		return nil, err
+	}
+	return os.ReadDir(name)
+}
+
+func (fs *FaultFS) MkdirAll(path string, perm os.FileMode) error {
+	if err := fs.crashedErr(); err != nil {
+		return err
+	}
+	return os.MkdirAll(path, perm)
+}
+
+func (fs *FaultFS) SyncDir(path string) error {
+	if err := fs.crashedErr(); err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// faultWrite applies whatever fault is armed for path to a write of p at
+// off (off is -1 for a sequential Write; callers pass the file's current
+// position), returning the byte count and error the caller should report,
+// and the slice actually safe to persist to the underlying file.
+func (fs *FaultFS) faultWrite(path string, p []byte, off int64) (toPersist []byte, reportN int, reportErr error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if fs.crashed {
+		return nil, 0, ErrSimulatedCrash
+	}
+
+	toPersist = p
+	reportN = len(p)
+
+	if n, ok := fs.shortWrites[path]; ok {
+		delete(fs.shortWrites, path)
+		if n < len(p) {
+			toPersist = p[:n]
+			reportN = n
+		}
+	}
+
+	if threshold, ok := fs.crashAfter[path]; ok {
+		base := fs.written[path]
+		if off >= 0 {
+			base = off
+		}
+		total := base + int64(len(toPersist))
+		if total >= threshold {
+			keep := threshold - base
+			if keep < 0 {
+				keep = 0
+			}
+			if keep > int64(len(toPersist)) {
+				keep = int64(len(toPersist))
+			}
+			toPersist = toPersist[:keep]
+			fs.crashed = true
+		}
+	}
+	fs.written[path] += int64(len(toPersist))
+
+	return toPersist, reportN, nil
+}
+
+// faultSync reports whether the next Sync on path should fail, consuming
+// one use of InjectFsyncError if so.
+func (fs *FaultFS) faultSync(path string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if fs.crashed {
+		return ErrSimulatedCrash
+	}
+
+	remaining, ok := fs.fsyncErrors[path]
+	if !ok || remaining <= 0 {
+		return nil
+	}
+	fs.fsyncErrors[path] = remaining - 1
+	return fs.fsyncErr[path]
+}
+
+// applyTornWrite applies an armed InjectTornWrite fault for path to f
+// after a write has already landed, truncating it. Must run after the
+// underlying write, and without fs.mu held, since it does its own I/O.
+func (fs *FaultFS) applyTornWrite(path string, f *os.File) error {
+	fs.mu.Lock()
+	keepBytes, ok := fs.tornWrites[path]
+	if ok {
+		delete(fs.tornWrites, path)
+	}
+	fs.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	return f.Truncate(keepBytes)
+}
+
+// faultFile wraps an *os.File opened through a FaultFS, routing Write,
+// WriteAt, and Sync through it so injected faults apply.
+type faultFile struct {
+	*os.File
+	fs   *FaultFS
+	path string
+}
+
+func (f *faultFile) Write(p []byte) (int, error) {
+	pos, err := f.File.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	toPersist, reportN, err := f.fs.faultWrite(f.path, p, pos)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := f.File.Write(toPersist); err != nil {
+		return 0, err
+	}
+	if err := f.fs.applyTornWrite(f.path, f.File); err != nil {
+		return reportN, err
+	}
+	return reportN, nil
+}
+
+func (f *faultFile) WriteAt(p []byte, off int64) (int, error) {
+	toPersist, reportN, err := f.fs.faultWrite(f.path, p, off)
+	if err != nil {
+		return 0, err
+	}
+	if len(toPersist) > 0 {
+		if _, err := f.File.WriteAt(toPersist, off); err != nil {
+			return 0, err
+		}
+	}
+	if err := f.fs.applyTornWrite(f.path, f.File); err != nil {
+		return reportN, err
+	}
+	return reportN, nil
+}
+
+func (f *faultFile) Sync() error {
+	if err := f.fs.faultSync(f.path); err != nil {
+		return err
+	}
+	return f.File.Sync()
+}
@@ -0,0 +1,139 @@
+package waltest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rosedblabs/wal"
+	"github.com/stretchr/testify/require"
+)
+
+// activeSegmentPath returns the path of dir's only segment file, for
+// tests that need to arm a fault against it directly.
+func activeSegmentPath(t *testing.T, dir string) string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	require.Nil(t, err)
+	for _, e := range entries {
+		if filepath.Ext(e.Name()) == ".SEG" {
+			return filepath.Join(dir, e.Name())
+		}
+	}
+	t.Fatal("no segment file found")
+	return ""
+}
+
+func TestShortWriteOnActiveSegmentIsToleratedWithoutRepair(t *testing.T) {
+	dir, err := os.MkdirTemp("", "waltest-short-write-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	ffs := NewFaultFS()
+	w, err := wal.Open(wal.WithDirPath(dir), wal.WithFS(ffs))
+	require.Nil(t, err)
+
+	_, err = w.Write([]byte("0123456789"))
+	require.Nil(t, err)
+
+	segPath := activeSegmentPath(t, dir)
+	ffs.InjectShortWrite(segPath, 4)
+
+	// This write's header makes it to disk but its payload is truncated --
+	// a torn tail write. Deliberately not calling w.Close: a real torn
+	// write implies the process died before it got the chance to.
+	_, err = w.Write([]byte("abcdefghij"))
+	require.Nil(t, err)
+
+	w2, repaired, err := AssertRecoverable(dir, wal.WithDirPath(dir))
+	require.Nil(t, err)
+	defer w2.Close()
+	require.False(t, repaired, "a declared chunk length that no longer fits the file is dropped by Open's own scan, with nothing for Repair to do")
+
+	result, err := wal.Verify(dir)
+	require.Nil(t, err)
+	require.Equal(t, 1, result.ChunksScanned)
+}
+
+func TestTornWriteIsRecoverableWithRepair(t *testing.T) {
+	dir, err := os.MkdirTemp("", "waltest-torn-write-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	ffs := NewFaultFS()
+	w, err := wal.Open(wal.WithDirPath(dir), wal.WithFS(ffs))
+	require.Nil(t, err)
+
+	_, err = w.Write([]byte("0123456789"))
+	require.Nil(t, err)
+
+	segPath := activeSegmentPath(t, dir)
+	sizeBefore, err := os.Stat(segPath)
+	require.Nil(t, err)
+	ffs.InjectTornWrite(segPath, sizeBefore.Size()+4)
+
+	// Deliberately not calling w.Close: a real torn write implies the
+	// process died before it got the chance to.
+	_, err = w.Write([]byte("abcdefghij"))
+	require.Nil(t, err)
+
+	w2, repaired, err := AssertRecoverable(dir, wal.WithDirPath(dir))
+	require.Nil(t, err)
+	defer w2.Close()
+	require.True(t, repaired, "the header's declared length still fits the file, so Open's scan accepts it as the last chunk and only the CRC check catches it")
+
+	result, err := wal.Verify(dir)
+	require.Nil(t, err)
+	require.Equal(t, 1, result.ChunksScanned)
+}
+
+func TestInjectFsyncErrorFailsSync(t *testing.T) {
+	dir, err := os.MkdirTemp("", "waltest-fsync-error-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	ffs := NewFaultFS()
+	w, err := wal.Open(wal.WithDirPath(dir), wal.WithFS(ffs), wal.WithSync(true))
+	require.Nil(t, err)
+	defer w.Close()
+
+	segPath := activeSegmentPath(t, dir)
+	wantErr := os.ErrPermission
+	ffs.InjectFsyncError(segPath, 1, wantErr)
+
+	_, err = w.Write([]byte("hello"))
+	require.ErrorIs(t, err, wantErr)
+}
+
+func TestCrashAfterStopsWritesAndPreservesPriorData(t *testing.T) {
+	dir, err := os.MkdirTemp("", "waltest-crash-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	ffs := NewFaultFS()
+	w, err := wal.Open(wal.WithDirPath(dir), wal.WithFS(ffs))
+	require.Nil(t, err)
+
+	_, err = w.Write([]byte("kept"))
+	require.Nil(t, err)
+
+	segPath := activeSegmentPath(t, dir)
+	sizeBeforeCrash, err := os.Stat(segPath)
+	require.Nil(t, err)
+	ffs.CrashAfter(segPath, sizeBeforeCrash.Size()+2)
+
+	_, err = w.Write([]byte("this write should be cut short by the crash"))
+	require.Error(t, err)
+	require.True(t, ffs.Crashed())
+
+	_, err = w.Write([]byte("never happens"))
+	require.ErrorIs(t, err, ErrSimulatedCrash)
+
+	w2, err := wal.Open(wal.WithDirPath(dir), wal.WithRecoveryMode(wal.RecoverTail))
+	require.Nil(t, err)
+	defer w2.Close()
+
+	result, err := wal.Verify(dir)
+	require.Nil(t, err)
+	require.Equal(t, 1, result.ChunksScanned)
+}
@@ -0,0 +1,31 @@
+package waltest
+
+import "github.com/rosedblabs/wal"
+
+// AssertRecoverable opens the WAL at dirPath with opts, repairing it
+// first if the initial Open fails the way Options.RecoveryMode's doc
+// comment describes (a torn tail write or, with RecoverSkipCorrupt,
+// corruption anywhere in the active segment). It returns the reopened
+// WAL and whether Repair had to run, so a test can assert recovery
+// actually happened rather than Open having simply succeeded on its own.
+//
+// Call this after a fault armed through a FaultFS has fired, in place of
+// wal.Open, to check that the WAL comes back up and that whatever it
+// wrote before the fault is still readable with wal.Verify or
+// WAL.Reader.
+func AssertRecoverable(dirPath string, opts ...wal.Option) (w *wal.WAL, repaired bool, err error) {
+	w, err = wal.Open(opts...)
+	if err == nil {
+		return w, false, nil
+	}
+
+	if _, rerr := wal.Repair(dirPath, opts...); rerr != nil {
+		return nil, false, rerr
+	}
+
+	w, err = wal.Open(opts...)
+	if err != nil {
+		return nil, true, err
+	}
+	return w, true, nil
+}
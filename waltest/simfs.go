@@ -0,0 +1,157 @@
+package waltest
+
+import (
+	"errors"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rosedblabs/wal"
+)
+
+// ErrSimulatedFault is returned by a SimFS operation chosen at random, by
+// SimConfig.ErrorRate, to fail rather than reach the wrapped FS.
+var ErrSimulatedFault = errors.New("waltest: simulated fault")
+
+// SimConfig configures a SimFS.
+type SimConfig struct {
+	// Latency is added before every operation reaches the wrapped FS, to
+	// emulate a backend slower than local disk (network-attached storage,
+	// a remote block device).
+	Latency time.Duration
+
+	// Jitter adds a uniformly distributed extra delay in [0, Jitter) on
+	// top of Latency, so capacity tests see a spread of latencies instead
+	// of one fixed number.
+	Jitter time.Duration
+
+	// ErrorRate is the probability, in [0,1], that a given operation
+	// fails with ErrSimulatedFault instead of running at all.
+	ErrorRate float64
+}
+
+// SimFS wraps a wal.FS and injects configurable per-operation latency,
+// jitter, and a random error rate, so a capacity test can emulate a
+// backend slower or less reliable than local NVMe (EBS-like network
+// storage, a flaky filesystem) and verify a WAL's timeout and
+// backpressure handling under it. Unlike FaultFS, whose faults are
+// one-shot and arranged precisely before the operation meant to trip
+// them, SimFS's faults are statistical and apply continuously to every
+// operation for as long as it's wrapped.
+type SimFS struct {
+	fs  wal.FS
+	cfg SimConfig
+
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// NewSimFS returns a SimFS wrapping fs with cfg's latency, jitter, and
+// error rate applied to every operation.
+func NewSimFS(fs wal.FS, cfg SimConfig) *SimFS {
+	return &SimFS{fs: fs, cfg: cfg, rng: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+// delay sleeps for cfg's configured latency plus jitter, and reports
+// ErrSimulatedFault if this call was chosen to fail.
+func (s *SimFS) delay() error {
+	s.mu.Lock()
+	sleep := s.cfg.Latency
+	if s.cfg.Jitter > 0 {
+		sleep += time.Duration(s.rng.Int63n(int64(s.cfg.Jitter)))
+	}
+	fail := s.cfg.ErrorRate > 0 && s.rng.Float64() < s.cfg.ErrorRate
+	s.mu.Unlock()
+
+	if sleep > 0 {
+		time.Sleep(sleep)
+	}
+	if fail {
+		return ErrSimulatedFault
+	}
+	return nil
+}
+
+func (s *SimFS) OpenFile(name string, flag int, perm os.FileMode) (wal.File, error) {
+	if err := s.delay(); err != nil {
+		return nil, err
+	}
+	f, err := s.fs.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return &simFile{File: f, fs: s}, nil
+}
+
+func (s *SimFS) Remove(name string) error {
+	if err := s.delay(); err != nil {
+		return err
+	}
+	return s.fs.Remove(name)
+}
+
+func (s *SimFS) ReadDir(name string) ([]os.DirEntry, error) {
+	if err := s.delay(); err != nil {
+		return nil, err
+	}
+	return s.fs.ReadDir(name)
+}
+
+func (s *SimFS) MkdirAll(path string, perm os.FileMode) error {
+	if err := s.delay(); err != nil {
+		return err
+	}
+	return s.fs.MkdirAll(path, perm)
+}
+
+func (s *SimFS) SyncDir(path string) error {
+	if err := s.delay(); err != nil {
+		return err
+	}
+	return s.fs.SyncDir(path)
+}
+
+// simFile wraps a wal.File opened through a SimFS, routing every
+// operation back through its delay so reads and writes against an
+// already-open file pay the same simulated latency and error rate as
+// opening it did.
+type simFile struct {
+	wal.File
+	fs *SimFS
+}
+
+func (f *simFile) Read(p []byte) (int, error) {
+	if err := f.fs.delay(); err != nil {
+		return 0, err
+	}
+	return f.File.Read(p)
+}
+
+func (f *simFile) ReadAt(p []byte, off int64) (int, error) {
+	if err := f.fs.delay(); err != nil {
+		return 0, err
+	}
+	return f.File.ReadAt(p, off)
+}
+
+func (f *simFile) Write(p []byte) (int, error) {
+	if err := f.fs.delay(); err != nil {
+		return 0, err
+	}
+	return f.File.Write(p)
+}
+
+func (f *simFile) WriteAt(p []byte, off int64) (int, error) {
+	if err := f.fs.delay(); err != nil {
+		return 0, err
+	}
+	return f.File.WriteAt(p, off)
+}
+
+func (f *simFile) Sync() error {
+	if err := f.fs.delay(); err != nil {
+		return err
+	}
+	return f.File.Sync()
+}
@@ -0,0 +1,54 @@
+package waltest
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/rosedblabs/wal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSimFSDelaysEachWriteByAtLeastLatency(t *testing.T) {
+	dir, err := os.MkdirTemp("", "waltest-simfs-latency-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	sim := NewSimFS(wal.DefaultOptions.FS, SimConfig{Latency: 20 * time.Millisecond})
+	w, err := wal.Open(wal.WithDirPath(dir), wal.WithFS(sim))
+	require.Nil(t, err)
+	defer w.Close()
+
+	start := time.Now()
+	_, err = w.Write([]byte("hello"))
+	require.Nil(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+}
+
+func TestSimFSWithErrorRateOneFailsEveryOperation(t *testing.T) {
+	dir, err := os.MkdirTemp("", "waltest-simfs-errorrate-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	sim := NewSimFS(wal.DefaultOptions.FS, SimConfig{ErrorRate: 1})
+	_, err = wal.Open(wal.WithDirPath(dir), wal.WithFS(sim))
+	require.ErrorIs(t, err, ErrSimulatedFault)
+}
+
+func TestSimFSWithNoFaultsBehavesLikeTheWrappedFS(t *testing.T) {
+	dir, err := os.MkdirTemp("", "waltest-simfs-passthrough-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	sim := NewSimFS(wal.DefaultOptions.FS, SimConfig{})
+	w, err := wal.Open(wal.WithDirPath(dir), wal.WithFS(sim))
+	require.Nil(t, err)
+	defer w.Close()
+
+	pos, err := w.Write([]byte("hello"))
+	require.Nil(t, err)
+	data, err := w.Read(pos)
+	require.Nil(t, err)
+	assert.Equal(t, []byte("hello"), data)
+}
@@ -0,0 +1,63 @@
+package wal
+
+import "context"
+
+// watchBufferSize is how many undelivered positions Watch buffers per
+// subscriber before dropping the newest rather than blocking the writer
+// that produced it.
+const watchBufferSize = 256
+
+// Watch returns a channel that receives the position of every write this
+// WAL commits from this call onward, in order, until ctx is done, at
+// which point the channel is closed. It is the push-based counterpart to
+// polling a Reader in a loop: an in-process indexer or cache invalidator
+// that only cares about positions going forward, not about replaying
+// history or resuming after a restart, can read from it directly instead.
+//
+// "Commits" matches Write's own durability contract: if Options.Sync is
+// on, a position is only sent once that write's fsync -- whether inline,
+// a solo deferred one, or an Options.GroupCommit leader's -- has
+// returned; if Options.Sync is off, a position is sent as soon as Write
+// itself returns, the same point a polling reader would first be able to
+// see it. WriteAll delivers the same way, one position per chunk it
+// wrote, once the whole batch has succeeded.
+//
+// The returned channel is buffered; a consumer that falls far enough
+// behind a heavy writer has positions dropped rather than applying
+// backpressure to Write. A consumer that cares about not missing any
+// needs a way to resume from a known position instead -- see Watcher or
+// TailReader, both of which read back from the log itself rather than
+// relying on a live feed.
+func (w *WAL) Watch(ctx context.Context) <-chan *ChunkPosition {
+	ch := make(chan *ChunkPosition, watchBufferSize)
+
+	w.watchMu.Lock()
+	id := w.nextWatchID
+	w.nextWatchID++
+	w.watchers[id] = ch
+	w.watchMu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		w.watchMu.Lock()
+		delete(w.watchers, id)
+		w.watchMu.Unlock()
+		close(ch)
+	}()
+
+	return ch
+}
+
+// notifyWatchers sends pos to every channel registered with Watch,
+// dropping it for any subscriber whose buffer is already full instead of
+// blocking the writer that produced it.
+func (w *WAL) notifyWatchers(pos *ChunkPosition) {
+	w.watchMu.Lock()
+	defer w.watchMu.Unlock()
+	for _, ch := range w.watchers {
+		select {
+		case ch <- pos:
+		default:
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package wal
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchReceivesEveryCommittedWrite(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-watch-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := w.Watch(ctx)
+
+	pos, err := w.Write([]byte("hello"))
+	require.Nil(t, err)
+
+	select {
+	case got := <-ch:
+		require.Equal(t, pos, got)
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for Watch to deliver a position")
+	}
+}
+
+func TestWatchClosesItsChannelWhenContextIsDone(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-watch-close-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := w.Watch(ctx)
+	cancel()
+
+	select {
+	case _, ok := <-ch:
+		require.False(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for Watch's channel to close")
+	}
+}
+
+func TestWatchDeliversPositionsFromWriteAll(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-watch-writeall-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := w.Watch(ctx)
+
+	w.PendingWrites([]byte("one"))
+	w.PendingWrites([]byte("two"))
+	positions, err := w.WriteAll()
+	require.Nil(t, err)
+	require.Len(t, positions, 2)
+
+	for _, want := range positions {
+		select {
+		case got := <-ch:
+			require.Equal(t, want, got)
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for Watch to deliver a WriteAll position")
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package wal
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// WatcherOptions configures a Watcher.
+type WatcherOptions struct {
+	// From resumes tailing after this position instead of from the start
+	// of the log. Leave nil to start from the beginning.
+	From *ChunkPosition
+
+	// MinBackoff is the initial delay a Watcher waits before retrying a
+	// read that found a partial chunk at the tail of the active segment.
+	MinBackoff time.Duration
+
+	// MaxBackoff caps the exponential backoff applied between retries.
+	MaxBackoff time.Duration
+}
+
+// DefaultWatcherOptions are the WatcherOptions used for any zero-valued
+// fields passed to NewWatcher.
+var DefaultWatcherOptions = WatcherOptions{
+	MinBackoff: 10 * time.Millisecond,
+	MaxBackoff: time.Second,
+}
+
+// OnRecord is called by a Watcher for every record it reads, in order.
+// Returning a non-nil error stops the Watcher.
+type OnRecord func(pos *ChunkPosition, data []byte) error
+
+// Watcher tails a live WAL, delivering every record written to it -- past
+// and future -- to an OnRecord callback in order, surviving segment
+// rotations along the way. It is modeled on Prometheus's wlog.Watcher and
+// is meant for replication and change-data-capture use cases built on top
+// of a WAL.
+//
+// A Watcher reads with the same Reader the WAL exposes for normal
+// iteration; it adds retry-with-backoff around reads that land on a
+// partial chunk at the tail of the active segment, so a reader that's
+// caught up to a writer still in the middle of a Write doesn't see that as
+// an error.
+//
+// A Watcher stops on the first error other than a caught-up-with-the-tail
+// condition, whether from resolving its starting position, from Reader, or
+// from OnRecord; that error is available from Err after done is closed
+// (Stop returns once that has happened). A Watcher that stops this way
+// does not resume on its own; callers that want to keep tailing need to
+// check Err after Stop (or after OnRecord's caller otherwise learns the
+// Watcher exited) and start a new one, typically from Position.
+type Watcher struct {
+	wal      *WAL
+	opts     WatcherOptions
+	onRecord OnRecord
+
+	mu          sync.Mutex
+	recordsRead uint64
+	bytesRead   int64
+	lastPos     *ChunkPosition
+	err         error
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewWatcher returns a Watcher over w. Call Start to begin tailing in the
+// background.
+func NewWatcher(w *WAL, opts WatcherOptions, onRecord OnRecord) *Watcher {
+	if opts.MinBackoff <= 0 {
+		opts.MinBackoff = DefaultWatcherOptions.MinBackoff
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = DefaultWatcherOptions.MaxBackoff
+	}
+	return &Watcher{
+		wal:      w,
+		opts:     opts,
+		onRecord: onRecord,
+		lastPos:  opts.From,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start begins tailing in a background goroutine. It returns immediately;
+// call Stop to shut the Watcher down.
+func (wr *Watcher) Start() {
+	go wr.run()
+}
+
+// Stop signals the Watcher to stop and blocks until its goroutine exits.
+func (wr *Watcher) Stop() {
+	close(wr.stop)
+	<-wr.done
+}
+
+// RecordsRead returns the number of records delivered to OnRecord so far.
+func (wr *Watcher) RecordsRead() uint64 {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+	return wr.recordsRead
+}
+
+// BytesRead returns the total number of payload bytes delivered to
+// OnRecord so far. Comparing it against the active-segment-size gauge
+// reported by a Collector gives an estimate of read lag in bytes.
+func (wr *Watcher) BytesRead() int64 {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+	return wr.bytesRead
+}
+
+// Position returns the position of the last record delivered to OnRecord,
+// suitable for passing as WatcherOptions.From to resume tailing later.
+func (wr *Watcher) Position() *ChunkPosition {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+	return wr.lastPos
+}
+
+// Err returns the error that stopped the Watcher, if it stopped on its
+// own rather than via Stop. It is only meaningful after done is closed
+// (i.e. after Stop returns, or once the caller otherwise observes the
+// Watcher has exited); it is nil if the Watcher is still running, was
+// stopped via Stop, or was stopped by OnRecord returning a non-nil error
+// (which Err does not report, since the caller already has it).
+func (wr *Watcher) Err() error {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+	return wr.err
+}
+
+func (wr *Watcher) run() {
+	defer close(wr.done)
+
+	var reader *Reader
+	var err error
+	if wr.lastPos != nil {
+		reader, err = wr.wal.NewReaderWithStart(wr.lastPos)
+	} else {
+		reader = wr.wal.NewReader()
+	}
+	if err != nil {
+		wr.setErr(err)
+		return
+	}
+
+	backoff := wr.opts.MinBackoff
+	for {
+		select {
+		case <-wr.stop:
+			return
+		default:
+		}
+
+		data, pos, err := reader.Next()
+		if err == nil {
+			backoff = wr.opts.MinBackoff
+			if err := wr.onRecord(pos, data); err != nil {
+				return
+			}
+			wr.mu.Lock()
+			wr.recordsRead++
+			wr.bytesRead += int64(len(data))
+			wr.lastPos = pos
+			wr.mu.Unlock()
+			wr.wal.options.MetricsCollector.IncRecordsRead()
+			wr.reportReadLag(pos)
+			continue
+		}
+		if errors.Is(err, io.EOF) {
+			// Nothing new yet: this is either a chunk that's still being
+			// written at the tail of the active segment, or the true
+			// live tail. Back off and retry instead of treating it as an
+			// error; a later Next call picks up wherever the writer (or
+			// segment rotation) left off.
+			select {
+			case <-time.After(backoff):
+			case <-wr.stop:
+				return
+			}
+			if backoff *= 2; backoff > wr.opts.MaxBackoff {
+				backoff = wr.opts.MaxBackoff
+			}
+			continue
+		}
+		// A terminal error: a real I/O failure, or corruption (ErrInvalidCRC)
+		// reported by Reader.Next. Surface it via Err rather than exiting
+		// silently, so a caller watching for the Watcher to stop can tell a
+		// real failure apart from a clean Stop.
+		wr.setErr(err)
+		return
+	}
+}
+
+func (wr *Watcher) setErr(err error) {
+	wr.mu.Lock()
+	wr.err = err
+	wr.mu.Unlock()
+}
+
+// reportReadLag reports how far behind the active segment's current size
+// pos is, as an estimate of how much unread data remains.
+func (wr *Watcher) reportReadLag(pos *ChunkPosition) {
+	segs := wr.wal.orderedSegments()
+	if len(segs) == 0 {
+		return
+	}
+	active := segs[len(segs)-1]
+	if pos.SegmentId != active.id {
+		return
+	}
+	lag := active.Size() - (pos.ChunkOffset + int64(chunkHeaderSize) + int64(pos.ChunkSize))
+	if lag < 0 {
+		lag = 0
+	}
+	wr.wal.options.MetricsCollector.SetReadLagBytes(lag)
+}
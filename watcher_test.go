@@ -0,0 +1,70 @@
+package wal
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatcherTailsLiveWrites(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-watcher-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"))
+	require.Nil(t, err)
+	defer w.Close()
+
+	received := make(chan string, 16)
+	watcher := NewWatcher(w, WatcherOptions{}, func(pos *ChunkPosition, data []byte) error {
+		received <- string(data)
+		return nil
+	})
+	watcher.Start()
+	defer watcher.Stop()
+
+	_, err = w.Write([]byte("hello"))
+	require.Nil(t, err)
+
+	select {
+	case got := <-received:
+		assert.Equal(t, "hello", got)
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for watcher to deliver a record")
+	}
+	assert.Equal(t, uint64(1), watcher.RecordsRead())
+}
+
+func TestWatcherSurfacesTerminalError(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-watcher-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"))
+	require.Nil(t, err)
+	defer w.Close()
+
+	pos, err := w.Write([]byte("hello"))
+	require.Nil(t, err)
+
+	// Corrupt the chunk's CRC so Reader.Next reports ErrInvalidCRC instead
+	// of the "nothing new yet" io.EOF a Watcher otherwise retries past.
+	corrupted := w.segmentByID(pos.SegmentId)
+	_, err = corrupted.fd.WriteAt([]byte{0, 0, 0, 0}, segmentHeaderSize+4)
+	require.Nil(t, err)
+
+	watcher := NewWatcher(w, WatcherOptions{}, func(pos *ChunkPosition, data []byte) error {
+		return nil
+	})
+	watcher.Start()
+
+	select {
+	case <-watcher.done:
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for watcher to stop on corruption")
+	}
+	assert.ErrorIs(t, watcher.Err(), ErrInvalidCRC)
+}
@@ -0,0 +1,100 @@
+package wal
+
+import (
+	"fmt"
+	"time"
+)
+
+// Mode identifies the durability semantics a WAL was opened with.
+type Mode byte
+
+const (
+	// ModeAhead is the default, durable write-ahead mode: Write honors
+	// Options.Sync and Options.BytesPerSync exactly as before this mode
+	// was introduced.
+	ModeAhead Mode = iota
+
+	// ModeBehind is write-behind mode: Write never fsyncs inline,
+	// regardless of Options.Sync. Data only reaches disk on an explicit
+	// Sync call, on segment rotation, or via the background flusher
+	// started by OpenWBL when WithFlushInterval or WithBytesPerSync is
+	// set. It is intended for out-of-order or backfill ingestion where
+	// per-write durability is not required.
+	ModeBehind
+)
+
+// String implements fmt.Stringer.
+func (m Mode) String() string {
+	switch m {
+	case ModeAhead:
+		return "ahead"
+	case ModeBehind:
+		return "behind"
+	default:
+		return fmt.Sprintf("Mode(%d)", byte(m))
+	}
+}
+
+// modeByte is the offset, within a segment file's header, of the byte that
+// records the Mode the segment was created under. Open checks this byte
+// against the Options.Mode the log is being opened with and refuses to
+// proceed on a mismatch, so a write-ahead log and a write-behind log
+// sharing a directory can never have their segments mixed up, provided
+// they use distinct SegmentFileExt values as OpenWBL requires.
+const modeByte = 5
+
+// ErrModeMismatch is returned by Open when a segment's header records a
+// different Mode than the one the log is being opened with.
+var ErrModeMismatch = fmt.Errorf("wal: segment was created in a different mode than the log is being opened with")
+
+// OpenWBL opens a write-behind log: a WAL using the same segment machinery
+// as Open, but with relaxed durability for out-of-order or backfill
+// ingestion. It is equivalent to calling Open with WithMode(ModeBehind)
+// prepended to opts, and it additionally starts the background flusher
+// whenever WithFlushInterval or WithBytesPerSync is set, since Write
+// itself never fsyncs in ModeBehind.
+//
+// Callers that need a durable, in-order log and a relaxed out-of-order log
+// over the same data should open both in the same directory with distinct
+// SegmentFileExt values: one via Open, the other via OpenWBL.
+func OpenWBL(opts ...Option) (*WAL, error) {
+	opts = append([]Option{WithMode(ModeBehind)}, opts...)
+	w, err := Open(opts...)
+	if err != nil {
+		return nil, err
+	}
+	if w.options.FlushInterval > 0 || w.options.BytesPerSync > 0 {
+		w.startFlusher(w.options.FlushInterval)
+	}
+	return w, nil
+}
+
+// startFlusher runs in its own goroutine for the lifetime of a WAL,
+// syncing the active segment on whichever comes first: interval (if
+// positive) elapsing, or write's BytesPerSync nudge via w.flushReq. Either
+// way, data written without an explicit Sync is bounded in how long it can
+// stay unflushed. It exits once the WAL is closed. OpenWBL starts it for
+// Options.FlushInterval (and, in ModeBehind, BytesPerSync); Open starts it
+// for Options.SyncInterval.
+func (w *WAL) startFlusher(interval time.Duration) {
+	w.flusherDone = make(chan struct{})
+	w.flushReq = make(chan struct{}, 1)
+	go func() {
+		var tickerC <-chan time.Time
+		if interval > 0 {
+			ticker := w.options.Clock.NewTicker(interval)
+			defer ticker.Stop()
+			tickerC = ticker.C()
+		}
+		for {
+			select {
+			case <-tickerC:
+				_, _ = w.Sync()
+			case <-w.flushReq:
+				_, _ = w.Sync()
+			case <-w.flusherDone:
+				return
+			}
+		}
+	}()
+}
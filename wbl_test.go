@@ -0,0 +1,95 @@
+package wal
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenWBLWritesAndReads(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-wbl-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := OpenWBL(WithDirPath(dir), WithSegmentFileExt(".WBL"))
+	require.Nil(t, err)
+	defer w.Close()
+
+	pos, err := w.Write([]byte("hello"))
+	require.Nil(t, err)
+
+	data, err := w.Read(pos)
+	require.Nil(t, err)
+	assert.Equal(t, []byte("hello"), data)
+}
+
+func TestOpenWBLRejectsModeMismatch(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-wbl-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".WBL"))
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+
+	_, err = OpenWBL(WithDirPath(dir), WithSegmentFileExt(".WBL"))
+	assert.ErrorIs(t, err, ErrModeMismatch)
+}
+
+func TestOpenWBLWriteDoesNotSyncInline(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-wbl-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	// Sync is ignored in ModeBehind: Write must return without fsyncing.
+	w, err := OpenWBL(WithDirPath(dir), WithSegmentFileExt(".WBL"), WithSync(true))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("hello"))
+	require.Nil(t, err)
+	assert.NotZero(t, w.bytesSinceSync)
+}
+
+func TestOpenWBLFlushIntervalSyncsInBackground(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-wbl-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := OpenWBL(WithDirPath(dir), WithSegmentFileExt(".WBL"), WithFlushInterval(10*time.Millisecond))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("hello"))
+	require.Nil(t, err)
+
+	require.Eventually(t, func() bool {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		return w.bytesSinceSync == 0
+	}, time.Second, time.Millisecond)
+}
+
+func TestOpenWBLBytesPerSyncSyncsInBackground(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-wbl-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	// No FlushInterval: only crossing BytesPerSync should trigger the
+	// background flusher.
+	w, err := OpenWBL(WithDirPath(dir), WithSegmentFileExt(".WBL"), WithBytesPerSync(4))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("hello"))
+	require.Nil(t, err)
+
+	require.Eventually(t, func() bool {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		return w.bytesSinceSync == 0
+	}, time.Second, time.Millisecond)
+}
@@ -0,0 +1,46 @@
+package wal
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestSyncWithoutGroupCommitDoesNotHoldTheLockForFsync exercises the
+// contention Write used to have with itself: with Options.Sync true and
+// GroupCommit false, Write used to call fsync inline while still holding
+// w.mu, so a second concurrent Write couldn't even append its own data
+// until the first one's entire fsync had finished. Each Write now fsyncs
+// its own segment (via deferSolo) after releasing the lock, so two
+// concurrent Writes' fsyncs run alongside each other instead of back to
+// back.
+func TestSyncWithoutGroupCommitDoesNotHoldTheLockForFsync(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-solo-sync-lock-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	delay := 150 * time.Millisecond
+	w, err := Open(WithDirPath(dir), WithFS(delaySyncFS{delay: &delay}), WithSync(true))
+	require.Nil(t, err)
+	defer w.Close()
+
+	const writers = 2
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := w.Write([]byte(fmt.Sprintf("record-%d", i)))
+			require.Nil(t, err)
+		}(i)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	require.Less(t, elapsed, delay+delay/2)
+}
@@ -0,0 +1,101 @@
+package wal
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteAllBatchesIntoOneSegment(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-writeall-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"))
+	require.Nil(t, err)
+	defer w.Close()
+
+	want := []string{"first", "second", "third"}
+	for _, s := range want {
+		w.PendingWrites([]byte(s))
+	}
+	positions, err := w.WriteAll()
+	require.Nil(t, err)
+	require.Equal(t, len(want), len(positions))
+
+	for i, pos := range positions {
+		data, err := w.Read(pos)
+		require.Nil(t, err)
+		assert.Equal(t, want[i], string(data))
+	}
+}
+
+func TestWriteAllSplitsBatchAcrossRotatedSegments(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-writeall-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"), WithSegmentSize(64))
+	require.Nil(t, err)
+	defer w.Close()
+
+	var want [][]byte
+	for i := 0; i < 20; i++ {
+		data := []byte{byte(i)}
+		want = append(want, data)
+		w.PendingWrites(data)
+	}
+	positions, err := w.WriteAll()
+	require.Nil(t, err)
+	require.Equal(t, len(want), len(positions))
+
+	segIDs := map[SegmentID]bool{}
+	for i, pos := range positions {
+		segIDs[pos.SegmentId] = true
+		data, err := w.Read(pos)
+		require.Nil(t, err)
+		assert.Equal(t, want[i], data)
+	}
+	assert.Greater(t, len(segIDs), 1, "enough pending writes to have rotated across segments")
+}
+
+func TestWriteAllRejectsEmptyRecordWithoutWritingAny(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-writeall-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentFileExt(".SEG"))
+	require.Nil(t, err)
+	defer w.Close()
+
+	w.PendingWrites([]byte("first"))
+	w.PendingWrites(nil)
+	positions, err := w.WriteAll()
+	assert.Equal(t, ErrEmptyRecord, err)
+	assert.Empty(t, positions)
+
+	_, err = w.Write([]byte("still usable"))
+	require.Nil(t, err)
+}
+
+func TestWriteAllOnCustomFSFallsBackToOneByOne(t *testing.T) {
+	w, err := OpenInMemory()
+	require.Nil(t, err)
+	defer w.Close()
+
+	want := []string{"alpha", "beta", "gamma"}
+	for _, s := range want {
+		w.PendingWrites([]byte(s))
+	}
+	positions, err := w.WriteAll()
+	require.Nil(t, err)
+	require.Equal(t, len(want), len(positions))
+
+	for i, pos := range positions {
+		data, err := w.Read(pos)
+		require.Nil(t, err)
+		assert.Equal(t, want[i], string(data))
+	}
+}
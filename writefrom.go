@@ -0,0 +1,158 @@
+package wal
+
+import (
+	"fmt"
+	"io"
+)
+
+// writeFromChunkSize bounds how much of the io.Reader WriteFrom buffers in
+// memory for any one physical chunk it writes: size can be arbitrarily
+// large without WriteFrom ever holding more of it in memory at once than
+// this, splitting the rest into however many additional chunks it takes.
+const writeFromChunkSize = 4 * MB
+
+// writeFromMore and writeFromLast tag every physical chunk WriteFrom
+// splits a record into, so ReadTo knows when to stop chaining reads
+// together: a record at or under writeFromChunkSize is a single chunk
+// tagged writeFromLast.
+const (
+	writeFromMore byte = iota
+	writeFromLast
+)
+
+// ErrStreamSizeMismatch is returned by WriteFrom when r runs out before
+// yielding size bytes, and by ReadTo when it reaches the end of the log
+// without ever finding the writeFromLast chunk a complete WriteFrom call
+// would have left behind -- most likely because a crash landed partway
+// through the original WriteFrom, the same way an incomplete Batch is
+// left on disk by a crash partway through Commit.
+var ErrStreamSizeMismatch = fmt.Errorf("wal: stream ended before the declared size was reached")
+
+// WriteFrom is Write for a record too large to ever hold in memory as a
+// single []byte: it reads exactly size bytes from r and streams them into
+// the WAL as a chain of chunks of at most writeFromChunkSize each,
+// instead of one chunk holding all of it, returning the position of the
+// chain's first chunk. Read it back with ReadTo, not Read or
+// Reader.Next, which would otherwise misread a continuation chunk as an
+// unrelated record of its own -- the same caveat WriteWithFlags's doc
+// comment explains for its own framing byte.
+//
+// WriteFrom holds w.mu for the whole call, the same tradeoff WriteAll's
+// single-lock batch makes, and, like WriteAll, compresses and encrypts
+// each chunk independently rather than r's stream as a whole, so
+// Options.Compression sees less context per chunk than Write gets for an
+// equivalently-sized in-memory record. It always writes synchronously,
+// independent of Options.Pipelined, which has no queued position to hand
+// back here without holding the very payload in memory this exists to
+// avoid.
+//
+// If r returns an error, or fewer than size bytes before io.EOF,
+// WriteFrom returns ErrStreamSizeMismatch (or r's own error) and the
+// position of whatever chunks it already wrote -- durable, but an
+// incomplete chain ReadTo will refuse to reassemble.
+func (w *WAL) WriteFrom(r io.Reader, size int64) (pos *ChunkPosition, err error) {
+	if size <= 0 {
+		return nil, ErrEmptyRecord
+	}
+	if w.options.MaxRecordSize > 0 && size > w.options.MaxRecordSize {
+		return nil, &ErrRecordTooLarge{Size: int(size), MaxSize: w.options.MaxRecordSize}
+	}
+
+	chunkSize := int64(writeFromChunkSize)
+	if w.options.MaxRecordSize > 0 && w.options.MaxRecordSize-1 < chunkSize {
+		chunkSize = w.options.MaxRecordSize - 1
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return nil, ErrClosed
+	}
+	if w.options.ReadOnly {
+		return nil, ErrReadOnly
+	}
+
+	frame := make([]byte, 1+chunkSize)
+	remaining := size
+	for remaining > 0 {
+		n := chunkSize
+		if remaining < n {
+			n = remaining
+		}
+		if _, err := io.ReadFull(r, frame[1:1+n]); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return pos, ErrStreamSizeMismatch
+			}
+			return pos, err
+		}
+		if n == remaining {
+			frame[0] = writeFromLast
+		} else {
+			frame[0] = writeFromMore
+		}
+
+		chunkPos, _, err := w.write(frame[:1+n], true)
+		if err != nil {
+			return pos, err
+		}
+		if pos == nil {
+			pos = chunkPos
+		}
+		remaining -= n
+	}
+
+	// write's own per-chunk sync decisions assume one chunk per logical
+	// record; passing allowGroupCommit true above suppresses all of them
+	// (GroupCommit doesn't apply -- w.mu is already held for the whole
+	// call, so there's nothing else to batch with) so that, mirroring
+	// writeBatch, the chain gets exactly one fsync here instead of one
+	// per chunk.
+	if w.options.Mode == ModeAhead && w.options.Sync {
+		if err := w.sync(); err != nil {
+			return pos, err
+		}
+	}
+
+	w.notifyWatchers(pos)
+	return pos, nil
+}
+
+// ReadTo reads back a record WriteFrom wrote, starting at pos -- which
+// must be the position WriteFrom returned, not one of the continuation
+// chunks it chained after it -- and streams its payload into w one
+// physical chunk at a time, the same way WriteFrom wrote it, never
+// holding more of the record in memory than a single chunk. It returns
+// the number of bytes written to w.
+//
+// Mirroring WriteFrom's own tradeoff, each chunk is decompressed and
+// decrypted independently; a w.Write error, or any error reading a chunk,
+// stops early and returns what was written to w so far alongside the
+// error.
+func (w *WAL) ReadTo(dst io.Writer, pos *ChunkPosition) (n int64, err error) {
+	r, err := w.NewReaderWithRange(pos, nil)
+	if err != nil {
+		return 0, err
+	}
+	for {
+		decoded, _, err := r.nextDecoded()
+		if err != nil {
+			if err == io.EOF {
+				return n, ErrStreamSizeMismatch
+			}
+			return n, err
+		}
+		frame, _ := stripTimestamp(w.options.Timestamps, decoded)
+		if len(frame) == 0 {
+			return n, ErrStreamSizeMismatch
+		}
+
+		written, err := dst.Write(frame[1:])
+		n += int64(written)
+		if err != nil {
+			return n, err
+		}
+		if frame[0] == writeFromLast {
+			return n, nil
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package wal
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteFromRoundTrip(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-writefrom-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	data := []byte("hello, streaming world")
+	pos, err := w.WriteFrom(bytes.NewReader(data), int64(len(data)))
+	require.Nil(t, err)
+
+	var buf bytes.Buffer
+	n, err := w.ReadTo(&buf, pos)
+	require.Nil(t, err)
+	assert.EqualValues(t, len(data), n)
+	assert.Equal(t, data, buf.Bytes())
+}
+
+func TestWriteFromSpansMultipleChunksAndSegments(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-writefrom-span-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	// MaxRecordSize clamps writeFromChunkSize down to 9, splitting this
+	// 10-byte record into two physical chunks; SegmentSize is small
+	// enough that the second chunk lands in a new segment.
+	w, err := Open(WithDirPath(dir), WithMaxRecordSize(10), WithSegmentSize(20))
+	require.Nil(t, err)
+	defer w.Close()
+
+	data := []byte("0123456789")
+	pos, err := w.WriteFrom(bytes.NewReader(data), int64(len(data)))
+	require.Nil(t, err)
+
+	require.Greater(t, w.activeSegment.id, pos.SegmentId)
+
+	var buf bytes.Buffer
+	n, err := w.ReadTo(&buf, pos)
+	require.Nil(t, err)
+	assert.EqualValues(t, len(data), n)
+	assert.Equal(t, data, buf.Bytes())
+}
+
+func TestWriteFromRejectsOversizedRecord(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-writefrom-oversized-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithMaxRecordSize(4))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.WriteFrom(bytes.NewReader([]byte("too big")), 7)
+	require.NotNil(t, err)
+	var tooLarge *ErrRecordTooLarge
+	assert.ErrorAs(t, err, &tooLarge)
+}
+
+func TestWriteFromShortReaderReturnsStreamSizeMismatch(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-writefrom-short-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithMaxRecordSize(10))
+	require.Nil(t, err)
+	defer w.Close()
+
+	// MaxRecordSize clamps the first chunk to 9 bytes, which succeeds;
+	// the reader then runs dry before the declared 10th byte.
+	pos, err := w.WriteFrom(bytes.NewReader([]byte("123456789")), 10)
+	require.ErrorIs(t, err, ErrStreamSizeMismatch)
+	require.NotNil(t, pos)
+
+	var buf bytes.Buffer
+	_, err = w.ReadTo(&buf, pos)
+	require.ErrorIs(t, err, ErrStreamSizeMismatch)
+}
+
+func TestWriteFromReturnsUnderlyingReaderError(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-writefrom-readerr-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	boom := io.ErrClosedPipe
+	_, err = w.WriteFrom(errReader{err: boom}, 5)
+	require.ErrorIs(t, err, boom)
+}
+
+type errReader struct{ err error }
+
+func (r errReader) Read([]byte) (int, error) { return 0, r.err }
@@ -0,0 +1,90 @@
+package wal
+
+import "fmt"
+
+// ErrTailMismatch is returned by WriteIfTail when expected no longer
+// matches the WAL's actual tail -- something else was appended (or, for a
+// nil expected, the WAL is no longer empty) since the caller last observed
+// it.
+var ErrTailMismatch = fmt.Errorf("wal: expected tail position no longer matches the WAL's actual tail")
+
+// WriteIfTail appends data the same way Write does, but only if expected
+// is still the position LastPosition would return -- i.e. nothing has been
+// appended since the caller last observed it. A nil expected means the
+// caller expects the WAL to still be completely empty. It returns
+// ErrTailMismatch if that's no longer true, leaving the WAL unchanged.
+//
+// This is compare-and-append: several cooperating writers can each hold
+// the last position they wrote (or nil, before any of them have written
+// anything) and race to extend the log, with at most one succeeding per
+// expected tail. That makes it the primitive a leader handoff needs --
+// a new leader's first write only goes through if no other writer
+// (in particular, a leader it's replacing) has appended anything since the
+// position it was handed -- without a separate lock service to arbitrate.
+//
+// The comparison and the append happen under the same lock, so it's exact
+// even against another WriteIfTail, Write, WriteAll, or any other writer
+// racing against it -- including under Options.Pipelined, where the
+// comparison first drains anything still sitting in the write queue so
+// expected is checked against the WAL's actual tail, not a reservation
+// that hasn't been appended yet.
+func (w *WAL) WriteIfTail(expected *ChunkPosition, data []byte) (pos *ChunkPosition, err error) {
+	var state any
+	if w.options.Hooks.BeforeWrite != nil {
+		state = w.options.Hooks.BeforeWrite()
+	}
+	defer func() {
+		if w.options.Hooks.AfterWrite != nil {
+			w.options.Hooks.AfterWrite(state, pos, err)
+		}
+		if err == nil {
+			w.notifyWatchers(pos)
+			if mirrErr := w.mirrorWrite(data); mirrErr != nil {
+				err = mirrErr
+			}
+		}
+	}()
+
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil, ErrClosed
+	}
+	if w.options.ReadOnly {
+		w.mu.Unlock()
+		return nil, ErrReadOnly
+	}
+	if err := w.drainWriteQueueLocked(); err != nil {
+		w.mu.Unlock()
+		return nil, err
+	}
+	if !tailsEqual(expected, w.lastPos) {
+		w.mu.Unlock()
+		return nil, ErrTailMismatch
+	}
+
+	var seg *segment
+	var kind deferKind
+	pos, kind, err = w.write(data, true)
+	if kind != deferNone {
+		seg = w.activeSegment
+	}
+	w.mu.Unlock()
+	if err != nil || kind == deferNone {
+		return pos, err
+	}
+	if kind == deferGroup {
+		return pos, w.requestGroupSync(seg)
+	}
+	return pos, w.syncSegmentUnlocked(seg)
+}
+
+// tailsEqual reports whether a and b refer to the same tail position,
+// treating nil as the empty-log tail -- unlike ChunkPosition.Equal, which
+// panics if either side is nil.
+func tailsEqual(a, b *ChunkPosition) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(b)
+}
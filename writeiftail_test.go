@@ -0,0 +1,121 @@
+package wal
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteIfTailSucceedsWhenExpectedMatchesCurrentTail(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-writeiftail-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	first, err := w.Write([]byte("hello"))
+	require.Nil(t, err)
+
+	second, err := w.WriteIfTail(first, []byte("world"))
+	require.Nil(t, err)
+	require.Equal(t, second, w.LastPosition())
+}
+
+func TestWriteIfTailAcceptsNilExpectedOnAnEmptyWAL(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-writeiftail-empty-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	require.Nil(t, w.LastPosition())
+	pos, err := w.WriteIfTail(nil, []byte("first"))
+	require.Nil(t, err)
+	require.Equal(t, pos, w.LastPosition())
+}
+
+func TestWriteIfTailFailsWithErrTailMismatchWhenSomethingElseWasAppended(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-writeiftail-mismatch-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	stale, err := w.Write([]byte("hello"))
+	require.Nil(t, err)
+	_, err = w.Write([]byte("someone else's write"))
+	require.Nil(t, err)
+
+	_, err = w.WriteIfTail(stale, []byte("world"))
+	require.True(t, errors.Is(err, ErrTailMismatch))
+}
+
+func TestWriteIfTailFailsWithErrTailMismatchWhenNilExpectedButWALIsNotEmpty(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-writeiftail-mismatch-nil-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("hello"))
+	require.Nil(t, err)
+
+	_, err = w.WriteIfTail(nil, []byte("world"))
+	require.True(t, errors.Is(err, ErrTailMismatch))
+}
+
+func TestWriteIfTailWorksAcrossARotation(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-writeiftail-rotation-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithSegmentSize(32))
+	require.Nil(t, err)
+	defer w.Close()
+
+	var last *ChunkPosition
+	for i := 0; i < 10; i++ {
+		last, err = w.WriteIfTail(last, []byte("0123456789"))
+		require.Nil(t, err)
+	}
+	require.Greater(t, w.activeSegment.id, SegmentID(0))
+}
+
+func TestWriteIfTailOnlyOneOfSeveralCooperatingWritersWins(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-writeiftail-handoff-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	leaderPos, err := w.Write([]byte("leader epoch 1"))
+	require.Nil(t, err)
+
+	var wins int64
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := w.WriteIfTail(leaderPos, []byte("leader epoch 2")); err == nil {
+				atomic.AddInt64(&wins, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, int64(1), wins)
+}
@@ -0,0 +1,106 @@
+package wal
+
+// writeLingerBatch accumulates the data passed to joinWriteLinger calls
+// that arrive within Options.WriteLinger of each other, so they can be
+// appended and fsynced together in one writeBatch/sync call instead of
+// one each. done is closed once the batch has been flushed, waking every
+// joinWriteLinger call blocked on it; positions and err are only valid to
+// read after that.
+type writeLingerBatch struct {
+	datas     [][]byte
+	done      chan struct{}
+	positions []*ChunkPosition
+	err       error
+}
+
+// joinWriteLinger implements Write under Options.WriteLinger: it joins the
+// batch Options.WriteLinger is currently accumulating -- starting a new one,
+// and its timer, if none is in flight -- then blocks until that batch is
+// flushed. Unlike Options.Pipelined, the call doesn't return until its data
+// is actually durable; WriteLinger only delays when the append and fsync
+// happen, not whether Write waits for them.
+func (w *WAL) joinWriteLinger(data []byte) (*ChunkPosition, error) {
+	if len(data) == 0 {
+		return nil, ErrEmptyRecord
+	}
+	if w.options.MaxRecordSize > 0 && int64(len(data)) > w.options.MaxRecordSize {
+		return nil, &ErrRecordTooLarge{Size: len(data), MaxSize: w.options.MaxRecordSize}
+	}
+
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil, ErrClosed
+	}
+	if w.options.ReadOnly {
+		w.mu.Unlock()
+		return nil, ErrReadOnly
+	}
+
+	batch := w.writeLingerBatch
+	if batch == nil {
+		batch = &writeLingerBatch{done: make(chan struct{})}
+		w.writeLingerBatch = batch
+		w.startWriteLingerTimer(batch)
+	}
+	idx := len(batch.datas)
+	batch.datas = append(batch.datas, data)
+	w.mu.Unlock()
+
+	<-batch.done
+	if batch.err != nil {
+		return nil, batch.err
+	}
+	return batch.positions[idx], nil
+}
+
+// startWriteLingerTimer runs batch's one-shot linger timer: once
+// Options.WriteLinger has passed, it flushes batch if it's still the
+// current one. If Close's drainWriteLingerLocked flushes batch first, its
+// close of batch.done wakes this goroutine early too, instead of leaving
+// it parked on the ticker for the rest of WriteLinger.
+func (w *WAL) startWriteLingerTimer(batch *writeLingerBatch) {
+	go func() {
+		ticker := w.options.Clock.NewTicker(w.options.WriteLinger)
+		defer ticker.Stop()
+		select {
+		case <-ticker.C():
+		case <-batch.done:
+			return
+		}
+
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		if w.writeLingerBatch != batch {
+			return
+		}
+		w.writeLingerBatch = nil
+		w.flushWriteLingerLocked(batch)
+	}()
+}
+
+// flushWriteLingerLocked appends and syncs batch.datas as a single batch,
+// then wakes every joinWriteLinger call blocked on it. mu must already be
+// held, and batch must already be detached from w.writeLingerBatch, the
+// same as drainWriteQueueLocked's queue.
+func (w *WAL) flushWriteLingerLocked(batch *writeLingerBatch) {
+	defer close(batch.done)
+	batch.positions, batch.err = w.writeBatch(batch.datas)
+	if batch.err != nil {
+		return
+	}
+	batch.err = w.sync()
+}
+
+// drainWriteLingerLocked flushes whatever batch Options.WriteLinger is
+// currently accumulating, instead of leaving Close to wait out its timer.
+// mu must already be held. It is a no-op if nothing is currently batched.
+func (w *WAL) drainWriteLingerLocked() error {
+	batch := w.writeLingerBatch
+	if batch == nil {
+		return nil
+	}
+	w.writeLingerBatch = nil
+	w.flushWriteLingerLocked(batch)
+	return batch.err
+}
@@ -0,0 +1,114 @@
+package wal
+
+import (
+	"os"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteLingerCoalescesConcurrentWritesIntoOneFsync(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-write-linger-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	clock := NewFakeClock(time.Unix(0, 0))
+	collector := &fsyncCountingCollector{}
+	w, err := Open(WithDirPath(dir), WithSync(true), WithWriteLinger(10*time.Millisecond), WithClock(clock), WithMetricsCollector(collector))
+	require.Nil(t, err)
+	defer w.Close()
+
+	const writers = 5
+	positions := make([]*ChunkPosition, writers)
+	errs := make([]error, writers)
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			positions[i], errs[i] = w.Write([]byte{byte('a' + i)})
+		}(i)
+	}
+
+	// Every writer above is blocked on the same linger batch until its
+	// timer fires, the same asynchronous-ticker-registration caveat
+	// TestSyncIntervalFlushesOnFakeClockTick notes for NewTicker.
+	require.Eventually(t, func() bool {
+		clock.Tick(10 * time.Millisecond)
+		clock.RunPending()
+		return collector.fsyncs.Load() > 0
+	}, time.Second, time.Millisecond, "write-linger batch never flushed after a FakeClock tick")
+
+	wg.Wait()
+	require.EqualValues(t, 1, collector.fsyncs.Load())
+
+	for i, pos := range positions {
+		require.Nil(t, errs[i])
+		data, err := w.Read(pos)
+		require.Nil(t, err)
+		require.Equal(t, []byte{byte('a' + i)}, data)
+	}
+}
+
+func TestCloseDrainsPendingWriteLingerBatch(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-write-linger-close-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir), WithWriteLinger(time.Hour))
+	require.Nil(t, err)
+
+	var pos *ChunkPosition
+	var writeErr error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		pos, writeErr = w.Write([]byte("linger me"))
+	}()
+
+	// WriteLinger is an hour here, so the only way this Write ever returns
+	// is if Close notices the batch it joined and drains it -- wait for it
+	// to actually join before racing Close against it.
+	require.Eventually(t, func() bool {
+		w.mu.RLock()
+		defer w.mu.RUnlock()
+		return w.writeLingerBatch != nil
+	}, time.Second, time.Millisecond, "write never joined a linger batch")
+
+	require.Nil(t, w.Close())
+	<-done
+
+	require.Nil(t, writeErr)
+	require.NotNil(t, pos)
+}
+
+func TestCloseDoesNotLeakTheWriteLingerTimerGoroutine(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-write-linger-leak-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	before := runtime.NumGoroutine()
+
+	w, err := Open(WithDirPath(dir), WithWriteLinger(time.Hour))
+	require.Nil(t, err)
+	go w.Write([]byte("linger me"))
+
+	require.Eventually(t, func() bool {
+		w.mu.RLock()
+		defer w.mu.RUnlock()
+		return w.writeLingerBatch != nil
+	}, time.Second, time.Millisecond, "write never joined a linger batch")
+	require.Nil(t, w.Close())
+
+	// An hour-long WriteLinger means the timer goroutine startWriteLingerTimer
+	// started for that batch only exits this soon if Close's early flush
+	// woke it up itself, rather than leaving it parked on the ticker. The
+	// +1 allows for Eventually's own polling goroutine, alive for the
+	// duration of this very check.
+	require.Eventually(t, func() bool {
+		return runtime.NumGoroutine() <= before+1
+	}, time.Second, 10*time.Millisecond, "write-linger timer goroutine outlived Close")
+}
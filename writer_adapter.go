@@ -0,0 +1,22 @@
+package wal
+
+// WriterAdapter adapts a WAL to the io.Writer interface, so it can be
+// plugged into code that already expects one (loggers, encoders, and the
+// like). Each Write call becomes exactly one chunk.
+type WriterAdapter struct {
+	wal *WAL
+}
+
+// NewWriterAdapter returns a WriterAdapter backed by w.
+func NewWriterAdapter(w *WAL) *WriterAdapter {
+	return &WriterAdapter{wal: w}
+}
+
+// Write appends p as a single chunk and returns len(p), nil on success, to
+// satisfy io.Writer. p is not retained.
+func (a *WriterAdapter) Write(p []byte) (n int, err error) {
+	if _, err := a.wal.Write(p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,40 @@
+package wal
+
+import (
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriterAdapterWritesOneChunkPerCall(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-writer-adapter-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	adapter := NewWriterAdapter(w)
+	n, err := io.WriteString(adapter, "hello")
+	require.Nil(t, err)
+	require.Equal(t, 5, n)
+
+	n, err = io.WriteString(adapter, "world")
+	require.Nil(t, err)
+	require.Equal(t, 5, n)
+
+	r := w.NewReader()
+	data, _, err := r.Next()
+	require.Nil(t, err)
+	require.Equal(t, []byte("hello"), data)
+
+	data, _, err = r.Next()
+	require.Nil(t, err)
+	require.Equal(t, []byte("world"), data)
+
+	_, _, err = r.Next()
+	require.Equal(t, io.EOF, err)
+}
@@ -0,0 +1,39 @@
+package wal
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteRawAppendsWhenTheChecksumMatches(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-writeraw-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	data := []byte("relayed record")
+	pos, err := w.WriteRaw(data, ChecksumCRC32C, checksumPayload(ChecksumCRC32C, data))
+	require.Nil(t, err)
+
+	got, err := w.Read(pos)
+	require.Nil(t, err)
+	require.Equal(t, data, got)
+}
+
+func TestWriteRawRejectsAMismatchedChecksum(t *testing.T) {
+	dir, err := os.MkdirTemp("", "wal-writeraw-mismatch-test")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := Open(WithDirPath(dir))
+	require.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.WriteRaw([]byte("relayed record"), ChecksumCRC32C, 12345)
+	require.ErrorIs(t, err, ErrInvalidCRC)
+}
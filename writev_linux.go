@@ -0,0 +1,32 @@
+//go:build linux
+
+package wal
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// writevFile writes bufs to f with a single writev(2) syscall. A short
+// write -- the kernel accepting fewer bytes than requested, same as a
+// plain write(2) can do -- is retried with whatever bufs weren't fully
+// written yet, the same way os.File.Write already transparently retries a
+// short write internally.
+func writevFile(f *os.File, bufs [][]byte) error {
+	for len(bufs) > 0 {
+		n, err := unix.Writev(int(f.Fd()), bufs)
+		if err != nil {
+			return err
+		}
+		for n > 0 {
+			if n < len(bufs[0]) {
+				bufs[0] = bufs[0][n:]
+				break
+			}
+			n -= len(bufs[0])
+			bufs = bufs[1:]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+
+package wal
+
+import "os"
+
+// writevFile falls back to one Write call per buffer on platforms without
+// the golang.org/x/sys/unix writev wrapper this package uses on Linux.
+func writevFile(f *os.File, bufs [][]byte) error {
+	for _, b := range bufs {
+		if _, err := f.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}